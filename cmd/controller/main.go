@@ -70,6 +70,8 @@ dynamically provisions and optimizes Kubernetes nodes using the VPSie cloud plat
 	// Add flags
 	addFlags(cmd, opts)
 
+	cmd.AddCommand(newSimulateScaleDownCommand())
+
 	return cmd
 }
 
@@ -86,6 +88,8 @@ func addFlags(cmd *cobra.Command, opts *controller.Options) {
 		"Address for the metrics server to bind to")
 	flags.StringVar(&opts.HealthProbeAddr, "health-addr", opts.HealthProbeAddr,
 		"Address for the health probe server to bind to")
+	flags.StringVar(&opts.DebugAddr, "debug-addr", opts.DebugAddr,
+		"Address for the unauthenticated debug server serving scale-down simulations to bind to (\"0\" to disable)")
 
 	// Leader election configuration
 	flags.BoolVar(&opts.EnableLeaderElection, "leader-election", opts.EnableLeaderElection,
@@ -150,6 +154,56 @@ func addFlags(cmd *cobra.Command, opts *controller.Options) {
 		"Sample rate for Sentry performance traces (0.0 to 1.0)")
 	flags.Float64Var(&opts.SentryErrorSampleRate, "sentry-error-sample-rate", opts.SentryErrorSampleRate,
 		"Sample rate for Sentry error events (0.0 to 1.0)")
+
+	// Cost configuration
+	flags.StringVar(&opts.PricingConfigMapName, "pricing-configmap", opts.PricingConfigMapName,
+		"Name of the ConfigMap containing the pricing plan (committed pricing and discounts)")
+	flags.StringVar(&opts.PricingConfigMapNamespace, "pricing-configmap-namespace", opts.PricingConfigMapNamespace,
+		"Namespace of the pricing plan ConfigMap")
+	flags.StringVar(&opts.PriceCatalogFile, "price-catalog-file", opts.PriceCatalogFile,
+		"Path to an offline price catalog file (YAML or JSON) used when live pricing is unavailable")
+	flags.StringVar(&opts.PriceCatalogConfigMapName, "price-catalog-configmap", opts.PriceCatalogConfigMapName,
+		"Name of the ConfigMap containing the offline price catalog")
+	flags.StringVar(&opts.PriceCatalogConfigMapNamespace, "price-catalog-configmap-namespace", opts.PriceCatalogConfigMapNamespace,
+		"Namespace of the price catalog ConfigMap")
+	flags.StringVar(&opts.PriceCatalogMode, "price-catalog-mode", opts.PriceCatalogMode,
+		"How the price catalog is used: fallback (only when the API is unavailable) or override (catalog prices win)")
+	flags.DurationVar(&opts.SavingsLedgerWindow, "savings-ledger-window", opts.SavingsLedgerWindow,
+		"Period NodeGroup cost is measured over before and after a scale-down to verify its savings (0 disables the ledger)")
+	flags.StringVar(&opts.SavingsLedgerConfigMapName, "savings-ledger-configmap", opts.SavingsLedgerConfigMapName,
+		"Name of the ConfigMap the savings ledger is persisted in (empty keeps it in memory only)")
+	flags.StringVar(&opts.SavingsLedgerConfigMapNamespace, "savings-ledger-configmap-namespace", opts.SavingsLedgerConfigMapNamespace,
+		"Namespace of the savings ledger ConfigMap")
+
+	// Carbon-aware placement configuration
+	flags.StringVar(&opts.CarbonIntensityFile, "carbon-intensity-file", opts.CarbonIntensityFile,
+		"Path to a per-datacenter carbon intensity table (YAML or JSON) for carbon-aware placement")
+	flags.StringSliceVar(&opts.CandidateDatacenterIDs, "candidate-datacenter-ids", opts.CandidateDatacenterIDs,
+		"Comma-separated VPSie datacenter IDs dynamic NodeGroups may be created in")
+	flags.StringVar(&opts.CarbonObjective, "carbon-objective", opts.CarbonObjective,
+		"How carbon intensity is weighed against cost: cost, balanced or low-carbon")
+
+	// Scale-up configuration
+	flags.StringVar(&opts.PendingPodDetection, "pending-pod-detection", opts.PendingPodDetection,
+		"How unschedulable pods are detected: events (FailedScheduling events) or pods (PodScheduled condition)")
+
+	// Predictive scaling configuration
+	flags.StringVar(&opts.PredictiveScaling, "predictive-scaling", opts.PredictiveScaling,
+		"Predictive scale-up mode: off, shadow (forecast and export metrics only) or active (pre-scale NodeGroups)")
+	flags.DurationVar(&opts.PredictiveScalingHorizon, "predictive-scaling-horizon", opts.PredictiveScalingHorizon,
+		"How far ahead node demand is forecast and NodeGroups are pre-scaled")
+	flags.IntVar(&opts.PredictiveScalingLookbackDays, "predictive-scaling-lookback-days", opts.PredictiveScalingLookbackDays,
+		"Number of previous days of demand history a forecast draws on")
+	flags.StringVar(&opts.PredictiveScalingConfigMapName, "predictive-scaling-configmap", opts.PredictiveScalingConfigMapName,
+		"Name of the ConfigMap the demand history is persisted in (empty keeps it in memory only)")
+	flags.StringVar(&opts.PredictiveScalingConfigMapNamespace, "predictive-scaling-configmap-namespace", opts.PredictiveScalingConfigMapNamespace,
+		"Namespace of the demand history ConfigMap")
+
+	// Drain configuration
+	flags.Float64Var(&opts.DrainEvictionQPS, "drain-eviction-qps", opts.DrainEvictionQPS,
+		"Eviction requests per second across all node drains (0 for unlimited)")
+	flags.IntVar(&opts.DrainEvictionBurst, "drain-eviction-burst", opts.DrainEvictionBurst,
+		"Eviction requests allowed at once across all node drains")
 }
 
 // run starts the controller manager
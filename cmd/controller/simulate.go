@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/controller"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/scaler"
+)
+
+// simulateOptions holds the flags of the simulate-scale-down command
+type simulateOptions struct {
+	endpoint  string
+	nodeGroup string
+	namespace string
+	node      string
+	output    string
+	timeout   time.Duration
+}
+
+// newSimulateScaleDownCommand creates the command querying a running
+// controller for a scale-down dry-run
+func newSimulateScaleDownCommand() *cobra.Command {
+	opts := &simulateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "simulate-scale-down",
+		Short: "Explain whether nodes would be scaled down",
+		Long: `Runs the scale-down checks of a running controller for a NodeGroup or a
+single node without side effects and reports every check that passed or failed.
+
+The controller serves simulations on its debug address, which only listens on
+localhost, and tracks utilization on the leader. Reach the leader's debug
+address through kubectl port-forward.`,
+		Example: `  kubectl -n kube-system port-forward pod/<leader-pod> 8082
+  vpsie-autoscaler simulate-scale-down --nodegroup general-purpose -n kube-system
+  vpsie-autoscaler simulate-scale-down --node worker-1 -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), opts.timeout)
+			defer cancel()
+			return runSimulateScaleDown(ctx, opts, cmd.OutOrStdout())
+		},
+		SilenceUsage: true,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.endpoint, "endpoint", "http://localhost:8082",
+		"Base URL of the controller's debug server")
+	flags.StringVar(&opts.nodeGroup, "nodegroup", "",
+		"NodeGroup to simulate (derived from --node if omitted)")
+	flags.StringVarP(&opts.namespace, "namespace", "n", "",
+		"Namespace of the NodeGroup, required if its name is ambiguous")
+	flags.StringVar(&opts.node, "node", "",
+		"Only simulate this node")
+	flags.StringVarP(&opts.output, "output", "o", scaler.SimulationFormatText,
+		"Output format (text, json)")
+	flags.DurationVar(&opts.timeout, "timeout", 30*time.Second,
+		"Timeout of the request to the controller")
+
+	return cmd
+}
+
+// runSimulateScaleDown fetches a simulation report from the controller and
+// writes it to out
+func runSimulateScaleDown(ctx context.Context, opts *simulateOptions, out io.Writer) error {
+	if opts.nodeGroup == "" && opts.node == "" {
+		return fmt.Errorf("--nodegroup or --node is required")
+	}
+	if opts.output != scaler.SimulationFormatText && opts.output != scaler.SimulationFormatJSON {
+		return fmt.Errorf("invalid output format %q, must be one of: text, json", opts.output)
+	}
+
+	endpoint, err := url.Parse(strings.TrimSuffix(opts.endpoint, "/") + controller.ScaleDownSimulationPath)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	query := url.Values{}
+	query.Set("format", scaler.SimulationFormatJSON)
+	if opts.nodeGroup != "" {
+		query.Set("nodegroup", opts.nodeGroup)
+	}
+	if opts.namespace != "" {
+		query.Set("namespace", opts.namespace)
+	}
+	if opts.node != "" {
+		query.Set("node", opts.node)
+	}
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query controller: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("controller returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var report scaler.SimulationReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return fmt.Errorf("failed to decode report: %w", err)
+	}
+
+	return scaler.WriteSimulationReport(out, &report, opts.output)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/controller"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/scaler"
+)
+
+func TestRunSimulateScaleDown(t *testing.T) {
+	var query map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, controller.ScaleDownSimulationPath, r.URL.Path)
+		query = map[string]string{}
+		for key := range r.URL.Query() {
+			query[key] = r.URL.Query().Get(key)
+		}
+		if r.URL.Query().Get("nodegroup") == "missing" {
+			http.Error(w, "NodeGroup missing not found", http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(scaler.SimulationReport{
+			NodeGroup: "general",
+			Namespace: "kube-system",
+			Nodes: []scaler.NodeSimulation{{
+				Node:      "worker-1",
+				Removable: true,
+				Checks:    []scaler.SimulationCheck{{Name: "policy", Passed: true, Message: "policies allow scale-down"}},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	t.Run("text output", func(t *testing.T) {
+		var out bytes.Buffer
+		err := runSimulateScaleDown(context.Background(), &simulateOptions{
+			endpoint:  server.URL + "/",
+			nodeGroup: "general",
+			namespace: "kube-system",
+			output:    scaler.SimulationFormatText,
+		}, &out)
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]string{
+			"nodegroup": "general",
+			"namespace": "kube-system",
+			"format":    scaler.SimulationFormatJSON,
+		}, query)
+		assert.Contains(t, out.String(), "Node worker-1: removable")
+		assert.Contains(t, out.String(), "[PASS] policy: policies allow scale-down")
+	})
+
+	t.Run("json output", func(t *testing.T) {
+		var out bytes.Buffer
+		err := runSimulateScaleDown(context.Background(), &simulateOptions{
+			endpoint: server.URL,
+			node:     "worker-1",
+			output:   scaler.SimulationFormatJSON,
+		}, &out)
+		require.NoError(t, err)
+
+		assert.Equal(t, "worker-1", query["node"])
+		var report scaler.SimulationReport
+		require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+		assert.Equal(t, "general", report.NodeGroup)
+	})
+
+	t.Run("controller error", func(t *testing.T) {
+		err := runSimulateScaleDown(context.Background(), &simulateOptions{
+			endpoint:  server.URL,
+			nodeGroup: "missing",
+			output:    scaler.SimulationFormatText,
+		}, &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "404")
+		assert.Contains(t, err.Error(), "NodeGroup missing not found")
+	})
+}
+
+func TestRunSimulateScaleDown_InvalidOptions(t *testing.T) {
+	err := runSimulateScaleDown(context.Background(), &simulateOptions{output: scaler.SimulationFormatText}, &bytes.Buffer{})
+	assert.Error(t, err)
+
+	err = runSimulateScaleDown(context.Background(), &simulateOptions{nodeGroup: "general", output: "yaml"}, &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+func TestSimulateScaleDownCommand(t *testing.T) {
+	cmd := newRootCommand()
+
+	simulate, _, err := cmd.Find([]string{"simulate-scale-down"})
+	require.NoError(t, err)
+	assert.Equal(t, "simulate-scale-down", simulate.Name())
+
+	for _, name := range []string{"endpoint", "nodegroup", "namespace", "node", "output", "timeout"} {
+		assert.NotNil(t, simulate.Flags().Lookup(name), "flag %s", name)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	autoscalerv1alpha1 "github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/costreport"
+	vpsieclient "github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/client"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
+)
+
+var (
+	// Version information (set via ldflags during build)
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+
+	// Scheme for all Kubernetes API types
+	scheme = runtime.NewScheme()
+)
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = autoscalerv1alpha1.AddToScheme(scheme)
+}
+
+// reportOptions holds the cost-report command line options
+type reportOptions struct {
+	Kubeconfig                string
+	Namespace                 string
+	Output                    string
+	PriceCatalogFile          string
+	Offline                   bool
+	VPSieSecretName           string
+	VPSieSecretNamespace      string
+	PricingConfigMapName      string
+	PricingConfigMapNamespace string
+	SavingsLedgerConfigMap    string
+	SavingsLedgerNamespace    string
+	Timeout                   time.Duration
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// newRootCommand creates the cost-report command
+func newRootCommand() *cobra.Command {
+	opts := &reportOptions{
+		Output:                    string(costreport.FormatTable),
+		VPSieSecretName:           "vpsie-secret",
+		VPSieSecretNamespace:      "kube-system",
+		PricingConfigMapNamespace: "kube-system",
+		SavingsLedgerConfigMap:    "vpsie-autoscaler-savings-ledger",
+		SavingsLedgerNamespace:    "kube-system",
+		Timeout:                   2 * time.Minute,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "cost-report",
+		Short: "Report VPSie NodeGroup spend, waste and recommended changes",
+		Long: `cost-report reads NodeGroups, Nodes, Pods and node metrics from a cluster,
+prices them with the VPSie API or an offline price catalog, and prints current
+spend, a waste estimate and recommended optimizations as a table, JSON or CSV.
+Utilization is measured with each NodeGroup's scale-down utilization mode.`,
+		Version: fmt.Sprintf("%s (commit: %s, built: %s)", Version, Commit, BuildDate),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(cmd.Context(), opts)
+		},
+		SilenceUsage: true,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.Kubeconfig, "kubeconfig", opts.Kubeconfig,
+		"Path to kubeconfig file (defaults to KUBECONFIG or ~/.kube/config)")
+	flags.StringVarP(&opts.Namespace, "namespace", "n", opts.Namespace,
+		"Only report NodeGroups in this namespace (default: all namespaces)")
+	flags.StringVarP(&opts.Output, "output", "o", opts.Output,
+		"Output format: table, json or csv")
+	flags.StringVar(&opts.PriceCatalogFile, "price-catalog-file", opts.PriceCatalogFile,
+		"Path to an offline price catalog file (YAML or JSON)")
+	flags.BoolVar(&opts.Offline, "offline", opts.Offline,
+		"Price from the price catalog only, without contacting the VPSie API")
+	flags.StringVar(&opts.VPSieSecretName, "vpsie-secret-name", opts.VPSieSecretName,
+		"Name of the Kubernetes secret containing VPSie API credentials")
+	flags.StringVar(&opts.VPSieSecretNamespace, "vpsie-secret-namespace", opts.VPSieSecretNamespace,
+		"Namespace of the VPSie credentials secret")
+	flags.StringVar(&opts.PricingConfigMapName, "pricing-configmap", opts.PricingConfigMapName,
+		"Name of the ConfigMap containing the pricing plan (committed pricing and discounts)")
+	flags.StringVar(&opts.PricingConfigMapNamespace, "pricing-configmap-namespace", opts.PricingConfigMapNamespace,
+		"Namespace of the pricing plan ConfigMap")
+	flags.StringVar(&opts.SavingsLedgerConfigMap, "savings-ledger-configmap", opts.SavingsLedgerConfigMap,
+		"Name of the controller's savings ledger ConfigMap used to weight recommendation confidence (empty to disable)")
+	flags.StringVar(&opts.SavingsLedgerNamespace, "savings-ledger-configmap-namespace", opts.SavingsLedgerNamespace,
+		"Namespace of the savings ledger ConfigMap")
+	flags.DurationVar(&opts.Timeout, "timeout", opts.Timeout,
+		"Maximum time to spend generating the report")
+
+	return cmd
+}
+
+// run generates and prints the cost report
+func run(ctx context.Context, opts *reportOptions) error {
+	if opts.Offline && opts.PriceCatalogFile == "" {
+		return fmt.Errorf("--offline requires --price-catalog-file")
+	}
+
+	switch costreport.Format(opts.Output) {
+	case costreport.FormatTable, costreport.FormatJSON, costreport.FormatCSV:
+	default:
+		return fmt.Errorf("invalid output format '%s', must be one of: table, json, csv", opts.Output)
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	config, err := buildKubeConfig(opts.Kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	k8sClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	crClient, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("failed to create controller-runtime client: %w", err)
+	}
+
+	calculator, err := newCalculator(ctx, k8sClient, opts)
+	if err != nil {
+		return err
+	}
+
+	ledger, err := newSavingsLedger(ctx, k8sClient, opts)
+	if err != nil {
+		return err
+	}
+
+	nodeGroupList := &autoscalerv1alpha1.NodeGroupList{}
+	var listOpts []client.ListOption
+	if opts.Namespace != "" {
+		listOpts = append(listOpts, client.InNamespace(opts.Namespace))
+	}
+	if err := crClient.List(ctx, nodeGroupList, listOpts...); err != nil {
+		return fmt.Errorf("failed to list NodeGroups: %w", err)
+	}
+
+	nodeList, err := k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	podList, err := k8sClient.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	// Usage is optional, NodeGroups measured by usage fall back to requests
+	// without metrics-server
+	var nodeMetrics []metricsv1beta1.NodeMetrics
+	metricsClient, err := metricsclientset.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics client: %w", err)
+	}
+	if metricsList, err := metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{}); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to get node metrics, utilization is based on pod requests: %v\n", err)
+	} else {
+		nodeMetrics = metricsList.Items
+	}
+
+	report, err := costreport.NewGenerator(calculator, ledger).Generate(ctx, nodeGroupList.Items, nodeList.Items, podList.Items, nodeMetrics)
+	if err != nil {
+		return fmt.Errorf("failed to generate cost report: %w", err)
+	}
+
+	return costreport.Write(os.Stdout, report, costreport.Format(opts.Output))
+}
+
+// newCalculator creates a cost calculator priced from the VPSie API and/or the
+// offline price catalog
+func newCalculator(ctx context.Context, k8sClient kubernetes.Interface, opts *reportOptions) (*cost.Calculator, error) {
+	var catalog *cost.PriceCatalog
+	if opts.PriceCatalogFile != "" {
+		var err error
+		catalog, err = cost.LoadPriceCatalogFile(opts.PriceCatalogFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var calculator *cost.Calculator
+	if opts.Offline {
+		calculator = cost.NewCalculator(nil)
+	} else {
+		vpsieClient, err := vpsieclient.NewClient(ctx, k8sClient, &vpsieclient.ClientOptions{
+			SecretName:      opts.VPSieSecretName,
+			SecretNamespace: opts.VPSieSecretNamespace,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create VPSie client (use --offline with --price-catalog-file to skip): %w", err)
+		}
+		calculator = cost.NewCalculator(vpsieClient)
+	}
+
+	if catalog != nil {
+		calculator.SetPriceCatalog(catalog, cost.CatalogModeFallback)
+	}
+
+	if opts.PricingConfigMapName != "" {
+		plan, err := cost.LoadPricingPlanFromConfigMap(ctx, k8sClient, opts.PricingConfigMapNamespace, opts.PricingConfigMapName)
+		if err != nil {
+			return nil, err
+		}
+		calculator.SetPricingPlan(plan)
+	}
+
+	return calculator, nil
+}
+
+// newSavingsLedger loads the savings ledger the controller persists in a ConfigMap.
+// Returns nil if no ConfigMap is configured.
+func newSavingsLedger(ctx context.Context, k8sClient kubernetes.Interface, opts *reportOptions) (*cost.SavingsLedger, error) {
+	if opts.SavingsLedgerConfigMap == "" {
+		return nil, nil
+	}
+
+	ledger := cost.NewSavingsLedger(nil, 0)
+	ledger.SetStore(cost.NewConfigMapLedgerStore(k8sClient, opts.SavingsLedgerNamespace, opts.SavingsLedgerConfigMap))
+	if err := ledger.Load(ctx); err != nil {
+		return nil, err
+	}
+	return ledger, nil
+}
+
+// buildKubeConfig creates a Kubernetes client configuration from the kubeconfig
+// path or the default loading rules
+func buildKubeConfig(kubeconfig string) (*rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		loadingRules.ExplicitPath = kubeconfig
+	}
+
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build config from kubeconfig: %w", err)
+	}
+	return config, nil
+}
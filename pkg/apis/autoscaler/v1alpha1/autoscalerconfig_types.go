@@ -42,6 +42,24 @@ type NodeGroupDefaults struct {
 	// +optional
 	DatacenterID string `json:"datacenterID,omitempty"`
 
+	// CandidateDatacenterIDs are datacenters dynamic NodeGroups may be created in.
+	// The datacenter is chosen by CarbonObjective; DatacenterID is used when empty.
+	// +optional
+	CandidateDatacenterIDs []string `json:"candidateDatacenterIDs,omitempty"`
+
+	// CarbonObjective weighs datacenter carbon intensity against cost when choosing
+	// a datacenter for dynamic NodeGroups
+	// Values: "cost", "balanced", "low-carbon"
+	// +kubebuilder:validation:Enum=cost;balanced;low-carbon
+	// +optional
+	CarbonObjective string `json:"carbonObjective,omitempty"`
+
+	// IdleTTL is how long a dynamic NodeGroup may run without workload pods
+	// before it is drained and deleted. Zero disables the garbage collection.
+	// +kubebuilder:default="30m"
+	// +optional
+	IdleTTL *metav1.Duration `json:"idleTTL,omitempty"`
+
 	// OfferingIDs is a list of allowed VPSie offering/boxsize IDs
 	// +optional
 	OfferingIDs []string `json:"offeringIDs,omitempty"`
@@ -145,6 +163,19 @@ type GlobalAutoscalerSettings struct {
 	// +optional
 	UnschedulablePodGracePeriodSeconds int32 `json:"unschedulablePodGracePeriodSeconds,omitempty"`
 
+	// ExpendablePodsPriorityCutoff is the pod priority below which pending pods
+	// never trigger scale-up. Such pods only run on spare capacity.
+	// +kubebuilder:default=-10
+	// +optional
+	ExpendablePodsPriorityCutoff *int32 `json:"expendablePodsPriorityCutoff,omitempty"`
+
+	// EnablePreemptionCheck skips scale-up for pending pods the scheduler has
+	// nominated a node for, when the pod fits on that node once the
+	// lower-priority pods it preempts are gone
+	// +kubebuilder:default=true
+	// +optional
+	EnablePreemptionCheck *bool `json:"enablePreemptionCheck,omitempty"`
+
 	// EnableDynamicNodeGroupCreation controls whether the autoscaler can create new NodeGroups
 	// +kubebuilder:default=true
 	// +optional
@@ -170,6 +201,25 @@ type GlobalAutoscalerSettings struct {
 	// +kubebuilder:default=120
 	// +optional
 	PodEvictionTimeoutSeconds int32 `json:"podEvictionTimeoutSeconds,omitempty"`
+
+	// Expander is the strategy for choosing between NodeGroups that can schedule the
+	// same pending pods. Exactly one NodeGroup is scaled up per set of pods.
+	// Values: "least-waste", "cheapest", "most-pods", "random", "priority"
+	// +kubebuilder:validation:Enum=least-waste;cheapest;most-pods;random;priority
+	// +kubebuilder:default=least-waste
+	// +optional
+	Expander string `json:"expander,omitempty"`
+
+	// PriorityConfigMapName is the ConfigMap holding NodeGroup priorities for the
+	// priority expander, under the "priorities" key
+	// +kubebuilder:default=vpsie-autoscaler-priority-expander
+	// +optional
+	PriorityConfigMapName string `json:"priorityConfigMapName,omitempty"`
+
+	// PriorityConfigMapNamespace is the namespace of the priority expander ConfigMap
+	// +kubebuilder:default=kube-system
+	// +optional
+	PriorityConfigMapNamespace string `json:"priorityConfigMapNamespace,omitempty"`
 }
 
 // AutoscalerConfigStatus defines the observed state of AutoscalerConfig
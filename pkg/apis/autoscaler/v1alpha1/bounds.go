@@ -0,0 +1,22 @@
+package v1alpha1
+
+// Node count bound helpers. These are defined here in the API types package so
+// the controller, event and scaler packages apply the same bounds.
+
+// EffectiveMinNodes returns the minimum node count currently in force: the
+// active scaling schedule's if a window is open, otherwise spec.minNodes.
+func EffectiveMinNodes(ng *NodeGroup) int32 {
+	if ng.Status.ActiveSchedule != nil {
+		return ng.Status.ActiveSchedule.MinNodes
+	}
+	return ng.Spec.MinNodes
+}
+
+// EffectiveMaxNodes returns the maximum node count currently in force: the
+// active scaling schedule's if a window is open, otherwise spec.maxNodes.
+func EffectiveMaxNodes(ng *NodeGroup) int32 {
+	if ng.Status.ActiveSchedule != nil {
+		return ng.Status.ActiveSchedule.MaxNodes
+	}
+	return ng.Spec.MaxNodes
+}
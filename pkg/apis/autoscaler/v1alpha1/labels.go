@@ -1,5 +1,7 @@
 package v1alpha1
 
+import "time"
+
 // Label and annotation keys used for NodeGroup and VPSieNode management.
 // These constants are defined here in the API types package to avoid circular
 // dependencies between controller and event packages.
@@ -47,6 +49,25 @@ const (
 
 	// CreationReasonInitial indicates the node was created during initial nodegroup setup
 	CreationReasonInitial = "initial"
+
+	// ConsolidationLabelKey is the label key recording the ID of the consolidation
+	// a VPSieNode was created for or retired by
+	ConsolidationLabelKey = "autoscaler.vpsie.com/consolidation"
+
+	// AutoManagedAnnotationKey marks NodeGroups the autoscaler created for pending pods.
+	// Auto-managed NodeGroups are garbage-collected once idle for their idle TTL.
+	AutoManagedAnnotationKey = "autoscaler.vpsie.com/auto-managed"
+
+	// AutoManagedAnnotationValue is the expected value for the auto-managed annotation.
+	AutoManagedAnnotationValue = "true"
+
+	// SkipFailedDrainHooksAnnotationKey set to "true" on a VPSieNode lets its
+	// termination carry on past drain hooks that failed with the Abort policy.
+	SkipFailedDrainHooksAnnotationKey = "autoscaler.vpsie.com/skip-failed-drain-hooks"
+
+	// IdleTTLAnnotationKey is the annotation key for how long an auto-managed NodeGroup
+	// may run without workload pods before it is deleted, as a Go duration (e.g. "30m").
+	IdleTTLAnnotationKey = "autoscaler.vpsie.com/idle-ttl"
 )
 
 // IsManagedNodeGroup checks if the NodeGroup has the managed label set to "true".
@@ -72,3 +93,25 @@ func SetNodeGroupManaged(ng *NodeGroup) {
 	}
 	ng.Labels[ManagedLabelKey] = ManagedLabelValue
 }
+
+// IsAutoManagedNodeGroup checks if the NodeGroup was created by the autoscaler
+// and carries the auto-managed annotation.
+func IsAutoManagedNodeGroup(ng *NodeGroup) bool {
+	if ng == nil || ng.Annotations == nil {
+		return false
+	}
+	return ng.Annotations[AutoManagedAnnotationKey] == AutoManagedAnnotationValue
+}
+
+// NodeGroupIdleTTL returns the idle TTL of an auto-managed NodeGroup.
+// Returns false if the NodeGroup is not auto-managed or has no valid, positive idle TTL.
+func NodeGroupIdleTTL(ng *NodeGroup) (time.Duration, bool) {
+	if !IsAutoManagedNodeGroup(ng) {
+		return 0, false
+	}
+	ttl, err := time.ParseDuration(ng.Annotations[IdleTTLAnnotationKey])
+	if err != nil || ttl <= 0 {
+		return 0, false
+	}
+	return ttl, true
+}
@@ -1,6 +1,8 @@
 package v1alpha1
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -65,6 +67,12 @@ type NodeGroupSpec struct {
 	// +optional
 	Taints []corev1.Taint `json:"taints,omitempty"`
 
+	// ExtendedResources are the hugepages and extended resources (e.g.
+	// "nvidia.com/gpu") each node in this group provides. Pending pods
+	// requesting one of these resources only match NodeGroups declaring it.
+	// +optional
+	ExtendedResources corev1.ResourceList `json:"extendedResources,omitempty"`
+
 	// ScaleUpPolicy defines when and how to scale up the node group
 	// +optional
 	ScaleUpPolicy ScaleUpPolicy `json:"scaleUpPolicy,omitempty"`
@@ -102,6 +110,305 @@ type NodeGroupSpec struct {
 	// CostOptimization defines cost optimization settings for this NodeGroup
 	// +optional
 	CostOptimization *CostOptimizationConfig `json:"costOptimization,omitempty"`
+
+	// Headroom is spare capacity kept free in the node group so new pods can
+	// schedule immediately while the autoscaler adds nodes
+	// +optional
+	Headroom *HeadroomConfig `json:"headroom,omitempty"`
+
+	// Schedules override the node count bounds during recurring time windows.
+	// The first entry whose window is open applies.
+	// +optional
+	Schedules []ScalingSchedule `json:"schedules,omitempty"`
+
+	// Drain configures how nodes are drained before they are removed
+	// +optional
+	Drain *DrainConfig `json:"drain,omitempty"`
+
+	// Consolidation replaces underutilized nodes with fewer nodes of a larger
+	// offering from OfferingIDs when that lowers the cost
+	// +optional
+	Consolidation *ConsolidationConfig `json:"consolidation,omitempty"`
+}
+
+// HeadroomConfig defines the spare capacity maintained in a NodeGroup.
+// When several fields are set, the largest requirement applies.
+type HeadroomConfig struct {
+	// SpareNodes is the number of nodes worth of unrequested allocatable
+	// CPU and memory to keep available
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SpareNodes int32 `json:"spareNodes,omitempty"`
+
+	// SpareCPUPercent is the percentage of the NodeGroup's allocatable CPU
+	// to keep unrequested
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	SpareCPUPercent int32 `json:"spareCPUPercent,omitempty"`
+
+	// SpareMemoryPercent is the percentage of the NodeGroup's allocatable
+	// memory to keep unrequested
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	SpareMemoryPercent int32 `json:"spareMemoryPercent,omitempty"`
+}
+
+// ScalingSchedule overrides a NodeGroup's node counts during a recurring
+// time window, for example weekdays from 08:00 for 11 hours.
+//
+// While the window is open MinNodes and MaxNodes replace the spec bounds for
+// all scaling, including reactive scale-up and scale-down. MaxNodes is raised
+// to MinNodes if lower. DesiredNodes is applied once when the window opens;
+// reactive scaling then moves the desired count freely within the bounds.
+// When the window closes the spec bounds apply again.
+type ScalingSchedule struct {
+	// Name identifies the schedule in status and events
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Schedule is a five-field cron expression (minute hour day-of-month
+	// month day-of-week) for the start of each window, e.g. "0 8 * * 1-5"
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Duration is how long each window stays open, e.g. "11h"
+	Duration metav1.Duration `json:"duration"`
+
+	// TimeZone is the IANA time zone Schedule is evaluated in, e.g. "Europe/Berlin"
+	// +kubebuilder:default=UTC
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// MinNodes replaces spec.minNodes while the window is open
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinNodes *int32 `json:"minNodes,omitempty"`
+
+	// MaxNodes replaces spec.maxNodes while the window is open
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxNodes *int32 `json:"maxNodes,omitempty"`
+
+	// DesiredNodes is the desired node count set when the window opens
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DesiredNodes *int32 `json:"desiredNodes,omitempty"`
+}
+
+// ActiveScalingSchedule describes the scaling schedule currently in force
+type ActiveScalingSchedule struct {
+	// Name is the name of the schedule entry
+	Name string `json:"name"`
+
+	// MinNodes is the minimum node count while the window is open
+	MinNodes int32 `json:"minNodes"`
+
+	// MaxNodes is the maximum node count while the window is open
+	MaxNodes int32 `json:"maxNodes"`
+
+	// StartTime is when the window opened
+	StartTime metav1.Time `json:"startTime"`
+
+	// EndTime is when the window closes
+	EndTime metav1.Time `json:"endTime"`
+}
+
+// DrainConfig defines how the nodes of a NodeGroup are drained
+type DrainConfig struct {
+	// SkipNamespaces lists namespaces whose pods are not evicted. They are
+	// removed together with the node.
+	// +optional
+	SkipNamespaces []string `json:"skipNamespaces,omitempty"`
+
+	// DeleteEmptyDirData allows evicting pods with emptyDir volumes, whose
+	// data is lost. When false, nodes running such pods are not drained.
+	// +kubebuilder:default=true
+	// +optional
+	DeleteEmptyDirData *bool `json:"deleteEmptyDirData,omitempty"`
+
+	// ForceDeleteAfter is how long after a drain starts pods that still
+	// cannot be evicted, e.g. because of a PodDisruptionBudget, or have not
+	// terminated are deleted directly. Unset never force-deletes.
+	// +optional
+	ForceDeleteAfter *metav1.Duration `json:"forceDeleteAfter,omitempty"`
+
+	// MaxConcurrentEvictions bounds the evictions in flight per drained
+	// node. Pods are evicted lowest priority first and system-critical pods
+	// only once all other pods were evicted. Defaults to 10.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	MaxConcurrentEvictions *int32 `json:"maxConcurrentEvictions,omitempty"`
+
+	// PreDrainHooks run in order when a node's termination starts, before
+	// the node is cordoned and drained
+	// +optional
+	PreDrainHooks []DrainHook `json:"preDrainHooks,omitempty"`
+
+	// PostDrainHooks run in order after the node is drained, before the
+	// node and its VPS are deleted
+	// +optional
+	PostDrainHooks []DrainHook `json:"postDrainHooks,omitempty"`
+}
+
+// DrainHookStage identifies when a drain hook runs
+type DrainHookStage string
+
+const (
+	// DrainHookStagePreDrain hooks run before the node is drained
+	DrainHookStagePreDrain DrainHookStage = "PreDrain"
+
+	// DrainHookStagePostDrain hooks run after the node is drained
+	DrainHookStagePostDrain DrainHookStage = "PostDrain"
+)
+
+// MaxHTTPDrainHookTimeout is the longest timeout of an HTTP drain hook
+const MaxHTTPDrainHookTimeout = 5 * time.Minute
+
+// HookFailurePolicy defines what happens when a drain hook fails
+type HookFailurePolicy string
+
+const (
+	// HookFailurePolicyAbort stops the node's termination when the hook
+	// fails, until the policy is changed to Continue or the VPSieNode is
+	// annotated with SkipFailedDrainHooksAnnotationKey
+	HookFailurePolicyAbort HookFailurePolicy = "Abort"
+
+	// HookFailurePolicyContinue carries on with the termination when the hook fails
+	HookFailurePolicyContinue HookFailurePolicy = "Continue"
+)
+
+// DrainHook is an action run while a node is terminated, for example to
+// flush state or deregister the node from an external load balancer.
+// Exactly one of HTTP and Job must be set. The result of each hook is
+// recorded as a "<stage>Hook-<name>" condition on the VPSieNode.
+type DrainHook struct {
+	// Name identifies the hook in the VPSieNode conditions
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=40
+	Name string `json:"name"`
+
+	// HTTP calls an endpoint
+	// +optional
+	HTTP *HTTPDrainHook `json:"http,omitempty"`
+
+	// Job runs a Job on the node
+	// +optional
+	Job *JobDrainHook `json:"job,omitempty"`
+
+	// Timeout bounds the hook. Defaults to 30s for HTTP and 10m for Job hooks.
+	// HTTP hooks block the node's reconcile, so their timeout is at most 5m.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// FailurePolicy is Abort to stop the node's termination when the hook
+	// fails or times out, or Continue to carry on
+	// +kubebuilder:validation:Enum=Abort;Continue
+	// +kubebuilder:default=Abort
+	// +optional
+	FailurePolicy HookFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// HTTPDrainHook calls an HTTP endpoint. The request body is a JSON object
+// with the stage, hook, nodeName, vpsieNode, nodeGroup and namespace.
+// Any 2xx response is a success.
+type HTTPDrainHook struct {
+	// URL is the http or https endpoint to call
+	// +kubebuilder:validation:MinLength=1
+	URL string `json:"url"`
+
+	// Method is the HTTP method
+	// +kubebuilder:validation:Enum=GET;POST;PUT
+	// +kubebuilder:default=POST
+	// +optional
+	Method string `json:"method,omitempty"`
+
+	// Headers are added to the request
+	// +optional
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// JobDrainHook runs a Job pinned to the node being terminated. The Job is
+// created in the NodeGroup's namespace and tolerates all taints. Its
+// completion is a success; failing or exceeding the timeout is a failure.
+type JobDrainHook struct {
+	// Template is the pod template of the Job. RestartPolicy defaults to Never.
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Template corev1.PodTemplateSpec `json:"template"`
+
+	// BackoffLimit is the number of retries before the Job fails
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=0
+	// +optional
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+}
+
+// ConsolidationConfig defines when underutilized nodes of a NodeGroup are
+// consolidated. The pods of N underutilized nodes are packed by their
+// requests onto M < N nodes of a larger offering. The replacements are
+// created first, in a VPSie node group of that offering; the old nodes are
+// drained and deleted once all replacements are Ready and their allocatable
+// holds the pods. Pods that fit on the remaining nodes are left to scale-down.
+type ConsolidationConfig struct {
+	// Enabled controls whether nodes are consolidated
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// MinSavingsPercent is the minimum reduction of the monthly cost of the
+	// replaced nodes required to consolidate them
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=10
+	// +optional
+	MinSavingsPercent int32 `json:"minSavingsPercent,omitempty"`
+
+	// ReplacementTimeoutSeconds is how long the replacement nodes may take to
+	// become Ready. When it passes they are deleted and the old nodes kept.
+	// +kubebuilder:validation:Minimum=60
+	// +kubebuilder:default=900
+	// +optional
+	ReplacementTimeoutSeconds int32 `json:"replacementTimeoutSeconds,omitempty"`
+}
+
+// ConsolidationPhase is the phase of a consolidation
+type ConsolidationPhase string
+
+const (
+	// ConsolidationPhaseProvisioning means the replacement nodes are being created
+	ConsolidationPhaseProvisioning ConsolidationPhase = "Provisioning"
+
+	// ConsolidationPhaseDraining means the replaced nodes are being drained and deleted
+	ConsolidationPhaseDraining ConsolidationPhase = "Draining"
+)
+
+// ConsolidationStatus describes the consolidation in progress. The
+// VPSieNodes taking part in it carry the ConsolidationLabelKey label with
+// its ID.
+type ConsolidationStatus struct {
+	// ID identifies the consolidation
+	ID string `json:"id"`
+
+	// Phase is the phase of the consolidation
+	Phase ConsolidationPhase `json:"phase"`
+
+	// TargetOffering is the offering of the replacement nodes
+	TargetOffering string `json:"targetOffering"`
+
+	// ReplacementNodes are the names of the VPSieNodes created to replace RetiringNodes
+	ReplacementNodes []string `json:"replacementNodes"`
+
+	// RetiringNodes are the names of the VPSieNodes deleted once the replacements are Ready
+	RetiringNodes []string `json:"retiringNodes"`
+
+	// EstimatedMonthlySavings is the expected reduction of the monthly cost
+	// +optional
+	EstimatedMonthlySavings string `json:"estimatedMonthlySavings,omitempty"`
+
+	// StartedAt is when the consolidation started
+	StartedAt metav1.Time `json:"startedAt"`
 }
 
 // ScaleUpPolicy defines the scale-up behavior for a NodeGroup
@@ -182,11 +489,21 @@ type MultiRegionConfig struct {
 	DatacenterIDs []string `json:"datacenterIDs,omitempty"`
 
 	// DistributionStrategy defines how to distribute nodes across regions
-	// Values: "balanced", "weighted", "primary-backup"
+	// Values: "balanced", "weighted", "primary-backup", "carbon-aware"
+	// "carbon-aware" places nodes beyond MinNodesPerRegion in the datacenter with the
+	// lowest carbon intensity
 	// +kubebuilder:default="balanced"
 	// +optional
 	DistributionStrategy string `json:"distributionStrategy,omitempty"`
 
+	// CarbonObjective weighs datacenter carbon intensity against cost when choosing
+	// between otherwise equal datacenters
+	// Values: "cost", "balanced", "low-carbon"
+	// +kubebuilder:validation:Enum=cost;balanced;low-carbon
+	// +kubebuilder:default="cost"
+	// +optional
+	CarbonObjective string `json:"carbonObjective,omitempty"`
+
 	// WeightedDistribution defines custom weights for each datacenter
 	// Only used when DistributionStrategy is "weighted"
 	// Key is datacenter ID, value is weight (higher = more nodes)
@@ -277,8 +594,92 @@ type ScaleDownPolicy struct {
 	// +kubebuilder:default=600
 	// +optional
 	CooldownSeconds int32 `json:"cooldownSeconds,omitempty"`
+
+	// UtilizationMode selects what the CPU and memory thresholds are compared
+	// against: Usage as reported by metrics-server, Requests of the pods on
+	// the node, or the Max of both. The cost optimizer and rebalancer
+	// measure the NodeGroup's utilization the same way.
+	// +kubebuilder:validation:Enum=Usage;Requests;Max
+	// +kubebuilder:default=Usage
+	// +optional
+	UtilizationMode UtilizationMode `json:"utilizationMode,omitempty"`
+
+	// UtilizationAggregation selects how utilization samples are aggregated
+	// over the observation window: their Mean, median (P50) or 95th
+	// percentile (P95)
+	// +kubebuilder:validation:Enum=Mean;P50;P95
+	// +kubebuilder:default=Mean
+	// +optional
+	UtilizationAggregation UtilizationAggregation `json:"utilizationAggregation,omitempty"`
+
+	// DeletionOrder selects which nodes are removed first on scale-down.
+	// Default removes underutilized nodes by utilization and pod count and
+	// empty nodes longest empty first. OldestFirst forces node rotation.
+	// SpreadPreserving removes nodes from the datacenters with the most nodes
+	// first. Nodes that are not ready are always removed first.
+	// +kubebuilder:validation:Enum=Default;OldestFirst;NewestFirst;LeastUtilized;MostExpensiveFirst;FewestPods;SpreadPreserving
+	// +kubebuilder:default=Default
+	// +optional
+	DeletionOrder NodeDeletionOrder `json:"deletionOrder,omitempty"`
 }
 
+// NodeDeletionOrder defines which nodes are removed first on scale-down
+type NodeDeletionOrder string
+
+const (
+	// NodeDeletionOrderDefault keeps the order of each scale-down path
+	NodeDeletionOrderDefault NodeDeletionOrder = "Default"
+
+	// NodeDeletionOrderOldestFirst removes the oldest nodes first
+	NodeDeletionOrderOldestFirst NodeDeletionOrder = "OldestFirst"
+
+	// NodeDeletionOrderNewestFirst removes the newest nodes first
+	NodeDeletionOrderNewestFirst NodeDeletionOrder = "NewestFirst"
+
+	// NodeDeletionOrderLeastUtilized removes the least utilized nodes first
+	NodeDeletionOrderLeastUtilized NodeDeletionOrder = "LeastUtilized"
+
+	// NodeDeletionOrderMostExpensiveFirst removes the nodes of the most
+	// expensive offering first
+	NodeDeletionOrderMostExpensiveFirst NodeDeletionOrder = "MostExpensiveFirst"
+
+	// NodeDeletionOrderFewestPods removes the nodes running the fewest
+	// workload pods first
+	NodeDeletionOrderFewestPods NodeDeletionOrder = "FewestPods"
+
+	// NodeDeletionOrderSpreadPreserving removes nodes from the datacenters
+	// with the most nodes first, keeping the NodeGroup spread across them
+	NodeDeletionOrderSpreadPreserving NodeDeletionOrder = "SpreadPreserving"
+)
+
+// UtilizationMode defines what node utilization is measured from
+type UtilizationMode string
+
+const (
+	// UtilizationModeUsage measures utilization from metrics-server usage
+	UtilizationModeUsage UtilizationMode = "Usage"
+
+	// UtilizationModeRequests measures utilization from pod resource requests
+	UtilizationModeRequests UtilizationMode = "Requests"
+
+	// UtilizationModeMax measures utilization as the higher of usage and requests
+	UtilizationModeMax UtilizationMode = "Max"
+)
+
+// UtilizationAggregation defines how utilization samples are aggregated
+type UtilizationAggregation string
+
+const (
+	// UtilizationAggregationMean aggregates samples to their mean
+	UtilizationAggregationMean UtilizationAggregation = "Mean"
+
+	// UtilizationAggregationP50 aggregates samples to their median
+	UtilizationAggregationP50 UtilizationAggregation = "P50"
+
+	// UtilizationAggregationP95 aggregates samples to their 95th percentile
+	UtilizationAggregationP95 UtilizationAggregation = "P95"
+)
+
 // InstanceTypeInfo contains information about a VPSie offering/instance type
 type InstanceTypeInfo struct {
 	// OfferingID is the VPSie offering ID
@@ -302,6 +703,25 @@ type NodeGroupStatus struct {
 	// DesiredNodes is the number of nodes the autoscaler wants to maintain
 	DesiredNodes int32 `json:"desiredNodes"`
 
+	// HeadroomNodes is the number of nodes DesiredNodes includes to maintain
+	// the configured headroom
+	// +optional
+	HeadroomNodes int32 `json:"headroomNodes,omitempty"`
+
+	// PredictedNodes is the node count the predictive scaler expects to be needed
+	// within its forecast horizon. DesiredNodes is kept at or above it until
+	// PredictionExpiresAt.
+	// +optional
+	PredictedNodes int32 `json:"predictedNodes,omitempty"`
+
+	// PredictionExpiresAt is when PredictedNodes stops applying unless refreshed
+	// +optional
+	PredictionExpiresAt *metav1.Time `json:"predictionExpiresAt,omitempty"`
+
+	// ActiveSchedule is the scaling schedule whose window is open, if any
+	// +optional
+	ActiveSchedule *ActiveScalingSchedule `json:"activeSchedule,omitempty"`
+
 	// ReadyNodes is the number of nodes that are ready to accept workloads
 	ReadyNodes int32 `json:"readyNodes"`
 
@@ -310,6 +730,18 @@ type NodeGroupStatus struct {
 	// +optional
 	VPSieGroupID int `json:"vpsieGroupID,omitempty"`
 
+	// OfferingGroups maps offerings other than KubeSizeID to the numeric IDs
+	// of the VPSie node groups created for them, into which consolidation
+	// provisions its replacement nodes
+	// +optional
+	OfferingGroups map[string]int `json:"offeringGroups,omitempty"`
+
+	// DatacenterGroups maps multi-region datacenters other than DatacenterID
+	// to the numeric IDs of the VPSie node groups provisioning nodes there.
+	// Datacenters without a group are not placed in.
+	// +optional
+	DatacenterGroups map[string]int `json:"datacenterGroups,omitempty"`
+
 	// Nodes is a list of nodes in this group with their details
 	// +optional
 	Nodes []NodeInfo `json:"nodes,omitempty"`
@@ -330,6 +762,15 @@ type NodeGroupStatus struct {
 	// +optional
 	LastScaleDownTime *metav1.Time `json:"lastScaleDownTime,omitempty"`
 
+	// IdleSince is when the last workload pod left the nodes of an auto-managed
+	// NodeGroup. The NodeGroup is deleted once it stays idle for its idle TTL.
+	// +optional
+	IdleSince *metav1.Time `json:"idleSince,omitempty"`
+
+	// Consolidation is the consolidation in progress, if any
+	// +optional
+	Consolidation *ConsolidationStatus `json:"consolidation,omitempty"`
+
 	// ObservedGeneration is the generation observed by the controller
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
@@ -415,6 +856,7 @@ type NodeGroupCondition struct {
 // +kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.status.desiredNodes`,description="Desired nodes"
 // +kubebuilder:printcolumn:name="Current",type=integer,JSONPath=`.status.currentNodes`,description="Current nodes"
 // +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyNodes`,description="Ready nodes"
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.status.activeSchedule.name`,description="Active scaling schedule",priority=1
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // NodeGroup is the Schema for the nodegroups API
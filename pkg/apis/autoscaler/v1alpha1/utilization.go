@@ -0,0 +1,77 @@
+package v1alpha1
+
+import (
+	"math"
+	"sort"
+)
+
+// Utilization helpers. These are defined here in the API types package so the
+// scaler, cost optimizer and rebalancer measure utilization the same way.
+
+// EffectiveUtilizationMode returns the utilization mode of a scale-down
+// policy, defaulting to Usage
+func EffectiveUtilizationMode(policy *ScaleDownPolicy) UtilizationMode {
+	switch policy.UtilizationMode {
+	case UtilizationModeRequests, UtilizationModeMax:
+		return policy.UtilizationMode
+	default:
+		return UtilizationModeUsage
+	}
+}
+
+// EffectiveUtilizationAggregation returns the utilization aggregation of a
+// scale-down policy, defaulting to Mean
+func EffectiveUtilizationAggregation(policy *ScaleDownPolicy) UtilizationAggregation {
+	switch policy.UtilizationAggregation {
+	case UtilizationAggregationP50, UtilizationAggregationP95:
+		return policy.UtilizationAggregation
+	default:
+		return UtilizationAggregationMean
+	}
+}
+
+// ResolveUtilization returns the utilization percentage of a resource under
+// the given mode from its usage and requested percentages
+func ResolveUtilization(mode UtilizationMode, usage, requests float64) float64 {
+	switch mode {
+	case UtilizationModeRequests:
+		return requests
+	case UtilizationModeMax:
+		return math.Max(usage, requests)
+	default:
+		return usage
+	}
+}
+
+// AggregateUtilization aggregates utilization percentages with the given
+// aggregation. Percentiles use the nearest-rank method. It returns zero for
+// no values.
+func AggregateUtilization(aggregation UtilizationAggregation, values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	var percentile float64
+	switch aggregation {
+	case UtilizationAggregationP50:
+		percentile = 50
+	case UtilizationAggregationP95:
+		percentile = 95
+	default:
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,39 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEffectiveUtilizationPolicy(t *testing.T) {
+	policy := &ScaleDownPolicy{}
+	assert.Equal(t, UtilizationModeUsage, EffectiveUtilizationMode(policy))
+	assert.Equal(t, UtilizationAggregationMean, EffectiveUtilizationAggregation(policy))
+
+	policy.UtilizationMode = UtilizationModeMax
+	policy.UtilizationAggregation = UtilizationAggregationP95
+	assert.Equal(t, UtilizationModeMax, EffectiveUtilizationMode(policy))
+	assert.Equal(t, UtilizationAggregationP95, EffectiveUtilizationAggregation(policy))
+}
+
+func TestResolveUtilization(t *testing.T) {
+	assert.Equal(t, 20.0, ResolveUtilization(UtilizationModeUsage, 20, 60))
+	assert.Equal(t, 20.0, ResolveUtilization("", 20, 60))
+	assert.Equal(t, 60.0, ResolveUtilization(UtilizationModeRequests, 20, 60))
+	assert.Equal(t, 60.0, ResolveUtilization(UtilizationModeMax, 20, 60))
+	assert.Equal(t, 70.0, ResolveUtilization(UtilizationModeMax, 70, 60))
+}
+
+func TestAggregateUtilization(t *testing.T) {
+	values := []float64{90, 10, 30, 20, 40, 50, 60, 70, 80, 100}
+
+	assert.Equal(t, 55.0, AggregateUtilization(UtilizationAggregationMean, values))
+	assert.Equal(t, 50.0, AggregateUtilization(UtilizationAggregationP50, values))
+	assert.Equal(t, 100.0, AggregateUtilization(UtilizationAggregationP95, values))
+	assert.Equal(t, 40.0, AggregateUtilization(UtilizationAggregationP95, []float64{40}))
+	assert.Equal(t, 0.0, AggregateUtilization(UtilizationAggregationP50, nil))
+
+	// The values are not reordered
+	assert.Equal(t, 90.0, values[0])
+}
@@ -113,6 +113,14 @@ type VPSieNodeStatus struct {
 	// +optional
 	DeletedAt *metav1.Time `json:"deletedAt,omitempty"`
 
+	// DrainProgress is the percentage of the node's pods evicted by the
+	// current or last drain. Pods count once their eviction was accepted, so
+	// it reaches 100 while the last pods may still be terminating.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	DrainProgress int32 `json:"drainProgress,omitempty"`
+
 	// Conditions represent the latest available observations of the node's state
 	// +optional
 	Conditions []VPSieNodeCondition `json:"conditions,omitempty"`
@@ -6,9 +6,27 @@ package v1alpha1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActiveScalingSchedule) DeepCopyInto(out *ActiveScalingSchedule) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.EndTime.DeepCopyInto(&out.EndTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActiveScalingSchedule.
+func (in *ActiveScalingSchedule) DeepCopy() *ActiveScalingSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(ActiveScalingSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AutoscalerConfig) DeepCopyInto(out *AutoscalerConfig) {
 	*out = *in
@@ -72,7 +90,7 @@ func (in *AutoscalerConfigList) DeepCopyObject() runtime.Object {
 func (in *AutoscalerConfigSpec) DeepCopyInto(out *AutoscalerConfigSpec) {
 	*out = *in
 	in.NodeGroupDefaults.DeepCopyInto(&out.NodeGroupDefaults)
-	out.GlobalSettings = in.GlobalSettings
+	in.GlobalSettings.DeepCopyInto(&out.GlobalSettings)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalerConfigSpec.
@@ -104,6 +122,47 @@ func (in *AutoscalerConfigStatus) DeepCopy() *AutoscalerConfigStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConsolidationConfig) DeepCopyInto(out *ConsolidationConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsolidationConfig.
+func (in *ConsolidationConfig) DeepCopy() *ConsolidationConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ConsolidationConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConsolidationStatus) DeepCopyInto(out *ConsolidationStatus) {
+	*out = *in
+	if in.ReplacementNodes != nil {
+		in, out := &in.ReplacementNodes, &out.ReplacementNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RetiringNodes != nil {
+		in, out := &in.RetiringNodes, &out.RetiringNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsolidationStatus.
+func (in *ConsolidationStatus) DeepCopy() *ConsolidationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ConsolidationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CostOptimizationConfig) DeepCopyInto(out *CostOptimizationConfig) {
 	*out = *in
@@ -119,9 +178,98 @@ func (in *CostOptimizationConfig) DeepCopy() *CostOptimizationConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DrainConfig) DeepCopyInto(out *DrainConfig) {
+	*out = *in
+	if in.SkipNamespaces != nil {
+		in, out := &in.SkipNamespaces, &out.SkipNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeleteEmptyDirData != nil {
+		in, out := &in.DeleteEmptyDirData, &out.DeleteEmptyDirData
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ForceDeleteAfter != nil {
+		in, out := &in.ForceDeleteAfter, &out.ForceDeleteAfter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxConcurrentEvictions != nil {
+		in, out := &in.MaxConcurrentEvictions, &out.MaxConcurrentEvictions
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PreDrainHooks != nil {
+		in, out := &in.PreDrainHooks, &out.PreDrainHooks
+		*out = make([]DrainHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PostDrainHooks != nil {
+		in, out := &in.PostDrainHooks, &out.PostDrainHooks
+		*out = make([]DrainHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DrainConfig.
+func (in *DrainConfig) DeepCopy() *DrainConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(DrainConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DrainHook) DeepCopyInto(out *DrainHook) {
+	*out = *in
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(HTTPDrainHook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Job != nil {
+		in, out := &in.Job, &out.Job
+		*out = new(JobDrainHook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DrainHook.
+func (in *DrainHook) DeepCopy() *DrainHook {
+	if in == nil {
+		return nil
+	}
+	out := new(DrainHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GlobalAutoscalerSettings) DeepCopyInto(out *GlobalAutoscalerSettings) {
 	*out = *in
+	if in.ExpendablePodsPriorityCutoff != nil {
+		in, out := &in.ExpendablePodsPriorityCutoff, &out.ExpendablePodsPriorityCutoff
+		*out = new(int32)
+		**out = **in
+	}
+	if in.EnablePreemptionCheck != nil {
+		in, out := &in.EnablePreemptionCheck, &out.EnablePreemptionCheck
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalAutoscalerSettings.
@@ -134,6 +282,64 @@ func (in *GlobalAutoscalerSettings) DeepCopy() *GlobalAutoscalerSettings {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPDrainHook) DeepCopyInto(out *HTTPDrainHook) {
+	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPDrainHook.
+func (in *HTTPDrainHook) DeepCopy() *HTTPDrainHook {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPDrainHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HeadroomConfig) DeepCopyInto(out *HeadroomConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HeadroomConfig.
+func (in *HeadroomConfig) DeepCopy() *HeadroomConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(HeadroomConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JobDrainHook) DeepCopyInto(out *JobDrainHook) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+	if in.BackoffLimit != nil {
+		in, out := &in.BackoffLimit, &out.BackoffLimit
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JobDrainHook.
+func (in *JobDrainHook) DeepCopy() *JobDrainHook {
+	if in == nil {
+		return nil
+	}
+	out := new(JobDrainHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InstanceTypeInfo) DeepCopyInto(out *InstanceTypeInfo) {
 	*out = *in
@@ -223,6 +429,16 @@ func (in *NodeGroupCondition) DeepCopy() *NodeGroupCondition {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeGroupDefaults) DeepCopyInto(out *NodeGroupDefaults) {
 	*out = *in
+	if in.CandidateDatacenterIDs != nil {
+		in, out := &in.CandidateDatacenterIDs, &out.CandidateDatacenterIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IdleTTL != nil {
+		in, out := &in.IdleTTL, &out.IdleTTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	if in.OfferingIDs != nil {
 		in, out := &in.OfferingIDs, &out.OfferingIDs
 		*out = make([]string, len(*in))
@@ -330,6 +546,13 @@ func (in *NodeGroupSpec) DeepCopyInto(out *NodeGroupSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ExtendedResources != nil {
+		in, out := &in.ExtendedResources, &out.ExtendedResources
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
 	out.ScaleUpPolicy = in.ScaleUpPolicy
 	out.ScaleDownPolicy = in.ScaleDownPolicy
 	if in.SSHKeyIDs != nil {
@@ -357,6 +580,28 @@ func (in *NodeGroupSpec) DeepCopyInto(out *NodeGroupSpec) {
 		*out = new(CostOptimizationConfig)
 		**out = **in
 	}
+	if in.Headroom != nil {
+		in, out := &in.Headroom, &out.Headroom
+		*out = new(HeadroomConfig)
+		**out = **in
+	}
+	if in.Schedules != nil {
+		in, out := &in.Schedules, &out.Schedules
+		*out = make([]ScalingSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Drain != nil {
+		in, out := &in.Drain, &out.Drain
+		*out = new(DrainConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Consolidation != nil {
+		in, out := &in.Consolidation, &out.Consolidation
+		*out = new(ConsolidationConfig)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeGroupSpec.
@@ -372,6 +617,20 @@ func (in *NodeGroupSpec) DeepCopy() *NodeGroupSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeGroupStatus) DeepCopyInto(out *NodeGroupStatus) {
 	*out = *in
+	if in.OfferingGroups != nil {
+		in, out := &in.OfferingGroups, &out.OfferingGroups
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DatacenterGroups != nil {
+		in, out := &in.DatacenterGroups, &out.DatacenterGroups
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Nodes != nil {
 		in, out := &in.Nodes, &out.Nodes
 		*out = make([]NodeInfo, len(*in))
@@ -386,6 +645,15 @@ func (in *NodeGroupStatus) DeepCopyInto(out *NodeGroupStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PredictionExpiresAt != nil {
+		in, out := &in.PredictionExpiresAt, &out.PredictionExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ActiveSchedule != nil {
+		in, out := &in.ActiveSchedule, &out.ActiveSchedule
+		*out = new(ActiveScalingSchedule)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.LastScaleTime != nil {
 		in, out := &in.LastScaleTime, &out.LastScaleTime
 		*out = (*in).DeepCopy()
@@ -398,6 +666,15 @@ func (in *NodeGroupStatus) DeepCopyInto(out *NodeGroupStatus) {
 		in, out := &in.LastScaleDownTime, &out.LastScaleDownTime
 		*out = (*in).DeepCopy()
 	}
+	if in.IdleSince != nil {
+		in, out := &in.IdleSince, &out.IdleSince
+		*out = (*in).DeepCopy()
+	}
+	if in.Consolidation != nil {
+		in, out := &in.Consolidation, &out.Consolidation
+		*out = new(ConsolidationStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeGroupStatus.
@@ -478,6 +755,37 @@ func (in *ScaleUpPolicy) DeepCopy() *ScaleUpPolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScalingSchedule) DeepCopyInto(out *ScalingSchedule) {
+	*out = *in
+	out.Duration = in.Duration
+	if in.MinNodes != nil {
+		in, out := &in.MinNodes, &out.MinNodes
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxNodes != nil {
+		in, out := &in.MaxNodes, &out.MaxNodes
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DesiredNodes != nil {
+		in, out := &in.DesiredNodes, &out.DesiredNodes
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScalingSchedule.
+func (in *ScalingSchedule) DeepCopy() *ScalingSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(ScalingSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SpotInstanceConfig) DeepCopyInto(out *SpotInstanceConfig) {
 	*out = *in
@@ -17,6 +17,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
@@ -29,7 +30,9 @@ import (
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/controller/nodegroup"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/controller/vpsienode"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/drain"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/events"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/predictive"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/scaler"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/tracing"
 	vpsieclient "github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/client"
@@ -54,6 +57,12 @@ type ControllerManager struct {
 	webhookServer     *webhook.Server
 	tracer            *tracing.Tracer
 	clusterConfig     *DiscoveredClusterConfig // Auto-discovered cluster configuration
+	carbonIntensity   cost.CarbonIntensitySource
+	costCalculator    *cost.Calculator
+	predictor         *predictive.Predictor
+	savingsLedger     *cost.SavingsLedger
+	savingsTracker    *savingsTracker
+	costOptimizer     *cost.Optimizer
 }
 
 // DiscoveredClusterConfig holds cluster configuration discovered from VPSie API
@@ -508,15 +517,42 @@ func NewManager(config *rest.Config, opts *Options) (*ControllerManager, error)
 		return nil, fmt.Errorf("failed to create VPSie client: %w", err)
 	}
 
+	// Evictions of all drains (scale-down, termination, rebalancing) share one rate limit
+	drain.SetEvictionRateLimit(opts.DrainEvictionQPS, opts.DrainEvictionBurst)
+
 	// Create ScaleDownManager
 	scaleDownConfig := scaler.DefaultConfig()
 	scaleDownManager := scaler.NewScaleDownManager(k8sClient, metricsClient, logger, scaleDownConfig)
 
+	// Scale-down simulations expose cluster details without authentication, so
+	// they are served on their own localhost-only listener
+	if opts.DebugAddr != "" && opts.DebugAddr != "0" {
+		simulationHandler := &ScaleDownSimulationHandler{}
+		simulationHandler.SetDependencies(mgr.GetClient(), scaleDownManager)
+		if err := mgr.Add(newDebugServer(opts.DebugAddr, simulationHandler, logger)); err != nil {
+			return nil, fmt.Errorf("failed to add debug server: %w", err)
+		}
+	}
+
 	// Create cost calculator for cost-aware NodeGroup selection
 	costCalculator := cost.NewCalculator(vpsieClient)
 
+	configureCostCalculator(ctx, costCalculator, k8sClient, opts, logger)
+
+	carbonIntensity := loadCarbonIntensity(opts, logger)
+	if carbonIntensity != nil {
+		costCalculator.SetCarbonIntensity(carbonIntensity)
+	}
+
 	// Create ResourceAnalyzer for scale-up decisions with cost-aware selection
 	resourceAnalyzer := events.NewResourceAnalyzer(logger, costCalculator)
+	if carbonIntensity != nil {
+		objective, err := cost.ParseCarbonObjective(opts.CarbonObjective)
+		if err != nil {
+			return nil, fmt.Errorf("invalid carbon objective: %w", err)
+		}
+		resourceAnalyzer.SetCarbonObjective(objective)
+	}
 
 	// Create health checker with K8s client for enhanced checks
 	healthChecker := NewHealthChecker(vpsieClient)
@@ -550,6 +586,40 @@ func NewManager(config *rest.Config, opts *Options) (*ControllerManager, error)
 		scheme:           scheme,
 		tracer:           tracer,
 		clusterConfig:    clusterConfig,
+		costCalculator:   costCalculator,
+	}
+	if carbonIntensity != nil {
+		cm.carbonIntensity = carbonIntensity
+	}
+
+	// NodeGroup cost snapshots shared by the savings ledger and the optimizer
+	costStorage := cost.NewMemoryCostStorage()
+	costAnalyzer := cost.NewAnalyzer(costCalculator, costStorage)
+
+	// Verify the savings of scale-downs against the measured NodeGroup cost
+	if opts.SavingsLedgerWindow > 0 {
+		cm.savingsLedger = cost.NewSavingsLedger(costAnalyzer, opts.SavingsLedgerWindow)
+		if opts.SavingsLedgerConfigMapName != "" {
+			cm.savingsLedger.SetStore(cost.NewConfigMapLedgerStore(k8sClient,
+				opts.SavingsLedgerConfigMapNamespace, opts.SavingsLedgerConfigMapName))
+		}
+		cm.savingsTracker = &savingsTracker{
+			client:      mgr.GetClient(),
+			analyzer:    costAnalyzer,
+			storage:     costStorage,
+			ledger:      cm.savingsLedger,
+			metrics:     getCostMetrics(),
+			utilization: scaleDownManager.GetNodeUtilization,
+			interval:    costSnapshotInterval,
+			logger:      logger.Named("savings-ledger"),
+		}
+	}
+
+	// Recommend optimizations with confidence weighted by the savings the
+	// ledger measured for optimizations applied in the past
+	cm.costOptimizer = cost.NewOptimizer(costCalculator, costAnalyzer, vpsieClient)
+	if cm.savingsLedger != nil {
+		cm.costOptimizer.SetSavingsLedger(cm.savingsLedger)
 	}
 
 	// Create DynamicNodeGroupCreator for automatic NodeGroup provisioning
@@ -594,6 +664,9 @@ func NewManager(config *rest.Config, opts *Options) (*ControllerManager, error)
 			KubernetesVersion:   effectiveK8sVersion,
 			KubeSizeID:          effectiveKubeSizeID,
 			Project:             effectiveProjectID,
+
+			CandidateDatacenterIDs: opts.CandidateDatacenterIDs,
+			CarbonObjective:        opts.CarbonObjective,
 		}
 		configSource := "manual"
 		if clusterConfig != nil && needsDiscovery {
@@ -623,6 +696,9 @@ func NewManager(config *rest.Config, opts *Options) (*ControllerManager, error)
 		logger,
 		nodeGroupTemplate,
 	)
+	if carbonIntensity != nil {
+		dynamicCreator.SetCarbonIntensity(carbonIntensity)
+	}
 
 	// Create EventWatcher and ScaleUpController for pending pod detection
 	// ScaleUpController is created first with nil watcher, then wired up after EventWatcher is created
@@ -641,12 +717,36 @@ func NewManager(config *rest.Config, opts *Options) (*ControllerManager, error)
 		scaleUpController.HandleScaleUp,
 	)
 
+	detectionMode, err := events.ParseDetectionMode(opts.PendingPodDetection)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pending pod detection: %w", err)
+	}
+	eventWatcher.SetDetectionMode(detectionMode)
+
 	// Wire up the ScaleUpController with the EventWatcher
 	scaleUpController.SetWatcher(eventWatcher)
 
 	cm.eventWatcher = eventWatcher
 	cm.scaleUpController = scaleUpController
 
+	// Create the predictive scaler, learning demand from NodeGroups and pending pods
+	predictiveMode, err := predictive.ParseMode(opts.PredictiveScaling)
+	if err != nil {
+		return nil, fmt.Errorf("invalid predictive scaling: %w", err)
+	}
+	if predictiveMode != predictive.ModeOff {
+		cm.predictor = predictive.NewPredictor(mgr.GetClient(), predictive.Config{
+			Mode:            predictiveMode,
+			Horizon:         opts.PredictiveScalingHorizon,
+			LookbackSeasons: opts.PredictiveScalingLookbackDays,
+		}, logger)
+		if opts.PredictiveScalingConfigMapName != "" {
+			cm.predictor.SetStore(predictive.NewConfigMapHistoryStore(k8sClient,
+				opts.PredictiveScalingConfigMapNamespace, opts.PredictiveScalingConfigMapName))
+		}
+		scaleUpController.SetDemandRecorder(cm.predictor)
+	}
+
 	// Add health checks to manager
 	if err := cm.setupHealthChecks(); err != nil {
 		return nil, fmt.Errorf("failed to setup health checks: %w", err)
@@ -694,6 +794,11 @@ func (cm *ControllerManager) setupHealthChecks() error {
 
 // setupControllers sets up all controllers with the manager
 func (cm *ControllerManager) setupControllers() error {
+	cm.scaleDownManager.SetEventRecorder(cm.mgr.GetEventRecorderFor("scale-down"))
+	if cm.costCalculator != nil {
+		cm.scaleDownManager.SetOfferingPricer(cm.costCalculator)
+	}
+
 	// Setup NodeGroup controller
 	nodeGroupReconciler := nodegroup.NewNodeGroupReconciler(
 		cm.mgr.GetClient(),
@@ -702,6 +807,15 @@ func (cm *ControllerManager) setupControllers() error {
 		cm.logger,
 		cm.scaleDownManager,
 	)
+	if cm.carbonIntensity != nil {
+		nodeGroupReconciler.CarbonIntensity = cm.carbonIntensity
+	}
+	if cm.costCalculator != nil {
+		nodeGroupReconciler.Pricer = cm.costCalculator
+	}
+	if cm.savingsLedger != nil {
+		nodeGroupReconciler.Ledger = cm.savingsLedger
+	}
 
 	if err := nodeGroupReconciler.SetupWithManager(cm.mgr); err != nil {
 		return fmt.Errorf("failed to setup NodeGroup controller: %w", err)
@@ -715,6 +829,7 @@ func (cm *ControllerManager) setupControllers() error {
 		cm.mgr.GetClient(),
 		cm.scheme,
 		cm.vpsieClient,
+		drain.NewNodeDrainer(cm.k8sClient, cm.logger.Named("drain")),
 		cm.logger,
 		cm.options.SSHKeyIDs,
 		cm.options.FailedVPSieNodeTTL,
@@ -862,6 +977,44 @@ func (cm *ControllerManager) Start(ctx context.Context) error {
 	// Start node utilization metrics collection
 	cm.startMetricsCollection(ctx)
 
+	// Start predictive scaling once elected, so only the leader acts on forecasts
+	if cm.predictor != nil {
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					cm.logger.Error("panic recovered in predictive scaler",
+						zap.Any("panic", r),
+						zap.Stack("stack"))
+				}
+			}()
+			select {
+			case <-ctx.Done():
+				return
+			case <-cm.mgr.Elected():
+			}
+			cm.predictor.Run(ctx)
+		}()
+	}
+
+	// Track the savings ledger once elected, so only the leader writes it
+	if cm.savingsTracker != nil {
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					cm.logger.Error("panic recovered in savings ledger",
+						zap.Any("panic", r),
+						zap.Stack("stack"))
+				}
+			}()
+			select {
+			case <-ctx.Done():
+				return
+			case <-cm.mgr.Elected():
+			}
+			cm.savingsTracker.Run(ctx)
+		}()
+	}
+
 	// Start webhook server if enabled
 	if cm.webhookServer != nil {
 		certFile := fmt.Sprintf("%s/%s", cm.options.WebhookCertDir, cm.options.WebhookCertFile)
@@ -956,6 +1109,11 @@ func (cm *ControllerManager) GetHealthChecker() *HealthChecker {
 	return cm.healthChecker
 }
 
+// GetCostOptimizer returns the cost optimizer
+func (cm *ControllerManager) GetCostOptimizer() *cost.Optimizer {
+	return cm.costOptimizer
+}
+
 // GetScaleDownManager returns the scale-down manager
 func (cm *ControllerManager) GetScaleDownManager() *scaler.ScaleDownManager {
 	return cm.scaleDownManager
@@ -1015,6 +1173,35 @@ func (cm *ControllerManager) loadAndActivateAutoscalerConfig(ctx context.Context
 		cm.logger.Info("AutoscalerConfig status updated to active")
 	}
 
+	// Apply scale-up settings: the expander choosing between matching NodeGroups
+	// and how many nodes a single scale-up may add
+	if cm.scaleUpController != nil {
+		settings := config.Spec.GlobalSettings
+		if err := cm.scaleUpController.SetExpanderStrategy(
+			events.ExpanderStrategy(settings.Expander),
+			types.NamespacedName{
+				Name:      settings.PriorityConfigMapName,
+				Namespace: settings.PriorityConfigMapNamespace,
+			},
+		); err != nil {
+			cm.logger.Warn("Invalid expander in AutoscalerConfig, keeping current strategy",
+				zap.String("expander", settings.Expander),
+				zap.Error(err),
+			)
+		} else {
+			cm.logger.Info("Configured scale-up expander",
+				zap.String("expander", string(cm.scaleUpController.Expander().Strategy())),
+			)
+		}
+		cm.scaleUpController.SetMaxConcurrentScaleUps(settings.MaxConcurrentScaleUps)
+		if settings.ExpendablePodsPriorityCutoff != nil {
+			cm.scaleUpController.SetExpendablePodsPriorityCutoff(*settings.ExpendablePodsPriorityCutoff)
+		}
+		if settings.EnablePreemptionCheck != nil {
+			cm.scaleUpController.SetPreemptionCheck(*settings.EnablePreemptionCheck)
+		}
+	}
+
 	// Apply configuration to the DynamicNodeGroupCreator if we have one
 	if cm.scaleUpController != nil {
 		cm.logger.Info("Configuration from AutoscalerConfig will be used for dynamic NodeGroup creation")
@@ -1288,3 +1475,99 @@ func newLogger(opts *Options) (*zap.Logger, error) {
 
 	return logger, nil
 }
+
+// loadCarbonIntensity loads the carbon intensity table. Returns nil when no table
+// is configured or it cannot be loaded, which disables carbon-aware placement.
+func loadCarbonIntensity(opts *Options, logger *zap.Logger) *cost.CarbonIntensityTable {
+	if opts.CarbonIntensityFile == "" {
+		return nil
+	}
+
+	table, err := cost.LoadCarbonIntensityFile(opts.CarbonIntensityFile)
+	if err != nil {
+		logger.Warn("Failed to load carbon intensity table, carbon-aware placement disabled",
+			zap.String("file", opts.CarbonIntensityFile),
+			zap.Error(err))
+		return nil
+	}
+
+	logger.Info("Loaded carbon intensity table",
+		zap.String("file", opts.CarbonIntensityFile),
+		zap.String("version", table.Version),
+		zap.Int("datacenters", len(table.Datacenters)),
+		zap.String("objective", opts.CarbonObjective),
+	)
+	return table
+}
+
+// configureCostCalculator applies the negotiated pricing plan and the offline price
+// catalog to the cost calculator. Load failures are logged and leave the calculator
+// on live list prices.
+func configureCostCalculator(ctx context.Context, calculator *cost.Calculator, k8sClient kubernetes.Interface, opts *Options, logger *zap.Logger) {
+	if opts.PricingConfigMapName != "" {
+		pricingPlan, err := cost.LoadPricingPlanFromConfigMap(ctx, k8sClient, opts.PricingConfigMapNamespace, opts.PricingConfigMapName)
+		if err != nil {
+			logger.Warn("Failed to load pricing plan, using list prices",
+				zap.String("configMap", opts.PricingConfigMapNamespace+"/"+opts.PricingConfigMapName),
+				zap.Error(err))
+		} else {
+			calculator.SetPricingPlan(pricingPlan)
+			logger.Info("Loaded pricing plan",
+				zap.String("configMap", opts.PricingConfigMapNamespace+"/"+opts.PricingConfigMapName),
+				zap.Int("contractTerms", len(pricingPlan.ContractTerms)),
+				zap.Int("commitments", len(pricingPlan.Commitments)),
+			)
+		}
+	}
+
+	var catalog *cost.PriceCatalog
+	var source string
+	var err error
+	switch {
+	case opts.PriceCatalogFile != "":
+		source = opts.PriceCatalogFile
+		catalog, err = cost.LoadPriceCatalogFile(opts.PriceCatalogFile)
+	case opts.PriceCatalogConfigMapName != "":
+		source = opts.PriceCatalogConfigMapNamespace + "/" + opts.PriceCatalogConfigMapName
+		catalog, err = cost.LoadPriceCatalogFromConfigMap(ctx, k8sClient, opts.PriceCatalogConfigMapNamespace, opts.PriceCatalogConfigMapName)
+	default:
+		return
+	}
+	if err != nil {
+		logger.Warn("Failed to load price catalog, pricing from the live API only",
+			zap.String("source", source),
+			zap.Error(err))
+		return
+	}
+
+	mode := cost.CatalogMode(opts.PriceCatalogMode)
+	if mode == "" {
+		mode = cost.CatalogModeFallback
+	}
+	calculator.SetPriceCatalog(catalog, mode)
+	logger.Info("Loaded price catalog",
+		zap.String("source", source),
+		zap.String("version", catalog.Version),
+		zap.String("mode", string(mode)),
+		zap.Int("offerings", len(catalog.Offerings)),
+	)
+
+	drifts, err := calculator.DetectPriceDrift(ctx)
+	if err != nil {
+		logger.Warn("Failed to check price catalog drift", zap.Error(err))
+		return
+	}
+	for _, drift := range drifts {
+		if drift.MissingLive {
+			logger.Warn("Price catalog offering not found in live offerings",
+				zap.String("offeringID", drift.OfferingID))
+			continue
+		}
+		logger.Warn("Price catalog price differs from live price",
+			zap.String("offeringID", drift.OfferingID),
+			zap.Float64("catalogMonthly", drift.CatalogMonthly),
+			zap.Float64("liveMonthly", drift.LiveMonthly),
+			zap.Float64("driftPercent", drift.DriftPercent),
+		)
+	}
+}
@@ -167,8 +167,8 @@ func UpdateConditionsForReconcile(ng *v1alpha1.NodeGroup) {
 	SetErrorCondition(ng, false, ReasonReconciling, "Reconciliation in progress")
 
 	// Update capacity conditions
-	atMin := ng.Status.CurrentNodes <= ng.Spec.MinNodes
-	atMax := ng.Status.CurrentNodes >= ng.Spec.MaxNodes
+	atMin := ng.Status.CurrentNodes <= v1alpha1.EffectiveMinNodes(ng)
+	atMax := ng.Status.CurrentNodes >= v1alpha1.EffectiveMaxNodes(ng)
 
 	SetAtMinCapacityCondition(ng, atMin, "")
 	SetAtMaxCapacityCondition(ng, atMax, "")
@@ -199,8 +199,8 @@ func UpdateConditionsAfterScale(ng *v1alpha1.NodeGroup, scaleDirection string) {
 	}
 
 	// Update capacity conditions
-	atMin := ng.Status.CurrentNodes <= ng.Spec.MinNodes
-	atMax := ng.Status.CurrentNodes >= ng.Spec.MaxNodes
+	atMin := ng.Status.CurrentNodes <= v1alpha1.EffectiveMinNodes(ng)
+	atMax := ng.Status.CurrentNodes >= v1alpha1.EffectiveMaxNodes(ng)
 
 	SetAtMinCapacityCondition(ng, atMin, "")
 	SetAtMaxCapacityCondition(ng, atMax, "")
@@ -0,0 +1,801 @@
+package nodegroup
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/drain"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/events"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/scaler"
+	vpsieclient "github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/client"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
+)
+
+const (
+	// DefaultConsolidationMinSavingsPercent is the minimum cost reduction
+	// required to consolidate when none is configured
+	DefaultConsolidationMinSavingsPercent = 10
+
+	// DefaultConsolidationReplacementTimeout is how long replacement nodes may
+	// take to become Ready when no timeout is configured
+	DefaultConsolidationReplacementTimeout = 15 * time.Minute
+)
+
+// consolidationNode is an underutilized node that may be replaced
+type consolidationNode struct {
+	NodeName  string
+	VPSieNode string
+	Offering  *cost.OfferingCost
+
+	AllocatableCPU    int64 // millicores
+	AllocatableMemory int64 // bytes
+
+	// Pods are the workload pods moved to the replacement nodes
+	Pods []*corev1.Pod
+
+	// OverheadCPU and OverheadMemory are the requests of the DaemonSet and
+	// mirror pods, which run on every node
+	OverheadCPU    int64
+	OverheadMemory int64
+
+	// Labels and Taints are the node's, which replacements share apart from
+	// the offering and hostname
+	Labels map[string]string
+	Taints []corev1.Taint
+}
+
+// consolidationOffering is an offering replacements may use and the capacity
+// a replacement node of it offers to pods
+type consolidationOffering struct {
+	Cost     *cost.OfferingCost
+	Template events.NodeTemplate
+}
+
+// consolidationPlan replaces the Retiring nodes with Replacements nodes of the Target offering
+type consolidationPlan struct {
+	Retiring     []consolidationNode
+	Target       *cost.OfferingCost
+	Replacements int
+
+	CurrentMonthlyCost float64
+	NewMonthlyCost     float64
+}
+
+// Savings returns the expected reduction of the monthly cost
+func (p *consolidationPlan) Savings() float64 {
+	return p.CurrentMonthlyCost - p.NewMonthlyCost
+}
+
+// consolidationLimits bounds the plans considered for a NodeGroup
+type consolidationLimits struct {
+	// MaxAdded is how many replacement nodes can be created above the current count
+	MaxAdded int
+	// MaxRemoved is how many nodes the NodeGroup can shrink by
+	MaxRemoved int
+	// MinSavingsPercent is the minimum reduction of the retiring nodes' cost
+	MinSavingsPercent int32
+	// FreeCPU and FreeMemory are the unrequested resources of the NodeGroup's schedulable nodes
+	FreeCPU    int64
+	FreeMemory int64
+}
+
+// planConsolidation finds the cheapest replacement of a prefix of nodes,
+// which are ordered by how little they are used, with fewer nodes of a larger
+// offering. The pods of the retiring nodes are binpacked onto nodes of the
+// offering's template, which accounts for init containers, extended resources,
+// the pod limit and hostname anti-affinity. Prefixes whose pods fit in the
+// free capacity of the rest of the NodeGroup are skipped: removing the nodes
+// is cheaper than replacing them. It returns nil if no replacement saves enough.
+func planConsolidation(analyzer *events.ResourceAnalyzer, nodes []consolidationNode, offerings []consolidationOffering, limits consolidationLimits) *consolidationPlan {
+	var best *consolidationPlan
+
+	for n := len(nodes); n >= 2; n-- {
+		retiring := nodes[:n]
+
+		var (
+			podCPU, podMemory   int64
+			freeCPU, freeMemory int64
+			currentCost         float64
+			pods                []*corev1.Pod
+		)
+		for _, node := range retiring {
+			cpu, memory := podsRequests(node.Pods)
+			podCPU += cpu
+			podMemory += memory
+			freeCPU += node.AllocatableCPU - cpu - node.OverheadCPU
+			freeMemory += node.AllocatableMemory - memory - node.OverheadMemory
+			currentCost += node.Offering.MonthlyCost
+			pods = append(pods, node.Pods...)
+		}
+
+		if podCPU <= limits.FreeCPU-freeCPU && podMemory <= limits.FreeMemory-freeMemory {
+			continue
+		}
+
+		for _, offering := range offerings {
+			if !isLargerOffering(offering.Cost, retiring) ||
+				!schedulableOnReplacement(retiring, offering.Cost.OfferingID) {
+				continue
+			}
+
+			packing := analyzer.EstimateNodesForPods(pods, offering.Template)
+			replacements := packing.Nodes
+			if len(packing.Unschedulable) > 0 || replacements < 1 || replacements >= n ||
+				replacements > limits.MaxAdded || n-replacements > limits.MaxRemoved {
+				continue
+			}
+
+			newCost := float64(replacements) * offering.Cost.MonthlyCost
+			if newCost >= currentCost ||
+				(currentCost-newCost)*100 < currentCost*float64(limits.MinSavingsPercent) {
+				continue
+			}
+
+			plan := &consolidationPlan{
+				Retiring:           retiring,
+				Target:             offering.Cost,
+				Replacements:       replacements,
+				CurrentMonthlyCost: currentCost,
+				NewMonthlyCost:     newCost,
+			}
+			if best == nil || plan.Savings() > best.Savings() ||
+				(plan.Savings() == best.Savings() && plan.Replacements < best.Replacements) {
+				best = plan
+			}
+		}
+	}
+
+	return best
+}
+
+// isLargerOffering returns true if the offering has at least the CPU and
+// memory of every retiring node's offering and more of one of them
+func isLargerOffering(offering *cost.OfferingCost, retiring []consolidationNode) bool {
+	for _, node := range retiring {
+		specs := node.Offering.Specs
+		if offering.Specs.CPU < specs.CPU || offering.Specs.MemoryMB < specs.MemoryMB {
+			return false
+		}
+		if offering.Specs.CPU == specs.CPU && offering.Specs.MemoryMB == specs.MemoryMB {
+			return false
+		}
+	}
+	return true
+}
+
+// schedulableOnReplacement reports whether the pods of the retiring nodes
+// tolerate the taints and match the node selection of a replacement node of
+// the offering, which has the labels of the node the pod runs on apart from
+// the offering and hostname
+func schedulableOnReplacement(retiring []consolidationNode, offeringID string) bool {
+	for _, node := range retiring {
+		replacement := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Labels: make(map[string]string, len(node.Labels)+1)},
+			Spec:       corev1.NodeSpec{Taints: node.Taints},
+		}
+		for key, value := range node.Labels {
+			replacement.Labels[key] = value
+		}
+		delete(replacement.Labels, corev1.LabelHostname)
+		replacement.Labels[v1alpha1.OfferingLabelKey] = offeringID
+
+		for _, pod := range node.Pods {
+			if !events.PodSchedulableOnNode(pod, replacement) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// podsRequests returns the summed CPU (millicores) and memory (bytes) requests
+// of the pods, taking init containers into account
+func podsRequests(pods []*corev1.Pod) (cpu, memory int64) {
+	for _, pod := range pods {
+		podCPU, podMemory := events.PodResourceRequests(pod)
+		cpu += podCPU
+		memory += podMemory
+	}
+	return cpu, memory
+}
+
+// consolidationNodes returns the underutilized candidates that can be
+// replaced, in the order they were identified. Nodes whose VPSieNode is not
+// Ready or whose pods must not be evicted are skipped.
+func (r *NodeGroupReconciler) consolidationNodes(
+	ctx context.Context,
+	vpsieNodes []v1alpha1.VPSieNode,
+	candidates []*scaler.ScaleDownCandidate,
+	logger *zap.Logger,
+) []consolidationNode {
+	byNodeName := make(map[string]*v1alpha1.VPSieNode, len(vpsieNodes))
+	for i := range vpsieNodes {
+		vn := &vpsieNodes[i]
+		for _, name := range []string{vn.Spec.NodeName, vn.Status.Hostname, vn.Status.NodeName} {
+			if name != "" {
+				byNodeName[name] = vn
+			}
+		}
+	}
+
+	offerings := make(map[string]*cost.OfferingCost)
+	var nodes []consolidationNode
+	for _, candidate := range candidates {
+		vn, ok := byNodeName[candidate.Node.Name]
+		if !ok || vn.Status.Phase != v1alpha1.VPSieNodePhaseReady || !vn.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		offering, ok := offerings[vn.Spec.InstanceType]
+		if !ok {
+			var err error
+			offering, err = r.Pricer.GetOfferingCost(ctx, vn.Spec.InstanceType)
+			if err != nil {
+				logger.Warn("Failed to get offering cost, skipping node for consolidation",
+					zap.String("node", candidate.Node.Name),
+					zap.String("offering", vn.Spec.InstanceType),
+					zap.Error(err),
+				)
+			}
+			offerings[vn.Spec.InstanceType] = offering
+		}
+		if offering == nil {
+			continue
+		}
+
+		node := consolidationNode{
+			NodeName:          candidate.Node.Name,
+			VPSieNode:         vn.Name,
+			Offering:          offering,
+			AllocatableCPU:    candidate.Node.Status.Allocatable.Cpu().MilliValue(),
+			AllocatableMemory: candidate.Node.Status.Allocatable.Memory().Value(),
+			Labels:            candidate.Node.Labels,
+			Taints:            candidate.Node.Spec.Taints,
+		}
+		blocked := false
+		var overhead []*corev1.Pod
+		for _, pod := range candidate.Pods {
+			if drain.BlockingReason(pod) != "" {
+				blocked = true
+				break
+			}
+			if drain.IsWorkloadPod(pod) {
+				node.Pods = append(node.Pods, pod)
+			} else if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+				overhead = append(overhead, pod)
+			}
+		}
+		if blocked {
+			continue
+		}
+		node.OverheadCPU, node.OverheadMemory = podsRequests(overhead)
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+// startConsolidation plans a consolidation of the NodeGroup's underutilized
+// nodes and, if one saves enough, creates the replacement VPSieNodes and
+// records it in status. It returns true if a consolidation was started.
+func (r *NodeGroupReconciler) startConsolidation(
+	ctx context.Context,
+	ng *v1alpha1.NodeGroup,
+	vpsieNodes []v1alpha1.VPSieNode,
+	floorNodes int32,
+	logger *zap.Logger,
+) (bool, error) {
+	config := ng.Spec.Consolidation
+	if config == nil || !config.Enabled || r.ScaleDownManager == nil || r.Pricer == nil {
+		return false, nil
+	}
+	if CountNodesInTransition(vpsieNodes) > 0 || scaleDownCoolingDown(ng, logger) {
+		return false, nil
+	}
+	// Replacements are provisioned in the NodeGroup's own datacenter, which
+	// would undo a multi-region distribution
+	if ng.Spec.MultiRegion != nil && ng.Spec.MultiRegion.Enabled {
+		return false, nil
+	}
+
+	candidates, err := r.ScaleDownManager.IdentifyUnderutilizedNodes(ctx, ng)
+	if err != nil {
+		logger.Error("Failed to identify underutilized nodes for consolidation", zap.Error(err))
+		return false, nil
+	}
+	if len(candidates) < 2 {
+		return false, nil
+	}
+
+	nodes := r.consolidationNodes(ctx, vpsieNodes, candidates, logger)
+	if len(nodes) < 2 {
+		return false, nil
+	}
+
+	// Replacement capacity is derived like a scale-up's node template, from
+	// the NodeGroup's nodes and the DaemonSets that would run on them
+	nodeList := &corev1.NodeList{}
+	if err := r.List(ctx, nodeList, client.MatchingLabels{v1alpha1.NodeGroupLabelKey: ng.Name}); err != nil {
+		logger.Warn("Failed to list NodeGroup nodes, skipping consolidation", zap.Error(err))
+		return false, nil
+	}
+	daemonSetList := &appsv1.DaemonSetList{}
+	if err := r.List(ctx, daemonSetList); err != nil {
+		logger.Warn("Failed to list DaemonSets, skipping consolidation", zap.Error(err))
+		return false, nil
+	}
+
+	analyzer := events.NewResourceAnalyzer(logger, nil)
+	var offerings []consolidationOffering
+	for _, id := range ng.Spec.OfferingIDs {
+		offering, err := r.Pricer.GetOfferingCost(ctx, id)
+		if err != nil {
+			logger.Warn("Failed to get offering cost", zap.String("offering", id), zap.Error(err))
+			continue
+		}
+		instanceType := v1alpha1.InstanceTypeInfo{
+			OfferingID: id,
+			CPU:        offering.Specs.CPU,
+			MemoryMB:   offering.Specs.MemoryMB,
+			DiskGB:     offering.Specs.DiskGB,
+		}
+		offerings = append(offerings, consolidationOffering{
+			Cost:     offering,
+			Template: analyzer.BuildNodeTemplate(ng, instanceType, nodeList.Items, daemonSetList.Items),
+		})
+	}
+
+	capacity, err := r.getNodeGroupCapacity(ctx, ng)
+	if err != nil {
+		logger.Warn("Failed to calculate NodeGroup capacity, skipping consolidation", zap.Error(err))
+		return false, nil
+	}
+
+	minNodes := v1alpha1.EffectiveMinNodes(ng)
+	if floorNodes > minNodes {
+		minNodes = floorNodes
+	}
+	limits := consolidationLimits{
+		MaxAdded:          int(v1alpha1.EffectiveMaxNodes(ng) - ng.Status.CurrentNodes),
+		MaxRemoved:        int(ng.Status.CurrentNodes - minNodes),
+		MinSavingsPercent: config.MinSavingsPercent,
+		FreeCPU:           capacity.AllocatableCPU - capacity.RequestedCPU,
+		FreeMemory:        capacity.AllocatableMemory - capacity.RequestedMemory,
+	}
+	if limits.MinSavingsPercent == 0 {
+		limits.MinSavingsPercent = DefaultConsolidationMinSavingsPercent
+	}
+
+	plan := planConsolidation(analyzer, nodes, offerings, limits)
+	if plan == nil {
+		logger.Debug("No consolidation saves enough",
+			zap.Int("candidates", len(nodes)),
+			zap.Int32("minSavingsPercent", limits.MinSavingsPercent),
+		)
+		return false, nil
+	}
+
+	groupID, err := r.offeringGroupID(ctx, ng, plan.Target.OfferingID, logger)
+	if err != nil {
+		logger.Warn("No VPSie node group for the target offering, skipping consolidation",
+			zap.String("targetOffering", plan.Target.OfferingID),
+			zap.Error(err),
+		)
+		return false, nil
+	}
+
+	id := fmt.Sprintf("consolidation-%d", time.Now().Unix())
+	logger.Info("Starting consolidation",
+		zap.String("consolidation", id),
+		zap.Int("retiring", len(plan.Retiring)),
+		zap.Int("replacements", plan.Replacements),
+		zap.String("targetOffering", plan.Target.OfferingID),
+		zap.Float64("currentMonthlyCost", plan.CurrentMonthlyCost),
+		zap.Float64("newMonthlyCost", plan.NewMonthlyCost),
+	)
+
+	var replacements []string
+	for i := 0; i < plan.Replacements; i++ {
+		vpsieNode := r.buildVPSieNode(ng)
+		vpsieNode.Spec.InstanceType = plan.Target.OfferingID
+		vpsieNode.Spec.VPSieGroupID = groupID
+		vpsieNode.Labels[v1alpha1.ConsolidationLabelKey] = id
+
+		err := controllerutil.SetControllerReference(ng, vpsieNode, r.Scheme)
+		if err == nil {
+			err = r.Create(ctx, vpsieNode)
+		}
+		if err != nil {
+			logger.Error("Failed to create replacement VPSieNode", zap.Error(err))
+			r.deleteVPSieNodes(ctx, ng.Namespace, replacements, logger)
+			SetErrorCondition(ng, true, ReasonNodeProvisioningFailed, fmt.Sprintf("Failed to create replacement VPSieNode: %v", err))
+			metrics.ConsolidationsTotal.WithLabelValues(ng.Name, ng.Namespace, "failed").Inc()
+			return false, err
+		}
+		replacements = append(replacements, vpsieNode.Name)
+	}
+
+	retiring := make([]string, 0, len(plan.Retiring))
+	for _, node := range plan.Retiring {
+		retiring = append(retiring, node.VPSieNode)
+		if err := r.labelConsolidationNode(ctx, ng.Namespace, node.VPSieNode, id); err != nil {
+			logger.Warn("Failed to label retiring VPSieNode",
+				zap.String("vpsienode", node.VPSieNode),
+				zap.Error(err),
+			)
+		}
+	}
+
+	ng.Status.Consolidation = &v1alpha1.ConsolidationStatus{
+		ID:                      id,
+		Phase:                   v1alpha1.ConsolidationPhaseProvisioning,
+		TargetOffering:          plan.Target.OfferingID,
+		ReplacementNodes:        replacements,
+		RetiringNodes:           retiring,
+		EstimatedMonthlySavings: fmt.Sprintf("%.2f", plan.Savings()),
+		StartedAt:               metav1.Now(),
+	}
+
+	r.Recorder.Eventf(ng, corev1.EventTypeNormal, "ConsolidationStarted",
+		"Replacing %d nodes with %d nodes of offering %s, saving an estimated %.2f per month",
+		len(retiring), len(replacements), plan.Target.OfferingID, plan.Savings())
+	metrics.ConsolidationsTotal.WithLabelValues(ng.Name, ng.Namespace, "started").Inc()
+
+	return true, nil
+}
+
+// reconcileConsolidation advances the consolidation in progress. Once all
+// replacement nodes are Ready the retiring VPSieNodes are deleted, which
+// drains and terminates their nodes. The consolidation is abandoned if a
+// replacement fails or isn't Ready within the replacement timeout.
+func (r *NodeGroupReconciler) reconcileConsolidation(
+	ctx context.Context,
+	ng *v1alpha1.NodeGroup,
+	vpsieNodes []v1alpha1.VPSieNode,
+	logger *zap.Logger,
+) (ctrl.Result, error) {
+	consolidation := ng.Status.Consolidation
+	logger = logger.With(zap.String("consolidation", consolidation.ID))
+
+	byName := make(map[string]*v1alpha1.VPSieNode, len(vpsieNodes))
+	for i := range vpsieNodes {
+		byName[vpsieNodes[i].Name] = &vpsieNodes[i]
+	}
+
+	switch consolidation.Phase {
+	case v1alpha1.ConsolidationPhaseProvisioning:
+		ready := 0
+		for _, name := range consolidation.ReplacementNodes {
+			vn, ok := byName[name]
+			if !ok || vn.Status.Phase == v1alpha1.VPSieNodePhaseFailed || !vn.DeletionTimestamp.IsZero() {
+				return r.abortConsolidation(ctx, ng, fmt.Sprintf("replacement node %s failed", name), logger)
+			}
+			if vn.Status.Phase == v1alpha1.VPSieNodePhaseReady {
+				ready++
+			}
+		}
+
+		if ready < len(consolidation.ReplacementNodes) {
+			timeout := DefaultConsolidationReplacementTimeout
+			if ng.Spec.Consolidation != nil && ng.Spec.Consolidation.ReplacementTimeoutSeconds > 0 {
+				timeout = time.Duration(ng.Spec.Consolidation.ReplacementTimeoutSeconds) * time.Second
+			}
+			if time.Since(consolidation.StartedAt.Time) > timeout {
+				return r.abortConsolidation(ctx, ng,
+					fmt.Sprintf("replacement nodes not Ready after %s", timeout), logger)
+			}
+			logger.Debug("Waiting for replacement nodes",
+				zap.Int("ready", ready),
+				zap.Int("replacements", len(consolidation.ReplacementNodes)),
+			)
+			return ctrl.Result{RequeueAfter: FastRequeueAfter}, nil
+		}
+
+		shortfall, err := r.replacementShortfall(ctx, consolidation, byName, logger)
+		if err != nil {
+			logger.Error("Failed to check replacement node capacity", zap.Error(err))
+			return ctrl.Result{}, err
+		}
+		if shortfall != "" {
+			return r.abortConsolidation(ctx, ng, shortfall, logger)
+		}
+
+		logger.Info("Replacement nodes Ready, retiring nodes",
+			zap.Strings("retiring", consolidation.RetiringNodes),
+		)
+		if err := r.retireConsolidationNodes(ctx, consolidation.RetiringNodes, byName, logger); err != nil {
+			SetErrorCondition(ng, true, ReasonKubernetesAPIError, fmt.Sprintf("Failed to delete retiring VPSieNode: %v", err))
+			return ctrl.Result{}, err
+		}
+		consolidation.Phase = v1alpha1.ConsolidationPhaseDraining
+		r.Recorder.Eventf(ng, corev1.EventTypeNormal, "ConsolidationDraining",
+			"Replacement nodes Ready, draining %d retiring nodes", len(consolidation.RetiringNodes))
+		return ctrl.Result{RequeueAfter: FastRequeueAfter}, nil
+
+	case v1alpha1.ConsolidationPhaseDraining:
+		remaining := 0
+		for _, name := range consolidation.RetiringNodes {
+			if _, ok := byName[name]; ok {
+				remaining++
+			}
+		}
+		if remaining > 0 {
+			// Deletions that failed before are retried
+			if err := r.retireConsolidationNodes(ctx, consolidation.RetiringNodes, byName, logger); err != nil {
+				return ctrl.Result{}, err
+			}
+			logger.Debug("Waiting for retiring nodes to be deleted", zap.Int("remaining", remaining))
+			return ctrl.Result{RequeueAfter: FastRequeueAfter}, nil
+		}
+
+		removed := int32(len(consolidation.RetiringNodes) - len(consolidation.ReplacementNodes))
+		SetDesiredNodes(ng, ClampNodes(ng, ng.Status.DesiredNodes-removed))
+		now := metav1.Now()
+		ng.Status.LastScaleDownTime = &now
+		ng.Status.Consolidation = nil
+
+		logger.Info("Consolidation completed", zap.Int32("nodesRemoved", removed))
+		r.Recorder.Eventf(ng, corev1.EventTypeNormal, "ConsolidationCompleted",
+			"Replaced %d nodes with %d nodes of offering %s",
+			len(consolidation.RetiringNodes), len(consolidation.ReplacementNodes), consolidation.TargetOffering)
+		metrics.ConsolidationsTotal.WithLabelValues(ng.Name, ng.Namespace, "completed").Inc()
+		return ctrl.Result{RequeueAfter: DefaultRequeueAfter}, nil
+	}
+
+	return r.abortConsolidation(ctx, ng, fmt.Sprintf("unknown phase %q", consolidation.Phase), logger)
+}
+
+// replacementShortfall binpacks the pods of the retiring nodes onto the Ready
+// replacement nodes alongside the pods already running there. Their
+// allocatable may fall short of the template the plan was based on, and their
+// taints and labels are only known now. It returns why the pods don't fit, or
+// an empty string if they do.
+func (r *NodeGroupReconciler) replacementShortfall(
+	ctx context.Context,
+	consolidation *v1alpha1.ConsolidationStatus,
+	byName map[string]*v1alpha1.VPSieNode,
+	logger *zap.Logger,
+) (string, error) {
+	nodes := make([]*corev1.Node, 0, len(consolidation.ReplacementNodes))
+	running := make(map[string][]*corev1.Pod, len(consolidation.ReplacementNodes))
+	for _, name := range consolidation.ReplacementNodes {
+		nodeName := vpsieNodeNodeName(byName[name])
+		if nodeName == "" {
+			return fmt.Sprintf("replacement node %s has no Node", name), nil
+		}
+		node := &corev1.Node{}
+		if err := r.Get(ctx, client.ObjectKey{Name: nodeName}, node); err != nil {
+			if apierrors.IsNotFound(err) {
+				return fmt.Sprintf("replacement node %s has no Node", name), nil
+			}
+			return "", fmt.Errorf("get Node %s: %w", nodeName, err)
+		}
+		nodes = append(nodes, node)
+
+		pods, err := r.listNodePods(ctx, nodeName)
+		if err != nil {
+			return "", err
+		}
+		for _, pod := range pods {
+			if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+				running[nodeName] = append(running[nodeName], pod)
+			}
+		}
+	}
+
+	var moving []*corev1.Pod
+	for _, name := range consolidation.RetiringNodes {
+		nodeName := vpsieNodeNodeName(byName[name])
+		if nodeName == "" {
+			continue
+		}
+		pods, err := r.listNodePods(ctx, nodeName)
+		if err != nil {
+			return "", err
+		}
+		for _, pod := range pods {
+			if drain.IsWorkloadPod(pod) {
+				moving = append(moving, pod)
+			}
+		}
+	}
+
+	if unplaced := events.NewResourceAnalyzer(logger, nil).FitPodsOnNodes(moving, nodes, running); len(unplaced) > 0 {
+		return fmt.Sprintf("%d of %d pods of the retiring nodes don't fit on the replacement nodes", len(unplaced), len(moving)), nil
+	}
+	return "", nil
+}
+
+// listNodePods returns the pods bound to the node
+func (r *NodeGroupReconciler) listNodePods(ctx context.Context, nodeName string) ([]*corev1.Pod, error) {
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, client.MatchingFields{"spec.nodeName": nodeName}); err != nil {
+		return nil, fmt.Errorf("list pods on node %s: %w", nodeName, err)
+	}
+	pods := make([]*corev1.Pod, 0, len(podList.Items))
+	for i := range podList.Items {
+		pods = append(pods, &podList.Items[i])
+	}
+	return pods, nil
+}
+
+// vpsieNodeNodeName returns the name of the VPSieNode's Kubernetes node, or
+// an empty string if it isn't known
+func vpsieNodeNodeName(vn *v1alpha1.VPSieNode) string {
+	if vn == nil {
+		return ""
+	}
+	for _, name := range []string{vn.Status.NodeName, vn.Spec.NodeName, vn.Status.Hostname} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// offeringGroupID returns the numeric ID of the VPSie node group whose nodes
+// use the offering. Nodes are added to a VPSie node group rather than created
+// with an offering, so an offering other than the NodeGroup's KubeSizeID gets
+// a group of its own, created on first use and kept in Status.OfferingGroups.
+func (r *NodeGroupReconciler) offeringGroupID(ctx context.Context, ng *v1alpha1.NodeGroup, offeringID string, logger *zap.Logger) (int, error) {
+	if offeringID == strconv.Itoa(ng.Spec.KubeSizeID) && ng.Status.VPSieGroupID != 0 {
+		return ng.Status.VPSieGroupID, nil
+	}
+	if id, ok := ng.Status.OfferingGroups[offeringID]; ok {
+		return id, nil
+	}
+
+	kubeSizeID, err := strconv.Atoi(offeringID)
+	if err != nil {
+		return 0, fmt.Errorf("offering %q is not a VPSie Kubernetes size ID", offeringID)
+	}
+	if r.VPSieClient == nil {
+		return 0, fmt.Errorf("no VPSie client to create a node group for offering %s", offeringID)
+	}
+
+	groupName := fmt.Sprintf("%s-%s", ng.Name, offeringID)
+	id, err := r.findVPSieNodeGroup(ctx, ng.Spec.ResourceIdentifier, groupName, kubeSizeID)
+	if err != nil {
+		return 0, err
+	}
+	if id == 0 {
+		req := vpsieclient.CreateK8sNodeGroupRequest{
+			ClusterIdentifier: ng.Spec.ResourceIdentifier,
+			GroupName:         groupName,
+			KubeSizeID:        kubeSizeID,
+		}
+		if _, err := r.VPSieClient.CreateK8sNodeGroup(ctx, req); err != nil {
+			return 0, fmt.Errorf("create VPSie node group %s: %w", groupName, err)
+		}
+		id, err = r.findVPSieNodeGroup(ctx, ng.Spec.ResourceIdentifier, groupName, kubeSizeID)
+		if err != nil {
+			return 0, err
+		}
+		if id == 0 {
+			return 0, fmt.Errorf("could not find numeric ID for created node group %s", groupName)
+		}
+		logger.Info("Created VPSie node group for offering",
+			zap.String("group", groupName),
+			zap.String("offering", offeringID),
+			zap.Int("vpsieGroupID", id),
+		)
+	}
+
+	if ng.Status.OfferingGroups == nil {
+		ng.Status.OfferingGroups = make(map[string]int)
+	}
+	ng.Status.OfferingGroups[offeringID] = id
+	return id, nil
+}
+
+// findVPSieNodeGroup returns the numeric ID of the cluster's VPSie node group
+// with the name and size, or 0 if there is none
+func (r *NodeGroupReconciler) findVPSieNodeGroup(ctx context.Context, clusterID, name string, kubeSizeID int) (int, error) {
+	groups, err := r.VPSieClient.ListK8sNodeGroups(ctx, clusterID)
+	if err != nil {
+		return 0, fmt.Errorf("list VPSie node groups: %w", err)
+	}
+	for _, group := range groups {
+		if group.GroupName == name && group.BoxsizeID == kubeSizeID {
+			return group.ID, nil
+		}
+	}
+	return 0, nil
+}
+
+// abortConsolidation deletes the replacement nodes of the consolidation in
+// progress and keeps the retiring ones. The scale-down cooldown starts over
+// so the consolidation isn't retried immediately.
+func (r *NodeGroupReconciler) abortConsolidation(
+	ctx context.Context,
+	ng *v1alpha1.NodeGroup,
+	reason string,
+	logger *zap.Logger,
+) (ctrl.Result, error) {
+	consolidation := ng.Status.Consolidation
+	logger.Warn("Abandoning consolidation", zap.String("reason", reason))
+
+	r.deleteVPSieNodes(ctx, ng.Namespace, consolidation.ReplacementNodes, logger)
+	for _, name := range consolidation.RetiringNodes {
+		if err := r.labelConsolidationNode(ctx, ng.Namespace, name, ""); err != nil && !apierrors.IsNotFound(err) {
+			logger.Warn("Failed to unlabel VPSieNode", zap.String("vpsienode", name), zap.Error(err))
+		}
+	}
+
+	now := metav1.Now()
+	ng.Status.LastScaleDownTime = &now
+	ng.Status.Consolidation = nil
+
+	r.Recorder.Eventf(ng, corev1.EventTypeWarning, "ConsolidationFailed",
+		"Consolidation %s abandoned: %s", consolidation.ID, reason)
+	metrics.ConsolidationsTotal.WithLabelValues(ng.Name, ng.Namespace, "failed").Inc()
+
+	return ctrl.Result{RequeueAfter: DefaultRequeueAfter}, nil
+}
+
+// retireConsolidationNodes deletes the retiring VPSieNodes not yet being deleted
+func (r *NodeGroupReconciler) retireConsolidationNodes(
+	ctx context.Context,
+	names []string,
+	byName map[string]*v1alpha1.VPSieNode,
+	logger *zap.Logger,
+) error {
+	for _, name := range names {
+		vn, ok := byName[name]
+		if !ok || !vn.DeletionTimestamp.IsZero() {
+			continue
+		}
+		logger.Info("Deleting retiring VPSieNode", zap.String("vpsienode", name))
+		if err := r.Delete(ctx, vn); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("delete VPSieNode %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// deleteVPSieNodes deletes the named VPSieNodes, logging failures
+func (r *NodeGroupReconciler) deleteVPSieNodes(ctx context.Context, namespace string, names []string, logger *zap.Logger) {
+	for _, name := range names {
+		vn := &v1alpha1.VPSieNode{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		if err := r.Delete(ctx, vn); err != nil && !apierrors.IsNotFound(err) {
+			logger.Error("Failed to delete VPSieNode", zap.String("vpsienode", name), zap.Error(err))
+		}
+	}
+}
+
+// labelConsolidationNode sets the consolidation label of a VPSieNode to id,
+// or removes it if id is empty
+func (r *NodeGroupReconciler) labelConsolidationNode(ctx context.Context, namespace, name, id string) error {
+	vn := &v1alpha1.VPSieNode{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, vn); err != nil {
+		return err
+	}
+	if vn.Labels[v1alpha1.ConsolidationLabelKey] == id {
+		return nil
+	}
+
+	patch := client.MergeFrom(vn.DeepCopy())
+	if id == "" {
+		delete(vn.Labels, v1alpha1.ConsolidationLabelKey)
+	} else {
+		if vn.Labels == nil {
+			vn.Labels = make(map[string]string)
+		}
+		vn.Labels[v1alpha1.ConsolidationLabelKey] = id
+	}
+	return r.Patch(ctx, vn, patch)
+}
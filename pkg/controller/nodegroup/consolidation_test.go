@@ -0,0 +1,547 @@
+package nodegroup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/events"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/scaler"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
+)
+
+const consolidationTestGiB = int64(1024 * 1024 * 1024)
+
+var (
+	smallOffering = &cost.OfferingCost{
+		OfferingID:  "small",
+		MonthlyCost: 20,
+		Specs:       cost.ResourceSpecs{CPU: 2, MemoryMB: 4096},
+	}
+	largeOffering = &cost.OfferingCost{
+		OfferingID:  "large",
+		MonthlyCost: 50,
+		Specs:       cost.ResourceSpecs{CPU: 8, MemoryMB: 16384},
+	}
+)
+
+// fakePricer prices offerings from a fixed table
+type fakePricer map[string]*cost.OfferingCost
+
+func (p fakePricer) GetOfferingCost(_ context.Context, offeringID string) (*cost.OfferingCost, error) {
+	offering, ok := p[offeringID]
+	if !ok {
+		return nil, fmt.Errorf("offering %s not found", offeringID)
+	}
+	return offering, nil
+}
+
+func newConsolidationTestPod(name, nodeName string, cpu string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse(cpu),
+						corev1.ResourceMemory: resource.MustParse("1Gi"),
+					},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func newConsolidationTestNodes(count int) []consolidationNode {
+	var nodes []consolidationNode
+	for i := 1; i <= count; i++ {
+		name := fmt.Sprintf("node-%d", i)
+		nodes = append(nodes, consolidationNode{
+			NodeName:          name,
+			VPSieNode:         "vn-" + name,
+			Offering:          smallOffering,
+			AllocatableCPU:    1900,
+			AllocatableMemory: 3 * consolidationTestGiB,
+			Pods:              []*corev1.Pod{newConsolidationTestPod("pod-"+name, name, "1")},
+		})
+	}
+	return nodes
+}
+
+// consolidationTestOfferings returns the offerings with the capacity of a
+// node of each, which loses the same share to reservations as the small nodes
+func consolidationTestOfferings(offerings ...*cost.OfferingCost) []consolidationOffering {
+	templates := map[string]events.NodeTemplate{
+		"small": {OfferingID: "small", CPUMillis: 1900, MemoryBytes: 3 * consolidationTestGiB, MaxPods: 110},
+		"large": {OfferingID: "large", CPUMillis: 7600, MemoryBytes: 12 * consolidationTestGiB, MaxPods: 110},
+	}
+	result := make([]consolidationOffering, 0, len(offerings))
+	for _, offering := range offerings {
+		result = append(result, consolidationOffering{Cost: offering, Template: templates[offering.OfferingID]})
+	}
+	return result
+}
+
+func TestPlanConsolidation(t *testing.T) {
+	analyzer := events.NewResourceAnalyzer(zap.NewNop(), nil)
+	// The retiring nodes' own free capacity: the rest of the group has none
+	limits := consolidationLimits{
+		MaxAdded:          5,
+		MaxRemoved:        5,
+		MinSavingsPercent: 10,
+		FreeCPU:           4 * 900,
+		FreeMemory:        4 * 2 * consolidationTestGiB,
+	}
+
+	tests := []struct {
+		name         string
+		offerings    []consolidationOffering
+		limits       func(l *consolidationLimits)
+		pods         func(pod *corev1.Pod)
+		retiring     int
+		replacements int
+	}{
+		{
+			name:         "four small nodes onto one large",
+			offerings:    consolidationTestOfferings(smallOffering, largeOffering),
+			retiring:     4,
+			replacements: 1,
+		},
+		{
+			name:      "no larger offering",
+			offerings: consolidationTestOfferings(smallOffering),
+		},
+		{
+			name:      "savings below minimum",
+			offerings: consolidationTestOfferings(largeOffering),
+			limits:    func(l *consolidationLimits) { l.MinSavingsPercent = 50 },
+		},
+		{
+			name:      "pods fit on the rest of the group",
+			offerings: consolidationTestOfferings(largeOffering),
+			limits: func(l *consolidationLimits) {
+				l.FreeCPU += 8000
+				l.FreeMemory += 16 * consolidationTestGiB
+			},
+		},
+		{
+			name:      "no room for replacements",
+			offerings: consolidationTestOfferings(largeOffering),
+			limits:    func(l *consolidationLimits) { l.MaxAdded = 0 },
+		},
+		{
+			name:         "limited by the minimum node count",
+			offerings:    consolidationTestOfferings(largeOffering),
+			limits:       func(l *consolidationLimits) { l.MaxRemoved = 2 },
+			retiring:     3,
+			replacements: 1,
+		},
+		{
+			name:      "init containers need a large node each",
+			offerings: consolidationTestOfferings(largeOffering),
+			pods: func(pod *corev1.Pod) {
+				pod.Spec.InitContainers = []corev1.Container{{
+					Name: "init",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("7Gi")},
+					},
+				}}
+			},
+		},
+		{
+			name:      "extended resources the large offering doesn't have",
+			offerings: consolidationTestOfferings(largeOffering),
+			pods: func(pod *corev1.Pod) {
+				pod.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("1")
+			},
+		},
+		{
+			name:      "anti-affinity keeps pods on separate nodes",
+			offerings: consolidationTestOfferings(largeOffering),
+			pods: func(pod *corev1.Pod) {
+				pod.Labels = map[string]string{"app": "web"}
+				pod.Spec.Affinity = &corev1.Affinity{
+					PodAntiAffinity: &corev1.PodAntiAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+							LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+							TopologyKey:   corev1.LabelHostname,
+						}},
+					},
+				}
+			},
+		},
+		{
+			name:      "pods pinned to the small offering",
+			offerings: consolidationTestOfferings(largeOffering),
+			pods: func(pod *corev1.Pod) {
+				pod.Spec.NodeSelector = map[string]string{v1alpha1.OfferingLabelKey: "small"}
+			},
+		},
+		{
+			name: "pod limit of the large offering",
+			offerings: func() []consolidationOffering {
+				offerings := consolidationTestOfferings(largeOffering)
+				offerings[0].Template.MaxPods = 1
+				return offerings
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := newConsolidationTestNodes(4)
+			for i := range nodes {
+				nodes[i].Labels = map[string]string{v1alpha1.OfferingLabelKey: "small"}
+				if tt.pods != nil {
+					tt.pods(nodes[i].Pods[0])
+				}
+			}
+			l := limits
+			if tt.limits != nil {
+				tt.limits(&l)
+			}
+			plan := planConsolidation(analyzer, nodes, tt.offerings, l)
+			if tt.retiring == 0 {
+				assert.Nil(t, plan)
+				return
+			}
+			require.NotNil(t, plan)
+			assert.Len(t, plan.Retiring, tt.retiring)
+			assert.Equal(t, tt.replacements, plan.Replacements)
+			assert.Equal(t, "large", plan.Target.OfferingID)
+			assert.Less(t, plan.NewMonthlyCost, plan.CurrentMonthlyCost)
+		})
+	}
+}
+
+func TestOfferingGroupID(t *testing.T) {
+	reconciler, _ := newConsolidationTestReconciler()
+	ng := newConsolidationTestNodeGroup()
+	ng.Spec.KubeSizeID = 7
+	ng.Status.VPSieGroupID = 12
+	ng.Status.OfferingGroups = map[string]int{"9": 34}
+	ctx := context.Background()
+
+	// The NodeGroup's own size uses its VPSie node group
+	id, err := reconciler.offeringGroupID(ctx, ng, "7", zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, 12, id)
+
+	id, err = reconciler.offeringGroupID(ctx, ng, "9", zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, 34, id)
+
+	// Other offerings need a VPSie node group created for them
+	_, err = reconciler.offeringGroupID(ctx, ng, "11", zap.NewNop())
+	assert.Error(t, err)
+	_, err = reconciler.offeringGroupID(ctx, ng, "large", zap.NewNop())
+	assert.Error(t, err)
+}
+
+func newConsolidationTestNodeGroup() *v1alpha1.NodeGroup {
+	return &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ng", Namespace: "default"},
+		Spec: v1alpha1.NodeGroupSpec{
+			MinNodes:        1,
+			MaxNodes:        10,
+			OfferingIDs:     []string{"small", "large"},
+			ScaleDownPolicy: v1alpha1.ScaleDownPolicy{Enabled: true},
+			Consolidation:   &v1alpha1.ConsolidationConfig{Enabled: true, MinSavingsPercent: 10},
+		},
+		Status: v1alpha1.NodeGroupStatus{CurrentNodes: 3, DesiredNodes: 3},
+	}
+}
+
+func newConsolidationTestReconciler(objects ...client.Object) (*NodeGroupReconciler, client.Client) {
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	k8sClient := ctrlclient.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).
+		WithStatusSubresource(&v1alpha1.NodeGroup{}).
+		WithIndex(&corev1.Pod{}, "spec.nodeName", func(obj client.Object) []string {
+			return []string{obj.(*corev1.Pod).Spec.NodeName}
+		}).
+		Build()
+
+	return &NodeGroupReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Logger:   zap.NewNop(),
+		Recorder: record.NewFakeRecorder(10),
+		Pricer:   fakePricer{"small": smallOffering, "large": largeOffering},
+	}, k8sClient
+}
+
+func TestStartConsolidation(t *testing.T) {
+	ng := newConsolidationTestNodeGroup()
+	objects := []client.Object{ng}
+
+	var vpsieNodes []v1alpha1.VPSieNode
+	var candidates []*scaler.ScaleDownCandidate
+	for i := 1; i <= 3; i++ {
+		name := fmt.Sprintf("node-%d", i)
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{NodeGroupNameLabelKey: ng.Name},
+			},
+			Status: corev1.NodeStatus{
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1900m"),
+					corev1.ResourceMemory: resource.MustParse("3Gi"),
+				},
+			},
+		}
+		pod := newConsolidationTestPod("pod-"+name, name, "1")
+		vn := v1alpha1.VPSieNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "vn-" + name, Namespace: "default"},
+			Spec:       v1alpha1.VPSieNodeSpec{InstanceType: "small", NodeGroupName: ng.Name},
+			Status:     v1alpha1.VPSieNodeStatus{Phase: v1alpha1.VPSieNodePhaseReady, NodeName: name},
+		}
+		vpsieNodes = append(vpsieNodes, vn)
+		candidates = append(candidates, &scaler.ScaleDownCandidate{Node: node, Pods: []*corev1.Pod{pod}})
+		objects = append(objects, node, pod, &vpsieNodes[len(vpsieNodes)-1])
+	}
+
+	mockSDM := new(MockScaleDownManager)
+	mockSDM.On("IdentifyUnderutilizedNodes", mock.Anything, ng).Return(candidates, nil)
+
+	reconciler, k8sClient := newConsolidationTestReconciler(objects...)
+	reconciler.ScaleDownManager = mockSDM
+
+	// Without a VPSie node group for the target offering no replacement can be provisioned
+	ctx := context.Background()
+	started, err := reconciler.startConsolidation(ctx, ng, vpsieNodes, 0, zap.NewNop())
+	require.NoError(t, err)
+	require.False(t, started)
+	assert.Nil(t, ng.Status.Consolidation)
+
+	ng.Status.OfferingGroups = map[string]int{"large": 42}
+	started, err = reconciler.startConsolidation(ctx, ng, vpsieNodes, 0, zap.NewNop())
+	require.NoError(t, err)
+	require.True(t, started)
+
+	consolidation := ng.Status.Consolidation
+	require.NotNil(t, consolidation)
+	assert.Equal(t, v1alpha1.ConsolidationPhaseProvisioning, consolidation.Phase)
+	assert.Equal(t, "large", consolidation.TargetOffering)
+	assert.Equal(t, []string{"vn-node-1", "vn-node-2", "vn-node-3"}, consolidation.RetiringNodes)
+	require.Len(t, consolidation.ReplacementNodes, 1)
+	assert.Equal(t, "10.00", consolidation.EstimatedMonthlySavings)
+
+	var list v1alpha1.VPSieNodeList
+	require.NoError(t, k8sClient.List(ctx, &list,
+		client.MatchingLabels{v1alpha1.ConsolidationLabelKey: consolidation.ID}))
+	assert.Len(t, list.Items, 4)
+
+	replacement := &v1alpha1.VPSieNode{}
+	require.NoError(t, k8sClient.Get(ctx,
+		client.ObjectKey{Namespace: "default", Name: consolidation.ReplacementNodes[0]}, replacement))
+	assert.Equal(t, "large", replacement.Spec.InstanceType)
+	assert.Equal(t, 42, replacement.Spec.VPSieGroupID)
+
+	// Disabled consolidation doesn't look at candidates
+	ng.Status.Consolidation = nil
+	ng.Spec.Consolidation.Enabled = false
+	started, err = reconciler.startConsolidation(ctx, ng, vpsieNodes, 0, zap.NewNop())
+	assert.NoError(t, err)
+	assert.False(t, started)
+	mockSDM.AssertNumberOfCalls(t, "IdentifyUnderutilizedNodes", 2)
+}
+
+func newConsolidatingNodeGroup() (*v1alpha1.NodeGroup, []client.Object) {
+	ng := newConsolidationTestNodeGroup()
+	ng.Status.CurrentNodes = 4
+	ng.Status.DesiredNodes = 3
+	ng.Status.Consolidation = &v1alpha1.ConsolidationStatus{
+		ID:               "consolidation-1",
+		Phase:            v1alpha1.ConsolidationPhaseProvisioning,
+		TargetOffering:   "large",
+		ReplacementNodes: []string{"vn-large"},
+		RetiringNodes:    []string{"vn-node-1", "vn-node-2", "vn-node-3"},
+		StartedAt:        metav1.Now(),
+	}
+
+	objects := []client.Object{ng}
+	for _, name := range append([]string{"vn-large"}, ng.Status.Consolidation.RetiringNodes...) {
+		nodeName := strings.TrimPrefix(name, "vn-")
+		objects = append(objects, &v1alpha1.VPSieNode{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{v1alpha1.ConsolidationLabelKey: "consolidation-1"},
+			},
+			Status: v1alpha1.VPSieNodeStatus{Phase: v1alpha1.VPSieNodePhaseReady, NodeName: nodeName},
+		})
+		if name != "vn-large" {
+			objects = append(objects, newConsolidationTestPod("pod-"+nodeName, nodeName, "1"))
+		}
+	}
+	objects = append(objects, &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "large"},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("3900m"),
+				corev1.ResourceMemory: resource.MustParse("7Gi"),
+			},
+		},
+	})
+	return ng, objects
+}
+
+func listConsolidationTestVPSieNodes(t *testing.T, k8sClient client.Client) []v1alpha1.VPSieNode {
+	var list v1alpha1.VPSieNodeList
+	require.NoError(t, k8sClient.List(context.Background(), &list))
+	return list.Items
+}
+
+func TestReconcileConsolidation(t *testing.T) {
+	ng, objects := newConsolidatingNodeGroup()
+	reconciler, k8sClient := newConsolidationTestReconciler(objects...)
+	ctx := context.Background()
+
+	// Replacements Ready: the retiring nodes are deleted
+	result, err := reconciler.reconcileConsolidation(ctx, ng, listConsolidationTestVPSieNodes(t, k8sClient), zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, FastRequeueAfter, result.RequeueAfter)
+	require.NotNil(t, ng.Status.Consolidation)
+	assert.Equal(t, v1alpha1.ConsolidationPhaseDraining, ng.Status.Consolidation.Phase)
+
+	remaining := listConsolidationTestVPSieNodes(t, k8sClient)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, "vn-large", remaining[0].Name)
+
+	// Retiring nodes gone: the consolidation completes
+	ng.Status.CurrentNodes = 1
+	_, err = reconciler.reconcileConsolidation(ctx, ng, remaining, zap.NewNop())
+	require.NoError(t, err)
+	assert.Nil(t, ng.Status.Consolidation)
+	assert.Equal(t, int32(1), ng.Status.DesiredNodes)
+	assert.NotNil(t, ng.Status.LastScaleDownTime)
+}
+
+func TestReconcileConsolidation_Abort(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(ng *v1alpha1.NodeGroup, replacement *v1alpha1.VPSieNode, node *corev1.Node)
+	}{
+		{
+			name: "replacement failed",
+			modify: func(_ *v1alpha1.NodeGroup, replacement *v1alpha1.VPSieNode, _ *corev1.Node) {
+				replacement.Status.Phase = v1alpha1.VPSieNodePhaseFailed
+			},
+		},
+		{
+			name: "replacement timeout",
+			modify: func(ng *v1alpha1.NodeGroup, replacement *v1alpha1.VPSieNode, _ *corev1.Node) {
+				replacement.Status.Phase = v1alpha1.VPSieNodePhaseProvisioning
+				ng.Status.Consolidation.StartedAt = metav1.NewTime(time.Now().Add(-time.Hour))
+			},
+		},
+		{
+			name: "replacement allocatable short of the retiring pods",
+			modify: func(_ *v1alpha1.NodeGroup, _ *v1alpha1.VPSieNode, node *corev1.Node) {
+				node.Status.Allocatable[corev1.ResourceCPU] = resource.MustParse("2500m")
+			},
+		},
+		{
+			name: "replacement tainted against the retiring pods",
+			modify: func(_ *v1alpha1.NodeGroup, _ *v1alpha1.VPSieNode, node *corev1.Node) {
+				node.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "batch", Effect: corev1.TaintEffectNoSchedule}}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ng, objects := newConsolidatingNodeGroup()
+			tt.modify(ng, objects[1].(*v1alpha1.VPSieNode), objects[len(objects)-1].(*corev1.Node))
+			reconciler, k8sClient := newConsolidationTestReconciler(objects...)
+			ctx := context.Background()
+
+			_, err := reconciler.reconcileConsolidation(ctx, ng, listConsolidationTestVPSieNodes(t, k8sClient), zap.NewNop())
+			require.NoError(t, err)
+			assert.Nil(t, ng.Status.Consolidation)
+			assert.Equal(t, int32(3), ng.Status.DesiredNodes)
+
+			// The replacement is deleted and the retiring nodes kept without the label
+			remaining := listConsolidationTestVPSieNodes(t, k8sClient)
+			require.Len(t, remaining, 3)
+			for _, vn := range remaining {
+				assert.NotEqual(t, "vn-large", vn.Name)
+				assert.NotContains(t, vn.Labels, v1alpha1.ConsolidationLabelKey)
+			}
+		})
+	}
+}
+
+func TestReconcile_ScaleUpAbandonsConsolidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		phase     v1alpha1.ConsolidationPhase
+		abandoned bool
+	}{
+		{name: "provisioning", phase: v1alpha1.ConsolidationPhaseProvisioning, abandoned: true},
+		{name: "draining", phase: v1alpha1.ConsolidationPhaseDraining},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ng, objects := newConsolidatingNodeGroup()
+			ng.Spec.DatacenterID = "dc-1"
+			ng.Spec.KubernetesVersion = "v1.28.0"
+			ng.Status.Consolidation.Phase = tt.phase
+			// Pending pods raised the desired count past the replacement
+			ng.Status.DesiredNodes = 6
+			for _, obj := range objects {
+				if vn, ok := obj.(*v1alpha1.VPSieNode); ok {
+					for key, value := range GetNodeGroupLabels(ng) {
+						vn.Labels[key] = value
+					}
+				}
+			}
+			reconciler, k8sClient := newConsolidationTestReconciler(objects...)
+
+			result, err := reconciler.reconcile(context.Background(), ng, zap.NewNop())
+			require.NoError(t, err)
+			assert.Equal(t, FastRequeueAfter, result.RequeueAfter)
+
+			vpsieNodes := listConsolidationTestVPSieNodes(t, k8sClient)
+			if !tt.abandoned {
+				// The retiring nodes keep draining onto the replacement
+				require.NotNil(t, ng.Status.Consolidation)
+				require.Len(t, vpsieNodes, 1)
+				assert.Equal(t, "vn-large", vpsieNodes[0].Name)
+				return
+			}
+
+			assert.Nil(t, ng.Status.Consolidation)
+			assert.Equal(t, int32(6), ng.Status.DesiredNodes)
+			require.Len(t, vpsieNodes, 3)
+			for _, vn := range vpsieNodes {
+				assert.NotEqual(t, "vn-large", vn.Name)
+				assert.NotContains(t, vn.Labels, v1alpha1.ConsolidationLabelKey)
+			}
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/scaler"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/tracing"
 	vpsieclient "github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/client"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
 )
 
 const (
@@ -42,11 +43,21 @@ type ScaleDownManagerInterface interface {
 	IdentifyUnderutilizedNodes(ctx context.Context, ng *v1alpha1.NodeGroup) ([]*scaler.ScaleDownCandidate, error)
 	ScaleDown(ctx context.Context, ng *v1alpha1.NodeGroup, candidates []*scaler.ScaleDownCandidate) error
 	UpdateNodeUtilization(ctx context.Context) error
+	// IdentifyEmptyNodes finds nodes that have run no workload pods for the empty-node unneeded time
+	IdentifyEmptyNodes(ctx context.Context, ng *v1alpha1.NodeGroup) ([]*scaler.ScaleDownCandidate, error)
+	// ScaleDownEmptyNodes drains empty nodes in bulk and returns the drained ones
+	ScaleDownEmptyNodes(ctx context.Context, ng *v1alpha1.NodeGroup, candidates []*scaler.ScaleDownCandidate) ([]*scaler.ScaleDownCandidate, error)
 	// GetMaxNodesPerScaleDown returns the maximum number of nodes that can be scaled down
 	// in a single operation. This is a safety limit to prevent aggressive scale-down.
 	GetMaxNodesPerScaleDown() int
 }
 
+// SavingsRecorder records applied scale-downs so their predicted savings can be
+// verified against the NodeGroup's measured cost
+type SavingsRecorder interface {
+	RecordApplied(ctx context.Context, nodeGroup, namespace string, opportunity *cost.Opportunity, appliedAt time.Time) (*cost.LedgerEntry, error)
+}
+
 // NodeGroupReconciler reconciles a NodeGroup object
 type NodeGroupReconciler struct {
 	client.Client
@@ -56,6 +67,18 @@ type NodeGroupReconciler struct {
 	Logger           *zap.Logger
 	Recorder         record.EventRecorder
 
+	// CarbonIntensity is the optional carbon intensity source used for
+	// carbon-aware multi-region placement and emissions metrics
+	CarbonIntensity cost.CarbonIntensitySource
+
+	// Pricer is the optional source of offering prices and specs used to
+	// plan consolidations. Consolidation is disabled without it.
+	Pricer scaler.OfferingPricer
+
+	// Ledger is the optional savings ledger scale-downs are recorded in.
+	// Scale-downs are only recorded when Pricer is set too.
+	Ledger SavingsRecorder
+
 	// Secret watching for credential rotation
 	SecretName        string // Name of the secret containing VPSie credentials
 	SecretNamespace   string // Namespace of the secret
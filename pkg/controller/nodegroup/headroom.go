@@ -0,0 +1,197 @@
+package nodegroup
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/scaler"
+)
+
+// nodeGroupCapacity is the allocatable and requested resources of a NodeGroup's schedulable nodes
+type nodeGroupCapacity struct {
+	Nodes             int32
+	AllocatableCPU    int64 // millicores
+	AllocatableMemory int64 // bytes
+	RequestedCPU      int64 // millicores
+	RequestedMemory   int64 // bytes
+}
+
+// headroomPlan is the node count needed to keep a NodeGroup's headroom free
+type headroomPlan struct {
+	// Target is the number of schedulable nodes that leaves the headroom unrequested
+	Target int32
+
+	// HeadroomNodes is how many of Target exceed the nodes needed for current requests
+	HeadroomNodes int32
+}
+
+// HasHeadroom returns true if the NodeGroup requests any spare capacity
+func HasHeadroom(ng *v1alpha1.NodeGroup) bool {
+	h := ng.Spec.Headroom
+	return h != nil && (h.SpareNodes > 0 || h.SpareCPUPercent > 0 || h.SpareMemoryPercent > 0)
+}
+
+// validateHeadroom validates the NodeGroup's headroom settings
+func validateHeadroom(h *v1alpha1.HeadroomConfig) error {
+	if h == nil {
+		return nil
+	}
+	if h.SpareNodes < 0 {
+		return fmt.Errorf("headroom.spareNodes must be >= 0, got %d", h.SpareNodes)
+	}
+	if h.SpareCPUPercent < 0 || h.SpareCPUPercent > 100 {
+		return fmt.Errorf("headroom.spareCPUPercent must be between 0 and 100, got %d", h.SpareCPUPercent)
+	}
+	if h.SpareMemoryPercent < 0 || h.SpareMemoryPercent > 100 {
+		return fmt.Errorf("headroom.spareMemoryPercent must be between 0 and 100, got %d", h.SpareMemoryPercent)
+	}
+	return nil
+}
+
+// planHeadroom finds the smallest node count, up to the effective MaxNodes,
+// whose unrequested allocatable satisfies the headroom. New nodes are assumed
+// to have the average allocatable of the existing ones. Without existing nodes
+// the capacity of a node is unknown, so only SpareNodes can be honoured.
+func planHeadroom(ng *v1alpha1.NodeGroup, capacity nodeGroupCapacity) headroomPlan {
+	h := ng.Spec.Headroom
+	if !HasHeadroom(ng) {
+		return headroomPlan{}
+	}
+
+	if capacity.Nodes == 0 {
+		target := h.SpareNodes
+		if maxNodes := v1alpha1.EffectiveMaxNodes(ng); target > maxNodes {
+			target = maxNodes
+		}
+		return headroomPlan{Target: target, HeadroomNodes: target}
+	}
+
+	nodeCPU := capacity.AllocatableCPU / int64(capacity.Nodes)
+	nodeMemory := capacity.AllocatableMemory / int64(capacity.Nodes)
+
+	satisfied := func(nodes int32) bool {
+		totalCPU := nodeCPU * int64(nodes)
+		totalMemory := nodeMemory * int64(nodes)
+		freeCPU := totalCPU - capacity.RequestedCPU
+		freeMemory := totalMemory - capacity.RequestedMemory
+
+		return freeCPU >= int64(h.SpareNodes)*nodeCPU &&
+			freeMemory >= int64(h.SpareNodes)*nodeMemory &&
+			freeCPU*100 >= int64(h.SpareCPUPercent)*totalCPU &&
+			freeMemory*100 >= int64(h.SpareMemoryPercent)*totalMemory
+	}
+
+	maxNodes := v1alpha1.EffectiveMaxNodes(ng)
+	target := maxNodes
+	for nodes := int32(0); nodes <= maxNodes; nodes++ {
+		if satisfied(nodes) {
+			target = nodes
+			break
+		}
+	}
+
+	// Nodes needed for the current requests alone
+	var needed int32
+	for needed < target &&
+		(nodeCPU*int64(needed) < capacity.RequestedCPU || nodeMemory*int64(needed) < capacity.RequestedMemory) {
+		needed++
+	}
+
+	return headroomPlan{Target: target, HeadroomNodes: target - needed}
+}
+
+// getNodeGroupCapacity sums the allocatable and requested resources of the
+// NodeGroup's schedulable nodes. Cordoned nodes are skipped since their free
+// capacity cannot absorb new pods.
+func (r *NodeGroupReconciler) getNodeGroupCapacity(ctx context.Context, ng *v1alpha1.NodeGroup) (nodeGroupCapacity, error) {
+	capacity := nodeGroupCapacity{}
+
+	nodeList := &corev1.NodeList{}
+	if err := r.List(ctx, nodeList, client.MatchingLabels{NodeGroupNameLabelKey: ng.Name}); err != nil {
+		return capacity, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	nodes := make(map[string]bool, len(nodeList.Items))
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		if node.Spec.Unschedulable || !node.DeletionTimestamp.IsZero() {
+			continue
+		}
+		nodes[node.Name] = true
+		capacity.Nodes++
+		capacity.AllocatableCPU += node.Status.Allocatable.Cpu().MilliValue()
+		capacity.AllocatableMemory += node.Status.Allocatable.Memory().Value()
+	}
+
+	// Pods are listed per node through the manager's spec.nodeName index
+	var pods []*corev1.Pod
+	for nodeName := range nodes {
+		podList := &corev1.PodList{}
+		if err := r.List(ctx, podList, client.MatchingFields{"spec.nodeName": nodeName}); err != nil {
+			return capacity, fmt.Errorf("failed to list pods on node %s: %w", nodeName, err)
+		}
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+				continue
+			}
+			pods = append(pods, pod)
+		}
+	}
+	capacity.RequestedCPU, capacity.RequestedMemory = scaler.CalculateResourceRequests(pods)
+
+	return capacity, nil
+}
+
+// reconcileHeadroom returns the node count that keeps the NodeGroup's headroom
+// free. It returns 0 if no headroom is configured, and false if the capacity
+// cannot be determined.
+func (r *NodeGroupReconciler) reconcileHeadroom(ctx context.Context, ng *v1alpha1.NodeGroup, logger *zap.Logger) (int32, bool) {
+	if !HasHeadroom(ng) {
+		return 0, true
+	}
+
+	capacity, err := r.getNodeGroupCapacity(ctx, ng)
+	if err != nil {
+		logger.Warn("Failed to calculate NodeGroup capacity, skipping headroom", zap.Error(err))
+		return 0, false
+	}
+
+	plan := planHeadroom(ng, capacity)
+
+	logger.Debug("Calculated headroom",
+		zap.Int32("schedulableNodes", capacity.Nodes),
+		zap.Int64("allocatableCPU", capacity.AllocatableCPU),
+		zap.Int64("requestedCPU", capacity.RequestedCPU),
+		zap.Int64("allocatableMemory", capacity.AllocatableMemory),
+		zap.Int64("requestedMemory", capacity.RequestedMemory),
+		zap.Int32("target", plan.Target),
+		zap.Int32("headroomNodes", plan.HeadroomNodes),
+	)
+
+	return plan.Target, true
+}
+
+// applyHeadroom raises desired to the headroom target and records in status how
+// many nodes the raise added. The headroom target is a floor rather than a new
+// base: baseDesiredNodes takes the previous raise out again, so the nodes are
+// released once the headroom no longer needs them.
+func applyHeadroom(ng *v1alpha1.NodeGroup, desired, target int32) int32 {
+	if target > desired {
+		ng.Status.HeadroomNodes = target - desired
+		return target
+	}
+	ng.Status.HeadroomNodes = 0
+	return desired
+}
+
+// baseDesiredNodes returns the desired node count without the nodes the
+// previous reconcile added to maintain the headroom
+func baseDesiredNodes(ng *v1alpha1.NodeGroup) int32 {
+	return ClampNodes(ng, CalculateDesiredNodes(ng)-ng.Status.HeadroomNodes)
+}
@@ -0,0 +1,218 @@
+package nodegroup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+)
+
+func newHeadroomNodeGroup(headroom *v1alpha1.HeadroomConfig) *v1alpha1.NodeGroup {
+	return &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ng", Namespace: "default"},
+		Spec: v1alpha1.NodeGroupSpec{
+			MinNodes: 1,
+			MaxNodes: 10,
+			Headroom: headroom,
+		},
+	}
+}
+
+func TestPlanHeadroom(t *testing.T) {
+	const gib = int64(1024 * 1024 * 1024)
+
+	// Three 4 CPU / 8Gi nodes with 9 CPU and 12Gi requested
+	capacity := nodeGroupCapacity{
+		Nodes:             3,
+		AllocatableCPU:    12000,
+		AllocatableMemory: 24 * gib,
+		RequestedCPU:      9000,
+		RequestedMemory:   12 * gib,
+	}
+
+	tests := []struct {
+		name          string
+		headroom      *v1alpha1.HeadroomConfig
+		capacity      nodeGroupCapacity
+		target        int32
+		headroomNodes int32
+	}{
+		{
+			name:     "no headroom",
+			capacity: capacity,
+			target:   0,
+		},
+		{
+			name:          "one spare node",
+			headroom:      &v1alpha1.HeadroomConfig{SpareNodes: 1},
+			capacity:      capacity,
+			target:        4,
+			headroomNodes: 1,
+		},
+		{
+			name:          "spare CPU percent",
+			headroom:      &v1alpha1.HeadroomConfig{SpareCPUPercent: 40},
+			capacity:      capacity,
+			target:        4,
+			headroomNodes: 1,
+		},
+		{
+			name:          "spare memory percent already satisfied",
+			headroom:      &v1alpha1.HeadroomConfig{SpareMemoryPercent: 40},
+			capacity:      capacity,
+			target:        3,
+			headroomNodes: 0,
+		},
+		{
+			name:          "largest requirement applies",
+			headroom:      &v1alpha1.HeadroomConfig{SpareNodes: 1, SpareCPUPercent: 50},
+			capacity:      capacity,
+			target:        5,
+			headroomNodes: 2,
+		},
+		{
+			name:          "capped at MaxNodes",
+			headroom:      &v1alpha1.HeadroomConfig{SpareNodes: 20},
+			capacity:      capacity,
+			target:        10,
+			headroomNodes: 7,
+		},
+		{
+			name:          "no nodes honours spare nodes",
+			headroom:      &v1alpha1.HeadroomConfig{SpareNodes: 2, SpareCPUPercent: 50},
+			target:        2,
+			headroomNodes: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := planHeadroom(newHeadroomNodeGroup(tt.headroom), tt.capacity)
+			assert.Equal(t, tt.target, plan.Target)
+			assert.Equal(t, tt.headroomNodes, plan.HeadroomNodes)
+		})
+	}
+}
+
+func TestValidateHeadroom(t *testing.T) {
+	assert.NoError(t, validateHeadroom(nil))
+	assert.NoError(t, validateHeadroom(&v1alpha1.HeadroomConfig{SpareNodes: 1, SpareCPUPercent: 20}))
+	assert.Error(t, validateHeadroom(&v1alpha1.HeadroomConfig{SpareNodes: -1}))
+	assert.Error(t, validateHeadroom(&v1alpha1.HeadroomConfig{SpareCPUPercent: 101}))
+	assert.Error(t, validateHeadroom(&v1alpha1.HeadroomConfig{SpareMemoryPercent: -5}))
+}
+
+func TestReconcileHeadroom(t *testing.T) {
+	newNode := func(name string, unschedulable bool) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{NodeGroupNameLabelKey: "test-ng"},
+			},
+			Spec: corev1.NodeSpec{Unschedulable: unschedulable},
+			Status: corev1.NodeStatus{
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("8Gi"),
+				},
+			},
+		}
+	}
+	newPod := func(name, nodeName, cpu string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.PodSpec{
+				NodeName: nodeName,
+				Containers: []corev1.Container{{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse(cpu),
+							corev1.ResourceMemory: resource.MustParse("1Gi"),
+						},
+					},
+				}},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	k8sClient := ctrlclient.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			newNode("node-1", false),
+			newNode("node-2", false),
+			// Cordoned nodes are not counted
+			newNode("node-3", true),
+			newPod("pod-1", "node-1", "3", corev1.PodRunning),
+			newPod("pod-2", "node-2", "3", corev1.PodRunning),
+			newPod("pod-3", "node-3", "3", corev1.PodRunning),
+			newPod("done", "node-1", "1", corev1.PodSucceeded),
+		).
+		WithIndex(&corev1.Pod{}, "spec.nodeName", func(obj client.Object) []string {
+			return []string{obj.(*corev1.Pod).Spec.NodeName}
+		}).
+		Build()
+
+	reconciler := &NodeGroupReconciler{Client: k8sClient, Scheme: scheme, Logger: zap.NewNop()}
+
+	capacity, err := reconciler.getNodeGroupCapacity(context.Background(), newHeadroomNodeGroup(nil))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), capacity.Nodes)
+	assert.Equal(t, int64(8000), capacity.AllocatableCPU)
+	assert.Equal(t, int64(6000), capacity.RequestedCPU)
+
+	ng := newHeadroomNodeGroup(&v1alpha1.HeadroomConfig{SpareNodes: 1})
+	target, ok := reconciler.reconcileHeadroom(context.Background(), ng, zap.NewNop())
+	assert.True(t, ok)
+	assert.Equal(t, int32(3), target)
+
+	// Without headroom there is no target
+	ng.Spec.Headroom = nil
+	target, ok = reconciler.reconcileHeadroom(context.Background(), ng, zap.NewNop())
+	assert.True(t, ok)
+	assert.Equal(t, int32(0), target)
+}
+
+func TestApplyHeadroom(t *testing.T) {
+	ng := newHeadroomNodeGroup(&v1alpha1.HeadroomConfig{SpareNodes: 1})
+	ng.Status.DesiredNodes = 2
+
+	// The target raises desired and the raise is recorded
+	desired := applyHeadroom(ng, baseDesiredNodes(ng), 4)
+	assert.Equal(t, int32(4), desired)
+	assert.Equal(t, int32(2), ng.Status.HeadroomNodes)
+	ng.Status.DesiredNodes = desired
+
+	// A lower target releases the nodes instead of ratcheting
+	assert.Equal(t, int32(2), baseDesiredNodes(ng))
+	desired = applyHeadroom(ng, baseDesiredNodes(ng), 3)
+	assert.Equal(t, int32(3), desired)
+	assert.Equal(t, int32(1), ng.Status.HeadroomNodes)
+	ng.Status.DesiredNodes = desired
+
+	// Nodes added for pending pods stay in the base
+	ng.Status.DesiredNodes += 2
+	desired = applyHeadroom(ng, baseDesiredNodes(ng), 3)
+	assert.Equal(t, int32(4), desired)
+	assert.Equal(t, int32(0), ng.Status.HeadroomNodes)
+	ng.Status.DesiredNodes = desired
+
+	// Removing the headroom leaves the base
+	assert.Equal(t, int32(4), applyHeadroom(ng, baseDesiredNodes(ng), 0))
+	assert.Equal(t, int32(0), ng.Status.HeadroomNodes)
+}
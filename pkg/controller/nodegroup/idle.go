@@ -0,0 +1,106 @@
+package nodegroup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/drain"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
+)
+
+// isNodeGroupIdle returns true if none of the NodeGroup's nodes run workload
+// pods and no scale-up is in progress
+func (r *NodeGroupReconciler) isNodeGroupIdle(ctx context.Context, ng *v1alpha1.NodeGroup) (bool, error) {
+	if ng.Status.DesiredNodes > ng.Status.CurrentNodes {
+		return false, nil
+	}
+
+	nodeList := &corev1.NodeList{}
+	if err := r.List(ctx, nodeList, client.MatchingLabels{NodeGroupNameLabelKey: ng.Name}); err != nil {
+		return false, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	if len(nodeList.Items) == 0 {
+		return true, nil
+	}
+
+	for i := range nodeList.Items {
+		podList := &corev1.PodList{}
+		if err := r.List(ctx, podList, client.MatchingFields{"spec.nodeName": nodeList.Items[i].Name}); err != nil {
+			return false, fmt.Errorf("failed to list pods: %w", err)
+		}
+		for j := range podList.Items {
+			if drain.IsWorkloadPod(&podList.Items[j]) {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// reconcileIdleTTL garbage-collects an auto-managed NodeGroup that stayed idle
+// for its idle TTL. The time it became idle is kept in Status.IdleSince.
+// Deleting the NodeGroup deletes its VPSieNodes, which drain their nodes
+// before the VPS is terminated. It returns true if the NodeGroup was deleted.
+func (r *NodeGroupReconciler) reconcileIdleTTL(ctx context.Context, ng *v1alpha1.NodeGroup, now time.Time, logger *zap.Logger) (bool, error) {
+	ttl, ok := v1alpha1.NodeGroupIdleTTL(ng)
+	if !ok {
+		ng.Status.IdleSince = nil
+		return false, nil
+	}
+
+	idle, err := r.isNodeGroupIdle(ctx, ng)
+	if err != nil {
+		return false, err
+	}
+	if !idle {
+		if ng.Status.IdleSince != nil {
+			logger.Info("Auto-managed NodeGroup is in use again",
+				zap.Time("idleSince", ng.Status.IdleSince.Time))
+		}
+		ng.Status.IdleSince = nil
+		return false, nil
+	}
+
+	if ng.Status.IdleSince == nil {
+		logger.Info("Auto-managed NodeGroup became idle", zap.Duration("idleTTL", ttl))
+		idleSince := metav1.NewTime(now)
+		ng.Status.IdleSince = &idleSince
+		return false, nil
+	}
+
+	idleFor := now.Sub(ng.Status.IdleSince.Time)
+	if idleFor < ttl {
+		logger.Debug("Auto-managed NodeGroup idle TTL not expired",
+			zap.Duration("idleFor", idleFor),
+			zap.Duration("idleTTL", ttl),
+		)
+		return false, nil
+	}
+
+	logger.Info("Auto-managed NodeGroup idle TTL expired, deleting",
+		zap.Duration("idleFor", idleFor),
+		zap.Duration("idleTTL", ttl),
+		zap.Int32("currentNodes", ng.Status.CurrentNodes),
+	)
+	r.Recorder.Eventf(ng, corev1.EventTypeNormal, "IdleTTLExpired",
+		"Deleting auto-managed NodeGroup idle for %s (TTL %s)", idleFor.Round(time.Second), ttl)
+
+	if err := r.Delete(ctx, ng); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to delete idle NodeGroup: %w", err)
+	}
+	metrics.DynamicNodeGroupDeletionsTotal.WithLabelValues(ng.Namespace).Inc()
+
+	return true, nil
+}
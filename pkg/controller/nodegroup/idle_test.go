@@ -0,0 +1,141 @@
+package nodegroup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+)
+
+func newAutoManagedNodeGroup(ttl string) *v1alpha1.NodeGroup {
+	return &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "auto-ng",
+			Namespace: "kube-system",
+			Labels:    map[string]string{v1alpha1.ManagedLabelKey: v1alpha1.ManagedLabelValue},
+			Annotations: map[string]string{
+				v1alpha1.AutoManagedAnnotationKey: v1alpha1.AutoManagedAnnotationValue,
+				v1alpha1.IdleTTLAnnotationKey:     ttl,
+			},
+		},
+		Spec: v1alpha1.NodeGroupSpec{MinNodes: 1, MaxNodes: 5},
+		Status: v1alpha1.NodeGroupStatus{
+			CurrentNodes: 1,
+			DesiredNodes: 1,
+		},
+	}
+}
+
+func newIdleReconciler(t *testing.T, objects ...client.Object) *NodeGroupReconciler {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	k8sClient := ctrlclient.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).
+		WithIndex(&corev1.Pod{}, "spec.nodeName", func(obj client.Object) []string {
+			return []string{obj.(*corev1.Pod).Spec.NodeName}
+		}).
+		Build()
+	return &NodeGroupReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Logger:   zap.NewNop(),
+		Recorder: record.NewFakeRecorder(10),
+	}
+}
+
+func TestReconcileIdleTTL(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   "auto-node-1",
+		Labels: map[string]string{NodeGroupNameLabelKey: "auto-ng"},
+	}}
+	daemon := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "kube-proxy-abc",
+			Namespace:       "kube-system",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: "kube-proxy"}},
+		},
+		Spec: corev1.PodSpec{NodeName: "auto-node-1"},
+	}
+	workload := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "auto-node-1"},
+	}
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	t.Run("busy NodeGroup is kept", func(t *testing.T) {
+		ng := newAutoManagedNodeGroup("30m")
+		ng.Status.IdleSince = &metav1.Time{Time: now.Add(-time.Hour)}
+		r := newIdleReconciler(t, ng, node, daemon, workload)
+
+		deleted, err := r.reconcileIdleTTL(ctx, ng, now, zap.NewNop())
+		require.NoError(t, err)
+		assert.False(t, deleted)
+		assert.Nil(t, ng.Status.IdleSince)
+	})
+
+	t.Run("idle NodeGroup is deleted after its TTL", func(t *testing.T) {
+		ng := newAutoManagedNodeGroup("30m")
+		r := newIdleReconciler(t, ng, node, daemon)
+
+		deleted, err := r.reconcileIdleTTL(ctx, ng, now, zap.NewNop())
+		require.NoError(t, err)
+		assert.False(t, deleted)
+		require.NotNil(t, ng.Status.IdleSince)
+		assert.True(t, ng.Status.IdleSince.Time.Equal(now))
+
+		deleted, err = r.reconcileIdleTTL(ctx, ng, now.Add(29*time.Minute), zap.NewNop())
+		require.NoError(t, err)
+		assert.False(t, deleted)
+
+		deleted, err = r.reconcileIdleTTL(ctx, ng, now.Add(30*time.Minute), zap.NewNop())
+		require.NoError(t, err)
+		assert.True(t, deleted)
+
+		err = r.Get(ctx, client.ObjectKeyFromObject(ng), &v1alpha1.NodeGroup{})
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("scale-up in progress is not idle", func(t *testing.T) {
+		ng := newAutoManagedNodeGroup("30m")
+		ng.Status.DesiredNodes = 2
+		r := newIdleReconciler(t, ng)
+
+		deleted, err := r.reconcileIdleTTL(ctx, ng, now, zap.NewNop())
+		require.NoError(t, err)
+		assert.False(t, deleted)
+		assert.Nil(t, ng.Status.IdleSince)
+	})
+
+	t.Run("NodeGroups without an idle TTL are never collected", func(t *testing.T) {
+		for name, ng := range map[string]*v1alpha1.NodeGroup{
+			"invalid TTL": newAutoManagedNodeGroup("soon"),
+			"not auto-managed": {
+				ObjectMeta: metav1.ObjectMeta{Name: "auto-ng", Namespace: "kube-system"},
+			},
+		} {
+			ng.Status.IdleSince = &metav1.Time{Time: now.Add(-24 * time.Hour)}
+			r := newIdleReconciler(t, ng)
+
+			deleted, err := r.reconcileIdleTTL(ctx, ng, now, zap.NewNop())
+			require.NoError(t, err, name)
+			assert.False(t, deleted, name)
+			assert.Nil(t, ng.Status.IdleSince, name)
+		}
+	})
+}
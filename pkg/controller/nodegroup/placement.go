@@ -0,0 +1,333 @@
+package nodegroup
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
+	vpsieclient "github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/client"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
+)
+
+const (
+	// DistributionBalanced spreads nodes evenly across datacenters
+	DistributionBalanced = "balanced"
+
+	// DistributionWeighted spreads nodes according to WeightedDistribution
+	DistributionWeighted = "weighted"
+
+	// DistributionPrimaryBackup places nodes in the primary datacenter
+	DistributionPrimaryBackup = "primary-backup"
+
+	// DistributionCarbonAware places nodes in the datacenter ranked best by CarbonObjective
+	DistributionCarbonAware = "carbon-aware"
+)
+
+// selectDatacenter chooses the datacenter for the next node of a NodeGroup.
+// Without multi-region distribution this is always Spec.DatacenterID. Otherwise
+// datacenters below MinNodesPerRegion are filled first, then the distribution
+// strategy narrows the candidates and the CarbonObjective picks among them.
+// Only datacenters with a VPSie node group to provision into are considered.
+func (r *NodeGroupReconciler) selectDatacenter(ng *v1alpha1.NodeGroup, vpsieNodes []v1alpha1.VPSieNode) string {
+	multiRegion := ng.Spec.MultiRegion
+	if multiRegion == nil || !multiRegion.Enabled {
+		return ng.Spec.DatacenterID
+	}
+	datacenters := placeableDatacenters(ng)
+	if len(datacenters) == 0 {
+		return ng.Spec.DatacenterID
+	}
+
+	counts := make(map[string]int32, len(datacenters))
+	for i := range vpsieNodes {
+		counts[vpsieNodes[i].Spec.DatacenterID]++
+	}
+	byCount := func(dc string) float64 {
+		return float64(counts[dc])
+	}
+
+	var candidates []string
+	for _, dc := range datacenters {
+		if counts[dc] < multiRegion.MinNodesPerRegion {
+			candidates = append(candidates, dc)
+		}
+	}
+
+	objective, err := cost.ParseCarbonObjective(multiRegion.CarbonObjective)
+	if err != nil {
+		objective = cost.CarbonObjectiveCost
+	}
+
+	if len(candidates) == 0 {
+		switch multiRegion.DistributionStrategy {
+		case DistributionPrimaryBackup:
+			if slices.Contains(datacenters, multiRegion.PrimaryDatacenter) {
+				return multiRegion.PrimaryDatacenter
+			}
+			candidates = datacenters
+		case DistributionWeighted:
+			candidates = leastLoaded(datacenters, func(dc string) float64 {
+				weight := multiRegion.WeightedDistribution[dc]
+				if weight <= 0 {
+					weight = 1
+				}
+				return float64(counts[dc]) / float64(weight)
+			})
+		case DistributionCarbonAware:
+			if r.CarbonIntensity == nil {
+				candidates = leastLoaded(datacenters, byCount)
+				break
+			}
+			candidates = datacenters
+			// The strategy asks for carbon to be weighed even when the
+			// objective is left at cost
+			if objective == cost.CarbonObjectiveCost {
+				objective = cost.CarbonObjectiveLowCarbon
+			}
+		default:
+			candidates = leastLoaded(datacenters, byCount)
+		}
+	}
+
+	if len(candidates) == 1 || r.CarbonIntensity == nil {
+		return candidates[0]
+	}
+
+	// Nodes of a NodeGroup share a size and price, so only intensity differs
+	specs := nodeGroupSpecs(vpsieNodes)
+	placements := make([]cost.PlacementCandidate, 0, len(candidates))
+	for _, dc := range candidates {
+		placements = append(placements, cost.PlacementCandidate{DatacenterID: dc, Specs: specs})
+	}
+	best := cost.RankPlacements(r.CarbonIntensity, objective, placements)[0]
+
+	datacenter, _ := metrics.SanitizeLabel(best.DatacenterID)
+	metrics.CarbonPlacementDecisionsTotal.WithLabelValues("multi_region", string(objective), datacenter).Inc()
+
+	return best.DatacenterID
+}
+
+// placeableDatacenters returns the multi-region datacenters the NodeGroup's
+// nodes can be provisioned in, in their configured order
+func placeableDatacenters(ng *v1alpha1.NodeGroup) []string {
+	var datacenters []string
+	for _, dc := range ng.Spec.MultiRegion.DatacenterIDs {
+		if _, ok := datacenterGroupID(ng, dc); ok {
+			datacenters = append(datacenters, dc)
+		}
+	}
+	return datacenters
+}
+
+// datacenterGroupID returns the numeric ID of the VPSie node group that
+// provisions the NodeGroup's nodes in the datacenter
+func datacenterGroupID(ng *v1alpha1.NodeGroup, datacenterID string) (int, bool) {
+	if datacenterID == ng.Spec.DatacenterID {
+		return ng.Status.VPSieGroupID, true
+	}
+	id, ok := ng.Status.DatacenterGroups[datacenterID]
+	return id, ok
+}
+
+// resolveDatacenterGroups finds or creates the VPSie node group of every
+// multi-region datacenter other than Spec.DatacenterID and records it in
+// Status.DatacenterGroups. Nodes are added to a VPSie node group rather than
+// created in a datacenter, and a group's datacenter follows from its
+// Kubernetes size, so each datacenter gets a group of its offer matching the
+// size of KubeSizeID. Datacenters whose group can't be resolved are logged
+// and left out of placement.
+func (r *NodeGroupReconciler) resolveDatacenterGroups(ctx context.Context, ng *v1alpha1.NodeGroup, logger *zap.Logger) {
+	multiRegion := ng.Spec.MultiRegion
+	if multiRegion == nil || !multiRegion.Enabled || r.VPSieClient == nil {
+		return
+	}
+
+	var missing []string
+	for _, dc := range multiRegion.DatacenterIDs {
+		if _, ok := datacenterGroupID(ng, dc); !ok {
+			missing = append(missing, dc)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	groups, err := r.VPSieClient.ListK8sNodeGroups(ctx, ng.Spec.ResourceIdentifier)
+	if err != nil {
+		logger.Warn("Failed to list VPSie node groups for multi-region placement", zap.Error(err))
+		return
+	}
+
+	var size *vpsieclient.K8sOffer
+	created := false
+	for _, dc := range missing {
+		if findGroupID(groups, datacenterGroupName(ng, dc)) != 0 {
+			continue
+		}
+		if size == nil {
+			if size, err = r.kubeSizeOffer(ctx, ng.Spec.DatacenterID, ng.Spec.KubeSizeID); err != nil {
+				logger.Warn("Failed to find the NodeGroup's Kubernetes size", zap.Error(err))
+				return
+			}
+		}
+		if err := r.createDatacenterGroup(ctx, ng, dc, size); err != nil {
+			logger.Warn("Failed to create VPSie node group for datacenter, not placing nodes there",
+				zap.String("datacenter", dc),
+				zap.Error(err),
+			)
+			continue
+		}
+		created = true
+	}
+
+	if created {
+		if groups, err = r.VPSieClient.ListK8sNodeGroups(ctx, ng.Spec.ResourceIdentifier); err != nil {
+			logger.Warn("Failed to list VPSie node groups for multi-region placement", zap.Error(err))
+			return
+		}
+	}
+
+	for _, dc := range missing {
+		id := findGroupID(groups, datacenterGroupName(ng, dc))
+		if id == 0 {
+			continue
+		}
+		if ng.Status.DatacenterGroups == nil {
+			ng.Status.DatacenterGroups = make(map[string]int)
+		}
+		ng.Status.DatacenterGroups[dc] = id
+		logger.Info("Resolved VPSie node group for datacenter",
+			zap.String("datacenter", dc),
+			zap.Int("vpsieGroupID", id),
+		)
+	}
+}
+
+// createDatacenterGroup creates the NodeGroup's VPSie node group in the
+// datacenter, using the cheapest offer there with the CPU, memory and disk
+// of size
+func (r *NodeGroupReconciler) createDatacenterGroup(ctx context.Context, ng *v1alpha1.NodeGroup, datacenterID string, size *vpsieclient.K8sOffer) error {
+	offers, err := r.VPSieClient.ListK8sOffers(ctx, datacenterID)
+	if err != nil {
+		return fmt.Errorf("list K8s offers: %w", err)
+	}
+
+	var match *vpsieclient.K8sOffer
+	for i := range offers {
+		offer := &offers[i]
+		if offer.CPU != size.CPU || offer.RAM != size.RAM || offer.Disk != size.Disk {
+			continue
+		}
+		if match == nil || offer.Price < match.Price {
+			match = offer
+		}
+	}
+	if match == nil {
+		return fmt.Errorf("no K8s offer with %d CPU, %d MB RAM and %d GB disk", size.CPU, size.RAM, size.Disk)
+	}
+
+	req := vpsieclient.CreateK8sNodeGroupRequest{
+		ClusterIdentifier: ng.Spec.ResourceIdentifier,
+		GroupName:         datacenterGroupName(ng, datacenterID),
+		KubeSizeID:        match.ID,
+	}
+	if _, err := r.VPSieClient.CreateK8sNodeGroup(ctx, req); err != nil {
+		return fmt.Errorf("create VPSie node group %s: %w", req.GroupName, err)
+	}
+	return nil
+}
+
+// kubeSizeOffer returns the K8s offer of the Kubernetes size in the datacenter
+func (r *NodeGroupReconciler) kubeSizeOffer(ctx context.Context, datacenterID string, kubeSizeID int) (*vpsieclient.K8sOffer, error) {
+	offers, err := r.VPSieClient.ListK8sOffers(ctx, datacenterID)
+	if err != nil {
+		return nil, fmt.Errorf("list K8s offers: %w", err)
+	}
+	for i := range offers {
+		if offers[i].ID == kubeSizeID {
+			return &offers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no K8s offer %d in datacenter %s", kubeSizeID, datacenterID)
+}
+
+// datacenterGroupName returns the name of the NodeGroup's VPSie node group in
+// a datacenter other than Spec.DatacenterID
+func datacenterGroupName(ng *v1alpha1.NodeGroup, datacenterID string) string {
+	return fmt.Sprintf("%s-%s", ng.Name, datacenterID)
+}
+
+// findGroupID returns the numeric ID of the named VPSie node group, or 0
+func findGroupID(groups []vpsieclient.K8sNodeGroup, name string) int {
+	for _, group := range groups {
+		if group.GroupName == name {
+			return group.ID
+		}
+	}
+	return 0
+}
+
+// leastLoaded returns the datacenters with the lowest load, keeping their order
+func leastLoaded(datacenters []string, load func(string) float64) []string {
+	var result []string
+	var lowest float64
+	for _, dc := range datacenters {
+		l := load(dc)
+		switch {
+		case len(result) == 0 || l < lowest:
+			result = []string{dc}
+			lowest = l
+		case l == lowest:
+			result = append(result, dc)
+		}
+	}
+	return result
+}
+
+// nodeGroupSpecs returns the resources of the NodeGroup's first provisioned node,
+// or a single vCPU when no node has reported its resources yet
+func nodeGroupSpecs(vpsieNodes []v1alpha1.VPSieNode) cost.ResourceSpecs {
+	for i := range vpsieNodes {
+		if res := vpsieNodes[i].Status.Resources; res.CPU > 0 {
+			return cost.ResourceSpecs{CPU: res.CPU, MemoryMB: res.MemoryMB, DiskGB: res.DiskGB}
+		}
+	}
+	return cost.ResourceSpecs{CPU: 1}
+}
+
+// recordEmissions publishes the estimated emissions of the NodeGroup's nodes per datacenter
+func (r *NodeGroupReconciler) recordEmissions(ng *v1alpha1.NodeGroup, vpsieNodes []v1alpha1.VPSieNode) {
+	if r.CarbonIntensity == nil {
+		return
+	}
+
+	nodegroup, _ := metrics.SanitizeLabel(ng.Name)
+	namespace, _ := metrics.SanitizeLabel(ng.Namespace)
+
+	emissions := make(map[string]float64)
+	for i := range vpsieNodes {
+		vn := &vpsieNodes[i]
+		res := vn.Status.Resources
+		grams, ok := cost.EstimateEmissions(r.CarbonIntensity, vn.Spec.DatacenterID,
+			cost.ResourceSpecs{CPU: res.CPU, MemoryMB: res.MemoryMB, DiskGB: res.DiskGB})
+		if !ok {
+			continue
+		}
+		emissions[vn.Spec.DatacenterID] += grams
+	}
+
+	// Drop datacenters the NodeGroup no longer has nodes in
+	metrics.NodeGroupEstimatedEmissions.DeletePartialMatch(prometheus.Labels{
+		"nodegroup": nodegroup,
+		"namespace": namespace,
+	})
+	for dc, grams := range emissions {
+		datacenter, _ := metrics.SanitizeLabel(dc)
+		metrics.NodeGroupEstimatedEmissions.WithLabelValues(nodegroup, namespace, datacenter).Set(grams)
+	}
+}
@@ -0,0 +1,206 @@
+package nodegroup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
+)
+
+func newPlacementNodeGroup(multiRegion *v1alpha1.MultiRegionConfig) *v1alpha1.NodeGroup {
+	return &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ng", Namespace: "default"},
+		Spec: v1alpha1.NodeGroupSpec{
+			DatacenterID: "dc-1",
+			OfferingIDs:  []string{"offering-1"},
+			MultiRegion:  multiRegion,
+		},
+		Status: v1alpha1.NodeGroupStatus{
+			VPSieGroupID:     1,
+			DatacenterGroups: map[string]int{"dc-2": 2, "dc-3": 3},
+		},
+	}
+}
+
+func newPlacementNodes(datacenters ...string) []v1alpha1.VPSieNode {
+	nodes := make([]v1alpha1.VPSieNode, 0, len(datacenters))
+	for _, dc := range datacenters {
+		nodes = append(nodes, v1alpha1.VPSieNode{
+			Spec: v1alpha1.VPSieNodeSpec{DatacenterID: dc},
+			Status: v1alpha1.VPSieNodeStatus{
+				Resources: v1alpha1.NodeResources{CPU: 2, MemoryMB: 4096},
+			},
+		})
+	}
+	return nodes
+}
+
+func TestSelectDatacenter(t *testing.T) {
+	table, err := cost.ParseCarbonIntensityTable([]byte(`
+version: test
+datacenters:
+  - {id: dc-1, intensity: 500}
+  - {id: dc-2, intensity: 50}
+  - {id: dc-3, intensity: 300}
+`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		multiRegion *v1alpha1.MultiRegionConfig
+		nodes       []v1alpha1.VPSieNode
+		carbon      bool
+		expected    string
+	}{
+		{
+			name:     "multi-region disabled uses spec datacenter",
+			expected: "dc-1",
+		},
+		{
+			name: "fills datacenters below the per-region minimum first",
+			multiRegion: &v1alpha1.MultiRegionConfig{
+				Enabled:           true,
+				DatacenterIDs:     []string{"dc-1", "dc-2"},
+				MinNodesPerRegion: 1,
+			},
+			nodes:    newPlacementNodes("dc-1"),
+			expected: "dc-2",
+		},
+		{
+			name: "balanced picks the least loaded datacenter",
+			multiRegion: &v1alpha1.MultiRegionConfig{
+				Enabled:              true,
+				DatacenterIDs:        []string{"dc-1", "dc-2", "dc-3"},
+				DistributionStrategy: DistributionBalanced,
+			},
+			nodes:    newPlacementNodes("dc-1", "dc-2"),
+			expected: "dc-3",
+		},
+		{
+			name: "weighted divides load by weight",
+			multiRegion: &v1alpha1.MultiRegionConfig{
+				Enabled:              true,
+				DatacenterIDs:        []string{"dc-1", "dc-2"},
+				DistributionStrategy: DistributionWeighted,
+				WeightedDistribution: map[string]int32{"dc-1": 3, "dc-2": 1},
+			},
+			nodes:    newPlacementNodes("dc-1", "dc-1", "dc-2"),
+			expected: "dc-1",
+		},
+		{
+			name: "primary-backup uses the primary datacenter",
+			multiRegion: &v1alpha1.MultiRegionConfig{
+				Enabled:              true,
+				DatacenterIDs:        []string{"dc-1", "dc-2"},
+				DistributionStrategy: DistributionPrimaryBackup,
+				PrimaryDatacenter:    "dc-2",
+			},
+			expected: "dc-2",
+		},
+		{
+			name: "balanced ties are broken by carbon objective",
+			multiRegion: &v1alpha1.MultiRegionConfig{
+				Enabled:              true,
+				DatacenterIDs:        []string{"dc-1", "dc-3", "dc-2"},
+				DistributionStrategy: DistributionBalanced,
+				CarbonObjective:      "low-carbon",
+			},
+			carbon:   true,
+			expected: "dc-2",
+		},
+		{
+			name: "carbon-aware places beyond the minimum in the lowest-carbon datacenter",
+			multiRegion: &v1alpha1.MultiRegionConfig{
+				Enabled:              true,
+				DatacenterIDs:        []string{"dc-1", "dc-2", "dc-3"},
+				DistributionStrategy: DistributionCarbonAware,
+				CarbonObjective:      "balanced",
+				MinNodesPerRegion:    1,
+			},
+			nodes:    newPlacementNodes("dc-1", "dc-2", "dc-2", "dc-3"),
+			carbon:   true,
+			expected: "dc-2",
+		},
+		{
+			name: "carbon-aware ranks by intensity under the cost objective",
+			multiRegion: &v1alpha1.MultiRegionConfig{
+				Enabled:              true,
+				DatacenterIDs:        []string{"dc-1", "dc-2"},
+				DistributionStrategy: DistributionCarbonAware,
+				CarbonObjective:      "cost",
+			},
+			carbon:   true,
+			expected: "dc-2",
+		},
+		{
+			name: "carbon-aware without intensities balances",
+			multiRegion: &v1alpha1.MultiRegionConfig{
+				Enabled:              true,
+				DatacenterIDs:        []string{"dc-1", "dc-2"},
+				DistributionStrategy: DistributionCarbonAware,
+			},
+			nodes:    newPlacementNodes("dc-1"),
+			expected: "dc-2",
+		},
+		{
+			name: "balanced cost ties keep datacenter order",
+			multiRegion: &v1alpha1.MultiRegionConfig{
+				Enabled:              true,
+				DatacenterIDs:        []string{"dc-1", "dc-2"},
+				DistributionStrategy: DistributionBalanced,
+				CarbonObjective:      "cost",
+			},
+			carbon:   true,
+			expected: "dc-1",
+		},
+		{
+			name: "datacenters without a VPSie node group are skipped",
+			multiRegion: &v1alpha1.MultiRegionConfig{
+				Enabled:           true,
+				DatacenterIDs:     []string{"dc-1", "dc-4"},
+				MinNodesPerRegion: 1,
+			},
+			nodes:    newPlacementNodes("dc-1"),
+			expected: "dc-1",
+		},
+		{
+			name: "primary without a VPSie node group falls back to the first datacenter",
+			multiRegion: &v1alpha1.MultiRegionConfig{
+				Enabled:              true,
+				DatacenterIDs:        []string{"dc-4", "dc-3", "dc-2"},
+				DistributionStrategy: DistributionPrimaryBackup,
+				PrimaryDatacenter:    "dc-4",
+			},
+			expected: "dc-3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &NodeGroupReconciler{}
+			if tt.carbon {
+				r.CarbonIntensity = table
+			}
+			assert.Equal(t, tt.expected, r.selectDatacenter(newPlacementNodeGroup(tt.multiRegion), tt.nodes))
+		})
+	}
+}
+
+func TestDatacenterGroupID(t *testing.T) {
+	ng := newPlacementNodeGroup(nil)
+
+	id, ok := datacenterGroupID(ng, "dc-1")
+	assert.True(t, ok)
+	assert.Equal(t, 1, id)
+
+	id, ok = datacenterGroupID(ng, "dc-3")
+	assert.True(t, ok)
+	assert.Equal(t, 3, id)
+
+	_, ok = datacenterGroupID(ng, "dc-4")
+	assert.False(t, ok)
+}
@@ -16,7 +16,9 @@ import (
 
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/scaler"
 	vpsieclient "github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/client"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
 )
 
 const (
@@ -96,6 +98,7 @@ func (r *NodeGroupReconciler) reconcile(ctx context.Context, ng *v1alpha1.NodeGr
 	logger.Info("Found VPSieNodes",
 		zap.Int("count", len(vpsieNodes)),
 	)
+	r.recordEmissions(ng, vpsieNodes)
 
 	// Update status with current state BEFORE creating patch
 	if err := UpdateNodeGroupStatus(ctx, r.Client, ng, vpsieNodes); err != nil {
@@ -103,8 +106,71 @@ func (r *NodeGroupReconciler) reconcile(ctx context.Context, ng *v1alpha1.NodeGr
 		return ctrl.Result{}, err
 	}
 
-	// Calculate desired nodes
+	// Garbage-collect auto-managed NodeGroups that stayed idle for their TTL
+	now := time.Now()
+	deleted, err := r.reconcileIdleTTL(ctx, ng, now, logger)
+	if err != nil {
+		logger.Warn("Failed to evaluate idle TTL", zap.Error(err))
+	}
+	if deleted {
+		return ctrl.Result{}, nil
+	}
+
+	// Apply the scaling schedule whose window is open. Its bounds replace the
+	// spec bounds for all scaling and its DesiredNodes is set once per window.
+	previousSchedule := ng.Status.ActiveSchedule
+	openedSchedule, err := updateActiveSchedule(ng, now)
+	if err != nil {
+		logger.Error("Failed to evaluate scaling schedules", zap.Error(err))
+	}
+	if openedSchedule != nil {
+		logger.Info("Scaling schedule window opened",
+			zap.String("schedule", openedSchedule.Name),
+			zap.Int32("min", ng.Status.ActiveSchedule.MinNodes),
+			zap.Int32("max", ng.Status.ActiveSchedule.MaxNodes),
+			zap.Time("endTime", ng.Status.ActiveSchedule.EndTime.Time),
+		)
+		r.Recorder.Eventf(ng, corev1.EventTypeNormal, "ScheduleStarted",
+			"Scaling schedule %q active until %s (min %d, max %d)", openedSchedule.Name,
+			ng.Status.ActiveSchedule.EndTime.UTC().Format(time.RFC3339),
+			ng.Status.ActiveSchedule.MinNodes, ng.Status.ActiveSchedule.MaxNodes)
+	} else if previousSchedule != nil && ng.Status.ActiveSchedule == nil {
+		logger.Info("Scaling schedule window closed", zap.String("schedule", previousSchedule.Name))
+		r.Recorder.Eventf(ng, corev1.EventTypeNormal, "ScheduleEnded",
+			"Scaling schedule %q ended", previousSchedule.Name)
+	}
+
+	// Calculate desired nodes, raising them to keep the configured headroom free.
+	// If the capacity is unknown the previous headroom nodes are kept.
 	desired := CalculateDesiredNodes(ng)
+	headroomTarget, headroomKnown := r.reconcileHeadroom(ctx, ng, logger)
+	if headroomKnown {
+		desired = baseDesiredNodes(ng)
+	}
+	if openedSchedule != nil && openedSchedule.DesiredNodes != nil {
+		desired = ClampNodes(ng, *openedSchedule.DesiredNodes)
+	}
+	if headroomKnown {
+		if headroomTarget > desired {
+			logger.Info("Raising desired node count to maintain headroom",
+				zap.Int32("desired", desired),
+				zap.Int32("headroomTarget", headroomTarget),
+			)
+		}
+		desired = applyHeadroom(ng, desired, headroomTarget)
+	}
+	predictedNodes := ActivePrediction(ng, now)
+	if predictedNodes > desired {
+		logger.Info("Raising desired node count to predicted demand",
+			zap.Int32("desired", desired),
+			zap.Int32("predicted", predictedNodes),
+		)
+		desired = predictedNodes
+	}
+	floorNodes := headroomTarget
+	if predictedNodes > floorNodes {
+		floorNodes = predictedNodes
+	}
 	if ng.Status.DesiredNodes != desired {
 		SetDesiredNodes(ng, desired)
 		logger.Info("Updated desired node count",
@@ -120,7 +186,21 @@ func (r *NodeGroupReconciler) reconcile(ctx context.Context, ng *v1alpha1.NodeGr
 	var result ctrl.Result
 	var reconcileErr error
 
-	if needsScaleUp {
+	if ng.Status.Consolidation != nil && needsScaleUp &&
+		ng.Status.Consolidation.Phase == v1alpha1.ConsolidationPhaseProvisioning {
+		// Demand grew past the nodes the consolidation provisions: abandon it
+		// rather than hold the scale-up off until the replacements are Ready.
+		// The scale-up runs once the replacements are gone from the count.
+		needsScaleDown = false
+		result, reconcileErr = r.abortConsolidation(ctx, ng, "more nodes are needed for pending pods", logger)
+		result.RequeueAfter = FastRequeueAfter
+	} else if ng.Status.Consolidation != nil {
+		// A consolidation in progress owns the node count until it completes.
+		// While draining, the retiring nodes being deleted are no longer
+		// counted, which would otherwise look like a need to scale up.
+		needsScaleUp, needsScaleDown = false, false
+		result, reconcileErr = r.reconcileConsolidation(ctx, ng, vpsieNodes, logger)
+	} else if needsScaleUp {
 		logger.Info("Scaling up",
 			zap.Int32("current", ng.Status.CurrentNodes),
 			zap.Int32("desired", ng.Status.DesiredNodes),
@@ -141,17 +221,31 @@ func (r *NodeGroupReconciler) reconcile(ctx context.Context, ng *v1alpha1.NodeGr
 	} else {
 		// No explicit scaling needed - check if utilization-based scale-down should trigger
 		if r.ScaleDownManager != nil && ng.Spec.ScaleDownPolicy.Enabled &&
-			ng.Status.CurrentNodes > ng.Spec.MinNodes {
-			// Evaluate if we should scale down based on utilization
-			if shouldScaleDown, nodesToRemove := r.evaluateUtilizationBasedScaleDown(ctx, ng, logger); shouldScaleDown {
+			ng.Status.CurrentNodes > v1alpha1.EffectiveMinNodes(ng) {
+			// Empty nodes are removed in bulk first, without waiting for utilization samples
+			if removed, err := r.reconcileEmptyNodeScaleDown(ctx, ng, vpsieNodes, floorNodes, logger); removed > 0 || err != nil {
+				if removed > 0 {
+					newDesired := ng.Status.CurrentNodes - int32(removed)
+					SetDesiredNodes(ng, newDesired)
+					r.Recorder.Eventf(ng, corev1.EventTypeNormal, "ScalingDown",
+						"Empty node scale-down: reducing from %d to %d nodes (-%d nodes)",
+						ng.Status.CurrentNodes, newDesired, removed)
+					needsScaleDown = true // For condition update below
+				}
+				result, reconcileErr = ctrl.Result{RequeueAfter: FastRequeueAfter}, err
+			} else if started, err := r.startConsolidation(ctx, ng, vpsieNodes, floorNodes, logger); started || err != nil {
+				// Underutilized nodes are replaced by fewer larger ones where that is cheaper
+				result, reconcileErr = ctrl.Result{RequeueAfter: FastRequeueAfter}, err
+			} else if shouldScaleDown, nodesToRemove := r.evaluateUtilizationBasedScaleDown(ctx, ng, floorNodes, logger); shouldScaleDown {
+				// Evaluate if we should scale down based on utilization
 				logger.Info("Utilization-based scale-down triggered",
 					zap.Int32("current", ng.Status.CurrentNodes),
 					zap.Int("nodesToRemove", nodesToRemove),
 				)
 				// Reduce DesiredNodes to trigger scale-down
 				newDesired := ng.Status.CurrentNodes - int32(nodesToRemove)
-				if newDesired < ng.Spec.MinNodes {
-					newDesired = ng.Spec.MinNodes
+				if minNodes := v1alpha1.EffectiveMinNodes(ng); newDesired < minNodes {
+					newDesired = minNodes
 				}
 				SetDesiredNodes(ng, newDesired)
 				r.Recorder.Eventf(ng, corev1.EventTypeNormal, "ScalingDown",
@@ -162,7 +256,7 @@ func (r *NodeGroupReconciler) reconcile(ctx context.Context, ng *v1alpha1.NodeGr
 			} else {
 				logger.Debug("No utilization-based scale-down needed",
 					zap.Int32("current", ng.Status.CurrentNodes),
-					zap.Int32("min", ng.Spec.MinNodes),
+					zap.Int32("min", v1alpha1.EffectiveMinNodes(ng)),
 				)
 				result = ctrl.Result{RequeueAfter: DefaultRequeueAfter}
 			}
@@ -291,7 +385,10 @@ func (r *NodeGroupReconciler) reconcileScaleUp(
 		zap.Int32("desiredNodes", ng.Status.DesiredNodes),
 	)
 
+	r.resolveDatacenterGroups(ctx, ng, logger)
 	vpsieNode := r.buildVPSieNode(ng)
+	vpsieNode.Spec.DatacenterID = r.selectDatacenter(ng, vpsieNodes)
+	vpsieNode.Spec.VPSieGroupID, _ = datacenterGroupID(ng, vpsieNode.Spec.DatacenterID)
 
 	// Set owner reference
 	if err := controllerutil.SetControllerReference(ng, vpsieNode, r.Scheme); err != nil {
@@ -384,7 +481,28 @@ func (r *NodeGroupReconciler) reconcileIntelligentScaleDown(
 
 	// After successful drain, delete the corresponding VPSieNode CRs
 	// The VPSieNode controller will handle VM termination and K8s node deletion
+	deleted, deletionErrors := r.deleteDrainedVPSieNodes(ctx, ng, vpsieNodes, candidates, logger)
+	r.recordScaleDownSavings(ctx, ng, deleted, logger)
+
+	logger.Info("Intelligent scale-down completed",
+		zap.Int("nodesDrained", len(candidates)),
+		zap.Int("vpsieNodesDeleted", len(deleted)),
+		zap.Int("deletionsFailed", len(deletionErrors)),
+	)
+
+	// Requeue to verify scale-down progress
+	return ctrl.Result{RequeueAfter: FastRequeueAfter}, nil
+}
 
+// deleteDrainedVPSieNodes deletes the VPSieNodes of the drained candidate
+// nodes. It returns the deleted VPSieNodes and the deletions that failed.
+func (r *NodeGroupReconciler) deleteDrainedVPSieNodes(
+	ctx context.Context,
+	ng *v1alpha1.NodeGroup,
+	vpsieNodes []v1alpha1.VPSieNode,
+	candidates []*scaler.ScaleDownCandidate,
+	logger *zap.Logger,
+) ([]*v1alpha1.VPSieNode, []error) {
 	// Build maps for O(1) lookup instead of O(n*m) nested loops
 	// Map by Status.NodeName (set when node joins K8s cluster)
 	vpsieNodeByNodeName := make(map[string]*v1alpha1.VPSieNode)
@@ -412,7 +530,7 @@ func (r *NodeGroupReconciler) reconcileIntelligentScaleDown(
 		zap.Int("byHostname", len(vpsieNodeByHostname)),
 	)
 
-	deletedCount := 0
+	var deleted []*v1alpha1.VPSieNode
 	var deletionErrors []error
 	for _, candidate := range candidates {
 		nodeName := candidate.Node.Name
@@ -457,7 +575,7 @@ func (r *NodeGroupReconciler) reconcileIntelligentScaleDown(
 			continue
 		}
 
-		deletedCount++
+		deleted = append(deleted, vn)
 	}
 
 	// Log warning if some deletions failed
@@ -468,14 +586,44 @@ func (r *NodeGroupReconciler) reconcileIntelligentScaleDown(
 		)
 	}
 
-	logger.Info("Intelligent scale-down completed",
-		zap.Int("nodesDrained", len(candidates)),
-		zap.Int("vpsieNodesDeleted", deletedCount),
-		zap.Int("deletionsFailed", len(deletionErrors)),
-	)
+	return deleted, deletionErrors
+}
 
-	// Requeue to verify scale-down progress
-	return ctrl.Result{RequeueAfter: FastRequeueAfter}, nil
+// recordScaleDownSavings records removed VPSieNodes in the savings ledger,
+// predicting the savings from their offerings' monthly prices
+func (r *NodeGroupReconciler) recordScaleDownSavings(
+	ctx context.Context,
+	ng *v1alpha1.NodeGroup,
+	removed []*v1alpha1.VPSieNode,
+	logger *zap.Logger,
+) {
+	if r.Ledger == nil || r.Pricer == nil || len(removed) == 0 {
+		return
+	}
+
+	var monthly float64
+	for _, vn := range removed {
+		offering, err := r.Pricer.GetOfferingCost(ctx, vn.Spec.InstanceType)
+		if err != nil {
+			logger.Debug("Not recording scale-down in savings ledger, offering price unknown",
+				zap.String("vpsienode", vn.Name),
+				zap.String("offering", vn.Spec.InstanceType),
+				zap.Error(err),
+			)
+			return
+		}
+		monthly += offering.MonthlyCost
+	}
+
+	_, err := r.Ledger.RecordApplied(ctx, ng.Name, ng.Namespace, &cost.Opportunity{
+		Type:           cost.OptimizationScaleDown,
+		Description:    fmt.Sprintf("Remove %d nodes", len(removed)),
+		MonthlySavings: monthly,
+		AnnualSavings:  monthly * 12,
+	}, time.Now())
+	if err != nil {
+		logger.Warn("Failed to record scale-down in savings ledger", zap.Error(err))
+	}
 }
 
 // reconcileSimpleScaleDown is the fallback simple scale-down (original implementation)
@@ -499,62 +647,125 @@ func (r *NodeGroupReconciler) reconcileSimpleScaleDown(
 	)
 
 	// Find nodes to delete (prefer nodes that are not ready)
-	nodesToDelete := selectNodesToDelete(vpsieNodes, int(nodesToRemove))
+	var price func(offeringID string) (float64, bool)
+	if r.Pricer != nil {
+		price = scaler.OfferingPrices(ctx, r.Pricer)
+	}
+	nodesToDelete := selectNodesToDelete(vpsieNodes, int(nodesToRemove), ng.Spec.ScaleDownPolicy.DeletionOrder, price)
 
 	// Delete selected nodes
-	for _, vn := range nodesToDelete {
+	var deleted []*v1alpha1.VPSieNode
+	for i := range nodesToDelete {
+		vn := &nodesToDelete[i]
 		logger.Info("Deleting VPSieNode",
 			zap.String("vpsienode", vn.Name),
 			zap.String("phase", string(vn.Status.Phase)),
 		)
 
-		if err := r.Delete(ctx, &vn); err != nil {
+		if err := r.Delete(ctx, vn); err != nil {
 			logger.Error("Failed to delete VPSieNode",
 				zap.String("vpsienode", vn.Name),
 				zap.Error(err),
 			)
+			r.recordScaleDownSavings(ctx, ng, deleted, logger)
 			SetErrorCondition(ng, true, ReasonKubernetesAPIError, fmt.Sprintf("Failed to delete VPSieNode: %v", err))
 			return ctrl.Result{}, err
 		}
+		deleted = append(deleted, vn)
 	}
+	r.recordScaleDownSavings(ctx, ng, deleted, logger)
 
 	// Requeue to check progress
 	return ctrl.Result{RequeueAfter: FastRequeueAfter}, nil
 }
 
+// reconcileEmptyNodeScaleDown removes nodes that have run no workload pods
+// for the empty-node unneeded time, several at once. Unlike the removal of
+// underutilized nodes it ignores the scale-down cooldown, but still waits for
+// the stabilization window after a scale-up. Nodes are never removed below
+// MinNodes or floorNodes. It returns how many VPSieNodes were deleted.
+func (r *NodeGroupReconciler) reconcileEmptyNodeScaleDown(
+	ctx context.Context,
+	ng *v1alpha1.NodeGroup,
+	vpsieNodes []v1alpha1.VPSieNode,
+	floorNodes int32,
+	logger *zap.Logger,
+) (int, error) {
+	if r.ScaleDownManager == nil || !ng.Spec.ScaleDownPolicy.Enabled {
+		return 0, nil
+	}
+
+	if ng.Status.LastScaleTime != nil {
+		stabilization := time.Duration(ng.Spec.ScaleDownPolicy.StabilizationWindowSeconds) * time.Second
+		if time.Since(ng.Status.LastScaleTime.Time) < stabilization {
+			return 0, nil
+		}
+	}
+
+	minNodes := v1alpha1.EffectiveMinNodes(ng)
+	if floorNodes > minNodes {
+		minNodes = floorNodes
+	}
+	maxRemovable := int(ng.Status.CurrentNodes - minNodes)
+	if maxRemovable <= 0 {
+		return 0, nil
+	}
+
+	candidates, err := r.ScaleDownManager.IdentifyEmptyNodes(ctx, ng)
+	if err != nil {
+		logger.Error("Failed to identify empty nodes", zap.Error(err))
+		return 0, nil
+	}
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+	if len(candidates) > maxRemovable {
+		candidates = candidates[:maxRemovable]
+	}
+
+	logger.Info("Found empty nodes for scale-down",
+		zap.Int("candidateCount", len(candidates)),
+		zap.Int32("current", ng.Status.CurrentNodes),
+		zap.Int32("min", minNodes),
+	)
+
+	drained, err := r.ScaleDownManager.ScaleDownEmptyNodes(ctx, ng, candidates)
+	if err != nil {
+		logger.Error("Empty node scale-down failed", zap.Error(err))
+		if len(drained) == 0 {
+			SetErrorCondition(ng, true, ReasonScaleDownFailed, fmt.Sprintf("Empty node scale-down failed: %v", err))
+			return 0, err
+		}
+	}
+
+	deleted, deletionErrors := r.deleteDrainedVPSieNodes(ctx, ng, vpsieNodes, drained, logger)
+	r.recordScaleDownSavings(ctx, ng, deleted, logger)
+
+	logger.Info("Empty node scale-down completed",
+		zap.Int("nodesDrained", len(drained)),
+		zap.Int("vpsieNodesDeleted", len(deleted)),
+		zap.Int("deletionsFailed", len(deletionErrors)),
+	)
+
+	return len(deleted), nil
+}
+
 // evaluateUtilizationBasedScaleDown checks if scale-down should be triggered based on node utilization.
 // Returns true and the number of nodes to remove if scale-down is warranted.
+// Nodes are never removed below floorNodes, the node count needed to keep the
+// NodeGroup's headroom free and to meet predicted demand.
 func (r *NodeGroupReconciler) evaluateUtilizationBasedScaleDown(
 	ctx context.Context,
 	ng *v1alpha1.NodeGroup,
+	floorNodes int32,
 	logger *zap.Logger,
 ) (bool, int) {
 	if r.ScaleDownManager == nil {
 		return false, 0
 	}
 
-	// Check cooldown period from last scale action
-	if ng.Status.LastScaleDownTime != nil {
-		cooldown := time.Duration(ng.Spec.ScaleDownPolicy.CooldownSeconds) * time.Second
-		if time.Since(ng.Status.LastScaleDownTime.Time) < cooldown {
-			logger.Debug("Within scale-down cooldown period",
-				zap.Duration("cooldown", cooldown),
-				zap.Duration("elapsed", time.Since(ng.Status.LastScaleDownTime.Time)),
-			)
-			return false, 0
-		}
-	}
-
-	// Also check cooldown from last scale-up (stabilization)
-	if ng.Status.LastScaleTime != nil {
-		stabilization := time.Duration(ng.Spec.ScaleDownPolicy.StabilizationWindowSeconds) * time.Second
-		if time.Since(ng.Status.LastScaleTime.Time) < stabilization {
-			logger.Debug("Within stabilization window after scale-up",
-				zap.Duration("stabilization", stabilization),
-				zap.Duration("elapsed", time.Since(ng.Status.LastScaleTime.Time)),
-			)
-			return false, 0
-		}
+	if scaleDownCoolingDown(ng, logger) {
+		return false, 0
 	}
 
 	// Identify underutilized nodes
@@ -569,15 +780,19 @@ func (r *NodeGroupReconciler) evaluateUtilizationBasedScaleDown(
 		return false, 0
 	}
 
-	// Determine how many nodes can be removed while staying above MinNodes
+	// Determine how many nodes can be removed while staying above MinNodes and the floor
 	currentNodes := int(ng.Status.CurrentNodes)
-	minNodes := int(ng.Spec.MinNodes)
+	minNodes := int(v1alpha1.EffectiveMinNodes(ng))
+	if int(floorNodes) > minNodes {
+		minNodes = int(floorNodes)
+	}
 	maxRemovable := currentNodes - minNodes
 
 	if maxRemovable <= 0 {
 		logger.Debug("At minimum nodes, cannot scale down",
 			zap.Int("current", currentNodes),
 			zap.Int("min", minNodes),
+			zap.Int32("floor", floorNodes),
 		)
 		return false, 0
 	}
@@ -611,6 +826,37 @@ func (r *NodeGroupReconciler) evaluateUtilizationBasedScaleDown(
 	return true, nodesToRemove
 }
 
+// scaleDownCoolingDown reports whether the NodeGroup is within the scale-down
+// cooldown after its last scale-down or the stabilization window after its
+// last scale action.
+func scaleDownCoolingDown(ng *v1alpha1.NodeGroup, logger *zap.Logger) bool {
+	// Check cooldown period from last scale action
+	if ng.Status.LastScaleDownTime != nil {
+		cooldown := time.Duration(ng.Spec.ScaleDownPolicy.CooldownSeconds) * time.Second
+		if time.Since(ng.Status.LastScaleDownTime.Time) < cooldown {
+			logger.Debug("Within scale-down cooldown period",
+				zap.Duration("cooldown", cooldown),
+				zap.Duration("elapsed", time.Since(ng.Status.LastScaleDownTime.Time)),
+			)
+			return true
+		}
+	}
+
+	// Also check cooldown from last scale-up (stabilization)
+	if ng.Status.LastScaleTime != nil {
+		stabilization := time.Duration(ng.Spec.ScaleDownPolicy.StabilizationWindowSeconds) * time.Second
+		if time.Since(ng.Status.LastScaleTime.Time) < stabilization {
+			logger.Debug("Within stabilization window after scale-up",
+				zap.Duration("stabilization", stabilization),
+				zap.Duration("elapsed", time.Since(ng.Status.LastScaleTime.Time)),
+			)
+			return true
+		}
+	}
+
+	return false
+}
+
 // buildVPSieNode creates a new VPSieNode spec for the NodeGroup
 func (r *NodeGroupReconciler) buildVPSieNode(ng *v1alpha1.NodeGroup) *v1alpha1.VPSieNode {
 	// Generate unique name
@@ -649,38 +895,52 @@ func (r *NodeGroupReconciler) buildVPSieNode(ng *v1alpha1.NodeGroup) *v1alpha1.V
 }
 
 // selectNodesToDelete selects which nodes should be deleted during scale-down
-// Prioritizes nodes that are not ready
-func selectNodesToDelete(vpsieNodes []v1alpha1.VPSieNode, count int) []v1alpha1.VPSieNode {
+// in the NodeGroup's deletion order. Nodes that are not ready are selected
+// first. price is optional.
+func selectNodesToDelete(
+	vpsieNodes []v1alpha1.VPSieNode,
+	count int,
+	order v1alpha1.NodeDeletionOrder,
+	price func(offeringID string) (float64, bool),
+) []v1alpha1.VPSieNode {
 	if count >= len(vpsieNodes) {
 		return vpsieNodes
 	}
 
-	// Sort nodes by priority (not ready first, then oldest)
-	var notReady []v1alpha1.VPSieNode
-	var ready []v1alpha1.VPSieNode
-
+	// Deletion order strategies rank Nodes, so stand in a Node for each
+	// VPSieNode carrying what they look at
+	index := make(map[string]int, len(vpsieNodes))
+	nodes := make([]*corev1.Node, len(vpsieNodes))
+	candidates := make([]*scaler.ScaleDownCandidate, len(vpsieNodes))
 	for i := range vpsieNodes {
-		vn := vpsieNodes[i]
-		if vn.Status.Phase != v1alpha1.VPSieNodePhaseReady {
-			notReady = append(notReady, vn)
-		} else {
-			ready = append(ready, vn)
+		vn := &vpsieNodes[i]
+		ready := corev1.ConditionFalse
+		if vn.Status.Phase == v1alpha1.VPSieNodePhaseReady {
+			ready = corev1.ConditionTrue
 		}
+		nodes[i] = &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              vn.Name,
+				CreationTimestamp: vn.CreationTimestamp,
+				Labels: map[string]string{
+					v1alpha1.DatacenterLabelKey: vn.Spec.DatacenterID,
+					v1alpha1.OfferingLabelKey:   vn.Spec.InstanceType,
+				},
+			},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+			},
+		}
+		candidates[i] = &scaler.ScaleDownCandidate{Node: nodes[i]}
+		index[vn.Name] = i
 	}
 
-	var result []v1alpha1.VPSieNode
-
-	// First, select not-ready nodes
-	for i := 0; i < count && i < len(notReady); i++ {
-		result = append(result, notReady[i])
-	}
+	scaler.OrderForDeletion(candidates, order, &scaler.DeletionOrderInput{Nodes: nodes, Price: price})
 
-	// If we need more, select from ready nodes (oldest first)
-	remaining := count - len(result)
-	for i := 0; i < remaining && i < len(ready); i++ {
-		result = append(result, ready[i])
+	result := make([]v1alpha1.VPSieNode, 0, count)
+	for _, candidate := range candidates[:count] {
+		result = append(result, vpsieNodes[index[candidate.Node.Name]])
 	}
-
 	return result
 }
 
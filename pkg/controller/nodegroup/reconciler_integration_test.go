@@ -45,6 +45,22 @@ func (m *MockScaleDownManager) UpdateNodeUtilization(ctx context.Context) error
 	return args.Error(0)
 }
 
+func (m *MockScaleDownManager) IdentifyEmptyNodes(ctx context.Context, ng *v1alpha1.NodeGroup) ([]*scaler.ScaleDownCandidate, error) {
+	args := m.Called(ctx, ng)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*scaler.ScaleDownCandidate), args.Error(1)
+}
+
+func (m *MockScaleDownManager) ScaleDownEmptyNodes(ctx context.Context, ng *v1alpha1.NodeGroup, candidates []*scaler.ScaleDownCandidate) ([]*scaler.ScaleDownCandidate, error) {
+	args := m.Called(ctx, ng, candidates)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*scaler.ScaleDownCandidate), args.Error(1)
+}
+
 func (m *MockScaleDownManager) GetMaxNodesPerScaleDown() int {
 	args := m.Called()
 	return args.Int(0)
@@ -345,6 +361,78 @@ func TestReconcileIntelligentScaleDown_ScaleDownError(t *testing.T) {
 	t.Log("✓ Error condition was set with ReasonScaleDownFailed")
 }
 
+// TestReconcileEmptyNodeScaleDown tests that empty nodes are removed in bulk
+// down to MinNodes and their VPSieNodes are deleted
+func TestReconcileEmptyNodeScaleDown(t *testing.T) {
+	logger := zap.NewNop()
+
+	ng := &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-ng",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.NodeGroupSpec{
+			MinNodes:        2,
+			MaxNodes:        10,
+			ScaleDownPolicy: v1alpha1.ScaleDownPolicy{Enabled: true},
+		},
+		Status: v1alpha1.NodeGroupStatus{
+			CurrentNodes: 4,
+			DesiredNodes: 4,
+		},
+	}
+
+	var vpsieNodes []v1alpha1.VPSieNode
+	var candidates []*scaler.ScaleDownCandidate
+	for _, name := range []string{"node-1", "node-2", "node-3"} {
+		vpsieNodes = append(vpsieNodes, v1alpha1.VPSieNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "vn-" + name, Namespace: "default"},
+			Status:     v1alpha1.VPSieNodeStatus{NodeName: name},
+		})
+		candidates = append(candidates, &scaler.ScaleDownCandidate{
+			Node: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}},
+		})
+	}
+
+	// Only two nodes may be removed above MinNodes
+	mockSDM := new(MockScaleDownManager)
+	mockSDM.On("IdentifyEmptyNodes", mock.Anything, ng).Return(candidates, nil)
+	mockSDM.On("ScaleDownEmptyNodes", mock.Anything, ng, candidates[:2]).Return(candidates[:2], nil)
+
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+	builder := ctrlclient.NewClientBuilder().WithScheme(scheme).WithObjects(ng)
+	for i := range vpsieNodes {
+		builder = builder.WithObjects(&vpsieNodes[i])
+	}
+	k8sClient := builder.Build()
+
+	reconciler := &NodeGroupReconciler{
+		Client:           k8sClient,
+		Scheme:           scheme,
+		ScaleDownManager: mockSDM,
+		Logger:           logger,
+	}
+
+	ctx := context.Background()
+	removed, err := reconciler.reconcileEmptyNodeScaleDown(ctx, ng, vpsieNodes, 0, logger)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	mockSDM.AssertExpectations(t)
+
+	var remaining v1alpha1.VPSieNodeList
+	assert.NoError(t, k8sClient.List(ctx, &remaining))
+	if assert.Len(t, remaining.Items, 1) {
+		assert.Equal(t, "vn-node-3", remaining.Items[0].Name)
+	}
+
+	// The headroom floor leaves nothing to remove
+	removed, err = reconciler.reconcileEmptyNodeScaleDown(ctx, ng, vpsieNodes, 4, logger)
+	assert.NoError(t, err)
+	assert.Zero(t, removed)
+	mockSDM.AssertNumberOfCalls(t, "IdentifyEmptyNodes", 1)
+}
+
 // TestReconcileScaleDown_FallbackToSimple tests fallback when ScaleDownManager is nil
 func TestReconcileScaleDown_FallbackToSimple(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
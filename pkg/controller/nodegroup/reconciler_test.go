@@ -1,12 +1,17 @@
 package nodegroup
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
 )
 
 func TestBuildVPSieNode(t *testing.T) {
@@ -132,7 +137,7 @@ func TestSelectNodesToDelete(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := selectNodesToDelete(tt.nodes, tt.count)
+			result := selectNodesToDelete(tt.nodes, tt.count, "", nil)
 			assert.Len(t, result, tt.expectLen)
 
 			if tt.expectNonReady {
@@ -150,6 +155,50 @@ func TestSelectNodesToDelete(t *testing.T) {
 	}
 }
 
+func TestSelectNodesToDelete_DeletionOrder(t *testing.T) {
+	now := time.Now()
+	newVPSieNode := func(name, datacenter, offering string, age time.Duration, phase v1alpha1.VPSieNodePhase) v1alpha1.VPSieNode {
+		return v1alpha1.VPSieNode{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(now.Add(-age))},
+			Spec:       v1alpha1.VPSieNodeSpec{DatacenterID: datacenter, InstanceType: offering},
+			Status:     v1alpha1.VPSieNodeStatus{Phase: phase},
+		}
+	}
+	nodes := []v1alpha1.VPSieNode{
+		newVPSieNode("a-new", "dc-a", "small", time.Hour, v1alpha1.VPSieNodePhaseReady),
+		newVPSieNode("a-old", "dc-a", "large", 72*time.Hour, v1alpha1.VPSieNodePhaseReady),
+		newVPSieNode("b-mid", "dc-b", "small", 24*time.Hour, v1alpha1.VPSieNodePhaseReady),
+		newVPSieNode("a-pending", "dc-a", "small", time.Minute, v1alpha1.VPSieNodePhasePending),
+	}
+	price := func(offeringID string) (float64, bool) {
+		prices := map[string]float64{"small": 10, "large": 40}
+		p, ok := prices[offeringID]
+		return p, ok
+	}
+
+	tests := []struct {
+		order v1alpha1.NodeDeletionOrder
+		count int
+		want  []string
+	}{
+		{order: v1alpha1.NodeDeletionOrderDefault, count: 2, want: []string{"a-pending", "a-new"}},
+		{order: v1alpha1.NodeDeletionOrderOldestFirst, count: 2, want: []string{"a-pending", "a-old"}},
+		{order: v1alpha1.NodeDeletionOrderNewestFirst, count: 2, want: []string{"a-pending", "a-new"}},
+		{order: v1alpha1.NodeDeletionOrderMostExpensiveFirst, count: 2, want: []string{"a-pending", "a-old"}},
+		{order: v1alpha1.NodeDeletionOrderSpreadPreserving, count: 3, want: []string{"a-pending", "a-new", "a-old"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.order), func(t *testing.T) {
+			var names []string
+			for _, vn := range selectNodesToDelete(nodes, tt.count, tt.order, price) {
+				names = append(names, vn.Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
 func TestGenerateRandomSuffix(t *testing.T) {
 	suffix1 := generateRandomSuffix()
 	suffix2 := generateRandomSuffix()
@@ -461,3 +510,43 @@ func TestBuildVPSieNodeHasManagedLabel(t *testing.T) {
 	assert.Equal(t, ng.Name, vn.Labels[NodeGroupNameLabelKey],
 		"VPSieNode should have nodegroup name label")
 }
+
+// fakeSavingsRecorder keeps the opportunities recorded in the savings ledger
+type fakeSavingsRecorder struct {
+	recorded []*cost.Opportunity
+}
+
+func (f *fakeSavingsRecorder) RecordApplied(_ context.Context, nodeGroup, namespace string, opportunity *cost.Opportunity, appliedAt time.Time) (*cost.LedgerEntry, error) {
+	f.recorded = append(f.recorded, opportunity)
+	return &cost.LedgerEntry{NodeGroupName: nodeGroup, Namespace: namespace, AppliedAt: appliedAt}, nil
+}
+
+func TestRecordScaleDownSavings(t *testing.T) {
+	ng := &v1alpha1.NodeGroup{ObjectMeta: metav1.ObjectMeta{Name: "test-ng", Namespace: "default"}}
+	removed := []*v1alpha1.VPSieNode{
+		{ObjectMeta: metav1.ObjectMeta{Name: "vn-1"}, Spec: v1alpha1.VPSieNodeSpec{InstanceType: "small"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "vn-2"}, Spec: v1alpha1.VPSieNodeSpec{InstanceType: "large"}},
+	}
+
+	ledger := &fakeSavingsRecorder{}
+	r := &NodeGroupReconciler{
+		Pricer: fakePricer{"small": smallOffering, "large": largeOffering},
+		Ledger: ledger,
+	}
+
+	r.recordScaleDownSavings(context.Background(), ng, removed, zap.NewNop())
+	require.Len(t, ledger.recorded, 1)
+	assert.Equal(t, cost.OptimizationScaleDown, ledger.recorded[0].Type)
+	assert.Equal(t, 70.0, ledger.recorded[0].MonthlySavings)
+	assert.Equal(t, 840.0, ledger.recorded[0].AnnualSavings)
+
+	// Nothing is recorded when a removed node's price is unknown
+	removed = append(removed, &v1alpha1.VPSieNode{Spec: v1alpha1.VPSieNodeSpec{InstanceType: "unknown"}})
+	r.recordScaleDownSavings(context.Background(), ng, removed, zap.NewNop())
+	assert.Len(t, ledger.recorded, 1)
+
+	// Nor without a pricer
+	r.Pricer = nil
+	r.recordScaleDownSavings(context.Background(), ng, removed[:1], zap.NewNop())
+	assert.Len(t, ledger.recorded, 1)
+}
@@ -0,0 +1,305 @@
+package nodegroup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+)
+
+// maxScheduleDuration bounds how long a scaling schedule window may stay open
+const maxScheduleDuration = 7 * 24 * time.Hour
+
+// cronField is the set of values a cron field matches, one bit per value
+type cronField uint64
+
+func (f cronField) has(v int) bool {
+	return f&(1<<uint(v)) != 0
+}
+
+var (
+	cronMonthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	cronDayNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+// cronSchedule is a parsed five-field cron expression evaluated in a time zone
+type cronSchedule struct {
+	minute, hour, dom, month, dow cronField
+	// domAny and dowAny record a "*" day field. As in cron, when both day
+	// fields are restricted a day matching either of them matches.
+	domAny, dowAny bool
+	location       *time.Location
+}
+
+// parseCronSchedule parses a cron expression of the form
+// "minute hour day-of-month month day-of-week". Fields accept "*", values,
+// ranges ("1-5"), steps ("*/15", "8-18/2") and comma separated lists.
+// Months and days of the week may also be given as three-letter names.
+func parseCronSchedule(expr string, location *time.Location) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, got %d", expr, len(fields))
+	}
+
+	s := &cronSchedule{location: location}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7, cronDayNames); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	// 7 is an alias for Sunday
+	if s.dow.has(7) {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domAny = fields[2] == "*"
+	s.dowAny = fields[4] == "*"
+
+	return s, nil
+}
+
+func parseCronField(field string, lo, hi int, names map[string]int) (cronField, error) {
+	var bits cronField
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		var start, end int
+		switch {
+		case rangePart == "*":
+			start, end = lo, hi
+		case strings.Contains(rangePart, "-"):
+			first, last, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseCronValue(first, lo, hi, names); err != nil {
+				return 0, err
+			}
+			if end, err = parseCronValue(last, lo, hi, names); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			var err error
+			if start, err = parseCronValue(rangePart, lo, hi, names); err != nil {
+				return 0, err
+			}
+			end = start
+			// "5/15" means every 15 starting at 5
+			if hasStep {
+				end = hi
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(value string, lo, hi int, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	if n < lo || n > hi {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", n, lo, hi)
+	}
+	return n, nil
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom.has(t.Day())
+	dowMatch := s.dow.has(int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// next returns the first time after t the schedule matches, or the zero time
+// if it does not match within five years (e.g. "0 0 30 2 *")
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !s.month.has(int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+			continue
+		}
+		if !s.hour.has(t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+			continue
+		}
+		if !s.minute.has(t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// scheduleLocation returns the time zone of a scaling schedule, UTC if unset
+func scheduleLocation(schedule *v1alpha1.ScalingSchedule) (*time.Location, error) {
+	if schedule.TimeZone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(schedule.TimeZone)
+}
+
+// ValidateSchedules validates the scaling schedules of a NodeGroup. It is
+// shared by the reconciler and the admission webhook.
+func ValidateSchedules(schedules []v1alpha1.ScalingSchedule) error {
+	names := make(map[string]bool, len(schedules))
+	for i := range schedules {
+		schedule := &schedules[i]
+		if schedule.Name == "" {
+			return fmt.Errorf("schedules[%d].name is required", i)
+		}
+		if names[schedule.Name] {
+			return fmt.Errorf("schedules[%d].name %q is duplicated", i, schedule.Name)
+		}
+		names[schedule.Name] = true
+
+		location, err := scheduleLocation(schedule)
+		if err != nil {
+			return fmt.Errorf("schedules[%d].timeZone %q is invalid: %w", i, schedule.TimeZone, err)
+		}
+		if _, err := parseCronSchedule(schedule.Schedule, location); err != nil {
+			return fmt.Errorf("schedules[%d].schedule is invalid: %w", i, err)
+		}
+		if schedule.Duration.Duration <= 0 || schedule.Duration.Duration > maxScheduleDuration {
+			return fmt.Errorf("schedules[%d].duration must be > 0 and <= %s, got %s",
+				i, maxScheduleDuration, schedule.Duration.Duration)
+		}
+		if schedule.MinNodes != nil && *schedule.MinNodes < 0 {
+			return fmt.Errorf("schedules[%d].minNodes must be >= 0, got %d", i, *schedule.MinNodes)
+		}
+		if schedule.MaxNodes != nil && *schedule.MaxNodes < 1 {
+			return fmt.Errorf("schedules[%d].maxNodes must be >= 1, got %d", i, *schedule.MaxNodes)
+		}
+		if schedule.MinNodes != nil && schedule.MaxNodes != nil && *schedule.MinNodes > *schedule.MaxNodes {
+			return fmt.Errorf("schedules[%d].minNodes (%d) must be <= maxNodes (%d)",
+				i, *schedule.MinNodes, *schedule.MaxNodes)
+		}
+		if schedule.DesiredNodes != nil && *schedule.DesiredNodes < 0 {
+			return fmt.Errorf("schedules[%d].desiredNodes must be >= 0, got %d", i, *schedule.DesiredNodes)
+		}
+	}
+	return nil
+}
+
+// scheduleWindow returns the window of a schedule open at now. It returns
+// false if no window is open.
+func scheduleWindow(schedule *v1alpha1.ScalingSchedule, now time.Time) (start, end time.Time, open bool, err error) {
+	location, err := scheduleLocation(schedule)
+	if err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+	cron, err := parseCronSchedule(schedule.Schedule, location)
+	if err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+	duration := schedule.Duration.Duration
+
+	// Find the latest window start in (now-duration, now]
+	start = cron.next(now.Add(-duration - time.Minute))
+	for !start.IsZero() && !start.Add(duration).After(now) {
+		start = cron.next(start)
+	}
+	if start.IsZero() || start.After(now) {
+		return time.Time{}, time.Time{}, false, nil
+	}
+	for {
+		later := cron.next(start)
+		if later.IsZero() || later.After(now) {
+			break
+		}
+		start = later
+	}
+	return start, start.Add(duration), true, nil
+}
+
+// updateActiveSchedule sets Status.ActiveSchedule to the first schedule entry
+// whose window is open at now, or clears it. It returns the entry if its
+// window opened since the status was last updated so its DesiredNodes can be
+// applied once.
+func updateActiveSchedule(ng *v1alpha1.NodeGroup, now time.Time) (*v1alpha1.ScalingSchedule, error) {
+	previous := ng.Status.ActiveSchedule
+
+	for i := range ng.Spec.Schedules {
+		schedule := &ng.Spec.Schedules[i]
+		start, end, open, err := scheduleWindow(schedule, now)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", schedule.Name, err)
+		}
+		if !open {
+			continue
+		}
+
+		minNodes := ng.Spec.MinNodes
+		if schedule.MinNodes != nil {
+			minNodes = *schedule.MinNodes
+		}
+		maxNodes := ng.Spec.MaxNodes
+		if schedule.MaxNodes != nil {
+			maxNodes = *schedule.MaxNodes
+		}
+		if maxNodes < minNodes {
+			maxNodes = minNodes
+		}
+
+		ng.Status.ActiveSchedule = &v1alpha1.ActiveScalingSchedule{
+			Name:      schedule.Name,
+			MinNodes:  minNodes,
+			MaxNodes:  maxNodes,
+			StartTime: metav1.NewTime(start),
+			EndTime:   metav1.NewTime(end),
+		}
+
+		if previous != nil && previous.Name == schedule.Name && previous.StartTime.Time.Equal(start) {
+			return nil, nil
+		}
+		return schedule, nil
+	}
+
+	ng.Status.ActiveSchedule = nil
+	return nil, nil
+}
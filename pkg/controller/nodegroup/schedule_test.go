@@ -0,0 +1,245 @@
+package nodegroup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+)
+
+func int32Ptr(v int32) *int32 {
+	return &v
+}
+
+func newScheduledNodeGroup(schedules ...v1alpha1.ScalingSchedule) *v1alpha1.NodeGroup {
+	return &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ng", Namespace: "default"},
+		Spec: v1alpha1.NodeGroupSpec{
+			MinNodes:  1,
+			MaxNodes:  10,
+			Schedules: schedules,
+		},
+	}
+}
+
+func businessHours() v1alpha1.ScalingSchedule {
+	return v1alpha1.ScalingSchedule{
+		Name:     "business-hours",
+		Schedule: "0 8 * * mon-fri",
+		Duration: metav1.Duration{Duration: 11 * time.Hour},
+		TimeZone: "Europe/Berlin",
+		MinNodes: int32Ptr(5),
+	}
+}
+
+func TestParseCronSchedule(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"0 8 * * 1-5",
+		"*/15 8-18/2 1,15 jan-jun SUN",
+		"30 6 * * 7",
+		"5/20 * * * *",
+	}
+	for _, expr := range valid {
+		_, err := parseCronSchedule(expr, time.UTC)
+		assert.NoError(t, err, expr)
+	}
+
+	invalid := []string{
+		"",
+		"0 8 * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * * weekday",
+	}
+	for _, expr := range invalid {
+		_, err := parseCronSchedule(expr, time.UTC)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		expr     string
+		location *time.Location
+		after    time.Time
+		expected time.Time
+	}{
+		{
+			name:     "later the same day",
+			expr:     "0 8 * * *",
+			location: time.UTC,
+			after:    time.Date(2026, 1, 5, 6, 30, 0, 0, time.UTC),
+			expected: time.Date(2026, 1, 5, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "strictly after",
+			expr:     "0 8 * * *",
+			location: time.UTC,
+			after:    time.Date(2026, 1, 5, 8, 0, 0, 0, time.UTC),
+			expected: time.Date(2026, 1, 6, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekdays skip the weekend in the schedule's time zone",
+			expr:     "0 8 * * 1-5",
+			location: berlin,
+			after:    time.Date(2026, 1, 9, 18, 0, 0, 0, time.UTC),
+			expected: time.Date(2026, 1, 12, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "day of month or day of week",
+			expr:     "0 0 1 * sun",
+			location: time.UTC,
+			after:    time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+			expected: time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "steps",
+			expr:     "*/20 * * * *",
+			location: time.UTC,
+			after:    time.Date(2026, 1, 5, 8, 41, 30, 0, time.UTC),
+			expected: time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := parseCronSchedule(tt.expr, tt.location)
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(cron.next(tt.after)), "got %s", cron.next(tt.after))
+		})
+	}
+
+	// A schedule that never matches
+	cron, err := parseCronSchedule("0 0 30 2 *", time.UTC)
+	require.NoError(t, err)
+	assert.True(t, cron.next(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero())
+}
+
+func TestValidateSchedules(t *testing.T) {
+	assert.NoError(t, ValidateSchedules(nil))
+	assert.NoError(t, ValidateSchedules([]v1alpha1.ScalingSchedule{businessHours()}))
+
+	noName := businessHours()
+	noName.Name = ""
+	badZone := businessHours()
+	badZone.TimeZone = "Mars/Olympus"
+	badCron := businessHours()
+	badCron.Schedule = "0 8 * *"
+	noDuration := businessHours()
+	noDuration.Duration = metav1.Duration{}
+	minAboveMax := businessHours()
+	minAboveMax.MaxNodes = int32Ptr(3)
+
+	for name, schedules := range map[string][]v1alpha1.ScalingSchedule{
+		"missing name":      {noName},
+		"duplicate name":    {businessHours(), businessHours()},
+		"invalid time zone": {badZone},
+		"invalid cron":      {badCron},
+		"missing duration":  {noDuration},
+		"min above max":     {minAboveMax},
+	} {
+		assert.Error(t, ValidateSchedules(schedules), name)
+	}
+}
+
+func TestUpdateActiveSchedule(t *testing.T) {
+	// Monday 2026-01-05 10:00 in Berlin
+	monday := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+
+	t.Run("window opens once", func(t *testing.T) {
+		ng := newScheduledNodeGroup(businessHours())
+
+		opened, err := updateActiveSchedule(ng, monday)
+		require.NoError(t, err)
+		require.NotNil(t, opened)
+		assert.Equal(t, "business-hours", opened.Name)
+		require.NotNil(t, ng.Status.ActiveSchedule)
+		assert.Equal(t, int32(5), ng.Status.ActiveSchedule.MinNodes)
+		assert.Equal(t, int32(10), ng.Status.ActiveSchedule.MaxNodes)
+		assert.True(t, ng.Status.ActiveSchedule.StartTime.Time.Equal(time.Date(2026, 1, 5, 7, 0, 0, 0, time.UTC)))
+		assert.True(t, ng.Status.ActiveSchedule.EndTime.Time.Equal(time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC)))
+
+		// Later in the same window nothing new opens
+		opened, err = updateActiveSchedule(ng, monday.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Nil(t, opened)
+		assert.NotNil(t, ng.Status.ActiveSchedule)
+
+		// After the window closes the spec bounds apply again
+		opened, err = updateActiveSchedule(ng, monday.Add(10*time.Hour))
+		require.NoError(t, err)
+		assert.Nil(t, opened)
+		assert.Nil(t, ng.Status.ActiveSchedule)
+
+		// The next morning's window is a new activation
+		opened, err = updateActiveSchedule(ng, monday.Add(24*time.Hour))
+		require.NoError(t, err)
+		assert.NotNil(t, opened)
+	})
+
+	t.Run("not active at the weekend", func(t *testing.T) {
+		ng := newScheduledNodeGroup(businessHours())
+		opened, err := updateActiveSchedule(ng, monday.Add(-48*time.Hour))
+		require.NoError(t, err)
+		assert.Nil(t, opened)
+		assert.Nil(t, ng.Status.ActiveSchedule)
+	})
+
+	t.Run("first open entry wins", func(t *testing.T) {
+		freeze := v1alpha1.ScalingSchedule{
+			Name:     "freeze",
+			Schedule: "0 0 * * *",
+			Duration: metav1.Duration{Duration: 24 * time.Hour},
+			MinNodes: int32Ptr(2),
+			MaxNodes: int32Ptr(2),
+		}
+		ng := newScheduledNodeGroup(freeze, businessHours())
+
+		_, err := updateActiveSchedule(ng, monday)
+		require.NoError(t, err)
+		require.NotNil(t, ng.Status.ActiveSchedule)
+		assert.Equal(t, "freeze", ng.Status.ActiveSchedule.Name)
+	})
+
+	t.Run("max raised to min", func(t *testing.T) {
+		schedule := businessHours()
+		schedule.MinNodes = int32Ptr(12)
+		ng := newScheduledNodeGroup(schedule)
+
+		_, err := updateActiveSchedule(ng, monday)
+		require.NoError(t, err)
+		require.NotNil(t, ng.Status.ActiveSchedule)
+		assert.Equal(t, int32(12), ng.Status.ActiveSchedule.MinNodes)
+		assert.Equal(t, int32(12), ng.Status.ActiveSchedule.MaxNodes)
+	})
+}
+
+func TestScheduleBounds(t *testing.T) {
+	ng := newScheduledNodeGroup(businessHours())
+	ng.Status.DesiredNodes = 2
+	ng.Status.CurrentNodes = 5
+
+	assert.Equal(t, int32(2), CalculateDesiredNodes(ng))
+	assert.True(t, CanScaleDown(ng))
+
+	_, err := updateActiveSchedule(ng, time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(5), CalculateDesiredNodes(ng))
+	assert.False(t, CanScaleDown(ng))
+	assert.Equal(t, int32(10), ClampNodes(ng, 15))
+}
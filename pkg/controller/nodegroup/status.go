@@ -3,6 +3,7 @@ package nodegroup
 import (
 	"context"
 	"fmt"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -86,7 +87,7 @@ func UpdateNodeGroupStatus(ctx context.Context, c client.Client, ng *v1alpha1.No
 
 	// Set desired nodes if not set
 	if ng.Status.DesiredNodes == 0 {
-		ng.Status.DesiredNodes = ng.Spec.MinNodes
+		ng.Status.DesiredNodes = v1alpha1.EffectiveMinNodes(ng)
 	}
 
 	return nil
@@ -109,52 +110,67 @@ func SetDesiredNodes(ng *v1alpha1.NodeGroup, desired int32) {
 }
 
 // CalculateDesiredNodes calculates the desired number of nodes based on spec constraints
+// and the active scaling schedule
 func CalculateDesiredNodes(ng *v1alpha1.NodeGroup) int32 {
 	desired := ng.Status.DesiredNodes
 
 	// If not set, start with minimum
 	if desired == 0 {
-		desired = ng.Spec.MinNodes
+		desired = v1alpha1.EffectiveMinNodes(ng)
 	}
 
 	// Ensure desired is within min/max bounds
-	if desired < ng.Spec.MinNodes {
-		desired = ng.Spec.MinNodes
+	return ClampNodes(ng, desired)
+}
+
+// ClampNodes bounds a node count by the NodeGroup's effective MinNodes and MaxNodes
+func ClampNodes(ng *v1alpha1.NodeGroup, nodes int32) int32 {
+	if minNodes := v1alpha1.EffectiveMinNodes(ng); nodes < minNodes {
+		return minNodes
 	}
+	if maxNodes := v1alpha1.EffectiveMaxNodes(ng); nodes > maxNodes {
+		return maxNodes
+	}
+	return nodes
+}
 
-	if desired > ng.Spec.MaxNodes {
-		desired = ng.Spec.MaxNodes
+// ActivePrediction returns the predictive scaler's node count, bounded by
+// the effective MinNodes and MaxNodes, or 0 if there is none or it has expired
+func ActivePrediction(ng *v1alpha1.NodeGroup, now time.Time) int32 {
+	if ng.Status.PredictedNodes <= 0 || ng.Status.PredictionExpiresAt == nil ||
+		!now.Before(ng.Status.PredictionExpiresAt.Time) {
+		return 0
 	}
 
-	return desired
+	return ClampNodes(ng, ng.Status.PredictedNodes)
 }
 
 // NeedsScaleUp returns true if the NodeGroup needs to scale up
 func NeedsScaleUp(ng *v1alpha1.NodeGroup) bool {
 	return ng.Status.CurrentNodes < ng.Status.DesiredNodes &&
-		ng.Status.CurrentNodes < ng.Spec.MaxNodes
+		ng.Status.CurrentNodes < v1alpha1.EffectiveMaxNodes(ng)
 }
 
 // NeedsScaleDown returns true if the NodeGroup needs to scale down
 func NeedsScaleDown(ng *v1alpha1.NodeGroup) bool {
 	return ng.Status.CurrentNodes > ng.Status.DesiredNodes &&
-		ng.Status.CurrentNodes > ng.Spec.MinNodes
+		ng.Status.CurrentNodes > v1alpha1.EffectiveMinNodes(ng)
 }
 
 // CanScaleUp returns true if the NodeGroup can scale up
 func CanScaleUp(ng *v1alpha1.NodeGroup) bool {
-	return ng.Status.CurrentNodes < ng.Spec.MaxNodes
+	return ng.Status.CurrentNodes < v1alpha1.EffectiveMaxNodes(ng)
 }
 
 // CanScaleDown returns true if the NodeGroup can scale down
 func CanScaleDown(ng *v1alpha1.NodeGroup) bool {
-	return ng.Status.CurrentNodes > ng.Spec.MinNodes
+	return ng.Status.CurrentNodes > v1alpha1.EffectiveMinNodes(ng)
 }
 
 // CalculateNodesToAdd returns the number of nodes to add during scale-up
 func CalculateNodesToAdd(ng *v1alpha1.NodeGroup) int32 {
 	needed := ng.Status.DesiredNodes - ng.Status.CurrentNodes
-	canAdd := ng.Spec.MaxNodes - ng.Status.CurrentNodes
+	canAdd := v1alpha1.EffectiveMaxNodes(ng) - ng.Status.CurrentNodes
 
 	if needed > canAdd {
 		return canAdd
@@ -166,7 +182,7 @@ func CalculateNodesToAdd(ng *v1alpha1.NodeGroup) int32 {
 // CalculateNodesToRemove returns the number of nodes to remove during scale-down
 func CalculateNodesToRemove(ng *v1alpha1.NodeGroup) int32 {
 	excess := ng.Status.CurrentNodes - ng.Status.DesiredNodes
-	canRemove := ng.Status.CurrentNodes - ng.Spec.MinNodes
+	canRemove := ng.Status.CurrentNodes - v1alpha1.EffectiveMinNodes(ng)
 
 	if excess > canRemove {
 		return canRemove
@@ -208,6 +224,14 @@ func ValidateNodeGroupSpec(ng *v1alpha1.NodeGroup) error {
 		return fmt.Errorf("invalid kubernetesVersion format: %w", err)
 	}
 
+	if err := validateHeadroom(ng.Spec.Headroom); err != nil {
+		return err
+	}
+
+	if err := ValidateSchedules(ng.Spec.Schedules); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -2,6 +2,7 @@ package nodegroup
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -202,6 +203,28 @@ func TestCalculateDesiredNodes(t *testing.T) {
 	}
 }
 
+func TestActivePrediction(t *testing.T) {
+	now := time.Now()
+	newNodeGroup := func(predicted int32, expiresAt *metav1.Time) *v1alpha1.NodeGroup {
+		return &v1alpha1.NodeGroup{
+			Spec: v1alpha1.NodeGroupSpec{MinNodes: 2, MaxNodes: 10},
+			Status: v1alpha1.NodeGroupStatus{
+				PredictedNodes:      predicted,
+				PredictionExpiresAt: expiresAt,
+			},
+		}
+	}
+	future := metav1.NewTime(now.Add(time.Minute))
+	past := metav1.NewTime(now.Add(-time.Minute))
+
+	assert.Equal(t, int32(6), ActivePrediction(newNodeGroup(6, &future), now))
+	assert.Equal(t, int32(10), ActivePrediction(newNodeGroup(15, &future), now), "bounded by MaxNodes")
+	assert.Equal(t, int32(2), ActivePrediction(newNodeGroup(1, &future), now), "bounded by MinNodes")
+	assert.Zero(t, ActivePrediction(newNodeGroup(6, &past), now), "expired")
+	assert.Zero(t, ActivePrediction(newNodeGroup(6, nil), now))
+	assert.Zero(t, ActivePrediction(newNodeGroup(0, &future), now))
+}
+
 func TestNeedsScaleUp(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -3,6 +3,9 @@ package controller
 import (
 	"fmt"
 	"time"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/drain"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
 )
 
 // Options holds configuration options for the controller manager
@@ -17,6 +20,12 @@ type Options struct {
 	// HealthProbeAddr is the address the health probe endpoint binds to
 	HealthProbeAddr string
 
+	// DebugAddr is the address the debug server serving scale-down
+	// simulations binds to. It exposes node, pod and PodDisruptionBudget
+	// details without authentication, so it listens on localhost by default.
+	// Empty or "0" disables it.
+	DebugAddr string
+
 	// EnableLeaderElection enables leader election for controller manager.
 	// Enabling this will ensure there is only one active controller manager
 	EnableLeaderElection bool
@@ -102,39 +111,141 @@ type Options struct {
 
 	// SentryErrorSampleRate is the sample rate for error events (0.0 to 1.0)
 	SentryErrorSampleRate float64
+
+	// Cost configuration
+
+	// PricingConfigMapName is the name of the ConfigMap containing the negotiated pricing plan
+	// (committed pricing, category discounts). If empty, VPSie list prices are used
+	PricingConfigMapName string
+
+	// PricingConfigMapNamespace is the namespace of the pricing plan ConfigMap
+	PricingConfigMapNamespace string
+
+	// PriceCatalogFile is the path to an offline price catalog (YAML or JSON).
+	// Takes precedence over PriceCatalogConfigMapName
+	PriceCatalogFile string
+
+	// PriceCatalogConfigMapName is the name of the ConfigMap containing the offline price catalog
+	PriceCatalogConfigMapName string
+
+	// PriceCatalogConfigMapNamespace is the namespace of the price catalog ConfigMap
+	PriceCatalogConfigMapNamespace string
+
+	// PriceCatalogMode controls how the price catalog is used (fallback, override)
+	PriceCatalogMode string
+
+	// SavingsLedgerWindow is the period NodeGroup cost is measured over before
+	// and after a scale-down or optimization to verify its savings. Zero
+	// disables the savings ledger
+	SavingsLedgerWindow time.Duration
+
+	// SavingsLedgerConfigMapName is the name of the ConfigMap the savings ledger
+	// is persisted in. If empty, ledger entries are lost on restart
+	SavingsLedgerConfigMapName string
+
+	// SavingsLedgerConfigMapNamespace is the namespace of the savings ledger ConfigMap
+	SavingsLedgerConfigMapNamespace string
+
+	// Carbon-aware placement configuration
+
+	// CarbonIntensityFile is the path to a per-datacenter carbon intensity table (YAML or JSON).
+	// If empty, placement ignores carbon intensity and no emissions are reported
+	CarbonIntensityFile string
+
+	// CandidateDatacenterIDs are the datacenters dynamic NodeGroups may be created in
+	CandidateDatacenterIDs []string
+
+	// CarbonObjective weighs carbon intensity against cost (cost, balanced, low-carbon)
+	CarbonObjective string
+
+	// Scale-up configuration
+
+	// PendingPodDetection selects how unschedulable pods are detected: events
+	// (FailedScheduling events) or pods (PodScheduled condition via a pod informer)
+	PendingPodDetection string
+
+	// Predictive scaling configuration
+
+	// PredictiveScaling selects the predictive scaler mode: off, shadow (forecast
+	// and export metrics only) or active (also pre-scale DesiredNodes)
+	PredictiveScaling string
+
+	// PredictiveScalingHorizon is how far ahead node demand is forecast
+	PredictiveScalingHorizon time.Duration
+
+	// PredictiveScalingLookbackDays is how many previous days a forecast draws on
+	PredictiveScalingLookbackDays int
+
+	// PredictiveScalingConfigMapName is the name of the ConfigMap the demand
+	// history is persisted in. Empty keeps it in memory only.
+	PredictiveScalingConfigMapName string
+
+	// PredictiveScalingConfigMapNamespace is the namespace of the demand history ConfigMap
+	PredictiveScalingConfigMapNamespace string
+
+	// Drain configuration
+
+	// DrainEvictionQPS is the rate of eviction requests across all node drains.
+	// Zero removes the limit
+	DrainEvictionQPS float64
+
+	// DrainEvictionBurst is the number of eviction requests allowed at once
+	// across all node drains
+	DrainEvictionBurst int
 }
 
 // NewDefaultOptions returns Options with default values
 func NewDefaultOptions() *Options {
 	return &Options{
-		Kubeconfig:              "",
-		MetricsAddr:             ":8080",
-		HealthProbeAddr:         ":8081",
-		EnableLeaderElection:    true,
-		LeaderElectionID:        "vpsie-autoscaler-leader",
-		LeaderElectionNamespace: "kube-system",
-		SyncPeriod:              10 * time.Minute,
-		VPSieSecretName:         "vpsie-secret",
-		VPSieSecretNamespace:    "kube-system",
-		LogLevel:                "info",
-		LogFormat:               "json",
-		DevelopmentMode:         false,
-		SSHKeyIDs:               nil, // No SSH keys by default
-		DefaultDatacenterID:     "",  // Must be set for dynamic NodeGroup creation
-		DefaultOfferingIDs:      nil, // Must be set for dynamic NodeGroup creation
-		ResourceIdentifier:      "",  // Must be set for dynamic NodeGroup creation
-		KubernetesVersion:       "",  // Must be set for dynamic NodeGroup creation
-		KubeSizeID:              0,   // Must be set for dynamic NodeGroup creation
-		FailedVPSieNodeTTL:      30 * time.Minute,
-		EnableWebhook:           false,
-		WebhookAddr:             ":9443",
-		WebhookCertDir:          "/var/run/webhook-certs",
-		WebhookCertFile:         "tls.crt",
-		WebhookKeyFile:          "tls.key",
-		SentryDSN:               "",  // Set via SENTRY_DSN env var or --sentry-dsn flag
-		SentryEnvironment:       "",  // Defaults to "development" if not set
-		SentryTracesSampleRate:  0.1, // 10% of transactions
-		SentryErrorSampleRate:   1.0, // 100% of errors
+		Kubeconfig:                          "",
+		MetricsAddr:                         ":8080",
+		HealthProbeAddr:                     ":8081",
+		DebugAddr:                           "127.0.0.1:8082",
+		EnableLeaderElection:                true,
+		LeaderElectionID:                    "vpsie-autoscaler-leader",
+		LeaderElectionNamespace:             "kube-system",
+		SyncPeriod:                          10 * time.Minute,
+		VPSieSecretName:                     "vpsie-secret",
+		VPSieSecretNamespace:                "kube-system",
+		LogLevel:                            "info",
+		LogFormat:                           "json",
+		DevelopmentMode:                     false,
+		SSHKeyIDs:                           nil, // No SSH keys by default
+		DefaultDatacenterID:                 "",  // Must be set for dynamic NodeGroup creation
+		DefaultOfferingIDs:                  nil, // Must be set for dynamic NodeGroup creation
+		ResourceIdentifier:                  "",  // Must be set for dynamic NodeGroup creation
+		KubernetesVersion:                   "",  // Must be set for dynamic NodeGroup creation
+		KubeSizeID:                          0,   // Must be set for dynamic NodeGroup creation
+		FailedVPSieNodeTTL:                  30 * time.Minute,
+		EnableWebhook:                       false,
+		WebhookAddr:                         ":9443",
+		WebhookCertDir:                      "/var/run/webhook-certs",
+		WebhookCertFile:                     "tls.crt",
+		WebhookKeyFile:                      "tls.key",
+		SentryDSN:                           "",  // Set via SENTRY_DSN env var or --sentry-dsn flag
+		SentryEnvironment:                   "",  // Defaults to "development" if not set
+		SentryTracesSampleRate:              0.1, // 10% of transactions
+		SentryErrorSampleRate:               1.0, // 100% of errors
+		PricingConfigMapName:                "",  // List prices unless a pricing plan is configured
+		PricingConfigMapNamespace:           "kube-system",
+		PriceCatalogFile:                    "", // No offline price catalog by default
+		PriceCatalogConfigMapName:           "",
+		PriceCatalogConfigMapNamespace:      "kube-system",
+		PriceCatalogMode:                    "fallback",
+		SavingsLedgerWindow:                 cost.DefaultLedgerWindow,
+		SavingsLedgerConfigMapName:          "vpsie-autoscaler-savings-ledger",
+		SavingsLedgerConfigMapNamespace:     "kube-system",
+		CarbonIntensityFile:                 "", // Carbon-aware placement disabled by default
+		CandidateDatacenterIDs:              nil,
+		CarbonObjective:                     "cost",
+		PendingPodDetection:                 "events",
+		PredictiveScaling:                   "off",
+		PredictiveScalingHorizon:            30 * time.Minute,
+		PredictiveScalingLookbackDays:       7,
+		PredictiveScalingConfigMapName:      "vpsie-autoscaler-predictive-history",
+		PredictiveScalingConfigMapNamespace: "kube-system",
+		DrainEvictionQPS:                    drain.DefaultEvictionQPS,
+		DrainEvictionBurst:                  drain.DefaultEvictionBurst,
 	}
 }
 
@@ -152,6 +263,10 @@ func (o *Options) Validate() error {
 		return fmt.Errorf("metrics address and health probe address cannot be the same")
 	}
 
+	if o.DebugAddr != "" && o.DebugAddr != "0" && (o.DebugAddr == o.MetricsAddr || o.DebugAddr == o.HealthProbeAddr) {
+		return fmt.Errorf("debug address cannot be the same as the metrics or health probe address")
+	}
+
 	if o.EnableLeaderElection {
 		if o.LeaderElectionID == "" {
 			return fmt.Errorf("leader election ID cannot be empty when leader election is enabled")
@@ -198,6 +313,52 @@ func (o *Options) Validate() error {
 		return fmt.Errorf("failed VPSieNode TTL cannot be negative")
 	}
 
+	// Validate price catalog mode (empty is treated as fallback)
+	if o.PriceCatalogMode != "" && o.PriceCatalogMode != "fallback" && o.PriceCatalogMode != "override" {
+		return fmt.Errorf("invalid price catalog mode '%s', must be one of: fallback, override", o.PriceCatalogMode)
+	}
+
+	// Validate savings ledger window (0 is valid, meaning disabled)
+	if o.SavingsLedgerWindow < 0 {
+		return fmt.Errorf("savings ledger window cannot be negative")
+	}
+
+	// Validate carbon objective (empty is treated as cost)
+	validCarbonObjectives := map[string]bool{
+		"":           true,
+		"cost":       true,
+		"balanced":   true,
+		"low-carbon": true,
+	}
+	if !validCarbonObjectives[o.CarbonObjective] {
+		return fmt.Errorf("invalid carbon objective '%s', must be one of: cost, balanced, low-carbon", o.CarbonObjective)
+	}
+
+	// Validate pending pod detection mode (empty is treated as events)
+	if o.PendingPodDetection != "" && o.PendingPodDetection != "events" && o.PendingPodDetection != "pods" {
+		return fmt.Errorf("invalid pending pod detection mode '%s', must be one of: events, pods", o.PendingPodDetection)
+	}
+
+	// Validate predictive scaling (empty is treated as off)
+	if o.PredictiveScaling != "" && o.PredictiveScaling != "off" &&
+		o.PredictiveScaling != "shadow" && o.PredictiveScaling != "active" {
+		return fmt.Errorf("invalid predictive scaling mode '%s', must be one of: off, shadow, active", o.PredictiveScaling)
+	}
+	if o.PredictiveScalingHorizon < 0 {
+		return fmt.Errorf("predictive scaling horizon cannot be negative")
+	}
+	if o.PredictiveScalingLookbackDays < 0 {
+		return fmt.Errorf("predictive scaling lookback days cannot be negative")
+	}
+
+	// Validate drain eviction rate (0 QPS is valid, meaning unlimited)
+	if o.DrainEvictionQPS < 0 {
+		return fmt.Errorf("drain eviction QPS cannot be negative")
+	}
+	if o.DrainEvictionQPS > 0 && o.DrainEvictionBurst < 1 {
+		return fmt.Errorf("drain eviction burst must be at least 1")
+	}
+
 	// Validate webhook configuration
 	if o.EnableWebhook {
 		if o.WebhookAddr == "" {
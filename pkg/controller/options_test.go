@@ -14,6 +14,7 @@ func TestNewDefaultOptions(t *testing.T) {
 	assert.NotNil(t, opts)
 	assert.Equal(t, ":8080", opts.MetricsAddr)
 	assert.Equal(t, ":8081", opts.HealthProbeAddr)
+	assert.Equal(t, "127.0.0.1:8082", opts.DebugAddr)
 	assert.True(t, opts.EnableLeaderElection)
 	assert.Equal(t, "vpsie-autoscaler-leader", opts.LeaderElectionID)
 	assert.Equal(t, "kube-system", opts.LeaderElectionNamespace)
@@ -23,6 +24,10 @@ func TestNewDefaultOptions(t *testing.T) {
 	assert.Equal(t, "info", opts.LogLevel)
 	assert.Equal(t, "json", opts.LogFormat)
 	assert.False(t, opts.DevelopmentMode)
+	assert.Equal(t, float64(10), opts.DrainEvictionQPS)
+	assert.Equal(t, 20, opts.DrainEvictionBurst)
+	assert.Equal(t, 7*24*time.Hour, opts.SavingsLedgerWindow)
+	assert.Equal(t, "vpsie-autoscaler-savings-ledger", opts.SavingsLedgerConfigMapName)
 }
 
 func TestOptions_Validate(t *testing.T) {
@@ -256,6 +261,60 @@ func TestOptions_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "negative drain eviction QPS",
+			opts: &Options{
+				MetricsAddr:             ":8080",
+				HealthProbeAddr:         ":8081",
+				EnableLeaderElection:    true,
+				LeaderElectionID:        "test",
+				LeaderElectionNamespace: "default",
+				SyncPeriod:              time.Minute,
+				VPSieSecretName:         "secret",
+				VPSieSecretNamespace:    "default",
+				LogLevel:                "info",
+				LogFormat:               "json",
+				DrainEvictionQPS:        -1,
+			},
+			wantErr: true,
+			errMsg:  "drain eviction QPS cannot be negative",
+		},
+		{
+			name: "negative savings ledger window",
+			opts: &Options{
+				MetricsAddr:             ":8080",
+				HealthProbeAddr:         ":8081",
+				EnableLeaderElection:    true,
+				LeaderElectionID:        "test",
+				LeaderElectionNamespace: "default",
+				SyncPeriod:              time.Minute,
+				VPSieSecretName:         "secret",
+				VPSieSecretNamespace:    "default",
+				LogLevel:                "info",
+				LogFormat:               "json",
+				SavingsLedgerWindow:     -time.Hour,
+			},
+			wantErr: true,
+			errMsg:  "savings ledger window cannot be negative",
+		},
+		{
+			name: "debug address same as metrics address",
+			opts: &Options{
+				MetricsAddr:             ":8080",
+				HealthProbeAddr:         ":8081",
+				DebugAddr:               ":8080",
+				EnableLeaderElection:    true,
+				LeaderElectionID:        "test",
+				LeaderElectionNamespace: "default",
+				SyncPeriod:              time.Minute,
+				VPSieSecretName:         "secret",
+				VPSieSecretNamespace:    "default",
+				LogLevel:                "info",
+				LogFormat:               "json",
+			},
+			wantErr: true,
+			errMsg:  "debug address cannot be the same as the metrics or health probe address",
+		},
 		{
 			name: "valid with console log format",
 			opts: &Options{
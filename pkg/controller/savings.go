@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/scaler"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
+)
+
+const (
+	// costSnapshotInterval is how often NodeGroup cost snapshots are recorded
+	// for the savings ledger
+	costSnapshotInterval = 15 * time.Minute
+
+	// ledgerRetentionWindows is how many ledger windows verified entries are
+	// kept for, feeding prediction accuracy back into confidence scores
+	ledgerRetentionWindows = 8
+)
+
+var (
+	costMetricsOnce sync.Once
+	costMetrics     *cost.Metrics
+)
+
+// getCostMetrics returns the cost metrics, registered with the controller
+// metrics registry on first use
+func getCostMetrics() *cost.Metrics {
+	costMetricsOnce.Do(func() {
+		costMetrics = cost.NewMetrics(ctrlmetrics.Registry)
+	})
+	return costMetrics
+}
+
+// savingsTracker records NodeGroup cost snapshots and verifies the savings
+// ledger against them once each entry's window has elapsed
+type savingsTracker struct {
+	client      client.Client
+	analyzer    *cost.Analyzer
+	storage     cost.CostStorage
+	ledger      *cost.SavingsLedger
+	metrics     *cost.Metrics
+	utilization func(nodeName string) (*scaler.NodeUtilization, bool)
+	interval    time.Duration
+	logger      *zap.Logger
+}
+
+// Run restores the ledger and then records snapshots and verifies entries
+// every interval until the context is cancelled
+func (t *savingsTracker) Run(ctx context.Context) {
+	t.logger.Info("Starting savings ledger",
+		zap.Duration("window", t.ledger.Window()),
+		zap.Duration("snapshotInterval", t.interval),
+	)
+
+	if err := t.ledger.Load(ctx); err != nil {
+		t.logger.Error("Failed to restore savings ledger, starting empty", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.logger.Info("Stopping savings ledger")
+			return
+		case <-ticker.C:
+			if err := t.Sample(ctx, time.Now()); err != nil {
+				t.logger.Error("Failed to update savings ledger", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Sample records a cost snapshot of every NodeGroup, verifies ledger entries
+// whose window has elapsed and drops snapshots and entries no longer needed
+func (t *savingsTracker) Sample(ctx context.Context, now time.Time) error {
+	ngList := &v1alpha1.NodeGroupList{}
+	if err := t.client.List(ctx, ngList); err != nil {
+		return fmt.Errorf("failed to list NodeGroups: %w", err)
+	}
+
+	for i := range ngList.Items {
+		ng := &ngList.Items[i]
+		if err := t.analyzer.RecordCost(ctx, ng, t.nodeGroupUtilization(ng)); err != nil {
+			t.logger.Debug("Failed to record NodeGroup cost snapshot",
+				zap.String("nodegroup", ng.Name),
+				zap.String("namespace", ng.Namespace),
+				zap.Error(err),
+			)
+			continue
+		}
+		t.metrics.RecordSnapshot(ng.Name, ng.Namespace)
+	}
+
+	window := t.ledger.Window()
+	updated, err := t.ledger.Verify(ctx, now)
+	for i := range updated {
+		entry := &updated[i]
+		t.metrics.RecordLedgerEntry(entry)
+		t.logger.Info("Verified savings ledger entry",
+			zap.String("id", entry.ID),
+			zap.String("nodegroup", entry.NodeGroupName),
+			zap.String("namespace", entry.Namespace),
+			zap.String("type", string(entry.Type)),
+			zap.String("status", string(entry.Status)),
+			zap.Float64("predictedMonthlySavings", entry.PredictedMonthlySavings),
+			zap.Float64("realizedMonthlySavings", entry.RealizedMonthlySavings),
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify savings ledger: %w", err)
+	}
+
+	// Snapshots are needed for a window on each side of pending entries
+	if err := t.storage.DeleteOldSnapshots(ctx, now.Add(-2*window)); err != nil {
+		return fmt.Errorf("failed to delete old cost snapshots: %w", err)
+	}
+	if err := t.ledger.Prune(ctx, now.Add(-ledgerRetentionWindows*window)); err != nil {
+		return fmt.Errorf("failed to prune savings ledger: %w", err)
+	}
+	return nil
+}
+
+// nodeGroupUtilization returns the mean utilization of a NodeGroup's nodes
+// from their latest utilization samples
+func (t *savingsTracker) nodeGroupUtilization(ng *v1alpha1.NodeGroup) cost.ResourceUtilization {
+	utilization := cost.ResourceUtilization{NodeCount: ng.Status.CurrentNodes}
+	if t.utilization == nil {
+		return utilization
+	}
+
+	var sampled float64
+	for _, node := range ng.Status.Nodes {
+		util, ok := t.utilization(node.NodeName)
+		if !ok || len(util.Samples) == 0 {
+			continue
+		}
+		latest := util.Samples[len(util.Samples)-1]
+		utilization.CPUPercent += latest.CPUUtilization
+		utilization.MemoryPercent += latest.MemoryUtilization
+		utilization.CPURequestsPercent += latest.CPURequests
+		utilization.MemoryRequestsPercent += latest.MemoryRequests
+		sampled++
+	}
+	if sampled > 0 {
+		utilization.CPUPercent /= sampled
+		utilization.MemoryPercent /= sampled
+		utilization.CPURequestsPercent /= sampled
+		utilization.MemoryRequestsPercent /= sampled
+	}
+	return utilization
+}
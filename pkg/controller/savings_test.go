@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/scaler"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
+)
+
+const savingsTestCatalog = `
+version: "2024-06-01"
+offerings:
+  - id: small-1
+    name: Small Instance
+    cpu: 2
+    ram: 2048
+    disk: 50
+    price: 10.0
+    hourly_price: 0.0137
+    available: true
+    category: standard
+`
+
+func newSavingsTestNodeGroup(nodes ...string) *v1alpha1.NodeGroup {
+	ng := &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "workers", Namespace: "default"},
+		Spec: v1alpha1.NodeGroupSpec{
+			MinNodes:    1,
+			MaxNodes:    5,
+			OfferingIDs: []string{"small-1"},
+		},
+	}
+	setSavingsTestNodes(ng, nodes...)
+	return ng
+}
+
+func setSavingsTestNodes(ng *v1alpha1.NodeGroup, nodes ...string) {
+	ng.Status.Nodes = nil
+	for _, name := range nodes {
+		ng.Status.Nodes = append(ng.Status.Nodes, v1alpha1.NodeInfo{NodeName: name, InstanceType: "small-1"})
+	}
+	ng.Status.CurrentNodes = int32(len(nodes))
+}
+
+func newSavingsTestTracker(t *testing.T, window time.Duration, objects ...client.Object) *savingsTracker {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	catalog, err := cost.ParsePriceCatalog([]byte(savingsTestCatalog))
+	require.NoError(t, err)
+	calculator := cost.NewCalculator(nil)
+	calculator.SetPriceCatalog(catalog, cost.CatalogModeOverride)
+
+	storage := cost.NewMemoryCostStorage()
+	analyzer := cost.NewAnalyzer(calculator, storage)
+
+	return &savingsTracker{
+		client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		analyzer: analyzer,
+		storage:  storage,
+		ledger:   cost.NewSavingsLedger(analyzer, window),
+		metrics:  getCostMetrics(),
+		interval: time.Minute,
+		logger:   zaptest.NewLogger(t),
+	}
+}
+
+func TestSavingsTrackerSample(t *testing.T) {
+	ctx := context.Background()
+	window := time.Hour
+	ng := newSavingsTestNodeGroup("node-1", "node-2", "node-3")
+	tracker := newSavingsTestTracker(t, window, ng)
+
+	// Baseline with three nodes
+	for i := 0; i < cost.MinLedgerSamples; i++ {
+		require.NoError(t, tracker.Sample(ctx, time.Now()))
+	}
+	snapshots, err := tracker.storage.GetSnapshots(ctx, "workers", "default", time.Now().Add(-window), time.Now())
+	require.NoError(t, err)
+	assert.Len(t, snapshots, cost.MinLedgerSamples)
+
+	time.Sleep(time.Millisecond)
+	appliedAt := time.Now()
+	entry, err := tracker.ledger.RecordApplied(ctx, "workers", "default", &cost.Opportunity{
+		Type:           cost.OptimizationScaleDown,
+		MonthlySavings: 10.0,
+	}, appliedAt)
+	require.NoError(t, err)
+	assert.Equal(t, cost.LedgerStatusPending, entry.Status)
+	assert.InDelta(t, 30.0, entry.BaselineMonthly, 1.0)
+	time.Sleep(time.Millisecond)
+
+	// One node removed
+	current := &v1alpha1.NodeGroup{}
+	require.NoError(t, tracker.client.Get(ctx, client.ObjectKeyFromObject(ng), current))
+	setSavingsTestNodes(current, "node-1", "node-2")
+	require.NoError(t, tracker.client.Update(ctx, current))
+
+	for i := 0; i < cost.MinLedgerSamples; i++ {
+		require.NoError(t, tracker.Sample(ctx, time.Now()))
+	}
+	assert.Equal(t, cost.LedgerStatusPending, tracker.ledger.Entries()[0].Status,
+		"entries are not verified before their window elapses")
+
+	require.NoError(t, tracker.Sample(ctx, appliedAt.Add(window+time.Minute)))
+
+	entries := tracker.ledger.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, cost.LedgerStatusVerified, entries[0].Status)
+	assert.InDelta(t, 10.0, entries[0].RealizedMonthlySavings, 1.0)
+}
+
+func TestSavingsTrackerNodeGroupUtilization(t *testing.T) {
+	tracker := newSavingsTestTracker(t, time.Hour)
+	ng := newSavingsTestNodeGroup("node-1", "node-2", "node-3")
+
+	utilization := tracker.nodeGroupUtilization(ng)
+	assert.Equal(t, int32(3), utilization.NodeCount)
+	assert.Zero(t, utilization.CPUPercent)
+
+	tracker.utilization = func(nodeName string) (*scaler.NodeUtilization, bool) {
+		switch nodeName {
+		case "node-1":
+			return &scaler.NodeUtilization{Samples: []scaler.UtilizationSample{
+				{CPUUtilization: 90, MemoryUtilization: 90},
+				{CPUUtilization: 20, MemoryUtilization: 40, CPURequests: 50, MemoryRequests: 60},
+			}}, true
+		case "node-2":
+			return &scaler.NodeUtilization{Samples: []scaler.UtilizationSample{
+				{CPUUtilization: 40, MemoryUtilization: 60, CPURequests: 30, MemoryRequests: 20},
+			}}, true
+		}
+		return nil, false
+	}
+
+	// Only nodes with samples are averaged, from their latest sample
+	utilization = tracker.nodeGroupUtilization(ng)
+	assert.Equal(t, int32(3), utilization.NodeCount)
+	assert.InDelta(t, 30.0, utilization.CPUPercent, 0.001)
+	assert.InDelta(t, 50.0, utilization.MemoryPercent, 0.001)
+	assert.InDelta(t, 40.0, utilization.CPURequestsPercent, 0.001)
+	assert.InDelta(t, 40.0, utilization.MemoryRequestsPercent, 0.001)
+}
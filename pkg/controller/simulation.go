@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/scaler"
+)
+
+// ScaleDownSimulationPath is the path of the scale-down dry-run endpoint on
+// the debug server
+const ScaleDownSimulationPath = "/debug/scale-down"
+
+// ScaleDownSimulationHandler serves scale-down dry-runs for a NodeGroup or node.
+//
+// Query parameters:
+//   - nodegroup: the NodeGroup to simulate (derived from the node if omitted)
+//   - namespace: the NodeGroup's namespace, required if its name is ambiguous
+//   - node: only simulate this node
+//   - format: json (default) or text
+//
+// Utilization is tracked by the leader, so only the leader's reports reflect
+// the observation window. One simulation runs at a time; concurrent requests
+// are rejected.
+type ScaleDownSimulationHandler struct {
+	mu               sync.RWMutex
+	reader           client.Reader
+	scaleDownManager *scaler.ScaleDownManager
+
+	// running is held while a simulation runs
+	running sync.Mutex
+}
+
+// SetDependencies sets the client used to look up NodeGroups and the
+// ScaleDownManager running the checks. Requests fail until they are set.
+func (h *ScaleDownSimulationHandler) SetDependencies(reader client.Reader, scaleDownManager *scaler.ScaleDownManager) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reader = reader
+	h.scaleDownManager = scaleDownManager
+}
+
+// ServeHTTP implements http.Handler
+func (h *ScaleDownSimulationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.mu.RLock()
+	reader, scaleDownManager := h.reader, h.scaleDownManager
+	h.mu.RUnlock()
+	if reader == nil || scaleDownManager == nil {
+		http.Error(w, "scale-down manager not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	if !h.running.TryLock() {
+		http.Error(w, "a simulation is already running", http.StatusTooManyRequests)
+		return
+	}
+	defer h.running.Unlock()
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = scaler.SimulationFormatJSON
+	}
+	if format != scaler.SimulationFormatJSON && format != scaler.SimulationFormatText {
+		http.Error(w, fmt.Sprintf("invalid format %q, must be one of: json, text", format), http.StatusBadRequest)
+		return
+	}
+
+	nodeGroup, status, err := findSimulationNodeGroup(r.Context(), reader,
+		query.Get("namespace"), query.Get("nodegroup"), query.Get("node"))
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	report, err := scaleDownManager.SimulateScaleDown(r.Context(), nodeGroup, query.Get("node"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("simulation failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if format == scaler.SimulationFormatJSON {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	_ = scaler.WriteSimulationReport(w, report, format)
+}
+
+// findSimulationNodeGroup returns the NodeGroup to simulate and, on failure,
+// the HTTP status to respond with
+func findSimulationNodeGroup(
+	ctx context.Context,
+	reader client.Reader,
+	namespace, name, nodeName string,
+) (*v1alpha1.NodeGroup, int, error) {
+	if name == "" {
+		if nodeName == "" {
+			return nil, http.StatusBadRequest, fmt.Errorf("nodegroup or node is required")
+		}
+		node := &corev1.Node{}
+		if err := reader.Get(ctx, client.ObjectKey{Name: nodeName}, node); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, http.StatusNotFound, fmt.Errorf("node %s not found", nodeName)
+			}
+			return nil, http.StatusInternalServerError, fmt.Errorf("failed to get node: %w", err)
+		}
+		name = node.Labels[v1alpha1.NodeGroupLabelKey]
+		if name == "" {
+			return nil, http.StatusNotFound, fmt.Errorf("node %s doesn't belong to a NodeGroup", nodeName)
+		}
+	}
+
+	if namespace != "" {
+		nodeGroup := &v1alpha1.NodeGroup{}
+		if err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, nodeGroup); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, http.StatusNotFound, fmt.Errorf("NodeGroup %s/%s not found", namespace, name)
+			}
+			return nil, http.StatusInternalServerError, fmt.Errorf("failed to get NodeGroup: %w", err)
+		}
+		return nodeGroup, http.StatusOK, nil
+	}
+
+	list := &v1alpha1.NodeGroupList{}
+	if err := reader.List(ctx, list); err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to list NodeGroups: %w", err)
+	}
+	var matches []*v1alpha1.NodeGroup
+	for i := range list.Items {
+		if list.Items[i].Name == name {
+			matches = append(matches, &list.Items[i])
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, http.StatusNotFound, fmt.Errorf("NodeGroup %s not found", name)
+	case 1:
+		return matches[0], http.StatusOK, nil
+	default:
+		return nil, http.StatusBadRequest, fmt.Errorf("NodeGroup %s exists in %d namespaces, namespace is required", name, len(matches))
+	}
+}
+
+// debugServer serves the debug endpoints on every replica. The endpoints are
+// unauthenticated, so the server is meant to listen on localhost only.
+type debugServer struct {
+	addr    string
+	handler http.Handler
+	logger  *zap.Logger
+}
+
+// newDebugServer creates the debug server serving scale-down simulations
+func newDebugServer(addr string, simulation http.Handler, logger *zap.Logger) *debugServer {
+	mux := http.NewServeMux()
+	mux.Handle(ScaleDownSimulationPath, simulation)
+	return &debugServer{addr: addr, handler: mux, logger: logger}
+}
+
+// Start serves the debug endpoints until the context is cancelled
+func (s *debugServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on debug address %s: %w", s.addr, err)
+	}
+
+	server := &http.Server{
+		Handler:           s.handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			s.logger.Error("Failed to shut down debug server", zap.Error(err))
+		}
+	}()
+
+	s.logger.Info("Starting debug server", zap.String("address", listener.Addr().String()))
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("debug server failed: %w", err)
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (s *debugServer) NeedLeaderElection() bool {
+	return false
+}
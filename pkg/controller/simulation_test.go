@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/scaler"
+)
+
+func newSimulationTestHandler(t *testing.T, objects ...client.Object) *ScaleDownSimulationHandler {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	// The ScaleDownManager lists the nodes through the clientset
+	var nodes []runtime.Object
+	for _, obj := range objects {
+		if node, ok := obj.(*corev1.Node); ok {
+			nodes = append(nodes, node.DeepCopy())
+		}
+	}
+	scaleDownManager := scaler.NewScaleDownManager(k8sfake.NewSimpleClientset(nodes...), nil, zaptest.NewLogger(t), scaler.DefaultConfig())
+
+	handler := &ScaleDownSimulationHandler{}
+	handler.SetDependencies(reader, scaleDownManager)
+	return handler
+}
+
+func newSimulationTestNodeGroup(namespace string) *v1alpha1.NodeGroup {
+	return &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "general",
+			Namespace: namespace,
+			Labels:    map[string]string{v1alpha1.ManagedLabelKey: v1alpha1.ManagedLabelValue},
+		},
+		Spec: v1alpha1.NodeGroupSpec{
+			MinNodes:        1,
+			MaxNodes:        5,
+			ScaleDownPolicy: v1alpha1.ScaleDownPolicy{Enabled: true},
+		},
+	}
+}
+
+func TestScaleDownSimulationHandler_NotReady(t *testing.T) {
+	handler := &ScaleDownSimulationHandler{}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ScaleDownSimulationPath+"?nodegroup=general", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestScaleDownSimulationHandler(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   "worker-1",
+		Labels: map[string]string{v1alpha1.NodeGroupLabelKey: "general"},
+	}}
+	unlabeled := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-2"}}
+
+	tests := []struct {
+		name        string
+		objects     []client.Object
+		query       string
+		wantStatus  int
+		contentType string
+		namespace   string
+	}{
+		{
+			name:        "NodeGroup by name",
+			objects:     []client.Object{newSimulationTestNodeGroup("kube-system")},
+			query:       "?nodegroup=general",
+			wantStatus:  http.StatusOK,
+			contentType: "application/json",
+			namespace:   "kube-system",
+		},
+		{
+			name:        "NodeGroup by namespace and name as text",
+			objects:     []client.Object{newSimulationTestNodeGroup("kube-system"), newSimulationTestNodeGroup("default")},
+			query:       "?nodegroup=general&namespace=default&format=text",
+			wantStatus:  http.StatusOK,
+			contentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:        "NodeGroup derived from the node",
+			objects:     []client.Object{newSimulationTestNodeGroup("kube-system"), node},
+			query:       "?node=worker-1",
+			wantStatus:  http.StatusOK,
+			contentType: "application/json",
+			namespace:   "kube-system",
+		},
+		{
+			name:       "ambiguous NodeGroup name",
+			objects:    []client.Object{newSimulationTestNodeGroup("kube-system"), newSimulationTestNodeGroup("default")},
+			query:      "?nodegroup=general",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown NodeGroup",
+			query:      "?nodegroup=general&namespace=default",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "node outside any NodeGroup",
+			objects:    []client.Object{unlabeled},
+			query:      "?node=worker-2",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "missing nodegroup and node",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid format",
+			objects:    []client.Object{newSimulationTestNodeGroup("default")},
+			query:      "?nodegroup=general&format=yaml",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newSimulationTestHandler(t, tt.objects...)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ScaleDownSimulationPath+tt.query, nil))
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.contentType != "" {
+				assert.Equal(t, tt.contentType, rec.Header().Get("Content-Type"))
+			}
+			if tt.namespace != "" {
+				var report scaler.SimulationReport
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+				assert.Equal(t, "general", report.NodeGroup)
+				assert.Equal(t, tt.namespace, report.Namespace)
+				assert.NotEmpty(t, report.Checks)
+			}
+		})
+	}
+}
+
+func TestScaleDownSimulationHandler_MethodNotAllowed(t *testing.T) {
+	handler := newSimulationTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ScaleDownSimulationPath, nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestScaleDownSimulationHandler_OneAtATime(t *testing.T) {
+	handler := newSimulationTestHandler(t, newSimulationTestNodeGroup("default"))
+	handler.running.Lock()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ScaleDownSimulationPath+"?nodegroup=general", nil))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	handler.running.Unlock()
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ScaleDownSimulationPath+"?nodegroup=general", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestDebugServer(t *testing.T) {
+	server := newDebugServer("127.0.0.1:0", http.NotFoundHandler(), zaptest.NewLogger(t))
+	assert.False(t, server.NeedLeaderElection())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Start(ctx) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("debug server did not stop")
+	}
+}
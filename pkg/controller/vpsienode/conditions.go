@@ -60,6 +60,18 @@ const (
 
 	// ReasonTTLExpired indicates the VPSieNode was deleted due to TTL expiration
 	ReasonTTLExpired = "TTLExpired"
+
+	// ReasonHookRunning indicates a drain hook is running
+	ReasonHookRunning = "HookRunning"
+
+	// ReasonHookSucceeded indicates a drain hook succeeded
+	ReasonHookSucceeded = "HookSucceeded"
+
+	// ReasonHookFailed indicates a drain hook failed
+	ReasonHookFailed = "HookFailed"
+
+	// ReasonHookTimedOut indicates a drain hook did not finish within its timeout
+	ReasonHookTimedOut = "HookTimedOut"
 )
 
 // SetCondition sets or updates a condition on the VPSieNode
@@ -7,6 +7,7 @@ import (
 
 	"github.com/getsentry/sentry-go"
 	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -17,6 +18,7 @@ import (
 
 	"github.com/vpsie/vpsie-k8s-autoscaler/internal/logging"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/drain"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/tracing"
 )
 
@@ -51,14 +53,16 @@ func NewVPSieNodeReconciler(
 	client client.Client,
 	scheme *runtime.Scheme,
 	vpsieClient VPSieClientInterface,
+	nodeDrainer drain.Drainer,
 	logger *zap.Logger,
 	sshKeyIDs []string,
 	failedNodeTTL time.Duration,
 ) *VPSieNodeReconciler {
 	provisioner := NewProvisioner(vpsieClient, sshKeyIDs)
 	joiner := NewJoiner(client, provisioner)
-	drainer := NewDrainer(client)
+	drainer := NewDrainer(client, nodeDrainer)
 	terminator := NewTerminator(drainer, provisioner)
+	terminator.SetHookRunner(NewHookRunner(client))
 	stateMachine := NewStateMachine(provisioner, joiner, terminator, failedNodeTTL, client)
 
 	// Create and inject discoverer for async VPS ID discovery
@@ -89,6 +93,7 @@ func (r *VPSieNodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.VPSieNode{}).
 		Owns(&corev1.Node{}).
+		Owns(&batchv1.Job{}).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: DefaultMaxConcurrentReconciles,
 		}).
@@ -99,6 +104,7 @@ func (r *VPSieNodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
 // +kubebuilder:rbac:groups=autoscaler.vpsie.com,resources=vpsienodes/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=autoscaler.vpsie.com,resources=vpsienodes/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop
 func (r *VPSieNodeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
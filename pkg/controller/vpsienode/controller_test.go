@@ -55,7 +55,7 @@ func TestPendingPhaseTransition(t *testing.T) {
 
 	provisioner := NewProvisioner(mockVPSie, nil)
 	joiner := NewJoiner(client, provisioner)
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	terminator := NewTerminator(drainer, provisioner)
 	reconciler.stateMachine = NewStateMachine(provisioner, joiner, terminator, 24*time.Hour, client)
 	reconciler.provisioner = provisioner
@@ -118,7 +118,7 @@ func TestProvisioningPhaseTransition(t *testing.T) {
 
 	provisioner := NewProvisioner(mockVPSie, nil)
 	joiner := NewJoiner(client, provisioner)
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	terminator := NewTerminator(drainer, provisioner)
 	reconciler.stateMachine = NewStateMachine(provisioner, joiner, terminator, 24*time.Hour, client)
 	reconciler.provisioner = provisioner
@@ -211,7 +211,7 @@ func TestProvisioningPhaseWithExistingVPS(t *testing.T) {
 
 	provisioner := NewProvisioner(mockVPSie, nil)
 	joiner := NewJoiner(client, provisioner)
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	terminator := NewTerminator(drainer, provisioner)
 	reconciler.stateMachine = NewStateMachine(provisioner, joiner, terminator, 24*time.Hour, client)
 	reconciler.provisioner = provisioner
@@ -276,7 +276,7 @@ func TestProvisionedPhaseTransition(t *testing.T) {
 
 	provisioner := NewProvisioner(mockVPSie, nil)
 	joiner := NewJoiner(client, provisioner)
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	terminator := NewTerminator(drainer, provisioner)
 	reconciler.stateMachine = NewStateMachine(provisioner, joiner, terminator, 24*time.Hour, client)
 	reconciler.provisioner = provisioner
@@ -361,7 +361,7 @@ func TestJoiningPhaseTransitionWithNode(t *testing.T) {
 
 	provisioner := NewProvisioner(mockVPSie, nil)
 	joiner := NewJoiner(client, provisioner)
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	terminator := NewTerminator(drainer, provisioner)
 	reconciler.stateMachine = NewStateMachine(provisioner, joiner, terminator, 24*time.Hour, client)
 	reconciler.provisioner = provisioner
@@ -432,7 +432,7 @@ func TestJoiningPhaseWaitingForNode(t *testing.T) {
 
 	provisioner := NewProvisioner(mockVPSie, nil)
 	joiner := NewJoiner(client, provisioner)
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	terminator := NewTerminator(drainer, provisioner)
 	reconciler.stateMachine = NewStateMachine(provisioner, joiner, terminator, 24*time.Hour, client)
 	reconciler.provisioner = provisioner
@@ -505,7 +505,7 @@ func TestProvisioningTimeout(t *testing.T) {
 
 	provisioner := NewProvisioner(mockVPSie, nil)
 	joiner := NewJoiner(client, provisioner)
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	terminator := NewTerminator(drainer, provisioner)
 	reconciler.stateMachine = NewStateMachine(provisioner, joiner, terminator, 24*time.Hour, client)
 	reconciler.provisioner = provisioner
@@ -572,7 +572,7 @@ func TestJoiningTimeout(t *testing.T) {
 
 	provisioner := NewProvisioner(mockVPSie, nil)
 	joiner := NewJoiner(client, provisioner)
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	terminator := NewTerminator(drainer, provisioner)
 	reconciler.stateMachine = NewStateMachine(provisioner, joiner, terminator, 24*time.Hour, client)
 	reconciler.provisioner = provisioner
@@ -634,7 +634,7 @@ func TestVPSNotFoundError(t *testing.T) {
 
 	provisioner := NewProvisioner(mockVPSie, nil)
 	joiner := NewJoiner(client, provisioner)
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	terminator := NewTerminator(drainer, provisioner)
 	reconciler.stateMachine = NewStateMachine(provisioner, joiner, terminator, 24*time.Hour, client)
 	reconciler.provisioner = provisioner
@@ -3,337 +3,132 @@ package vpsienode
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
-	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/drain"
 )
 
 const (
 	// DefaultDrainTimeout is the default timeout for draining a node
 	DefaultDrainTimeout = 10 * time.Minute
 
-	// DefaultPodEvictionTimeout is the timeout for a single pod eviction
-	DefaultPodEvictionTimeout = 2 * time.Minute
-
-	// PodDeletionGracePeriod is the grace period for pod deletion
-	PodDeletionGracePeriod = 30 * time.Second
-
-	// PollInterval is the interval for polling pod status
-	PollInterval = 5 * time.Second
+	// drainProgressInterval is the minimum interval between updates of a
+	// VPSieNode's drain progress while its node is drained
+	drainProgressInterval = 5 * time.Second
 )
 
-// Drainer handles graceful node draining
+// Drainer drains and deletes the Kubernetes nodes of VPSieNodes
 type Drainer struct {
-	client        client.Client
-	drainTimeout  time.Duration
-	evictionRetry int
+	client       client.Client
+	nodeDrainer  drain.Drainer
+	drainTimeout time.Duration
 }
 
-// NewDrainer creates a new Drainer
-func NewDrainer(client client.Client) *Drainer {
+// NewDrainer creates a new Drainer that drains nodes with nodeDrainer
+func NewDrainer(client client.Client, nodeDrainer drain.Drainer) *Drainer {
 	return &Drainer{
-		client:        client,
-		drainTimeout:  DefaultDrainTimeout,
-		evictionRetry: 3,
+		client:       client,
+		nodeDrainer:  nodeDrainer,
+		drainTimeout: DefaultDrainTimeout,
 	}
 }
 
-// DrainNode gracefully drains a node before deletion
-func (d *Drainer) DrainNode(ctx context.Context, nodeName string, logger *zap.Logger) error {
-	logger.Info("Starting node drain",
-		zap.String("node", nodeName),
-		zap.Duration("timeout", d.drainTimeout),
-	)
-
-	// Step 1: Cordon the node (mark as unschedulable)
-	if err := d.cordonNode(ctx, nodeName, logger); err != nil {
-		return fmt.Errorf("failed to cordon node: %w", err)
-	}
-
-	// Step 2: Get all pods on the node
-	pods, err := d.getPodsOnNode(ctx, nodeName, logger)
-	if err != nil {
-		// Try to uncordon on failure
-		_ = d.uncordonNode(ctx, nodeName, logger)
-		return fmt.Errorf("failed to list pods on node: %w", err)
-	}
+// DrainNode gracefully drains a node before deletion, using the drain
+// options of the VPSieNode's NodeGroup
+func (d *Drainer) DrainNode(ctx context.Context, vn *v1alpha1.VPSieNode, nodeName string, logger *zap.Logger) error {
+	opts := d.drainOptions(ctx, vn, logger)
 
-	// Filter out DaemonSet pods and already terminated pods
-	podsToEvict := d.filterPodsToEvict(pods, logger)
+	opts.Progress = d.progressReporter(vn, logger)
 
-	logger.Info("Found pods to evict",
+	logger.Info("Starting node drain",
 		zap.String("node", nodeName),
-		zap.Int("totalPods", len(pods)),
-		zap.Int("podsToEvict", len(podsToEvict)),
+		zap.Duration("timeout", opts.Timeout),
 	)
 
-	// Step 3: Evict all pods
-	drainCtx, cancel := context.WithTimeout(ctx, d.drainTimeout)
-	defer cancel()
-
-	if err := d.evictPods(drainCtx, podsToEvict, logger); err != nil {
-		// Try to uncordon on failure
-		_ = d.uncordonNode(ctx, nodeName, logger)
-		return fmt.Errorf("failed to evict pods: %w", err)
+	if err := d.nodeDrainer.Drain(ctx, nodeName, opts); err != nil {
+		return err
 	}
-
-	logger.Info("Successfully drained node", zap.String("node", nodeName))
+	vn.Status.DrainProgress = 100
 	return nil
 }
 
-// cordonNode marks a node as unschedulable
-func (d *Drainer) cordonNode(ctx context.Context, nodeName string, logger *zap.Logger) error {
-	node := &corev1.Node{}
-	if err := d.client.Get(ctx, client.ObjectKey{Name: nodeName}, node); err != nil {
-		if apierrors.IsNotFound(err) {
-			logger.Info("Node not found, skipping cordon", zap.String("node", nodeName))
-			return nil
-		}
-		return fmt.Errorf("failed to get node: %w", err)
-	}
-
-	// Check if already cordoned
-	if node.Spec.Unschedulable {
-		logger.Debug("Node already cordoned", zap.String("node", nodeName))
-		return nil
-	}
-
-	// Mark as unschedulable
-	node.Spec.Unschedulable = true
-	if err := d.client.Update(ctx, node); err != nil {
-		return fmt.Errorf("failed to update node: %w", err)
-	}
-
-	logger.Info("Successfully cordoned node", zap.String("node", nodeName))
-	return nil
-}
-
-// uncordonNode marks a node as schedulable (rollback operation)
-func (d *Drainer) uncordonNode(ctx context.Context, nodeName string, logger *zap.Logger) error {
-	node := &corev1.Node{}
-	if err := d.client.Get(ctx, client.ObjectKey{Name: nodeName}, node); err != nil {
-		if apierrors.IsNotFound(err) {
-			logger.Info("Node not found, skipping uncordon", zap.String("node", nodeName))
-			return nil
-		}
-		return fmt.Errorf("failed to get node: %w", err)
-	}
-
-	// Check if already schedulable
-	if !node.Spec.Unschedulable {
-		logger.Debug("Node already uncordoned", zap.String("node", nodeName))
-		return nil
-	}
-
-	// Mark as schedulable
-	node.Spec.Unschedulable = false
-	if err := d.client.Update(ctx, node); err != nil {
-		return fmt.Errorf("failed to update node: %w", err)
-	}
-
-	logger.Warn("Uncordoned node after drain failure", zap.String("node", nodeName))
-	return nil
-}
-
-// getPodsOnNode returns all pods running on a node
-func (d *Drainer) getPodsOnNode(ctx context.Context, nodeName string, logger *zap.Logger) ([]corev1.Pod, error) {
-	podList := &corev1.PodList{}
-
-	// List pods on the specific node
-	err := d.client.List(ctx, podList, &client.ListOptions{
-		FieldSelector: fields.SelectorFromSet(fields.Set{"spec.nodeName": nodeName}),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list pods: %w", err)
-	}
-
-	return podList.Items, nil
-}
-
-// filterPodsToEvict filters out pods that should not be evicted
-func (d *Drainer) filterPodsToEvict(pods []corev1.Pod, logger *zap.Logger) []corev1.Pod {
-	var podsToEvict []corev1.Pod
-
-	for _, pod := range pods {
-		// Skip pods that are already terminating or terminated
-		if pod.DeletionTimestamp != nil {
-			logger.Debug("Skipping pod that is already terminating",
-				zap.String("pod", pod.Name),
-				zap.String("namespace", pod.Namespace),
-			)
-			continue
+// progressReporter returns a drain progress callback that patches the
+// VPSieNode's drain progress, at most every drainProgressInterval unless the
+// drain completed, and never to a lower value than already patched. The
+// callback is called concurrently by the evictions in flight. Drains continue
+// in the background after the reconcile was cancelled, so the patch goes to a
+// copy of the VPSieNode.
+func (d *Drainer) progressReporter(vn *v1alpha1.VPSieNode, logger *zap.Logger) func(evicted, total int) {
+	key := types.NamespacedName{Name: vn.Name, Namespace: vn.Namespace}
+	var mu sync.Mutex
+	var lastUpdate time.Time
+	reported := int32(-1)
+
+	return func(evicted, total int) {
+		percent := int32(drain.Percent(evicted, total))
+
+		mu.Lock()
+		if percent <= reported || (percent < 100 && time.Since(lastUpdate) < drainProgressInterval) {
+			mu.Unlock()
+			return
 		}
-
-		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
-			logger.Debug("Skipping terminated pod",
-				zap.String("pod", pod.Name),
-				zap.String("namespace", pod.Namespace),
-				zap.String("phase", string(pod.Status.Phase)),
-			)
-			continue
-		}
-
-		// Skip DaemonSet pods (they are managed by DaemonSet controller)
-		if isDaemonSetPod(&pod) {
-			logger.Debug("Skipping DaemonSet pod",
-				zap.String("pod", pod.Name),
-				zap.String("namespace", pod.Namespace),
-			)
-			continue
-		}
-
-		// Skip static pods (managed by kubelet)
-		if isStaticPod(&pod) {
-			logger.Debug("Skipping static pod",
-				zap.String("pod", pod.Name),
-				zap.String("namespace", pod.Namespace),
-			)
-			continue
-		}
-
-		podsToEvict = append(podsToEvict, pod)
-	}
-
-	return podsToEvict
-}
-
-// evictPods evicts all pods from the node
-func (d *Drainer) evictPods(ctx context.Context, pods []corev1.Pod, logger *zap.Logger) error {
-	if len(pods) == 0 {
-		logger.Info("No pods to evict")
-		return nil
-	}
-
-	// Create eviction for each pod
-	for _, pod := range pods {
-		if err := d.evictPod(ctx, &pod, logger); err != nil {
-			logger.Warn("Failed to create eviction for pod",
-				zap.String("pod", pod.Name),
-				zap.String("namespace", pod.Namespace),
+		lastUpdate = time.Now()
+		reported = percent
+		mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), drainProgressInterval)
+		defer cancel()
+
+		obj := &v1alpha1.VPSieNode{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+		patch := []byte(fmt.Sprintf(`{"status":{"drainProgress":%d}}`, percent))
+		if err := d.client.Status().Patch(ctx, obj, client.RawPatch(types.MergePatchType, patch)); err != nil {
+			logger.Debug("Failed to update drain progress",
+				zap.Int32("progress", percent),
 				zap.Error(err),
 			)
-			// Continue with other pods even if one fails
 		}
 	}
+}
 
-	// Wait for all pods to be deleted
-	return d.waitForPodsDeleted(ctx, pods, logger)
+// PodsToEvict returns the pods on the node a drain of the VPSieNode evicts
+func (d *Drainer) PodsToEvict(ctx context.Context, vn *v1alpha1.VPSieNode, nodeName string, logger *zap.Logger) ([]*corev1.Pod, error) {
+	return d.nodeDrainer.PodsToEvict(ctx, nodeName, d.drainOptions(ctx, vn, logger))
 }
 
-// evictPod creates an eviction for a single pod
-func (d *Drainer) evictPod(ctx context.Context, pod *corev1.Pod, logger *zap.Logger) error {
-	logger.Info("Evicting pod",
-		zap.String("pod", pod.Name),
-		zap.String("namespace", pod.Namespace),
-	)
+// drainOptions returns the drain options of the VPSieNode's NodeGroup. The
+// defaults apply if the NodeGroup cannot be read, e.g. because it was deleted.
+func (d *Drainer) drainOptions(ctx context.Context, vn *v1alpha1.VPSieNode, logger *zap.Logger) drain.Options {
+	opts := drain.DefaultOptions()
+	opts.Timeout = d.drainTimeout
+	opts.NodeGroup = vn.Spec.NodeGroupName
+	opts.NodeGroupNamespace = vn.Namespace
 
-	// Create eviction object
-	gracePeriod := int64(PodDeletionGracePeriod.Seconds())
-	eviction := &policyv1.Eviction{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      pod.Name,
-			Namespace: pod.Namespace,
-		},
-		DeleteOptions: &metav1.DeleteOptions{
-			GracePeriodSeconds: &gracePeriod,
-		},
+	if vn.Spec.NodeGroupName == "" {
+		return opts
 	}
 
-	// Try to evict the pod (respects PodDisruptionBudgets)
-	err := d.client.SubResource("eviction").Create(ctx, pod, eviction)
-	if err != nil {
-		if apierrors.IsNotFound(err) {
-			// Pod already deleted
-			logger.Debug("Pod already deleted",
-				zap.String("pod", pod.Name),
-				zap.String("namespace", pod.Namespace),
-			)
-			return nil
-		}
-		if apierrors.IsTooManyRequests(err) {
-			// PodDisruptionBudget prevents eviction
-			logger.Warn("PodDisruptionBudget prevents eviction, will retry",
-				zap.String("pod", pod.Name),
-				zap.String("namespace", pod.Namespace),
+	ng := &v1alpha1.NodeGroup{}
+	key := types.NamespacedName{Name: vn.Spec.NodeGroupName, Namespace: vn.Namespace}
+	if err := d.client.Get(ctx, key, ng); err != nil {
+		if !apierrors.IsNotFound(err) {
+			logger.Warn("Failed to get NodeGroup, draining with default options",
+				zap.String("nodegroup", vn.Spec.NodeGroupName),
+				zap.Error(err),
 			)
-			return err
 		}
-		return fmt.Errorf("failed to create eviction: %w", err)
+		return opts
 	}
 
-	logger.Debug("Successfully created eviction",
-		zap.String("pod", pod.Name),
-		zap.String("namespace", pod.Namespace),
-	)
-	return nil
-}
-
-// waitForPodsDeleted waits for all pods to be deleted
-func (d *Drainer) waitForPodsDeleted(ctx context.Context, pods []corev1.Pod, logger *zap.Logger) error {
-	logger.Info("Waiting for pods to be deleted", zap.Int("count", len(pods)))
-
-	return wait.PollUntilContextTimeout(ctx, PollInterval, d.drainTimeout, true, func(ctx context.Context) (bool, error) {
-		remaining := 0
-		for _, pod := range pods {
-			currentPod := &corev1.Pod{}
-			err := d.client.Get(ctx, client.ObjectKey{
-				Name:      pod.Name,
-				Namespace: pod.Namespace,
-			}, currentPod)
-
-			if err != nil {
-				if apierrors.IsNotFound(err) {
-					// Pod is deleted
-					continue
-				}
-				logger.Warn("Error checking pod status",
-					zap.String("pod", pod.Name),
-					zap.String("namespace", pod.Namespace),
-					zap.Error(err),
-				)
-				continue
-			}
-
-			remaining++
-		}
-
-		if remaining > 0 {
-			logger.Debug("Waiting for pods to terminate", zap.Int("remaining", remaining))
-			return false, nil
-		}
-
-		logger.Info("All pods have been deleted")
-		return true, nil
-	})
-}
-
-// isDaemonSetPod checks if a pod is managed by a DaemonSet
-func isDaemonSetPod(pod *corev1.Pod) bool {
-	for _, owner := range pod.OwnerReferences {
-		if owner.Kind == "DaemonSet" {
-			return true
-		}
-	}
-	return false
-}
-
-// isStaticPod checks if a pod is a static pod
-func isStaticPod(pod *corev1.Pod) bool {
-	// Static pods have a mirror pod annotation
-	if _, exists := pod.Annotations["kubernetes.io/config.mirror"]; exists {
-		return true
-	}
-	// Static pods also have no controller reference
-	return len(pod.OwnerReferences) == 0 && pod.Namespace == "kube-system"
+	return opts.WithNodeGroup(ng)
 }
 
 // DeleteNode deletes the Kubernetes Node object
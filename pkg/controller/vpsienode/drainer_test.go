@@ -2,24 +2,46 @@ package vpsienode
 
 import (
 	"context"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/drain"
 )
 
+// newTestDrainer creates a Drainer whose nodes and pods live in a fake
+// clientset holding objects
+func newTestDrainer(c client.Client, objects ...runtime.Object) *Drainer {
+	kubeClient := kubefake.NewSimpleClientset(objects...)
+	// The fake clientset accepts evictions without deleting the pods
+	kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		return true, nil, kubeClient.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+	})
+	return NewDrainer(c, drain.NewNodeDrainer(kubeClient, zap.NewNop()))
+}
+
 // TestDrainNode_Success tests successful node draining
 func TestDrainNode_Success(t *testing.T) {
 	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
 	_ = corev1.AddToScheme(scheme)
 
 	node := &corev1.Node{
@@ -30,422 +52,134 @@ func TestDrainNode_Success(t *testing.T) {
 			Unschedulable: false,
 		},
 	}
+	vn := &v1alpha1.VPSieNode{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-vn", Namespace: "default"},
+		Spec:       v1alpha1.VPSieNodeSpec{NodeGroupName: "test-ng", NodeName: "test-node"},
+	}
 
-	client := fake.NewClientBuilder().
-		WithScheme(scheme).
-		WithObjects(node).
-		WithIndex(&corev1.Pod{}, "spec.nodeName", func(obj client.Object) []string {
-			pod := obj.(*corev1.Pod)
-			return []string{pod.Spec.NodeName}
-		}).
-		Build()
-
-	drainer := NewDrainer(client)
-	logger := zap.NewNop()
+	kubeClient := kubefake.NewSimpleClientset(node)
+	drainer := NewDrainer(fake.NewClientBuilder().WithScheme(scheme).Build(), drain.NewNodeDrainer(kubeClient, zap.NewNop()))
 
-	err := drainer.DrainNode(context.Background(), "test-node", logger)
+	err := drainer.DrainNode(context.Background(), vn, "test-node", zap.NewNop())
 	require.NoError(t, err)
 
 	// Verify node was cordoned
-	updatedNode := &corev1.Node{}
-	err = client.Get(context.Background(), types.NamespacedName{Name: "test-node"}, updatedNode)
+	updatedNode, err := kubeClient.CoreV1().Nodes().Get(context.Background(), "test-node", metav1.GetOptions{})
 	require.NoError(t, err)
 	assert.True(t, updatedNode.Spec.Unschedulable, "Node should be cordoned")
 }
 
-// TestCordonNode tests cordoning a node
-func TestCordonNode(t *testing.T) {
+// TestDrainOptions tests that the NodeGroup's drain configuration is applied
+func TestDrainOptions(t *testing.T) {
 	scheme := runtime.NewScheme()
-	_ = corev1.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
 
-	node := &corev1.Node{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "test-node",
-		},
-		Spec: corev1.NodeSpec{
-			Unschedulable: false,
+	deleteEmptyDirData := false
+	maxConcurrentEvictions := int32(3)
+	ng := &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ng", Namespace: "default"},
+		Spec: v1alpha1.NodeGroupSpec{
+			Drain: &v1alpha1.DrainConfig{
+				SkipNamespaces:         []string{"monitoring"},
+				DeleteEmptyDirData:     &deleteEmptyDirData,
+				ForceDeleteAfter:       &metav1.Duration{Duration: 15 * time.Minute},
+				MaxConcurrentEvictions: &maxConcurrentEvictions,
+			},
 		},
 	}
+	drainer := newTestDrainer(fake.NewClientBuilder().WithScheme(scheme).WithObjects(ng).Build())
 
-	client := fake.NewClientBuilder().
-		WithScheme(scheme).
-		WithObjects(node).
-		Build()
-
-	drainer := NewDrainer(client)
-	logger := zap.NewNop()
-
-	err := drainer.cordonNode(context.Background(), "test-node", logger)
-	require.NoError(t, err)
-
-	// Verify node is now unschedulable
-	updatedNode := &corev1.Node{}
-	err = client.Get(context.Background(), types.NamespacedName{Name: "test-node"}, updatedNode)
-	require.NoError(t, err)
-	assert.True(t, updatedNode.Spec.Unschedulable)
-}
-
-// TestCordonNode_AlreadyCordoned tests cordoning an already cordoned node
-func TestCordonNode_AlreadyCordoned(t *testing.T) {
-	scheme := runtime.NewScheme()
-	_ = corev1.AddToScheme(scheme)
-
-	node := &corev1.Node{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "test-node",
-		},
-		Spec: corev1.NodeSpec{
-			Unschedulable: true, // Already cordoned
-		},
+	vn := &v1alpha1.VPSieNode{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-vn", Namespace: "default"},
+		Spec:       v1alpha1.VPSieNodeSpec{NodeGroupName: "test-ng"},
 	}
-
-	client := fake.NewClientBuilder().
-		WithScheme(scheme).
-		WithObjects(node).
-		Build()
-
-	drainer := NewDrainer(client)
-	logger := zap.NewNop()
-
-	err := drainer.cordonNode(context.Background(), "test-node", logger)
-	require.NoError(t, err, "Should succeed when node is already cordoned")
-}
-
-// TestCordonNode_NotFound tests cordoning a non-existent node
-func TestCordonNode_NotFound(t *testing.T) {
-	scheme := runtime.NewScheme()
-	_ = corev1.AddToScheme(scheme)
-
-	client := fake.NewClientBuilder().
-		WithScheme(scheme).
-		Build()
-
-	drainer := NewDrainer(client)
-	logger := zap.NewNop()
-
-	err := drainer.cordonNode(context.Background(), "non-existent-node", logger)
-	require.NoError(t, err, "Should succeed when node doesn't exist")
+	opts := drainer.drainOptions(context.Background(), vn, zap.NewNop())
+	assert.Equal(t, DefaultDrainTimeout, opts.Timeout)
+	assert.Equal(t, []string{"monitoring"}, opts.SkipNamespaces)
+	assert.False(t, opts.DeleteEmptyDirData)
+	assert.Equal(t, 15*time.Minute, opts.ForceDeleteAfter)
+	assert.Equal(t, 3, opts.MaxConcurrentEvictions)
+	assert.Equal(t, "test-ng", opts.NodeGroup)
+	assert.Equal(t, "default", opts.NodeGroupNamespace)
+
+	// A deleted NodeGroup drains with the defaults
+	vn.Spec.NodeGroupName = "deleted-ng"
+	opts = drainer.drainOptions(context.Background(), vn, zap.NewNop())
+	assert.Empty(t, opts.SkipNamespaces)
+	assert.True(t, opts.DeleteEmptyDirData)
+	assert.Zero(t, opts.ForceDeleteAfter)
+	assert.Equal(t, drain.DefaultMaxConcurrentEvictions, opts.MaxConcurrentEvictions)
 }
 
-// TestUncordonNode tests uncordoning a node (rollback)
-func TestUncordonNode(t *testing.T) {
+// TestDrainNode_Progress tests that the drain progress is recorded on the VPSieNode
+func TestDrainNode_Progress(t *testing.T) {
 	scheme := runtime.NewScheme()
-	_ = corev1.AddToScheme(scheme)
-
-	node := &corev1.Node{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "test-node",
-		},
-		Spec: corev1.NodeSpec{
-			Unschedulable: true, // Cordoned
-		},
-	}
-
-	client := fake.NewClientBuilder().
-		WithScheme(scheme).
-		WithObjects(node).
-		Build()
-
-	drainer := NewDrainer(client)
-	logger := zap.NewNop()
-
-	err := drainer.uncordonNode(context.Background(), "test-node", logger)
-	require.NoError(t, err)
-
-	// Verify node is now schedulable
-	updatedNode := &corev1.Node{}
-	err = client.Get(context.Background(), types.NamespacedName{Name: "test-node"}, updatedNode)
-	require.NoError(t, err)
-	assert.False(t, updatedNode.Spec.Unschedulable, "Node should be uncordoned")
-}
-
-// TestGetPodsOnNode tests listing pods on a node
-func TestGetPodsOnNode(t *testing.T) {
-	scheme := runtime.NewScheme()
-	_ = corev1.AddToScheme(scheme)
-
-	pod1 := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "pod-1",
-			Namespace: "default",
-		},
-		Spec: corev1.PodSpec{
-			NodeName: "test-node",
-		},
-	}
+	_ = v1alpha1.AddToScheme(scheme)
 
-	pod2 := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "pod-2",
-			Namespace: "default",
-		},
-		Spec: corev1.PodSpec{
-			NodeName: "test-node",
-		},
+	vn := &v1alpha1.VPSieNode{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-vn", Namespace: "default"},
+		Spec:       v1alpha1.VPSieNodeSpec{NodeName: "test-node"},
 	}
-
-	pod3 := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "pod-3",
-			Namespace: "default",
-		},
-		Spec: corev1.PodSpec{
-			NodeName: "other-node", // Different node
-		},
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vn).WithStatusSubresource(vn).Build()
+
+	objects := []runtime.Object{&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test-node"}}}
+	for _, name := range []string{"web-1", "web-2", "web-3"} {
+		objects = append(objects, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name)},
+			Spec:       corev1.PodSpec{NodeName: "test-node"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		})
 	}
+	drainer := newTestDrainer(c, objects...)
 
-	client := fake.NewClientBuilder().
-		WithScheme(scheme).
-		WithObjects(pod1, pod2, pod3).
-		WithIndex(&corev1.Pod{}, "spec.nodeName", func(obj client.Object) []string {
-			pod := obj.(*corev1.Pod)
-			return []string{pod.Spec.NodeName}
-		}).
-		Build()
-
-	drainer := NewDrainer(client)
-	logger := zap.NewNop()
+	require.NoError(t, drainer.DrainNode(context.Background(), vn, "test-node", zap.NewNop()))
+	assert.Equal(t, int32(100), vn.Status.DrainProgress)
 
-	pods, err := drainer.getPodsOnNode(context.Background(), "test-node", logger)
-	require.NoError(t, err)
-	assert.Len(t, pods, 2, "Should find 2 pods on test-node")
+	updated := &v1alpha1.VPSieNode{}
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: "test-vn", Namespace: "default"}, updated))
+	assert.Equal(t, int32(100), updated.Status.DrainProgress)
 }
 
-// TestFilterPodsToEvict tests filtering of pods to evict
-func TestFilterPodsToEvict(t *testing.T) {
+// TestProgressReporter tests that concurrent progress reports never lower the
+// VPSieNode's drain progress
+func TestProgressReporter(t *testing.T) {
 	scheme := runtime.NewScheme()
-	_ = corev1.AddToScheme(scheme)
-
-	logger := zap.NewNop()
-	drainer := &Drainer{}
-
-	tests := []struct {
-		name        string
-		pod         corev1.Pod
-		shouldEvict bool
-	}{
-		{
-			name: "regular pod should be evicted",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "regular-pod",
-					Namespace: "default",
-				},
-				Spec: corev1.PodSpec{
-					NodeName: "test-node",
-				},
-				Status: corev1.PodStatus{
-					Phase: corev1.PodRunning,
-				},
-			},
-			shouldEvict: true,
-		},
-		{
-			name: "DaemonSet pod should not be evicted",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "ds-pod",
-					Namespace: "default",
-					OwnerReferences: []metav1.OwnerReference{
-						{
-							Kind: "DaemonSet",
-							Name: "test-ds",
-						},
-					},
-				},
-				Spec: corev1.PodSpec{
-					NodeName: "test-node",
-				},
-				Status: corev1.PodStatus{
-					Phase: corev1.PodRunning,
-				},
-			},
-			shouldEvict: false,
-		},
-		{
-			name: "static pod should not be evicted",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "static-pod",
-					Namespace: "kube-system",
-					Annotations: map[string]string{
-						"kubernetes.io/config.mirror": "true",
-					},
-				},
-				Spec: corev1.PodSpec{
-					NodeName: "test-node",
-				},
-				Status: corev1.PodStatus{
-					Phase: corev1.PodRunning,
-				},
-			},
-			shouldEvict: false,
-		},
-		{
-			name: "succeeded pod should not be evicted",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "succeeded-pod",
-					Namespace: "default",
-				},
-				Spec: corev1.PodSpec{
-					NodeName: "test-node",
-				},
-				Status: corev1.PodStatus{
-					Phase: corev1.PodSucceeded,
-				},
-			},
-			shouldEvict: false,
-		},
-		{
-			name: "failed pod should not be evicted",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "failed-pod",
-					Namespace: "default",
-				},
-				Spec: corev1.PodSpec{
-					NodeName: "test-node",
-				},
-				Status: corev1.PodStatus{
-					Phase: corev1.PodFailed,
-				},
-			},
-			shouldEvict: false,
-		},
-		{
-			name: "terminating pod should not be evicted",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:              "terminating-pod",
-					Namespace:         "default",
-					DeletionTimestamp: &metav1.Time{},
-				},
-				Spec: corev1.PodSpec{
-					NodeName: "test-node",
-				},
-				Status: corev1.PodStatus{
-					Phase: corev1.PodRunning,
-				},
-			},
-			shouldEvict: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			filtered := drainer.filterPodsToEvict([]corev1.Pod{tt.pod}, logger)
-			if tt.shouldEvict {
-				assert.Len(t, filtered, 1, "Pod should be included in eviction list")
-			} else {
-				assert.Len(t, filtered, 0, "Pod should not be included in eviction list")
-			}
-		})
-	}
-}
-
-// TestIsDaemonSetPod tests DaemonSet pod detection
-func TestIsDaemonSetPod(t *testing.T) {
-	tests := []struct {
-		name     string
-		pod      *corev1.Pod
-		expected bool
-	}{
-		{
-			name: "pod with DaemonSet owner",
-			pod: &corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					OwnerReferences: []metav1.OwnerReference{
-						{Kind: "DaemonSet", Name: "test-ds"},
-					},
-				},
-			},
-			expected: true,
-		},
-		{
-			name: "pod with ReplicaSet owner",
-			pod: &corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					OwnerReferences: []metav1.OwnerReference{
-						{Kind: "ReplicaSet", Name: "test-rs"},
-					},
-				},
-			},
-			expected: false,
-		},
-		{
-			name: "pod with no owner",
-			pod: &corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{},
-			},
-			expected: false,
-		},
-	}
+	_ = v1alpha1.AddToScheme(scheme)
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := isDaemonSetPod(tt.pod)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
+	vn := &v1alpha1.VPSieNode{ObjectMeta: metav1.ObjectMeta{Name: "test-vn", Namespace: "default"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vn).WithStatusSubresource(vn).Build()
+	drainer := newTestDrainer(c)
+	report := drainer.progressReporter(vn, zap.NewNop())
 
-// TestIsStaticPod tests static pod detection
-func TestIsStaticPod(t *testing.T) {
-	tests := []struct {
-		name     string
-		pod      *corev1.Pod
-		expected bool
-	}{
-		{
-			name: "pod with mirror annotation",
-			pod: &corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Annotations: map[string]string{
-						"kubernetes.io/config.mirror": "true",
-					},
-				},
-			},
-			expected: true,
-		},
-		{
-			name: "kube-system pod with no owner",
-			pod: &corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "kube-system",
-				},
-			},
-			expected: true,
-		},
-		{
-			name: "kube-system pod with owner",
-			pod: &corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "kube-system",
-					OwnerReferences: []metav1.OwnerReference{
-						{Kind: "ReplicaSet", Name: "test-rs"},
-					},
-				},
-			},
-			expected: false,
-		},
-		{
-			name: "default namespace pod",
-			pod: &corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "default",
-				},
-			},
-			expected: false,
-		},
+	get := func() int32 {
+		updated := &v1alpha1.VPSieNode{}
+		require.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: "test-vn", Namespace: "default"}, updated))
+		return updated.Status.DrainProgress
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := isStaticPod(tt.pod)
-			assert.Equal(t, tt.expected, result)
-		})
+	report(1, 4)
+	assert.Equal(t, int32(25), get())
+
+	// Updates within the interval are skipped unless the drain completed
+	report(2, 4)
+	assert.Equal(t, int32(25), get())
+	report(4, 4)
+	assert.Equal(t, int32(100), get())
+
+	// A report arriving late does not lower the progress
+	report(3, 4)
+	assert.Equal(t, int32(100), get())
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 4; i++ {
+		wg.Add(1)
+		go func(evicted int) {
+			defer wg.Done()
+			report(evicted, 4)
+		}(i)
 	}
+	wg.Wait()
+	assert.Equal(t, int32(100), get())
 }
 
 // TestDeleteNode tests deleting a Kubernetes Node object
@@ -478,7 +212,7 @@ func TestDeleteNode(t *testing.T) {
 		WithObjects(node).
 		Build()
 
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	logger := zap.NewNop()
 
 	err := drainer.DeleteNode(context.Background(), vn, logger)
@@ -510,7 +244,7 @@ func TestDeleteNode_NotFound(t *testing.T) {
 		WithScheme(scheme).
 		Build()
 
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	logger := zap.NewNop()
 
 	err := drainer.DeleteNode(context.Background(), vn, logger)
@@ -536,7 +270,7 @@ func TestDeleteNode_NoNodeName(t *testing.T) {
 		WithScheme(scheme).
 		Build()
 
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	logger := zap.NewNop()
 
 	err := drainer.DeleteNode(context.Background(), vn, logger)
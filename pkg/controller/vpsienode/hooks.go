@@ -0,0 +1,426 @@
+package vpsienode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+)
+
+const (
+	// DefaultHTTPHookTimeout is the default timeout of HTTP drain hooks
+	DefaultHTTPHookTimeout = 30 * time.Second
+
+	// DefaultJobHookTimeout is the default timeout of Job drain hooks
+	DefaultJobHookTimeout = 10 * time.Minute
+
+	// HookPollInterval is how often a running Job drain hook is checked
+	HookPollInterval = 10 * time.Second
+
+	// DrainHookLabelKey labels the Jobs of drain hooks with the hook name
+	DrainHookLabelKey = "autoscaler.vpsie.com/drain-hook"
+
+	// hookJobTTL is how long finished hook Jobs are kept for inspection
+	hookJobTTL = int32(3600)
+
+	// maxHookResponseBytes bounds the response body quoted in a failed HTTP hook's condition
+	maxHookResponseBytes = 512
+)
+
+// ErrHookAborted is returned when a drain hook with the Abort failure policy failed
+var ErrHookAborted = errors.New("drain hook failed")
+
+// HookConditionType returns the type of the VPSieNode condition recording
+// the result of a drain hook, e.g. "PreDrainHook-flush-state"
+func HookConditionType(stage v1alpha1.DrainHookStage, name string) v1alpha1.VPSieNodeConditionType {
+	return v1alpha1.VPSieNodeConditionType(string(stage) + "Hook-" + name)
+}
+
+// HookRunner runs the drain hooks declared by NodeGroups while their nodes
+// are terminated.
+//
+// Hooks of a stage run one at a time in order. The result of each hook is
+// kept in a VPSieNode condition: Unknown while a Job hook runs, True when
+// the hook succeeded and False when it failed or timed out. Conditions make
+// the runner resumable across reconciles. A hook whose result could not be
+// persisted runs again: HTTP hooks are called again and Job hooks pick up
+// their existing Job, so HTTP hooks should be idempotent.
+type HookRunner struct {
+	client     client.Client
+	httpClient *http.Client
+}
+
+// NewHookRunner creates a new HookRunner
+func NewHookRunner(client client.Client) *HookRunner {
+	return &HookRunner{
+		client:     client,
+		httpClient: &http.Client{},
+	}
+}
+
+// hookRequest is the JSON body sent to HTTP drain hooks
+type hookRequest struct {
+	Stage     v1alpha1.DrainHookStage `json:"stage"`
+	Hook      string                  `json:"hook"`
+	NodeName  string                  `json:"nodeName"`
+	VPSieNode string                  `json:"vpsieNode"`
+	NodeGroup string                  `json:"nodeGroup"`
+	Namespace string                  `json:"namespace"`
+}
+
+// Run runs the drain hooks of the stage for the node of the VPSieNode. It
+// returns done once every hook has finished and the termination may
+// proceed. While a Job hook runs, the returned result requeues to poll it.
+// A failed hook with the Abort policy returns ErrHookAborted; the hook's
+// policy is read again on every call, so changing it to Continue, or
+// annotating the VPSieNode with SkipFailedDrainHooksAnnotationKey, resumes
+// the termination.
+func (h *HookRunner) Run(ctx context.Context, vn *v1alpha1.VPSieNode, stage v1alpha1.DrainHookStage, nodeName string, logger *zap.Logger) (ctrl.Result, bool, error) {
+	hooks, err := h.hooks(ctx, vn, stage)
+	if err != nil {
+		return ctrl.Result{}, false, err
+	}
+
+	for i := range hooks {
+		hook := &hooks[i]
+		condType := HookConditionType(stage, hook.Name)
+		hookLogger := logger.With(
+			zap.String("stage", string(stage)),
+			zap.String("hook", hook.Name),
+		)
+
+		cond := GetCondition(vn, condType)
+		if cond == nil || (cond.Status == string(corev1.ConditionUnknown) && hook.Job == nil) {
+			switch {
+			case hook.Job != nil:
+				if err := h.startJob(ctx, vn, stage, hook, nodeName, hookLogger); err != nil {
+					return ctrl.Result{}, false, err
+				}
+			case hook.HTTP != nil:
+				h.runHTTP(ctx, vn, stage, hook, nodeName, hookLogger)
+			default:
+				SetCondition(vn, condType, corev1.ConditionFalse, ReasonHookFailed, "Hook has neither http nor job set")
+			}
+			cond = GetCondition(vn, condType)
+		}
+
+		if cond.Status == string(corev1.ConditionUnknown) {
+			if err := h.checkJob(ctx, vn, stage, hook, cond, hookLogger); err != nil {
+				return ctrl.Result{}, false, err
+			}
+		}
+
+		switch cond.Status {
+		case string(corev1.ConditionTrue):
+			continue
+		case string(corev1.ConditionFalse):
+			if hook.FailurePolicy == v1alpha1.HookFailurePolicyContinue {
+				continue
+			}
+			if vn.Annotations[v1alpha1.SkipFailedDrainHooksAnnotationKey] == "true" {
+				hookLogger.Warn("Drain hook failed, skipped by annotation",
+					zap.String("annotation", v1alpha1.SkipFailedDrainHooksAnnotationKey),
+					zap.String("message", cond.Message),
+				)
+				continue
+			}
+			return ctrl.Result{}, false, fmt.Errorf("%w: %s hook %q: %s", ErrHookAborted, stage, hook.Name, cond.Message)
+		default:
+			return ctrl.Result{RequeueAfter: HookPollInterval}, false, nil
+		}
+	}
+
+	return ctrl.Result{}, true, nil
+}
+
+// Started returns true if any drain hook of the stage has run for the VPSieNode
+func (h *HookRunner) Started(vn *v1alpha1.VPSieNode, stage v1alpha1.DrainHookStage) bool {
+	prefix := string(HookConditionType(stage, ""))
+	for _, cond := range vn.Status.Conditions {
+		if strings.HasPrefix(string(cond.Type), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hooks returns the drain hooks of the stage declared by the VPSieNode's
+// NodeGroup. A deleted NodeGroup has no hooks.
+func (h *HookRunner) hooks(ctx context.Context, vn *v1alpha1.VPSieNode, stage v1alpha1.DrainHookStage) ([]v1alpha1.DrainHook, error) {
+	if vn.Spec.NodeGroupName == "" {
+		return nil, nil
+	}
+
+	ng := &v1alpha1.NodeGroup{}
+	key := types.NamespacedName{Name: vn.Spec.NodeGroupName, Namespace: vn.Namespace}
+	if err := h.client.Get(ctx, key, ng); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get NodeGroup %s: %w", vn.Spec.NodeGroupName, err)
+	}
+
+	if ng.Spec.Drain == nil {
+		return nil, nil
+	}
+	if stage == v1alpha1.DrainHookStagePreDrain {
+		return ng.Spec.Drain.PreDrainHooks, nil
+	}
+	return ng.Spec.Drain.PostDrainHooks, nil
+}
+
+// runHTTP calls an HTTP hook and records its result
+func (h *HookRunner) runHTTP(ctx context.Context, vn *v1alpha1.VPSieNode, stage v1alpha1.DrainHookStage, hook *v1alpha1.DrainHook, nodeName string, logger *zap.Logger) {
+	condType := HookConditionType(stage, hook.Name)
+	timeout := hookTimeout(hook)
+
+	logger.Info("Running HTTP drain hook", zap.String("url", hook.HTTP.URL))
+
+	err := h.callHTTP(ctx, vn, stage, hook, nodeName, timeout)
+	switch {
+	case err == nil:
+		logger.Info("HTTP drain hook succeeded")
+		SetCondition(vn, condType, corev1.ConditionTrue, ReasonHookSucceeded, "HTTP hook succeeded")
+	case errors.Is(err, context.DeadlineExceeded):
+		logger.Warn("HTTP drain hook timed out", zap.Duration("timeout", timeout))
+		SetCondition(vn, condType, corev1.ConditionFalse, ReasonHookTimedOut,
+			fmt.Sprintf("HTTP hook timed out after %s", timeout))
+	default:
+		logger.Warn("HTTP drain hook failed", zap.Error(err))
+		SetCondition(vn, condType, corev1.ConditionFalse, ReasonHookFailed, err.Error())
+	}
+}
+
+// callHTTP sends the hook request; any 2xx response is a success
+func (h *HookRunner) callHTTP(ctx context.Context, vn *v1alpha1.VPSieNode, stage v1alpha1.DrainHookStage, hook *v1alpha1.DrainHook, nodeName string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	method := hook.HTTP.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	var body io.Reader
+	if method != http.MethodGet {
+		payload, err := json.Marshal(hookRequest{
+			Stage:     stage,
+			Hook:      hook.Name,
+			NodeName:  nodeName,
+			VPSieNode: vn.Name,
+			NodeGroup: vn.Spec.NodeGroupName,
+			Namespace: vn.Namespace,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, hook.HTTP.URL, body)
+	if err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range hook.HTTP.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxHookResponseBytes))
+		return fmt.Errorf("HTTP hook returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// startJob creates the Job of a Job hook and marks the hook running. A Job
+// the API server rejects fails the hook.
+func (h *HookRunner) startJob(ctx context.Context, vn *v1alpha1.VPSieNode, stage v1alpha1.DrainHookStage, hook *v1alpha1.DrainHook, nodeName string, logger *zap.Logger) error {
+	condType := HookConditionType(stage, hook.Name)
+	job := h.buildJob(vn, stage, hook, nodeName)
+	if err := controllerutil.SetControllerReference(vn, job, h.client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner of hook Job: %w", err)
+	}
+
+	logger.Info("Starting Job drain hook", zap.String("job", job.Name))
+
+	if err := h.client.Create(ctx, job); err != nil {
+		switch {
+		case apierrors.IsAlreadyExists(err):
+			// Created by an earlier reconcile whose status update was lost
+		case apierrors.IsInvalid(err) || apierrors.IsForbidden(err):
+			logger.Warn("Failed to create drain hook Job", zap.Error(err))
+			SetCondition(vn, condType, corev1.ConditionFalse, ReasonHookFailed,
+				fmt.Sprintf("Failed to create Job %s: %v", job.Name, err))
+			return nil
+		default:
+			return fmt.Errorf("failed to create hook Job %s: %w", job.Name, err)
+		}
+	}
+
+	SetCondition(vn, condType, corev1.ConditionUnknown, ReasonHookRunning,
+		fmt.Sprintf("Job %s is running", job.Name))
+	return nil
+}
+
+// checkJob records the result of a finished Job hook. A Job running past
+// the hook's timeout is deleted and fails the hook.
+func (h *HookRunner) checkJob(ctx context.Context, vn *v1alpha1.VPSieNode, stage v1alpha1.DrainHookStage, hook *v1alpha1.DrainHook, cond *v1alpha1.VPSieNodeCondition, logger *zap.Logger) error {
+	condType := HookConditionType(stage, hook.Name)
+	name := hookJobName(vn.Name, stage, hook.Name)
+
+	job := &batchv1.Job{}
+	if err := h.client.Get(ctx, types.NamespacedName{Name: name, Namespace: vn.Namespace}, job); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Warn("Drain hook Job not found", zap.String("job", name))
+			SetCondition(vn, condType, corev1.ConditionFalse, ReasonHookFailed,
+				fmt.Sprintf("Job %s was deleted before it finished", name))
+			return nil
+		}
+		return fmt.Errorf("failed to get hook Job %s: %w", name, err)
+	}
+
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			logger.Info("Job drain hook succeeded", zap.String("job", name))
+			SetCondition(vn, condType, corev1.ConditionTrue, ReasonHookSucceeded,
+				fmt.Sprintf("Job %s completed", name))
+			return nil
+		case batchv1.JobFailed:
+			reason := ReasonHookFailed
+			if c.Reason == "DeadlineExceeded" {
+				reason = ReasonHookTimedOut
+			}
+			logger.Warn("Job drain hook failed", zap.String("job", name), zap.String("reason", c.Reason))
+			SetCondition(vn, condType, corev1.ConditionFalse, reason,
+				fmt.Sprintf("Job %s failed: %s", name, c.Message))
+			return nil
+		}
+	}
+
+	timeout := hookTimeout(hook)
+	if time.Since(cond.LastTransitionTime.Time) <= timeout {
+		return nil
+	}
+
+	logger.Warn("Job drain hook timed out", zap.String("job", name), zap.Duration("timeout", timeout))
+	propagation := metav1.DeletePropagationBackground
+	if err := h.client.Delete(ctx, job, &client.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !apierrors.IsNotFound(err) {
+		logger.Warn("Failed to delete timed out hook Job", zap.String("job", name), zap.Error(err))
+	}
+	SetCondition(vn, condType, corev1.ConditionFalse, ReasonHookTimedOut,
+		fmt.Sprintf("Job %s timed out after %s", name, timeout))
+	return nil
+}
+
+// buildJob returns the Job of a Job hook, pinned to the node and tolerating
+// its taints, including the unschedulable taint of a cordoned node
+func (h *HookRunner) buildJob(vn *v1alpha1.VPSieNode, stage v1alpha1.DrainHookStage, hook *v1alpha1.DrainHook, nodeName string) *batchv1.Job {
+	template := hook.Job.Template.DeepCopy()
+	template.Spec.NodeName = nodeName
+	if template.Spec.RestartPolicy == "" {
+		template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	}
+	template.Spec.Tolerations = append(template.Spec.Tolerations, corev1.Toleration{
+		Operator: corev1.TolerationOpExists,
+	})
+
+	labels := map[string]string{
+		v1alpha1.NodeGroupLabelKey: vn.Spec.NodeGroupName,
+		v1alpha1.VPSieNodeLabelKey: vn.Name,
+		DrainHookLabelKey:          hook.Name,
+	}
+	if template.Labels == nil {
+		template.Labels = map[string]string{}
+	}
+	for key, value := range labels {
+		template.Labels[key] = value
+	}
+
+	deadline := int64(hookTimeout(hook).Seconds())
+	ttl := hookJobTTL
+	backoffLimit := int32(0)
+	if hook.Job.BackoffLimit != nil {
+		backoffLimit = *hook.Job.BackoffLimit
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      hookJobName(vn.Name, stage, hook.Name),
+			Namespace: vn.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			ActiveDeadlineSeconds:   &deadline,
+			TTLSecondsAfterFinished: &ttl,
+			Template:                *template,
+		},
+	}
+}
+
+// hookJobName returns the name of the Job of a hook, e.g. "node-abc-pre-flush".
+// Names too long for a label value are shortened with a hash suffix.
+func hookJobName(vnName string, stage v1alpha1.DrainHookStage, hookName string) string {
+	prefix := "pre"
+	if stage == v1alpha1.DrainHookStagePostDrain {
+		prefix = "post"
+	}
+
+	name := fmt.Sprintf("%s-%s-%s", vnName, prefix, hookName)
+	if len(name) <= 63 {
+		return name
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(name))
+	return fmt.Sprintf("%s-%08x", strings.TrimRight(name[:54], "-."), hash.Sum32())
+}
+
+// hookTimeout returns the timeout of a hook. HTTP hooks run in the
+// reconcile, so their timeout is capped at MaxHTTPDrainHookTimeout.
+func hookTimeout(hook *v1alpha1.DrainHook) time.Duration {
+	if hook.Job != nil {
+		if hook.Timeout != nil && hook.Timeout.Duration > 0 {
+			return hook.Timeout.Duration
+		}
+		return DefaultJobHookTimeout
+	}
+	if hook.Timeout != nil && hook.Timeout.Duration > 0 {
+		return min(hook.Timeout.Duration, v1alpha1.MaxHTTPDrainHookTimeout)
+	}
+	return DefaultHTTPHookTimeout
+}
@@ -0,0 +1,356 @@
+package vpsienode
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+)
+
+func newHookTestClient(t *testing.T, drain *v1alpha1.DrainConfig, objects ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, batchv1.AddToScheme(scheme))
+
+	ng := &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ng", Namespace: "kube-system"},
+		Spec:       v1alpha1.NodeGroupSpec{Drain: drain},
+	}
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(append(objects, ng)...).
+		Build()
+}
+
+func newHookTestVPSieNode() *v1alpha1.VPSieNode {
+	return &v1alpha1.VPSieNode{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-vn", Namespace: "kube-system", UID: "vn-uid"},
+		Spec: v1alpha1.VPSieNodeSpec{
+			NodeGroupName: "test-ng",
+			NodeName:      "test-node",
+		},
+		Status: v1alpha1.VPSieNodeStatus{
+			Phase:    v1alpha1.VPSieNodePhaseTerminating,
+			NodeName: "test-node",
+		},
+	}
+}
+
+func TestHookRunner_HTTP(t *testing.T) {
+	var received hookRequest
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	c := newHookTestClient(t, &v1alpha1.DrainConfig{
+		PreDrainHooks: []v1alpha1.DrainHook{{
+			Name: "deregister",
+			HTTP: &v1alpha1.HTTPDrainHook{
+				URL:     server.URL,
+				Headers: map[string]string{"Authorization": "Bearer token"},
+			},
+		}},
+	})
+	runner := NewHookRunner(c)
+	vn := newHookTestVPSieNode()
+
+	_, done, err := runner.Run(context.Background(), vn, v1alpha1.DrainHookStagePreDrain, "test-node", zap.NewNop())
+	require.NoError(t, err)
+	assert.True(t, done)
+
+	assert.Equal(t, "Bearer token", authorization)
+	assert.Equal(t, hookRequest{
+		Stage:     v1alpha1.DrainHookStagePreDrain,
+		Hook:      "deregister",
+		NodeName:  "test-node",
+		VPSieNode: "test-vn",
+		NodeGroup: "test-ng",
+		Namespace: "kube-system",
+	}, received)
+
+	cond := GetCondition(vn, HookConditionType(v1alpha1.DrainHookStagePreDrain, "deregister"))
+	require.NotNil(t, cond)
+	assert.Equal(t, string(corev1.ConditionTrue), cond.Status)
+	assert.Equal(t, ReasonHookSucceeded, cond.Reason)
+
+	// Post-drain hooks are not declared
+	_, done, err = runner.Run(context.Background(), vn, v1alpha1.DrainHookStagePostDrain, "test-node", zap.NewNop())
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.True(t, runner.Started(vn, v1alpha1.DrainHookStagePreDrain))
+	assert.False(t, runner.Started(vn, v1alpha1.DrainHookStagePostDrain))
+}
+
+func TestHookRunner_HTTPFailurePolicy(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "load balancer unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := newHookTestClient(t, &v1alpha1.DrainConfig{
+		PostDrainHooks: []v1alpha1.DrainHook{{
+			Name: "notify",
+			HTTP: &v1alpha1.HTTPDrainHook{URL: server.URL},
+		}},
+	})
+	runner := NewHookRunner(c)
+	vn := newHookTestVPSieNode()
+	ctx := context.Background()
+
+	_, done, err := runner.Run(ctx, vn, v1alpha1.DrainHookStagePostDrain, "test-node", zap.NewNop())
+	require.ErrorIs(t, err, ErrHookAborted)
+	assert.False(t, done)
+
+	cond := GetCondition(vn, HookConditionType(v1alpha1.DrainHookStagePostDrain, "notify"))
+	require.NotNil(t, cond)
+	assert.Equal(t, string(corev1.ConditionFalse), cond.Status)
+	assert.Equal(t, ReasonHookFailed, cond.Reason)
+	assert.Contains(t, cond.Message, "load balancer unavailable")
+
+	// A failed hook is not run again
+	_, _, err = runner.Run(ctx, vn, v1alpha1.DrainHookStagePostDrain, "test-node", zap.NewNop())
+	require.ErrorIs(t, err, ErrHookAborted)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Changing the policy to Continue resumes the termination
+	ng := &v1alpha1.NodeGroup{}
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Name: "test-ng", Namespace: "kube-system"}, ng))
+	ng.Spec.Drain.PostDrainHooks[0].FailurePolicy = v1alpha1.HookFailurePolicyContinue
+	require.NoError(t, c.Update(ctx, ng))
+
+	_, done, err = runner.Run(ctx, vn, v1alpha1.DrainHookStagePostDrain, "test-node", zap.NewNop())
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestHookRunner_SkipFailedHooksAnnotation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "load balancer unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := newHookTestClient(t, &v1alpha1.DrainConfig{
+		PreDrainHooks: []v1alpha1.DrainHook{{
+			Name: "deregister",
+			HTTP: &v1alpha1.HTTPDrainHook{URL: server.URL},
+		}},
+	})
+	runner := NewHookRunner(c)
+	vn := newHookTestVPSieNode()
+	ctx := context.Background()
+
+	_, _, err := runner.Run(ctx, vn, v1alpha1.DrainHookStagePreDrain, "test-node", zap.NewNop())
+	require.ErrorIs(t, err, ErrHookAborted)
+
+	// The annotation lets the termination carry on past the failed hook
+	vn.Annotations = map[string]string{v1alpha1.SkipFailedDrainHooksAnnotationKey: "true"}
+	_, done, err := runner.Run(ctx, vn, v1alpha1.DrainHookStagePreDrain, "test-node", zap.NewNop())
+	require.NoError(t, err)
+	assert.True(t, done)
+}
+
+func TestHookTimeout(t *testing.T) {
+	httpHook := &v1alpha1.DrainHook{HTTP: &v1alpha1.HTTPDrainHook{URL: "https://example.com"}}
+	assert.Equal(t, DefaultHTTPHookTimeout, hookTimeout(httpHook))
+
+	httpHook.Timeout = &metav1.Duration{Duration: time.Hour}
+	assert.Equal(t, v1alpha1.MaxHTTPDrainHookTimeout, hookTimeout(httpHook))
+
+	jobHook := &v1alpha1.DrainHook{Job: &v1alpha1.JobDrainHook{}}
+	assert.Equal(t, DefaultJobHookTimeout, hookTimeout(jobHook))
+
+	jobHook.Timeout = &metav1.Duration{Duration: time.Hour}
+	assert.Equal(t, time.Hour, hookTimeout(jobHook))
+}
+
+func TestHookRunner_HTTPTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := newHookTestClient(t, &v1alpha1.DrainConfig{
+		PreDrainHooks: []v1alpha1.DrainHook{{
+			Name:          "slow",
+			HTTP:          &v1alpha1.HTTPDrainHook{URL: server.URL},
+			Timeout:       &metav1.Duration{Duration: 20 * time.Millisecond},
+			FailurePolicy: v1alpha1.HookFailurePolicyContinue,
+		}},
+	})
+	vn := newHookTestVPSieNode()
+
+	_, done, err := NewHookRunner(c).Run(context.Background(), vn, v1alpha1.DrainHookStagePreDrain, "test-node", zap.NewNop())
+	require.NoError(t, err)
+	assert.True(t, done)
+
+	cond := GetCondition(vn, HookConditionType(v1alpha1.DrainHookStagePreDrain, "slow"))
+	require.NotNil(t, cond)
+	assert.Equal(t, string(corev1.ConditionFalse), cond.Status)
+	assert.Equal(t, ReasonHookTimedOut, cond.Reason)
+}
+
+func TestHookRunner_Job(t *testing.T) {
+	c := newHookTestClient(t, &v1alpha1.DrainConfig{
+		PreDrainHooks: []v1alpha1.DrainHook{{
+			Name: "flush",
+			Job: &v1alpha1.JobDrainHook{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "flush", Image: "busybox"}}},
+				},
+			},
+		}},
+	})
+	runner := NewHookRunner(c)
+	vn := newHookTestVPSieNode()
+	ctx := context.Background()
+
+	result, done, err := runner.Run(ctx, vn, v1alpha1.DrainHookStagePreDrain, "test-node", zap.NewNop())
+	require.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, HookPollInterval, result.RequeueAfter)
+
+	condType := HookConditionType(v1alpha1.DrainHookStagePreDrain, "flush")
+	cond := GetCondition(vn, condType)
+	require.NotNil(t, cond)
+	assert.Equal(t, string(corev1.ConditionUnknown), cond.Status)
+	assert.Equal(t, ReasonHookRunning, cond.Reason)
+
+	job := &batchv1.Job{}
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Name: "test-vn-pre-flush", Namespace: "kube-system"}, job))
+	assert.Equal(t, "test-node", job.Spec.Template.Spec.NodeName)
+	assert.Equal(t, corev1.RestartPolicyNever, job.Spec.Template.Spec.RestartPolicy)
+	assert.Contains(t, job.Spec.Template.Spec.Tolerations, corev1.Toleration{Operator: corev1.TolerationOpExists})
+	assert.Equal(t, "flush", job.Labels[DrainHookLabelKey])
+	require.NotNil(t, job.Spec.ActiveDeadlineSeconds)
+	assert.Equal(t, int64(DefaultJobHookTimeout.Seconds()), *job.Spec.ActiveDeadlineSeconds)
+	require.Len(t, job.OwnerReferences, 1)
+	assert.Equal(t, "test-vn", job.OwnerReferences[0].Name)
+
+	// Still running
+	_, done, err = runner.Run(ctx, vn, v1alpha1.DrainHookStagePreDrain, "test-node", zap.NewNop())
+	require.NoError(t, err)
+	assert.False(t, done)
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	require.NoError(t, c.Status().Update(ctx, job))
+
+	_, done, err = runner.Run(ctx, vn, v1alpha1.DrainHookStagePreDrain, "test-node", zap.NewNop())
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.True(t, IsConditionTrue(vn, condType))
+}
+
+func TestHookRunner_JobTimeout(t *testing.T) {
+	c := newHookTestClient(t, &v1alpha1.DrainConfig{
+		PostDrainHooks: []v1alpha1.DrainHook{{
+			Name:    "handoff",
+			Timeout: &metav1.Duration{Duration: time.Minute},
+			Job: &v1alpha1.JobDrainHook{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "handoff", Image: "busybox"}}},
+				},
+			},
+		}},
+	})
+	runner := NewHookRunner(c)
+	vn := newHookTestVPSieNode()
+	ctx := context.Background()
+
+	_, done, err := runner.Run(ctx, vn, v1alpha1.DrainHookStagePostDrain, "test-node", zap.NewNop())
+	require.NoError(t, err)
+	assert.False(t, done)
+
+	// The Job started longer ago than the timeout
+	cond := GetCondition(vn, HookConditionType(v1alpha1.DrainHookStagePostDrain, "handoff"))
+	require.NotNil(t, cond)
+	cond.LastTransitionTime = metav1.NewTime(time.Now().Add(-2 * time.Minute))
+
+	_, done, err = runner.Run(ctx, vn, v1alpha1.DrainHookStagePostDrain, "test-node", zap.NewNop())
+	require.ErrorIs(t, err, ErrHookAborted)
+	assert.False(t, done)
+
+	cond = GetCondition(vn, HookConditionType(v1alpha1.DrainHookStagePostDrain, "handoff"))
+	assert.Equal(t, ReasonHookTimedOut, cond.Reason)
+
+	err = c.Get(ctx, types.NamespacedName{Name: "test-vn-post-handoff", Namespace: "kube-system"}, &batchv1.Job{})
+	assert.True(t, apierrors.IsNotFound(err), "timed out Job should be deleted")
+}
+
+func TestHookJobName(t *testing.T) {
+	assert.Equal(t, "node-1-pre-flush", hookJobName("node-1", v1alpha1.DrainHookStagePreDrain, "flush"))
+	assert.Equal(t, "node-1-post-flush", hookJobName("node-1", v1alpha1.DrainHookStagePostDrain, "flush"))
+
+	long := hookJobName(strings.Repeat("a", 50), v1alpha1.DrainHookStagePreDrain, strings.Repeat("b", 40))
+	assert.LessOrEqual(t, len(long), 63)
+	assert.NotEqual(t, long, hookJobName(strings.Repeat("a", 50), v1alpha1.DrainHookStagePreDrain, strings.Repeat("c", 40)))
+}
+
+// TestInitiateTermination_PreDrainHooks tests that a failing pre-drain hook
+// holds the node in Terminating until its failure policy allows continuing
+func TestInitiateTermination_PreDrainHooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test-node"}}
+	c := newHookTestClient(t, &v1alpha1.DrainConfig{
+		PreDrainHooks: []v1alpha1.DrainHook{{
+			Name: "deregister",
+			HTTP: &v1alpha1.HTTPDrainHook{URL: server.URL},
+		}},
+	}, node)
+
+	terminator := NewTerminator(newTestDrainer(c, node), NewProvisioner(NewMockVPSieClient(), nil))
+	terminator.SetHookRunner(NewHookRunner(c))
+	vn := newHookTestVPSieNode()
+	ctx := context.Background()
+
+	result, err := terminator.InitiateTermination(ctx, vn, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, DefaultRequeueAfter, result.RequeueAfter)
+	assert.Equal(t, v1alpha1.VPSieNodePhaseTerminating, vn.Status.Phase)
+	assert.NotNil(t, vn.Status.TerminatingAt)
+	assert.Contains(t, vn.Status.LastError, "deregister")
+
+	ng := &v1alpha1.NodeGroup{}
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Name: "test-ng", Namespace: "kube-system"}, ng))
+	ng.Spec.Drain.PreDrainHooks[0].FailurePolicy = v1alpha1.HookFailurePolicyContinue
+	require.NoError(t, c.Update(ctx, ng))
+
+	_, err = terminator.InitiateTermination(ctx, vn, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, v1alpha1.VPSieNodePhaseDeleting, vn.Status.Phase)
+}
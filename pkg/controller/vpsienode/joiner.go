@@ -300,6 +300,7 @@ func (j *Joiner) applyNodeConfiguration(ctx context.Context, vn *v1alpha1.VPSieN
 		v1alpha1.NodeGroupLabelKey:  vn.Spec.NodeGroupName,
 		v1alpha1.VPSieNodeLabelKey:  vn.Name,
 		v1alpha1.DatacenterLabelKey: vn.Spec.DatacenterID,
+		v1alpha1.OfferingLabelKey:   vn.Spec.InstanceType,
 	}
 
 	for key, value := range requiredLabels {
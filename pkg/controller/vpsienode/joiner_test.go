@@ -1,11 +1,19 @@
 package vpsienode
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/utils"
+	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestNewJoiner(t *testing.T) {
@@ -131,3 +139,31 @@ func TestRemoveString(t *testing.T) {
 		})
 	}
 }
+
+func TestJoiner_ApplyNodeConfiguration(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test-node"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(node).Build()
+	vn := &v1alpha1.VPSieNode{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-vn", Namespace: "default"},
+		Spec: v1alpha1.VPSieNodeSpec{
+			InstanceType:  "offering-1",
+			NodeGroupName: "test-ng",
+			DatacenterID:  "dc-1",
+		},
+	}
+
+	joiner := NewJoiner(k8sClient, nil)
+	require.NoError(t, joiner.applyNodeConfiguration(context.Background(), vn, node, zap.NewNop()))
+
+	updated := &corev1.Node{}
+	require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(node), updated))
+	assert.Equal(t, v1alpha1.ManagedLabelValue, updated.Labels[v1alpha1.ManagedLabelKey])
+	assert.Equal(t, "test-ng", updated.Labels[v1alpha1.NodeGroupLabelKey])
+	assert.Equal(t, "test-vn", updated.Labels[v1alpha1.VPSieNodeLabelKey])
+	assert.Equal(t, "dc-1", updated.Labels[v1alpha1.DatacenterLabelKey])
+	// Deletion order, bin-packing and rebalancing look up the offering by label
+	assert.Equal(t, "offering-1", updated.Labels[v1alpha1.OfferingLabelKey])
+}
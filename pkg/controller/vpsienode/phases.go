@@ -281,8 +281,8 @@ type TerminatingPhaseHandler struct {
 func (h *TerminatingPhaseHandler) Handle(ctx context.Context, vn *v1alpha1.VPSieNode, logger *zap.Logger) (ctrl.Result, error) {
 	logger.Info("Handling Terminating phase", zap.String("vpsienode", vn.Name))
 
-	// Drain the node and delete it from Kubernetes
-	return h.terminator.DrainAndDelete(ctx, vn, logger)
+	// Run the pre-drain hooks, then drain the node and delete it from Kubernetes
+	return h.terminator.InitiateTermination(ctx, vn, logger)
 }
 
 // DeletingPhaseHandler handles the Deleting phase
@@ -2,6 +2,7 @@ package vpsienode
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -25,6 +26,7 @@ const (
 type Terminator struct {
 	drainer     *Drainer
 	provisioner *Provisioner
+	hooks       *HookRunner
 }
 
 // NewTerminator creates a new Terminator
@@ -35,8 +37,15 @@ func NewTerminator(drainer *Drainer, provisioner *Provisioner) *Terminator {
 	}
 }
 
+// SetHookRunner sets the runner of the NodeGroups' drain hooks. Without a
+// runner, drain hooks are not run.
+func (t *Terminator) SetHookRunner(h *HookRunner) {
+	t.hooks = h
+}
+
 // InitiateTermination initiates the termination process
-// This is called when the VPSieNode enters the Terminating phase
+// This is called when the VPSieNode is in the Terminating phase. It runs the
+// NodeGroup's pre-drain hooks and, once they finished, drains and deletes the node.
 func (t *Terminator) InitiateTermination(ctx context.Context, vn *v1alpha1.VPSieNode, logger *zap.Logger) (ctrl.Result, error) {
 	logger.Info("Initiating node termination",
 		zap.String("vpsienode", vn.Name),
@@ -50,10 +59,20 @@ func (t *Terminator) InitiateTermination(ctx context.Context, vn *v1alpha1.VPSie
 	}
 
 	// Update status to indicate termination has started
-	SetPhase(vn, v1alpha1.VPSieNodePhaseTerminating, ReasonTerminating, "Starting node termination")
+	if vn.Status.Phase != v1alpha1.VPSieNodePhaseTerminating {
+		SetPhase(vn, v1alpha1.VPSieNodePhaseTerminating, ReasonTerminating, "Starting node termination")
+	}
 
-	// Requeue immediately to start the deletion process
-	return ctrl.Result{Requeue: true}, nil
+	// Pre-drain hooks run before the node is cordoned, so that workloads
+	// can still hand off to it while they run
+	if nodeName := terminationNodeName(vn); nodeName != "" {
+		result, done, err := t.runHooks(ctx, vn, v1alpha1.DrainHookStagePreDrain, nodeName, logger)
+		if !done {
+			return result, err
+		}
+	}
+
+	return t.DrainAndDelete(ctx, vn, logger)
 }
 
 // DrainAndDelete drains the node and deletes it from Kubernetes
@@ -64,15 +83,13 @@ func (t *Terminator) DrainAndDelete(ctx context.Context, vn *v1alpha1.VPSieNode,
 		zap.String("nodeName", vn.Status.NodeName),
 	)
 
-	nodeName := vn.Status.NodeName
-	if nodeName == "" {
-		nodeName = vn.Spec.NodeName
-	}
+	nodeName := terminationNodeName(vn)
 
-	// Step 1: Drain the node if it exists in Kubernetes
-	if nodeName != "" {
+	// Step 1: Drain the node if it exists in Kubernetes. A node whose
+	// post-drain hooks started was drained by an earlier reconcile.
+	if nodeName != "" && !t.postDrainHooksStarted(vn) {
 		logger.Info("Draining node", zap.String("node", nodeName))
-		if err := t.drainer.DrainNode(ctx, nodeName, logger); err != nil {
+		if err := t.drainer.DrainNode(ctx, vn, nodeName, logger); err != nil {
 			logger.Error("Failed to drain node",
 				zap.String("node", nodeName),
 				zap.Error(err),
@@ -83,8 +100,16 @@ func (t *Terminator) DrainAndDelete(ctx context.Context, vn *v1alpha1.VPSieNode,
 		} else {
 			logger.Info("Successfully drained node", zap.String("node", nodeName))
 		}
+	}
+
+	if nodeName != "" {
+		// Step 2: Run the post-drain hooks
+		result, done, err := t.runHooks(ctx, vn, v1alpha1.DrainHookStagePostDrain, nodeName, logger)
+		if !done {
+			return result, err
+		}
 
-		// Step 2: Delete the Kubernetes Node object
+		// Step 3: Delete the Kubernetes Node object
 		logger.Info("Deleting Kubernetes Node", zap.String("node", nodeName))
 		if err := t.drainer.DeleteNode(ctx, vn, logger); err != nil {
 			logger.Error("Failed to delete Kubernetes Node",
@@ -105,6 +130,39 @@ func (t *Terminator) DrainAndDelete(ctx context.Context, vn *v1alpha1.VPSieNode,
 	return ctrl.Result{Requeue: true}, nil
 }
 
+// runHooks runs the drain hooks of the stage. It returns done once the
+// termination may proceed. A hook failing with the Abort policy is recorded
+// as an error and the termination is retried later.
+func (t *Terminator) runHooks(ctx context.Context, vn *v1alpha1.VPSieNode, stage v1alpha1.DrainHookStage, nodeName string, logger *zap.Logger) (ctrl.Result, bool, error) {
+	if t.hooks == nil {
+		return ctrl.Result{}, true, nil
+	}
+
+	result, done, err := t.hooks.Run(ctx, vn, stage, nodeName, logger)
+	if errors.Is(err, ErrHookAborted) {
+		logger.Warn("Drain hook failed, termination aborted",
+			zap.String("node", nodeName),
+			zap.Error(err),
+		)
+		RecordError(vn, ReasonHookFailed, err.Error())
+		return ctrl.Result{RequeueAfter: DefaultRequeueAfter}, false, nil
+	}
+	return result, done, err
+}
+
+// postDrainHooksStarted returns true if a post-drain hook has run for the VPSieNode
+func (t *Terminator) postDrainHooksStarted(vn *v1alpha1.VPSieNode) bool {
+	return t.hooks != nil && t.hooks.Started(vn, v1alpha1.DrainHookStagePostDrain)
+}
+
+// terminationNodeName returns the name of the Kubernetes node of a VPSieNode
+func terminationNodeName(vn *v1alpha1.VPSieNode) string {
+	if vn.Status.NodeName != "" {
+		return vn.Status.NodeName
+	}
+	return vn.Spec.NodeName
+}
+
 // DeleteVPS deletes the VPS instance from VPSie
 // This is called during the Deleting phase
 func (t *Terminator) DeleteVPS(ctx context.Context, vn *v1alpha1.VPSieNode, logger *zap.Logger) (ctrl.Result, error) {
@@ -212,15 +270,12 @@ func (t *Terminator) CheckDrainStatus(ctx context.Context, vn *v1alpha1.VPSieNod
 		return true, nil
 	}
 
-	// Check if there are any pods still on the node
-	pods, err := t.drainer.getPodsOnNode(ctx, nodeName, logger)
+	// Check if there are any pods still on the node that should have been evicted
+	remainingPods, err := t.drainer.PodsToEvict(ctx, vn, nodeName, logger)
 	if err != nil {
 		return false, fmt.Errorf("failed to list pods on node: %w", err)
 	}
 
-	// Filter to pods that should have been evicted
-	remainingPods := t.drainer.filterPodsToEvict(pods, logger)
-
 	if len(remainingPods) > 0 {
 		logger.Debug("Pods still remaining on node",
 			zap.String("node", nodeName),
@@ -243,12 +298,12 @@ func (t *Terminator) GetDrainProgress(ctx context.Context, vn *v1alpha1.VPSieNod
 		return 100, nil
 	}
 
-	pods, err := t.drainer.getPodsOnNode(ctx, nodeName, logger)
+	pods, err := t.drainer.PodsToEvict(ctx, vn, nodeName, logger)
 	if err != nil {
 		return 0, fmt.Errorf("failed to list pods on node: %w", err)
 	}
 
-	totalPods := len(t.drainer.filterPodsToEvict(pods, logger))
+	totalPods := len(pods)
 	if totalPods == 0 {
 		return 100, nil
 	}
@@ -84,7 +84,7 @@ func TestTerminationFlow(t *testing.T) {
 
 	provisioner := NewProvisioner(mockVPSie, nil)
 	joiner := NewJoiner(client, provisioner)
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	terminator := NewTerminator(drainer, provisioner)
 	reconciler.stateMachine = NewStateMachine(provisioner, joiner, terminator, 24*time.Hour, client)
 	reconciler.provisioner = provisioner
@@ -180,6 +180,7 @@ func TestTerminationWithPods(t *testing.T) {
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "pod-1",
 			Namespace: "default",
+			UID:       "pod-1-uid",
 		},
 		Spec: corev1.PodSpec{
 			NodeName: "test-node",
@@ -193,6 +194,7 @@ func TestTerminationWithPods(t *testing.T) {
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "pod-2",
 			Namespace: "default",
+			UID:       "pod-2-uid",
 		},
 		Spec: corev1.PodSpec{
 			NodeName: "test-node",
@@ -207,6 +209,7 @@ func TestTerminationWithPods(t *testing.T) {
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "ds-pod",
 			Namespace: "default",
+			UID:       "ds-pod-uid",
 			OwnerReferences: []metav1.OwnerReference{
 				{
 					Kind: "DaemonSet",
@@ -250,7 +253,7 @@ func TestTerminationWithPods(t *testing.T) {
 
 	provisioner := NewProvisioner(mockVPSie, nil)
 	joiner := NewJoiner(client, provisioner)
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client, node, pod1, pod2, dsPod)
 	terminator := NewTerminator(drainer, provisioner)
 	reconciler.stateMachine = NewStateMachine(provisioner, joiner, terminator, 24*time.Hour, client)
 	reconciler.provisioner = provisioner
@@ -293,9 +296,7 @@ func TestTerminationWithPods(t *testing.T) {
 		assert.True(t, true, "Node was deleted during termination")
 	}
 
-	// Note: The fake client doesn't fully support pod eviction subresource,
-	// so we can't verify pod deletion in this test. The drain logic is tested
-	// separately in drainer_test.go
+	// The drain logic itself is tested in the drain package
 }
 
 // TestTerminationWithNonExistentNode tests termination when node doesn't exist
@@ -348,7 +349,7 @@ func TestTerminationWithNonExistentNode(t *testing.T) {
 
 	provisioner := NewProvisioner(mockVPSie, nil)
 	joiner := NewJoiner(client, provisioner)
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	terminator := NewTerminator(drainer, provisioner)
 	reconciler.stateMachine = NewStateMachine(provisioner, joiner, terminator, 24*time.Hour, client)
 	reconciler.provisioner = provisioner
@@ -429,7 +430,7 @@ func TestVPSDeletionFailure(t *testing.T) {
 	logger := zap.NewNop()
 
 	provisioner := NewProvisioner(mockVPSie, nil)
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	terminator := NewTerminator(drainer, provisioner)
 
 	// Try to delete VPS
@@ -478,7 +479,7 @@ func TestVPSAlreadyDeleted(t *testing.T) {
 	logger := zap.NewNop()
 
 	provisioner := NewProvisioner(mockVPSie, nil)
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	terminator := NewTerminator(drainer, provisioner)
 
 	// Try to delete non-existent VPS
@@ -523,7 +524,7 @@ func TestTerminationWithNoVPSID(t *testing.T) {
 	logger := zap.NewNop()
 
 	provisioner := NewProvisioner(mockVPSie, nil)
-	drainer := NewDrainer(client)
+	drainer := newTestDrainer(client)
 	terminator := NewTerminator(drainer, provisioner)
 
 	// Try to delete with no VPS ID
@@ -0,0 +1,245 @@
+// Package costreport builds offline cost reports for NodeGroups from a snapshot
+// of cluster state, using the cost calculator, analyzer and optimizer.
+package costreport
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	v1alpha1 "github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
+)
+
+// Report is a cost report for a set of NodeGroups
+type Report struct {
+	GeneratedAt time.Time         `json:"generatedAt"`
+	Currency    string            `json:"currency"`
+	NodeGroups  []NodeGroupReport `json:"nodeGroups"`
+	Totals      Totals            `json:"totals"`
+}
+
+// Totals summarizes a report across all NodeGroups
+type Totals struct {
+	Nodes            int32   `json:"nodes"`
+	MonthlyCost      float64 `json:"monthlyCost"`
+	EffectiveMonthly float64 `json:"effectiveMonthly"`
+	WasteMonthly     float64 `json:"wasteMonthly"`
+	PotentialSavings float64 `json:"potentialSavings"`
+}
+
+// NodeGroupReport is the cost report for a single NodeGroup
+type NodeGroupReport struct {
+	Name             string           `json:"name"`
+	Namespace        string           `json:"namespace"`
+	Nodes            int32            `json:"nodes"`
+	Offerings        []string         `json:"offerings"`
+	MonthlyCost      float64          `json:"monthlyCost"`
+	EffectiveMonthly float64          `json:"effectiveMonthly"`
+	CPUPercent       float64          `json:"cpuPercent"`
+	MemoryPercent    float64          `json:"memoryPercent"`
+	EfficiencyScore  float64          `json:"efficiencyScore"`
+	WasteMonthly     float64          `json:"wasteMonthly"`
+	PotentialSavings float64          `json:"potentialSavings"`
+	Recommendations  []Recommendation `json:"recommendations,omitempty"`
+	Notes            []string         `json:"notes,omitempty"`
+	Error            string           `json:"error,omitempty"`
+}
+
+// Recommendation is a recommended change for a NodeGroup
+type Recommendation struct {
+	Type           cost.OptimizationType `json:"type"`
+	Description    string                `json:"description"`
+	MonthlySavings float64               `json:"monthlySavings"`
+	Confidence     float64               `json:"confidence"`
+	Risk           cost.RiskLevel        `json:"risk"`
+}
+
+// Generator builds cost reports
+type Generator struct {
+	calculator *cost.Calculator
+	analyzer   *cost.Analyzer
+	optimizer  *cost.Optimizer
+}
+
+// NewGenerator creates a report generator that prices NodeGroups with calculator.
+// If ledger is not nil, recommendation confidence is scaled by the measured
+// accuracy of the savings predicted for optimizations applied in the past.
+func NewGenerator(calculator *cost.Calculator, ledger *cost.SavingsLedger) *Generator {
+	analyzer := cost.NewAnalyzer(calculator, cost.NewMemoryCostStorage())
+	optimizer := cost.NewOptimizer(calculator, analyzer, nil)
+	if ledger != nil {
+		optimizer.SetSavingsLedger(ledger)
+	}
+	return &Generator{
+		calculator: calculator,
+		analyzer:   analyzer,
+		optimizer:  optimizer,
+	}
+}
+
+// Generate builds a report for the given NodeGroups. Utilization is measured
+// with each NodeGroup's scale-down utilization mode from the resource requests
+// of pods running on its nodes and the nodes' usage in nodeMetrics, which may
+// be empty if metrics-server is unavailable.
+func (g *Generator) Generate(ctx context.Context, nodeGroups []v1alpha1.NodeGroup, nodes []corev1.Node, pods []corev1.Pod, nodeMetrics []metricsv1beta1.NodeMetrics) (*Report, error) {
+	report := &Report{
+		GeneratedAt: time.Now(),
+		Currency:    "USD",
+	}
+	if plan := g.calculator.PricingPlan(); plan != nil {
+		report.Currency = plan.Currency
+	}
+
+	podsByNode := make(map[string][]corev1.Pod)
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+	}
+
+	usageByNode := make(map[string]corev1.ResourceList, len(nodeMetrics))
+	for _, metrics := range nodeMetrics {
+		usageByNode[metrics.Name] = metrics.Usage
+	}
+
+	sorted := make([]v1alpha1.NodeGroup, len(nodeGroups))
+	copy(sorted, nodeGroups)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Namespace != sorted[j].Namespace {
+			return sorted[i].Namespace < sorted[j].Namespace
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	for i := range sorted {
+		ngReport := g.generateNodeGroup(ctx, &sorted[i], nodes, podsByNode, usageByNode)
+
+		report.NodeGroups = append(report.NodeGroups, ngReport)
+		report.Totals.Nodes += ngReport.Nodes
+		report.Totals.MonthlyCost += ngReport.MonthlyCost
+		report.Totals.EffectiveMonthly += ngReport.EffectiveMonthly
+		report.Totals.WasteMonthly += ngReport.WasteMonthly
+		report.Totals.PotentialSavings += ngReport.PotentialSavings
+	}
+
+	return report, nil
+}
+
+// generateNodeGroup builds the report for a single NodeGroup. Pricing failures are
+// recorded in the report instead of failing the whole run.
+func (g *Generator) generateNodeGroup(ctx context.Context, nodeGroup *v1alpha1.NodeGroup, nodes []corev1.Node, podsByNode map[string][]corev1.Pod, usageByNode map[string]corev1.ResourceList) NodeGroupReport {
+	ngReport := NodeGroupReport{
+		Name:      nodeGroup.Name,
+		Namespace: nodeGroup.Namespace,
+	}
+
+	utilization, hasUsage := nodeGroupUtilization(nodeGroup, nodes, podsByNode, usageByNode)
+	if !hasUsage && utilization.NodeCount > 0 && v1alpha1.EffectiveUtilizationMode(&nodeGroup.Spec.ScaleDownPolicy) != v1alpha1.UtilizationModeRequests {
+		ngReport.Notes = append(ngReport.Notes, "No usage metrics available, utilization is based on pod requests")
+	}
+
+	if err := g.analyzer.RecordCost(ctx, nodeGroup, utilization); err != nil {
+		ngReport.Error = err.Error()
+		return ngReport
+	}
+
+	analysis, err := g.analyzer.AnalyzeUtilization(ctx, nodeGroup)
+	if err != nil {
+		ngReport.Error = err.Error()
+		return ngReport
+	}
+	ngReport.CPUPercent = analysis.AverageUtilization.CPUPercent
+	ngReport.MemoryPercent = analysis.AverageUtilization.MemoryPercent
+	ngReport.EfficiencyScore = analysis.EfficiencyScore
+	ngReport.WasteMonthly = analysis.WasteEstimate
+	ngReport.Notes = append(ngReport.Notes, analysis.Recommendations...)
+
+	optimizations, err := g.optimizer.AnalyzeOptimizations(ctx, nodeGroup)
+	if err != nil {
+		ngReport.Error = fmt.Sprintf("failed to analyze optimizations: %v", err)
+		return ngReport
+	}
+
+	currentCost := optimizations.CurrentCost
+	ngReport.Nodes = currentCost.TotalNodes
+	ngReport.MonthlyCost = currentCost.TotalMonthly
+	ngReport.EffectiveMonthly = currentCost.EffectiveMonthly
+	ngReport.PotentialSavings = optimizations.PotentialSavings
+	for offeringID := range currentCost.InstanceTypes {
+		ngReport.Offerings = append(ngReport.Offerings, offeringID)
+	}
+	sort.Strings(ngReport.Offerings)
+
+	for _, opp := range optimizations.Opportunities {
+		ngReport.Recommendations = append(ngReport.Recommendations, Recommendation{
+			Type:           opp.Type,
+			Description:    opp.Description,
+			MonthlySavings: opp.MonthlySavings,
+			Confidence:     opp.ConfidenceScore,
+			Risk:           opp.Risk,
+		})
+	}
+
+	return ngReport
+}
+
+// nodeGroupUtilization computes the requested and used share of allocatable CPU
+// and memory across the nodes labelled as members of the NodeGroup. Usage
+// covers the nodes with metrics; without any it is taken to equal the requests
+// and false is returned.
+func nodeGroupUtilization(nodeGroup *v1alpha1.NodeGroup, nodes []corev1.Node, podsByNode map[string][]corev1.Pod, usageByNode map[string]corev1.ResourceList) (cost.ResourceUtilization, bool) {
+	var allocatableCPU, allocatableMemory, requestedCPU, requestedMemory int64
+	var measuredCPU, measuredMemory, usedCPU, usedMemory int64
+	var nodeCount int32
+
+	for i := range nodes {
+		node := &nodes[i]
+		if node.Labels[v1alpha1.NodeGroupLabelKey] != nodeGroup.Name {
+			continue
+		}
+		nodeCount++
+		allocatableCPU += node.Status.Allocatable.Cpu().MilliValue()
+		allocatableMemory += node.Status.Allocatable.Memory().Value()
+
+		if usage, ok := usageByNode[node.Name]; ok {
+			measuredCPU += node.Status.Allocatable.Cpu().MilliValue()
+			measuredMemory += node.Status.Allocatable.Memory().Value()
+			usedCPU += usage.Cpu().MilliValue()
+			usedMemory += usage.Memory().Value()
+		}
+
+		for _, pod := range podsByNode[node.Name] {
+			for _, container := range pod.Spec.Containers {
+				requestedCPU += container.Resources.Requests.Cpu().MilliValue()
+				requestedMemory += container.Resources.Requests.Memory().Value()
+			}
+		}
+	}
+
+	utilization := cost.ResourceUtilization{NodeCount: nodeCount}
+	if allocatableCPU > 0 {
+		utilization.CPURequestsPercent = float64(requestedCPU) / float64(allocatableCPU) * 100
+	}
+	if allocatableMemory > 0 {
+		utilization.MemoryRequestsPercent = float64(requestedMemory) / float64(allocatableMemory) * 100
+	}
+
+	if measuredCPU == 0 && measuredMemory == 0 {
+		utilization.CPUPercent = utilization.CPURequestsPercent
+		utilization.MemoryPercent = utilization.MemoryRequestsPercent
+		return utilization, false
+	}
+	if measuredCPU > 0 {
+		utilization.CPUPercent = float64(usedCPU) / float64(measuredCPU) * 100
+	}
+	if measuredMemory > 0 {
+		utilization.MemoryPercent = float64(usedMemory) / float64(measuredMemory) * 100
+	}
+	return utilization, true
+}
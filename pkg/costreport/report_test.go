@@ -0,0 +1,342 @@
+package costreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
+)
+
+const testCatalog = `
+version: "test"
+offerings:
+  - id: small-1
+    name: Small
+    cpu: 2
+    ram: 4096
+    disk: 50
+    price: 20.0
+    hourly_price: 0.03
+    available: true
+    category: standard
+  - id: large-1
+    name: Large
+    cpu: 8
+    ram: 16384
+    disk: 200
+    price: 60.0
+    hourly_price: 0.09
+    available: true
+    category: standard
+`
+
+func newTestGenerator(t *testing.T) *Generator {
+	t.Helper()
+	catalog, err := cost.ParsePriceCatalog([]byte(testCatalog))
+	require.NoError(t, err)
+
+	calculator := cost.NewCalculator(nil)
+	calculator.SetPriceCatalog(catalog, cost.CatalogModeOverride)
+	return NewGenerator(calculator, nil)
+}
+
+func newTestNode(name, nodeGroup string) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{v1alpha1.NodeGroupLabelKey: nodeGroup},
+		},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("2"),
+				corev1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+}
+
+func newTestPod(name, nodeName, cpu, memory string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse(cpu),
+						corev1.ResourceMemory: resource.MustParse(memory),
+					},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func newTestReport(t *testing.T) *Report {
+	t.Helper()
+
+	nodeGroups := []v1alpha1.NodeGroup{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "workers", Namespace: "default"},
+			Spec: v1alpha1.NodeGroupSpec{
+				MinNodes:    1,
+				MaxNodes:    5,
+				OfferingIDs: []string{"small-1", "large-1"},
+			},
+			Status: v1alpha1.NodeGroupStatus{
+				CurrentNodes: 2,
+				DesiredNodes: 2,
+				Nodes: []v1alpha1.NodeInfo{
+					{NodeName: "node-1", InstanceType: "small-1"},
+					{NodeName: "node-2", InstanceType: "small-1"},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "broken", Namespace: "default"},
+			Spec:       v1alpha1.NodeGroupSpec{MinNodes: 1, MaxNodes: 2, OfferingIDs: []string{"missing-1"}},
+		},
+	}
+
+	nodes := []corev1.Node{
+		newTestNode("node-1", "workers"),
+		newTestNode("node-2", "workers"),
+	}
+
+	pods := []corev1.Pod{
+		newTestPod("app-1", "node-1", "1", "1Gi"),
+		newTestPod("app-2", "node-2", "1", "1Gi"),
+		newTestPod("pending", "", "4", "8Gi"),
+	}
+
+	report, err := newTestGenerator(t).Generate(context.Background(), nodeGroups, nodes, pods, nil)
+	require.NoError(t, err)
+	return report
+}
+
+func TestGenerate(t *testing.T) {
+	report := newTestReport(t)
+
+	require.Len(t, report.NodeGroups, 2)
+
+	// NodeGroups are sorted by namespace and name
+	broken := report.NodeGroups[0]
+	assert.Equal(t, "broken", broken.Name)
+	assert.NotEmpty(t, broken.Error)
+
+	workers := report.NodeGroups[1]
+	assert.Equal(t, "workers", workers.Name)
+	assert.Empty(t, workers.Error)
+	assert.Equal(t, int32(2), workers.Nodes)
+	assert.Equal(t, []string{"small-1"}, workers.Offerings)
+	assert.InDelta(t, 0.03*2*730, workers.MonthlyCost, 0.001)
+	assert.InDelta(t, 50.0, workers.CPUPercent, 0.001)
+	assert.InDelta(t, 25.0, workers.MemoryPercent, 0.001)
+	assert.Greater(t, workers.WasteMonthly, 0.0)
+	assert.NotEmpty(t, workers.Notes)
+
+	assert.Equal(t, int32(2), report.Totals.Nodes)
+	assert.InDelta(t, workers.MonthlyCost, report.Totals.MonthlyCost, 0.001)
+}
+
+func TestGenerate_UtilizationMode(t *testing.T) {
+	nodes := []corev1.Node{
+		newTestNode("node-1", "workers"),
+		newTestNode("node-2", "workers"),
+	}
+	pods := []corev1.Pod{
+		newTestPod("app-1", "node-1", "1", "1Gi"),
+		newTestPod("app-2", "node-2", "1", "1Gi"),
+	}
+	var nodeMetrics []metricsv1beta1.NodeMetrics
+	for _, name := range []string{"node-1", "node-2"} {
+		nodeMetrics = append(nodeMetrics, metricsv1beta1.NodeMetrics{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Usage: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("200m"),
+				corev1.ResourceMemory: resource.MustParse("2Gi"),
+			},
+		})
+	}
+
+	tests := []struct {
+		name    string
+		mode    v1alpha1.UtilizationMode
+		metrics []metricsv1beta1.NodeMetrics
+		cpu     float64
+		memory  float64
+		note    bool
+	}{
+		{name: "usage", mode: v1alpha1.UtilizationModeUsage, metrics: nodeMetrics, cpu: 10, memory: 50},
+		{name: "requests", mode: v1alpha1.UtilizationModeRequests, metrics: nodeMetrics, cpu: 50, memory: 25},
+		{name: "max", mode: v1alpha1.UtilizationModeMax, metrics: nodeMetrics, cpu: 50, memory: 50},
+		{name: "usage without metrics", cpu: 50, memory: 25, note: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeGroups := []v1alpha1.NodeGroup{{
+				ObjectMeta: metav1.ObjectMeta{Name: "workers", Namespace: "default"},
+				Spec: v1alpha1.NodeGroupSpec{
+					MinNodes:        1,
+					MaxNodes:        5,
+					OfferingIDs:     []string{"small-1"},
+					ScaleDownPolicy: v1alpha1.ScaleDownPolicy{UtilizationMode: tt.mode},
+				},
+				Status: v1alpha1.NodeGroupStatus{
+					CurrentNodes: 2,
+					Nodes: []v1alpha1.NodeInfo{
+						{NodeName: "node-1", InstanceType: "small-1"},
+						{NodeName: "node-2", InstanceType: "small-1"},
+					},
+				},
+			}}
+
+			report, err := newTestGenerator(t).Generate(context.Background(), nodeGroups, nodes, pods, tt.metrics)
+			require.NoError(t, err)
+			require.Len(t, report.NodeGroups, 1)
+
+			workers := report.NodeGroups[0]
+			assert.Empty(t, workers.Error)
+			assert.InDelta(t, tt.cpu, workers.CPUPercent, 0.001)
+			assert.InDelta(t, tt.memory, workers.MemoryPercent, 0.001)
+			if tt.note {
+				assert.Contains(t, workers.Notes, "No usage metrics available, utilization is based on pod requests")
+			} else {
+				assert.NotContains(t, workers.Notes, "No usage metrics available, utilization is based on pod requests")
+			}
+		})
+	}
+}
+
+func TestGenerate_SavingsLedger(t *testing.T) {
+	catalog, err := cost.ParsePriceCatalog([]byte(testCatalog + `
+  - id: medium-1
+    name: Medium
+    cpu: 6
+    ram: 12288
+    disk: 100
+    price: 40.0
+    hourly_price: 0.06
+    available: true
+    category: standard
+`))
+	require.NoError(t, err)
+	calculator := cost.NewCalculator(nil)
+	calculator.SetPriceCatalog(catalog, cost.CatalogModeOverride)
+
+	nodeGroups := []v1alpha1.NodeGroup{{
+		ObjectMeta: metav1.ObjectMeta{Name: "workers", Namespace: "default"},
+		Spec:       v1alpha1.NodeGroupSpec{MinNodes: 1, MaxNodes: 5, OfferingIDs: []string{"medium-1", "large-1"}},
+		Status: v1alpha1.NodeGroupStatus{
+			CurrentNodes: 2,
+			DesiredNodes: 2,
+			Nodes: []v1alpha1.NodeInfo{
+				{NodeName: "node-1", InstanceType: "large-1"},
+				{NodeName: "node-2", InstanceType: "large-1"},
+			},
+		},
+	}}
+	nodes := []corev1.Node{newTestNode("node-1", "workers"), newTestNode("node-2", "workers")}
+	pods := []corev1.Pod{newTestPod("app-1", "node-1", "100m", "128Mi")}
+
+	// Past downsizes realized half of their predicted savings
+	var entries []cost.LedgerEntry
+	for i := 0; i < cost.MinLedgerEntriesForFeedback; i++ {
+		entries = append(entries, cost.LedgerEntry{
+			ID:                      fmt.Sprintf("default-workers-%d", i+1),
+			NodeGroupName:           "workers",
+			Namespace:               "default",
+			Type:                    cost.OptimizationDownsize,
+			PredictedMonthlySavings: 40,
+			Status:                  cost.LedgerStatusVerified,
+			RealizedMonthlySavings:  20,
+			PredictionError:         0.5,
+		})
+	}
+	data, err := json.Marshal(entries)
+	require.NoError(t, err)
+	clientset := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "savings-ledger", Namespace: "kube-system"},
+		Data:       map[string]string{cost.LedgerConfigMapKey: string(data)},
+	})
+
+	ledger := cost.NewSavingsLedger(nil, 0)
+	ledger.SetStore(cost.NewConfigMapLedgerStore(clientset, "kube-system", "savings-ledger"))
+	require.NoError(t, ledger.Load(context.Background()))
+
+	confidence := func(ledger *cost.SavingsLedger) float64 {
+		report, err := NewGenerator(calculator, ledger).Generate(context.Background(), nodeGroups, nodes, pods, nil)
+		require.NoError(t, err)
+		require.Len(t, report.NodeGroups, 1)
+		for _, rec := range report.NodeGroups[0].Recommendations {
+			if rec.Type == cost.OptimizationDownsize {
+				return rec.Confidence
+			}
+		}
+		t.Fatal("expected a downsize recommendation")
+		return 0
+	}
+
+	unweighted := confidence(nil)
+	assert.Greater(t, unweighted, 0.0)
+	assert.InDelta(t, unweighted*0.5, confidence(ledger), 0.001)
+}
+
+func TestWrite(t *testing.T) {
+	report := newTestReport(t)
+
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Write(&buf, report, FormatTable))
+		out := buf.String()
+		assert.Contains(t, out, "NODEGROUP")
+		assert.Contains(t, out, "workers")
+		assert.Contains(t, out, "TOTAL")
+		assert.Contains(t, out, "error:")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Write(&buf, report, FormatJSON))
+
+		var decoded Report
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Len(t, decoded.NodeGroups, 2)
+		assert.InDelta(t, report.Totals.MonthlyCost, decoded.Totals.MonthlyCost, 0.001)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Write(&buf, report, FormatCSV))
+
+		rows, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, len(rows), 3)
+		assert.Equal(t, "namespace", rows[0][0])
+		for _, row := range rows {
+			assert.Len(t, row, len(rows[0]))
+		}
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Error(t, Write(&buf, report, Format("yaml")))
+	})
+}
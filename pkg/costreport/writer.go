@@ -0,0 +1,138 @@
+package costreport
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Format is a report output format
+type Format string
+
+const (
+	// FormatTable renders a human-readable table
+	FormatTable Format = "table"
+
+	// FormatJSON renders the full report as JSON
+	FormatJSON Format = "json"
+
+	// FormatCSV renders one row per NodeGroup recommendation for spreadsheets
+	FormatCSV Format = "csv"
+)
+
+// Write renders the report to w in the given format
+func Write(w io.Writer, report *Report, format Format) error {
+	switch format {
+	case FormatTable, "":
+		return writeTable(w, report)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case FormatCSV:
+		return writeCSV(w, report)
+	default:
+		return fmt.Errorf("unsupported output format %q, must be one of: table, json, csv", format)
+	}
+}
+
+func writeTable(w io.Writer, report *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Cost report generated %s (%s)\n\n", report.GeneratedAt.Format("2006-01-02 15:04:05 MST"), report.Currency)
+	fmt.Fprintln(tw, "NAMESPACE\tNODEGROUP\tNODES\tOFFERINGS\tMONTHLY\tEFFECTIVE\tCPU%\tMEM%\tWASTE\tSAVINGS")
+	for _, ng := range report.NodeGroups {
+		if ng.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\t-\t-\t-\t-\t-\t-\t-\t-\n", ng.Namespace, ng.Name)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%.2f\t%.2f\t%.1f\t%.1f\t%.2f\t%.2f\n",
+			ng.Namespace, ng.Name, ng.Nodes, strings.Join(ng.Offerings, ","),
+			ng.MonthlyCost, ng.EffectiveMonthly, ng.CPUPercent, ng.MemoryPercent,
+			ng.WasteMonthly, ng.PotentialSavings)
+	}
+	fmt.Fprintf(tw, "TOTAL\t\t%d\t\t%.2f\t%.2f\t\t\t%.2f\t%.2f\n",
+		report.Totals.Nodes, report.Totals.MonthlyCost, report.Totals.EffectiveMonthly,
+		report.Totals.WasteMonthly, report.Totals.PotentialSavings)
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, ng := range report.NodeGroups {
+		if ng.Error == "" && len(ng.Recommendations) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s/%s:\n", ng.Namespace, ng.Name)
+		if ng.Error != "" {
+			fmt.Fprintf(w, "  error: %s\n", ng.Error)
+			continue
+		}
+		for _, rec := range ng.Recommendations {
+			fmt.Fprintf(w, "  - [%s, %s risk, %.0f%% confidence] %s (saves %.2f/month)\n",
+				rec.Type, rec.Risk, rec.Confidence*100, rec.Description, rec.MonthlySavings)
+		}
+	}
+
+	return nil
+}
+
+func writeCSV(w io.Writer, report *Report) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{
+		"namespace", "nodegroup", "nodes", "offerings", "monthly_cost", "effective_monthly",
+		"cpu_percent", "memory_percent", "waste_monthly", "potential_savings",
+		"recommendation_type", "recommendation", "recommendation_savings", "confidence", "risk", "error",
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, ng := range report.NodeGroups {
+		base := []string{
+			ng.Namespace,
+			ng.Name,
+			strconv.Itoa(int(ng.Nodes)),
+			strings.Join(ng.Offerings, " "),
+			formatFloat(ng.MonthlyCost),
+			formatFloat(ng.EffectiveMonthly),
+			formatFloat(ng.CPUPercent),
+			formatFloat(ng.MemoryPercent),
+			formatFloat(ng.WasteMonthly),
+			formatFloat(ng.PotentialSavings),
+		}
+
+		// One row per recommendation so spreadsheets can filter and sum savings
+		if len(ng.Recommendations) == 0 {
+			if err := cw.Write(append(base, "", "", "", "", "", ng.Error)); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, rec := range ng.Recommendations {
+			row := append(append([]string{}, base...),
+				string(rec.Type),
+				rec.Description,
+				formatFloat(rec.MonthlySavings),
+				formatFloat(rec.Confidence),
+				string(rec.Risk),
+				ng.Error,
+			)
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
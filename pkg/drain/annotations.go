@@ -0,0 +1,84 @@
+package drain
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Pod annotations controlling whether the node of a pod may be removed.
+// Workloads set them in their pod template. The cluster-autoscaler
+// annotations are honoured as aliases; our own take precedence.
+const (
+	// SafeToEvictAnnotation set to "false" blocks the removal of the pod's
+	// node. Set to "true" it allows the removal even if the pod stores data
+	// in local volumes.
+	SafeToEvictAnnotation = "autoscaler.vpsie.com/safe-to-evict"
+
+	// SafeToEvictLocalVolumesAnnotation lists, comma separated, the volumes
+	// of the pod whose local data may be lost when its node is removed
+	SafeToEvictLocalVolumesAnnotation = "autoscaler.vpsie.com/safe-to-evict-local-volumes"
+
+	// ClusterAutoscalerSafeToEvictAnnotation is the cluster-autoscaler alias of SafeToEvictAnnotation
+	ClusterAutoscalerSafeToEvictAnnotation = "cluster-autoscaler.kubernetes.io/safe-to-evict"
+
+	// ClusterAutoscalerSafeToEvictLocalVolumesAnnotation is the cluster-autoscaler
+	// alias of SafeToEvictLocalVolumesAnnotation
+	ClusterAutoscalerSafeToEvictLocalVolumesAnnotation = "cluster-autoscaler.kubernetes.io/safe-to-evict-local-volumes"
+)
+
+// safeToEvictAnnotation returns the key and value of the pod's safe-to-evict
+// annotation, or empty strings if it has none
+func safeToEvictAnnotation(pod *corev1.Pod) (string, string) {
+	for _, key := range []string{SafeToEvictAnnotation, ClusterAutoscalerSafeToEvictAnnotation} {
+		if value, exists := pod.Annotations[key]; exists {
+			return key, strings.TrimSpace(value)
+		}
+	}
+	return "", ""
+}
+
+// IsSafeToEvict reports whether the pod is annotated safe-to-evict=true
+func IsSafeToEvict(pod *corev1.Pod) bool {
+	_, value := safeToEvictAnnotation(pod)
+	return strings.EqualFold(value, "true")
+}
+
+// BlockingReason returns why the pod's annotations block the removal of its
+// node, or an empty string if they don't
+func BlockingReason(pod *corev1.Pod) string {
+	key, value := safeToEvictAnnotation(pod)
+	if !strings.EqualFold(value, "false") {
+		return ""
+	}
+	return fmt.Sprintf("pod %s/%s is annotated %s=false", pod.Namespace, pod.Name, key)
+}
+
+// IsLocalVolumeSafeToEvict reports whether the data of the pod's local
+// volume may be lost, because the pod is annotated safe-to-evict=true or
+// lists the volume in its safe-to-evict-local-volumes annotation
+func IsLocalVolumeSafeToEvict(pod *corev1.Pod, volumeName string) bool {
+	if IsSafeToEvict(pod) {
+		return true
+	}
+	for _, key := range []string{SafeToEvictLocalVolumesAnnotation, ClusterAutoscalerSafeToEvictLocalVolumesAnnotation} {
+		for _, name := range strings.Split(pod.Annotations[key], ",") {
+			if name = strings.TrimSpace(name); name != "" && name == volumeName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// blockedPods returns the reasons the pods' annotations block the drain
+func blockedPods(pods []*corev1.Pod) string {
+	var reasons []string
+	for _, pod := range pods {
+		if reason := BlockingReason(pod); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
@@ -0,0 +1,546 @@
+// Package drain cordons nodes and evicts their pods before the nodes are
+// removed. The scale-down manager, the VPSieNode terminator and the
+// rebalancer all drain nodes through it.
+package drain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
+)
+
+const (
+	// StartTimeAnnotation records when the node's drain started
+	StartTimeAnnotation = "autoscaler.vpsie.com/drain-start-time"
+
+	// StatusAnnotation records the state of the node's drain
+	StatusAnnotation = "autoscaler.vpsie.com/drain-status"
+
+	// Values of StatusAnnotation
+	StatusDraining = "draining"
+	StatusComplete = "complete"
+	StatusFailed   = "failed"
+	StatusTimeout  = "timeout"
+
+	// MaxEvictionRetries bounds the attempts to evict a pod that fail for
+	// reasons other than a PodDisruptionBudget. Evictions blocked by a
+	// budget are retried until the drain times out.
+	MaxEvictionRetries = 12
+
+	// detachGracePeriod is how long Drain keeps waiting for a drain after
+	// the caller's context is cancelled, e.g. on controller shutdown
+	detachGracePeriod = 30 * time.Second
+
+	// cleanupTimeout bounds the rollback and annotation updates after a drain
+	cleanupTimeout = 10 * time.Second
+
+	// nodeGroupNamespaceLabel is the node label metrics read the NodeGroup
+	// namespace from when the options do not name it
+	nodeGroupNamespaceLabel = "autoscaler.vpsie.com/nodegroup-namespace"
+)
+
+// Drainer cordons nodes and evicts their pods
+type Drainer interface {
+	// Cordon marks the node unschedulable
+	Cordon(ctx context.Context, nodeName string) error
+
+	// Uncordon marks the node schedulable again
+	Uncordon(ctx context.Context, nodeName string) error
+
+	// PodsToEvict returns the pods on the node a drain with opts evicts
+	PodsToEvict(ctx context.Context, nodeName string, opts Options) ([]*corev1.Pod, error)
+
+	// Drain cordons the node, evicts its pods and waits for them to terminate.
+	// The node is left cordoned on success and when pods may still be
+	// terminating; it is uncordoned when the drain fails before that.
+	Drain(ctx context.Context, nodeName string, opts Options) error
+}
+
+// NodeDrainer implements Drainer with the Kubernetes eviction API
+type NodeDrainer struct {
+	client  kubernetes.Interface
+	logger  *zap.Logger
+	limiter *rate.Limiter
+}
+
+var _ Drainer = &NodeDrainer{}
+
+// NewNodeDrainer creates a NodeDrainer
+func NewNodeDrainer(client kubernetes.Interface, logger *zap.Logger) *NodeDrainer {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &NodeDrainer{
+		client:  client,
+		logger:  logger,
+		limiter: evictionLimiter,
+	}
+}
+
+// Cordon marks the node unschedulable
+func (d *NodeDrainer) Cordon(ctx context.Context, nodeName string) error {
+	return d.setUnschedulable(ctx, nodeName, true)
+}
+
+// Uncordon marks the node schedulable again
+func (d *NodeDrainer) Uncordon(ctx context.Context, nodeName string) error {
+	return d.setUnschedulable(ctx, nodeName, false)
+}
+
+// PodsToEvict returns the pods on the node a drain with opts evicts
+func (d *NodeDrainer) PodsToEvict(ctx context.Context, nodeName string, opts Options) ([]*corev1.Pod, error) {
+	pods, err := d.nodePods(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	return filterPods(pods, opts), nil
+}
+
+// Drain cordons the node, evicts its pods and waits for them to terminate.
+//
+// Cleanup Policy:
+// - Before any pod is evicted: failures uncordon the node (rollback)
+// - On eviction timeout: leaves the node cordoned (pods still terminating)
+// - On other eviction failures: uncordons the node so the drain can be retried
+// - On successful drain: leaves the node cordoned for deletion
+//
+// Evictions run detached from ctx so a controller shutdown does not leave
+// a half-drained node behind; Drain returns at most detachGracePeriod after
+// ctx is cancelled while the drain continues in the background.
+func (d *NodeDrainer) Drain(ctx context.Context, nodeName string, opts Options) error {
+	startTime := time.Now()
+	opts = opts.withDefaults()
+	logger := d.logger.With(zap.String("node", nodeName))
+
+	node, err := d.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get node: %w", err)
+	}
+
+	// A node cordoned before the drain stays cordoned when it fails
+	wasCordoned := node.Spec.Unschedulable
+	nodeGroup, nodeGroupNamespace := metricLabels(node, opts)
+	recordDrainDuration := func(result string) {
+		metrics.NodeDrainDuration.WithLabelValues(nodeGroup, nodeGroupNamespace, result).
+			Observe(time.Since(startTime).Seconds())
+	}
+
+	logger.Info("Starting node drain", zap.Duration("timeout", opts.Timeout))
+
+	if err := d.Cordon(ctx, nodeName); err != nil {
+		recordDrainDuration("error")
+		return fmt.Errorf("failed to cordon node: %w", err)
+	}
+
+	pods, err := d.PodsToEvict(ctx, nodeName, opts)
+	if err != nil {
+		d.rollback(nodeName, wasCordoned, logger)
+		recordDrainDuration("error")
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	if reasons := blockedPods(pods); reasons != "" {
+		d.rollback(nodeName, wasCordoned, logger)
+		recordDrainDuration("error")
+		return fmt.Errorf("pods block the drain: %s", reasons)
+	}
+
+	if !opts.DeleteEmptyDirData {
+		if names := emptyDirPods(pods); names != "" {
+			d.rollback(nodeName, wasCordoned, logger)
+			recordDrainDuration("error")
+			return fmt.Errorf("pods with emptyDir data would lose it: %s", names)
+		}
+	}
+
+	if len(pods) == 0 {
+		logger.Info("No pods to evict")
+		recordDrainDuration("success")
+		metrics.NodeDrainPodsEvicted.WithLabelValues(nodeGroup, nodeGroupNamespace).Observe(0)
+		return nil
+	}
+
+	if err := d.annotate(ctx, nodeName, map[string]string{
+		StartTimeAnnotation: startTime.Format(time.RFC3339),
+		StatusAnnotation:    StatusDraining,
+	}); err != nil {
+		logger.Warn("Failed to annotate drain start", zap.Error(err))
+	}
+
+	logger.Info("Evicting pods from node",
+		zap.Int("podCount", len(pods)),
+		zap.Int("maxConcurrentEvictions", opts.MaxConcurrentEvictions),
+	)
+
+	drainCtx, drainCancel := context.WithTimeout(context.WithoutCancel(ctx), opts.Timeout)
+	defer drainCancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- d.evictAndWait(drainCtx, nodeName, pods, opts, startTime, nodeGroup, nodeGroupNamespace)
+	}()
+
+	detached := false
+	var drainErr error
+	select {
+	case drainErr = <-done:
+	case <-ctx.Done():
+		detached = true
+		logger.Warn("Drain detached due to parent cancellation, continuing in background")
+		select {
+		case drainErr = <-done:
+		case <-time.After(detachGracePeriod):
+			drainErr = fmt.Errorf("drain abandoned after parent cancellation")
+		}
+	}
+
+	cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cleanupCancel()
+
+	if drainErr != nil {
+		timedOut := errors.Is(drainCtx.Err(), context.DeadlineExceeded) || errors.Is(drainErr, context.DeadlineExceeded)
+		status, result := StatusFailed, "error"
+		if timedOut {
+			status, result = StatusTimeout, "timeout"
+		}
+		_ = d.annotate(cleanupCtx, nodeName, map[string]string{StatusAnnotation: status})
+		recordDrainDuration(result)
+
+		if !timedOut && !detached {
+			logger.Info("Rolling back cordon due to eviction failure", zap.Error(drainErr))
+			d.rollback(nodeName, wasCordoned, logger)
+		} else {
+			logger.Info("Leaving node cordoned - eviction in progress or parent cancelled")
+		}
+
+		if detached {
+			return fmt.Errorf("drain detached (parent cancelled): %w", drainErr)
+		}
+		return drainErr
+	}
+
+	_ = d.annotate(cleanupCtx, nodeName, map[string]string{StatusAnnotation: StatusComplete})
+	recordDrainDuration("success")
+	metrics.NodeDrainPodsEvicted.WithLabelValues(nodeGroup, nodeGroupNamespace).Observe(float64(len(pods)))
+
+	logger.Info("Node drain completed successfully",
+		zap.Int("podsEvicted", len(pods)),
+		zap.Bool("detached", detached),
+	)
+	return nil
+}
+
+// evictAndWait evicts the pods, waits for them to terminate and verifies
+// nothing evictable is left on the node. System-critical pods are evicted
+// only once all other pods were evicted.
+func (d *NodeDrainer) evictAndWait(ctx context.Context, nodeName string, pods []*corev1.Pod, opts Options, start time.Time, nodeGroup, nodeGroupNamespace string) error {
+	progress := newDrainProgress(nodeName, nodeGroup, nodeGroupNamespace, len(pods), opts.Progress)
+	defer progress.finish()
+
+	regular, critical := evictionOrder(pods)
+	for _, batch := range [][]*corev1.Pod{regular, critical} {
+		if err := d.evictPods(ctx, batch, opts, start, nodeGroup, nodeGroupNamespace, progress); err != nil {
+			return err
+		}
+	}
+
+	if err := d.waitForTermination(ctx, nodeName, pods, opts, start, nodeGroup, nodeGroupNamespace); err != nil {
+		return fmt.Errorf("timeout waiting for pods to terminate: %w", err)
+	}
+
+	remaining, err := d.PodsToEvict(ctx, nodeName, opts)
+	if err != nil {
+		return fmt.Errorf("failed to verify pod migration: %w", err)
+	}
+	if len(remaining) > 0 {
+		return fmt.Errorf("drain incomplete: %d pods still running", len(remaining))
+	}
+	return nil
+}
+
+// evictPods evicts the pods in order with at most
+// opts.MaxConcurrentEvictions evictions in flight
+func (d *NodeDrainer) evictPods(ctx context.Context, pods []*corev1.Pod, opts Options, start time.Time, nodeGroup, nodeGroupNamespace string, progress *drainProgress) error {
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, opts.MaxConcurrentEvictions)
+	errs := make([]error, len(pods))
+	for i, pod := range pods {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			errs = append(errs, fmt.Errorf("%d pods not evicted: %w", len(pods)-i, ctx.Err()))
+			return errors.Join(errs...)
+		}
+
+		wg.Add(1)
+		go func(i int, pod *corev1.Pod) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			if err := d.evictPod(ctx, pod, opts, start, nodeGroup, nodeGroupNamespace); err != nil {
+				errs[i] = fmt.Errorf("failed to evict pod %s/%s: %w", pod.Namespace, pod.Name, err)
+				return
+			}
+			progress.evicted()
+		}(i, pod)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// evictPod evicts a pod, retrying while a PodDisruptionBudget blocks the
+// eviction. Once opts.ForceDeleteAfter has passed the pod is deleted instead.
+func (d *NodeDrainer) evictPod(ctx context.Context, pod *corev1.Pod, opts Options, start time.Time, nodeGroup, nodeGroupNamespace string) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+		DeleteOptions: &metav1.DeleteOptions{
+			GracePeriodSeconds: opts.GracePeriodSeconds,
+			Preconditions:      &metav1.Preconditions{UID: &pod.UID},
+		},
+	}
+
+	failures := 0
+	for {
+		if err := d.limiter.Wait(ctx); err != nil {
+			if ctx.Err() == nil {
+				// The limiter refuses up front to wait past the deadline
+				err = context.DeadlineExceeded
+			}
+			return fmt.Errorf("eviction rate limit: %w", err)
+		}
+
+		err := d.client.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+		switch {
+		case err == nil:
+			d.logger.Info("Pod evicted",
+				zap.String("pod", pod.Name),
+				zap.String("namespace", pod.Namespace),
+			)
+			return nil
+		case apierrors.IsNotFound(err), apierrors.IsConflict(err):
+			// The pod is gone, or replaced by a new pod with the same name
+			return nil
+		case apierrors.IsTooManyRequests(err):
+			d.logger.Debug("Eviction blocked by PodDisruptionBudget, retrying",
+				zap.String("pod", pod.Name),
+				zap.String("namespace", pod.Namespace),
+			)
+		default:
+			failures++
+			d.logger.Warn("Eviction attempt failed",
+				zap.String("pod", pod.Name),
+				zap.String("namespace", pod.Namespace),
+				zap.Int("attempt", failures),
+				zap.Error(err),
+			)
+			if failures >= MaxEvictionRetries {
+				return fmt.Errorf("max eviction retries exceeded: %w", err)
+			}
+		}
+
+		if opts.forceDeleteDue(start) {
+			return d.forceDelete(ctx, pod, opts.GracePeriodSeconds, nodeGroup, nodeGroupNamespace)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %v", ctx.Err(), err)
+		case <-time.After(opts.RetryInterval):
+		}
+	}
+}
+
+// waitForTermination waits until none of the evicted pods remain on the
+// node. Once opts.ForceDeleteAfter has passed, pods still terminating are
+// deleted without a grace period.
+func (d *NodeDrainer) waitForTermination(ctx context.Context, nodeName string, evicted []*corev1.Pod, opts Options, start time.Time, nodeGroup, nodeGroupNamespace string) error {
+	evictedUIDs := make(map[types.UID]bool, len(evicted))
+	for _, pod := range evicted {
+		evictedUIDs[pod.UID] = true
+	}
+	forced := make(map[types.UID]bool)
+	noGracePeriod := int64(0)
+
+	return wait.PollUntilContextCancel(ctx, opts.RetryInterval, true, func(ctx context.Context) (bool, error) {
+		pods, err := d.nodePods(ctx, nodeName)
+		if err != nil {
+			return false, err
+		}
+
+		remaining := 0
+		for i := range pods {
+			pod := &pods[i]
+			if !evictedUIDs[pod.UID] {
+				continue
+			}
+			remaining++
+			if opts.forceDeleteDue(start) && !forced[pod.UID] {
+				forced[pod.UID] = true
+				if err := d.forceDelete(ctx, pod, &noGracePeriod, nodeGroup, nodeGroupNamespace); err != nil {
+					d.logger.Warn("Failed to force-delete terminating pod",
+						zap.String("pod", pod.Name),
+						zap.String("namespace", pod.Namespace),
+						zap.Error(err),
+					)
+				}
+			}
+		}
+
+		if remaining > 0 {
+			d.logger.Debug("Waiting for pods to terminate",
+				zap.String("node", nodeName),
+				zap.Int("remaining", remaining),
+			)
+			return false, nil
+		}
+		return true, nil
+	})
+}
+
+// forceDelete deletes a pod directly, bypassing PodDisruptionBudgets
+func (d *NodeDrainer) forceDelete(ctx context.Context, pod *corev1.Pod, gracePeriodSeconds *int64, nodeGroup, nodeGroupNamespace string) error {
+	d.logger.Warn("Force-deleting pod after drain timeout",
+		zap.String("pod", pod.Name),
+		zap.String("namespace", pod.Namespace),
+	)
+
+	err := d.client.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{
+		GracePeriodSeconds: gracePeriodSeconds,
+		Preconditions:      &metav1.Preconditions{UID: &pod.UID},
+	})
+	if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+		return fmt.Errorf("failed to force-delete pod: %w", err)
+	}
+
+	metrics.NodeDrainPodsForceDeletedTotal.WithLabelValues(nodeGroup, nodeGroupNamespace).Inc()
+	return nil
+}
+
+// nodePods lists the pods bound to the node
+func (d *NodeDrainer) nodePods(ctx context.Context, nodeName string) ([]corev1.Pod, error) {
+	podList, err := d.client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("spec.nodeName=%s", nodeName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pods := podList.Items[:0]
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName == nodeName {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+// setUnschedulable cordons or uncordons the node
+func (d *NodeDrainer) setUnschedulable(ctx context.Context, nodeName string, unschedulable bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := d.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if node.Spec.Unschedulable == unschedulable {
+			return nil
+		}
+
+		node.Spec.Unschedulable = unschedulable
+		if _, err := d.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+
+		d.logger.Info("Node schedulability changed",
+			zap.String("node", nodeName),
+			zap.Bool("unschedulable", unschedulable),
+		)
+		return nil
+	})
+}
+
+// annotate sets annotations on the node
+func (d *NodeDrainer) annotate(ctx context.Context, nodeName string, annotations map[string]string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := d.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if node.Annotations == nil {
+			node.Annotations = make(map[string]string, len(annotations))
+		}
+		for key, value := range annotations {
+			node.Annotations[key] = value
+		}
+		_, err = d.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// rollback uncordons the node after a failed drain unless it was cordoned before
+func (d *NodeDrainer) rollback(nodeName string, wasCordoned bool, logger *zap.Logger) {
+	if wasCordoned {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	if err := d.Uncordon(ctx, nodeName); err != nil {
+		logger.Warn("Failed to uncordon node after drain failure", zap.Error(err))
+	}
+}
+
+// metricLabels returns the sanitized NodeGroup labels for the drain metrics
+func metricLabels(node *corev1.Node, opts Options) (string, string) {
+	nodeGroup := opts.NodeGroup
+	if nodeGroup == "" {
+		nodeGroup = node.Labels[v1alpha1.NodeGroupLabelKey]
+	}
+	namespace := opts.NodeGroupNamespace
+	if namespace == "" {
+		namespace = node.Labels[nodeGroupNamespaceLabel]
+	}
+	if nodeGroup == "" {
+		nodeGroup = "unknown"
+	}
+	if namespace == "" {
+		namespace = "unknown"
+	}
+
+	nodeGroup, _ = metrics.SanitizeLabel(nodeGroup)
+	namespace, _ = metrics.SanitizeLabel(namespace)
+	return nodeGroup, namespace
+}
+
+// IsDraining reports whether a drain of the node is in progress
+func IsDraining(node *corev1.Node) bool {
+	return node.Annotations[StatusAnnotation] == StatusDraining
+}
+
+// Duration returns how long ago the node's last drain started
+func Duration(node *corev1.Node) time.Duration {
+	startTime, err := time.Parse(time.RFC3339, node.Annotations[StartTimeAnnotation])
+	if err != nil {
+		return 0
+	}
+	return time.Since(startTime)
+}
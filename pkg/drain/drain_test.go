@@ -0,0 +1,494 @@
+package drain
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
+)
+
+// newEvictingClientset returns a fake clientset whose evictions delete the
+// pod, or are rejected by a PodDisruptionBudget for the pods named in blocked
+func newEvictingClientset(blocked map[string]bool, objects ...runtime.Object) *fake.Clientset {
+	client := fake.NewSimpleClientset(objects...)
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		if blocked[eviction.Name] {
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		return true, nil, client.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+	})
+	return client
+}
+
+func newNode(name string) *corev1.Node {
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   name,
+		Labels: map[string]string{v1alpha1.NodeGroupLabelKey: "test-group"},
+	}}
+}
+
+func newPod(name, namespace, nodeName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, UID: types.UID(namespace + "/" + name)},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func fastOptions() Options {
+	opts := DefaultOptions()
+	opts.Timeout = 2 * time.Second
+	opts.RetryInterval = 5 * time.Millisecond
+	return opts
+}
+
+func podNames(pods []*corev1.Pod) []string {
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	return names
+}
+
+func TestIsDaemonSetPod(t *testing.T) {
+	pod := newPod("fluentd", "kube-system", "node-1")
+	assert.False(t, IsDaemonSetPod(pod))
+
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "fluentd"}}
+	assert.True(t, IsDaemonSetPod(pod))
+}
+
+func TestIsStaticPod(t *testing.T) {
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		expected bool
+	}{
+		{
+			name:     "regular pod",
+			pod:      newPod("web", "default", "node-1"),
+			expected: false,
+		},
+		{
+			name:     "bare pod in kube-system",
+			pod:      newPod("debug", "kube-system", "node-1"),
+			expected: false,
+		},
+		{
+			name: "owned by node",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Node", Name: "node-1"}},
+			}},
+			expected: true,
+		},
+		{
+			name: "mirror pod",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{corev1.MirrorPodAnnotationKey: "hash"},
+			}},
+			expected: true,
+		},
+		{
+			name: "config source annotation",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{"kubernetes.io/config.source": "file"},
+			}},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsStaticPod(tt.pod))
+		})
+	}
+}
+
+func TestIsWorkloadPod(t *testing.T) {
+	assert.True(t, IsWorkloadPod(&corev1.Pod{}))
+	assert.False(t, IsWorkloadPod(&corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodSucceeded}}))
+	assert.False(t, IsWorkloadPod(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: "kube-proxy"}},
+	}}))
+	assert.False(t, IsWorkloadPod(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{corev1.MirrorPodAnnotationKey: "hash"},
+	}}))
+}
+
+func TestFilterPods(t *testing.T) {
+	completed := newPod("completed", "default", "node-1")
+	completed.Status.Phase = corev1.PodSucceeded
+	terminating := newPod("terminating", "default", "node-1")
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	daemon := newPod("daemon", "default", "node-1")
+	daemon.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "ds"}}
+	mirror := newPod("mirror", "kube-system", "node-1")
+	mirror.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "hash"}
+
+	pods := []corev1.Pod{
+		*newPod("web", "default", "node-1"),
+		*newPod("prometheus", "monitoring", "node-1"),
+		*completed,
+		*terminating,
+		*daemon,
+		*mirror,
+	}
+
+	assert.Equal(t, []string{"web", "prometheus"}, podNames(filterPods(pods, DefaultOptions())))
+
+	opts := DefaultOptions()
+	opts.SkipNamespaces = []string{"monitoring"}
+	assert.Equal(t, []string{"web"}, podNames(filterPods(pods, opts)))
+}
+
+func TestCordonUncordon(t *testing.T) {
+	client := fake.NewSimpleClientset(newNode("node-1"))
+	drainer := NewNodeDrainer(client, zap.NewNop())
+	ctx := context.Background()
+
+	require.NoError(t, drainer.Cordon(ctx, "node-1"))
+	node, err := client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, node.Spec.Unschedulable)
+
+	// Cordoning twice is a no-op
+	require.NoError(t, drainer.Cordon(ctx, "node-1"))
+
+	require.NoError(t, drainer.Uncordon(ctx, "node-1"))
+	node, err = client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.False(t, node.Spec.Unschedulable)
+
+	assert.Error(t, drainer.Cordon(ctx, "missing-node"))
+}
+
+func TestDrain(t *testing.T) {
+	daemon := newPod("daemon", "default", "node-1")
+	daemon.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "ds"}}
+	client := newEvictingClientset(nil,
+		newNode("node-1"),
+		newPod("web-1", "default", "node-1"),
+		newPod("web-2", "default", "node-1"),
+		newPod("other", "default", "node-2"),
+		daemon,
+	)
+	drainer := NewNodeDrainer(client, zap.NewNop())
+	ctx := context.Background()
+
+	require.NoError(t, drainer.Drain(ctx, "node-1", fastOptions()))
+
+	node, err := client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, node.Spec.Unschedulable)
+	assert.Equal(t, StatusComplete, node.Annotations[StatusAnnotation])
+	assert.NotEmpty(t, node.Annotations[StartTimeAnnotation])
+
+	pods, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	var remaining []string
+	for _, pod := range pods.Items {
+		remaining = append(remaining, pod.Name)
+	}
+	assert.ElementsMatch(t, []string{"other", "daemon"}, remaining)
+}
+
+func TestDrain_EmptyDirData(t *testing.T) {
+	cache := newPod("cache", "default", "node-1")
+	cache.Spec.Volumes = []corev1.Volume{{
+		Name:         "scratch",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}}
+	client := newEvictingClientset(nil, newNode("node-1"), cache)
+	drainer := NewNodeDrainer(client, zap.NewNop())
+	ctx := context.Background()
+
+	opts := fastOptions()
+	opts.DeleteEmptyDirData = false
+	err := drainer.Drain(ctx, "node-1", opts)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "default/cache")
+
+	// The cordon is rolled back and the pod is left alone
+	node, err := client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.False(t, node.Spec.Unschedulable)
+	_, err = client.CoreV1().Pods("default").Get(ctx, "cache", metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	opts.DeleteEmptyDirData = true
+	require.NoError(t, drainer.Drain(ctx, "node-1", opts))
+
+	// Volumes the pod allows losing don't block the drain
+	cache = newPod("cache", "default", "node-2")
+	cache.Annotations = map[string]string{SafeToEvictLocalVolumesAnnotation: "scratch"}
+	cache.Spec.Volumes = []corev1.Volume{{
+		Name:         "scratch",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}}
+	client = newEvictingClientset(nil, newNode("node-2"), cache)
+	opts.DeleteEmptyDirData = false
+	require.NoError(t, NewNodeDrainer(client, zap.NewNop()).Drain(ctx, "node-2", opts))
+}
+
+func TestDrain_SafeToEvictAnnotations(t *testing.T) {
+	db := newPod("db", "default", "node-1")
+	db.Annotations = map[string]string{ClusterAutoscalerSafeToEvictAnnotation: "false"}
+	client := newEvictingClientset(nil, newNode("node-1"), db, newPod("web", "default", "node-1"))
+	drainer := NewNodeDrainer(client, zap.NewNop())
+	ctx := context.Background()
+
+	err := drainer.Drain(ctx, "node-1", fastOptions())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "default/db is annotated cluster-autoscaler.kubernetes.io/safe-to-evict=false")
+
+	// Nothing is evicted and the cordon is rolled back
+	node, err := client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.False(t, node.Spec.Unschedulable)
+	_, err = client.CoreV1().Pods("default").Get(ctx, "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+}
+
+func TestSafeToEvictAnnotations(t *testing.T) {
+	pod := newPod("cache", "default", "node-1")
+	assert.False(t, IsSafeToEvict(pod))
+	assert.Empty(t, BlockingReason(pod))
+	assert.False(t, IsLocalVolumeSafeToEvict(pod, "scratch"))
+
+	pod.Annotations = map[string]string{SafeToEvictLocalVolumesAnnotation: "logs, scratch"}
+	assert.True(t, IsLocalVolumeSafeToEvict(pod, "scratch"))
+	assert.False(t, IsLocalVolumeSafeToEvict(pod, "data"))
+
+	pod.Annotations = map[string]string{ClusterAutoscalerSafeToEvictAnnotation: "true"}
+	assert.True(t, IsSafeToEvict(pod))
+	assert.True(t, IsLocalVolumeSafeToEvict(pod, "data"))
+
+	// Our own annotation takes precedence over the cluster-autoscaler alias
+	pod.Annotations[SafeToEvictAnnotation] = "false"
+	assert.False(t, IsSafeToEvict(pod))
+	assert.Equal(t, "pod default/cache is annotated autoscaler.vpsie.com/safe-to-evict=false", BlockingReason(pod))
+}
+
+func TestDrain_PodDisruptionBudget(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("blocked eviction times out and leaves the node cordoned", func(t *testing.T) {
+		client := newEvictingClientset(map[string]bool{"db": true}, newNode("node-1"), newPod("db", "default", "node-1"))
+		drainer := NewNodeDrainer(client, zap.NewNop())
+
+		opts := fastOptions()
+		opts.Timeout = 50 * time.Millisecond
+		err := drainer.Drain(ctx, "node-1", opts)
+		require.Error(t, err)
+
+		node, err := client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.True(t, node.Spec.Unschedulable)
+		assert.Equal(t, StatusTimeout, node.Annotations[StatusAnnotation])
+	})
+
+	t.Run("blocked pod is force-deleted after the timeout", func(t *testing.T) {
+		client := newEvictingClientset(map[string]bool{"db": true}, newNode("node-1"), newPod("db", "default", "node-1"))
+		drainer := NewNodeDrainer(client, zap.NewNop())
+
+		opts := fastOptions()
+		opts.ForceDeleteAfter = 20 * time.Millisecond
+		require.NoError(t, drainer.Drain(ctx, "node-1", opts))
+
+		_, err := client.CoreV1().Pods("default").Get(ctx, "db", metav1.GetOptions{})
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+}
+
+func TestOptionsWithNodeGroup(t *testing.T) {
+	base := DefaultOptions()
+	assert.Equal(t, base, base.WithNodeGroup(nil))
+
+	ng := &v1alpha1.NodeGroup{ObjectMeta: metav1.ObjectMeta{Name: "workers", Namespace: "kube-system"}}
+	opts := base.WithNodeGroup(ng)
+	assert.Equal(t, "workers", opts.NodeGroup)
+	assert.Equal(t, "kube-system", opts.NodeGroupNamespace)
+	assert.True(t, opts.DeleteEmptyDirData)
+
+	deleteEmptyDirData := false
+	ng.Spec.Drain = &v1alpha1.DrainConfig{
+		SkipNamespaces:     []string{"monitoring"},
+		DeleteEmptyDirData: &deleteEmptyDirData,
+		ForceDeleteAfter:   &metav1.Duration{Duration: 10 * time.Minute},
+	}
+	opts = base.WithNodeGroup(ng)
+	assert.Equal(t, []string{"monitoring"}, opts.SkipNamespaces)
+	assert.False(t, opts.DeleteEmptyDirData)
+	assert.Equal(t, 10*time.Minute, opts.ForceDeleteAfter)
+	assert.Equal(t, base.Timeout, opts.Timeout)
+}
+
+func TestDrainAnnotations(t *testing.T) {
+	node := newNode("node-1")
+	assert.False(t, IsDraining(node))
+	assert.Zero(t, Duration(node))
+
+	node.Annotations = map[string]string{
+		StatusAnnotation:    StatusDraining,
+		StartTimeAnnotation: time.Now().Add(-5 * time.Minute).Format(time.RFC3339),
+	}
+	assert.True(t, IsDraining(node))
+	assert.InDelta(t, (5 * time.Minute).Seconds(), Duration(node).Seconds(), 2)
+
+	node.Annotations[StatusAnnotation] = StatusComplete
+	assert.False(t, IsDraining(node))
+}
+
+func TestDrain_EvictionOrderAndConcurrency(t *testing.T) {
+	priority := func(pod *corev1.Pod, value int32) *corev1.Pod {
+		pod.Spec.Priority = &value
+		return pod
+	}
+	dns := newPod("coredns", "kube-system", "node-1")
+	dns.Spec.PriorityClassName = "system-cluster-critical"
+
+	client := newEvictingClientset(nil,
+		newNode("node-1"),
+		priority(newPod("api", "default", "node-1"), 1000),
+		dns,
+		priority(newPod("batch", "default", "node-1"), -10),
+		newPod("web", "default", "node-1"),
+		priority(newPod("cache", "default", "node-1"), 500),
+	)
+
+	// Evictions are blocked by a PodDisruptionBudget at first, so each pod
+	// started holds its slot until they are unblocked
+	var mu sync.Mutex
+	var order []string
+	attempted := make(map[string]bool)
+	blocked := true
+	time.AfterFunc(50*time.Millisecond, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		blocked = false
+	})
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		name := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction).Name
+		mu.Lock()
+		defer mu.Unlock()
+		if !attempted[name] {
+			attempted[name] = true
+			order = append(order, name)
+		}
+		if blocked {
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		return false, nil, nil
+	})
+
+	drainer := NewNodeDrainer(client, zap.NewNop())
+	drainer.limiter = rate.NewLimiter(rate.Inf, 0)
+
+	var reports []int
+	var reportsMu sync.Mutex
+	opts := fastOptions()
+	opts.MaxConcurrentEvictions = 2
+	opts.Progress = func(evicted, total int) {
+		assert.Equal(t, 5, total)
+		reportsMu.Lock()
+		defer reportsMu.Unlock()
+		reports = append(reports, evicted)
+	}
+	require.NoError(t, drainer.Drain(context.Background(), "node-1", opts))
+
+	// Pods are started lowest priority first, two at a time, and the
+	// critical pod only after all others were evicted
+	require.Len(t, order, 5)
+	assert.ElementsMatch(t, []string{"batch", "web"}, order[:2])
+	assert.ElementsMatch(t, []string{"cache", "api"}, order[2:4])
+	assert.Equal(t, "coredns", order[4])
+	// Progress is reported concurrently, so reports may arrive out of order
+	assert.ElementsMatch(t, []int{1, 2, 3, 4, 5}, reports)
+
+	// The progress metric is removed once the drain ended
+	assert.Zero(t, testutil.CollectAndCount(metrics.NodeDrainProgress))
+}
+
+func TestDrainProgress_ReportsOutsideLock(t *testing.T) {
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+	progress := newDrainProgress("node-1", "ng", "default", 2, func(evicted, total int) {
+		if evicted == 1 {
+			close(blocked)
+			<-release
+		}
+	})
+	defer progress.finish()
+
+	go progress.evicted()
+	<-blocked
+
+	// A slow report does not hold up the next eviction
+	done := make(chan struct{})
+	go func() {
+		progress.evicted()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("eviction blocked by a slow progress report")
+	}
+	close(release)
+}
+
+func TestDrain_EvictionRateLimit(t *testing.T) {
+	client := newEvictingClientset(nil,
+		newNode("node-1"),
+		newPod("web-1", "default", "node-1"),
+		newPod("web-2", "default", "node-1"),
+		newPod("web-3", "default", "node-1"),
+	)
+	drainer := NewNodeDrainer(client, zap.NewNop())
+	drainer.limiter = rate.NewLimiter(rate.Every(50*time.Millisecond), 1)
+
+	start := time.Now()
+	require.NoError(t, drainer.Drain(context.Background(), "node-1", fastOptions()))
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestSetEvictionRateLimit(t *testing.T) {
+	defer SetEvictionRateLimit(DefaultEvictionQPS, DefaultEvictionBurst)
+
+	SetEvictionRateLimit(5, 2)
+	assert.Equal(t, rate.Limit(5), evictionLimiter.Limit())
+	assert.Equal(t, 2, evictionLimiter.Burst())
+
+	SetEvictionRateLimit(0, 0)
+	assert.Equal(t, rate.Inf, evictionLimiter.Limit())
+}
+
+func TestPercent(t *testing.T) {
+	assert.Equal(t, float64(100), Percent(0, 0))
+	assert.Equal(t, float64(25), Percent(1, 4))
+	assert.Equal(t, float64(100), Percent(4, 4))
+}
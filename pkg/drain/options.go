@@ -0,0 +1,131 @@
+package drain
+
+import (
+	"time"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+)
+
+const (
+	// DefaultTimeout is the default time allowed for evicting a node's pods
+	// and waiting for them to terminate
+	DefaultTimeout = 5 * time.Minute
+
+	// DefaultRetryInterval is the default interval between eviction attempts
+	// and between checks for evicted pods to terminate
+	DefaultRetryInterval = 5 * time.Second
+
+	// DefaultMaxConcurrentEvictions is the default number of evictions in
+	// flight per drained node
+	DefaultMaxConcurrentEvictions = 10
+)
+
+// Options configures a single node drain
+type Options struct {
+	// Timeout bounds evicting the pods and waiting for them to terminate
+	Timeout time.Duration
+
+	// RetryInterval is the interval between eviction attempts of a pod and
+	// between checks for evicted pods to terminate
+	RetryInterval time.Duration
+
+	// GracePeriodSeconds overrides the termination grace period of evicted
+	// pods. Nil uses each pod's own grace period.
+	GracePeriodSeconds *int64
+
+	// SkipNamespaces lists namespaces whose pods are not evicted. They are
+	// removed together with the node.
+	SkipNamespaces []string
+
+	// DeleteEmptyDirData allows evicting pods with emptyDir volumes, whose
+	// data is lost. Otherwise the drain fails while such pods run on the node.
+	DeleteEmptyDirData bool
+
+	// ForceDeleteAfter is how long after the drain started pods that still
+	// cannot be evicted, or have not terminated, are deleted directly,
+	// bypassing PodDisruptionBudgets. Zero never force-deletes.
+	ForceDeleteAfter time.Duration
+
+	// MaxConcurrentEvictions bounds the evictions in flight for the node.
+	// Evictions of all drains are further limited by SetEvictionRateLimit.
+	MaxConcurrentEvictions int
+
+	// Progress, if set, is called each time a pod was evicted with the
+	// number of pods evicted so far and the number of pods to evict. A pod
+	// counts once its eviction was accepted, before it terminated. Calls may
+	// be concurrent when several evictions are in flight.
+	Progress func(evicted, total int)
+
+	// NodeGroup and NodeGroupNamespace label the drain metrics. When empty
+	// they are taken from the node's labels.
+	NodeGroup          string
+	NodeGroupNamespace string
+}
+
+// DefaultOptions returns the options used when a NodeGroup configures nothing
+func DefaultOptions() Options {
+	return Options{
+		Timeout:                DefaultTimeout,
+		RetryInterval:          DefaultRetryInterval,
+		DeleteEmptyDirData:     true,
+		MaxConcurrentEvictions: DefaultMaxConcurrentEvictions,
+	}
+}
+
+// WithNodeGroup returns the options with the NodeGroup's drain configuration applied
+func (o Options) WithNodeGroup(ng *v1alpha1.NodeGroup) Options {
+	if ng == nil {
+		return o
+	}
+
+	o.NodeGroup = ng.Name
+	o.NodeGroupNamespace = ng.Namespace
+
+	cfg := ng.Spec.Drain
+	if cfg == nil {
+		return o
+	}
+	if len(cfg.SkipNamespaces) > 0 {
+		o.SkipNamespaces = append([]string(nil), cfg.SkipNamespaces...)
+	}
+	if cfg.DeleteEmptyDirData != nil {
+		o.DeleteEmptyDirData = *cfg.DeleteEmptyDirData
+	}
+	if cfg.ForceDeleteAfter != nil {
+		o.ForceDeleteAfter = cfg.ForceDeleteAfter.Duration
+	}
+	if cfg.MaxConcurrentEvictions != nil {
+		o.MaxConcurrentEvictions = int(*cfg.MaxConcurrentEvictions)
+	}
+	return o
+}
+
+// withDefaults fills in unset timings
+func (o Options) withDefaults() Options {
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.RetryInterval <= 0 {
+		o.RetryInterval = DefaultRetryInterval
+	}
+	if o.MaxConcurrentEvictions <= 0 {
+		o.MaxConcurrentEvictions = DefaultMaxConcurrentEvictions
+	}
+	return o
+}
+
+// skipsNamespace reports whether pods in the namespace are left on the node
+func (o Options) skipsNamespace(namespace string) bool {
+	for _, ns := range o.SkipNamespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// forceDeleteDue reports whether the force-delete timeout has passed for a
+// drain that started at start
+func (o Options) forceDeleteDue(start time.Time) bool {
+	return o.ForceDeleteAfter > 0 && time.Since(start) >= o.ForceDeleteAfter
+}
@@ -0,0 +1,127 @@
+package drain
+
+import (
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// configSourceAnnotation is set by the kubelet on pods it reads from a
+// static manifest
+const configSourceAnnotation = "kubernetes.io/config.source"
+
+// IsDaemonSetPod reports whether the pod is owned by a DaemonSet. Such pods
+// would be recreated on the node immediately and are never evicted.
+func IsDaemonSetPod(pod *corev1.Pod) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStaticPod reports whether the pod is a static pod or its mirror. The API
+// server cannot evict these; they go away with the node.
+func IsStaticPod(pod *corev1.Pod) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "Node" {
+			return true
+		}
+	}
+	if _, exists := pod.Annotations[corev1.MirrorPodAnnotationKey]; exists {
+		return true
+	}
+	_, exists := pod.Annotations[configSourceAnnotation]
+	return exists
+}
+
+// IsWorkloadPod reports whether the pod keeps its node in use: it has not
+// finished and is not a DaemonSet or static pod, which run on every node
+func IsWorkloadPod(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	return !IsDaemonSetPod(pod) && !IsStaticPod(pod)
+}
+
+// hasEmptyDir reports whether the pod stores data in an emptyDir volume
+// its annotations don't allow losing
+func hasEmptyDir(pod *corev1.Pod) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir != nil && !IsLocalVolumeSafeToEvict(pod, volume.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterPods returns the pods a drain evicts: running pods that are not
+// already terminating, not DaemonSet or static pods, and not in a skipped namespace
+func filterPods(pods []corev1.Pod, opts Options) []*corev1.Pod {
+	var filtered []*corev1.Pod
+	for i := range pods {
+		pod := &pods[i]
+		if !IsWorkloadPod(pod) || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if opts.skipsNamespace(pod.Namespace) {
+			continue
+		}
+		filtered = append(filtered, pod)
+	}
+	return filtered
+}
+
+// emptyDirPods returns the namespaced names of the pods with emptyDir volumes
+func emptyDirPods(pods []*corev1.Pod) string {
+	var names []string
+	for _, pod := range pods {
+		if hasEmptyDir(pod) {
+			names = append(names, pod.Namespace+"/"+pod.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// systemCriticalPriority is the lowest priority of the system-cluster-critical
+// and system-node-critical priority classes
+const systemCriticalPriority = 2000000000
+
+// podPriority returns the scheduling priority of the pod
+func podPriority(pod *corev1.Pod) int32 {
+	if pod.Spec.Priority != nil {
+		return *pod.Spec.Priority
+	}
+	return 0
+}
+
+// isCriticalPod reports whether the pod runs with a system-critical priority
+func isCriticalPod(pod *corev1.Pod) bool {
+	if pod.Spec.PriorityClassName == "system-cluster-critical" ||
+		pod.Spec.PriorityClassName == "system-node-critical" {
+		return true
+	}
+	return podPriority(pod) >= systemCriticalPriority
+}
+
+// evictionOrder splits the pods into the regular pods, lowest priority
+// first, and the system-critical pods, which are evicted last
+func evictionOrder(pods []*corev1.Pod) (regular, critical []*corev1.Pod) {
+	for _, pod := range pods {
+		if isCriticalPod(pod) {
+			critical = append(critical, pod)
+		} else {
+			regular = append(regular, pod)
+		}
+	}
+	byPriority := func(pods []*corev1.Pod) {
+		sort.SliceStable(pods, func(i, j int) bool {
+			return podPriority(pods[i]) < podPriority(pods[j])
+		})
+	}
+	byPriority(regular)
+	byPriority(critical)
+	return regular, critical
+}
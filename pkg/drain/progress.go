@@ -0,0 +1,59 @@
+package drain
+
+import (
+	"sync"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
+)
+
+// drainProgress tracks the share of a node's pods evicted by a drain in the
+// drain progress metric and reports it to Options.Progress. A pod counts once
+// its eviction was accepted, so 100% is reached while the last pods may
+// still be terminating.
+type drainProgress struct {
+	mu     sync.Mutex
+	count  int
+	total  int
+	labels []string
+	report func(evicted, total int)
+}
+
+// newDrainProgress starts tracking the eviction of total pods of the node
+func newDrainProgress(nodeName, nodeGroup, nodeGroupNamespace string, total int, report func(evicted, total int)) *drainProgress {
+	node, _ := metrics.SanitizeLabel(nodeName)
+	p := &drainProgress{
+		total:  total,
+		labels: []string{node, nodeGroup, nodeGroupNamespace},
+		report: report,
+	}
+	metrics.NodeDrainProgress.WithLabelValues(p.labels...).Set(0)
+	return p
+}
+
+// evicted records the eviction of a pod. The progress is reported after the
+// lock is released, since reporting may be slow (e.g. an API call) and would
+// otherwise hold up the other evictions.
+func (p *drainProgress) evicted() {
+	p.mu.Lock()
+	p.count++
+	count, total := p.count, p.total
+	metrics.NodeDrainProgress.WithLabelValues(p.labels...).Set(Percent(count, total))
+	p.mu.Unlock()
+
+	if p.report != nil {
+		p.report(count, total)
+	}
+}
+
+// finish removes the node from the drain progress metric once the drain ended
+func (p *drainProgress) finish() {
+	metrics.NodeDrainProgress.DeleteLabelValues(p.labels...)
+}
+
+// Percent returns the percentage of pods evicted, 100 if there are none
+func Percent(evicted, total int) float64 {
+	if total <= 0 {
+		return 100
+	}
+	return float64(evicted) * 100 / float64(total)
+}
@@ -0,0 +1,34 @@
+package drain
+
+import (
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultEvictionQPS is the default rate of eviction requests across
+	// all drains
+	DefaultEvictionQPS = 10
+
+	// DefaultEvictionBurst is the default number of eviction requests
+	// allowed at once across all drains
+	DefaultEvictionBurst = 20
+)
+
+// evictionLimiter is shared by all NodeDrainers, so concurrent drains by the
+// scale-down manager, the VPSieNode terminator and the rebalancer together
+// stay within the eviction rate
+var evictionLimiter = rate.NewLimiter(DefaultEvictionQPS, DefaultEvictionBurst)
+
+// SetEvictionRateLimit sets the rate of eviction requests across all drains
+// in the process. A qps of zero or less removes the limit.
+func SetEvictionRateLimit(qps float64, burst int) {
+	if qps <= 0 {
+		evictionLimiter.SetLimit(rate.Inf)
+		return
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	evictionLimiter.SetBurst(burst)
+	evictionLimiter.SetLimit(rate.Limit(qps))
+}
@@ -14,16 +14,39 @@ import (
 
 // ResourceDeficit represents the total resource deficit from pending pods
 type ResourceDeficit struct {
-	CPU    resource.Quantity
-	Memory resource.Quantity
-	Pods   int
+	CPU              resource.Quantity
+	Memory           resource.Quantity
+	EphemeralStorage resource.Quantity
+	Pods             int
+
+	// Extended holds the hugepages and extended resource requests
+	Extended corev1.ResourceList
+}
+
+// add adds a pod's resource requests to the deficit, without counting the pod
+func (d *ResourceDeficit) add(podRes PodResourceRequest) {
+	d.CPU.Add(podRes.CPU)
+	d.Memory.Add(podRes.Memory)
+	d.EphemeralStorage.Add(podRes.EphemeralStorage)
+	for name, quantity := range podRes.Extended {
+		if d.Extended == nil {
+			d.Extended = make(corev1.ResourceList)
+		}
+		total := d.Extended[name]
+		total.Add(quantity)
+		d.Extended[name] = total
+	}
 }
 
 // PodResourceRequest represents the resources requested by a pod
 type PodResourceRequest struct {
-	Pod    *corev1.Pod
-	CPU    resource.Quantity
-	Memory resource.Quantity
+	Pod              *corev1.Pod
+	CPU              resource.Quantity
+	Memory           resource.Quantity
+	EphemeralStorage resource.Quantity
+
+	// Extended holds the hugepages and extended resource requests
+	Extended corev1.ResourceList
 }
 
 // NodeGroupMatch represents a NodeGroup that can satisfy pending pods
@@ -38,6 +61,9 @@ type NodeGroupMatch struct {
 type ResourceAnalyzer struct {
 	logger     *zap.Logger
 	calculator *cost.Calculator
+
+	// objective weighs emissions against price when selecting instance types
+	objective cost.CarbonObjective
 }
 
 // NewResourceAnalyzer creates a new resource analyzer
@@ -49,6 +75,13 @@ func NewResourceAnalyzer(logger *zap.Logger, calculator *cost.Calculator) *Resou
 	}
 }
 
+// SetCarbonObjective sets how instance type selection weighs the emissions of
+// an offering's datacenter against its price. It has no effect unless the
+// calculator has a carbon intensity source.
+func (a *ResourceAnalyzer) SetCarbonObjective(objective cost.CarbonObjective) {
+	a.objective = objective
+}
+
 // CalculateDeficit calculates the total resource deficit from scheduling events
 func (a *ResourceAnalyzer) CalculateDeficit(events []SchedulingEvent) ResourceDeficit {
 	deficit := ResourceDeficit{
@@ -71,14 +104,15 @@ func (a *ResourceAnalyzer) CalculateDeficit(events []SchedulingEvent) ResourceDe
 		podResources := a.CalculatePodResources(event.Pod)
 
 		// Add to deficit
-		deficit.CPU.Add(podResources.CPU)
-		deficit.Memory.Add(podResources.Memory)
+		deficit.add(podResources)
 		deficit.Pods++
 	}
 
 	a.logger.Debug("Calculated resource deficit",
 		zap.String("cpu", deficit.CPU.String()),
 		zap.String("memory", deficit.Memory.String()),
+		zap.String("ephemeralStorage", deficit.EphemeralStorage.String()),
+		zap.Int("extendedResources", len(deficit.Extended)),
 		zap.Int("pods", deficit.Pods),
 	)
 
@@ -87,44 +121,36 @@ func (a *ResourceAnalyzer) CalculateDeficit(events []SchedulingEvent) ResourceDe
 
 // CalculatePodResources calculates the total resource requests for a pod
 func (a *ResourceAnalyzer) CalculatePodResources(pod *corev1.Pod) PodResourceRequest {
-	totalCPU := resource.Quantity{}
-	totalMemory := resource.Quantity{}
-
-	for _, container := range pod.Spec.Containers {
-		if cpu, ok := container.Resources.Requests[corev1.ResourceCPU]; ok {
-			totalCPU.Add(cpu)
-		}
-		if memory, ok := container.Resources.Requests[corev1.ResourceMemory]; ok {
-			totalMemory.Add(memory)
-		}
-	}
+	requests := podRequests(pod)
 
-	// Include init containers (they run sequentially, so use max, not sum)
-	for _, container := range pod.Spec.InitContainers {
-		if cpu, ok := container.Resources.Requests[corev1.ResourceCPU]; ok {
-			if cpu.Cmp(totalCPU) > 0 {
-				totalCPU = cpu
-			}
+	var extended corev1.ResourceList
+	for name, quantity := range requests {
+		if !isExtendedResource(name) {
+			continue
 		}
-		if memory, ok := container.Resources.Requests[corev1.ResourceMemory]; ok {
-			if memory.Cmp(totalMemory) > 0 {
-				totalMemory = memory
-			}
+		if extended == nil {
+			extended = make(corev1.ResourceList)
 		}
+		extended[name] = quantity
 	}
 
 	return PodResourceRequest{
-		Pod:    pod,
-		CPU:    totalCPU,
-		Memory: totalMemory,
+		Pod:              pod,
+		CPU:              requests[corev1.ResourceCPU],
+		Memory:           requests[corev1.ResourceMemory],
+		EphemeralStorage: requests[corev1.ResourceEphemeralStorage],
+		Extended:         extended,
 	}
 }
 
 // FindMatchingNodeGroups finds NodeGroups that can satisfy the pending pods.
 // Only managed NodeGroups (with autoscaler.vpsie.com/managed=true label) are considered.
+// volumes restricts pods with persistent volumes to NodeGroups in a compatible
+// topology; nil skips the check.
 func (a *ResourceAnalyzer) FindMatchingNodeGroups(
 	pendingPods []corev1.Pod,
 	nodeGroups []v1alpha1.NodeGroup,
+	volumes *VolumeTopology,
 ) []NodeGroupMatch {
 	matches := make([]NodeGroupMatch, 0)
 
@@ -137,7 +163,7 @@ func (a *ResourceAnalyzer) FindMatchingNodeGroups(
 			continue
 		}
 
-		match := a.matchNodeGroup(&ng, pendingPods)
+		match := a.matchNodeGroup(&ng, pendingPods, volumes)
 		if match != nil && len(match.MatchingPods) > 0 {
 			matches = append(matches, *match)
 		}
@@ -157,12 +183,13 @@ func (a *ResourceAnalyzer) FindMatchingNodeGroups(
 func (a *ResourceAnalyzer) matchNodeGroup(
 	ng *v1alpha1.NodeGroup,
 	pendingPods []corev1.Pod,
+	volumes *VolumeTopology,
 ) *NodeGroupMatch {
 	matchingPods := make([]*corev1.Pod, 0)
 
 	for i := range pendingPods {
 		pod := &pendingPods[i]
-		if a.podMatchesNodeGroup(pod, ng) {
+		if a.podMatchesNodeGroup(pod, ng, volumes) {
 			matchingPods = append(matchingPods, pod)
 		}
 	}
@@ -172,17 +199,7 @@ func (a *ResourceAnalyzer) matchNodeGroup(
 	}
 
 	// Calculate deficit for matching pods
-	deficit := ResourceDeficit{
-		CPU:    resource.Quantity{},
-		Memory: resource.Quantity{},
-		Pods:   len(matchingPods),
-	}
-
-	for _, pod := range matchingPods {
-		podRes := a.CalculatePodResources(pod)
-		deficit.CPU.Add(podRes.CPU)
-		deficit.Memory.Add(podRes.Memory)
-	}
+	deficit := a.podsDeficit(matchingPods)
 
 	// Calculate match score
 	score := a.calculateMatchScore(ng, matchingPods, deficit)
@@ -195,10 +212,26 @@ func (a *ResourceAnalyzer) matchNodeGroup(
 	}
 }
 
+// podsDeficit calculates the total resource requests of a set of pods
+func (a *ResourceAnalyzer) podsDeficit(pods []*corev1.Pod) ResourceDeficit {
+	deficit := ResourceDeficit{
+		CPU:    resource.Quantity{},
+		Memory: resource.Quantity{},
+		Pods:   len(pods),
+	}
+
+	for _, pod := range pods {
+		deficit.add(a.CalculatePodResources(pod))
+	}
+
+	return deficit
+}
+
 // podMatchesNodeGroup checks if a pod can be scheduled on a NodeGroup
 func (a *ResourceAnalyzer) podMatchesNodeGroup(
 	pod *corev1.Pod,
 	ng *v1alpha1.NodeGroup,
+	volumes *VolumeTopology,
 ) bool {
 	// Check node selector
 	if len(pod.Spec.NodeSelector) > 0 {
@@ -228,6 +261,31 @@ func (a *ResourceAnalyzer) podMatchesNodeGroup(
 		}
 	}
 
+	// Check hugepages and extended resources; only NodeGroups declaring
+	// them can provide them
+	for name := range a.CalculatePodResources(pod).Extended {
+		if _, ok := ng.Spec.ExtendedResources[name]; !ok {
+			a.logger.Debug("NodeGroup does not provide extended resource",
+				zap.String("pod", pod.Name),
+				zap.String("namespace", pod.Namespace),
+				zap.String("nodeGroup", ng.Name),
+				zap.String("resource", string(name)),
+			)
+			return false
+		}
+	}
+
+	// Check the topology of the pod's persistent volumes
+	if !volumes.NodeGroupSatisfies(pod, ng) {
+		a.logger.Debug("Pod volumes not accessible from NodeGroup",
+			zap.String("pod", pod.Name),
+			zap.String("namespace", pod.Namespace),
+			zap.String("nodeGroup", ng.Name),
+			zap.String("datacenter", ng.Spec.DatacenterID),
+		)
+		return false
+	}
+
 	return true
 }
 
@@ -270,13 +328,13 @@ func (a *ResourceAnalyzer) calculateMatchScore(
 	score += len(matchingPods) * 100
 
 	// Prefer NodeGroups with capacity to scale
-	availableCapacity := ng.Spec.MaxNodes - ng.Status.DesiredNodes
+	availableCapacity := v1alpha1.EffectiveMaxNodes(ng) - ng.Status.DesiredNodes
 	if availableCapacity > 0 {
 		score += int(availableCapacity) * 50
 	}
 
 	// Prefer NodeGroups that are not at max capacity
-	if ng.Status.DesiredNodes < ng.Spec.MaxNodes {
+	if ng.Status.DesiredNodes < v1alpha1.EffectiveMaxNodes(ng) {
 		score += 200
 	}
 
@@ -310,6 +368,7 @@ func (a *ResourceAnalyzer) calculateCostScore(ng *v1alpha1.NodeGroup, deficit Re
 	requirements := cost.ResourceRequirements{
 		MinCPU:      int(deficit.CPU.MilliValue() / 1000), // Convert to cores
 		MinMemoryMB: int(deficit.Memory.Value() / (1024 * 1024)),
+		MinDiskGB:   diskGB(deficit.EphemeralStorage),
 	}
 
 	// If requirements are 0, use minimal requirements
@@ -382,7 +441,10 @@ func sortNodeGroupMatches(matches []NodeGroupMatch) {
 	}
 }
 
-// EstimateNodesNeeded estimates how many nodes are needed to satisfy the deficit
+// EstimateNodesNeeded estimates how many nodes are needed to satisfy the deficit.
+// It divides the aggregate deficit by the instance size and is a lower bound:
+// it ignores per-pod fragmentation, reservations, DaemonSets and extended
+// resources, which EstimateNodesForPods accounts for.
 func (a *ResourceAnalyzer) EstimateNodesNeeded(
 	deficit ResourceDeficit,
 	instanceType v1alpha1.InstanceTypeInfo,
@@ -401,6 +463,13 @@ func (a *ResourceAnalyzer) EstimateNodesNeeded(
 	instanceMemoryBytes := int64(instanceType.MemoryMB) * 1024 * 1024
 	nodesByMemory := (memoryBytes + instanceMemoryBytes - 1) / instanceMemoryBytes
 
+	// Calculate based on ephemeral storage, which is backed by the offering's disk
+	var nodesByDisk int64
+	if storageBytes := deficit.EphemeralStorage.Value(); storageBytes > 0 && instanceType.DiskGB > 0 {
+		instanceDiskBytes := int64(instanceType.DiskGB) * 1024 * 1024 * 1024
+		nodesByDisk = (storageBytes + instanceDiskBytes - 1) / instanceDiskBytes
+	}
+
 	// Calculate based on pod count (assume 110 pods per node max)
 	maxPodsPerNode := int64(110)
 	nodesByPods := (int64(deficit.Pods) + maxPodsPerNode - 1) / maxPodsPerNode
@@ -410,6 +479,9 @@ func (a *ResourceAnalyzer) EstimateNodesNeeded(
 	if nodesByMemory > nodesNeeded {
 		nodesNeeded = nodesByMemory
 	}
+	if nodesByDisk > nodesNeeded {
+		nodesNeeded = nodesByDisk
+	}
 	if nodesByPods > nodesNeeded {
 		nodesNeeded = nodesByPods
 	}
@@ -422,6 +494,7 @@ func (a *ResourceAnalyzer) EstimateNodesNeeded(
 	a.logger.Debug("Estimated nodes needed",
 		zap.Int64("nodesByCPU", nodesByCPU),
 		zap.Int64("nodesByMemory", nodesByMemory),
+		zap.Int64("nodesByDisk", nodesByDisk),
 		zap.Int64("nodesByPods", nodesByPods),
 		zap.Int64("nodesNeeded", nodesNeeded),
 	)
@@ -429,6 +502,40 @@ func (a *ResourceAnalyzer) EstimateNodesNeeded(
 	return int(nodesNeeded)
 }
 
+// DefaultInstanceTypeInfo is assumed for offerings whose resources are unknown
+var DefaultInstanceTypeInfo = v1alpha1.InstanceTypeInfo{
+	CPU:      4,
+	MemoryMB: 8192,
+	DiskGB:   80,
+}
+
+// InstanceTypeInfo returns the resources of an offering. DefaultInstanceTypeInfo
+// is used when the calculator is not available or the offering cannot be priced.
+func (a *ResourceAnalyzer) InstanceTypeInfo(ctx context.Context, offeringID string) v1alpha1.InstanceTypeInfo {
+	info := DefaultInstanceTypeInfo
+	info.OfferingID = offeringID
+
+	if a.calculator == nil {
+		return info
+	}
+
+	offeringCost, err := a.calculator.GetOfferingCost(ctx, offeringID)
+	if err != nil || offeringCost.Specs.CPU <= 0 || offeringCost.Specs.MemoryMB <= 0 {
+		a.logger.Debug("Offering resources unknown, using defaults",
+			zap.String("offeringID", offeringID),
+			zap.Error(err),
+		)
+		return info
+	}
+
+	info.CPU = offeringCost.Specs.CPU
+	info.MemoryMB = offeringCost.Specs.MemoryMB
+	if offeringCost.Specs.DiskGB > 0 {
+		info.DiskGB = offeringCost.Specs.DiskGB
+	}
+	return info
+}
+
 // SelectInstanceType selects the optimal instance type for a NodeGroup
 // Uses cost-aware selection to find the cheapest offering that meets requirements
 func (a *ResourceAnalyzer) SelectInstanceType(
@@ -456,6 +563,7 @@ func (a *ResourceAnalyzer) SelectInstanceType(
 		requirements := cost.ResourceRequirements{
 			MinCPU:      int(deficit.CPU.MilliValue() / 1000), // Convert to cores
 			MinMemoryMB: int(deficit.Memory.Value() / (1024 * 1024)),
+			MinDiskGB:   diskGB(deficit.EphemeralStorage),
 		}
 
 		// Set minimum requirements if deficit is zero
@@ -466,15 +574,26 @@ func (a *ResourceAnalyzer) SelectInstanceType(
 			requirements.MinMemoryMB = 1024
 		}
 
-		// Find cheapest offering that meets requirements from allowed offerings
-		recommendation, err := a.calculator.FindCheapestOffering(ctx, requirements, ng.Spec.OfferingIDs)
+		// Find the best offering under the carbon objective, or the cheapest,
+		// that meets requirements from allowed offerings
+		requirements.Objective = a.objective
+		var recommendation *cost.Recommendation
+		var err error
+		if a.objective.CarbonWeight() > 0 && a.calculator.CarbonIntensity() != nil {
+			recommendation, err = a.calculator.FindLowestImpactOffering(ctx, requirements, ng.Spec.OfferingIDs)
+		} else {
+			recommendation, err = a.calculator.FindCheapestOffering(ctx, requirements, ng.Spec.OfferingIDs)
+		}
 		if err == nil && recommendation != nil {
-			a.logger.Info("Selected cheapest instance type for requirements",
+			a.logger.Info("Selected instance type for requirements",
+				zap.String("objective", string(requirements.Objective)),
 				zap.String("nodeGroup", ng.Name),
 				zap.String("instanceType", recommendation.OfferingID),
 				zap.String("offeringName", recommendation.OfferingName),
+				zap.String("pricingSource", string(recommendation.PricingSource)),
 				zap.Int("requiredCPU", requirements.MinCPU),
 				zap.Int("requiredMemoryMB", requirements.MinMemoryMB),
+				zap.Int("requiredDiskGB", requirements.MinDiskGB),
 			)
 			return recommendation.OfferingID, nil
 		}
@@ -500,3 +619,10 @@ func (a *ResourceAnalyzer) SelectInstanceType(
 
 	return "", fmt.Errorf("no instance types available for NodeGroup %s", ng.Name)
 }
+
+// diskGB converts an ephemeral storage request to whole gigabytes, rounding up
+// so offerings with too little disk are not selected
+func diskGB(storage resource.Quantity) int {
+	const gib = 1024 * 1024 * 1024
+	return int((storage.Value() + gib - 1) / gib)
+}
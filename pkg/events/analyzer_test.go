@@ -1,6 +1,7 @@
 package events
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -11,6 +12,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	vpsieclient "github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/client"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
 )
 
 // TestCalculatePodResources tests pod resource calculation
@@ -156,6 +159,55 @@ func TestCalculatePodResources(t *testing.T) {
 	}
 }
 
+// TestCalculatePodResourcesExtended tests ephemeral storage, hugepages and extended resource requests
+func TestCalculatePodResourcesExtended(t *testing.T) {
+	analyzer := NewResourceAnalyzer(zap.NewNop(), nil)
+
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: resource.MustParse("5Gi"),
+					"nvidia.com/gpu":                resource.MustParse("1"),
+					"hugepages-2Mi":                 resource.MustParse("256Mi"),
+				}}},
+				{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: resource.MustParse("5Gi"),
+					"nvidia.com/gpu":                resource.MustParse("1"),
+				}}},
+			},
+			InitContainers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: resource.MustParse("20Gi"),
+					"hugepages-2Mi":                 resource.MustParse("128Mi"),
+					"example.com/fpga":              resource.MustParse("1"),
+				}}},
+			},
+		},
+	}
+
+	result := analyzer.CalculatePodResources(pod)
+	assert.Equal(t, int64(20*1024*1024*1024), result.EphemeralStorage.Value())
+	require.Len(t, result.Extended, 3)
+	assert.Equal(t, int64(2), result.Extended.Name("nvidia.com/gpu", resource.DecimalSI).Value())
+	assert.Equal(t, int64(256*1024*1024), result.Extended.Name("hugepages-2Mi", resource.BinarySI).Value())
+	assert.Equal(t, int64(1), result.Extended.Name("example.com/fpga", resource.DecimalSI).Value())
+
+	deficit := analyzer.podsDeficit([]*corev1.Pod{pod, pod})
+	assert.Equal(t, int64(40*1024*1024*1024), deficit.EphemeralStorage.Value())
+	assert.Equal(t, int64(4), deficit.Extended.Name("nvidia.com/gpu", resource.DecimalSI).Value())
+}
+
+// TestIsExtendedResource tests which resources only NodeGroups declaring them provide
+func TestIsExtendedResource(t *testing.T) {
+	assert.True(t, isExtendedResource("nvidia.com/gpu"))
+	assert.True(t, isExtendedResource("hugepages-1Gi"))
+	assert.False(t, isExtendedResource(corev1.ResourceCPU))
+	assert.False(t, isExtendedResource(corev1.ResourceEphemeralStorage))
+	assert.False(t, isExtendedResource("kubernetes.io/batch-cpu"))
+	assert.False(t, isExtendedResource("requests.nvidia.com/gpu"))
+}
+
 // TestCalculateDeficit tests resource deficit calculation
 func TestCalculateDeficit(t *testing.T) {
 	analyzer := NewResourceAnalyzer(zap.NewNop(), nil)
@@ -276,6 +328,16 @@ func TestCalculateDeficit(t *testing.T) {
 func TestPodMatchesNodeGroup(t *testing.T) {
 	analyzer := NewResourceAnalyzer(zap.NewNop(), nil)
 
+	gpuPod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("1")},
+				},
+			}},
+		},
+	}
+
 	tests := []struct {
 		name      string
 		pod       *corev1.Pod
@@ -397,11 +459,27 @@ func TestPodMatchesNodeGroup(t *testing.T) {
 			},
 			matches: false,
 		},
+		{
+			name:      "NodeGroup does not declare requested extended resource",
+			pod:       gpuPod,
+			nodeGroup: &v1alpha1.NodeGroup{},
+			matches:   false,
+		},
+		{
+			name: "NodeGroup declares requested extended resource",
+			pod:  gpuPod,
+			nodeGroup: &v1alpha1.NodeGroup{
+				Spec: v1alpha1.NodeGroupSpec{
+					ExtendedResources: corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("2")},
+				},
+			},
+			matches: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := analyzer.podMatchesNodeGroup(tt.pod, tt.nodeGroup)
+			result := analyzer.podMatchesNodeGroup(tt.pod, tt.nodeGroup, nil)
 			assert.Equal(t, tt.matches, result)
 		})
 	}
@@ -543,7 +621,7 @@ func TestFindMatchingNodeGroups(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			matches := analyzer.FindMatchingNodeGroups(tt.pendingPods, tt.nodeGroups)
+			matches := analyzer.FindMatchingNodeGroups(tt.pendingPods, tt.nodeGroups, nil)
 			assert.Len(t, matches, tt.expectedMatches)
 
 			if tt.expectedNodeGroup != "" {
@@ -670,6 +748,17 @@ func TestEstimateNodesNeeded(t *testing.T) {
 			expectedMin: 2, // 200 pods / 110 max pods per node
 			expectedMax: 2,
 		},
+		{
+			name: "Ephemeral-storage-bound",
+			deficit: ResourceDeficit{
+				CPU:              resource.MustParse("1000m"),
+				Memory:           resource.MustParse("2Gi"),
+				EphemeralStorage: resource.MustParse("200Gi"),
+				Pods:             2,
+			},
+			expectedMin: 3, // 200 GB / 80 GB disk per node
+			expectedMax: 3,
+		},
 		{
 			name: "Small deficit",
 			deficit: ResourceDeficit{
@@ -788,3 +877,46 @@ func TestSelectInstanceType(t *testing.T) {
 		})
 	}
 }
+
+// offeringsClient serves a fixed offering catalog
+type offeringsClient struct {
+	vpsieclient.VPSieClient
+	offerings []vpsieclient.Offering
+}
+
+func (c *offeringsClient) ListOfferings(context.Context, *vpsieclient.ListOptions) ([]vpsieclient.Offering, error) {
+	return c.offerings, nil
+}
+
+// TestSelectInstanceTypeCarbonObjective tests that the carbon objective weighs
+// datacenter emissions against price
+func TestSelectInstanceTypeCarbonObjective(t *testing.T) {
+	calculator := cost.NewCalculator(&offeringsClient{offerings: []vpsieclient.Offering{
+		{ID: "coal-small", CPU: 2, RAM: 4096, Disk: 50, Price: 10.0, Available: true, DatacenterID: "dc-coal"},
+		{ID: "hydro-small", CPU: 2, RAM: 4096, Disk: 50, Price: 12.0, Available: true, DatacenterID: "dc-hydro"},
+	}})
+	table, err := cost.ParseCarbonIntensityTable([]byte(`
+version: test
+datacenters:
+  - {id: dc-coal, intensity: 800}
+  - {id: dc-hydro, intensity: 30}
+`))
+	require.NoError(t, err)
+	calculator.SetCarbonIntensity(table)
+
+	analyzer := NewResourceAnalyzer(zap.NewNop(), calculator)
+	ng := &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "ng-1"},
+		Spec:       v1alpha1.NodeGroupSpec{OfferingIDs: []string{"coal-small", "hydro-small"}},
+	}
+	deficit := ResourceDeficit{CPU: resource.MustParse("2"), Memory: resource.MustParse("4Gi"), Pods: 1}
+
+	selected, err := analyzer.SelectInstanceType(ng, deficit)
+	require.NoError(t, err)
+	assert.Equal(t, "coal-small", selected)
+
+	analyzer.SetCarbonObjective(cost.CarbonObjectiveLowCarbon)
+	selected, err = analyzer.SelectInstanceType(ng, deficit)
+	require.NoError(t, err)
+	assert.Equal(t, "hydro-small", selected)
+}
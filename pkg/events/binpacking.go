@@ -0,0 +1,530 @@
+package events
+
+import (
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+)
+
+const (
+	// DefaultMaxPodsPerNode is the kubelet's default pod limit, used when no
+	// node of the NodeGroup reports its allocatable pods
+	DefaultMaxPodsPerNode = 110
+
+	// evictionThresholdBytes is the kubelet's default hard eviction threshold for memory
+	evictionThresholdBytes = 100 * 1024 * 1024
+
+	// nodefsEvictionPercent is the kubelet's default hard eviction threshold for
+	// the node's root filesystem, which backs ephemeral storage
+	nodefsEvictionPercent = 10
+
+	// hostnameTopologyKey is the topology key scoping pod (anti-)affinity to a single node
+	hostnameTopologyKey = "kubernetes.io/hostname"
+)
+
+// NodeTemplate is the schedulable capacity of a node a NodeGroup would add,
+// after system reservations and DaemonSet pods
+type NodeTemplate struct {
+	// OfferingID is the offering the node would use
+	OfferingID string
+
+	// CPUMillis is the CPU available to pending pods, in millicores
+	CPUMillis int64
+
+	// MemoryBytes is the memory available to pending pods, in bytes
+	MemoryBytes int64
+
+	// EphemeralStorageBytes is the ephemeral storage available to pending pods, in bytes
+	EphemeralStorageBytes int64
+
+	// ExtendedResources are the hugepages and extended resources available to
+	// pending pods, in bytes for hugepages and units otherwise
+	ExtendedResources map[corev1.ResourceName]int64
+
+	// MaxPods is the number of pending pods the node can run
+	MaxPods int
+
+	// DaemonSetPods is the number of DaemonSet pods expected on the node
+	DaemonSetPods int
+}
+
+// BinpackingResult is the outcome of packing pending pods into template nodes
+type BinpackingResult struct {
+	// Nodes is the number of template nodes needed for the pods that fit
+	Nodes int
+
+	// Unschedulable are pods that do not fit on an empty template node
+	Unschedulable []*corev1.Pod
+}
+
+// binpackRequest is a pod's requests and its largest share of any of a node's resources
+type binpackRequest struct {
+	pod          *corev1.Pod
+	cpuMillis    int64
+	memoryBytes  int64
+	storageBytes int64
+	extended     map[corev1.ResourceName]int64
+	share        float64
+}
+
+// packedNode tracks the pods placed on one node during binpacking
+type packedNode struct {
+	capacity NodeTemplate
+
+	// node is the existing node the pods are placed on, nil for a template node
+	node *corev1.Node
+
+	cpuMillis    int64
+	memoryBytes  int64
+	storageBytes int64
+	extended     map[corev1.ResourceName]int64
+	pods         []*corev1.Pod
+}
+
+// BuildNodeTemplate derives the capacity of a new node of the NodeGroup. If an
+// existing node of the NodeGroup uses the same offering its allocatable is used,
+// since it already reflects kubelet reservations. Otherwise the reservations are
+// estimated from the offering's resources, with ephemeral storage backed by the
+// offering's disk. Extended resources are those declared in the NodeGroup spec,
+// with declared hugepages taken out of the estimated memory.
+// Requests of DaemonSets that would run on the node are subtracted in all cases.
+func (a *ResourceAnalyzer) BuildNodeTemplate(
+	ng *v1alpha1.NodeGroup,
+	instanceType v1alpha1.InstanceTypeInfo,
+	nodes []corev1.Node,
+	daemonSets []appsv1.DaemonSet,
+) NodeTemplate {
+	template := NodeTemplate{
+		OfferingID: instanceType.OfferingID,
+		MaxPods:    DefaultMaxPodsPerNode,
+	}
+
+	if len(ng.Spec.ExtendedResources) > 0 {
+		template.ExtendedResources = make(map[corev1.ResourceName]int64, len(ng.Spec.ExtendedResources))
+		for name, quantity := range ng.Spec.ExtendedResources {
+			template.ExtendedResources[name] = quantity.Value()
+		}
+	}
+
+	var existing *corev1.Node
+	for i := range nodes {
+		if nodes[i].Labels[v1alpha1.OfferingLabelKey] == instanceType.OfferingID {
+			existing = &nodes[i]
+			break
+		}
+	}
+
+	if existing != nil {
+		template.CPUMillis = existing.Status.Allocatable.Cpu().MilliValue()
+		template.MemoryBytes = existing.Status.Allocatable.Memory().Value()
+		template.EphemeralStorageBytes = existing.Status.Allocatable.StorageEphemeral().Value()
+		if pods, ok := existing.Status.Allocatable[corev1.ResourcePods]; ok {
+			template.MaxPods = int(pods.Value())
+		}
+		for name := range template.ExtendedResources {
+			if quantity, ok := existing.Status.Allocatable[name]; ok {
+				template.ExtendedResources[name] = quantity.Value()
+			}
+		}
+	} else {
+		capacityCPU := int64(instanceType.CPU) * 1000
+		capacityMemory := int64(instanceType.MemoryMB) * 1024 * 1024
+		capacityStorage := int64(instanceType.DiskGB) * 1024 * 1024 * 1024
+		reservedCPU, reservedMemory := systemReserved(capacityCPU, capacityMemory)
+		template.CPUMillis = capacityCPU - reservedCPU
+		template.MemoryBytes = capacityMemory - reservedMemory
+		// Hugepages are pre-allocated out of the node's memory
+		for name, value := range template.ExtendedResources {
+			if strings.HasPrefix(string(name), corev1.ResourceHugePagesPrefix) {
+				template.MemoryBytes -= value
+			}
+		}
+		template.EphemeralStorageBytes = capacityStorage - capacityStorage*nodefsEvictionPercent/100
+	}
+
+	for i := range daemonSets {
+		ds := &daemonSets[i]
+		if !daemonSetRunsOnNodeGroup(ds, ng) {
+			continue
+		}
+		pod := &corev1.Pod{Spec: ds.Spec.Template.Spec}
+		cpu, memory := PodResourceRequests(pod)
+		storage, extended := podScalarRequests(pod)
+		template.CPUMillis -= cpu
+		template.MemoryBytes -= memory
+		template.EphemeralStorageBytes -= storage
+		for name, value := range extended {
+			if _, ok := template.ExtendedResources[name]; ok {
+				template.ExtendedResources[name] -= value
+			}
+		}
+		template.MaxPods--
+		template.DaemonSetPods++
+	}
+
+	if template.CPUMillis < 0 {
+		template.CPUMillis = 0
+	}
+	if template.MemoryBytes < 0 {
+		template.MemoryBytes = 0
+	}
+	if template.EphemeralStorageBytes < 0 {
+		template.EphemeralStorageBytes = 0
+	}
+	for name, value := range template.ExtendedResources {
+		if value < 0 {
+			template.ExtendedResources[name] = 0
+		}
+	}
+	if template.MaxPods < 0 {
+		template.MaxPods = 0
+	}
+
+	return template
+}
+
+// systemReserved estimates the CPU (millicores) and memory (bytes) a node
+// reserves for the kubelet, container runtime and system daemons. The tiers
+// follow the reservations commonly applied by managed Kubernetes offerings.
+func systemReserved(cpuMillis, memoryBytes int64) (reservedCPU, reservedMemory int64) {
+	cpuTiers := []struct {
+		upTo    int64
+		percent float64
+	}{
+		{upTo: 1000, percent: 6},
+		{upTo: 2000, percent: 1},
+		{upTo: 4000, percent: 0.5},
+		{upTo: -1, percent: 0.25},
+	}
+	var lower int64
+	for _, tier := range cpuTiers {
+		if cpuMillis <= lower {
+			break
+		}
+		upper := cpuMillis
+		if tier.upTo > 0 && tier.upTo < upper {
+			upper = tier.upTo
+		}
+		reservedCPU += int64(float64(upper-lower) * tier.percent / 100)
+		lower = tier.upTo
+		if tier.upTo < 0 {
+			break
+		}
+	}
+
+	const gib = int64(1024 * 1024 * 1024)
+	memoryTiers := []struct {
+		upTo    int64
+		percent float64
+	}{
+		{upTo: 4 * gib, percent: 25},
+		{upTo: 8 * gib, percent: 20},
+		{upTo: 16 * gib, percent: 10},
+		{upTo: 128 * gib, percent: 6},
+		{upTo: -1, percent: 2},
+	}
+	lower = 0
+	for _, tier := range memoryTiers {
+		if memoryBytes <= lower {
+			break
+		}
+		upper := memoryBytes
+		if tier.upTo > 0 && tier.upTo < upper {
+			upper = tier.upTo
+		}
+		reservedMemory += int64(float64(upper-lower) * tier.percent / 100)
+		lower = tier.upTo
+		if tier.upTo < 0 {
+			break
+		}
+	}
+	reservedMemory += evictionThresholdBytes
+
+	return reservedCPU, reservedMemory
+}
+
+// daemonSetRunsOnNodeGroup reports whether the DaemonSet would place a pod on a
+// new node of the NodeGroup, based on the NodeGroup's labels and taints
+func daemonSetRunsOnNodeGroup(ds *appsv1.DaemonSet, ng *v1alpha1.NodeGroup) bool {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Labels: ng.Spec.Labels},
+		Spec:       corev1.NodeSpec{Taints: ng.Spec.Taints},
+	}
+	pod := &corev1.Pod{Spec: ds.Spec.Template.Spec}
+
+	return podToleratesNodeTaints(pod, node.Spec.Taints) && podMatchesNodeSelection(pod, node)
+}
+
+// EstimateNodesForPods estimates the template nodes needed for the pods using
+// first-fit-decreasing binpacking. Pods are sorted by their largest share of
+// any of the template's resources and placed on the first node with room for them
+// and no required anti-affinity conflict; pods with required affinity to a
+// placed pod are tried on that pod's node first. Both are only honoured for
+// the hostname topology, since all new nodes of a NodeGroup share other domains.
+func (a *ResourceAnalyzer) EstimateNodesForPods(pods []*corev1.Pod, template NodeTemplate) BinpackingResult {
+	result := BinpackingResult{}
+	if len(pods) == 0 {
+		return result
+	}
+
+	var nodes []*packedNode
+	for _, req := range binpackRequests(pods, template) {
+		empty := &packedNode{capacity: template}
+		if !empty.fits(req) {
+			result.Unschedulable = append(result.Unschedulable, req.pod)
+			continue
+		}
+
+		target := firstFit(nodes, req)
+		if target == nil {
+			target = empty
+			nodes = append(nodes, target)
+		}
+		target.place(req)
+	}
+
+	result.Nodes = len(nodes)
+
+	a.logger.Debug("Estimated nodes by binpacking",
+		zap.String("offering", template.OfferingID),
+		zap.Int("pods", len(pods)),
+		zap.Int("nodes", result.Nodes),
+		zap.Int("unschedulable", len(result.Unschedulable)),
+	)
+
+	return result
+}
+
+// FitPodsOnNodes places the pods on existing nodes, alongside the pods in
+// running by node name, with the same first-fit-decreasing binpacking as
+// EstimateNodesForPods. Nodes offer their allocatable resources and pods must
+// also tolerate a node's taints and match its node selection. It returns the
+// pods that fit on none of the nodes.
+func (a *ResourceAnalyzer) FitPodsOnNodes(pods []*corev1.Pod, nodes []*corev1.Node, running map[string][]*corev1.Pod) []*corev1.Pod {
+	if len(pods) == 0 {
+		return nil
+	}
+
+	packed := make([]*packedNode, 0, len(nodes))
+	var reference NodeTemplate
+	for _, node := range nodes {
+		target := &packedNode{capacity: nodeCapacity(node), node: node}
+		for _, pod := range running[node.Name] {
+			target.place(newBinpackRequest(pod, target.capacity))
+		}
+		packed = append(packed, target)
+
+		reference.CPUMillis = max(reference.CPUMillis, target.capacity.CPUMillis)
+		reference.MemoryBytes = max(reference.MemoryBytes, target.capacity.MemoryBytes)
+		reference.EphemeralStorageBytes = max(reference.EphemeralStorageBytes, target.capacity.EphemeralStorageBytes)
+	}
+
+	var unplaced []*corev1.Pod
+	for _, req := range binpackRequests(pods, reference) {
+		target := firstFit(packed, req)
+		if target == nil {
+			unplaced = append(unplaced, req.pod)
+			continue
+		}
+		target.place(req)
+	}
+
+	a.logger.Debug("Fitted pods on existing nodes",
+		zap.Int("pods", len(pods)),
+		zap.Int("nodes", len(nodes)),
+		zap.Int("unplaced", len(unplaced)),
+	)
+
+	return unplaced
+}
+
+// nodeCapacity returns the allocatable resources of an existing node as a template
+func nodeCapacity(node *corev1.Node) NodeTemplate {
+	capacity := NodeTemplate{
+		OfferingID:            node.Labels[v1alpha1.OfferingLabelKey],
+		CPUMillis:             node.Status.Allocatable.Cpu().MilliValue(),
+		MemoryBytes:           node.Status.Allocatable.Memory().Value(),
+		EphemeralStorageBytes: node.Status.Allocatable.StorageEphemeral().Value(),
+		MaxPods:               DefaultMaxPodsPerNode,
+	}
+	if pods, ok := node.Status.Allocatable[corev1.ResourcePods]; ok {
+		capacity.MaxPods = int(pods.Value())
+	}
+	for name, quantity := range node.Status.Allocatable {
+		if !isExtendedResource(name) {
+			continue
+		}
+		if capacity.ExtendedResources == nil {
+			capacity.ExtendedResources = make(map[corev1.ResourceName]int64)
+		}
+		capacity.ExtendedResources[name] = quantity.Value()
+	}
+	return capacity
+}
+
+// binpackRequests returns the requests of the pods, sorted by their largest
+// share of any of the template's resources
+func binpackRequests(pods []*corev1.Pod, template NodeTemplate) []binpackRequest {
+	requests := make([]binpackRequest, 0, len(pods))
+	for _, pod := range pods {
+		requests = append(requests, newBinpackRequest(pod, template))
+	}
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].share > requests[j].share
+	})
+	return requests
+}
+
+// newBinpackRequest returns the pod's requests and their largest share of the template's resources
+func newBinpackRequest(pod *corev1.Pod, template NodeTemplate) binpackRequest {
+	cpu, memory := PodResourceRequests(pod)
+	storage, extended := podScalarRequests(pod)
+	req := binpackRequest{pod: pod, cpuMillis: cpu, memoryBytes: memory, storageBytes: storage, extended: extended}
+	if template.CPUMillis > 0 {
+		req.share = float64(cpu) / float64(template.CPUMillis)
+	}
+	if template.MemoryBytes > 0 {
+		if share := float64(memory) / float64(template.MemoryBytes); share > req.share {
+			req.share = share
+		}
+	}
+	if template.EphemeralStorageBytes > 0 {
+		if share := float64(storage) / float64(template.EphemeralStorageBytes); share > req.share {
+			req.share = share
+		}
+	}
+	for name, value := range extended {
+		if capacity := template.ExtendedResources[name]; capacity > 0 {
+			if share := float64(value) / float64(capacity); share > req.share {
+				req.share = share
+			}
+		}
+	}
+	return req
+}
+
+// firstFit returns the node the request should be placed on: the first with
+// room for it that runs a pod it has required hostname affinity to, otherwise
+// the first with room for it. It returns nil if none has room.
+func firstFit(nodes []*packedNode, req binpackRequest) *packedNode {
+	for _, node := range nodes {
+		if podHasHostAffinityWith(req.pod, node.pods) && node.fits(req) {
+			return node
+		}
+	}
+	for _, node := range nodes {
+		if node.fits(req) {
+			return node
+		}
+	}
+	return nil
+}
+
+// fits reports whether the request fits in the node's remaining capacity
+// without a required anti-affinity conflict with its pods. Existing nodes also
+// check the pod against their taints and labels.
+func (n *packedNode) fits(req binpackRequest) bool {
+	if len(n.pods)+1 > n.capacity.MaxPods ||
+		n.cpuMillis+req.cpuMillis > n.capacity.CPUMillis ||
+		n.memoryBytes+req.memoryBytes > n.capacity.MemoryBytes ||
+		(req.storageBytes > 0 && n.storageBytes+req.storageBytes > n.capacity.EphemeralStorageBytes) {
+		return false
+	}
+	for name, value := range req.extended {
+		if n.extended[name]+value > n.capacity.ExtendedResources[name] {
+			return false
+		}
+	}
+	if n.node != nil && !PodSchedulableOnNode(req.pod, n.node) {
+		return false
+	}
+	for _, placed := range n.pods {
+		if podsAntiAffine(req.pod, placed) {
+			return false
+		}
+	}
+	return true
+}
+
+// place adds the request to the node
+func (n *packedNode) place(req binpackRequest) {
+	n.cpuMillis += req.cpuMillis
+	n.memoryBytes += req.memoryBytes
+	n.storageBytes += req.storageBytes
+	for name, value := range req.extended {
+		if n.extended == nil {
+			n.extended = make(map[corev1.ResourceName]int64)
+		}
+		n.extended[name] += value
+	}
+	n.pods = append(n.pods, req.pod)
+}
+
+// podsAntiAffine reports whether either pod's required hostname anti-affinity
+// excludes the other from its node
+func podsAntiAffine(a, b *corev1.Pod) bool {
+	return antiAffinityExcludes(a, b) || antiAffinityExcludes(b, a)
+}
+
+// antiAffinityExcludes reports whether the owner's required hostname anti-affinity selects the candidate
+func antiAffinityExcludes(owner, candidate *corev1.Pod) bool {
+	if owner.Spec.Affinity == nil || owner.Spec.Affinity.PodAntiAffinity == nil {
+		return false
+	}
+	for i := range owner.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		term := &owner.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i]
+		if term.TopologyKey == hostnameTopologyKey && affinityTermMatches(owner, term, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// podHasHostAffinityWith reports whether the pod's required hostname affinity
+// selects any of the placed pods
+func podHasHostAffinityWith(pod *corev1.Pod, placed []*corev1.Pod) bool {
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.PodAffinity == nil {
+		return false
+	}
+	for i := range pod.Spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		term := &pod.Spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i]
+		if term.TopologyKey != hostnameTopologyKey {
+			continue
+		}
+		for _, other := range placed {
+			if affinityTermMatches(pod, term, other) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// affinityTermMatches reports whether the candidate pod is selected by the owner's affinity term
+func affinityTermMatches(owner *corev1.Pod, term *corev1.PodAffinityTerm, candidate *corev1.Pod) bool {
+	if term.LabelSelector == nil {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+	if err != nil || !selector.Matches(labels.Set(candidate.Labels)) {
+		return false
+	}
+
+	// A namespace selector widens the term beyond what we can resolve here, so treat it as all namespaces
+	if len(term.Namespaces) == 0 {
+		return term.NamespaceSelector != nil || candidate.Namespace == owner.Namespace
+	}
+	for _, ns := range term.Namespaces {
+		if ns == candidate.Namespace {
+			return true
+		}
+	}
+	return false
+}
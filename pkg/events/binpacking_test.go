@@ -0,0 +1,427 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+)
+
+func newBinpackingPods(count int, cpu, memory string, labels map[string]string) []*corev1.Pod {
+	pods := make([]*corev1.Pod, 0, count)
+	for i := 0; i < count; i++ {
+		pod := newPredicatePod("pod", "", cpu, memory, labels)
+		pods = append(pods, &pod)
+	}
+	return pods
+}
+
+func TestEstimateNodesForPods(t *testing.T) {
+	analyzer := NewResourceAnalyzer(zap.NewNop(), nil)
+	template := NodeTemplate{
+		CPUMillis:   4000,
+		MemoryBytes: 8 * 1024 * 1024 * 1024,
+		MaxPods:     110,
+	}
+
+	antiAffine := func(pods []*corev1.Pod) []*corev1.Pod {
+		for _, pod := range pods {
+			pod.Spec.Affinity = &corev1.Affinity{
+				PodAntiAffinity: &corev1.PodAntiAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+						LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+						TopologyKey:   hostnameTopologyKey,
+					}},
+				},
+			}
+		}
+		return pods
+	}
+
+	tests := []struct {
+		name          string
+		pods          []*corev1.Pod
+		template      NodeTemplate
+		expected      int
+		unschedulable int
+	}{
+		{
+			name:     "no pods",
+			template: template,
+			expected: 0,
+		},
+		{
+			name: "fragmentation needs more nodes than the aggregate",
+			// 3 x 2.5 CPU = 7.5 CPU fits 2 nodes by total, but only one pod fits per node
+			pods:     newBinpackingPods(3, "2500m", "1Gi", nil),
+			template: template,
+			expected: 3,
+		},
+		{
+			name: "decreasing order fills gaps with small pods",
+			pods: append(
+				newBinpackingPods(4, "500m", "512Mi", nil),
+				newBinpackingPods(2, "3", "1Gi", nil)...,
+			),
+			template: template,
+			expected: 2,
+		},
+		{
+			name:     "max pods limits packing",
+			pods:     newBinpackingPods(5, "10m", "16Mi", nil),
+			template: NodeTemplate{CPUMillis: 4000, MemoryBytes: 8 * 1024 * 1024 * 1024, MaxPods: 2},
+			expected: 3,
+		},
+		{
+			name:     "anti-affinity spreads pods across nodes",
+			pods:     antiAffine(newBinpackingPods(3, "100m", "128Mi", map[string]string{"app": "web"})),
+			template: template,
+			expected: 3,
+		},
+		{
+			name: "extended resources limit packing",
+			pods: func() []*corev1.Pod {
+				pods := newBinpackingPods(3, "100m", "128Mi", nil)
+				for _, pod := range pods {
+					pod.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("1")
+				}
+				return pods
+			}(),
+			template: NodeTemplate{
+				CPUMillis:         4000,
+				MemoryBytes:       8 * 1024 * 1024 * 1024,
+				MaxPods:           110,
+				ExtendedResources: map[corev1.ResourceName]int64{"nvidia.com/gpu": 2},
+			},
+			expected: 2,
+		},
+		{
+			name: "ephemeral storage limits packing",
+			pods: func() []*corev1.Pod {
+				pods := newBinpackingPods(3, "100m", "128Mi", nil)
+				for _, pod := range pods {
+					pod.Spec.Containers[0].Resources.Requests[corev1.ResourceEphemeralStorage] = resource.MustParse("30Gi")
+				}
+				return pods
+			}(),
+			template: NodeTemplate{
+				CPUMillis:             4000,
+				MemoryBytes:           8 * 1024 * 1024 * 1024,
+				EphemeralStorageBytes: 72 * 1024 * 1024 * 1024,
+				MaxPods:               110,
+			},
+			expected: 2,
+		},
+		{
+			name: "pods requesting undeclared extended resources are unschedulable",
+			pods: func() []*corev1.Pod {
+				pods := newBinpackingPods(1, "100m", "128Mi", nil)
+				pods[0].Spec.Containers[0].Resources.Requests["hugepages-2Mi"] = resource.MustParse("64Mi")
+				return pods
+			}(),
+			template:      template,
+			expected:      0,
+			unschedulable: 1,
+		},
+		{
+			name:          "pods larger than a node are unschedulable",
+			pods:          append(newBinpackingPods(1, "8", "1Gi", nil), newBinpackingPods(1, "1", "1Gi", nil)...),
+			template:      template,
+			expected:      1,
+			unschedulable: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := analyzer.EstimateNodesForPods(tt.pods, tt.template)
+			assert.Equal(t, tt.expected, result.Nodes)
+			assert.Len(t, result.Unschedulable, tt.unschedulable)
+		})
+	}
+}
+
+func TestFitPodsOnNodes(t *testing.T) {
+	analyzer := NewResourceAnalyzer(zap.NewNop(), nil)
+
+	node := func(name string) *corev1.Node {
+		node := newPredicateNode(name, "4", "8Gi", 110, map[string]string{"pool": "general"})
+		return &node
+	}
+	running := func(nodeName, cpu string) map[string][]*corev1.Pod {
+		pod := newPredicatePod("running", nodeName, cpu, "1Gi", map[string]string{"app": "web"})
+		return map[string][]*corev1.Pod{nodeName: {&pod}}
+	}
+
+	tests := []struct {
+		name     string
+		pods     []*corev1.Pod
+		nodes    []*corev1.Node
+		running  map[string][]*corev1.Pod
+		unplaced int
+	}{
+		{
+			name:  "pods fit across nodes",
+			pods:  newBinpackingPods(4, "1500m", "1Gi", nil),
+			nodes: []*corev1.Node{node("node-1"), node("node-2")},
+		},
+		{
+			name:     "running pods take capacity",
+			pods:     newBinpackingPods(2, "1500m", "1Gi", nil),
+			nodes:    []*corev1.Node{node("node-1")},
+			running:  running("node-1", "2"),
+			unplaced: 1,
+		},
+		{
+			name: "init containers take capacity",
+			pods: func() []*corev1.Pod {
+				pods := newBinpackingPods(1, "500m", "1Gi", nil)
+				pods[0].Spec.InitContainers = []corev1.Container{{
+					Name: "init",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("6")},
+					},
+				}}
+				return pods
+			}(),
+			nodes:    []*corev1.Node{node("node-1")},
+			unplaced: 1,
+		},
+		{
+			name: "extended resources must be allocatable",
+			pods: func() []*corev1.Pod {
+				pods := newBinpackingPods(1, "100m", "128Mi", nil)
+				pods[0].Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("1")
+				return pods
+			}(),
+			nodes:    []*corev1.Node{node("node-1")},
+			unplaced: 1,
+		},
+		{
+			name: "taints must be tolerated",
+			pods: newBinpackingPods(1, "100m", "128Mi", nil),
+			nodes: func() []*corev1.Node {
+				tainted := node("node-1")
+				tainted.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}
+				return []*corev1.Node{tainted}
+			}(),
+			unplaced: 1,
+		},
+		{
+			name: "node selector must match",
+			pods: func() []*corev1.Pod {
+				pods := newBinpackingPods(1, "100m", "128Mi", nil)
+				pods[0].Spec.NodeSelector = map[string]string{"pool": "batch"}
+				return pods
+			}(),
+			nodes:    []*corev1.Node{node("node-1")},
+			unplaced: 1,
+		},
+		{
+			name: "anti-affinity with running pods",
+			pods: func() []*corev1.Pod {
+				pods := newBinpackingPods(1, "100m", "128Mi", map[string]string{"app": "web"})
+				pods[0].Spec.Affinity = &corev1.Affinity{
+					PodAntiAffinity: &corev1.PodAntiAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+							LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+							TopologyKey:   hostnameTopologyKey,
+						}},
+					},
+				}
+				return pods
+			}(),
+			nodes:    []*corev1.Node{node("node-1")},
+			running:  running("node-1", "100m"),
+			unplaced: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unplaced := analyzer.FitPodsOnNodes(tt.pods, tt.nodes, tt.running)
+			assert.Len(t, unplaced, tt.unplaced)
+		})
+	}
+}
+
+func TestBuildNodeTemplate(t *testing.T) {
+	analyzer := NewResourceAnalyzer(zap.NewNop(), nil)
+	ng := &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "ng-1", Namespace: "kube-system"},
+		Spec: v1alpha1.NodeGroupSpec{
+			Labels: map[string]string{"pool": "general"},
+			Taints: []corev1.Taint{{Key: "dedicated", Value: "general", Effect: corev1.TaintEffectNoSchedule}},
+		},
+	}
+	instanceType := v1alpha1.InstanceTypeInfo{OfferingID: "offering-1", CPU: 4, MemoryMB: 8192}
+
+	newDaemonSet := func(name, cpu, memory string, tolerations []corev1.Toleration, nodeSelector map[string]string) appsv1.DaemonSet {
+		return appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: appsv1.DaemonSetSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Tolerations:  tolerations,
+						NodeSelector: nodeSelector,
+						Containers: []corev1.Container{{
+							Name: name,
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse(cpu),
+									corev1.ResourceMemory: resource.MustParse(memory),
+								},
+							},
+						}},
+					},
+				},
+			},
+		}
+	}
+	tolerateAll := []corev1.Toleration{{Operator: corev1.TolerationOpExists}}
+	daemonSets := []appsv1.DaemonSet{
+		newDaemonSet("logging", "100m", "128Mi", tolerateAll, nil),
+		// Does not tolerate the NodeGroup taint
+		newDaemonSet("monitoring", "200m", "256Mi", nil, nil),
+		// Selects other nodes
+		newDaemonSet("gpu-driver", "500m", "512Mi", tolerateAll, map[string]string{"gpu": "true"}),
+	}
+
+	// Estimated from the offering: 4 CPU reserves 60+10+10 = 80m, 8GiB reserves 1GiB+0.8GiB+100Mi
+	template := analyzer.BuildNodeTemplate(ng, instanceType, nil, daemonSets)
+	assert.Equal(t, int64(4000-80-100), template.CPUMillis)
+	expectedMemory := int64(8192-1024-819-100-128) * 1024 * 1024
+	assert.InDelta(t, expectedMemory, template.MemoryBytes, 1024*1024)
+	assert.Equal(t, DefaultMaxPodsPerNode-1, template.MaxPods)
+	assert.Equal(t, 1, template.DaemonSetPods)
+
+	// An existing node of the same offering provides the allocatable
+	node := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-1",
+			Labels: map[string]string{v1alpha1.OfferingLabelKey: "offering-1"},
+		},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("3800m"),
+				corev1.ResourceMemory: resource.MustParse("7Gi"),
+				corev1.ResourcePods:   resource.MustParse("58"),
+			},
+		},
+	}
+	template = analyzer.BuildNodeTemplate(ng, instanceType, []corev1.Node{node}, daemonSets)
+	require.Equal(t, int64(3700), template.CPUMillis)
+	assert.Equal(t, int64(7*1024-128)*1024*1024, template.MemoryBytes)
+	assert.Equal(t, 57, template.MaxPods)
+}
+
+func TestBuildNodeTemplateScalarResources(t *testing.T) {
+	analyzer := NewResourceAnalyzer(zap.NewNop(), nil)
+	ng := &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "gpu", Namespace: "kube-system"},
+		Spec: v1alpha1.NodeGroupSpec{
+			ExtendedResources: corev1.ResourceList{
+				"nvidia.com/gpu": resource.MustParse("4"),
+				"hugepages-2Mi":  resource.MustParse("1Gi"),
+			},
+		},
+	}
+	instanceType := v1alpha1.InstanceTypeInfo{OfferingID: "gpu-1", CPU: 8, MemoryMB: 32768, DiskGB: 100}
+
+	daemonSet := appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "device-plugin"},
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name: "device-plugin",
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+								"hugepages-2Mi":                 resource.MustParse("128Mi"),
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+
+	// Ephemeral storage is the offering's disk less the kubelet's 10% eviction threshold
+	template := analyzer.BuildNodeTemplate(ng, instanceType, nil, []appsv1.DaemonSet{daemonSet})
+	assert.Equal(t, int64(90-1)*1024*1024*1024, template.EphemeralStorageBytes)
+	assert.Equal(t, map[corev1.ResourceName]int64{
+		"nvidia.com/gpu": 4,
+		"hugepages-2Mi":  (1024 - 128) * 1024 * 1024,
+	}, template.ExtendedResources)
+
+	// Declared hugepages are carved out of the estimated memory
+	_, reservedMemory := systemReserved(8000, 32768*1024*1024)
+	assert.Equal(t, int64(32768-1024)*1024*1024-reservedMemory, template.MemoryBytes)
+
+	// An existing node reports what its device plugins advertise
+	node := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "gpu-node-1",
+			Labels: map[string]string{v1alpha1.OfferingLabelKey: "gpu-1"},
+		},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:              resource.MustParse("7800m"),
+				corev1.ResourceMemory:           resource.MustParse("30Gi"),
+				corev1.ResourceEphemeralStorage: resource.MustParse("85Gi"),
+				"nvidia.com/gpu":                resource.MustParse("2"),
+			},
+		},
+	}
+	template = analyzer.BuildNodeTemplate(ng, instanceType, []corev1.Node{node}, nil)
+	assert.Equal(t, int64(85)*1024*1024*1024, template.EphemeralStorageBytes)
+	assert.Equal(t, int64(2), template.ExtendedResources["nvidia.com/gpu"])
+	assert.Equal(t, int64(1024*1024*1024), template.ExtendedResources["hugepages-2Mi"])
+}
+
+func TestMakeScaleUpDecisionUsesBinpacking(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+
+	logger := zap.NewNop()
+	k8sClient := fakeClient.NewClientBuilder().WithScheme(scheme).Build()
+	analyzer := NewResourceAnalyzer(logger, nil)
+	watcher := NewEventWatcher(k8sClient, fake.NewSimpleClientset(), logger, nil)
+	controller := NewScaleUpController(k8sClient, analyzer, watcher, nil, logger)
+
+	ng := &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "ng-1", Namespace: "kube-system"},
+		Spec:       v1alpha1.NodeGroupSpec{MinNodes: 1, MaxNodes: 4, OfferingIDs: []string{"offering-1"}},
+		Status:     v1alpha1.NodeGroupStatus{DesiredNodes: 1, ReadyNodes: 1},
+	}
+	// Each pod needs most of a default 4 CPU node, so three nodes are needed
+	pods := newBinpackingPods(3, "3", "1Gi", nil)
+	match := NodeGroupMatch{NodeGroup: ng, MatchingPods: pods, Deficit: analyzer.podsDeficit(pods)}
+
+	decision, err := controller.makeScaleUpDecision(context.Background(), match)
+	require.NoError(t, err)
+	require.NotNil(t, decision)
+	assert.Equal(t, 3, decision.NodesNeeded)
+	assert.Equal(t, int32(1), decision.NodesToAdd, "sequential scaling by default")
+
+	// Concurrent scale-ups add the estimated nodes, capped by MaxNodes
+	controller.SetMaxConcurrentScaleUps(5)
+	decision, err = controller.makeScaleUpDecision(context.Background(), match)
+	require.NoError(t, err)
+	require.NotNil(t, decision)
+	assert.Equal(t, int32(3), decision.NodesToAdd)
+	assert.Equal(t, int32(4), decision.DesiredNodes)
+}
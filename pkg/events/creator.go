@@ -15,16 +15,25 @@ import (
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
 	vpsieclient "github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/client"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
 )
 
+// DefaultDynamicNodeGroupIdleTTL is how long a dynamic NodeGroup may run without
+// workload pods before it is deleted, unless the template sets another TTL
+const DefaultDynamicNodeGroupIdleTTL = 30 * time.Minute
+
 // DynamicNodeGroupCreator creates NodeGroups dynamically when no suitable managed NodeGroup exists.
 // Created NodeGroups are always marked with the managed label (autoscaler.vpsie.com/managed=true)
-// to ensure they are processed by the autoscaler.
+// to ensure they are processed by the autoscaler, and with the auto-managed annotation so they
+// are garbage-collected once idle.
 type DynamicNodeGroupCreator struct {
 	client      client.Client
 	vpsieClient *vpsieclient.Client
 	logger      *zap.Logger
 	template    *NodeGroupTemplate
+
+	// carbon is the optional carbon intensity source for datacenter selection
+	carbon cost.CarbonIntensitySource
 }
 
 // NodeGroupTemplate provides default values for dynamically created NodeGroups.
@@ -45,6 +54,14 @@ type NodeGroupTemplate struct {
 	// DefaultDatacenterID is the datacenter to use if not specified
 	DefaultDatacenterID string
 
+	// CandidateDatacenterIDs are the datacenters a NodeGroup may be created in.
+	// One is chosen by CarbonObjective; DefaultDatacenterID is used when empty.
+	CandidateDatacenterIDs []string
+
+	// CarbonObjective weighs datacenter carbon intensity against price
+	// ("cost", "balanced" or "low-carbon")
+	CarbonObjective string
+
 	// ResourceIdentifier is the VPSie Kubernetes cluster identifier
 	ResourceIdentifier string
 
@@ -87,6 +104,29 @@ type NodeGroupTemplate struct {
 
 	// SpotConfig defines default spot instance configuration
 	SpotConfig *v1alpha1.SpotInstanceConfig
+
+	// IdleTTL is how long a dynamic NodeGroup may run without workload pods
+	// before it is drained and deleted. Zero disables the garbage collection.
+	IdleTTL time.Duration
+}
+
+// PodGroup is a set of pending pods with compatible scheduling requirements
+// that one dynamic NodeGroup can serve
+type PodGroup struct {
+	// Pods are the pending pods in the group
+	Pods []*corev1.Pod
+
+	// NodeSelector is the union of the pods' node selectors
+	NodeSelector map[string]string
+
+	// Taints are the taints required by the pods' tolerations; every pod in
+	// the group tolerates all of them
+	Taints []corev1.Taint
+
+	// CPU and Memory are the largest requests of a single pod, the minimum
+	// size of a node in the NodeGroup
+	CPU    resource.Quantity
+	Memory resource.Quantity
 }
 
 // DefaultNodeGroupTemplate returns a template with sensible defaults
@@ -102,6 +142,7 @@ func DefaultNodeGroupTemplate() *NodeGroupTemplate {
 		OSImageID:           "",
 		KubernetesVersion:   "",
 		KubeSizeID:          0,
+		IdleTTL:             DefaultDynamicNodeGroupIdleTTL,
 	}
 }
 
@@ -151,7 +192,7 @@ func (c *DynamicNodeGroupCreator) FindSuitableNodeGroup(
 // nodeGroupMatchesPod checks if a NodeGroup can satisfy a pod's scheduling requirements
 func (c *DynamicNodeGroupCreator) nodeGroupMatchesPod(ng *v1alpha1.NodeGroup, pod *corev1.Pod) bool {
 	// Check if NodeGroup has capacity
-	if ng.Status.DesiredNodes >= ng.Spec.MaxNodes {
+	if ng.Status.DesiredNodes >= v1alpha1.EffectiveMaxNodes(ng) {
 		return false
 	}
 
@@ -222,18 +263,40 @@ func (c *DynamicNodeGroupCreator) CreateNodeGroupForPod(
 	ctx context.Context,
 	pod *corev1.Pod,
 	namespace string,
+) (*v1alpha1.NodeGroup, error) {
+	return c.CreateNodeGroupForPods(ctx, c.newPodGroup(pod), namespace)
+}
+
+// CreateNodeGroupForPods creates a new NodeGroup for a group of pods with
+// compatible requirements. Its labels and taints come from the union of the
+// pods' node selectors and tolerations, and its size is the cheapest offering
+// that fits the largest pod. A node selector on the datacenter label pins the
+// datacenter. The NodeGroup is marked managed and auto-managed, with the
+// template's idle TTL.
+func (c *DynamicNodeGroupCreator) CreateNodeGroupForPods(
+	ctx context.Context,
+	group *PodGroup,
+	namespace string,
 ) (*v1alpha1.NodeGroup, error) {
 	// Validate template before creating NodeGroup
 	if err := c.ValidateTemplate(); err != nil {
 		return nil, err
 	}
+	if group == nil || len(group.Pods) == 0 {
+		return nil, fmt.Errorf("no pods to create a NodeGroup for")
+	}
 
 	if namespace == "" {
 		namespace = c.template.Namespace
 	}
 
-	// Select optimal KubeSizeID based on pod's resource requirements
-	kubeSizeID, err := c.SelectOptimalKubeSizeID(ctx, pod, c.template.DefaultDatacenterID)
+	datacenterID, pinned := group.NodeSelector[v1alpha1.DatacenterLabelKey]
+	if !pinned {
+		datacenterID = c.selectDatacenter(ctx, group.CPU, group.Memory)
+	}
+
+	// Select optimal KubeSizeID based on the group's resource requirements
+	kubeSizeID, err := c.selectKubeSizeID(ctx, group.Pods[0].Name, group.CPU, group.Memory, datacenterID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to select KubeSizeID: %w", err)
 	}
@@ -241,16 +304,19 @@ func (c *DynamicNodeGroupCreator) CreateNodeGroupForPod(
 	// Generate unique name
 	name := c.generateNodeGroupName()
 
-	c.logger.Info("Creating dynamic NodeGroup for pod",
+	c.logger.Info("Creating dynamic NodeGroup for pods",
 		zap.String("nodeGroup", name),
-		zap.String("pod", pod.Name),
+		zap.String("pod", group.Pods[0].Name),
+		zap.Int("pods", len(group.Pods)),
 		zap.String("namespace", namespace),
+		zap.String("datacenterID", datacenterID),
 		zap.Int("kubeSizeID", kubeSizeID),
 	)
 
-	// Build NodeGroup spec based on pod requirements
-	spec := c.buildNodeGroupSpec(pod)
-	// Override with dynamically selected KubeSizeID
+	// Build NodeGroup spec based on the pods' requirements
+	spec := c.buildNodeGroupSpec(group)
+	// Override with dynamically selected datacenter and KubeSizeID
+	spec.DatacenterID = datacenterID
 	spec.KubeSizeID = kubeSizeID
 
 	ng := &v1alpha1.NodeGroup{
@@ -260,9 +326,15 @@ func (c *DynamicNodeGroupCreator) CreateNodeGroupForPod(
 			Labels: map[string]string{
 				v1alpha1.ManagedLabelKey: v1alpha1.ManagedLabelValue,
 			},
+			Annotations: map[string]string{
+				v1alpha1.AutoManagedAnnotationKey: v1alpha1.AutoManagedAnnotationValue,
+			},
 		},
 		Spec: spec,
 	}
+	if c.template.IdleTTL > 0 {
+		ng.Annotations[v1alpha1.IdleTTLAnnotationKey] = c.template.IdleTTL.String()
+	}
 
 	// Create the NodeGroup
 	if err := c.client.Create(ctx, ng); err != nil {
@@ -278,11 +350,104 @@ func (c *DynamicNodeGroupCreator) CreateNodeGroupForPod(
 		zap.Int32("minNodes", spec.MinNodes),
 		zap.Int32("maxNodes", spec.MaxNodes),
 		zap.Int("kubeSizeID", spec.KubeSizeID),
+		zap.Duration("idleTTL", c.template.IdleTTL),
 	)
 
 	return ng, nil
 }
 
+// GroupPodsByRequirements partitions pending pods into groups with compatible
+// requirements, in order of first appearance. A pod joins the first group
+// whose node selectors do not conflict with its own and whose required taints
+// it tolerates while the group's pods tolerate its required taints. Pods with
+// a node selector are not grouped with pods without one, so generic pods do
+// not land on specialised nodes.
+func (c *DynamicNodeGroupCreator) GroupPodsByRequirements(pods []corev1.Pod) []*PodGroup {
+	var groups []*PodGroup
+	for i := range pods {
+		pod := &pods[i]
+		candidate := c.newPodGroup(pod)
+
+		joined := false
+		for _, group := range groups {
+			if c.podGroupsCompatible(group, candidate) {
+				group.merge(candidate)
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			groups = append(groups, candidate)
+		}
+	}
+	return groups
+}
+
+// newPodGroup returns the group of a single pod
+func (c *DynamicNodeGroupCreator) newPodGroup(pod *corev1.Pod) *PodGroup {
+	cpu, memory := c.calculatePodResources(pod)
+	group := &PodGroup{
+		Pods:         []*corev1.Pod{pod},
+		NodeSelector: make(map[string]string, len(pod.Spec.NodeSelector)),
+		Taints:       c.extractRequiredTaints(pod.Spec.Tolerations),
+		CPU:          cpu,
+		Memory:       memory,
+	}
+	for key, value := range pod.Spec.NodeSelector {
+		group.NodeSelector[key] = value
+	}
+	return group
+}
+
+// podGroupsCompatible reports whether two groups' pods can share a NodeGroup
+func (c *DynamicNodeGroupCreator) podGroupsCompatible(a, b *PodGroup) bool {
+	if (len(a.NodeSelector) == 0) != (len(b.NodeSelector) == 0) {
+		return false
+	}
+	for key, value := range b.NodeSelector {
+		if existing, ok := a.NodeSelector[key]; ok && existing != value {
+			return false
+		}
+	}
+	for _, pod := range a.Pods {
+		if !c.podToleratesTaints(pod, b.Taints) {
+			return false
+		}
+	}
+	for _, pod := range b.Pods {
+		if !c.podToleratesTaints(pod, a.Taints) {
+			return false
+		}
+	}
+	return true
+}
+
+// merge adds another group's pods and requirements to the group
+func (g *PodGroup) merge(other *PodGroup) {
+	g.Pods = append(g.Pods, other.Pods...)
+	for key, value := range other.NodeSelector {
+		g.NodeSelector[key] = value
+	}
+	for _, taint := range other.Taints {
+		duplicate := false
+		for i := range g.Taints {
+			if g.Taints[i].MatchTaint(&taint) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			g.Taints = append(g.Taints, taint)
+		}
+	}
+	if other.CPU.Cmp(g.CPU) > 0 {
+		g.CPU = other.CPU
+	}
+	if other.Memory.Cmp(g.Memory) > 0 {
+		g.Memory = other.Memory
+	}
+}
+
 // generateNodeGroupName generates a unique name for a dynamically created NodeGroup.
 // Uses UnixNano timestamp to prevent collisions when multiple NodeGroups are created
 // within the same second.
@@ -296,8 +461,8 @@ func (c *DynamicNodeGroupCreator) generateNodeGroupName() string {
 	return fmt.Sprintf("auto-%s-%d", datacenter, timestamp%10000000000)
 }
 
-// buildNodeGroupSpec builds a NodeGroup spec based on pod requirements and template defaults
-func (c *DynamicNodeGroupCreator) buildNodeGroupSpec(pod *corev1.Pod) v1alpha1.NodeGroupSpec {
+// buildNodeGroupSpec builds a NodeGroup spec based on the pods' requirements and template defaults
+func (c *DynamicNodeGroupCreator) buildNodeGroupSpec(group *PodGroup) v1alpha1.NodeGroupSpec {
 	spec := v1alpha1.NodeGroupSpec{
 		MinNodes:           c.template.MinNodes,
 		MaxNodes:           c.template.MaxNodes,
@@ -341,8 +506,8 @@ func (c *DynamicNodeGroupCreator) buildNodeGroupSpec(pod *corev1.Pod) v1alpha1.N
 		spec.Labels[key] = value
 	}
 
-	// Then, overlay pod's node selector labels (pod-specific labels take precedence)
-	for key, value := range pod.Spec.NodeSelector {
+	// Then, overlay the pods' node selector labels (pod-specific labels take precedence)
+	for key, value := range group.NodeSelector {
 		spec.Labels[key] = value
 	}
 
@@ -352,11 +517,10 @@ func (c *DynamicNodeGroupCreator) buildNodeGroupSpec(pod *corev1.Pod) v1alpha1.N
 		copy(spec.Taints, c.template.Taints)
 	}
 
-	// Extract tolerations that might indicate required taints
-	// Note: We only add taints for explicitly requested tolerations, not wildcard ones
-	podTaints := c.extractRequiredTaints(pod.Spec.Tolerations)
-	if len(podTaints) > 0 {
-		spec.Taints = append(spec.Taints, podTaints...)
+	// Add taints for the tolerations the pods explicitly request
+	// Note: wildcard and system tolerations were skipped when grouping
+	if len(group.Taints) > 0 {
+		spec.Taints = append(spec.Taints, group.Taints...)
 	}
 
 	return spec
@@ -416,6 +580,91 @@ func isSystemToleration(key string) bool {
 	return false
 }
 
+// SetCarbonIntensity sets the carbon intensity source used to choose between
+// CandidateDatacenterIDs. A nil source always uses DefaultDatacenterID.
+func (c *DynamicNodeGroupCreator) SetCarbonIntensity(source cost.CarbonIntensitySource) {
+	c.carbon = source
+}
+
+// SelectDatacenter chooses the datacenter for a new NodeGroup. Each candidate
+// datacenter is represented by its cheapest K8s offer that fits the pod, and the
+// candidates are ranked by the template's CarbonObjective. Falls back to
+// DefaultDatacenterID when carbon-aware selection is not configured or no
+// candidate can be priced.
+func (c *DynamicNodeGroupCreator) SelectDatacenter(ctx context.Context, pod *corev1.Pod) string {
+	cpuRequest, memoryRequest := c.calculatePodResources(pod)
+	return c.selectDatacenter(ctx, cpuRequest, memoryRequest)
+}
+
+// selectDatacenter chooses the datacenter for a new NodeGroup whose nodes
+// must fit the given requests
+func (c *DynamicNodeGroupCreator) selectDatacenter(ctx context.Context, cpuRequest, memoryRequest resource.Quantity) string {
+	defaultDC := c.template.DefaultDatacenterID
+	if c.carbon == nil || c.vpsieClient == nil || len(c.template.CandidateDatacenterIDs) == 0 {
+		return defaultDC
+	}
+
+	objective, err := cost.ParseCarbonObjective(c.template.CarbonObjective)
+	if err != nil {
+		c.logger.Warn("Invalid carbon objective, using default datacenter", zap.Error(err))
+		return defaultDC
+	}
+
+	cpuMillis := cpuRequest.MilliValue()
+	memoryMB := memoryRequest.Value() / (1024 * 1024)
+
+	var candidates []cost.PlacementCandidate
+	for _, datacenterID := range c.template.CandidateDatacenterIDs {
+		offers, err := c.vpsieClient.ListK8sOffers(ctx, datacenterID)
+		if err != nil {
+			c.logger.Warn("Failed to fetch K8s offers for candidate datacenter",
+				zap.String("datacenterID", datacenterID),
+				zap.Error(err))
+			continue
+		}
+
+		var cheapest *vpsieclient.K8sOffer
+		for i := range offers {
+			offer := &offers[i]
+			if int64(offer.CPU*1000) < cpuMillis || int64(offer.RAM) < memoryMB {
+				continue
+			}
+			if cheapest == nil || offer.Price < cheapest.Price {
+				cheapest = offer
+			}
+		}
+		if cheapest == nil {
+			continue
+		}
+
+		candidates = append(candidates, cost.PlacementCandidate{
+			DatacenterID: datacenterID,
+			OfferingID:   fmt.Sprintf("%d", cheapest.ID),
+			Specs:        cost.ResourceSpecs{CPU: cheapest.CPU, MemoryMB: cheapest.RAM, DiskGB: cheapest.Disk},
+			MonthlyCost:  cheapest.Price,
+		})
+	}
+
+	if len(candidates) == 0 {
+		return defaultDC
+	}
+
+	best := cost.RankPlacements(c.carbon, objective, candidates)[0]
+
+	datacenter, _ := metrics.SanitizeLabel(best.DatacenterID)
+	metrics.CarbonPlacementDecisionsTotal.WithLabelValues("dynamic_nodegroup", string(objective), datacenter).Inc()
+
+	c.logger.Info("Selected datacenter for dynamic NodeGroup",
+		zap.String("datacenterID", best.DatacenterID),
+		zap.String("objective", string(objective)),
+		zap.Float64("monthlyCost", best.MonthlyCost),
+		zap.Float64("emissionsGramsPerHour", best.EmissionsPerHour),
+		zap.Bool("emissionsKnown", best.EmissionsKnown),
+	)
+
+	return best.DatacenterID
+}
+
 // SetTemplate updates the template used for creating NodeGroups
 func (c *DynamicNodeGroupCreator) SetTemplate(template *NodeGroupTemplate) {
 	if template != nil {
@@ -432,6 +681,19 @@ func (c *DynamicNodeGroupCreator) SelectOptimalKubeSizeID(
 	ctx context.Context,
 	pod *corev1.Pod,
 	datacenterID string,
+) (int, error) {
+	cpuRequest, memoryRequest := c.calculatePodResources(pod)
+	return c.selectKubeSizeID(ctx, pod.Name, cpuRequest, memoryRequest, datacenterID)
+}
+
+// selectKubeSizeID selects the cheapest unused KubeSizeID that fits the
+// requests. Sizes of NodeGroups whose VPSie node group is not created yet,
+// such as those created earlier in the same scale-up, count as in use.
+func (c *DynamicNodeGroupCreator) selectKubeSizeID(
+	ctx context.Context,
+	podName string,
+	cpuRequest, memoryRequest resource.Quantity,
+	datacenterID string,
 ) (int, error) {
 	if c.vpsieClient == nil {
 		// Fallback to template's static KubeSizeID if no VPSie client
@@ -479,12 +741,19 @@ func (c *DynamicNodeGroupCreator) SelectOptimalKubeSizeID(
 			}
 		}
 	}
-
-	// Calculate pod's resource requirements
-	cpuRequest, memoryRequest := c.calculatePodResources(pod)
+	nodeGroups := &v1alpha1.NodeGroupList{}
+	if err := c.client.List(ctx, nodeGroups); err != nil {
+		c.logger.Warn("Failed to list NodeGroups, proceeding without their sizes", zap.Error(err))
+	} else {
+		for i := range nodeGroups.Items {
+			if size := nodeGroups.Items[i].Spec.KubeSizeID; size > 0 {
+				usedSizes[size] = true
+			}
+		}
+	}
 
 	c.logger.Info("Selecting optimal KubeSizeID for pod resources",
-		zap.String("pod", pod.Name),
+		zap.String("pod", podName),
 		zap.String("cpuRequest", cpuRequest.String()),
 		zap.String("memoryRequest", memoryRequest.String()),
 		zap.Int("availableOffers", len(offers)),
@@ -594,6 +863,9 @@ func (c *DynamicNodeGroupCreator) autoscalerConfigToTemplate(config *v1alpha1.Au
 		SSHKeyIDs:           defaults.SSHKeyIDs,
 		Tags:                defaults.Tags,
 		Notes:               defaults.Notes,
+
+		CandidateDatacenterIDs: defaults.CandidateDatacenterIDs,
+		CarbonObjective:        defaults.CarbonObjective,
 	}
 
 	// Apply defaults if not set
@@ -606,6 +878,10 @@ func (c *DynamicNodeGroupCreator) autoscalerConfigToTemplate(config *v1alpha1.Au
 	if template.MaxNodes == 0 {
 		template.MaxNodes = 10
 	}
+	template.IdleTTL = DefaultDynamicNodeGroupIdleTTL
+	if defaults.IdleTTL != nil {
+		template.IdleTTL = defaults.IdleTTL.Duration
+	}
 
 	// Copy policy configurations (use DeepCopy to avoid reference issues)
 	if defaults.ScaleUpPolicy.StabilizationWindowSeconds > 0 ||
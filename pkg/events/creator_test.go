@@ -497,3 +497,137 @@ func TestValidateTemplate(t *testing.T) {
 		}
 	})
 }
+
+func TestGroupPodsByRequirements(t *testing.T) {
+	creator := NewDynamicNodeGroupCreator(nil, nil, zap.NewNop(), nil)
+
+	newPod := func(name, cpu string, selector map[string]string, tolerations ...corev1.Toleration) corev1.Pod {
+		pod := newPredicatePod(name, "", cpu, "1Gi", nil)
+		pod.Spec.NodeSelector = selector
+		pod.Spec.Tolerations = tolerations
+		return pod
+	}
+	gpuToleration := corev1.Toleration{
+		Key:      "gpu",
+		Operator: corev1.TolerationOpExists,
+		Effect:   corev1.TaintEffectNoSchedule,
+	}
+
+	pods := []corev1.Pod{
+		newPod("web-1", "500m", nil),
+		newPod("train-1", "2", map[string]string{"accelerator": "nvidia"}, gpuToleration),
+		newPod("web-2", "3", nil),
+		newPod("train-2", "1", map[string]string{"accelerator": "nvidia", "tier": "batch"}, gpuToleration),
+		// Conflicts with the accelerator of the training pods
+		newPod("infer-1", "1", map[string]string{"accelerator": "amd"}),
+		// Tolerates the GPU taint but does not request it, so it shares the web group
+		newPod("web-3", "1", nil, corev1.Toleration{Operator: corev1.TolerationOpExists}),
+	}
+
+	groups := creator.GroupPodsByRequirements(pods)
+	if len(groups) != 3 {
+		t.Fatalf("Expected 3 groups, got %d", len(groups))
+	}
+
+	web := groups[0]
+	if len(web.Pods) != 3 || web.Pods[1].Name != "web-2" || web.Pods[2].Name != "web-3" {
+		t.Errorf("Expected web pods in the first group, got %d pods", len(web.Pods))
+	}
+	if len(web.NodeSelector) != 0 || len(web.Taints) != 0 {
+		t.Errorf("Expected generic web group, got selector %v taints %v", web.NodeSelector, web.Taints)
+	}
+	if web.CPU.String() != "3" {
+		t.Errorf("Expected largest CPU request 3, got %s", web.CPU.String())
+	}
+
+	train := groups[1]
+	if len(train.Pods) != 2 {
+		t.Errorf("Expected 2 training pods, got %d", len(train.Pods))
+	}
+	if train.NodeSelector["accelerator"] != "nvidia" || train.NodeSelector["tier"] != "batch" {
+		t.Errorf("Expected union of node selectors, got %v", train.NodeSelector)
+	}
+	if len(train.Taints) != 1 || train.Taints[0].Key != "gpu" {
+		t.Errorf("Expected one gpu taint, got %v", train.Taints)
+	}
+	if train.CPU.String() != "2" {
+		t.Errorf("Expected largest CPU request 2, got %s", train.CPU.String())
+	}
+
+	if groups[2].Pods[0].Name != "infer-1" {
+		t.Errorf("Expected conflicting pod in its own group, got %s", groups[2].Pods[0].Name)
+	}
+}
+
+func TestCreateNodeGroupForPods(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	ctx := context.Background()
+
+	newTemplate := func() *NodeGroupTemplate {
+		return &NodeGroupTemplate{
+			Namespace:           "kube-system",
+			MinNodes:            1,
+			MaxNodes:            5,
+			DefaultDatacenterID: "dc-default",
+			DefaultOfferingIDs:  []string{"offering-1"},
+			ResourceIdentifier:  "test-cluster",
+			KubeSizeID:          1,
+			IdleTTL:             DefaultDynamicNodeGroupIdleTTL,
+		}
+	}
+
+	t.Run("Marks NodeGroup auto-managed with idle TTL", func(t *testing.T) {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+		creator := NewDynamicNodeGroupCreator(fakeClient, nil, zap.NewNop(), newTemplate())
+
+		pods := []corev1.Pod{newPredicatePod("pod-1", "", "1", "1Gi", nil)}
+		groups := creator.GroupPodsByRequirements(pods)
+
+		ng, err := creator.CreateNodeGroupForPods(ctx, groups[0], "")
+		if err != nil {
+			t.Fatalf("Failed to create NodeGroup: %v", err)
+		}
+		if !v1alpha1.IsAutoManagedNodeGroup(ng) {
+			t.Error("Expected auto-managed annotation to be set")
+		}
+		if ttl, ok := v1alpha1.NodeGroupIdleTTL(ng); !ok || ttl != DefaultDynamicNodeGroupIdleTTL {
+			t.Errorf("Expected idle TTL %s, got %s", DefaultDynamicNodeGroupIdleTTL, ttl)
+		}
+	})
+
+	t.Run("Zero idle TTL disables garbage collection", func(t *testing.T) {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+		template := newTemplate()
+		template.IdleTTL = 0
+		creator := NewDynamicNodeGroupCreator(fakeClient, nil, zap.NewNop(), template)
+
+		ng, err := creator.CreateNodeGroupForPod(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-1"}}, "")
+		if err != nil {
+			t.Fatalf("Failed to create NodeGroup: %v", err)
+		}
+		if !v1alpha1.IsAutoManagedNodeGroup(ng) {
+			t.Error("Expected auto-managed annotation to be set")
+		}
+		if _, ok := v1alpha1.NodeGroupIdleTTL(ng); ok {
+			t.Error("Expected no idle TTL")
+		}
+	})
+
+	t.Run("Datacenter node selector pins the datacenter", func(t *testing.T) {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+		creator := NewDynamicNodeGroupCreator(fakeClient, nil, zap.NewNop(), newTemplate())
+
+		pod := newPredicatePod("pod-1", "", "1", "1Gi", nil)
+		pod.Spec.NodeSelector = map[string]string{v1alpha1.DatacenterLabelKey: "dc-pinned"}
+
+		ng, err := creator.CreateNodeGroupForPod(ctx, &pod, "")
+		if err != nil {
+			t.Fatalf("Failed to create NodeGroup: %v", err)
+		}
+		if ng.Spec.DatacenterID != "dc-pinned" {
+			t.Errorf("Expected datacenter dc-pinned, got %s", ng.Spec.DatacenterID)
+		}
+	})
+}
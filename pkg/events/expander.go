@@ -0,0 +1,308 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"sort"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
+)
+
+// ExpanderStrategy names a strategy for choosing between NodeGroups that can
+// schedule the same pending pods
+type ExpanderStrategy string
+
+const (
+	// ExpanderLeastWaste picks the NodeGroup leaving the least unused CPU and memory
+	ExpanderLeastWaste ExpanderStrategy = "least-waste"
+
+	// ExpanderCheapest picks the NodeGroup with the lowest cost per scheduled pod
+	ExpanderCheapest ExpanderStrategy = "cheapest"
+
+	// ExpanderMostPods picks the NodeGroup that schedules the most pending pods
+	ExpanderMostPods ExpanderStrategy = "most-pods"
+
+	// ExpanderRandom picks a NodeGroup at random
+	ExpanderRandom ExpanderStrategy = "random"
+
+	// ExpanderPriority picks the NodeGroup with the highest user-assigned priority
+	ExpanderPriority ExpanderStrategy = "priority"
+
+	// DefaultExpander is the strategy used when none is configured
+	DefaultExpander = ExpanderLeastWaste
+
+	// PriorityConfigMapKey is the ConfigMap data key holding NodeGroup priorities
+	PriorityConfigMapKey = "priorities"
+
+	// DefaultPriorityConfigMapName is the default name of the priority expander ConfigMap
+	DefaultPriorityConfigMapName = "vpsie-autoscaler-priority-expander"
+
+	// DefaultPriorityConfigMapNamespace is the default namespace of the priority expander ConfigMap
+	DefaultPriorityConfigMapNamespace = "kube-system"
+)
+
+// ExpansionOption is a viable scale-up of one NodeGroup for a set of pending pods
+type ExpansionOption struct {
+	// NodeGroup is the NodeGroup that would be scaled up
+	NodeGroup *v1alpha1.NodeGroup
+
+	// Pods are the pending pods the NodeGroup can schedule
+	Pods []*corev1.Pod
+
+	// Deficit is the total resource request of Pods
+	Deficit ResourceDeficit
+
+	// InstanceType is the offering new nodes would use
+	InstanceType v1alpha1.InstanceTypeInfo
+
+	// Template is the capacity of a new node available to Pods
+	Template NodeTemplate
+
+	// NodesNeeded is the estimated number of nodes needed to schedule Pods
+	NodesNeeded int
+}
+
+// Expander chooses the NodeGroup to scale up when several can schedule the
+// same pending pods. Options are ordered by match score, and implementations
+// break ties by keeping that order.
+type Expander interface {
+	// Strategy returns the strategy the expander implements
+	Strategy() ExpanderStrategy
+
+	// BestOption returns the option to scale up, or nil if options is empty
+	BestOption(ctx context.Context, options []ExpansionOption) *ExpansionOption
+}
+
+// NewExpander creates the expander for a strategy. The calculator is used by
+// the cheapest expander and may be nil; priorityConfigMap locates the priority
+// expander's ConfigMap.
+func NewExpander(
+	strategy ExpanderStrategy,
+	k8sClient client.Client,
+	calculator *cost.Calculator,
+	priorityConfigMap types.NamespacedName,
+	logger *zap.Logger,
+) (Expander, error) {
+	switch strategy {
+	case "", ExpanderLeastWaste:
+		return leastWasteExpander{}, nil
+	case ExpanderCheapest:
+		return &cheapestExpander{calculator: calculator, logger: logger.Named("cheapest-expander")}, nil
+	case ExpanderMostPods:
+		return mostPodsExpander{}, nil
+	case ExpanderRandom:
+		return randomExpander{}, nil
+	case ExpanderPriority:
+		if priorityConfigMap.Name == "" {
+			priorityConfigMap.Name = DefaultPriorityConfigMapName
+		}
+		if priorityConfigMap.Namespace == "" {
+			priorityConfigMap.Namespace = DefaultPriorityConfigMapNamespace
+		}
+		return &priorityExpander{
+			client:    k8sClient,
+			configMap: priorityConfigMap,
+			logger:    logger.Named("priority-expander"),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown expander strategy %q", strategy)
+	}
+}
+
+// bestByScore returns the option with the lowest score, keeping option order on ties
+func bestByScore(options []ExpansionOption, score func(*ExpansionOption) float64) *ExpansionOption {
+	var best *ExpansionOption
+	bestScore := math.Inf(1)
+	for i := range options {
+		if s := score(&options[i]); best == nil || s < bestScore {
+			best = &options[i]
+			bestScore = s
+		}
+	}
+	return best
+}
+
+// leastWasteExpander picks the option whose new nodes would have the smallest
+// fraction of CPU and memory left unused after scheduling the pods
+type leastWasteExpander struct{}
+
+func (leastWasteExpander) Strategy() ExpanderStrategy { return ExpanderLeastWaste }
+
+func (leastWasteExpander) BestOption(_ context.Context, options []ExpansionOption) *ExpansionOption {
+	return bestByScore(options, func(o *ExpansionOption) float64 {
+		nodes := o.NodesNeeded
+		if nodes < 1 {
+			nodes = 1
+		}
+		cpuCapacity := float64(o.Template.CPUMillis) * float64(nodes)
+		memoryCapacity := float64(o.Template.MemoryBytes) * float64(nodes)
+		if cpuCapacity <= 0 || memoryCapacity <= 0 {
+			return math.Inf(1)
+		}
+
+		cpuWaste := (cpuCapacity - float64(o.Deficit.CPU.MilliValue())) / cpuCapacity
+		memoryWaste := (memoryCapacity - float64(o.Deficit.Memory.Value())) / memoryCapacity
+		return math.Max(cpuWaste, 0) + math.Max(memoryWaste, 0)
+	})
+}
+
+// cheapestExpander picks the option with the lowest monthly cost of its new
+// nodes per pod scheduled. Options without a known price rank last.
+type cheapestExpander struct {
+	calculator *cost.Calculator
+	logger     *zap.Logger
+}
+
+func (e *cheapestExpander) Strategy() ExpanderStrategy { return ExpanderCheapest }
+
+func (e *cheapestExpander) BestOption(ctx context.Context, options []ExpansionOption) *ExpansionOption {
+	return bestByScore(options, func(o *ExpansionOption) float64 {
+		if e.calculator == nil || len(o.Pods) == 0 {
+			return math.Inf(1)
+		}
+
+		offeringCost, err := e.calculator.GetOfferingCost(ctx, o.InstanceType.OfferingID)
+		if err != nil {
+			e.logger.Debug("Failed to get offering cost, ranking NodeGroup last",
+				zap.String("nodeGroup", o.NodeGroup.Name),
+				zap.String("offeringID", o.InstanceType.OfferingID),
+				zap.Error(err),
+			)
+			return math.Inf(1)
+		}
+
+		nodes := o.NodesNeeded
+		if nodes < 1 {
+			nodes = 1
+		}
+		return offeringCost.MonthlyCost * float64(nodes) / float64(len(o.Pods))
+	})
+}
+
+// mostPodsExpander picks the option that schedules the most pending pods
+type mostPodsExpander struct{}
+
+func (mostPodsExpander) Strategy() ExpanderStrategy { return ExpanderMostPods }
+
+func (mostPodsExpander) BestOption(_ context.Context, options []ExpansionOption) *ExpansionOption {
+	return bestByScore(options, func(o *ExpansionOption) float64 {
+		return -float64(len(o.Pods))
+	})
+}
+
+// randomExpander picks an option at random
+type randomExpander struct{}
+
+func (randomExpander) Strategy() ExpanderStrategy { return ExpanderRandom }
+
+func (randomExpander) BestOption(_ context.Context, options []ExpansionOption) *ExpansionOption {
+	if len(options) == 0 {
+		return nil
+	}
+	return &options[rand.Intn(len(options))]
+}
+
+// priorityExpander picks the option whose NodeGroup name matches the highest
+// priority in a ConfigMap. The ConfigMap's "priorities" key maps priorities to
+// lists of NodeGroup name regular expressions, for example:
+//
+//	priorities: |
+//	  10:
+//	    - .*-spot-.*
+//	  50:
+//	    - ng-reserved
+//
+// Options matching no pattern are only chosen when no option matches one. If the
+// ConfigMap cannot be read all options are equal and the first is chosen.
+type priorityExpander struct {
+	client    client.Client
+	configMap types.NamespacedName
+	logger    *zap.Logger
+}
+
+func (e *priorityExpander) Strategy() ExpanderStrategy { return ExpanderPriority }
+
+func (e *priorityExpander) BestOption(ctx context.Context, options []ExpansionOption) *ExpansionOption {
+	if len(options) == 0 {
+		return nil
+	}
+
+	priorities, err := e.loadPriorities(ctx)
+	if err != nil {
+		e.logger.Warn("Failed to load NodeGroup priorities, falling back to match score",
+			zap.String("configMap", e.configMap.String()),
+			zap.Error(err),
+		)
+		return &options[0]
+	}
+
+	return bestByScore(options, func(o *ExpansionOption) float64 {
+		for _, p := range priorities {
+			for _, re := range p.patterns {
+				if re.MatchString(o.NodeGroup.Name) {
+					return -float64(p.priority)
+				}
+			}
+		}
+		return math.Inf(1)
+	})
+}
+
+// nodeGroupPriority is a priority and the NodeGroup name patterns assigned to it
+type nodeGroupPriority struct {
+	priority int
+	patterns []*regexp.Regexp
+}
+
+// loadPriorities reads the priority ConfigMap, returning priorities highest first
+func (e *priorityExpander) loadPriorities(ctx context.Context) ([]nodeGroupPriority, error) {
+	cm := &corev1.ConfigMap{}
+	if err := e.client.Get(ctx, e.configMap, cm); err != nil {
+		return nil, fmt.Errorf("failed to get ConfigMap: %w", err)
+	}
+
+	data, ok := cm.Data[PriorityConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap has no %q key", PriorityConfigMapKey)
+	}
+
+	return parsePriorities(data)
+}
+
+// parsePriorities parses a priority expander document, returning priorities highest first
+func parsePriorities(data string) ([]nodeGroupPriority, error) {
+	raw := map[int][]string{}
+	if err := yaml.Unmarshal([]byte(data), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse priorities: %w", err)
+	}
+
+	priorities := make([]nodeGroupPriority, 0, len(raw))
+	for priority, expressions := range raw {
+		p := nodeGroupPriority{priority: priority}
+		for _, expr := range expressions {
+			// Anchor patterns so "ng-a" does not also match "ng-a-spot"
+			re, err := regexp.Compile("^(?:" + expr + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid NodeGroup pattern %q for priority %d: %w", expr, priority, err)
+			}
+			p.patterns = append(p.patterns, re)
+		}
+		priorities = append(priorities, p)
+	}
+
+	sort.Slice(priorities, func(i, j int) bool {
+		return priorities[i].priority > priorities[j].priority
+	})
+
+	return priorities, nil
+}
@@ -0,0 +1,222 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+)
+
+func newExpansionOption(name string, pods, cpu, memoryMB int, deficitCPU, deficitMemory string) ExpansionOption {
+	podList := make([]*corev1.Pod, 0, pods)
+	for i := 0; i < pods; i++ {
+		podList = append(podList, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name + "-pod", Namespace: "default"}})
+	}
+	return ExpansionOption{
+		NodeGroup: &v1alpha1.NodeGroup{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"}},
+		Pods:      podList,
+		Deficit: ResourceDeficit{
+			CPU:    resource.MustParse(deficitCPU),
+			Memory: resource.MustParse(deficitMemory),
+			Pods:   pods,
+		},
+		InstanceType: v1alpha1.InstanceTypeInfo{OfferingID: name + "-offering", CPU: cpu, MemoryMB: memoryMB},
+		Template: NodeTemplate{
+			OfferingID:  name + "-offering",
+			CPUMillis:   int64(cpu) * 1000,
+			MemoryBytes: int64(memoryMB) * 1024 * 1024,
+			MaxPods:     DefaultMaxPodsPerNode,
+		},
+		NodesNeeded: 1,
+	}
+}
+
+func TestExpanders(t *testing.T) {
+	options := []ExpansionOption{
+		newExpansionOption("ng-large", 1, 16, 32768, "2", "4Gi"),
+		newExpansionOption("ng-small", 1, 2, 4096, "2", "4Gi"),
+		newExpansionOption("ng-many", 3, 8, 16384, "2", "4Gi"),
+	}
+
+	tests := []struct {
+		strategy ExpanderStrategy
+		expected string
+	}{
+		{strategy: ExpanderLeastWaste, expected: "ng-small"},
+		{strategy: ExpanderMostPods, expected: "ng-many"},
+		// Without a calculator no option has a price, so the first is chosen
+		{strategy: ExpanderCheapest, expected: "ng-large"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			expander, err := NewExpander(tt.strategy, nil, nil, types.NamespacedName{}, zap.NewNop())
+			require.NoError(t, err)
+			assert.Equal(t, tt.strategy, expander.Strategy())
+
+			best := expander.BestOption(context.Background(), options)
+			require.NotNil(t, best)
+			assert.Equal(t, tt.expected, best.NodeGroup.Name)
+		})
+	}
+
+	random, err := NewExpander(ExpanderRandom, nil, nil, types.NamespacedName{}, zap.NewNop())
+	require.NoError(t, err)
+	assert.NotNil(t, random.BestOption(context.Background(), options))
+	assert.Nil(t, random.BestOption(context.Background(), nil))
+
+	_, err = NewExpander("largest", nil, nil, types.NamespacedName{}, zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestPriorityExpander(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultPriorityConfigMapName, Namespace: DefaultPriorityConfigMapNamespace},
+		Data: map[string]string{
+			PriorityConfigMapKey: "10:\n  - .*-spot\n50:\n  - ng-reserved\n",
+		},
+	}
+	k8sClient := fakeClient.NewClientBuilder().WithScheme(scheme).WithObjects(configMap).Build()
+
+	expander, err := NewExpander(ExpanderPriority, k8sClient, nil, types.NamespacedName{}, zap.NewNop())
+	require.NoError(t, err)
+
+	options := []ExpansionOption{
+		newExpansionOption("ng-default", 1, 4, 8192, "1", "1Gi"),
+		newExpansionOption("ng-spot", 1, 4, 8192, "1", "1Gi"),
+		newExpansionOption("ng-reserved", 1, 4, 8192, "1", "1Gi"),
+	}
+
+	best := expander.BestOption(context.Background(), options)
+	require.NotNil(t, best)
+	assert.Equal(t, "ng-reserved", best.NodeGroup.Name)
+
+	// Patterns are anchored, so ng-reserved-b has no priority
+	options[2].NodeGroup.Name = "ng-reserved-b"
+	best = expander.BestOption(context.Background(), options)
+	require.NotNil(t, best)
+	assert.Equal(t, "ng-spot", best.NodeGroup.Name)
+
+	// Without the ConfigMap the first option is chosen
+	missing, err := NewExpander(ExpanderPriority, k8sClient, nil,
+		types.NamespacedName{Name: "missing", Namespace: "kube-system"}, zap.NewNop())
+	require.NoError(t, err)
+	best = missing.BestOption(context.Background(), options)
+	require.NotNil(t, best)
+	assert.Equal(t, "ng-default", best.NodeGroup.Name)
+
+	_, err = parsePriorities("10:\n  - \"[\"\n")
+	assert.Error(t, err)
+}
+
+func TestSelectScaleUpsChoosesOneNodeGroupPerPods(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	newNodeGroup := func(name string) *v1alpha1.NodeGroup {
+		return &v1alpha1.NodeGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"},
+			Spec:       v1alpha1.NodeGroupSpec{MinNodes: 1, MaxNodes: 10, OfferingIDs: []string{"offering-1"}},
+			Status:     v1alpha1.NodeGroupStatus{DesiredNodes: 1, ReadyNodes: 1},
+		}
+	}
+	newPod := func(name string) *corev1.Pod {
+		pod := newPredicatePod(name, "", "1", "1Gi", nil)
+		return &pod
+	}
+
+	podA, podB, podC := newPod("pod-a"), newPod("pod-b"), newPod("pod-c")
+
+	logger := zap.NewNop()
+	k8sClient := fakeClient.NewClientBuilder().WithScheme(scheme).Build()
+	analyzer := NewResourceAnalyzer(logger, nil)
+	watcher := NewEventWatcher(k8sClient, fake.NewSimpleClientset(), logger, nil)
+	controller := NewScaleUpController(k8sClient, analyzer, watcher, nil, logger)
+	require.NoError(t, controller.SetExpanderStrategy(ExpanderMostPods, types.NamespacedName{}))
+
+	matches := []NodeGroupMatch{
+		{NodeGroup: newNodeGroup("ng-1"), MatchingPods: []*corev1.Pod{podA}},
+		{NodeGroup: newNodeGroup("ng-2"), MatchingPods: []*corev1.Pod{podA, podB}},
+		{NodeGroup: newNodeGroup("ng-3"), MatchingPods: []*corev1.Pod{podB, podC}},
+	}
+	for i := range matches {
+		matches[i].Deficit = analyzer.podsDeficit(matches[i].MatchingPods)
+	}
+
+	decisions := controller.selectScaleUps(context.Background(), matches)
+
+	// ng-2 covers pod-a and pod-b, leaving only pod-c for ng-3; ng-1 is skipped
+	require.Len(t, decisions, 2)
+	assert.Equal(t, "ng-2", decisions[0].NodeGroup.Name)
+	assert.Equal(t, 2, decisions[0].MatchingPods)
+	assert.Equal(t, "ng-3", decisions[1].NodeGroup.Name)
+	assert.Equal(t, 1, decisions[1].MatchingPods)
+}
+
+func TestSelectScaleUpsResizesOptionsSharingPods(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	newNodeGroup := func(name string) *v1alpha1.NodeGroup {
+		return &v1alpha1.NodeGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"},
+			Spec:       v1alpha1.NodeGroupSpec{MinNodes: 1, MaxNodes: 10, OfferingIDs: []string{"offering-1"}},
+			Status:     v1alpha1.NodeGroupStatus{DesiredNodes: 1, ReadyNodes: 1},
+		}
+	}
+	// Each pod needs a node of the default instance type to itself
+	newPod := func(name, cpu string) *corev1.Pod {
+		pod := newPredicatePod(name, "", cpu, "1Gi", nil)
+		return &pod
+	}
+
+	podA, podB, podC, podD, podE := newPod("pod-a", "3"), newPod("pod-b", "3"), newPod("pod-c", "3"), newPod("pod-d", "3"), newPod("pod-e", "3")
+	podHuge := newPod("pod-huge", "16")
+
+	logger := zap.NewNop()
+	k8sClient := fakeClient.NewClientBuilder().WithScheme(scheme).Build()
+	analyzer := NewResourceAnalyzer(logger, nil)
+	watcher := NewEventWatcher(k8sClient, fake.NewSimpleClientset(), logger, nil)
+	controller := NewScaleUpController(k8sClient, analyzer, watcher, nil, logger)
+	controller.SetMaxConcurrentScaleUps(5)
+	require.NoError(t, controller.SetExpanderStrategy(ExpanderMostPods, types.NamespacedName{}))
+
+	matches := []NodeGroupMatch{
+		{NodeGroup: newNodeGroup("ng-1"), MatchingPods: []*corev1.Pod{podA, podB, podC, podD}},
+		{NodeGroup: newNodeGroup("ng-2"), MatchingPods: []*corev1.Pod{podC, podD, podE}},
+		{NodeGroup: newNodeGroup("ng-3"), MatchingPods: []*corev1.Pod{podC, podHuge}},
+	}
+	for i := range matches {
+		matches[i].Deficit = analyzer.podsDeficit(matches[i].MatchingPods)
+	}
+
+	decisions := controller.selectScaleUps(context.Background(), matches)
+
+	// ng-1 takes pod-a to pod-d. ng-2 is left with pod-e and adds one node
+	// rather than the three it needed for all its pods. ng-3 is left with a pod
+	// no node fits and is dropped.
+	require.Len(t, decisions, 2)
+	assert.Equal(t, "ng-1", decisions[0].NodeGroup.Name)
+	assert.Equal(t, int32(4), decisions[0].NodesToAdd)
+	assert.Equal(t, int32(5), decisions[0].DesiredNodes)
+	assert.Equal(t, "ng-2", decisions[1].NodeGroup.Name)
+	assert.Equal(t, 1, decisions[1].NodesNeeded)
+	assert.Equal(t, int32(1), decisions[1].NodesToAdd)
+	assert.Equal(t, int32(2), decisions[1].DesiredNodes)
+}
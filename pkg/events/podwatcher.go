@@ -0,0 +1,183 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// DetectionMode selects the source used to detect unschedulable pods
+type DetectionMode string
+
+const (
+	// DetectionModeEvents detects unschedulable pods from FailedScheduling events
+	DetectionModeEvents DetectionMode = "events"
+
+	// DetectionModePods detects unschedulable pods from their PodScheduled condition
+	DetectionModePods DetectionMode = "pods"
+
+	// DefaultPodResyncPeriod is how often pods that remain unschedulable are re-queued
+	DefaultPodResyncPeriod = 1 * time.Minute
+)
+
+// ParseDetectionMode parses a detection mode, defaulting to events when empty
+func ParseDetectionMode(value string) (DetectionMode, error) {
+	switch DetectionMode(value) {
+	case "", DetectionModeEvents:
+		return DetectionModeEvents, nil
+	case DetectionModePods:
+		return DetectionModePods, nil
+	default:
+		return "", fmt.Errorf("unknown pending pod detection mode %q (must be %q or %q)",
+			value, DetectionModeEvents, DetectionModePods)
+	}
+}
+
+// SetDetectionMode sets the source used to detect unschedulable pods.
+// Must be called before Start.
+func (w *EventWatcher) SetDetectionMode(mode DetectionMode) {
+	w.detectionMode = mode
+}
+
+// DetectionMode returns the source used to detect unschedulable pods
+func (w *EventWatcher) DetectionMode() DetectionMode {
+	return w.detectionMode
+}
+
+// startPodInformer creates an informer for pending pods that are not bound to a node.
+// The resync period re-queues pods that stay unschedulable, in the same way the
+// scheduler re-emits FailedScheduling events on every retry.
+func (w *EventWatcher) startPodInformer(ctx context.Context) error {
+	selector := fields.AndSelectors(
+		fields.OneTermEqualSelector("spec.nodeName", ""),
+		fields.OneTermEqualSelector("status.phase", string(corev1.PodPending)),
+	).String()
+
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(w.clientset, DefaultPodResyncPeriod,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = selector
+		}),
+	)
+	w.informer = informerFactory.Core().V1().Pods().Informer()
+
+	_, err := w.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			pod, ok := obj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			w.handlePod(nil, pod)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod, _ := oldObj.(*corev1.Pod)
+			pod, ok := newObj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			w.handlePod(oldPod, pod)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add pod handler: %w", err)
+	}
+
+	return nil
+}
+
+// handlePod buffers a pod whose PodScheduled condition reports it as unschedulable.
+// oldPod is nil for newly observed pods.
+func (w *EventWatcher) handlePod(oldPod, pod *corev1.Pod) {
+	if pod.Spec.NodeName != "" || pod.DeletionTimestamp != nil {
+		return
+	}
+
+	condition := unschedulableCondition(pod)
+	if condition == nil {
+		return
+	}
+
+	// Skip unrelated status updates; resyncs (same resource version) and new
+	// scheduling attempts are buffered
+	if oldPod != nil && oldPod.ResourceVersion != pod.ResourceVersion {
+		if oldCondition := unschedulableCondition(oldPod); oldCondition != nil &&
+			oldCondition.LastTransitionTime.Equal(&condition.LastTransitionTime) &&
+			oldCondition.Message == condition.Message {
+			return
+		}
+	}
+
+	// The message classification is refined against the cluster in classifyPodEvents
+	constraint := parseConstraint(condition.Message)
+
+	w.logger.Debug("Detected unschedulable pod",
+		zap.String("pod", pod.Name),
+		zap.String("namespace", pod.Namespace),
+		zap.String("constraint", string(constraint)),
+		zap.String("message", condition.Message),
+	)
+
+	w.bufferEvent(SchedulingEvent{
+		Pod:        pod,
+		Timestamp:  time.Now(),
+		Constraint: constraint,
+		Message:    condition.Message,
+	})
+}
+
+// classifyPodEvents replaces the message-derived constraint of each event with
+// the result of evaluating scheduling predicates against the current nodes. The
+// cluster is read through the manager's cached client, so classifying does not
+// LIST the API server on every tick. The snapshot is returned for reuse by the
+// scale-up handler. On failure to read the cluster the message-derived
+// constraints are kept and nil is returned.
+func (w *EventWatcher) classifyPodEvents(ctx context.Context, events []SchedulingEvent) *clusterSnapshot {
+	snapshot, err := loadClusterSnapshot(ctx, w.client)
+	if err != nil {
+		w.logger.Warn("Failed to read cluster for pod classification", zap.Error(err))
+		return nil
+	}
+
+	pods := make([]corev1.Pod, 0, len(events))
+	for i := range events {
+		pods = append(pods, *events[i].Pod)
+	}
+	if snapshot.volumes, err = LoadVolumeTopology(ctx, w.client, pods); err != nil {
+		w.logger.Warn("Failed to load volume topology for pod classification", zap.Error(err))
+	}
+
+	for i := range events {
+		result := snapshot.classify(events[i].Pod, events[i].Message)
+		events[i].Constraint = result.Constraint
+
+		w.logger.Debug("Classified unschedulable pod",
+			zap.String("pod", events[i].Pod.Name),
+			zap.String("namespace", events[i].Pod.Namespace),
+			zap.String("constraint", string(result.Constraint)),
+			zap.Any("nodeFailures", result.NodeFailures),
+			zap.Bool("fromMessage", result.FromMessage),
+		)
+	}
+
+	return snapshot
+}
+
+// unschedulableCondition returns the pod's PodScheduled condition if it reports
+// the pod as unschedulable, or nil otherwise
+func unschedulableCondition(pod *corev1.Pod) *corev1.PodCondition {
+	for i := range pod.Status.Conditions {
+		condition := &pod.Status.Conditions[i]
+		if condition.Type == corev1.PodScheduled &&
+			condition.Status == corev1.ConditionFalse &&
+			condition.Reason == corev1.PodReasonUnschedulable {
+			return condition
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newUnschedulablePod(name, message string) *corev1.Pod {
+	pod := newPredicatePod(name, "", "2", "1Gi", nil)
+	pod.ResourceVersion = "1"
+	pod.Status = corev1.PodStatus{
+		Phase: corev1.PodPending,
+		Conditions: []corev1.PodCondition{{
+			Type:               corev1.PodScheduled,
+			Status:             corev1.ConditionFalse,
+			Reason:             corev1.PodReasonUnschedulable,
+			Message:            message,
+			LastTransitionTime: metav1.NewTime(time.Now().Truncate(time.Second)),
+		}},
+	}
+	return &pod
+}
+
+func TestParseDetectionMode(t *testing.T) {
+	mode, err := ParseDetectionMode("")
+	require.NoError(t, err)
+	assert.Equal(t, DetectionModeEvents, mode)
+
+	mode, err = ParseDetectionMode("pods")
+	require.NoError(t, err)
+	assert.Equal(t, DetectionModePods, mode)
+
+	_, err = ParseDetectionMode("informer")
+	assert.Error(t, err)
+}
+
+func TestHandlePod(t *testing.T) {
+	watcher := NewEventWatcher(nil, fake.NewSimpleClientset(), zap.NewNop(), nil)
+	watcher.SetDetectionMode(DetectionModePods)
+
+	// Pods that are not unschedulable are ignored
+	scheduled := newPredicatePod("scheduled", "", "1", "1Gi", nil)
+	watcher.handlePod(nil, &scheduled)
+	assert.Empty(t, watcher.eventBuffer)
+
+	pod := newUnschedulablePod("pending", "0/3 nodes are available: 3 Insufficient cpu.")
+	watcher.handlePod(nil, pod)
+	require.Len(t, watcher.eventBuffer, 1)
+	assert.Nil(t, watcher.eventBuffer[0].Event)
+	assert.Equal(t, ConstraintCPU, watcher.eventBuffer[0].Constraint)
+
+	// Unrelated status updates are skipped
+	updated := pod.DeepCopy()
+	updated.ResourceVersion = "2"
+	watcher.handlePod(pod, updated)
+	assert.Len(t, watcher.eventBuffer, 1)
+
+	// Resyncs of a still unschedulable pod are buffered
+	watcher.handlePod(updated, updated)
+	assert.Len(t, watcher.eventBuffer, 2)
+
+	// New scheduling attempts are buffered
+	retried := updated.DeepCopy()
+	retried.ResourceVersion = "3"
+	retried.Status.Conditions[0].Message = "0/3 nodes are available: 3 Insufficient memory."
+	watcher.handlePod(updated, retried)
+	assert.Len(t, watcher.eventBuffer, 3)
+
+	// Bound pods are ignored
+	bound := retried.DeepCopy()
+	bound.Spec.NodeName = "node-1"
+	watcher.handlePod(nil, bound)
+	assert.Len(t, watcher.eventBuffer, 3)
+}
+
+func TestProcessEventsClassifiesPods(t *testing.T) {
+	node := newPredicateNode("node-1", "4", "8Gi", 110, nil)
+	running := newPredicatePod("running", "node-1", "100m", "128Mi", nil)
+	pending := newUnschedulablePod("pending", "0/1 nodes are available: 1 Insufficient cpu.")
+	pending.Spec.NodeSelector = map[string]string{"disktype": "ssd"}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	k8sClient := fakeClient.NewClientBuilder().WithScheme(scheme).WithObjects(&node, &running, pending).Build()
+
+	// The cluster is read through the cached client, never listed from the API server
+	clientset := fake.NewSimpleClientset()
+
+	var received []SchedulingEvent
+	watcher := NewEventWatcher(k8sClient, clientset, zap.NewNop(), func(ctx context.Context, events []SchedulingEvent) error {
+		received = events
+		return nil
+	})
+	watcher.SetDetectionMode(DetectionModePods)
+
+	watcher.handlePod(nil, pending)
+	watcher.processEvents(context.Background())
+
+	require.Len(t, received, 1)
+	assert.Equal(t, ConstraintNodeSelector, received[0].Constraint, "predicates should override the message")
+	assert.Empty(t, clientset.Actions())
+}
@@ -0,0 +1,448 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// predicatePriority breaks ties when several constraints block the same number
+// of nodes. Resource constraints come first because they are the ones a new
+// node can resolve, matching the precedence used by parseConstraint.
+var predicatePriority = []ResourceConstraint{
+	ConstraintPods,
+	ConstraintExtendedResource,
+	ConstraintEphemeralStorage,
+	ConstraintCPU,
+	ConstraintMemory,
+	ConstraintVolume,
+	ConstraintTaint,
+	ConstraintAntiAffinity,
+	ConstraintAffinity,
+	ConstraintNodeSelector,
+}
+
+// PodClassification explains why a pending pod does not fit any current node
+type PodClassification struct {
+	// Constraint is the constraint that blocked the most nodes
+	Constraint ResourceConstraint
+
+	// NodeFailures counts the nodes each constraint blocked
+	NodeFailures map[ResourceConstraint]int
+
+	// FromMessage is true when the predicates found a node the pod fits on (for
+	// example because the snapshot is newer than the scheduling attempt) and the
+	// constraint was taken from the scheduler's condition message instead
+	FromMessage bool
+}
+
+// clusterSnapshot is the node and pod state pending pods are evaluated against
+type clusterSnapshot struct {
+	nodes      []*corev1.Node
+	nodeByName map[string]*corev1.Node
+	podsByNode map[string][]*corev1.Pod
+
+	// volumes is the topology of the evaluated pods' volumes; nil skips the volume predicate
+	volumes *VolumeTopology
+}
+
+// newClusterSnapshot builds a snapshot from the cluster's nodes and pods. Pods
+// that are not bound or have finished do not consume node capacity.
+func newClusterSnapshot(nodes []corev1.Node, pods []corev1.Pod) *clusterSnapshot {
+	snapshot := &clusterSnapshot{
+		nodes:      make([]*corev1.Node, 0, len(nodes)),
+		nodeByName: make(map[string]*corev1.Node, len(nodes)),
+		podsByNode: make(map[string][]*corev1.Pod),
+	}
+
+	for i := range nodes {
+		snapshot.nodes = append(snapshot.nodes, &nodes[i])
+		snapshot.nodeByName[nodes[i].Name] = &nodes[i]
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		snapshot.podsByNode[pod.Spec.NodeName] = append(snapshot.podsByNode[pod.Spec.NodeName], pod)
+	}
+
+	return snapshot
+}
+
+// loadClusterSnapshot builds a snapshot from the nodes and pods read through c.
+// With the manager's client both lists are served from the informer cache.
+func loadClusterSnapshot(ctx context.Context, c client.Reader) (*clusterSnapshot, error) {
+	nodeList := &corev1.NodeList{}
+	if err := c.List(ctx, nodeList); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	podList := &corev1.PodList{}
+	if err := c.List(ctx, podList); err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	return newClusterSnapshot(nodeList.Items, podList.Items), nil
+}
+
+// clusterSnapshotKey is the context key of a snapshot shared within one pass over
+// the buffered events
+type clusterSnapshotKey struct{}
+
+// withClusterSnapshot returns a context carrying the snapshot, so the scale-up
+// handler reuses the snapshot the events were classified against
+func withClusterSnapshot(ctx context.Context, snapshot *clusterSnapshot) context.Context {
+	return context.WithValue(ctx, clusterSnapshotKey{}, snapshot)
+}
+
+// clusterSnapshotFromContext returns the snapshot carried by ctx, or nil
+func clusterSnapshotFromContext(ctx context.Context) *clusterSnapshot {
+	snapshot, _ := ctx.Value(clusterSnapshotKey{}).(*clusterSnapshot)
+	return snapshot
+}
+
+// classify evaluates the pod against every node and returns the constraint that
+// blocked the most nodes. message is the scheduler's explanation, used when no
+// node is blocked.
+func (s *clusterSnapshot) classify(pod *corev1.Pod, message string) PodClassification {
+	result := PodClassification{
+		NodeFailures: make(map[ResourceConstraint]int),
+	}
+
+	if len(s.nodes) == 0 {
+		result.Constraint = ConstraintNodeSelector
+		return result
+	}
+
+	fits := false
+	for _, node := range s.nodes {
+		constraint := s.evaluatePredicates(pod, node)
+		if constraint == "" {
+			fits = true
+			continue
+		}
+		result.NodeFailures[constraint]++
+	}
+
+	if fits || len(result.NodeFailures) == 0 {
+		result.Constraint = parseConstraint(message)
+		result.FromMessage = true
+		return result
+	}
+
+	best := 0
+	for _, constraint := range predicatePriority {
+		if count := result.NodeFailures[constraint]; count > best {
+			best = count
+			result.Constraint = constraint
+		}
+	}
+
+	return result
+}
+
+// evaluatePredicates returns the first constraint that prevents the pod from
+// running on the node, or an empty constraint if it fits. Predicates run in the
+// scheduler's filter order: node schedulability and taints, node selection,
+// resource fit, volume binding and topology, then inter-pod affinity.
+func (s *clusterSnapshot) evaluatePredicates(pod *corev1.Pod, node *corev1.Node) ResourceConstraint {
+	if node.Spec.Unschedulable || !podToleratesNodeTaints(pod, node.Spec.Taints) {
+		return ConstraintTaint
+	}
+
+	if !podMatchesNodeSelection(pod, node) {
+		return ConstraintNodeSelector
+	}
+
+	if constraint := s.resourceFit(pod, node); constraint != "" {
+		return constraint
+	}
+
+	if !s.volumes.nodeSatisfies(pod, node) {
+		return ConstraintVolume
+	}
+
+	if s.violatesAntiAffinity(pod, node) {
+		return ConstraintAntiAffinity
+	}
+
+	if !s.satisfiesAffinity(pod, node) {
+		return ConstraintAffinity
+	}
+
+	return ""
+}
+
+// resourceFit checks pod count, CPU, memory, ephemeral storage and extended
+// resources against the node's allocatable
+func (s *clusterSnapshot) resourceFit(pod *corev1.Pod, node *corev1.Node) ResourceConstraint {
+	nodePods := s.podsByNode[node.Name]
+
+	if maxPods, ok := node.Status.Allocatable[corev1.ResourcePods]; ok && int64(len(nodePods))+1 > maxPods.Value() {
+		return ConstraintPods
+	}
+
+	var usedCPU, usedMemory, usedStorage int64
+	usedExtended := make(map[corev1.ResourceName]int64)
+	for _, p := range nodePods {
+		cpu, memory := PodResourceRequests(p)
+		usedCPU += cpu
+		usedMemory += memory
+		storage, extended := podScalarRequests(p)
+		usedStorage += storage
+		for name, value := range extended {
+			usedExtended[name] += value
+		}
+	}
+
+	cpu, memory := PodResourceRequests(pod)
+	if cpu > 0 && usedCPU+cpu > node.Status.Allocatable.Cpu().MilliValue() {
+		return ConstraintCPU
+	}
+	if memory > 0 && usedMemory+memory > node.Status.Allocatable.Memory().Value() {
+		return ConstraintMemory
+	}
+
+	storage, extended := podScalarRequests(pod)
+	if storage > 0 && usedStorage+storage > node.Status.Allocatable.StorageEphemeral().Value() {
+		return ConstraintEphemeralStorage
+	}
+	for name, value := range extended {
+		allocatable := node.Status.Allocatable[name]
+		if usedExtended[name]+value > allocatable.Value() {
+			return ConstraintExtendedResource
+		}
+	}
+
+	return ""
+}
+
+// violatesAntiAffinity checks the pod's required anti-affinity terms against the
+// pods already running in the node's topology domain
+func (s *clusterSnapshot) violatesAntiAffinity(pod *corev1.Pod, node *corev1.Node) bool {
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.PodAntiAffinity == nil {
+		return false
+	}
+
+	for i := range pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		term := &pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i]
+		if s.topologyHasMatchingPod(pod, term, node) {
+			return true
+		}
+	}
+	return false
+}
+
+// satisfiesAffinity checks the pod's required affinity terms against the pods
+// already running in the node's topology domain
+func (s *clusterSnapshot) satisfiesAffinity(pod *corev1.Pod, node *corev1.Node) bool {
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.PodAffinity == nil {
+		return true
+	}
+
+	for i := range pod.Spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		term := &pod.Spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i]
+		if !s.topologyHasMatchingPod(pod, term, node) {
+			return false
+		}
+	}
+	return true
+}
+
+// topologyHasMatchingPod reports whether a pod matching the term runs on any node
+// sharing the node's value of the term's topology key
+func (s *clusterSnapshot) topologyHasMatchingPod(pod *corev1.Pod, term *corev1.PodAffinityTerm, node *corev1.Node) bool {
+	if term.LabelSelector == nil {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+	if err != nil {
+		return false
+	}
+
+	domain, ok := node.Labels[term.TopologyKey]
+	if !ok {
+		return false
+	}
+
+	namespaces := make(map[string]bool, len(term.Namespaces))
+	for _, ns := range term.Namespaces {
+		namespaces[ns] = true
+	}
+	// A namespace selector widens the term beyond what we can resolve here, so treat it as all namespaces
+	if len(namespaces) == 0 && term.NamespaceSelector == nil {
+		namespaces[pod.Namespace] = true
+	}
+
+	for nodeName, pods := range s.podsByNode {
+		other, ok := s.nodeByName[nodeName]
+		if !ok || other.Labels[term.TopologyKey] != domain {
+			continue
+		}
+		for _, p := range pods {
+			if len(namespaces) > 0 && !namespaces[p.Namespace] {
+				continue
+			}
+			if selector.Matches(labels.Set(p.Labels)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PodSchedulableOnNode reports whether the pod tolerates the node's taints and
+// matches its node selector and required node affinity
+func PodSchedulableOnNode(pod *corev1.Pod, node *corev1.Node) bool {
+	return podToleratesNodeTaints(pod, node.Spec.Taints) && podMatchesNodeSelection(pod, node)
+}
+
+// podToleratesNodeTaints checks that the pod tolerates all NoSchedule and NoExecute taints
+func podToleratesNodeTaints(pod *corev1.Pod, taints []corev1.Taint) bool {
+	for i := range taints {
+		taint := &taints[i]
+		if taint.Effect != corev1.TaintEffectNoSchedule && taint.Effect != corev1.TaintEffectNoExecute {
+			continue
+		}
+
+		tolerated := false
+		for j := range pod.Spec.Tolerations {
+			if pod.Spec.Tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// podMatchesNodeSelection checks the pod's node selector and required node affinity
+func podMatchesNodeSelection(pod *corev1.Pod, node *corev1.Node) bool {
+	for key, value := range pod.Spec.NodeSelector {
+		if node.Labels[key] != value {
+			return false
+		}
+	}
+
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil ||
+		pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+
+	// Terms are ORed, expressions within a term are ANDed
+	for _, term := range pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		matched := true
+		for i := range term.MatchExpressions {
+			if !nodeMatchesRequirement(node, &term.MatchExpressions[i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeMatchesRequirement checks a single node selector requirement against the node's labels
+func nodeMatchesRequirement(node *corev1.Node, req *corev1.NodeSelectorRequirement) bool {
+	value, exists := node.Labels[req.Key]
+
+	switch req.Operator {
+	case corev1.NodeSelectorOpIn:
+		if !exists {
+			return false
+		}
+		for _, v := range req.Values {
+			if value == v {
+				return true
+			}
+		}
+		return false
+	case corev1.NodeSelectorOpNotIn:
+		for _, v := range req.Values {
+			if exists && value == v {
+				return false
+			}
+		}
+		return true
+	case corev1.NodeSelectorOpExists:
+		return exists
+	case corev1.NodeSelectorOpDoesNotExist:
+		return !exists
+	default:
+		// Gt and Lt are not evaluated; assume they match so the scheduler's verdict stands
+		return true
+	}
+}
+
+// podRequests returns the pod's effective resource requests. Init containers run
+// sequentially, so for each resource the largest init container request is
+// compared with the sum of the regular containers.
+func podRequests(pod *corev1.Pod) corev1.ResourceList {
+	requests := make(corev1.ResourceList)
+	for _, container := range pod.Spec.Containers {
+		for name, quantity := range container.Resources.Requests {
+			total := requests[name]
+			total.Add(quantity)
+			requests[name] = total
+		}
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		for name, quantity := range container.Resources.Requests {
+			if current, ok := requests[name]; !ok || quantity.Cmp(current) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+
+	return requests
+}
+
+// PodResourceRequests returns the pod's CPU (millicores) and memory (bytes)
+// requests, taking init containers into account
+func PodResourceRequests(pod *corev1.Pod) (cpuMillis, memoryBytes int64) {
+	requests := podRequests(pod)
+	return requests.Cpu().MilliValue(), requests.Memory().Value()
+}
+
+// podScalarRequests returns the pod's ephemeral storage (bytes) and hugepages
+// and extended resource requests
+func podScalarRequests(pod *corev1.Pod) (ephemeralStorageBytes int64, extended map[corev1.ResourceName]int64) {
+	requests := podRequests(pod)
+	for name, quantity := range requests {
+		if !isExtendedResource(name) {
+			continue
+		}
+		if extended == nil {
+			extended = make(map[corev1.ResourceName]int64)
+		}
+		extended[name] = quantity.Value()
+	}
+	return requests.StorageEphemeral().Value(), extended
+}
+
+// isExtendedResource reports whether the resource is hugepages or an extended
+// resource such as "nvidia.com/gpu". Extended resources are fully qualified
+// names outside the kubernetes.io domain, advertised by device plugins or
+// operators rather than derived from the offering.
+func isExtendedResource(name corev1.ResourceName) bool {
+	if strings.HasPrefix(string(name), corev1.ResourceHugePagesPrefix) {
+		return true
+	}
+	return strings.Contains(string(name), "/") &&
+		!strings.Contains(string(name), "kubernetes.io/") &&
+		!strings.HasPrefix(string(name), corev1.DefaultResourceRequestsPrefix)
+}
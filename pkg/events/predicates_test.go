@@ -0,0 +1,241 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newPredicateNode(name, cpu, memory string, maxPods int64, labels map[string]string) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+				corev1.ResourcePods:   *resource.NewQuantity(maxPods, resource.DecimalSI),
+			},
+		},
+	}
+}
+
+func newPredicatePod(name, nodeName, cpu, memory string, labels map[string]string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse(cpu),
+						corev1.ResourceMemory: resource.MustParse(memory),
+					},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func TestClusterSnapshotClassify(t *testing.T) {
+	zoneA := map[string]string{"topology.kubernetes.io/zone": "a"}
+
+	tests := []struct {
+		name        string
+		nodes       []corev1.Node
+		pods        []corev1.Pod
+		pending     func() corev1.Pod
+		message     string
+		expected    ResourceConstraint
+		fromMessage bool
+	}{
+		{
+			name:     "no nodes",
+			pending:  func() corev1.Pod { return newPredicatePod("pending", "", "1", "1Gi", nil) },
+			expected: ConstraintNodeSelector,
+		},
+		{
+			name:  "insufficient cpu",
+			nodes: []corev1.Node{newPredicateNode("node-1", "2", "8Gi", 110, nil)},
+			pods:  []corev1.Pod{newPredicatePod("running", "node-1", "1500m", "1Gi", nil)},
+			pending: func() corev1.Pod {
+				return newPredicatePod("pending", "", "1", "1Gi", nil)
+			},
+			expected: ConstraintCPU,
+		},
+		{
+			name:  "insufficient memory",
+			nodes: []corev1.Node{newPredicateNode("node-1", "4", "2Gi", 110, nil)},
+			pending: func() corev1.Pod {
+				return newPredicatePod("pending", "", "1", "4Gi", nil)
+			},
+			expected: ConstraintMemory,
+		},
+		{
+			name:  "too many pods",
+			nodes: []corev1.Node{newPredicateNode("node-1", "4", "8Gi", 1, nil)},
+			pods:  []corev1.Pod{newPredicatePod("running", "node-1", "100m", "128Mi", nil)},
+			pending: func() corev1.Pod {
+				return newPredicatePod("pending", "", "100m", "128Mi", nil)
+			},
+			expected: ConstraintPods,
+		},
+		{
+			name: "untolerated taint",
+			nodes: func() []corev1.Node {
+				node := newPredicateNode("node-1", "4", "8Gi", 110, nil)
+				node.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}
+				return []corev1.Node{node}
+			}(),
+			pending: func() corev1.Pod {
+				return newPredicatePod("pending", "", "100m", "128Mi", nil)
+			},
+			expected: ConstraintTaint,
+		},
+		{
+			name:  "node selector",
+			nodes: []corev1.Node{newPredicateNode("node-1", "4", "8Gi", 110, nil)},
+			pending: func() corev1.Pod {
+				pod := newPredicatePod("pending", "", "100m", "128Mi", nil)
+				pod.Spec.NodeSelector = map[string]string{"disktype": "ssd"}
+				return pod
+			},
+			expected: ConstraintNodeSelector,
+		},
+		{
+			name:  "anti-affinity",
+			nodes: []corev1.Node{newPredicateNode("node-1", "4", "8Gi", 110, zoneA)},
+			pods:  []corev1.Pod{newPredicatePod("running", "node-1", "100m", "128Mi", map[string]string{"app": "web"})},
+			pending: func() corev1.Pod {
+				pod := newPredicatePod("pending", "", "100m", "128Mi", map[string]string{"app": "web"})
+				pod.Spec.Affinity = &corev1.Affinity{
+					PodAntiAffinity: &corev1.PodAntiAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+							LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+							TopologyKey:   "topology.kubernetes.io/zone",
+						}},
+					},
+				}
+				return pod
+			},
+			expected: ConstraintAntiAffinity,
+		},
+		{
+			name:  "affinity",
+			nodes: []corev1.Node{newPredicateNode("node-1", "4", "8Gi", 110, zoneA)},
+			pending: func() corev1.Pod {
+				pod := newPredicatePod("pending", "", "100m", "128Mi", nil)
+				pod.Spec.Affinity = &corev1.Affinity{
+					PodAffinity: &corev1.PodAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+							LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+							TopologyKey:   "topology.kubernetes.io/zone",
+						}},
+					},
+				}
+				return pod
+			},
+			expected: ConstraintAffinity,
+		},
+		{
+			name: "most blocked nodes wins",
+			nodes: func() []corev1.Node {
+				tainted := newPredicateNode("node-1", "4", "8Gi", 110, nil)
+				tainted.Spec.Taints = []corev1.Taint{{Key: "dedicated", Effect: corev1.TaintEffectNoSchedule}}
+				return []corev1.Node{
+					tainted,
+					newPredicateNode("node-2", "1", "8Gi", 110, nil),
+					newPredicateNode("node-3", "1", "8Gi", 110, nil),
+				}
+			}(),
+			pending: func() corev1.Pod {
+				return newPredicatePod("pending", "", "2", "128Mi", nil)
+			},
+			expected: ConstraintCPU,
+		},
+		{
+			name: "extended resource",
+			nodes: func() []corev1.Node {
+				gpu := newPredicateNode("node-1", "4", "8Gi", 110, nil)
+				gpu.Status.Allocatable["nvidia.com/gpu"] = resource.MustParse("1")
+				return []corev1.Node{gpu, newPredicateNode("node-2", "4", "8Gi", 110, nil)}
+			}(),
+			pods: func() []corev1.Pod {
+				pod := newPredicatePod("running", "node-1", "100m", "128Mi", nil)
+				pod.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("1")
+				return []corev1.Pod{pod}
+			}(),
+			pending: func() corev1.Pod {
+				pod := newPredicatePod("pending", "", "100m", "128Mi", nil)
+				pod.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("1")
+				return pod
+			},
+			expected: ConstraintExtendedResource,
+		},
+		{
+			name: "ephemeral storage",
+			nodes: func() []corev1.Node {
+				node := newPredicateNode("node-1", "4", "8Gi", 110, nil)
+				node.Status.Allocatable[corev1.ResourceEphemeralStorage] = resource.MustParse("20Gi")
+				return []corev1.Node{node}
+			}(),
+			pending: func() corev1.Pod {
+				pod := newPredicatePod("pending", "", "100m", "128Mi", nil)
+				pod.Spec.Containers[0].Resources.Requests[corev1.ResourceEphemeralStorage] = resource.MustParse("30Gi")
+				return pod
+			},
+			expected: ConstraintEphemeralStorage,
+		},
+		{
+			name:  "fits falls back to message",
+			nodes: []corev1.Node{newPredicateNode("node-1", "4", "8Gi", 110, nil)},
+			pending: func() corev1.Pod {
+				return newPredicatePod("pending", "", "100m", "128Mi", nil)
+			},
+			message:     "0/1 nodes are available: 1 Insufficient memory.",
+			expected:    ConstraintMemory,
+			fromMessage: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pending := tt.pending()
+			snapshot := newClusterSnapshot(tt.nodes, append(tt.pods, pending))
+
+			result := snapshot.classify(&pending, tt.message)
+			assert.Equal(t, tt.expected, result.Constraint)
+			assert.Equal(t, tt.fromMessage, result.FromMessage)
+		})
+	}
+}
+
+func TestPodMatchesNodeSelection(t *testing.T) {
+	node := newPredicateNode("node-1", "4", "8Gi", 110, map[string]string{"disktype": "ssd", "zone": "a"})
+
+	pod := newPredicatePod("pending", "", "100m", "128Mi", nil)
+	pod.Spec.Affinity = &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"b"}},
+					}},
+					{MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: "disktype", Operator: corev1.NodeSelectorOpExists},
+						{Key: "gpu", Operator: corev1.NodeSelectorOpDoesNotExist},
+					}},
+				},
+			},
+		},
+	}
+	assert.True(t, podMatchesNodeSelection(&pod, &node), "second term should match")
+
+	pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[1].MatchExpressions[0] =
+		corev1.NodeSelectorRequirement{Key: "disktype", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"ssd"}}
+	assert.False(t, podMatchesNodeSelection(&pod, &node), "no term should match")
+}
@@ -0,0 +1,145 @@
+package events
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
+)
+
+// DefaultExpendablePodsPriorityCutoff is the priority below which pending pods
+// do not trigger scale-up, matching the upstream cluster-autoscaler default
+const DefaultExpendablePodsPriorityCutoff int32 = -10
+
+// podPriority returns the pod's priority, or 0 if the admission controller did not set one
+func podPriority(pod *corev1.Pod) int32 {
+	if pod.Spec.Priority == nil {
+		return 0
+	}
+	return *pod.Spec.Priority
+}
+
+// preemptionWillSucceed reports whether the scheduler nominated a node for the
+// pod on which it fits once the lower-priority pods it preempts are gone. The
+// pod then schedules without a new node.
+func (s *clusterSnapshot) preemptionWillSucceed(pod *corev1.Pod) bool {
+	nodeName := pod.Status.NominatedNodeName
+	if nodeName == "" {
+		return false
+	}
+	node, ok := s.nodeByName[nodeName]
+	if !ok {
+		return false
+	}
+
+	priority := podPriority(pod)
+	remaining := make([]*corev1.Pod, 0, len(s.podsByNode[nodeName]))
+	for _, p := range s.podsByNode[nodeName] {
+		if podPriority(p) >= priority {
+			remaining = append(remaining, p)
+		}
+	}
+
+	afterPreemption := &clusterSnapshot{
+		nodes:      s.nodes,
+		nodeByName: s.nodeByName,
+		podsByNode: make(map[string][]*corev1.Pod, len(s.podsByNode)),
+		volumes:    s.volumes,
+	}
+	for name, pods := range s.podsByNode {
+		afterPreemption.podsByNode[name] = pods
+	}
+	afterPreemption.podsByNode[nodeName] = remaining
+
+	return afterPreemption.evaluatePredicates(pod, node) == ""
+}
+
+// Reasons a pending pod is ignored for scale-up
+const (
+	ignoreReasonExpendable = "expendable"
+	ignoreReasonPreemption = "preemption"
+)
+
+// filterScaleUpPods drops the pending pods that must not trigger scale-up:
+// pods with a priority below the expendable cutoff, and pods that will be
+// scheduled by preempting lower-priority pods on their nominated node. It
+// returns the kept pods and the reason each dropped pod was ignored, by UID.
+// If the cluster cannot be read for the preemption check, nominated pods are
+// kept.
+func (c *ScaleUpController) filterScaleUpPods(ctx context.Context, pendingPods []corev1.Pod) ([]corev1.Pod, map[types.UID]ignoredPod) {
+	ignored := make(map[types.UID]ignoredPod)
+	filtered := make([]corev1.Pod, 0, len(pendingPods))
+	nominated := false
+	for i := range pendingPods {
+		pod := &pendingPods[i]
+		if podPriority(pod) < c.expendablePodsPriorityCutoff {
+			c.logger.Debug("Pending pod is expendable, not scaling up for it",
+				zap.String("pod", pod.Name),
+				zap.String("namespace", pod.Namespace),
+				zap.Int32("priority", podPriority(pod)),
+				zap.Int32("cutoff", c.expendablePodsPriorityCutoff),
+			)
+			ignored[pod.UID] = ignoredPod{namespace: pod.Namespace, reason: ignoreReasonExpendable}
+			continue
+		}
+		if pod.Status.NominatedNodeName != "" {
+			nominated = true
+		}
+		filtered = append(filtered, *pod)
+	}
+
+	if !c.preemptionCheck || !nominated {
+		return filtered, ignored
+	}
+
+	snapshot := clusterSnapshotFromContext(ctx)
+	if snapshot == nil {
+		var err error
+		if snapshot, err = loadClusterSnapshot(ctx, c.client); err != nil {
+			c.logger.Warn("Failed to list cluster for preemption check", zap.Error(err))
+			return filtered, ignored
+		}
+	}
+
+	pods := filtered[:0]
+	for i := range filtered {
+		pod := &filtered[i]
+		if snapshot.preemptionWillSucceed(pod) {
+			c.logger.Debug("Pending pod will preempt lower-priority pods on its nominated node, not scaling up for it",
+				zap.String("pod", pod.Name),
+				zap.String("namespace", pod.Namespace),
+				zap.String("nominatedNode", pod.Status.NominatedNodeName),
+			)
+			ignored[pod.UID] = ignoredPod{namespace: pod.Namespace, reason: ignoreReasonPreemption}
+			continue
+		}
+		pods = append(pods, *pod)
+	}
+
+	return pods, ignored
+}
+
+// ignoredPod is a pending pod that did not trigger scale-up
+type ignoredPod struct {
+	namespace string
+	reason    string
+}
+
+// recordIgnoredPods counts each ignored pod once per reason. Pods stay pending
+// across scale-up attempts, so pods already ignored for the same reason on the
+// previous attempt are not counted again. Pods no longer ignored are forgotten.
+func (c *ScaleUpController) recordIgnoredPods(ignored map[types.UID]ignoredPod) {
+	c.ignoredPodsMu.Lock()
+	defer c.ignoredPodsMu.Unlock()
+
+	for uid, pod := range ignored {
+		if previous, ok := c.ignoredPods[uid]; ok && previous.reason == pod.reason {
+			continue
+		}
+		metrics.ScaleUpPodsIgnoredTotal.WithLabelValues(pod.namespace, pod.reason).Inc()
+	}
+	c.ignoredPods = ignored
+}
@@ -0,0 +1,139 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
+)
+
+func newPriorityPod(name, nodeName, cpu string, priority int32) corev1.Pod {
+	pod := newPredicatePod(name, nodeName, cpu, "256Mi", nil)
+	pod.UID = types.UID(name)
+	pod.Spec.Priority = &priority
+	if nodeName == "" {
+		pod.Status.Phase = corev1.PodPending
+	}
+	return pod
+}
+
+func TestPreemptionWillSucceed(t *testing.T) {
+	nodes := []corev1.Node{newPredicateNode("node-1", "2", "4Gi", 110, nil)}
+	running := []corev1.Pod{
+		newPriorityPod("batch", "node-1", "1", 0),
+		newPriorityPod("web", "node-1", "800m", 1000),
+	}
+	snapshot := newClusterSnapshot(nodes, running)
+
+	nominated := func(cpu string, priority int32, node string) *corev1.Pod {
+		pod := newPriorityPod("pending", "", cpu, priority)
+		pod.Status.NominatedNodeName = node
+		return &pod
+	}
+
+	// Preempting the batch pod frees 1 CPU next to the web pod
+	assert.True(t, snapshot.preemptionWillSucceed(nominated("1", 500, "node-1")))
+
+	// The web pod has a higher priority and is not preempted
+	assert.False(t, snapshot.preemptionWillSucceed(nominated("1500m", 500, "node-1")))
+
+	// Pods of the same priority are not preempted
+	assert.False(t, snapshot.preemptionWillSucceed(nominated("1", 0, "node-1")))
+
+	assert.False(t, snapshot.preemptionWillSucceed(nominated("1", 500, "")))
+	assert.False(t, snapshot.preemptionWillSucceed(nominated("1", 500, "node-gone")))
+
+	// The snapshot itself is not changed by the check
+	assert.Len(t, snapshot.podsByNode["node-1"], 2)
+}
+
+func TestFilterScaleUpPods(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	node := newPredicateNode("node-1", "2", "4Gi", 110, nil)
+	batch := newPriorityPod("batch", "node-1", "1500m", 0)
+	k8sClient := fakeClient.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(&node, &batch).
+		Build()
+
+	logger := zap.NewNop()
+	controller := NewScaleUpController(k8sClient, NewResourceAnalyzer(logger, nil), nil, nil, logger)
+
+	preempting := newPriorityPod("preempting", "", "1", 1000)
+	preempting.Status.NominatedNodeName = "node-1"
+	pending := []corev1.Pod{
+		newPriorityPod("expendable", "", "100m", -100),
+		newPriorityPod("regular", "", "100m", 0),
+		preempting,
+	}
+
+	names := func(pods []corev1.Pod) []string {
+		result := make([]string, 0, len(pods))
+		for i := range pods {
+			result = append(result, pods[i].Name)
+		}
+		return result
+	}
+
+	pods, ignored := controller.filterScaleUpPods(context.Background(), append([]corev1.Pod(nil), pending...))
+	assert.Equal(t, []string{"regular"}, names(pods))
+	assert.Equal(t, map[types.UID]ignoredPod{
+		"expendable": {namespace: "default", reason: ignoreReasonExpendable},
+		"preempting": {namespace: "default", reason: ignoreReasonPreemption},
+	}, ignored)
+
+	// A snapshot carried by the context is used instead of reading the cluster
+	web := newPriorityPod("web", "node-1", "1500m", 2000)
+	ctx := withClusterSnapshot(context.Background(), newClusterSnapshot([]corev1.Node{node}, []corev1.Pod{web}))
+	pods, _ = controller.filterScaleUpPods(ctx, append([]corev1.Pod(nil), pending...))
+	assert.Equal(t, []string{"regular", "preempting"}, names(pods))
+
+	controller.SetPreemptionCheck(false)
+	controller.SetExpendablePodsPriorityCutoff(-1000)
+	pods, ignored = controller.filterScaleUpPods(context.Background(), append([]corev1.Pod(nil), pending...))
+	assert.Equal(t, []string{"expendable", "regular", "preempting"}, names(pods))
+	assert.Empty(t, ignored)
+}
+
+func TestRecordIgnoredPods(t *testing.T) {
+	metrics.ScaleUpPodsIgnoredTotal.Reset()
+	logger := zap.NewNop()
+	controller := NewScaleUpController(nil, NewResourceAnalyzer(logger, nil), nil, nil, logger)
+
+	expendable := metrics.ScaleUpPodsIgnoredTotal.WithLabelValues("default", ignoreReasonExpendable)
+	preemption := metrics.ScaleUpPodsIgnoredTotal.WithLabelValues("default", ignoreReasonPreemption)
+
+	controller.recordIgnoredPods(map[types.UID]ignoredPod{
+		"a": {namespace: "default", reason: ignoreReasonExpendable},
+		"b": {namespace: "default", reason: ignoreReasonPreemption},
+	})
+	assert.Equal(t, 1.0, testutil.ToFloat64(expendable))
+	assert.Equal(t, 1.0, testutil.ToFloat64(preemption))
+
+	// Pods still ignored on the next attempt are not counted again
+	controller.recordIgnoredPods(map[types.UID]ignoredPod{
+		"a": {namespace: "default", reason: ignoreReasonExpendable},
+		"b": {namespace: "default", reason: ignoreReasonPreemption},
+		"c": {namespace: "default", reason: ignoreReasonExpendable},
+	})
+	assert.Equal(t, 2.0, testutil.ToFloat64(expendable))
+	assert.Equal(t, 1.0, testutil.ToFloat64(preemption))
+
+	// A pod ignored again after a gap is counted again
+	controller.recordIgnoredPods(nil)
+	controller.recordIgnoredPods(map[types.UID]ignoredPod{
+		"a": {namespace: "default", reason: ignoreReasonExpendable},
+	})
+	assert.Equal(t, 3.0, testutil.ToFloat64(expendable))
+}
@@ -3,10 +3,13 @@ package events
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
@@ -14,6 +17,10 @@ import (
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/tracing"
 )
 
+// maxDynamicNodeGroupsPerScaleUp bounds how many NodeGroups one scale-up creates
+// when no existing NodeGroup matches the pending pods
+const maxDynamicNodeGroupsPerScaleUp = 5
+
 // ScaleUpDecision represents a decision to scale up a NodeGroup
 type ScaleUpDecision struct {
 	NodeGroup    *v1alpha1.NodeGroup
@@ -21,6 +28,9 @@ type ScaleUpDecision struct {
 	DesiredNodes int32
 	NodesToAdd   int32
 	InstanceType string
+	InstanceInfo v1alpha1.InstanceTypeInfo
+	Template     NodeTemplate
+	NodesNeeded  int
 	MatchingPods int
 	Deficit      ResourceDeficit
 	Reason       string
@@ -32,7 +42,31 @@ type ScaleUpController struct {
 	analyzer *ResourceAnalyzer
 	watcher  *EventWatcher
 	creator  *DynamicNodeGroupCreator
+	expander Expander
 	logger   *zap.Logger
+
+	// maxConcurrentScaleUps caps the nodes added to a NodeGroup in one decision
+	maxConcurrentScaleUps int32
+
+	// expendablePodsPriorityCutoff is the priority below which pending pods
+	// do not trigger scale-up
+	expendablePodsPriorityCutoff int32
+
+	// preemptionCheck skips pods that will schedule by preemption on their nominated node
+	preemptionCheck bool
+
+	// demandRecorder optionally receives the nodes needed for pending pods
+	demandRecorder DemandRecorder
+
+	// ignoredPods are the pods ignored on the last scale-up attempt, so each is counted once
+	ignoredPods   map[types.UID]ignoredPod
+	ignoredPodsMu sync.Mutex
+}
+
+// DemandRecorder receives the nodes a NodeGroup needs for its pending pods,
+// for example to learn demand patterns for predictive scaling
+type DemandRecorder interface {
+	RecordPendingDemand(ng *v1alpha1.NodeGroup, nodesNeeded int)
 }
 
 // NewScaleUpController creates a new scale-up controller
@@ -48,7 +82,12 @@ func NewScaleUpController(
 		analyzer: analyzer,
 		watcher:  watcher,
 		creator:  creator,
+		expander: leastWasteExpander{},
 		logger:   logger.Named("scale-up-controller"),
+
+		maxConcurrentScaleUps:        1,
+		expendablePodsPriorityCutoff: DefaultExpendablePodsPriorityCutoff,
+		preemptionCheck:              true,
 	}
 }
 
@@ -57,6 +96,54 @@ func (c *ScaleUpController) SetWatcher(watcher *EventWatcher) {
 	c.watcher = watcher
 }
 
+// SetExpander sets the strategy used to choose between NodeGroups that can
+// schedule the same pending pods
+func (c *ScaleUpController) SetExpander(expander Expander) {
+	c.expander = expander
+}
+
+// SetExpanderStrategy creates and sets the expander for a strategy, using the
+// analyzer's cost calculator for the cheapest expander
+func (c *ScaleUpController) SetExpanderStrategy(strategy ExpanderStrategy, priorityConfigMap types.NamespacedName) error {
+	expander, err := NewExpander(strategy, c.client, c.analyzer.calculator, priorityConfigMap, c.logger)
+	if err != nil {
+		return err
+	}
+	c.expander = expander
+	return nil
+}
+
+// Expander returns the strategy used to choose between NodeGroups
+func (c *ScaleUpController) Expander() Expander {
+	return c.expander
+}
+
+// SetMaxConcurrentScaleUps sets how many nodes a single decision may add to a
+// NodeGroup. The default of 1 scales sequentially, one node at a time.
+func (c *ScaleUpController) SetMaxConcurrentScaleUps(n int32) {
+	if n < 1 {
+		n = 1
+	}
+	c.maxConcurrentScaleUps = n
+}
+
+// SetExpendablePodsPriorityCutoff sets the priority below which pending pods
+// do not trigger scale-up
+func (c *ScaleUpController) SetExpendablePodsPriorityCutoff(cutoff int32) {
+	c.expendablePodsPriorityCutoff = cutoff
+}
+
+// SetPreemptionCheck enables or disables skipping pods that will schedule by
+// preempting lower-priority pods on their nominated node
+func (c *ScaleUpController) SetPreemptionCheck(enabled bool) {
+	c.preemptionCheck = enabled
+}
+
+// SetDemandRecorder sets the recorder notified of each NodeGroup's pending demand
+func (c *ScaleUpController) SetDemandRecorder(recorder DemandRecorder) {
+	c.demandRecorder = recorder
+}
+
 // HandleScaleUp processes scheduling events and makes scale-up decisions
 func (c *ScaleUpController) HandleScaleUp(ctx context.Context, events []SchedulingEvent) error {
 	// Start Sentry transaction for tracing
@@ -76,6 +163,10 @@ func (c *ScaleUpController) HandleScaleUp(ctx context.Context, events []Scheduli
 		return fmt.Errorf("failed to get pending pods: %w", err)
 	}
 
+	// Expendable pods and pods the scheduler places by preemption need no new nodes
+	pendingPods, ignored := c.filterScaleUpPods(ctx, pendingPods)
+	c.recordIgnoredPods(ignored)
+
 	if len(pendingPods) == 0 {
 		c.logger.Debug("No pending pods, skipping scale-up")
 		return nil
@@ -92,7 +183,8 @@ func (c *ScaleUpController) HandleScaleUp(ctx context.Context, events []Scheduli
 	}
 
 	// Find matching NodeGroups
-	matches := c.analyzer.FindMatchingNodeGroups(pendingPods, nodeGroups)
+	volumes := c.loadVolumeTopology(ctx, pendingPods)
+	matches := c.analyzer.FindMatchingNodeGroups(pendingPods, nodeGroups, volumes)
 
 	// If no suitable NodeGroup exists, try to create one dynamically
 	if len(matches) == 0 && c.creator != nil {
@@ -100,25 +192,27 @@ func (c *ScaleUpController) HandleScaleUp(ctx context.Context, events []Scheduli
 			zap.Int("pendingPods", len(pendingPods)),
 		)
 
-		// Try to create a NodeGroup for the first pending pod
-		// (additional pods with similar requirements will be handled by the new NodeGroup)
-		ng, err := c.createNodeGroupForPendingPods(ctx, pendingPods)
+		// Create one NodeGroup per group of pods with compatible requirements
+		created, err := c.createNodeGroupsForPendingPods(ctx, pendingPods, volumes)
 		if err != nil {
 			c.logger.Error("Failed to create dynamic NodeGroup",
 				zap.Error(err),
 			)
-			return nil
 		}
 
-		if ng != nil {
-			c.logger.Info("Created dynamic NodeGroup",
-				zap.String("nodeGroup", ng.Name),
-				zap.String("namespace", ng.Namespace),
-			)
-
-			// Add the new NodeGroup to the list and re-find matches
-			nodeGroups = append(nodeGroups, *ng)
-			matches = c.analyzer.FindMatchingNodeGroups(pendingPods, nodeGroups)
+		if len(created) > 0 {
+			for _, ng := range created {
+				c.logger.Info("Created dynamic NodeGroup",
+					zap.String("nodeGroup", ng.Name),
+					zap.String("namespace", ng.Namespace),
+				)
+				nodeGroups = append(nodeGroups, *ng)
+			}
+
+			// Re-find matches including the new NodeGroups
+			matches = c.analyzer.FindMatchingNodeGroups(pendingPods, nodeGroups, volumes)
+		} else if err != nil {
+			return nil
 		}
 	}
 
@@ -131,28 +225,20 @@ func (c *ScaleUpController) HandleScaleUp(ctx context.Context, events []Scheduli
 		zap.Int("matchCount", len(matches)),
 	)
 
-	// Make scale-up decisions for each matching NodeGroup
-	decisions := make([]ScaleUpDecision, 0)
-	for _, match := range matches {
-		decision, err := c.makeScaleUpDecision(ctx, match)
-		if err != nil {
-			c.logger.Error("Failed to make scale-up decision",
-				zap.String("nodeGroup", match.NodeGroup.Name),
-				zap.Error(err),
-			)
-			continue
-		}
-
-		if decision != nil {
-			decisions = append(decisions, *decision)
-		}
-	}
+	// Make scale-up decisions, choosing one NodeGroup per set of pending pods
+	decisions := c.selectScaleUps(ctx, matches)
 
 	if len(decisions) == 0 {
 		c.logger.Info("No scale-up decisions made")
 		return nil
 	}
 
+	if c.demandRecorder != nil {
+		for _, decision := range decisions {
+			c.demandRecorder.RecordPendingDemand(decision.NodeGroup, decision.NodesNeeded)
+		}
+	}
+
 	// Execute scale-up decisions
 	for _, decision := range decisions {
 		if err := c.executeScaleUp(ctx, decision); err != nil {
@@ -184,11 +270,11 @@ func (c *ScaleUpController) makeScaleUpDecision(
 	}
 
 	// Check if already at max capacity
-	if ng.Status.DesiredNodes >= ng.Spec.MaxNodes {
+	if ng.Status.DesiredNodes >= v1alpha1.EffectiveMaxNodes(ng) {
 		c.logger.Debug("NodeGroup is at max capacity",
 			zap.String("nodeGroup", ng.Name),
 			zap.Int32("desiredNodes", ng.Status.DesiredNodes),
-			zap.Int32("maxNodes", ng.Spec.MaxNodes),
+			zap.Int32("maxNodes", v1alpha1.EffectiveMaxNodes(ng)),
 		)
 		metrics.ScaleUpDecisionsTotal.WithLabelValues(ng.Name, ng.Namespace, "skipped_max_capacity").Inc()
 		return nil, nil
@@ -200,17 +286,22 @@ func (c *ScaleUpController) makeScaleUpDecision(
 		return nil, fmt.Errorf("failed to select instance type: %w", err)
 	}
 
-	// Get instance type info (for now, use default values)
-	// TODO: Fetch actual instance type info from VPSie API
-	instanceInfo := v1alpha1.InstanceTypeInfo{
-		OfferingID: instanceType,
-		CPU:        4,    // Default
-		MemoryMB:   8192, // Default
-		DiskGB:     80,   // Default
-	}
+	// Get instance type info from the offering's pricing (defaults if unknown)
+	instanceInfo := c.analyzer.InstanceTypeInfo(ctx, instanceType)
 
-	// Estimate nodes needed
-	nodesNeeded := c.analyzer.EstimateNodesNeeded(match.Deficit, instanceInfo)
+	// Estimate nodes needed by packing the pods into template nodes
+	template := c.nodeTemplate(ctx, ng, instanceInfo)
+	packing := c.analyzer.EstimateNodesForPods(match.MatchingPods, template)
+	nodesNeeded := packing.Nodes
+	if len(packing.Unschedulable) > 0 {
+		c.logger.Warn("Pending pods do not fit on a new node of the NodeGroup",
+			zap.String("nodeGroup", ng.Name),
+			zap.String("instanceType", instanceType),
+			zap.Int("pods", len(packing.Unschedulable)),
+			zap.Int64("allocatableCPUMillis", template.CPUMillis),
+			zap.Int64("allocatableMemoryBytes", template.MemoryBytes),
+		)
+	}
 
 	// Sequential scaling: check if any nodes are still being provisioned (not yet ready)
 	// If DesiredNodes > ReadyNodes, nodes are in transition - wait for them to be Ready
@@ -233,9 +324,9 @@ func (c *ScaleUpController) makeScaleUpDecision(
 		return nil, nil
 	}
 
-	// Check if we actually need more nodes based on deficit
-	// nodesNeeded from EstimateNodesNeeded already represents the NEW nodes needed
-	// to satisfy the unschedulable pod deficit
+	// Check if we actually need more nodes based on binpacking
+	// nodesNeeded from EstimateNodesForPods already represents the NEW nodes needed
+	// to schedule the pending pods
 	if nodesNeeded <= 0 {
 		c.logger.Debug("No additional nodes needed based on deficit analysis",
 			zap.String("nodeGroup", ng.Name),
@@ -245,23 +336,20 @@ func (c *ScaleUpController) makeScaleUpDecision(
 	}
 
 	// Check available capacity
-	availableCapacity := ng.Spec.MaxNodes - ng.Status.DesiredNodes
+	availableCapacity := v1alpha1.EffectiveMaxNodes(ng) - ng.Status.DesiredNodes
 	if availableCapacity <= 0 {
 		c.logger.Debug("NodeGroup at max capacity",
 			zap.String("nodeGroup", ng.Name),
 			zap.Int32("desiredNodes", ng.Status.DesiredNodes),
-			zap.Int32("maxNodes", ng.Spec.MaxNodes),
+			zap.Int32("maxNodes", v1alpha1.EffectiveMaxNodes(ng)),
 		)
 		return nil, nil
 	}
 
-	// Sequential scaling: only add ONE node at a time
-	// Wait for it to be Ready, then re-evaluate if more are needed
-	nodesToAdd := int32(1)
-
+	nodesToAdd := c.nodesToAdd(ng, nodesNeeded)
 	desiredNodes := ng.Status.DesiredNodes + nodesToAdd
 
-	c.logger.Info("Scale-up decision made (sequential scaling: 1 node at a time)",
+	c.logger.Info("Scale-up decision made",
 		zap.String("nodeGroup", ng.Name),
 		zap.Int32("currentNodes", ng.Status.CurrentNodes),
 		zap.Int32("readyNodes", ng.Status.ReadyNodes),
@@ -272,22 +360,187 @@ func (c *ScaleUpController) makeScaleUpDecision(
 		zap.Int("matchingPods", len(match.MatchingPods)),
 	)
 
-	// Emit metrics for scale-up decision
-	metrics.ScaleUpDecisionsTotal.WithLabelValues(ng.Name, ng.Namespace, "executed").Inc()
-	metrics.ScaleUpDecisionNodesRequested.WithLabelValues(ng.Name, ng.Namespace).Observe(float64(nodesToAdd))
-
 	return &ScaleUpDecision{
 		NodeGroup:    ng,
 		CurrentNodes: ng.Status.DesiredNodes,
 		DesiredNodes: desiredNodes,
 		NodesToAdd:   nodesToAdd,
 		InstanceType: instanceType,
+		InstanceInfo: instanceInfo,
+		Template:     template,
+		NodesNeeded:  nodesNeeded,
 		MatchingPods: len(match.MatchingPods),
 		Deficit:      match.Deficit,
-		Reason:       fmt.Sprintf("Sequential scaling: adding 1 node for %d pending pods (estimated %d total needed)", len(match.MatchingPods), nodesNeeded),
+		Reason:       scaleUpReason(nodesToAdd, len(match.MatchingPods), nodesNeeded),
 	}, nil
 }
 
+// nodesToAdd returns how many of the needed nodes to add to the NodeGroup: up
+// to the concurrent scale-up limit (1 by default, in which case nodes are
+// added one at a time and re-evaluated once Ready) and the NodeGroup's capacity
+func (c *ScaleUpController) nodesToAdd(ng *v1alpha1.NodeGroup, nodesNeeded int) int32 {
+	nodesToAdd := int32(nodesNeeded)
+	if nodesToAdd > c.maxConcurrentScaleUps {
+		nodesToAdd = c.maxConcurrentScaleUps
+	}
+	if available := v1alpha1.EffectiveMaxNodes(ng) - ng.Status.DesiredNodes; nodesToAdd > available {
+		nodesToAdd = available
+	}
+	return max(nodesToAdd, 0)
+}
+
+// scaleUpReason describes a scale-up decision
+func scaleUpReason(nodesToAdd int32, pods, nodesNeeded int) string {
+	return fmt.Sprintf("Adding %d node(s) for %d pending pods (estimated %d total needed)", nodesToAdd, pods, nodesNeeded)
+}
+
+// nodeTemplate builds the template for a new node of the NodeGroup from its
+// existing nodes and the cluster's DaemonSets. List failures are logged and the
+// template is built without that input.
+func (c *ScaleUpController) nodeTemplate(
+	ctx context.Context,
+	ng *v1alpha1.NodeGroup,
+	instanceInfo v1alpha1.InstanceTypeInfo,
+) NodeTemplate {
+	nodeList := &corev1.NodeList{}
+	if err := c.client.List(ctx, nodeList, client.MatchingLabels{
+		v1alpha1.NodeGroupLabelKey: ng.Name,
+	}); err != nil {
+		c.logger.Debug("Failed to list NodeGroup nodes for node template",
+			zap.String("nodeGroup", ng.Name),
+			zap.Error(err),
+		)
+	}
+
+	daemonSetList := &appsv1.DaemonSetList{}
+	if err := c.client.List(ctx, daemonSetList); err != nil {
+		c.logger.Debug("Failed to list DaemonSets for node template",
+			zap.String("nodeGroup", ng.Name),
+			zap.Error(err),
+		)
+	}
+
+	return c.analyzer.BuildNodeTemplate(ng, instanceInfo, nodeList.Items, daemonSetList.Items)
+}
+
+// selectScaleUps makes scale-up decisions for the matching NodeGroups and lets
+// the expander choose between NodeGroups that can schedule the same pods. Pods
+// covered by a chosen NodeGroup are removed from the other options, so each
+// pending pod causes at most one NodeGroup to be scaled up.
+func (c *ScaleUpController) selectScaleUps(ctx context.Context, matches []NodeGroupMatch) []ScaleUpDecision {
+	decisions := make(map[string]*ScaleUpDecision, len(matches))
+	options := make([]ExpansionOption, 0, len(matches))
+	for _, match := range matches {
+		decision, err := c.makeScaleUpDecision(ctx, match)
+		if err != nil {
+			c.logger.Error("Failed to make scale-up decision",
+				zap.String("nodeGroup", match.NodeGroup.Name),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		if decision == nil {
+			continue
+		}
+
+		decisions[nodeGroupKey(match.NodeGroup)] = decision
+		options = append(options, ExpansionOption{
+			NodeGroup:    match.NodeGroup,
+			Pods:         match.MatchingPods,
+			Deficit:      match.Deficit,
+			InstanceType: decision.InstanceInfo,
+			Template:     decision.Template,
+			NodesNeeded:  decision.NodesNeeded,
+		})
+	}
+
+	selected := make([]ScaleUpDecision, 0)
+	covered := make(map[string]bool)
+	for len(options) > 0 {
+		best := c.expander.BestOption(ctx, options)
+		if best == nil {
+			break
+		}
+
+		ng := best.NodeGroup
+		decision := *decisions[nodeGroupKey(ng)]
+		decision.MatchingPods = len(best.Pods)
+		decision.Deficit = best.Deficit
+		decision.NodesNeeded = best.NodesNeeded
+		// Pods covered by earlier options may have shrunk the estimate
+		decision.NodesToAdd = c.nodesToAdd(ng, best.NodesNeeded)
+		decision.DesiredNodes = decision.CurrentNodes + decision.NodesToAdd
+		decision.Reason = scaleUpReason(decision.NodesToAdd, len(best.Pods), best.NodesNeeded)
+		selected = append(selected, decision)
+
+		c.logger.Info("Expander selected NodeGroup",
+			zap.String("expander", string(c.expander.Strategy())),
+			zap.String("nodeGroup", ng.Name),
+			zap.Int("pods", len(best.Pods)),
+			zap.Int("options", len(options)),
+		)
+
+		for _, pod := range best.Pods {
+			covered[podKey(pod)] = true
+		}
+		options = c.remainingOptions(options, ng, covered)
+	}
+
+	return selected
+}
+
+// remainingOptions drops the chosen NodeGroup and the pods it covers from the
+// options. Options left without pods, or whose remaining pods need no nodes,
+// are skipped in favour of the chosen NodeGroup.
+func (c *ScaleUpController) remainingOptions(
+	options []ExpansionOption,
+	chosen *v1alpha1.NodeGroup,
+	covered map[string]bool,
+) []ExpansionOption {
+	remaining := make([]ExpansionOption, 0, len(options))
+	for _, option := range options {
+		ng := option.NodeGroup
+		if nodeGroupKey(ng) == nodeGroupKey(chosen) {
+			continue
+		}
+
+		pods := make([]*corev1.Pod, 0, len(option.Pods))
+		for _, pod := range option.Pods {
+			if !covered[podKey(pod)] {
+				pods = append(pods, pod)
+			}
+		}
+
+		if len(pods) == 0 {
+			metrics.ScaleUpDecisionsTotal.WithLabelValues(ng.Name, ng.Namespace, "skipped_expander").Inc()
+			continue
+		}
+
+		if len(pods) < len(option.Pods) {
+			option.Pods = pods
+			option.Deficit = c.analyzer.podsDeficit(pods)
+			option.NodesNeeded = c.analyzer.EstimateNodesForPods(pods, option.Template).Nodes
+		}
+		if c.nodesToAdd(ng, option.NodesNeeded) == 0 {
+			metrics.ScaleUpDecisionsTotal.WithLabelValues(ng.Name, ng.Namespace, "skipped_expander").Inc()
+			continue
+		}
+		remaining = append(remaining, option)
+	}
+	return remaining
+}
+
+// nodeGroupKey returns the namespace/name key of a NodeGroup
+func nodeGroupKey(ng *v1alpha1.NodeGroup) string {
+	return ng.Namespace + "/" + ng.Name
+}
+
+// podKey returns the namespace/name key of a pod
+func podKey(pod *corev1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
+
 // executeScaleUp executes a scale-up decision by updating the NodeGroup
 func (c *ScaleUpController) executeScaleUp(ctx context.Context, decision ScaleUpDecision) error {
 	c.logger.Info("Executing scale-up",
@@ -333,6 +586,12 @@ func (c *ScaleUpController) executeScaleUp(ctx context.Context, decision ScaleUp
 	// Record scale event for cooldown
 	c.watcher.RecordScaleEvent(ng.Name)
 
+	// Decision metrics are emitted here rather than when deciding, so previews
+	// through GetScaleUpDecisions are not counted
+	metrics.ScaleUpDecisionsTotal.WithLabelValues(ng.Name, ng.Namespace, "executed").Inc()
+	metrics.ScaleUpDecisionNodesRequested.WithLabelValues(ng.Name, ng.Namespace).Observe(float64(decision.NodesToAdd))
+	metrics.ScaleUpExpanderSelectionsTotal.WithLabelValues(string(c.expander.Strategy()), ng.Name, ng.Namespace).Inc()
+
 	c.logger.Info("Scale-up executed successfully",
 		zap.String("nodeGroup", ng.Name),
 		zap.Int32("desiredNodes", ng.Status.DesiredNodes),
@@ -353,6 +612,7 @@ func (c *ScaleUpController) GetScaleUpDecisions(
 		return nil, fmt.Errorf("failed to get pending pods: %w", err)
 	}
 
+	pendingPods, _ = c.filterScaleUpPods(ctx, pendingPods)
 	if len(pendingPods) == 0 {
 		return nil, nil
 	}
@@ -368,29 +628,36 @@ func (c *ScaleUpController) GetScaleUpDecisions(
 	}
 
 	// Find matching NodeGroups
-	matches := c.analyzer.FindMatchingNodeGroups(pendingPods, nodeGroups)
+	matches := c.analyzer.FindMatchingNodeGroups(pendingPods, nodeGroups, c.loadVolumeTopology(ctx, pendingPods))
 	if len(matches) == 0 {
 		return nil, nil
 	}
 
 	// Make scale-up decisions
-	decisions := make([]ScaleUpDecision, 0)
-	for _, match := range matches {
-		decision, err := c.makeScaleUpDecision(ctx, match)
-		if err != nil {
-			c.logger.Error("Failed to make scale-up decision",
-				zap.String("nodeGroup", match.NodeGroup.Name),
-				zap.Error(err),
-			)
-			continue
-		}
+	return c.selectScaleUps(ctx, matches), nil
+}
+
+// loadVolumeTopology reads the volume topology of the pending pods. Pods whose
+// claims cannot be bound on any new node are logged. On failure scale-up
+// proceeds without volume topology.
+func (c *ScaleUpController) loadVolumeTopology(ctx context.Context, pendingPods []corev1.Pod) *VolumeTopology {
+	volumes, err := LoadVolumeTopology(ctx, c.client, pendingPods)
+	if err != nil {
+		c.logger.Warn("Failed to load volume topology of pending pods", zap.Error(err))
+		return nil
+	}
 
-		if decision != nil {
-			decisions = append(decisions, *decision)
+	for i := range pendingPods {
+		if claim, blocked := volumes.BlockedBy(&pendingPods[i]); blocked {
+			c.logger.Debug("Pending pod waits for its PersistentVolumeClaim to be bound, not scaling up for it",
+				zap.String("pod", pendingPods[i].Name),
+				zap.String("namespace", pendingPods[i].Namespace),
+				zap.String("claim", claim),
+			)
 		}
 	}
 
-	return decisions, nil
+	return volumes
 }
 
 // SetCreator sets the DynamicNodeGroupCreator reference (for deferred initialization)
@@ -398,36 +665,62 @@ func (c *ScaleUpController) SetCreator(creator *DynamicNodeGroupCreator) {
 	c.creator = creator
 }
 
-// createNodeGroupForPendingPods creates a dynamic NodeGroup for pending pods.
-// It groups pods by their scheduling requirements and creates a NodeGroup for the first group.
-func (c *ScaleUpController) createNodeGroupForPendingPods(
+// createNodeGroupsForPendingPods creates dynamic NodeGroups for pending pods.
+// Pods are grouped by compatible scheduling requirements and a NodeGroup is
+// created for each group, up to maxDynamicNodeGroupsPerScaleUp. Pods whose
+// volumes cannot be bound on any new node, or that request hugepages or extended
+// resources a dynamic NodeGroup does not declare, are left out. The NodeGroups
+// created before a failure are returned with the error.
+func (c *ScaleUpController) createNodeGroupsForPendingPods(
 	ctx context.Context,
 	pendingPods []corev1.Pod,
-) (*v1alpha1.NodeGroup, error) {
+	volumes *VolumeTopology,
+) ([]*v1alpha1.NodeGroup, error) {
 	if c.creator == nil {
 		return nil, fmt.Errorf("dynamic NodeGroup creator not configured")
 	}
 
-	if len(pendingPods) == 0 {
+	pods := make([]corev1.Pod, 0, len(pendingPods))
+	for i := range pendingPods {
+		if _, blocked := volumes.BlockedBy(&pendingPods[i]); blocked {
+			continue
+		}
+		if len(c.analyzer.CalculatePodResources(&pendingPods[i]).Extended) > 0 {
+			continue
+		}
+		pods = append(pods, pendingPods[i])
+	}
+	if len(pods) == 0 {
 		return nil, nil
 	}
 
-	// Use the first pending pod as representative for the NodeGroup requirements
-	// Pods with similar requirements will be able to schedule on the same NodeGroup
-	pod := &pendingPods[0]
-
-	c.logger.Info("Creating dynamic NodeGroup for pending pod",
-		zap.String("pod", pod.Name),
-		zap.String("namespace", pod.Namespace),
-	)
+	groups := c.creator.GroupPodsByRequirements(pods)
+	if len(groups) > maxDynamicNodeGroupsPerScaleUp {
+		c.logger.Info("Limiting dynamic NodeGroup creation",
+			zap.Int("podGroups", len(groups)),
+			zap.Int("limit", maxDynamicNodeGroupsPerScaleUp),
+		)
+		groups = groups[:maxDynamicNodeGroupsPerScaleUp]
+	}
 
 	// NodeGroups must be created in kube-system namespace (enforced by webhook)
 	namespace := "kube-system"
 
-	ng, err := c.creator.CreateNodeGroupForPod(ctx, pod, namespace)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create NodeGroup for pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	var created []*v1alpha1.NodeGroup
+	for _, group := range groups {
+		pod := group.Pods[0]
+		c.logger.Info("Creating dynamic NodeGroup for pending pods",
+			zap.String("pod", pod.Name),
+			zap.String("namespace", pod.Namespace),
+			zap.Int("pods", len(group.Pods)),
+		)
+
+		ng, err := c.creator.CreateNodeGroupForPods(ctx, group, namespace)
+		if err != nil {
+			return created, fmt.Errorf("failed to create NodeGroup for pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+		created = append(created, ng)
 	}
 
-	return ng, nil
+	return created, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -16,6 +17,7 @@ import (
 	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
 )
 
 // TestMakeScaleUpDecision tests scale-up decision making
@@ -333,9 +335,16 @@ func TestExecuteScaleUp(t *testing.T) {
 		Reason: "Scaling up to accommodate 5 pending pods",
 	}
 
+	metrics.ScaleUpDecisionsTotal.Reset()
+	metrics.ScaleUpExpanderSelectionsTotal.Reset()
+
 	err := controller.executeScaleUp(context.Background(), decision)
 	require.NoError(t, err)
 
+	// Decision metrics are emitted on execution
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ScaleUpDecisionsTotal.WithLabelValues("ng-1", "default", "executed")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ScaleUpExpanderSelectionsTotal.WithLabelValues(string(ExpanderLeastWaste), "ng-1", "default")))
+
 	// Verify NodeGroup was updated
 	updatedNG := &v1alpha1.NodeGroup{}
 	err = k8sClient.Get(context.Background(), client.ObjectKey{
@@ -682,9 +691,16 @@ func TestGetScaleUpDecisions(t *testing.T) {
 		},
 	}
 
+	metrics.ScaleUpDecisionsTotal.Reset()
+	metrics.ScaleUpExpanderSelectionsTotal.Reset()
+
 	decisions, err := controller.GetScaleUpDecisions(context.Background(), events)
 	require.NoError(t, err)
 
+	// Previews are not counted as executed decisions
+	assert.Zero(t, testutil.CollectAndCount(metrics.ScaleUpDecisionsTotal))
+	assert.Zero(t, testutil.CollectAndCount(metrics.ScaleUpExpanderSelectionsTotal))
+
 	require.Len(t, decisions, 1, "Should have one scale-up decision")
 	assert.Equal(t, "ng-prod", decisions[0].NodeGroup.Name)
 	assert.Equal(t, int32(3), decisions[0].DesiredNodes, "Sequential scaling: should add 1 node (2 + 1 = 3)")
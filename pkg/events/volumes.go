@@ -0,0 +1,338 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+)
+
+// podVolumes is the node topology a pod's persistent volumes require
+type podVolumes struct {
+	// terms holds the node selector terms of each volume with a topology.
+	// A node must match at least one term of every volume.
+	terms [][]corev1.NodeSelectorTerm
+
+	// blockedBy names a claim that keeps the pod pending whatever the node,
+	// such as a missing claim or an unbound claim with Immediate binding
+	blockedBy string
+}
+
+// VolumeTopology holds the topology constraints of pending pods' persistent
+// volumes, read from bound PersistentVolumes' node affinity and, for unbound
+// WaitForFirstConsumer claims, their StorageClass's allowed topologies.
+// A nil VolumeTopology places no constraints.
+type VolumeTopology struct {
+	pods map[string]*podVolumes
+
+	// datacenterLabels are the topology labels of existing nodes by datacenter,
+	// which new nodes in the same datacenter are expected to carry
+	datacenterLabels map[string]map[string]string
+}
+
+// LoadVolumeTopology reads the volume topology of the pods' persistent volume claims
+func LoadVolumeTopology(ctx context.Context, c client.Client, pods []corev1.Pod) (*VolumeTopology, error) {
+	topology := &VolumeTopology{pods: make(map[string]*podVolumes)}
+
+	for i := range pods {
+		volumes, err := loadPodVolumes(ctx, c, &pods[i])
+		if err != nil {
+			return nil, err
+		}
+		if volumes != nil {
+			topology.pods[podKey(&pods[i])] = volumes
+		}
+	}
+
+	if len(topology.pods) == 0 {
+		return topology, nil
+	}
+
+	nodeList := &corev1.NodeList{}
+	if err := c.List(ctx, nodeList); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	topology.datacenterLabels = datacenterTopologyLabels(nodeList.Items)
+
+	return topology, nil
+}
+
+// loadPodVolumes returns the topology of the pod's persistent volume claims,
+// or nil if they place no constraints on the node
+func loadPodVolumes(ctx context.Context, c client.Client, pod *corev1.Pod) (*podVolumes, error) {
+	volumes := &podVolumes{}
+
+	for _, volume := range pod.Spec.Volumes {
+		var claimName string
+		switch {
+		case volume.PersistentVolumeClaim != nil:
+			claimName = volume.PersistentVolumeClaim.ClaimName
+		case volume.Ephemeral != nil:
+			claimName = pod.Name + "-" + volume.Name
+		default:
+			continue
+		}
+
+		pvc := &corev1.PersistentVolumeClaim{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: claimName}, pvc); err != nil {
+			if apierrors.IsNotFound(err) {
+				volumes.blockedBy = claimName
+				continue
+			}
+			return nil, fmt.Errorf("failed to get PersistentVolumeClaim %s/%s: %w", pod.Namespace, claimName, err)
+		}
+
+		if pvc.Spec.VolumeName != "" {
+			pv := &corev1.PersistentVolume{}
+			if err := c.Get(ctx, types.NamespacedName{Name: pvc.Spec.VolumeName}, pv); err != nil {
+				if apierrors.IsNotFound(err) {
+					volumes.blockedBy = claimName
+					continue
+				}
+				return nil, fmt.Errorf("failed to get PersistentVolume %s: %w", pvc.Spec.VolumeName, err)
+			}
+			if pv.Spec.NodeAffinity != nil && pv.Spec.NodeAffinity.Required != nil &&
+				len(pv.Spec.NodeAffinity.Required.NodeSelectorTerms) > 0 {
+				volumes.terms = append(volumes.terms, pv.Spec.NodeAffinity.Required.NodeSelectorTerms)
+			}
+			continue
+		}
+
+		// Unbound claims without a class are bound by an external process
+		if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+			volumes.blockedBy = claimName
+			continue
+		}
+
+		storageClass := &storagev1.StorageClass{}
+		if err := c.Get(ctx, types.NamespacedName{Name: *pvc.Spec.StorageClassName}, storageClass); err != nil {
+			if apierrors.IsNotFound(err) {
+				volumes.blockedBy = claimName
+				continue
+			}
+			return nil, fmt.Errorf("failed to get StorageClass %s: %w", *pvc.Spec.StorageClassName, err)
+		}
+
+		// Immediate claims are bound before scheduling, so no node helps until then
+		if storageClass.VolumeBindingMode == nil ||
+			*storageClass.VolumeBindingMode != storagev1.VolumeBindingWaitForFirstConsumer {
+			volumes.blockedBy = claimName
+			continue
+		}
+
+		if terms := allowedTopologyTerms(storageClass.AllowedTopologies); len(terms) > 0 {
+			volumes.terms = append(volumes.terms, terms)
+		}
+	}
+
+	if len(volumes.terms) == 0 && volumes.blockedBy == "" {
+		return nil, nil
+	}
+	return volumes, nil
+}
+
+// allowedTopologyTerms converts a StorageClass's allowed topologies to node selector terms
+func allowedTopologyTerms(topologies []corev1.TopologySelectorTerm) []corev1.NodeSelectorTerm {
+	terms := make([]corev1.NodeSelectorTerm, 0, len(topologies))
+	for _, topology := range topologies {
+		term := corev1.NodeSelectorTerm{}
+		for _, expression := range topology.MatchLabelExpressions {
+			term.MatchExpressions = append(term.MatchExpressions, corev1.NodeSelectorRequirement{
+				Key:      expression.Key,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   expression.Values,
+			})
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// isTopologyLabel reports whether a node label describes the node's location
+// rather than the node itself, so all nodes in a datacenter share its value.
+// CSI drivers publish their topology under "topology.<driver>/" keys.
+func isTopologyLabel(key string) bool {
+	switch key {
+	case v1alpha1.DatacenterLabelKey,
+		corev1.LabelTopologyZone,
+		corev1.LabelTopologyRegion,
+		corev1.LabelFailureDomainBetaZone,
+		corev1.LabelFailureDomainBetaRegion:
+		return true
+	}
+	return strings.HasPrefix(key, "topology.")
+}
+
+// datacenterTopologyLabels collects the topology labels of the nodes in each datacenter
+func datacenterTopologyLabels(nodes []corev1.Node) map[string]map[string]string {
+	byDatacenter := make(map[string]map[string]string)
+	for i := range nodes {
+		datacenter, ok := nodes[i].Labels[v1alpha1.DatacenterLabelKey]
+		if !ok {
+			continue
+		}
+		labels, ok := byDatacenter[datacenter]
+		if !ok {
+			labels = make(map[string]string)
+			byDatacenter[datacenter] = labels
+		}
+		for key, value := range nodes[i].Labels {
+			if _, seen := labels[key]; !seen && isTopologyLabel(key) {
+				labels[key] = value
+			}
+		}
+	}
+	return byDatacenter
+}
+
+// BlockedBy returns the claim that keeps the pod pending whatever node is
+// added, if there is one
+func (v *VolumeTopology) BlockedBy(pod *corev1.Pod) (string, bool) {
+	if v == nil {
+		return "", false
+	}
+	volumes, ok := v.pods[podKey(pod)]
+	if !ok || volumes.blockedBy == "" {
+		return "", false
+	}
+	return volumes.blockedBy, true
+}
+
+// NodeGroupSatisfies reports whether a new node of the NodeGroup can satisfy
+// the topology of the pod's volumes. New nodes are expected to carry the
+// NodeGroup's labels and the topology labels of existing nodes in its
+// datacenter. If the datacenter has no nodes yet, topology labels other than
+// the datacenter label are unknown and assumed to match, unless every value
+// the volume allows is carried by nodes of other datacenters.
+func (v *VolumeTopology) NodeGroupSatisfies(pod *corev1.Pod, ng *v1alpha1.NodeGroup) bool {
+	if v == nil {
+		return true
+	}
+	volumes, ok := v.pods[podKey(pod)]
+	if !ok {
+		return true
+	}
+	if volumes.blockedBy != "" {
+		return false
+	}
+
+	datacenterLabels, known := v.datacenterLabels[ng.Spec.DatacenterID]
+	labels := make(map[string]string, len(datacenterLabels)+len(ng.Spec.Labels)+2)
+	for key, value := range datacenterLabels {
+		labels[key] = value
+	}
+	for key, value := range ng.Spec.Labels {
+		labels[key] = value
+	}
+	labels[v1alpha1.DatacenterLabelKey] = ng.Spec.DatacenterID
+	labels[v1alpha1.NodeGroupLabelKey] = ng.Name
+
+	var taken map[string]map[string]bool
+	if !known {
+		taken = v.takenTopology()
+	}
+	return volumes.matches(labels, "", taken)
+}
+
+// takenTopology collects the topology label values carried by existing nodes,
+// which a datacenter without nodes cannot have
+func (v *VolumeTopology) takenTopology() map[string]map[string]bool {
+	taken := make(map[string]map[string]bool)
+	for _, labels := range v.datacenterLabels {
+		for key, value := range labels {
+			if taken[key] == nil {
+				taken[key] = make(map[string]bool)
+			}
+			taken[key][value] = true
+		}
+	}
+	return taken
+}
+
+// nodeSatisfies reports whether an existing node satisfies the topology of the pod's volumes
+func (v *VolumeTopology) nodeSatisfies(pod *corev1.Pod, node *corev1.Node) bool {
+	if v == nil {
+		return true
+	}
+	volumes, ok := v.pods[podKey(pod)]
+	if !ok {
+		return true
+	}
+	if volumes.blockedBy != "" {
+		return false
+	}
+	return volumes.matches(node.Labels, node.Name, nil)
+}
+
+// matches checks every volume's terms against a node's labels and name. An
+// empty name stands for a node not created yet, which no field selector
+// matches. A non-nil taken treats missing topology labels as matching any
+// value not in taken.
+func (p *podVolumes) matches(labels map[string]string, name string, taken map[string]map[string]bool) bool {
+	node := &corev1.Node{}
+	node.Name = name
+	node.Labels = labels
+
+	for _, terms := range p.terms {
+		matched := false
+		for i := range terms {
+			if termMatches(&terms[i], node, taken) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// termMatches checks a node selector term; its requirements are ANDed
+func termMatches(term *corev1.NodeSelectorTerm, node *corev1.Node, taken map[string]map[string]bool) bool {
+	for i := range term.MatchExpressions {
+		req := &term.MatchExpressions[i]
+		if _, exists := node.Labels[req.Key]; !exists && taken != nil && isTopologyLabel(req.Key) {
+			if req.Operator != corev1.NodeSelectorOpIn {
+				continue
+			}
+			free := false
+			for _, value := range req.Values {
+				if !taken[req.Key][value] {
+					free = true
+					break
+				}
+			}
+			if !free {
+				return false
+			}
+			continue
+		}
+		if !nodeMatchesRequirement(node, req) {
+			return false
+		}
+	}
+	for _, req := range term.MatchFields {
+		if req.Key != "metadata.name" || node.Name == "" {
+			return false
+		}
+		in := false
+		for _, value := range req.Values {
+			if value == node.Name {
+				in = true
+				break
+			}
+		}
+		if in != (req.Operator == corev1.NodeSelectorOpIn) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,229 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+)
+
+const csiDatacenterKey = "topology.csi.vpsie.com/datacenter"
+
+func newVolumePod(name string, claims ...string) corev1.Pod {
+	pod := newPredicatePod(name, "", "500m", "512Mi", nil)
+	pod.Status.Phase = corev1.PodPending
+	for _, claim := range claims {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: claim,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+			},
+		})
+	}
+	return pod
+}
+
+func newVolumeClient(t *testing.T) client.Client {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, storagev1.AddToScheme(scheme))
+
+	waitForFirstConsumer := storagev1.VolumeBindingWaitForFirstConsumer
+	immediate := storagev1.VolumeBindingImmediate
+	className := func(name string) *string { return &name }
+
+	newClaim := func(name, class, volume string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: className(class),
+				VolumeName:       volume,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+				},
+			},
+		}
+	}
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			&storagev1.StorageClass{
+				ObjectMeta:        metav1.ObjectMeta{Name: "dc2-wffc"},
+				Provisioner:       "csi.vpsie.com",
+				VolumeBindingMode: &waitForFirstConsumer,
+				AllowedTopologies: []corev1.TopologySelectorTerm{{
+					MatchLabelExpressions: []corev1.TopologySelectorLabelRequirement{
+						{Key: csiDatacenterKey, Values: []string{"dc-2"}},
+					},
+				}},
+			},
+			&storagev1.StorageClass{
+				ObjectMeta:        metav1.ObjectMeta{Name: "immediate"},
+				Provisioner:       "csi.vpsie.com",
+				VolumeBindingMode: &immediate,
+			},
+			&corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-dc1"},
+				Spec: corev1.PersistentVolumeSpec{
+					NodeAffinity: &corev1.VolumeNodeAffinity{
+						Required: &corev1.NodeSelector{
+							NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+								MatchExpressions: []corev1.NodeSelectorRequirement{
+									{Key: csiDatacenterKey, Operator: corev1.NodeSelectorOpIn, Values: []string{"dc-1"}},
+								},
+							}},
+						},
+					},
+				},
+			},
+			newClaim("bound-dc1", "dc2-wffc", "pv-dc1"),
+			newClaim("wffc-dc2", "dc2-wffc", ""),
+			newClaim("pending-immediate", "immediate", ""),
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name: "node-dc1",
+				Labels: map[string]string{
+					v1alpha1.DatacenterLabelKey: "dc-1",
+					csiDatacenterKey:            "dc-1",
+					corev1.LabelHostname:        "node-dc1",
+				},
+			}},
+		).
+		Build()
+}
+
+func TestLoadVolumeTopology(t *testing.T) {
+	k8sClient := newVolumeClient(t)
+
+	pods := []corev1.Pod{
+		newVolumePod("bound", "bound-dc1"),
+		newVolumePod("wffc", "wffc-dc2"),
+		newVolumePod("immediate", "pending-immediate"),
+		newVolumePod("missing", "does-not-exist"),
+		newVolumePod("stateless"),
+	}
+
+	volumes, err := LoadVolumeTopology(context.Background(), k8sClient, pods)
+	require.NoError(t, err)
+
+	_, blocked := volumes.BlockedBy(&pods[0])
+	assert.False(t, blocked)
+	claim, blocked := volumes.BlockedBy(&pods[2])
+	assert.True(t, blocked)
+	assert.Equal(t, "pending-immediate", claim)
+	_, blocked = volumes.BlockedBy(&pods[3])
+	assert.True(t, blocked)
+	assert.NotContains(t, volumes.pods, podKey(&pods[4]))
+
+	// Only topology labels describe the datacenter
+	assert.Equal(t, map[string]string{
+		v1alpha1.DatacenterLabelKey: "dc-1",
+		csiDatacenterKey:            "dc-1",
+	}, volumes.datacenterLabels["dc-1"])
+}
+
+func TestVolumeTopologyNodeGroupSatisfies(t *testing.T) {
+	k8sClient := newVolumeClient(t)
+
+	pods := []corev1.Pod{
+		newVolumePod("bound", "bound-dc1"),
+		newVolumePod("wffc", "wffc-dc2"),
+		newVolumePod("immediate", "pending-immediate"),
+		newVolumePod("stateless"),
+	}
+	volumes, err := LoadVolumeTopology(context.Background(), k8sClient, pods)
+	require.NoError(t, err)
+
+	newNodeGroup := func(datacenter string) *v1alpha1.NodeGroup {
+		return &v1alpha1.NodeGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "ng-" + datacenter, Namespace: "kube-system"},
+			Spec:       v1alpha1.NodeGroupSpec{DatacenterID: datacenter},
+		}
+	}
+	dc1 := newNodeGroup("dc-1")
+	dc2 := newNodeGroup("dc-2")
+	dc3 := newNodeGroup("dc-3")
+
+	// A bound volume is only accessible from its datacenter, even where the
+	// CSI topology label is unknown
+	assert.True(t, volumes.NodeGroupSatisfies(&pods[0], dc1))
+	assert.False(t, volumes.NodeGroupSatisfies(&pods[0], dc2))
+
+	// dc-2 has no nodes, so its CSI topology label is unknown and assumed to
+	// match; dc-1's nodes show it is outside the allowed topology
+	assert.False(t, volumes.NodeGroupSatisfies(&pods[1], dc1))
+	assert.True(t, volumes.NodeGroupSatisfies(&pods[1], dc2))
+
+	// Claims waiting for Immediate binding are not helped by any NodeGroup
+	assert.False(t, volumes.NodeGroupSatisfies(&pods[2], dc1))
+	assert.False(t, volumes.NodeGroupSatisfies(&pods[2], dc3))
+
+	assert.True(t, volumes.NodeGroupSatisfies(&pods[3], dc2))
+
+	// Without volume topology nothing is filtered
+	var none *VolumeTopology
+	assert.True(t, none.NodeGroupSatisfies(&pods[0], dc2))
+}
+
+func TestFindMatchingNodeGroupsVolumeTopology(t *testing.T) {
+	k8sClient := newVolumeClient(t)
+	analyzer := NewResourceAnalyzer(zap.NewNop(), nil)
+
+	pending := []corev1.Pod{newVolumePod("bound", "bound-dc1")}
+	nodeGroups := []v1alpha1.NodeGroup{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ng-dc2", Namespace: "kube-system", Labels: map[string]string{v1alpha1.ManagedLabelKey: v1alpha1.ManagedLabelValue}},
+			Spec:       v1alpha1.NodeGroupSpec{DatacenterID: "dc-2", MinNodes: 0, MaxNodes: 5},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ng-dc1", Namespace: "kube-system", Labels: map[string]string{v1alpha1.ManagedLabelKey: v1alpha1.ManagedLabelValue}},
+			Spec:       v1alpha1.NodeGroupSpec{DatacenterID: "dc-1", MinNodes: 0, MaxNodes: 5},
+		},
+	}
+
+	// Without volume topology both NodeGroups match
+	assert.Len(t, analyzer.FindMatchingNodeGroups(pending, nodeGroups, nil), 2)
+
+	volumes, err := LoadVolumeTopology(context.Background(), k8sClient, pending)
+	require.NoError(t, err)
+	matches := analyzer.FindMatchingNodeGroups(pending, nodeGroups, volumes)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "ng-dc1", matches[0].NodeGroup.Name)
+}
+
+func TestClusterSnapshotClassifyVolume(t *testing.T) {
+	k8sClient := newVolumeClient(t)
+
+	nodes := []corev1.Node{
+		newPredicateNode("node-dc2", "4", "8Gi", 110, map[string]string{
+			v1alpha1.DatacenterLabelKey: "dc-2",
+			csiDatacenterKey:            "dc-2",
+		}),
+	}
+	pending := newVolumePod("bound", "bound-dc1")
+
+	snapshot := newClusterSnapshot(nodes, nil)
+	result := snapshot.classify(&pending, "")
+	assert.True(t, result.FromMessage, "fits without volume topology")
+
+	volumes, err := LoadVolumeTopology(context.Background(), k8sClient, []corev1.Pod{pending})
+	require.NoError(t, err)
+	snapshot.volumes = volumes
+
+	result = snapshot.classify(&pending, "")
+	assert.Equal(t, ConstraintVolume, result.Constraint)
+	assert.Equal(t, 1, result.NodeFailures[ConstraintVolume])
+}
@@ -47,6 +47,15 @@ var (
 	cpuPatternRe    = regexp.MustCompile(`insufficient.*cpu`)
 	memoryPatternRe = regexp.MustCompile(`insufficient.*memory`)
 
+	// Ephemeral storage, hugepages and extended resource patterns, e.g.
+	// "insufficient ephemeral-storage", "insufficient hugepages-2mi" or
+	// "insufficient nvidia.com/gpu"
+	ephemeralStoragePatternRe = regexp.MustCompile(`insufficient ephemeral-storage`)
+	extendedResourcePatternRe = regexp.MustCompile(`insufficient (hugepages-[0-9a-z]+|[a-z0-9]([a-z0-9.-]*[a-z0-9])?/[a-z0-9]([a-z0-9._-]*[a-z0-9])?)`)
+
+	// Volume patterns (binding and topology of persistent volumes)
+	volumePatternRe = regexp.MustCompile(`volume node affinity conflict|no available volume zone|unbound immediate persistentvolumeclaims|didn't find available persistent volumes|persistentvolumeclaim ".*" not found`)
+
 	// Taint patterns
 	taintPatternRe = regexp.MustCompile(`untolerated taint|had taint|taints that the pod didn't tolerate`)
 
@@ -71,6 +80,17 @@ const (
 	// ConstraintPods indicates too many pods
 	ConstraintPods ResourceConstraint = "pods"
 
+	// ConstraintEphemeralStorage indicates insufficient ephemeral storage
+	ConstraintEphemeralStorage ResourceConstraint = "ephemeral_storage"
+
+	// ConstraintExtendedResource indicates insufficient hugepages or an
+	// insufficient extended resource such as "nvidia.com/gpu"
+	ConstraintExtendedResource ResourceConstraint = "extended_resource"
+
+	// ConstraintVolume indicates the pod's persistent volumes couldn't be bound
+	// or are not accessible from the node's topology
+	ConstraintVolume ResourceConstraint = "volume"
+
 	// ConstraintNodeSelector indicates pod's node selector couldn't be satisfied
 	ConstraintNodeSelector ResourceConstraint = "node_selector"
 
@@ -109,6 +129,7 @@ type EventWatcher struct {
 	lastScaleTime       map[string]time.Time
 	lastScaleTimeMu     sync.RWMutex
 	stabilizationWindow time.Duration
+	detectionMode       DetectionMode
 }
 
 // ScaleUpHandler is called when scale-up is needed
@@ -130,13 +151,44 @@ func NewEventWatcher(
 		scaleUpHandler:      scaleUpHandler,
 		lastScaleTime:       make(map[string]time.Time),
 		stabilizationWindow: DefaultStabilizationWindow,
+		detectionMode:       DetectionModeEvents,
 	}
 }
 
 // Start starts the event watcher
 func (w *EventWatcher) Start(ctx context.Context) error {
-	w.logger.Info("Starting event watcher")
+	w.logger.Info("Starting event watcher",
+		zap.String("detectionMode", string(w.detectionMode)),
+	)
+
+	var err error
+	if w.detectionMode == DetectionModePods {
+		err = w.startPodInformer(ctx)
+	} else {
+		err = w.startEventInformer(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Start informer
+	go w.informer.Run(w.stopCh)
+
+	// Wait for cache sync
+	if !cache.WaitForCacheSync(w.stopCh, w.informer.HasSynced) {
+		return fmt.Errorf("failed to sync %s cache", w.detectionMode)
+	}
+
+	w.logger.Info("Event watcher started and cache synced")
+
+	// Start event processor
+	go w.processEventsLoop(ctx)
+
+	return nil
+}
 
+// startEventInformer creates the informer for FailedScheduling events
+func (w *EventWatcher) startEventInformer(ctx context.Context) error {
 	// Create informer factory
 	informerFactory := informers.NewSharedInformerFactory(w.clientset, 0)
 	w.informer = informerFactory.Core().V1().Events().Informer()
@@ -162,19 +214,6 @@ func (w *EventWatcher) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to add event handler: %w", err)
 	}
 
-	// Start informer
-	go w.informer.Run(w.stopCh)
-
-	// Wait for cache sync
-	if !cache.WaitForCacheSync(w.stopCh, w.informer.HasSynced) {
-		return fmt.Errorf("failed to sync event cache")
-	}
-
-	w.logger.Info("Event watcher started and cache synced")
-
-	// Start event processor
-	go w.processEventsLoop(ctx)
-
 	return nil
 }
 
@@ -218,16 +257,17 @@ func (w *EventWatcher) handleEvent(ctx context.Context, event *corev1.Event) {
 		return
 	}
 
-	// Create scheduling event
-	schedEvent := SchedulingEvent{
+	w.bufferEvent(SchedulingEvent{
 		Pod:        pod,
 		Event:      event,
 		Timestamp:  time.Now(),
 		Constraint: constraint,
 		Message:    event.Message,
-	}
+	})
+}
 
-	// Add to buffer with size limit to prevent unbounded growth
+// bufferEvent adds a scheduling event to the buffer with a size limit to prevent unbounded growth
+func (w *EventWatcher) bufferEvent(schedEvent SchedulingEvent) {
 	w.eventBufferMu.Lock()
 	if len(w.eventBuffer) >= MaxEventBufferSize {
 		// Drop oldest event to make room
@@ -317,6 +357,13 @@ func (w *EventWatcher) processEvents(ctx context.Context) {
 		return
 	}
 
+	// Pod conditions carry no per-node breakdown, so classify them against the cluster
+	if w.detectionMode == DetectionModePods {
+		if snapshot := w.classifyPodEvents(ctx, recentEvents); snapshot != nil {
+			ctx = withClusterSnapshot(ctx, snapshot)
+		}
+	}
+
 	// Call scale-up handler
 	if w.scaleUpHandler != nil {
 		if err := w.scaleUpHandler(ctx, recentEvents); err != nil {
@@ -376,6 +423,15 @@ func parseConstraint(message string) ResourceConstraint {
 		return ConstraintPods
 	}
 
+	// Check extended resources and ephemeral storage before CPU and memory,
+	// since they narrow which NodeGroups and offerings can help the most
+	if extendedResourcePatternRe.MatchString(message) {
+		return ConstraintExtendedResource
+	}
+	if ephemeralStoragePatternRe.MatchString(message) {
+		return ConstraintEphemeralStorage
+	}
+
 	// Check CPU
 	if cpuPatternRe.MatchString(message) {
 		return ConstraintCPU
@@ -386,6 +442,11 @@ func parseConstraint(message string) ResourceConstraint {
 		return ConstraintMemory
 	}
 
+	// Check volumes (before node selector since volume messages also list available nodes)
+	if volumePatternRe.MatchString(message) {
+		return ConstraintVolume
+	}
+
 	// Check taints (often combined with affinity in messages)
 	if taintPatternRe.MatchString(message) {
 		return ConstraintTaint
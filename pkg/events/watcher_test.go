@@ -55,6 +55,47 @@ func TestParseConstraint(t *testing.T) {
 			message:    "INSUFFICIENT MEMORY",
 			constraint: ConstraintMemory,
 		},
+		// Volume constraints
+		{
+			name:       "Volume node affinity conflict",
+			message:    "0/3 nodes are available: 3 node(s) had volume node affinity conflict.",
+			constraint: ConstraintVolume,
+		},
+		{
+			name:       "Unbound immediate claim",
+			message:    "0/3 nodes are available: pod has unbound immediate PersistentVolumeClaims.",
+			constraint: ConstraintVolume,
+		},
+		{
+			name:       "No persistent volumes to bind",
+			message:    "0/3 nodes are available: 1 node(s) had untolerated taint {node-role.kubernetes.io/control-plane: }, 2 node(s) didn't find available persistent volumes to bind.",
+			constraint: ConstraintVolume,
+		},
+		{
+			name:       "Volume conflict mixed with CPU",
+			message:    "0/3 nodes are available: 1 Insufficient cpu, 2 node(s) had volume node affinity conflict.",
+			constraint: ConstraintCPU,
+		},
+		{
+			name:       "Insufficient ephemeral storage",
+			message:    "0/3 nodes are available: 3 Insufficient ephemeral-storage.",
+			constraint: ConstraintEphemeralStorage,
+		},
+		{
+			name:       "Insufficient hugepages",
+			message:    "0/3 nodes are available: 3 Insufficient hugepages-2Mi.",
+			constraint: ConstraintExtendedResource,
+		},
+		{
+			name:       "Insufficient extended resource",
+			message:    "0/3 nodes are available: 3 Insufficient nvidia.com/gpu.",
+			constraint: ConstraintExtendedResource,
+		},
+		{
+			name:       "Extended resource mixed with CPU",
+			message:    "0/3 nodes are available: 1 Insufficient cpu, 2 Insufficient nvidia.com/gpu.",
+			constraint: ConstraintExtendedResource,
+		},
 		// Taint constraints
 		{
 			name:       "Taint constraint - didn't tolerate",
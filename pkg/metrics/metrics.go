@@ -272,6 +272,17 @@ var (
 		[]string{"nodegroup", "namespace", "error_type"},
 	)
 
+	// ConsolidationsTotal tracks consolidations of nodes onto fewer, larger nodes
+	// by result (started, completed, failed)
+	ConsolidationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "consolidations_total",
+			Help:      "Total number of node consolidations by result",
+		},
+		[]string{"nodegroup", "namespace", "result"},
+	)
+
 	// UnschedulablePodsTotal tracks the number of unschedulable pods detected
 	UnschedulablePodsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -359,7 +370,7 @@ var (
 		prometheus.HistogramOpts{
 			Namespace: Namespace,
 			Name:      "node_drain_duration_seconds",
-			Help:      "Time taken to drain a node during scale-down",
+			Help:      "Time taken to drain a node",
 			Buckets:   prometheus.ExponentialBuckets(1, 2, 12), // 1s to ~68 minutes
 		},
 		[]string{"nodegroup", "namespace", "result"}, // result: success, timeout, error
@@ -376,6 +387,28 @@ var (
 		[]string{"nodegroup", "namespace"},
 	)
 
+	// NodeDrainPodsForceDeletedTotal tracks pods deleted directly after they
+	// could not be evicted within the NodeGroup's force-delete timeout
+	NodeDrainPodsForceDeletedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "node_drain_pods_force_deleted_total",
+			Help:      "Total number of pods force-deleted during node drain",
+		},
+		[]string{"nodegroup", "namespace"},
+	)
+
+	// NodeDrainProgress tracks the percentage of a node's pods evicted while
+	// the node is drained
+	NodeDrainProgress = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "node_drain_progress_percent",
+			Help:      "Percentage of the pods of a node being drained whose eviction was accepted",
+		},
+		[]string{"node", "nodegroup", "namespace"},
+	)
+
 	// Phase 2 Enhanced Metrics
 
 	// ReconciliationQueueDepth tracks the current depth of the reconciliation queue
@@ -567,6 +600,16 @@ var (
 		[]string{"result", "namespace"}, // result: success, failure
 	)
 
+	// DynamicNodeGroupDeletionsTotal tracks auto-managed NodeGroups deleted after their idle TTL
+	DynamicNodeGroupDeletionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "dynamic_nodegroup_idle_deletions_total",
+			Help:      "Total number of auto-managed NodeGroups deleted after staying idle for their idle TTL",
+		},
+		[]string{"namespace"},
+	)
+
 	// EventWatcher Metrics
 
 	// EventBufferSize tracks the current size of the event buffer
@@ -594,7 +637,7 @@ var (
 			Name:      "scale_up_decisions_total",
 			Help:      "Total number of scale-up decisions made",
 		},
-		[]string{"nodegroup", "namespace", "result"}, // result: executed, skipped_cooldown, skipped_max_capacity
+		[]string{"nodegroup", "namespace", "result"}, // result: executed, skipped_cooldown, skipped_max_capacity, skipped_provisioning, skipped_expander
 	)
 
 	// ScaleUpDecisionNodesRequested tracks nodes requested in scale-up decisions
@@ -608,6 +651,26 @@ var (
 		[]string{"nodegroup", "namespace"},
 	)
 
+	// ScaleUpExpanderSelectionsTotal tracks NodeGroups chosen by the scale-up expander
+	ScaleUpExpanderSelectionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "scale_up_expander_selections_total",
+			Help:      "Total number of NodeGroups chosen by the scale-up expander",
+		},
+		[]string{"expander", "nodegroup", "namespace"},
+	)
+
+	// ScaleUpPodsIgnoredTotal tracks pending pods that did not trigger scale-up
+	ScaleUpPodsIgnoredTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "scale_up_pods_ignored_total",
+			Help:      "Total number of pending pods ignored for scale-up",
+		},
+		[]string{"namespace", "reason"}, // reason: expendable, preemption
+	)
+
 	// WebhookNamespaceValidationRejectionsTotal tracks namespace validation rejections in webhooks
 	WebhookNamespaceValidationRejectionsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -665,6 +728,105 @@ var (
 		[]string{"reason"},
 		// reason: timeout, api_error, not_found
 	)
+
+	// Cost Pricing Metrics
+
+	// CostPricingSourceTotal tracks which price source priced each cost decision
+	CostPricingSourceTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "cost_pricing_source_total",
+			Help:      "Total number of cost lookups by the price source that served them",
+		},
+		[]string{"source", "operation"},
+		// source: live, catalog
+		// operation: offering_cost, cheapest_offering, lowest_impact_offering
+	)
+
+	// PriceCatalogDriftPercent tracks the price difference between the offline catalog and live offerings
+	PriceCatalogDriftPercent = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "price_catalog_drift_percent",
+			Help:      "Percentage difference between the catalog monthly price and the live monthly price per offering",
+		},
+		[]string{"offering"},
+	)
+
+	// PriceCatalogMissingOfferings tracks catalog offerings that no longer exist in the live API
+	PriceCatalogMissingOfferings = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "price_catalog_missing_offerings",
+			Help:      "Number of price catalog offerings not found in the live VPSie offerings",
+		},
+	)
+
+	// NodeGroupEstimatedEmissions tracks the estimated emissions of a NodeGroup's nodes
+	NodeGroupEstimatedEmissions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "nodegroup_estimated_emissions_grams_per_hour",
+			Help:      "Estimated emissions of the NodeGroup's nodes in gCO2e per hour, by datacenter",
+		},
+		[]string{"nodegroup", "namespace", "datacenter"},
+	)
+
+	// CarbonPlacementDecisionsTotal tracks carbon-aware offering and datacenter choices
+	CarbonPlacementDecisionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "carbon_placement_decisions_total",
+			Help:      "Total number of carbon-aware placement decisions by chosen datacenter",
+		},
+		[]string{"component", "objective", "datacenter"},
+		// component: offering, dynamic_nodegroup, multi_region
+		// objective: cost, balanced, low-carbon
+	)
+
+	// Predictive Scaling Metrics
+
+	// PredictiveScalingPredictedNodes tracks the node demand forecast for the next horizon
+	PredictiveScalingPredictedNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "predictive_scaling_predicted_nodes",
+			Help:      "Node demand forecast for a NodeGroup over the next forecast horizon",
+		},
+		[]string{"nodegroup", "namespace"},
+	)
+
+	// PredictiveScalingObservedNodes tracks the node demand observed at the last sample
+	PredictiveScalingObservedNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "predictive_scaling_observed_nodes",
+			Help:      "Node demand observed for a NodeGroup: nodes running pods plus nodes needed for pending pods",
+		},
+		[]string{"nodegroup", "namespace"},
+	)
+
+	// PredictiveScalingErrorNodes tracks forecast error once the forecast window has passed
+	PredictiveScalingErrorNodes = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "predictive_scaling_error_nodes",
+			Help:      "Predicted minus actual peak node demand over each elapsed forecast window",
+			Buckets:   []float64{-8, -4, -2, -1, 0, 1, 2, 4, 8},
+		},
+		[]string{"nodegroup", "namespace"},
+	)
+
+	// PredictiveScalingPreScalesTotal tracks forecasts exceeding the NodeGroup's desired nodes
+	PredictiveScalingPreScalesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "predictive_scaling_pre_scales_total",
+			Help:      "Total number of forecasts above a NodeGroup's desired nodes, applied or not",
+		},
+		[]string{"nodegroup", "namespace", "mode"},
+		// mode: shadow (recorded only), active (applied to status)
+	)
 )
 
 // RegisterMetrics registers all metrics with the controller-runtime metrics registry
@@ -696,6 +858,7 @@ func RegisterMetrics() {
 		ScaleUpNodesAdded,
 		ScaleDownNodesRemoved,
 		ScaleDownErrorsTotal,
+		ConsolidationsTotal,
 		UnschedulablePodsTotal,
 		PendingPodsGauge,
 		NodeProvisioningDuration,
@@ -706,6 +869,8 @@ func RegisterMetrics() {
 		SafetyCheckFailuresTotal,
 		NodeDrainDuration,
 		NodeDrainPodsEvicted,
+		NodeDrainPodsForceDeletedTotal,
+		NodeDrainProgress,
 		// Phase 2 Enhanced Metrics
 		ReconciliationQueueDepth,
 		ScalingDecisionsTotal,
@@ -728,10 +893,13 @@ func RegisterMetrics() {
 		AuditEventsTotal,
 		// Dynamic NodeGroup and Event Watcher Metrics
 		DynamicNodeGroupCreationsTotal,
+		DynamicNodeGroupDeletionsTotal,
 		EventBufferSize,
 		EventBufferDropped,
 		ScaleUpDecisionsTotal,
 		ScaleUpDecisionNodesRequested,
+		ScaleUpExpanderSelectionsTotal,
+		ScaleUpPodsIgnoredTotal,
 		// Webhook Metrics
 		WebhookNamespaceValidationRejectionsTotal,
 		// VPSieNode TTL Garbage Collection Metrics
@@ -740,6 +908,20 @@ func RegisterMetrics() {
 		VPSieNodeDiscoveryDuration,
 		VPSieNodeDiscoveryStrategyUsed,
 		VPSieNodeDiscoveryFailuresTotal,
+		// Cost Pricing Metrics
+		CostPricingSourceTotal,
+		PriceCatalogDriftPercent,
+		PriceCatalogMissingOfferings,
+
+		// Carbon Metrics
+		NodeGroupEstimatedEmissions,
+		CarbonPlacementDecisionsTotal,
+
+		// Predictive Scaling Metrics
+		PredictiveScalingPredictedNodes,
+		PredictiveScalingObservedNodes,
+		PredictiveScalingErrorNodes,
+		PredictiveScalingPreScalesTotal,
 	)
 }
 
@@ -769,6 +951,7 @@ func ResetMetrics() {
 	ScaleUpNodesAdded.Reset()
 	ScaleDownNodesRemoved.Reset()
 	ScaleDownErrorsTotal.Reset()
+	ConsolidationsTotal.Reset()
 	UnschedulablePodsTotal.Reset()
 	PendingPodsGauge.Reset()
 	NodeProvisioningDuration.Reset()
@@ -779,6 +962,8 @@ func ResetMetrics() {
 	SafetyCheckFailuresTotal.Reset()
 	NodeDrainDuration.Reset()
 	NodeDrainPodsEvicted.Reset()
+	NodeDrainPodsForceDeletedTotal.Reset()
+	NodeDrainProgress.Reset()
 	// Phase 2 Enhanced Metrics
 	ReconciliationQueueDepth.Reset()
 	ScalingDecisionsTotal.Reset()
@@ -792,8 +977,11 @@ func ResetMetrics() {
 	NodeGroupCostCurrent.Reset()
 	// Dynamic NodeGroup and Event Watcher Metrics
 	DynamicNodeGroupCreationsTotal.Reset()
+	DynamicNodeGroupDeletionsTotal.Reset()
 	ScaleUpDecisionsTotal.Reset()
 	ScaleUpDecisionNodesRequested.Reset()
+	ScaleUpExpanderSelectionsTotal.Reset()
+	ScaleUpPodsIgnoredTotal.Reset()
 	// Webhook Metrics
 	WebhookNamespaceValidationRejectionsTotal.Reset()
 	// VPSieNode TTL Garbage Collection Metrics
@@ -802,4 +990,14 @@ func ResetMetrics() {
 	VPSieNodeDiscoveryStrategyUsed.Reset()
 	VPSieNodeDiscoveryFailuresTotal.Reset()
 	// Note: VPSieNodeDiscoveryDuration is a Histogram without Reset() method
+	// Cost Pricing Metrics
+	CostPricingSourceTotal.Reset()
+	PriceCatalogDriftPercent.Reset()
+	PriceCatalogMissingOfferings.Set(0)
+	NodeGroupEstimatedEmissions.Reset()
+	CarbonPlacementDecisionsTotal.Reset()
+	PredictiveScalingPredictedNodes.Reset()
+	PredictiveScalingObservedNodes.Reset()
+	PredictiveScalingErrorNodes.Reset()
+	PredictiveScalingPreScalesTotal.Reset()
 }
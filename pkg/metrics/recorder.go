@@ -20,8 +20,8 @@ func RecordNodeGroupMetrics(ng *v1alpha1.NodeGroup) {
 	NodeGroupDesiredNodes.With(labels).Set(float64(ng.Status.DesiredNodes))
 	NodeGroupCurrentNodes.With(labels).Set(float64(ng.Status.CurrentNodes))
 	NodeGroupReadyNodes.With(labels).Set(float64(ng.Status.ReadyNodes))
-	NodeGroupMinNodes.With(labels).Set(float64(ng.Spec.MinNodes))
-	NodeGroupMaxNodes.With(labels).Set(float64(ng.Spec.MaxNodes))
+	NodeGroupMinNodes.With(labels).Set(float64(v1alpha1.EffectiveMinNodes(ng)))
+	NodeGroupMaxNodes.With(labels).Set(float64(v1alpha1.EffectiveMaxNodes(ng)))
 }
 
 // RecordVPSieNodePhase records the phase of a VPSieNode
@@ -0,0 +1,75 @@
+package predictive
+
+import (
+	"math"
+	"time"
+)
+
+// Sample is the node demand of a NodeGroup observed at a point in time
+type Sample struct {
+	Time   time.Time
+	Demand int32
+}
+
+// History holds a NodeGroup's demand samples in time order
+type History struct {
+	samples []Sample
+}
+
+// Add appends a sample and drops samples older than the retention
+func (h *History) Add(sample Sample, retention time.Duration) {
+	h.samples = append(h.samples, sample)
+
+	cutoff := sample.Time.Add(-retention)
+	drop := 0
+	for drop < len(h.samples) && h.samples[drop].Time.Before(cutoff) {
+		drop++
+	}
+	if drop > 0 {
+		h.samples = append([]Sample(nil), h.samples[drop:]...)
+	}
+}
+
+// Len returns the number of samples held
+func (h *History) Len() int {
+	return len(h.samples)
+}
+
+// Peak returns the highest demand sampled after from and up to and including to
+func (h *History) Peak(from, to time.Time) (int32, bool) {
+	var peak int32
+	found := false
+	for _, s := range h.samples {
+		if !s.Time.After(from) || s.Time.After(to) {
+			continue
+		}
+		if !found || s.Demand > peak {
+			peak = s.Demand
+			found = true
+		}
+	}
+	return peak, found
+}
+
+// Forecast predicts the peak demand between now and now+horizon from the
+// same window in each of the previous lookback seasons. The peaks of the
+// seasons with samples are averaged and rounded up. It returns false if no
+// previous season has samples in the window.
+func Forecast(h *History, now time.Time, horizon, season time.Duration, lookback int) (int32, bool) {
+	var sum float64
+	seasons := 0
+	for i := 1; i <= lookback; i++ {
+		start := now.Add(-time.Duration(i) * season)
+		peak, ok := h.Peak(start, start.Add(horizon))
+		if !ok {
+			continue
+		}
+		sum += float64(peak)
+		seasons++
+	}
+
+	if seasons == 0 {
+		return 0, false
+	}
+	return int32(math.Ceil(sum / float64(seasons))), true
+}
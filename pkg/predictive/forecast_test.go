@@ -0,0 +1,65 @@
+package predictive
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	start := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	h := &History{}
+	for i := 0; i < 6; i++ {
+		h.Add(Sample{Time: start.Add(time.Duration(i) * time.Hour), Demand: int32(i)}, 3*time.Hour)
+	}
+
+	// Samples older than the retention are dropped
+	assert.Equal(t, 4, h.Len())
+
+	peak, ok := h.Peak(start.Add(3*time.Hour), start.Add(5*time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, int32(5), peak)
+
+	// The window excludes its start
+	peak, ok = h.Peak(start.Add(3*time.Hour), start.Add(4*time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, int32(4), peak)
+
+	_, ok = h.Peak(start.Add(6*time.Hour), start.Add(7*time.Hour))
+	assert.False(t, ok)
+}
+
+func TestForecast(t *testing.T) {
+	day := 24 * time.Hour
+	horizon := 30 * time.Minute
+	now := time.Date(2026, 1, 8, 8, 45, 0, 0, time.UTC)
+
+	// A morning peak at 09:00 on each of the previous three days
+	h := &History{}
+	peaks := []int32{6, 4, 5}
+	for i, peak := range peaks {
+		dayStart := now.Add(-time.Duration(len(peaks)-i) * day)
+		h.Add(Sample{Time: dayStart.Add(-time.Hour), Demand: 2}, 8*day)
+		h.Add(Sample{Time: dayStart.Add(15 * time.Minute), Demand: peak}, 8*day)
+		h.Add(Sample{Time: dayStart.Add(2 * time.Hour), Demand: 2}, 8*day)
+	}
+
+	predicted, ok := Forecast(h, now, horizon, day, 7)
+	assert.True(t, ok)
+	assert.Equal(t, int32(5), predicted, "mean of the daily peaks, rounded up")
+
+	// Only the most recent season
+	predicted, ok = Forecast(h, now, horizon, day, 1)
+	assert.True(t, ok)
+	assert.Equal(t, int32(5), predicted)
+
+	// An hour later the window holds no peak
+	predicted, ok = Forecast(h, now.Add(time.Hour), horizon, day, 7)
+	assert.False(t, ok)
+	assert.Zero(t, predicted)
+
+	// Without a previous season there is no forecast
+	_, ok = Forecast(&History{}, now, horizon, day, 7)
+	assert.False(t, ok)
+}
@@ -0,0 +1,479 @@
+package predictive
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/drain"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
+)
+
+// Mode selects what the predictor does with its forecasts
+type Mode string
+
+const (
+	// ModeOff disables predictive scaling
+	ModeOff Mode = "off"
+
+	// ModeShadow records forecasts and their accuracy without acting on them
+	ModeShadow Mode = "shadow"
+
+	// ModeActive also keeps DesiredNodes at or above the forecast
+	ModeActive Mode = "active"
+
+	// DefaultInterval is how often demand is sampled and forecast
+	DefaultInterval = 5 * time.Minute
+
+	// DefaultHorizon is how far ahead demand is forecast
+	DefaultHorizon = 30 * time.Minute
+
+	// DefaultSeason is the period demand is expected to repeat with
+	DefaultSeason = 24 * time.Hour
+
+	// DefaultLookbackSeasons is how many previous seasons a forecast draws on
+	DefaultLookbackSeasons = 7
+)
+
+// ParseMode parses a predictive scaling mode. An empty string is treated as off.
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", ModeOff:
+		return ModeOff, nil
+	case ModeShadow:
+		return ModeShadow, nil
+	case ModeActive:
+		return ModeActive, nil
+	default:
+		return "", fmt.Errorf("unknown predictive scaling mode %q, must be one of: off, shadow, active", s)
+	}
+}
+
+// Config configures the predictor
+type Config struct {
+	// Mode selects whether forecasts are only recorded or also applied
+	Mode Mode
+
+	// Interval is how often demand is sampled and forecast
+	Interval time.Duration
+
+	// Horizon is how far ahead demand is forecast
+	Horizon time.Duration
+
+	// Season is the period demand is expected to repeat with
+	Season time.Duration
+
+	// LookbackSeasons is how many previous seasons a forecast draws on
+	LookbackSeasons int
+}
+
+// DefaultConfig returns the default predictor configuration, in shadow mode
+func DefaultConfig() Config {
+	return Config{
+		Mode:            ModeShadow,
+		Interval:        DefaultInterval,
+		Horizon:         DefaultHorizon,
+		Season:          DefaultSeason,
+		LookbackSeasons: DefaultLookbackSeasons,
+	}
+}
+
+// evaluation is a forecast awaiting comparison with the demand it predicted
+type evaluation struct {
+	madeAt    time.Time
+	windowEnd time.Time
+	predicted int32
+}
+
+// Predictor learns each NodeGroup's node demand and forecasts it a horizon
+// ahead. Demand is the number of nodes running pods other than DaemonSet and
+// static pods, plus the nodes the scale-up controller estimated for pending
+// pods since the last sample. Empty nodes are not demand, so nodes added ahead
+// of a forecast do not reinforce it.
+//
+// In shadow mode forecasts are only exported as metrics. In active mode they
+// are also written to the NodeGroup status as PredictedNodes, which the
+// NodeGroup controller keeps DesiredNodes at or above until it expires.
+// Without a store, or if it cannot be loaded, history starts empty and no
+// forecast is made until a season of demand has been sampled again.
+type Predictor struct {
+	client client.Client
+	config Config
+	logger *zap.Logger
+	now    func() time.Time
+	store  HistoryStore
+
+	mu          sync.Mutex
+	history     map[string]*History
+	pending     map[string]int32
+	evaluations map[string][]evaluation
+}
+
+// NewPredictor creates a predictor. Unset config fields take their defaults.
+func NewPredictor(k8sClient client.Client, config Config, logger *zap.Logger) *Predictor {
+	defaults := DefaultConfig()
+	if config.Mode == "" {
+		config.Mode = defaults.Mode
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.Horizon <= 0 {
+		config.Horizon = defaults.Horizon
+	}
+	if config.Season <= 0 {
+		config.Season = defaults.Season
+	}
+	if config.LookbackSeasons <= 0 {
+		config.LookbackSeasons = defaults.LookbackSeasons
+	}
+
+	return &Predictor{
+		client:      k8sClient,
+		config:      config,
+		logger:      logger.Named("predictive-scaler"),
+		now:         time.Now,
+		history:     make(map[string]*History),
+		pending:     make(map[string]int32),
+		evaluations: make(map[string][]evaluation),
+	}
+}
+
+// Config returns the predictor's configuration
+func (p *Predictor) Config() Config {
+	return p.config
+}
+
+// SetStore sets where demand history is persisted. Run restores the history
+// stored by a previous run.
+func (p *Predictor) SetStore(store HistoryStore) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.store = store
+}
+
+// Load restores the demand history persisted in the predictor's store.
+// Samples already recorded for a NodeGroup replace its stored ones.
+func (p *Predictor) Load(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.store == nil {
+		return nil
+	}
+
+	stored, err := p.store.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load demand history: %w", err)
+	}
+
+	retention := p.retention()
+	for key, samples := range stored {
+		if _, ok := p.history[key]; ok {
+			continue
+		}
+		history := &History{}
+		for _, sample := range samples {
+			history.Add(sample, retention)
+		}
+		p.history[key] = history
+	}
+	return nil
+}
+
+// save persists the demand history to the predictor's store
+func (p *Predictor) save(ctx context.Context) error {
+	p.mu.Lock()
+	store := p.store
+	samples := make(map[string][]Sample, len(p.history))
+	for key, history := range p.history {
+		samples[key] = append([]Sample(nil), history.samples...)
+	}
+	p.mu.Unlock()
+
+	if store == nil {
+		return nil
+	}
+	if err := store.Save(ctx, samples); err != nil {
+		return fmt.Errorf("failed to persist demand history: %w", err)
+	}
+	return nil
+}
+
+// retention is how long demand samples are kept
+func (p *Predictor) retention() time.Duration {
+	return time.Duration(p.config.LookbackSeasons)*p.config.Season + p.config.Horizon + p.config.Interval
+}
+
+// RecordPendingDemand records the nodes a NodeGroup needs for its pending
+// pods. The highest value since the last sample is added to its demand.
+func (p *Predictor) RecordPendingDemand(ng *v1alpha1.NodeGroup, nodesNeeded int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := nodeGroupKey(ng)
+	if int32(nodesNeeded) > p.pending[key] {
+		p.pending[key] = int32(nodesNeeded)
+	}
+}
+
+// Run restores the demand history and then samples and forecasts every
+// interval until the context is cancelled
+func (p *Predictor) Run(ctx context.Context) {
+	p.logger.Info("Starting predictive scaling",
+		zap.String("mode", string(p.config.Mode)),
+		zap.Duration("interval", p.config.Interval),
+		zap.Duration("horizon", p.config.Horizon),
+		zap.Duration("season", p.config.Season),
+		zap.Int("lookbackSeasons", p.config.LookbackSeasons),
+	)
+
+	if err := p.Load(ctx); err != nil {
+		p.logger.Error("Failed to restore demand history, forecasts resume after a season", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.logger.Info("Stopping predictive scaling")
+			return
+		case <-ticker.C:
+			if err := p.Sample(ctx); err != nil {
+				p.logger.Error("Failed to sample NodeGroup demand", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Sample records the current demand of every managed NodeGroup, scores
+// forecasts whose window has passed, forecasts the next horizon and persists
+// the demand history. Failing to persist it is logged, not returned.
+func (p *Predictor) Sample(ctx context.Context) error {
+	ngList := &v1alpha1.NodeGroupList{}
+	if err := p.client.List(ctx, ngList, client.MatchingLabels{
+		v1alpha1.ManagedLabelKey: v1alpha1.ManagedLabelValue,
+	}); err != nil {
+		return fmt.Errorf("failed to list NodeGroups: %w", err)
+	}
+
+	busyNodes, err := p.busyNodesByNodeGroup(ctx, ngList.Items)
+	if err != nil {
+		return err
+	}
+
+	now := p.now()
+	seen := make(map[string]bool, len(ngList.Items))
+	for i := range ngList.Items {
+		ng := &ngList.Items[i]
+		seen[nodeGroupKey(ng)] = true
+		p.sampleNodeGroup(ctx, ng, busyNodes[nodeGroupKey(ng)], now)
+	}
+
+	p.forgetNodeGroups(seen)
+
+	if err := p.save(ctx); err != nil {
+		p.logger.Error("Failed to persist demand history", zap.Error(err))
+	}
+	return nil
+}
+
+// sampleNodeGroup records one NodeGroup's demand and forecasts its next horizon
+func (p *Predictor) sampleNodeGroup(ctx context.Context, ng *v1alpha1.NodeGroup, busyNodes int32, now time.Time) {
+	key := nodeGroupKey(ng)
+	nodegroup, _ := metrics.SanitizeLabel(ng.Name)
+	namespace, _ := metrics.SanitizeLabel(ng.Namespace)
+	logger := p.logger.With(zap.String("nodeGroup", ng.Name), zap.String("namespace", ng.Namespace))
+
+	p.mu.Lock()
+	demand := busyNodes + p.pending[key]
+	delete(p.pending, key)
+
+	history, ok := p.history[key]
+	if !ok {
+		history = &History{}
+		p.history[key] = history
+	}
+	history.Add(Sample{Time: now, Demand: demand}, p.retention())
+
+	// Score forecasts whose window has passed against the peak demand seen in it
+	var remaining []evaluation
+	for _, e := range p.evaluations[key] {
+		if now.Before(e.windowEnd) {
+			remaining = append(remaining, e)
+			continue
+		}
+		if actual, ok := history.Peak(e.madeAt, e.windowEnd); ok {
+			metrics.PredictiveScalingErrorNodes.WithLabelValues(nodegroup, namespace).
+				Observe(float64(e.predicted - actual))
+		}
+	}
+
+	predicted, ok := Forecast(history, now, p.config.Horizon, p.config.Season, p.config.LookbackSeasons)
+	if ok {
+		if minNodes := v1alpha1.EffectiveMinNodes(ng); predicted < minNodes {
+			predicted = minNodes
+		}
+		if maxNodes := v1alpha1.EffectiveMaxNodes(ng); predicted > maxNodes {
+			predicted = maxNodes
+		}
+		remaining = append(remaining, evaluation{madeAt: now, windowEnd: now.Add(p.config.Horizon), predicted: predicted})
+	}
+	p.evaluations[key] = remaining
+	p.mu.Unlock()
+
+	metrics.PredictiveScalingObservedNodes.WithLabelValues(nodegroup, namespace).Set(float64(demand))
+
+	if !ok {
+		logger.Debug("Not enough demand history to forecast",
+			zap.Int32("demand", demand),
+			zap.Int("samples", history.Len()),
+		)
+		return
+	}
+
+	metrics.PredictiveScalingPredictedNodes.WithLabelValues(nodegroup, namespace).Set(float64(predicted))
+
+	if predicted > ng.Status.DesiredNodes {
+		metrics.PredictiveScalingPreScalesTotal.WithLabelValues(nodegroup, namespace, string(p.config.Mode)).Inc()
+		logger.Info("Forecast demand exceeds desired nodes",
+			zap.String("mode", string(p.config.Mode)),
+			zap.Int32("demand", demand),
+			zap.Int32("predicted", predicted),
+			zap.Int32("desired", ng.Status.DesiredNodes),
+			zap.Duration("horizon", p.config.Horizon),
+		)
+	}
+
+	if p.config.Mode != ModeActive {
+		return
+	}
+
+	// Refresh the prediction every sample so it expires if the predictor stops
+	expiresAt := metav1.NewTime(now.Add(2 * p.config.Interval))
+	patch := client.MergeFrom(ng.DeepCopy())
+	ng.Status.PredictedNodes = predicted
+	ng.Status.PredictionExpiresAt = &expiresAt
+	if err := p.client.Status().Patch(ctx, ng, patch); err != nil {
+		logger.Error("Failed to apply predicted nodes", zap.Int32("predicted", predicted), zap.Error(err))
+	}
+}
+
+// busyNodesByNodeGroup counts, per NodeGroup namespace/name key, the nodes
+// running pods other than DaemonSet and static pods. Nodes only carry the
+// NodeGroup name, so a node is attributed through its VPSieNode, or by name
+// when a single NodeGroup has that name.
+func (p *Predictor) busyNodesByNodeGroup(ctx context.Context, nodeGroups []v1alpha1.NodeGroup) (map[string]int32, error) {
+	nodeList := &corev1.NodeList{}
+	if err := p.client.List(ctx, nodeList, client.HasLabels{v1alpha1.NodeGroupLabelKey}); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	vnList := &v1alpha1.VPSieNodeList{}
+	if err := p.client.List(ctx, vnList, client.HasLabels{v1alpha1.NodeGroupLabelKey}); err != nil {
+		return nil, fmt.Errorf("failed to list VPSieNodes: %w", err)
+	}
+
+	vnKeys := make(map[string]string, len(vnList.Items))
+	for i := range vnList.Items {
+		vn := &vnList.Items[i]
+		nodeName := vn.Status.NodeName
+		if nodeName == "" {
+			nodeName = vn.Spec.NodeName
+		}
+		if nodeName != "" {
+			vnKeys[nodeName] = vn.Namespace + "/" + vn.Labels[v1alpha1.NodeGroupLabelKey]
+		}
+	}
+
+	// An empty key marks a name shared by NodeGroups in several namespaces
+	nameKeys := make(map[string]string, len(nodeGroups))
+	for i := range nodeGroups {
+		if _, ok := nameKeys[nodeGroups[i].Name]; ok {
+			nameKeys[nodeGroups[i].Name] = ""
+			continue
+		}
+		nameKeys[nodeGroups[i].Name] = nodeGroupKey(&nodeGroups[i])
+	}
+
+	nodeKeys := make(map[string]string, len(nodeList.Items))
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		key, ok := vnKeys[node.Name]
+		if !ok {
+			key = nameKeys[node.Labels[v1alpha1.NodeGroupLabelKey]]
+		}
+		if key == "" {
+			p.logger.Debug("Not counting node of an ambiguous NodeGroup",
+				zap.String("node", node.Name),
+				zap.String("nodeGroup", node.Labels[v1alpha1.NodeGroupLabelKey]),
+			)
+			continue
+		}
+		nodeKeys[node.Name] = key
+	}
+
+	podList := &corev1.PodList{}
+	if err := p.client.List(ctx, podList); err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	busy := make(map[string]bool)
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if _, ok := nodeKeys[pod.Spec.NodeName]; !ok || !drain.IsWorkloadPod(pod) {
+			continue
+		}
+		busy[pod.Spec.NodeName] = true
+	}
+
+	counts := make(map[string]int32)
+	for node := range busy {
+		counts[nodeKeys[node]]++
+	}
+	return counts, nil
+}
+
+// forgetNodeGroups drops the history and metrics of NodeGroups no longer present
+func (p *Predictor) forgetNodeGroups(seen map[string]bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key := range p.history {
+		if seen[key] {
+			continue
+		}
+		delete(p.history, key)
+		delete(p.pending, key)
+		delete(p.evaluations, key)
+
+		namespace, name := splitKey(key)
+		nodegroup, _ := metrics.SanitizeLabel(name)
+		ns, _ := metrics.SanitizeLabel(namespace)
+		labels := prometheus.Labels{"nodegroup": nodegroup, "namespace": ns}
+		metrics.PredictiveScalingPredictedNodes.DeletePartialMatch(labels)
+		metrics.PredictiveScalingObservedNodes.DeletePartialMatch(labels)
+	}
+}
+
+// nodeGroupKey returns the namespace/name key of a NodeGroup
+func nodeGroupKey(ng *v1alpha1.NodeGroup) string {
+	return ng.Namespace + "/" + ng.Name
+}
+
+// splitKey splits a namespace/name key
+func splitKey(key string) (namespace, name string) {
+	namespace, name, _ = strings.Cut(key, "/")
+	return namespace, name
+}
@@ -0,0 +1,297 @@
+package predictive
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
+)
+
+func newPredictorClient(t *testing.T) client.Client {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	ng := &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ng-1",
+			Namespace: "kube-system",
+			Labels:    map[string]string{v1alpha1.ManagedLabelKey: v1alpha1.ManagedLabelValue},
+		},
+		Spec:   v1alpha1.NodeGroupSpec{MinNodes: 1, MaxNodes: 10},
+		Status: v1alpha1.NodeGroupStatus{CurrentNodes: 3, DesiredNodes: 3},
+	}
+	newNode := func(name string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{v1alpha1.NodeGroupLabelKey: "ng-1"},
+		}}
+	}
+	newPod := func(name, nodeName string, ownerKind string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		if ownerKind != "" {
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: "owner", APIVersion: "apps/v1"}}
+		}
+		return pod
+	}
+
+	return fakeClient.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			ng,
+			newNode("node-1"),
+			newNode("node-2"),
+			newNode("node-3"),
+			newPod("web-1", "node-1", "ReplicaSet"),
+			newPod("web-2", "node-1", "ReplicaSet"),
+			newPod("logging-2", "node-2", "DaemonSet"),
+			// Nodes of other NodeGroups are not counted
+			newPod("other", "node-9", "ReplicaSet"),
+		).
+		WithStatusSubresource(ng).
+		Build()
+}
+
+func TestParseMode(t *testing.T) {
+	mode, err := ParseMode("")
+	require.NoError(t, err)
+	assert.Equal(t, ModeOff, mode)
+
+	mode, err = ParseMode("active")
+	require.NoError(t, err)
+	assert.Equal(t, ModeActive, mode)
+
+	_, err = ParseMode("aggressive")
+	assert.Error(t, err)
+}
+
+func TestPredictorSample(t *testing.T) {
+	metrics.ResetMetrics()
+
+	tests := []struct {
+		mode          Mode
+		expectApplied bool
+	}{
+		{mode: ModeShadow, expectApplied: false},
+		{mode: ModeActive, expectApplied: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			k8sClient := newPredictorClient(t)
+			predictor := NewPredictor(k8sClient, Config{Mode: tt.mode}, zap.NewNop())
+			now := time.Date(2026, 1, 8, 8, 45, 0, 0, time.UTC)
+			predictor.now = func() time.Time { return now }
+
+			// Yesterday demand peaked at 6 nodes shortly after this time
+			key := "kube-system/ng-1"
+			predictor.history[key] = &History{}
+			predictor.history[key].Add(Sample{Time: now.Add(-24*time.Hour + 15*time.Minute), Demand: 6}, 8*24*time.Hour)
+
+			ng := &v1alpha1.NodeGroup{ObjectMeta: metav1.ObjectMeta{Name: "ng-1", Namespace: "kube-system"}}
+			predictor.RecordPendingDemand(ng, 1)
+			predictor.RecordPendingDemand(ng, 2)
+
+			require.NoError(t, predictor.Sample(context.Background()))
+
+			// One node runs workload pods and two more are needed for pending pods
+			assert.Equal(t, float64(3), testutil.ToFloat64(
+				metrics.PredictiveScalingObservedNodes.WithLabelValues("ng-1", "kube-system")))
+			assert.Equal(t, float64(6), testutil.ToFloat64(
+				metrics.PredictiveScalingPredictedNodes.WithLabelValues("ng-1", "kube-system")))
+			assert.Equal(t, float64(1), testutil.ToFloat64(
+				metrics.PredictiveScalingPreScalesTotal.WithLabelValues("ng-1", "kube-system", string(tt.mode))))
+
+			updated := &v1alpha1.NodeGroup{}
+			require.NoError(t, k8sClient.Get(context.Background(),
+				types.NamespacedName{Name: "ng-1", Namespace: "kube-system"}, updated))
+			if tt.expectApplied {
+				assert.Equal(t, int32(6), updated.Status.PredictedNodes)
+				require.NotNil(t, updated.Status.PredictionExpiresAt)
+				assert.True(t, updated.Status.PredictionExpiresAt.Time.After(now))
+			} else {
+				assert.Zero(t, updated.Status.PredictedNodes)
+				assert.Nil(t, updated.Status.PredictionExpiresAt)
+			}
+
+			// Once the horizon has passed the forecast is scored against observed demand
+			now = now.Add(DefaultHorizon)
+			require.NoError(t, predictor.Sample(context.Background()))
+			assert.Equal(t, 1, testutil.CollectAndCount(metrics.PredictiveScalingErrorNodes))
+			assert.Empty(t, predictor.pending)
+		})
+	}
+}
+
+func TestPredictorForgetsDeletedNodeGroups(t *testing.T) {
+	metrics.ResetMetrics()
+
+	k8sClient := newPredictorClient(t)
+	predictor := NewPredictor(k8sClient, Config{Mode: ModeShadow}, zap.NewNop())
+	predictor.history["kube-system/deleted"] = &History{}
+	metrics.PredictiveScalingObservedNodes.WithLabelValues("deleted", "kube-system").Set(1)
+
+	require.NoError(t, predictor.Sample(context.Background()))
+
+	assert.NotContains(t, predictor.history, "kube-system/deleted")
+	assert.Contains(t, predictor.history, "kube-system/ng-1")
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.PredictiveScalingObservedNodes))
+}
+
+func TestPredictorSeparatesNamespaces(t *testing.T) {
+	metrics.ResetMetrics()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	objects := []client.Object{}
+	for _, tc := range []struct {
+		namespace string
+		nodes     []string
+	}{
+		{namespace: "team-a", nodes: []string{"node-a1", "node-a2"}},
+		{namespace: "team-b", nodes: []string{"node-b1"}},
+	} {
+		objects = append(objects, &v1alpha1.NodeGroup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "workers",
+				Namespace: tc.namespace,
+				Labels:    map[string]string{v1alpha1.ManagedLabelKey: v1alpha1.ManagedLabelValue},
+			},
+			Spec: v1alpha1.NodeGroupSpec{MinNodes: 1, MaxNodes: 10},
+		})
+		for _, name := range tc.nodes {
+			objects = append(objects,
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+					Name:   name,
+					Labels: map[string]string{v1alpha1.NodeGroupLabelKey: "workers"},
+				}},
+				&v1alpha1.VPSieNode{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "vn-" + name,
+						Namespace: tc.namespace,
+						Labels:    map[string]string{v1alpha1.NodeGroupLabelKey: "workers"},
+					},
+					Status: v1alpha1.VPSieNodeStatus{NodeName: name},
+				},
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "web-" + name,
+						Namespace:       "default",
+						OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web", APIVersion: "apps/v1"}},
+					},
+					Spec:   corev1.PodSpec{NodeName: name},
+					Status: corev1.PodStatus{Phase: corev1.PodRunning},
+				},
+			)
+		}
+	}
+	// A node without a VPSieNode cannot be told apart between the two NodeGroups
+	objects = append(objects,
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-unknown",
+			Labels: map[string]string{v1alpha1.NodeGroupLabelKey: "workers"},
+		}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "web-unknown",
+				Namespace:       "default",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web", APIVersion: "apps/v1"}},
+			},
+			Spec:   corev1.PodSpec{NodeName: "node-unknown"},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	)
+
+	k8sClient := fakeClient.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	predictor := NewPredictor(k8sClient, Config{Mode: ModeShadow}, zap.NewNop())
+
+	require.NoError(t, predictor.Sample(context.Background()))
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(
+		metrics.PredictiveScalingObservedNodes.WithLabelValues("workers", "team-a")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(
+		metrics.PredictiveScalingObservedNodes.WithLabelValues("workers", "team-b")))
+	require.Contains(t, predictor.history, "team-a/workers")
+	require.Contains(t, predictor.history, "team-b/workers")
+	assert.Equal(t, int32(2), predictor.history["team-a/workers"].samples[0].Demand)
+	assert.Equal(t, int32(1), predictor.history["team-b/workers"].samples[0].Demand)
+}
+
+func TestPredictorHistoryStore(t *testing.T) {
+	now := time.Date(2026, 1, 8, 8, 45, 0, 0, time.UTC)
+	yesterday := Sample{Time: now.Add(-24*time.Hour + 15*time.Minute), Demand: 6}
+
+	t.Run("Cold start without stored history does not forecast", func(t *testing.T) {
+		metrics.ResetMetrics()
+
+		k8sClient := newPredictorClient(t)
+		predictor := NewPredictor(k8sClient, Config{Mode: ModeActive}, zap.NewNop())
+		predictor.now = func() time.Time { return now }
+		predictor.SetStore(NewConfigMapHistoryStore(fakeclientset.NewSimpleClientset(), "kube-system", "history"))
+
+		require.NoError(t, predictor.Load(context.Background()))
+		require.NoError(t, predictor.Sample(context.Background()))
+
+		assert.Equal(t, 0, testutil.CollectAndCount(metrics.PredictiveScalingPredictedNodes))
+		updated := &v1alpha1.NodeGroup{}
+		require.NoError(t, k8sClient.Get(context.Background(),
+			types.NamespacedName{Name: "ng-1", Namespace: "kube-system"}, updated))
+		assert.Zero(t, updated.Status.PredictedNodes)
+		assert.Nil(t, updated.Status.PredictionExpiresAt)
+	})
+
+	t.Run("History survives a restart", func(t *testing.T) {
+		metrics.ResetMetrics()
+
+		clientset := fakeclientset.NewSimpleClientset()
+		store := NewConfigMapHistoryStore(clientset, "kube-system", "history")
+
+		// The previous leader sampled yesterday's peak and persisted it
+		previous := NewPredictor(newPredictorClient(t), Config{Mode: ModeActive}, zap.NewNop())
+		previous.now = func() time.Time { return yesterday.Time }
+		previous.SetStore(store)
+		previous.RecordPendingDemand(&v1alpha1.NodeGroup{ObjectMeta: metav1.ObjectMeta{Name: "ng-1", Namespace: "kube-system"}}, 5)
+		require.NoError(t, previous.Sample(context.Background()))
+
+		cm, err := clientset.CoreV1().ConfigMaps("kube-system").Get(context.Background(), "history", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Contains(t, cm.Data, "kube-system.ng-1")
+
+		k8sClient := newPredictorClient(t)
+		predictor := NewPredictor(k8sClient, Config{Mode: ModeActive}, zap.NewNop())
+		predictor.now = func() time.Time { return now }
+		predictor.SetStore(store)
+
+		require.NoError(t, predictor.Load(context.Background()))
+		require.NoError(t, predictor.Sample(context.Background()))
+
+		assert.Equal(t, float64(6), testutil.ToFloat64(
+			metrics.PredictiveScalingPredictedNodes.WithLabelValues("ng-1", "kube-system")))
+		updated := &v1alpha1.NodeGroup{}
+		require.NoError(t, k8sClient.Get(context.Background(),
+			types.NamespacedName{Name: "ng-1", Namespace: "kube-system"}, updated))
+		assert.Equal(t, int32(6), updated.Status.PredictedNodes)
+	})
+}
@@ -0,0 +1,126 @@
+package predictive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// HistoryStore persists the demand history of NodeGroups across controller
+// restarts and leader failovers
+type HistoryStore interface {
+	// Load returns the stored samples by namespace/name key, none if nothing
+	// was stored yet
+	Load(ctx context.Context) (map[string][]Sample, error)
+
+	// Save replaces the stored samples
+	Save(ctx context.Context, samples map[string][]Sample) error
+}
+
+// ConfigMapHistoryStore persists demand history in a ConfigMap, one data key
+// per NodeGroup holding its samples as JSON [unix seconds, demand] pairs. A
+// week of 5 minute samples takes about 40KB per NodeGroup, so the 1MiB
+// ConfigMap limit holds some 25 NodeGroups. The ConfigMap is created on the
+// first save.
+type ConfigMapHistoryStore struct {
+	clientset kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewConfigMapHistoryStore creates a history store backed by the named ConfigMap
+func NewConfigMapHistoryStore(clientset kubernetes.Interface, namespace, name string) *ConfigMapHistoryStore {
+	return &ConfigMapHistoryStore{
+		clientset: clientset,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// Load returns the samples stored in the ConfigMap, none if it does not exist
+func (s *ConfigMapHistoryStore) Load(ctx context.Context) (map[string][]Sample, error) {
+	cm, err := s.clientset.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get demand history ConfigMap %s/%s: %w", s.namespace, s.name, err)
+	}
+
+	samples := make(map[string][]Sample, len(cm.Data))
+	for dataKey, data := range cm.Data {
+		var pairs [][2]int64
+		if err := json.Unmarshal([]byte(data), &pairs); err != nil {
+			return nil, fmt.Errorf("failed to parse demand history of %s in ConfigMap %s/%s: %w",
+				dataKey, s.namespace, s.name, err)
+		}
+
+		history := make([]Sample, 0, len(pairs))
+		for _, pair := range pairs {
+			history = append(history, Sample{Time: time.Unix(pair[0], 0).UTC(), Demand: int32(pair[1])})
+		}
+		samples[historyKey(dataKey)] = history
+	}
+	return samples, nil
+}
+
+// Save replaces the samples stored in the ConfigMap
+func (s *ConfigMapHistoryStore) Save(ctx context.Context, samples map[string][]Sample) error {
+	data := make(map[string]string, len(samples))
+	for key, history := range samples {
+		pairs := make([][2]int64, 0, len(history))
+		for _, sample := range history {
+			pairs = append(pairs, [2]int64{sample.Time.Unix(), int64(sample.Demand)})
+		}
+		encoded, err := json.Marshal(pairs)
+		if err != nil {
+			return fmt.Errorf("failed to encode demand history of %s: %w", key, err)
+		}
+		data[dataKey(key)] = string(encoded)
+	}
+
+	configMaps := s.clientset.CoreV1().ConfigMaps(s.namespace)
+	cm, err := configMaps.Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.name,
+				Namespace: s.namespace,
+			},
+			Data: data,
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create demand history ConfigMap %s/%s: %w", s.namespace, s.name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get demand history ConfigMap %s/%s: %w", s.namespace, s.name, err)
+	}
+
+	cm.Data = data
+	if _, err := configMaps.Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update demand history ConfigMap %s/%s: %w", s.namespace, s.name, err)
+	}
+	return nil
+}
+
+// dataKey converts a namespace/name key to a ConfigMap data key. Namespaces
+// cannot contain dots, so the first dot separates the two again.
+func dataKey(key string) string {
+	namespace, name := splitKey(key)
+	return namespace + "." + name
+}
+
+// historyKey converts a ConfigMap data key back to a namespace/name key
+func historyKey(dataKey string) string {
+	namespace, name, _ := strings.Cut(dataKey, ".")
+	return namespace + "/" + name
+}
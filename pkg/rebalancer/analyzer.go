@@ -3,10 +3,13 @@ package rebalancer
 import (
 	"context"
 	"fmt"
+	"math"
 	"sort"
 	"time"
 
 	v1alpha1 "github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/drain"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/scaler"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
@@ -20,8 +23,19 @@ type Analyzer struct {
 	kubeClient    kubernetes.Interface
 	costOptimizer *cost.Optimizer
 	config        *AnalyzerConfig
+	events        *EventRecorder
+	utilization   UtilizationSource
 }
 
+// UtilizationSource provides the utilization samples tracked for a node, such
+// as the scale-down manager
+type UtilizationSource interface {
+	GetNodeUtilization(nodeName string) (*scaler.NodeUtilization, bool)
+}
+
+// utilizationWindow is how far back node utilization samples are aggregated
+const utilizationWindow = time.Hour
+
 // NewAnalyzer creates a new rebalance analyzer
 func NewAnalyzer(kubeClient kubernetes.Interface, costOptimizer *cost.Optimizer, config *AnalyzerConfig) *Analyzer {
 	if config == nil {
@@ -40,6 +54,18 @@ func NewAnalyzer(kubeClient kubernetes.Interface, costOptimizer *cost.Optimizer,
 	}
 }
 
+// SetEventRecorder sets the recorder of events on pods blocking a rebalance
+func (a *Analyzer) SetEventRecorder(events *EventRecorder) {
+	a.events = events
+}
+
+// SetUtilizationSource sets the source of node utilization. Candidates are
+// then ranked by their utilization under the NodeGroup's scale-down
+// utilization mode and aggregation.
+func (a *Analyzer) SetUtilizationSource(source UtilizationSource) {
+	a.utilization = source
+}
+
 // AnalyzeRebalanceOpportunities identifies which nodes should be rebalanced.
 // NodeGroup isolation: Only managed NodeGroups (with autoscaler.vpsie.com/managed=true label)
 // are analyzed for rebalancing to prevent the rebalancer from interfering with
@@ -201,6 +227,12 @@ func (a *Analyzer) identifyCandidates(ctx context.Context, nodeGroup *v1alpha1.N
 			return nil, fmt.Errorf("failed to get workloads for node %s: %w", node.Name, err)
 		}
 
+		// Skip nodes running pods annotated as not safe to evict
+		if a.hasBlockedWorkloads(workloads) {
+			a.recordBlockedPods(ctx, node)
+			continue
+		}
+
 		// Check if node has local storage (if configured to skip)
 		if a.config.SkipNodesWithLocalStorage && a.hasLocalStorage(workloads) {
 			continue
@@ -308,7 +340,7 @@ func (a *Analyzer) checkNodeGroupHealth(ctx context.Context, nodeGroup *v1alpha1
 
 	// Check if NodeGroup has minimum nodes
 	currentNodes := nodeGroup.Status.CurrentNodes
-	minNodes := nodeGroup.Spec.MinNodes
+	minNodes := v1alpha1.EffectiveMinNodes(nodeGroup)
 
 	check.Details["current_nodes"] = currentNodes
 	check.Details["min_nodes"] = minNodes
@@ -381,7 +413,7 @@ func (a *Analyzer) checkResourceCapacity(ctx context.Context, nodeGroup *v1alpha
 
 	// Check if we have room to add new nodes before removing old ones
 	currentNodes := nodeGroup.Status.CurrentNodes
-	maxNodes := nodeGroup.Spec.MaxNodes
+	maxNodes := v1alpha1.EffectiveMaxNodes(nodeGroup)
 	nodesToRebalance := int32(len(candidates))
 
 	check.Details["current_nodes"] = currentNodes
@@ -684,6 +716,8 @@ func (a *Analyzer) getNodeGroupNodes(ctx context.Context, nodeGroup *v1alpha1.No
 			}
 		}
 
+		node.CPUUtilization, node.MemoryUtilization, node.HasUtilization = a.nodeUtilization(nodeGroup, n.Name)
+
 		// Get pods running on this node
 		pods, err := a.kubeClient.CoreV1().Pods("").List(ctx, metav1.ListOptions{
 			FieldSelector: fmt.Sprintf("spec.nodeName=%s", n.Name),
@@ -705,24 +739,22 @@ func (a *Analyzer) getNodeWorkloads(ctx context.Context, node *Node) ([]Workload
 
 	for _, pod := range node.Pods {
 		// Skip DaemonSet pods (they'll be recreated automatically)
-		if pod.OwnerReferences != nil {
-			for _, owner := range pod.OwnerReferences {
-				if owner.Kind == "DaemonSet" {
-					continue
-				}
-			}
+		if drain.IsDaemonSetPod(pod) {
+			continue
 		}
 
+		blockingReason := drain.BlockingReason(pod)
 		workload := Workload{
-			Name:      pod.Name,
-			Namespace: pod.Namespace,
-			Kind:      "Pod",
-			CanEvict:  true,
+			Name:           pod.Name,
+			Namespace:      pod.Namespace,
+			Kind:           "Pod",
+			CanEvict:       blockingReason == "",
+			BlockingReason: blockingReason,
 		}
 
-		// Check for local storage
+		// Check for local storage the pod's annotations don't allow losing
 		for _, volume := range pod.Spec.Volumes {
-			if volume.EmptyDir != nil || volume.HostPath != nil {
+			if (volume.EmptyDir != nil || volume.HostPath != nil) && !drain.IsLocalVolumeSafeToEvict(pod, volume.Name) {
 				workload.HasLocalStorage = true
 				break
 			}
@@ -734,6 +766,31 @@ func (a *Analyzer) getNodeWorkloads(ctx context.Context, node *Node) ([]Workload
 	return workloads, nil
 }
 
+func (a *Analyzer) hasBlockedWorkloads(workloads []Workload) bool {
+	for _, w := range workloads {
+		if !w.CanEvict {
+			return true
+		}
+	}
+	return false
+}
+
+// recordBlockedPods records an event on each pod whose annotations keep the
+// node from being rebalanced
+func (a *Analyzer) recordBlockedPods(ctx context.Context, node *Node) {
+	logger := log.FromContext(ctx)
+	for _, pod := range node.Pods {
+		reason := drain.BlockingReason(pod)
+		if reason == "" {
+			continue
+		}
+		logger.Info("Skipping node with a pod that cannot be evicted", "node", node.Name, "reason", reason)
+		if a.events != nil {
+			a.events.RecordPodEvictionBlocked(ctx, pod, node.Name, reason)
+		}
+	}
+}
+
 func (a *Analyzer) hasLocalStorage(workloads []Workload) bool {
 	for _, w := range workloads {
 		if w.HasLocalStorage {
@@ -754,12 +811,37 @@ func (a *Analyzer) calculateNodePriorityScore(node *Node, optimization *cost.Opp
 	podCount := float64(len(node.Pods))
 	score += (100 - podCount) * 0.5
 
+	// Less utilized nodes have higher priority (less to move)
+	if node.HasUtilization {
+		score += (100 - math.Max(node.CPUUtilization, node.MemoryUtilization)) * 0.2
+	}
+
 	// Cost savings increase priority
 	score += optimization.MonthlySavings * 0.01
 
 	return score
 }
 
+// nodeUtilization returns the node's CPU and memory utilization measured the
+// way the NodeGroup's scale-down measures it, or false if no source is set or
+// the node is not tracked
+func (a *Analyzer) nodeUtilization(nodeGroup *v1alpha1.NodeGroup, nodeName string) (cpu, memory float64, ok bool) {
+	if a.utilization == nil {
+		return 0, 0, false
+	}
+	util, ok := a.utilization.GetNodeUtilization(nodeName)
+	if !ok || len(util.Samples) == 0 {
+		return 0, 0, false
+	}
+
+	cpu, memory = util.Aggregate(
+		v1alpha1.EffectiveUtilizationMode(&nodeGroup.Spec.ScaleDownPolicy),
+		v1alpha1.EffectiveUtilizationAggregation(&nodeGroup.Spec.ScaleDownPolicy),
+		time.Now().Add(-utilizationWindow),
+	)
+	return cpu, memory, true
+}
+
 func (a *Analyzer) getPriorityReason(node *Node, score float64) string {
 	if score > 50 {
 		return "High priority: old node with significant savings potential"
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/scaler"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -64,6 +66,54 @@ func TestNewAnalyzer(t *testing.T) {
 	})
 }
 
+func TestIdentifyCandidates_PodAnnotations(t *testing.T) {
+	analyzer := NewAnalyzer(fake.NewSimpleClientset(), nil, nil)
+	optimization := &cost.Opportunity{CurrentOffering: "small", RecommendedOffering: "medium"}
+
+	newPod := func(name string, annotations map[string]string, volumes ...corev1.Volume) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: annotations},
+			Spec:       corev1.PodSpec{Volumes: volumes},
+		}
+	}
+	scratch := corev1.Volume{
+		Name:         "scratch",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}
+
+	nodes := []*Node{
+		{Name: "plain", OfferingID: "small", Pods: []*corev1.Pod{newPod("web", nil)}},
+		{Name: "blocked", OfferingID: "small", Pods: []*corev1.Pod{
+			newPod("db", map[string]string{"cluster-autoscaler.kubernetes.io/safe-to-evict": "false"}),
+		}},
+		{Name: "local-storage", OfferingID: "small", Pods: []*corev1.Pod{newPod("cache", nil, scratch)}},
+		{Name: "local-storage-allowed", OfferingID: "small", Pods: []*corev1.Pod{
+			newPod("cache", map[string]string{"autoscaler.vpsie.com/safe-to-evict-local-volumes": "scratch"}, scratch),
+		}},
+	}
+
+	candidates, err := analyzer.identifyCandidates(context.Background(), &v1alpha1.NodeGroup{}, nodes, optimization)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, c := range candidates {
+		names = append(names, c.NodeName)
+	}
+	if len(names) != 2 || names[0] != "plain" || names[1] != "local-storage-allowed" {
+		t.Errorf("Expected candidates [plain local-storage-allowed], got %v", names)
+	}
+
+	workloads, _ := analyzer.getNodeWorkloads(context.Background(), nodes[1])
+	if len(workloads) != 1 || workloads[0].CanEvict {
+		t.Fatalf("Expected one workload that cannot be evicted, got %+v", workloads)
+	}
+	if workloads[0].BlockingReason == "" {
+		t.Error("Expected a blocking reason")
+	}
+}
+
 func TestCheckClusterHealth(t *testing.T) {
 	ctx := context.Background()
 
@@ -274,3 +324,49 @@ func createUnhealthyNode(name, nodeGroup, offering string) *corev1.Node {
 	}
 	return node
 }
+
+type fakeUtilizationSource map[string]*scaler.NodeUtilization
+
+func (f fakeUtilizationSource) GetNodeUtilization(nodeName string) (*scaler.NodeUtilization, bool) {
+	util, ok := f[nodeName]
+	return util, ok
+}
+
+func TestNodeUtilization_UsesScaleDownPolicy(t *testing.T) {
+	now := time.Now()
+	analyzer := NewAnalyzer(fake.NewSimpleClientset(), nil, nil)
+	nodeGroup := &v1alpha1.NodeGroup{ObjectMeta: metav1.ObjectMeta{Name: "ng"}}
+
+	if _, _, ok := analyzer.nodeUtilization(nodeGroup, "node-1"); ok {
+		t.Error("expected no utilization without a source")
+	}
+
+	analyzer.SetUtilizationSource(fakeUtilizationSource{
+		"node-1": {Samples: []scaler.UtilizationSample{
+			{Timestamp: now.Add(-time.Minute), CPUUtilization: 10, MemoryUtilization: 20, CPURequests: 60, MemoryRequests: 50},
+		}},
+	})
+
+	cpu, memory, ok := analyzer.nodeUtilization(nodeGroup, "node-1")
+	if !ok || cpu != 10 || memory != 20 {
+		t.Errorf("expected usage 10/20 by default, got %v/%v (%v)", cpu, memory, ok)
+	}
+
+	nodeGroup.Spec.ScaleDownPolicy.UtilizationMode = v1alpha1.UtilizationModeRequests
+	cpu, memory, _ = analyzer.nodeUtilization(nodeGroup, "node-1")
+	if cpu != 60 || memory != 50 {
+		t.Errorf("expected requests 60/50 in Requests mode, got %v/%v", cpu, memory)
+	}
+
+	if _, _, ok := analyzer.nodeUtilization(nodeGroup, "node-2"); ok {
+		t.Error("expected no utilization for an untracked node")
+	}
+
+	// Less utilized nodes are rebalanced first
+	optimization := &cost.Opportunity{}
+	busy := &Node{Name: "busy", CPUUtilization: 80, MemoryUtilization: 20, HasUtilization: true}
+	idle := &Node{Name: "idle", CPUUtilization: 10, MemoryUtilization: 20, HasUtilization: true}
+	if analyzer.calculateNodePriorityScore(idle, optimization) <= analyzer.calculateNodePriorityScore(busy, optimization) {
+		t.Error("expected the less utilized node to have a higher priority")
+	}
+}
@@ -63,6 +63,9 @@ const (
 	EventNodeTerminated   = "NodeTerminated"
 	EventNodeFailed       = "NodeFailed"
 
+	// Pod events
+	EventPodEvictionBlocked = "RebalanceBlocked"
+
 	// Batch events
 	EventBatchStarted   = "BatchStarted"
 	EventBatchCompleted = "BatchCompleted"
@@ -159,6 +162,13 @@ func (e *EventRecorder) RecordNodeFailed(ctx context.Context, nodeGroup *v1alpha
 	e.recorder.Event(nodeGroup, corev1.EventTypeWarning, EventNodeFailed, message)
 }
 
+// RecordPodEvictionBlocked records an event on a pod whose annotations keep
+// its node from being rebalanced
+func (e *EventRecorder) RecordPodEvictionBlocked(ctx context.Context, pod *corev1.Pod, nodeName, reason string) {
+	message := fmt.Sprintf("Pod blocks rebalancing of node %s: %s", nodeName, reason)
+	e.recorder.Event(pod, corev1.EventTypeWarning, EventPodEvictionBlocked, message)
+}
+
 // RecordBatchStarted records a batch execution start event
 func (e *EventRecorder) RecordBatchStarted(ctx context.Context, nodeGroup *v1alpha1.NodeGroup, batch *NodeBatch) {
 	message := fmt.Sprintf("Started batch %d with %d nodes (estimated duration: %s)",
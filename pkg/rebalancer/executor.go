@@ -6,9 +6,10 @@ import (
 	"time"
 
 	"github.com/vpsie/vpsie-k8s-autoscaler/internal/logging"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/drain"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/client"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
 	corev1 "k8s.io/api/core/v1"
-	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
@@ -20,6 +21,8 @@ type Executor struct {
 	kubeClient  kubernetes.Interface
 	vpsieClient *client.Client
 	config      *ExecutorConfig
+	ledger      *cost.SavingsLedger
+	drainer     drain.Drainer
 }
 
 // NewExecutor creates a new rebalance executor
@@ -37,9 +40,21 @@ func NewExecutor(kubeClient kubernetes.Interface, vpsieClient *client.Client, co
 		kubeClient:  kubeClient,
 		vpsieClient: vpsieClient,
 		config:      config,
+		drainer:     drain.NewNodeDrainer(kubeClient, nil),
 	}
 }
 
+// SetSavingsLedger sets the ledger that applied optimizations are recorded in
+// so their predicted savings can later be verified against actual spend
+func (e *Executor) SetSavingsLedger(ledger *cost.SavingsLedger) {
+	e.ledger = ledger
+}
+
+// SetDrainer sets the drainer nodes are drained with
+func (e *Executor) SetDrainer(drainer drain.Drainer) {
+	e.drainer = drainer
+}
+
 // ExecuteRebalance executes a complete rebalancing plan
 func (e *Executor) ExecuteRebalance(ctx context.Context, plan *RebalancePlan) (*RebalanceResult, error) {
 	// Add correlation ID for request tracing if not already present
@@ -120,6 +135,12 @@ func (e *Executor) ExecuteRebalance(ctx context.Context, plan *RebalancePlan) (*
 	result.Duration = time.Since(startTime)
 	result.SavingsRealized = plan.Optimization.MonthlySavings
 
+	if e.ledger != nil {
+		if _, err := e.ledger.RecordApplied(ctx, plan.NodeGroupName, plan.Namespace, plan.Optimization, completedAt); err != nil {
+			logger.Error(err, "Failed to record optimization in savings ledger")
+		}
+	}
+
 	logger.Info("Rebalance execution completed",
 		"planID", plan.ID,
 		"nodesRebalanced", result.NodesRebalanced,
@@ -386,86 +407,10 @@ func (e *Executor) DrainNode(ctx context.Context, node *Node) error {
 	logger := log.FromContext(ctx)
 	logger.Info("Draining node", "nodeName", node.Name)
 
-	// Get the Kubernetes node
-	k8sNode, err := e.kubeClient.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to get node %s: %w", node.Name, err)
-	}
-
-	// Cordon the node
-	k8sNode.Spec.Unschedulable = true
-	_, err = e.kubeClient.CoreV1().Nodes().Update(ctx, k8sNode, metav1.UpdateOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to cordon node %s: %w", node.Name, err)
-	}
-
-	logger.Info("Node cordoned", "nodeName", node.Name)
-
-	// Get pods on the node
-	pods, err := e.kubeClient.CoreV1().Pods("").List(ctx, metav1.ListOptions{
-		FieldSelector: fmt.Sprintf("spec.nodeName=%s", node.Name),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to list pods on node %s: %w", node.Name, err)
-	}
-
-	// Evict each pod
-	for _, pod := range pods.Items {
-		// Skip DaemonSet pods
-		if e.isDaemonSetPod(&pod) {
-			continue
-		}
-
-		// Skip mirror pods
-		if e.isMirrorPod(&pod) {
-			continue
-		}
-
-		logger.Info("Evicting pod", "pod", pod.Name, "namespace", pod.Namespace)
-
-		eviction := &policyv1.Eviction{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      pod.Name,
-				Namespace: pod.Namespace,
-			},
-		}
-
-		err = e.kubeClient.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
-		if err != nil {
-			logger.Error(err, "Failed to evict pod", "pod", pod.Name)
-			// Continue with other pods
-		}
-	}
-
-	// Wait for pods to be evicted (with timeout)
-	// PollUntilContextTimeout creates its own timeout context internally,
-	// but respects parent context cancellation for graceful shutdown
-	err = wait.PollUntilContextTimeout(ctx, e.config.HealthCheckInterval, e.config.DrainTimeout, true, func(pollCtx context.Context) (bool, error) {
-		pods, err := e.kubeClient.CoreV1().Pods("").List(ctx, metav1.ListOptions{
-			FieldSelector: fmt.Sprintf("spec.nodeName=%s", node.Name),
-		})
-		if err != nil {
-			return false, err
-		}
-
-		// Count non-DaemonSet, non-mirror pods
-		count := 0
-		for _, pod := range pods.Items {
-			if !e.isDaemonSetPod(&pod) && !e.isMirrorPod(&pod) {
-				count++
-			}
-		}
-
-		if count == 0 {
-			return true, nil
-		}
-
-		logger.Info("Waiting for pods to drain", "nodeName", node.Name, "remainingPods", count)
-		return false, nil
-	})
-
-	if err != nil {
-		return fmt.Errorf("timeout waiting for node %s to drain: %w", node.Name, err)
+	opts := drain.DefaultOptions()
+	opts.Timeout = e.config.DrainTimeout
+	if err := e.drainer.Drain(ctx, node.Name, opts); err != nil {
+		return fmt.Errorf("failed to drain node %s: %w", node.Name, err)
 	}
 
 	logger.Info("Node drained successfully", "nodeName", node.Name)
@@ -689,20 +634,6 @@ func (e *Executor) waitForNodeReady(ctx context.Context, node *Node) error {
 	return nil
 }
 
-func (e *Executor) isDaemonSetPod(pod *corev1.Pod) bool {
-	for _, owner := range pod.OwnerReferences {
-		if owner.Kind == "DaemonSet" {
-			return true
-		}
-	}
-	return false
-}
-
-func (e *Executor) isMirrorPod(pod *corev1.Pod) bool {
-	_, isMirror := pod.Annotations[corev1.MirrorPodAnnotationKey]
-	return isMirror
-}
-
 // batchResult contains the results of executing a single batch
 type batchResult struct {
 	NodesRebalanced int32
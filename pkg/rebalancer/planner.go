@@ -322,8 +322,8 @@ func (p *Planner) ValidatePlan(plan *RebalancePlan, nodeGroup *v1alpha1.NodeGrou
 	}
 
 	// Check that total nodes doesn't exceed limits
-	if plan.TotalNodes > nodeGroup.Spec.MaxNodes {
-		return fmt.Errorf("plan would create %d nodes but max is %d", plan.TotalNodes, nodeGroup.Spec.MaxNodes)
+	if maxNodes := v1alpha1.EffectiveMaxNodes(nodeGroup); plan.TotalNodes > maxNodes {
+		return fmt.Errorf("plan would create %d nodes but max is %d", plan.TotalNodes, maxNodes)
 	}
 
 	// Check strategy is valid
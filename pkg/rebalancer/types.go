@@ -44,6 +44,7 @@ type Workload struct {
 	HasLocalStorage  bool
 	IsCritical       bool
 	CanEvict         bool
+	BlockingReason   string // Why the workload cannot be evicted
 	EvictionEstimate time.Duration
 }
 
@@ -188,7 +189,7 @@ type RebalanceResult struct {
 	NodesRebalanced int32
 	NodesFailed     int32
 	Duration        time.Duration
-	SavingsRealized float64
+	SavingsRealized float64 // Predicted savings; verified by cost.SavingsLedger after the fact
 	Errors          []error
 }
 
@@ -202,6 +203,12 @@ type Node struct {
 	Pods       []*corev1.Pod
 	Cordoned   bool
 	Draining   bool
+
+	// CPUUtilization and MemoryUtilization are measured with the NodeGroup's
+	// scale-down utilization mode and aggregation when HasUtilization is set
+	CPUUtilization    float64
+	MemoryUtilization float64
+	HasUtilization    bool
 }
 
 // NodeSpec represents the specification for provisioning a new node
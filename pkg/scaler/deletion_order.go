@@ -0,0 +1,201 @@
+package scaler
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+
+	autoscalerv1alpha1 "github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/utils"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
+)
+
+// DeletionOrderStrategy orders scale-down candidates so the first is removed
+// first. It is used by every scale-down path: underutilized nodes, empty
+// nodes and the simple fallback of the NodeGroup controller.
+type DeletionOrderStrategy interface {
+	// Order sorts the candidates in place. Candidates the strategy can't
+	// tell apart keep their order.
+	Order(candidates []*ScaleDownCandidate, input *DeletionOrderInput)
+}
+
+// DeletionOrderInput is what strategies may know about the NodeGroup beyond
+// the candidates. Both fields are optional.
+type DeletionOrderInput struct {
+	// Nodes are all nodes of the NodeGroup, including those kept
+	Nodes []*corev1.Node
+
+	// Price returns the monthly price of an offering, false if unknown
+	Price func(offeringID string) (float64, bool)
+}
+
+// OfferingPricer returns the price and resources of a VPSie offering
+type OfferingPricer interface {
+	GetOfferingCost(ctx context.Context, offeringID string) (*cost.OfferingCost, error)
+}
+
+// NewDeletionOrderStrategy returns the strategy implementing a deletion
+// order. Default and unknown orders keep the order of the scale-down path.
+func NewDeletionOrderStrategy(order autoscalerv1alpha1.NodeDeletionOrder) DeletionOrderStrategy {
+	switch order {
+	case autoscalerv1alpha1.NodeDeletionOrderOldestFirst:
+		return creationOrder{}
+	case autoscalerv1alpha1.NodeDeletionOrderNewestFirst:
+		return creationOrder{newestFirst: true}
+	case autoscalerv1alpha1.NodeDeletionOrderLeastUtilized:
+		return leastUtilizedOrder{}
+	case autoscalerv1alpha1.NodeDeletionOrderMostExpensiveFirst:
+		return mostExpensiveOrder{}
+	case autoscalerv1alpha1.NodeDeletionOrderFewestPods:
+		return fewestPodsOrder{}
+	case autoscalerv1alpha1.NodeDeletionOrderSpreadPreserving:
+		return spreadPreservingOrder{}
+	default:
+		return defaultOrder{}
+	}
+}
+
+// OrderForDeletion orders candidates with a NodeGroup's deletion order.
+// Nodes that are not ready are moved first whatever the order.
+func OrderForDeletion(candidates []*ScaleDownCandidate, order autoscalerv1alpha1.NodeDeletionOrder, input *DeletionOrderInput) {
+	NewDeletionOrderStrategy(order).Order(candidates, input)
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return !utils.IsNodeReady(candidates[i].Node) && utils.IsNodeReady(candidates[j].Node)
+	})
+}
+
+// OfferingPrices returns a Price function for DeletionOrderInput that asks
+// the pricer at most once per offering
+func OfferingPrices(ctx context.Context, pricer OfferingPricer) func(offeringID string) (float64, bool) {
+	type price struct {
+		monthly float64
+		ok      bool
+	}
+	prices := make(map[string]price)
+
+	return func(offeringID string) (float64, bool) {
+		if offeringID == "" {
+			return 0, false
+		}
+		if p, ok := prices[offeringID]; ok {
+			return p.monthly, p.ok
+		}
+
+		var p price
+		if offering, err := pricer.GetOfferingCost(ctx, offeringID); err == nil && offering != nil {
+			p = price{monthly: offering.MonthlyCost, ok: true}
+		}
+		prices[offeringID] = p
+		return p.monthly, p.ok
+	}
+}
+
+// defaultOrder keeps the order of the scale-down path
+type defaultOrder struct{}
+
+func (defaultOrder) Order([]*ScaleDownCandidate, *DeletionOrderInput) {}
+
+// creationOrder removes the oldest, or newest, nodes first
+type creationOrder struct {
+	newestFirst bool
+}
+
+func (o creationOrder) Order(candidates []*ScaleDownCandidate, _ *DeletionOrderInput) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ci, cj := candidates[i].Node.CreationTimestamp.Time, candidates[j].Node.CreationTimestamp.Time
+		if o.newestFirst {
+			return ci.After(cj)
+		}
+		return ci.Before(cj)
+	})
+}
+
+// leastUtilizedOrder removes the nodes with the lowest mean of CPU and memory
+// utilization first. Nodes without utilization data go last.
+type leastUtilizedOrder struct{}
+
+func (leastUtilizedOrder) Order(candidates []*ScaleDownCandidate, _ *DeletionOrderInput) {
+	utilization := func(candidate *ScaleDownCandidate) float64 {
+		if candidate.Utilization == nil {
+			return math.Inf(1)
+		}
+		return (candidate.Utilization.CPUUtilization + candidate.Utilization.MemoryUtilization) / 2
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return utilization(candidates[i]) < utilization(candidates[j])
+	})
+}
+
+// mostExpensiveOrder removes the nodes of the most expensive offering first.
+// Nodes of offerings without a price go last.
+type mostExpensiveOrder struct{}
+
+func (mostExpensiveOrder) Order(candidates []*ScaleDownCandidate, input *DeletionOrderInput) {
+	if input == nil || input.Price == nil {
+		return
+	}
+
+	prices := make(map[string]float64, len(candidates))
+	for _, candidate := range candidates {
+		price, ok := input.Price(candidate.Node.Labels[autoscalerv1alpha1.OfferingLabelKey])
+		if !ok {
+			price = -1
+		}
+		prices[candidate.Node.Name] = price
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return prices[candidates[i].Node.Name] > prices[candidates[j].Node.Name]
+	})
+}
+
+// fewestPodsOrder removes the nodes running the fewest workload pods first
+type fewestPodsOrder struct{}
+
+func (fewestPodsOrder) Order(candidates []*ScaleDownCandidate, _ *DeletionOrderInput) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(workloadPods(candidates[i].Pods)) < len(workloadPods(candidates[j].Pods))
+	})
+}
+
+// spreadPreservingOrder removes nodes from the datacenter with the most
+// nodes left first, so removing any number of the first candidates keeps the
+// NodeGroup as evenly spread across datacenters as the candidates allow
+type spreadPreservingOrder struct{}
+
+func (spreadPreservingOrder) Order(candidates []*ScaleDownCandidate, input *DeletionOrderInput) {
+	datacenter := func(node *corev1.Node) string {
+		return node.Labels[autoscalerv1alpha1.DatacenterLabelKey]
+	}
+
+	// Count the nodes of each datacenter, the candidates at least
+	remaining := make(map[string]int)
+	counted := make(map[string]bool)
+	if input != nil {
+		for _, node := range input.Nodes {
+			remaining[datacenter(node)]++
+			counted[node.Name] = true
+		}
+	}
+	for _, candidate := range candidates {
+		if !counted[candidate.Node.Name] {
+			remaining[datacenter(candidate.Node)]++
+		}
+	}
+
+	pending := make([]*ScaleDownCandidate, len(candidates))
+	copy(pending, candidates)
+	for i := range candidates {
+		next := 0
+		for j := 1; j < len(pending); j++ {
+			if remaining[datacenter(pending[j].Node)] > remaining[datacenter(pending[next].Node)] {
+				next = j
+			}
+		}
+		candidates[i] = pending[next]
+		remaining[datacenter(pending[next].Node)]--
+		pending = append(pending[:next], pending[next+1:]...)
+	}
+}
@@ -0,0 +1,203 @@
+package scaler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autoscalerv1alpha1 "github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/cost"
+)
+
+// fakeOfferingPricer prices offerings from a map and counts lookups
+type fakeOfferingPricer struct {
+	prices  map[string]float64
+	lookups int
+}
+
+func (p *fakeOfferingPricer) GetOfferingCost(ctx context.Context, offeringID string) (*cost.OfferingCost, error) {
+	p.lookups++
+	price, ok := p.prices[offeringID]
+	if !ok {
+		return nil, fmt.Errorf("offering %s not found", offeringID)
+	}
+	return &cost.OfferingCost{OfferingID: offeringID, MonthlyCost: price}, nil
+}
+
+func newDeletionOrderTestCandidate(name, datacenter, offering string, age time.Duration, utilization float64, workloads int) *ScaleDownCandidate {
+	node := createTestNode(name, "test-group", 4000, 8000000000)
+	node.CreationTimestamp = metav1.NewTime(time.Now().Add(-age))
+	node.Labels[autoscalerv1alpha1.DatacenterLabelKey] = datacenter
+	node.Labels[autoscalerv1alpha1.OfferingLabelKey] = offering
+
+	var pods []*corev1.Pod
+	pods = append(pods, newEmptyNodeTestPod(name+"-daemon", name, "DaemonSet"))
+	for i := 0; i < workloads; i++ {
+		pods = append(pods, newEmptyNodeTestPod(fmt.Sprintf("%s-app-%d", name, i), name, "ReplicaSet"))
+	}
+
+	return &ScaleDownCandidate{
+		Node:        node,
+		Pods:        pods,
+		Utilization: &NodeUtilization{NodeName: name, CPUUtilization: utilization, MemoryUtilization: utilization},
+	}
+}
+
+func candidateNames(candidates []*ScaleDownCandidate) []string {
+	names := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		names[i] = candidate.Node.Name
+	}
+	return names
+}
+
+func TestOrderForDeletion(t *testing.T) {
+	newCandidates := func() []*ScaleDownCandidate {
+		return []*ScaleDownCandidate{
+			newDeletionOrderTestCandidate("node-1", "dc-a", "small", 2*time.Hour, 30, 3),
+			newDeletionOrderTestCandidate("node-2", "dc-a", "large", 48*time.Hour, 20, 1),
+			newDeletionOrderTestCandidate("node-3", "dc-b", "medium", 24*time.Hour, 10, 2),
+		}
+	}
+	pricer := &fakeOfferingPricer{prices: map[string]float64{"small": 10, "medium": 20, "large": 40}}
+
+	tests := []struct {
+		order autoscalerv1alpha1.NodeDeletionOrder
+		want  []string
+	}{
+		{order: autoscalerv1alpha1.NodeDeletionOrderDefault, want: []string{"node-1", "node-2", "node-3"}},
+		{order: "", want: []string{"node-1", "node-2", "node-3"}},
+		{order: autoscalerv1alpha1.NodeDeletionOrderOldestFirst, want: []string{"node-2", "node-3", "node-1"}},
+		{order: autoscalerv1alpha1.NodeDeletionOrderNewestFirst, want: []string{"node-1", "node-3", "node-2"}},
+		{order: autoscalerv1alpha1.NodeDeletionOrderLeastUtilized, want: []string{"node-3", "node-2", "node-1"}},
+		{order: autoscalerv1alpha1.NodeDeletionOrderMostExpensiveFirst, want: []string{"node-2", "node-3", "node-1"}},
+		{order: autoscalerv1alpha1.NodeDeletionOrderFewestPods, want: []string{"node-2", "node-3", "node-1"}},
+		// dc-a also runs node-4, which is kept
+		{order: autoscalerv1alpha1.NodeDeletionOrderSpreadPreserving, want: []string{"node-1", "node-2", "node-3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.order), func(t *testing.T) {
+			candidates := newCandidates()
+			nodes := []*corev1.Node{candidates[0].Node, candidates[1].Node, candidates[2].Node,
+				createTestNode("node-4", "test-group", 4000, 8000000000)}
+			nodes[3].Labels[autoscalerv1alpha1.DatacenterLabelKey] = "dc-a"
+
+			OrderForDeletion(candidates, tt.order, &DeletionOrderInput{
+				Nodes: nodes,
+				Price: OfferingPrices(context.Background(), pricer),
+			})
+			if got := candidateNames(candidates); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestOrderForDeletion_SpreadPreserving(t *testing.T) {
+	// dc-a runs three nodes and dc-b two, all candidates
+	candidates := []*ScaleDownCandidate{
+		newDeletionOrderTestCandidate("b-1", "dc-b", "small", time.Hour, 10, 0),
+		newDeletionOrderTestCandidate("b-2", "dc-b", "small", time.Hour, 10, 0),
+		newDeletionOrderTestCandidate("a-1", "dc-a", "small", time.Hour, 10, 0),
+		newDeletionOrderTestCandidate("a-2", "dc-a", "small", time.Hour, 10, 0),
+		newDeletionOrderTestCandidate("a-3", "dc-a", "small", time.Hour, 10, 0),
+	}
+
+	OrderForDeletion(candidates, autoscalerv1alpha1.NodeDeletionOrderSpreadPreserving, nil)
+
+	want := []string{"a-1", "b-1", "a-2", "b-2", "a-3"}
+	if got := candidateNames(candidates); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestOrderForDeletion_NotReadyFirst(t *testing.T) {
+	notReady := newDeletionOrderTestCandidate("node-2", "dc-a", "small", time.Hour, 50, 0)
+	notReady.Node.Status.Conditions[0].Status = corev1.ConditionFalse
+	candidates := []*ScaleDownCandidate{
+		newDeletionOrderTestCandidate("node-1", "dc-a", "small", 48*time.Hour, 10, 0),
+		notReady,
+	}
+
+	OrderForDeletion(candidates, autoscalerv1alpha1.NodeDeletionOrderOldestFirst, nil)
+
+	if candidates[0].Node.Name != "node-2" {
+		t.Errorf("expected the not ready node first, got %v", candidateNames(candidates))
+	}
+}
+
+func TestOrderForDeletion_MissingData(t *testing.T) {
+	withoutUtilization := newDeletionOrderTestCandidate("node-1", "dc-a", "unknown", time.Hour, 0, 0)
+	withoutUtilization.Utilization = nil
+	candidates := []*ScaleDownCandidate{
+		withoutUtilization,
+		newDeletionOrderTestCandidate("node-2", "dc-a", "small", time.Hour, 40, 0),
+	}
+
+	// Nodes without utilization data go last
+	OrderForDeletion(candidates, autoscalerv1alpha1.NodeDeletionOrderLeastUtilized, nil)
+	if candidates[0].Node.Name != "node-2" {
+		t.Errorf("expected node-2 first, got %v", candidateNames(candidates))
+	}
+
+	// Without prices the order is kept; offerings without a price go last
+	OrderForDeletion(candidates, autoscalerv1alpha1.NodeDeletionOrderMostExpensiveFirst, nil)
+	if candidates[0].Node.Name != "node-2" {
+		t.Errorf("expected the order to be kept without prices, got %v", candidateNames(candidates))
+	}
+	candidates[0], candidates[1] = candidates[1], candidates[0]
+	pricer := &fakeOfferingPricer{prices: map[string]float64{"small": 10}}
+	OrderForDeletion(candidates, autoscalerv1alpha1.NodeDeletionOrderMostExpensiveFirst,
+		&DeletionOrderInput{Price: OfferingPrices(context.Background(), pricer)})
+	if candidates[0].Node.Name != "node-2" {
+		t.Errorf("expected the priced node-2 first, got %v", candidateNames(candidates))
+	}
+}
+
+func TestOfferingPrices(t *testing.T) {
+	pricer := &fakeOfferingPricer{prices: map[string]float64{"small": 10}}
+	price := OfferingPrices(context.Background(), pricer)
+
+	for i := 0; i < 3; i++ {
+		if p, ok := price("small"); !ok || p != 10 {
+			t.Errorf("expected small to cost 10, got %v %v", p, ok)
+		}
+		if _, ok := price("unknown"); ok {
+			t.Error("expected no price for an unknown offering")
+		}
+	}
+	if _, ok := price(""); ok {
+		t.Error("expected no price without an offering")
+	}
+	if pricer.lookups != 2 {
+		t.Errorf("expected one lookup per offering, got %d", pricer.lookups)
+	}
+}
+
+func TestIdentifyEmptyNodes_DeletionOrder(t *testing.T) {
+	older := createTestNode("node-1", "test-group", 4000, 8000000000)
+	older.CreationTimestamp = metav1.NewTime(time.Now().Add(-48 * time.Hour))
+	newer := createTestNode("node-2", "test-group", 4000, 8000000000)
+	newer.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+
+	manager := NewScaleDownManager(newEmptyNodeTestClient(older, newer), nil, zaptest.NewLogger(t), DefaultConfig())
+	manager.emptyNodes["node-1"] = emptyNode{nodeGroup: "test-group", since: time.Now().Add(-5 * time.Minute)}
+	manager.emptyNodes["node-2"] = emptyNode{nodeGroup: "test-group", since: time.Now().Add(-10 * time.Minute)}
+
+	nodeGroup := newEmptyNodeTestNodeGroup()
+	nodeGroup.Spec.ScaleDownPolicy.DeletionOrder = autoscalerv1alpha1.NodeDeletionOrderOldestFirst
+
+	candidates, err := manager.IdentifyEmptyNodes(context.Background(), nodeGroup)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := candidateNames(candidates); fmt.Sprint(got) != "[node-1 node-2]" {
+		t.Errorf("expected the oldest node first, got %v", got)
+	}
+}
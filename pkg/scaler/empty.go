@@ -0,0 +1,266 @@
+package scaler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	autoscalerv1alpha1 "github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/drain"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
+)
+
+// emptyNode records since when a node of a NodeGroup has been empty
+type emptyNode struct {
+	nodeGroup string
+	since     time.Time
+}
+
+// IdentifyEmptyNodes finds nodes running no pods other than DaemonSet and
+// mirror pods for at least EmptyNodeUnneededTime. Nodes must also have been
+// Ready for EmptyNodeUnneededTime, so new nodes are not removed before their
+// pods are scheduled, and like other scale-down candidates only nodes created
+// for resource metrics are considered.
+//
+// Empty nodes don't need utilization samples or the observation window of
+// underutilized nodes: removing them evicts no workload. Candidates are
+// returned in the NodeGroup's deletion order, longest empty first by default.
+func (s *ScaleDownManager) IdentifyEmptyNodes(
+	ctx context.Context,
+	nodeGroup *autoscalerv1alpha1.NodeGroup,
+) ([]*ScaleDownCandidate, error) {
+	if s.config.MaxEmptyNodesPerScaleDown <= 0 {
+		return nil, nil
+	}
+
+	// NodeGroup isolation: Defensive check to ensure only managed NodeGroups are processed
+	if !autoscalerv1alpha1.IsManagedNodeGroup(nodeGroup) {
+		return nil, nil
+	}
+
+	nodes, err := s.getNodeGroupNodes(ctx, nodeGroup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes: %w", err)
+	}
+
+	now := time.Now()
+	empty := make(map[string]*ScaleDownCandidate)
+	for _, node := range nodes {
+		// Cordoned nodes are already being drained or were cordoned by an operator
+		if node.Spec.Unschedulable || s.isNodeProtected(node) {
+			continue
+		}
+
+		creationReason := node.Annotations[autoscalerv1alpha1.CreationReasonAnnotationKey]
+		if creationReason != "" && creationReason != autoscalerv1alpha1.CreationReasonMetrics {
+			continue
+		}
+
+		readySince, ready := nodeReadySince(node)
+		if !ready || now.Sub(readySince) < s.config.EmptyNodeUnneededTime {
+			continue
+		}
+
+		pods, err := s.getNodePods(ctx, node.Name)
+		if err != nil {
+			s.logger.Errorw("failed to get pods for node",
+				"node", node.Name,
+				"error", err)
+			continue
+		}
+		if len(workloadPods(pods)) > 0 {
+			continue
+		}
+
+		empty[node.Name] = &ScaleDownCandidate{
+			Node:         node,
+			Pods:         pods,
+			SafeToRemove: true,
+			Reason:       "node is empty",
+		}
+	}
+
+	s.emptyLock.Lock()
+
+	// Forget nodes of the NodeGroup that are gone or run workloads again
+	for name, tracked := range s.emptyNodes {
+		if _, ok := empty[name]; !ok && tracked.nodeGroup == nodeGroup.Name {
+			delete(s.emptyNodes, name)
+		}
+	}
+
+	var candidates []*ScaleDownCandidate
+	for name, candidate := range empty {
+		tracked, ok := s.emptyNodes[name]
+		if !ok {
+			tracked = emptyNode{nodeGroup: nodeGroup.Name, since: now}
+			s.emptyNodes[name] = tracked
+		}
+		if now.Sub(tracked.since) < s.config.EmptyNodeUnneededTime {
+			continue
+		}
+		candidate.Priority = int(now.Sub(tracked.since) / time.Second)
+		candidates = append(candidates, candidate)
+	}
+	s.emptyLock.Unlock()
+
+	sortEmptyCandidates(candidates)
+	s.orderCandidates(ctx, nodeGroup, candidates, nodes)
+	return candidates, nil
+}
+
+// ScaleDownEmptyNodes drains empty nodes in parallel, at most
+// MaxEmptyNodesPerScaleDown at once, and returns the drained ones.
+//
+// Unlike ScaleDown, the cooldown since the last scale-down and the
+// rescheduling checks don't apply: the nodes run no workload. A node a pod
+// was scheduled to since it was identified is skipped.
+func (s *ScaleDownManager) ScaleDownEmptyNodes(
+	ctx context.Context,
+	nodeGroup *autoscalerv1alpha1.NodeGroup,
+	candidates []*ScaleDownCandidate,
+) ([]*ScaleDownCandidate, error) {
+	maxNodes := s.config.MaxEmptyNodesPerScaleDown
+	if len(candidates) > maxNodes {
+		candidates = candidates[:maxNodes]
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	s.logger.Infow("initiating empty node scale-down",
+		"nodeGroup", nodeGroup.Name,
+		"candidates", len(candidates))
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		drained []*ScaleDownCandidate
+		errs    []error
+	)
+
+	for _, candidate := range candidates {
+		wg.Add(1)
+		go func(candidate *ScaleDownCandidate) {
+			defer wg.Done()
+
+			node := candidate.Node
+			if !s.policyEngine.AllowScaleDown(ctx, nodeGroup, node) {
+				metrics.ScaleDownBlockedTotal.WithLabelValues(
+					nodeGroup.Name,
+					nodeGroup.Namespace,
+					"policy_constraint",
+				).Inc()
+				return
+			}
+
+			pods, err := s.getNodePods(ctx, node.Name)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("failed to get pods of %s: %w", node.Name, err))
+				mu.Unlock()
+				return
+			}
+			if len(workloadPods(pods)) > 0 {
+				s.logger.Infow("skipping node - no longer empty",
+					"node", node.Name,
+					"nodeGroup", nodeGroup.Name)
+				return
+			}
+
+			if err := s.drainNode(ctx, node, nodeGroup); err != nil {
+				metrics.ScaleDownErrorsTotal.WithLabelValues(
+					nodeGroup.Name,
+					nodeGroup.Namespace,
+					"drain_failed",
+				).Inc()
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("failed to drain node %s: %w", node.Name, err))
+				mu.Unlock()
+				return
+			}
+
+			mu.Lock()
+			drained = append(drained, candidate)
+			mu.Unlock()
+		}(candidate)
+	}
+	wg.Wait()
+
+	if len(drained) > 0 {
+		s.emptyLock.Lock()
+		for _, candidate := range drained {
+			delete(s.emptyNodes, candidate.Node.Name)
+		}
+		s.emptyLock.Unlock()
+
+		s.scaleDownLock.Lock()
+		s.lastScaleDown[nodeGroup.Name] = time.Now()
+		s.scaleDownLock.Unlock()
+
+		metrics.ScaleDownNodesRemoved.WithLabelValues(
+			nodeGroup.Name,
+			nodeGroup.Namespace,
+		).Observe(float64(len(drained)))
+		metrics.ScaleDownTotal.WithLabelValues(
+			nodeGroup.Name,
+			nodeGroup.Namespace,
+		).Inc()
+	}
+
+	sortEmptyCandidates(drained)
+
+	s.logger.Infow("empty node scale-down completed",
+		"nodeGroup", nodeGroup.Name,
+		"drained", len(drained),
+		"failed", len(errs))
+
+	if len(errs) > 0 {
+		return drained, fmt.Errorf("empty node scale-down completed with %d errors (succeeded: %d): %v", len(errs), len(drained), errs)
+	}
+	return drained, nil
+}
+
+// sortEmptyCandidates sorts empty node candidates longest empty first. Their
+// priority is the number of seconds they have been empty.
+func sortEmptyCandidates(candidates []*ScaleDownCandidate) {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Priority != candidates[j].Priority {
+			return candidates[i].Priority > candidates[j].Priority
+		}
+		return candidates[i].Node.Name < candidates[j].Node.Name
+	})
+}
+
+// nodeReadySince returns since when a node has been Ready, from its Ready
+// condition's transition or else its creation, and false if it is not Ready
+func nodeReadySince(node *corev1.Node) (time.Time, bool) {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type != corev1.NodeReady {
+			continue
+		}
+		if condition.Status != corev1.ConditionTrue {
+			return time.Time{}, false
+		}
+		if !condition.LastTransitionTime.IsZero() {
+			return condition.LastTransitionTime.Time, true
+		}
+		return node.CreationTimestamp.Time, true
+	}
+	return time.Time{}, false
+}
+
+// workloadPods returns the pods that keep their node in use
+func workloadPods(pods []*corev1.Pod) []*corev1.Pod {
+	var workload []*corev1.Pod
+	for _, pod := range pods {
+		if drain.IsWorkloadPod(pod) {
+			workload = append(workload, pod)
+		}
+	}
+	return workload
+}
@@ -0,0 +1,250 @@
+package scaler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	autoscalerv1alpha1 "github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+)
+
+// newEmptyNodeTestClient returns a clientset honouring the spec.nodeName
+// field selector of pod lists, which the fake clientset ignores
+func newEmptyNodeTestClient(objects ...runtime.Object) *fake.Clientset {
+	client := fake.NewSimpleClientset(objects...)
+	client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		restrictions := action.(k8stesting.ListAction).GetListRestrictions()
+		nodeName, ok := restrictions.Fields.RequiresExactMatch("spec.nodeName")
+		if !ok {
+			return false, nil, nil
+		}
+		obj, err := client.Tracker().List(corev1.SchemeGroupVersion.WithResource("pods"),
+			corev1.SchemeGroupVersion.WithKind("Pod"), action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		list := obj.(*corev1.PodList)
+		filtered := &corev1.PodList{}
+		for _, pod := range list.Items {
+			if pod.Spec.NodeName == nodeName {
+				filtered.Items = append(filtered.Items, pod)
+			}
+		}
+		return true, filtered, nil
+	})
+	return client
+}
+
+func newEmptyNodeTestPod(name, nodeName string, ownerKind string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID(name),
+		},
+		Spec: corev1.PodSpec{NodeName: nodeName},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+		},
+	}
+	if ownerKind != "" {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{
+			{Kind: ownerKind, Name: name + "-owner", Controller: &controller},
+		}
+	}
+	return pod
+}
+
+func newEmptyNodeTestNodeGroup() *autoscalerv1alpha1.NodeGroup {
+	return &autoscalerv1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-group",
+			Namespace: "default",
+			Labels: map[string]string{
+				autoscalerv1alpha1.ManagedLabelKey: autoscalerv1alpha1.ManagedLabelValue,
+			},
+		},
+		Spec: autoscalerv1alpha1.NodeGroupSpec{
+			MinNodes: 0,
+			MaxNodes: 10,
+			ScaleDownPolicy: autoscalerv1alpha1.ScaleDownPolicy{
+				Enabled: true,
+			},
+		},
+	}
+}
+
+func TestIdentifyEmptyNodes(t *testing.T) {
+	cordoned := createTestNode("node-4", "test-group", 4000, 8000000000)
+	cordoned.Spec.Unschedulable = true
+
+	client := newEmptyNodeTestClient(
+		createTestNode("node-1", "test-group", 4000, 8000000000),
+		createTestNode("node-2", "test-group", 4000, 8000000000),
+		createTestNode("node-3", "test-group", 4000, 8000000000),
+		cordoned,
+		newEmptyNodeTestPod("daemon", "node-1", "DaemonSet"),
+		newEmptyNodeTestPod("web", "node-2", "ReplicaSet"),
+	)
+
+	config := DefaultConfig()
+	config.EmptyNodeUnneededTime = 2 * time.Minute
+	manager := NewScaleDownManager(client, nil, zaptest.NewLogger(t), config)
+	nodeGroup := newEmptyNodeTestNodeGroup()
+	ctx := context.Background()
+
+	// Empty nodes are tracked but not removed before the unneeded time
+	candidates, err := manager.IdentifyEmptyNodes(ctx, nodeGroup)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(candidates) != 0 {
+		t.Fatalf("expected no candidates before the unneeded time, got %d", len(candidates))
+	}
+	if len(manager.emptyNodes) != 2 {
+		t.Fatalf("expected node-1 and node-3 to be tracked as empty, got %v", manager.emptyNodes)
+	}
+	if _, ok := manager.emptyNodes["node-2"]; ok {
+		t.Error("expected node-2 running a workload pod not to be tracked")
+	}
+
+	manager.emptyNodes["node-1"] = emptyNode{nodeGroup: "test-group", since: time.Now().Add(-5 * time.Minute)}
+	manager.emptyNodes["node-3"] = emptyNode{nodeGroup: "test-group", since: time.Now().Add(-3 * time.Minute)}
+
+	candidates, err = manager.IdentifyEmptyNodes(ctx, nodeGroup)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(candidates) != 2 {
+		t.Fatalf("expected 2 candidates, got %d", len(candidates))
+	}
+	if candidates[0].Node.Name != "node-1" || candidates[1].Node.Name != "node-3" {
+		t.Errorf("expected longest empty node first, got %s, %s", candidates[0].Node.Name, candidates[1].Node.Name)
+	}
+
+	// A node running a workload again is forgotten
+	if _, err := client.CoreV1().Pods("default").Create(ctx, newEmptyNodeTestPod("job", "node-3", "Job"), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to create pod: %v", err)
+	}
+	candidates, err = manager.IdentifyEmptyNodes(ctx, nodeGroup)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].Node.Name != "node-1" {
+		t.Errorf("expected only node-1, got %d candidates", len(candidates))
+	}
+	if _, ok := manager.emptyNodes["node-3"]; ok {
+		t.Error("expected node-3 to be forgotten")
+	}
+}
+
+func TestIdentifyEmptyNodes_Eligibility(t *testing.T) {
+	recentlyReady := createTestNode("node-recent", "test-group", 4000, 8000000000)
+	recentlyReady.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-30 * time.Second))
+	notReady := createTestNode("node-not-ready", "test-group", 4000, 8000000000)
+	notReady.Status.Conditions[0].Status = corev1.ConditionFalse
+	manual := createTestNode("node-manual", "test-group", 4000, 8000000000)
+	manual.Annotations = map[string]string{
+		autoscalerv1alpha1.CreationReasonAnnotationKey: autoscalerv1alpha1.CreationReasonManual,
+	}
+	metricsNode := createTestNode("node-metrics", "test-group", 4000, 8000000000)
+	metricsNode.Annotations = map[string]string{
+		autoscalerv1alpha1.CreationReasonAnnotationKey: autoscalerv1alpha1.CreationReasonMetrics,
+	}
+	metricsNode.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-time.Hour))
+
+	manager := NewScaleDownManager(newEmptyNodeTestClient(recentlyReady, notReady, manual, metricsNode),
+		nil, zaptest.NewLogger(t), DefaultConfig())
+	for _, name := range []string{"node-recent", "node-not-ready", "node-manual", "node-metrics"} {
+		manager.emptyNodes[name] = emptyNode{nodeGroup: "test-group", since: time.Now().Add(-10 * time.Minute)}
+	}
+
+	candidates, err := manager.IdentifyEmptyNodes(context.Background(), newEmptyNodeTestNodeGroup())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := candidateNames(candidates); fmt.Sprint(got) != "[node-metrics]" {
+		t.Errorf("expected only the metrics node Ready long enough, got %v", got)
+	}
+}
+
+func TestIdentifyEmptyNodes_Disabled(t *testing.T) {
+	client := newEmptyNodeTestClient(createTestNode("node-1", "test-group", 4000, 8000000000))
+
+	config := DefaultConfig()
+	config.EmptyNodeUnneededTime = 0
+	config.MaxEmptyNodesPerScaleDown = 0
+	manager := NewScaleDownManager(client, nil, zaptest.NewLogger(t), config)
+
+	candidates, err := manager.IdentifyEmptyNodes(context.Background(), newEmptyNodeTestNodeGroup())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(candidates) != 0 {
+		t.Errorf("expected no candidates with the empty-node path disabled, got %d", len(candidates))
+	}
+}
+
+func TestScaleDownEmptyNodes(t *testing.T) {
+	nodes := []*corev1.Node{
+		createTestNode("node-1", "test-group", 4000, 8000000000),
+		createTestNode("node-2", "test-group", 4000, 8000000000),
+		createTestNode("node-3", "test-group", 4000, 8000000000),
+		createTestNode("node-4", "test-group", 4000, 8000000000),
+	}
+	client := newEmptyNodeTestClient(
+		nodes[0], nodes[1], nodes[2], nodes[3],
+		newEmptyNodeTestPod("daemon", "node-1", "DaemonSet"),
+		// Scheduled to node-3 after it was identified as empty
+		newEmptyNodeTestPod("web", "node-3", "ReplicaSet"),
+	)
+
+	config := DefaultConfig()
+	config.MaxEmptyNodesPerScaleDown = 3
+	config.EmptyNodeUnneededTime = 0
+	manager := NewScaleDownManager(client, nil, zaptest.NewLogger(t), config)
+
+	var candidates []*ScaleDownCandidate
+	for i, node := range nodes {
+		candidates = append(candidates, &ScaleDownCandidate{Node: node, Priority: 100 - i})
+	}
+
+	ctx := context.Background()
+	drained, err := manager.ScaleDownEmptyNodes(ctx, newEmptyNodeTestNodeGroup(), candidates)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// node-4 exceeds the bulk limit and node-3 is no longer empty
+	if len(drained) != 2 || drained[0].Node.Name != "node-1" || drained[1].Node.Name != "node-2" {
+		t.Fatalf("expected node-1 and node-2 to be drained, got %d nodes", len(drained))
+	}
+	for name, cordoned := range map[string]bool{"node-1": true, "node-2": true, "node-3": false, "node-4": false} {
+		node, err := client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get node: %v", err)
+		}
+		if node.Spec.Unschedulable != cordoned {
+			t.Errorf("expected %s cordoned=%v, got %v", name, cordoned, node.Spec.Unschedulable)
+		}
+	}
+
+	// The DaemonSet pod is not evicted
+	if _, err := client.CoreV1().Pods("default").Get(ctx, "daemon", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected DaemonSet pod to remain, got %v", err)
+	}
+
+	if manager.isOutsideCooldownPeriod("test-group") {
+		t.Error("expected the scale-down time to be recorded")
+	}
+}
@@ -0,0 +1,140 @@
+package scaler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// UnneededSinceAnnotation records since when a node has been underutilized,
+	// as an RFC 3339 timestamp. It is removed when the node is needed again.
+	UnneededSinceAnnotation = "autoscaler.vpsie.com/unneeded-since"
+
+	// UtilizationSummaryAnnotation records the rolling utilization of a node
+	// as a JSON-encoded UtilizationSummary
+	UtilizationSummaryAnnotation = "autoscaler.vpsie.com/utilization-summary"
+
+	// DefaultUtilizationPersistInterval is how often a node's rolling
+	// utilization is written to its annotations
+	DefaultUtilizationPersistInterval = 5 * time.Minute
+)
+
+// UtilizationSummary is the rolling utilization of a node persisted on the
+// Node so scale-down progress survives a restart or leader failover
+type UtilizationSummary struct {
+	CPUUtilization    float64   `json:"cpu"`
+	MemoryUtilization float64   `json:"memory"`
+	Samples           int       `json:"samples"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+// restoreNodeUtilization seeds the utilization tracking of a node seen for
+// the first time from its annotations. The summary becomes a single sample at
+// its update time carrying the rolling utilization. Summaries older than the
+// observation window are ignored.
+func (s *ScaleDownManager) restoreNodeUtilization(util *NodeUtilization, node *corev1.Node, now time.Time) {
+	summary, ok := parseUtilizationSummary(node)
+	if !ok || summary.UpdatedAt.After(now) || now.Sub(summary.UpdatedAt) > s.config.ObservationWindow {
+		return
+	}
+
+	// The summary was measured under the NodeGroup's utilization mode, so it
+	// stands for both usage and requests
+	util.Samples = append(util.Samples, UtilizationSample{
+		Timestamp:         summary.UpdatedAt,
+		CPUUtilization:    summary.CPUUtilization,
+		MemoryUtilization: summary.MemoryUtilization,
+		CPURequests:       summary.CPUUtilization,
+		MemoryRequests:    summary.MemoryUtilization,
+	})
+
+	if since, err := time.Parse(time.RFC3339, node.Annotations[UnneededSinceAnnotation]); err == nil &&
+		!since.After(summary.UpdatedAt) {
+		util.UnneededSince = since
+	}
+
+	s.logger.Infow("restored node utilization from annotations",
+		"node", node.Name,
+		"cpu", fmt.Sprintf("%.2f%%", summary.CPUUtilization),
+		"memory", fmt.Sprintf("%.2f%%", summary.MemoryUtilization),
+		"unneededSince", util.UnneededSince)
+}
+
+// persistNodeUtilization writes a node's unneeded-since time and rolling
+// utilization to its annotations. A change of the unneeded-since time is
+// written right away; the summary alone at most every
+// UtilizationPersistInterval.
+func (s *ScaleDownManager) persistNodeUtilization(ctx context.Context, node *corev1.Node) error {
+	interval := s.config.UtilizationPersistInterval
+	if interval <= 0 {
+		return nil
+	}
+
+	util, ok := s.GetNodeUtilization(node.Name)
+	if !ok {
+		return nil
+	}
+
+	var since string
+	if !util.UnneededSince.IsZero() {
+		since = util.UnneededSince.UTC().Format(time.RFC3339)
+	}
+	sinceChanged := node.Annotations[UnneededSinceAnnotation] != since
+
+	summary, ok := parseUtilizationSummary(node)
+	if !sinceChanged && ok && util.LastUpdated.Sub(summary.UpdatedAt) < interval {
+		return nil
+	}
+
+	data, err := json.Marshal(UtilizationSummary{
+		CPUUtilization:    math.Round(util.CPUUtilization*100) / 100,
+		MemoryUtilization: math.Round(util.MemoryUtilization*100) / 100,
+		Samples:           len(util.Samples),
+		UpdatedAt:         util.LastUpdated.UTC().Truncate(time.Second),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode utilization summary: %w", err)
+	}
+
+	// A null value removes the annotation
+	var unneededSince interface{}
+	if since != "" {
+		unneededSince = since
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				UtilizationSummaryAnnotation: string(data),
+				UnneededSinceAnnotation:      unneededSince,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode patch: %w", err)
+	}
+
+	if _, err := s.client.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to patch node annotations: %w", err)
+	}
+	return nil
+}
+
+// parseUtilizationSummary returns the utilization summary persisted on a node
+func parseUtilizationSummary(node *corev1.Node) (UtilizationSummary, bool) {
+	var summary UtilizationSummary
+	value, ok := node.Annotations[UtilizationSummaryAnnotation]
+	if !ok {
+		return summary, false
+	}
+	if err := json.Unmarshal([]byte(value), &summary); err != nil || summary.UpdatedAt.IsZero() {
+		return summary, false
+	}
+	return summary, true
+}
@@ -0,0 +1,162 @@
+package scaler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func newPersistenceTestMetrics(name string, cpuMillis int64) *metricsv1beta1.NodeMetrics {
+	return &metricsv1beta1.NodeMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Usage: corev1.ResourceList{
+			corev1.ResourceCPU:    *resource.NewMilliQuantity(cpuMillis, resource.DecimalSI),
+			corev1.ResourceMemory: *resource.NewQuantity(800000000, resource.BinarySI),
+		},
+	}
+}
+
+func annotateUtilization(t *testing.T, node *corev1.Node, summary UtilizationSummary, unneededSince time.Time) {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		t.Fatalf("failed to encode summary: %v", err)
+	}
+	node.Annotations = map[string]string{
+		UtilizationSummaryAnnotation: string(data),
+		UnneededSinceAnnotation:      unneededSince.UTC().Format(time.RFC3339),
+	}
+}
+
+func TestRestoreNodeUtilization(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name          string
+		updatedAt     time.Time
+		restored      bool
+		readyForScale bool
+	}{
+		{
+			name:          "recent summary",
+			updatedAt:     now.Add(-2 * time.Minute),
+			restored:      true,
+			readyForScale: true,
+		},
+		{
+			name:      "summary older than the observation window",
+			updatedAt: now.Add(-30 * time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := createTestNode("node-1", "test-group", 4000, 8000000000)
+			unneededSince := now.Add(-20 * time.Minute)
+			annotateUtilization(t, node, UtilizationSummary{
+				CPUUtilization:    12,
+				MemoryUtilization: 15,
+				Samples:           10,
+				UpdatedAt:         tt.updatedAt,
+			}, unneededSince)
+
+			manager := NewScaleDownManager(fake.NewSimpleClientset(node), nil, zaptest.NewLogger(t), DefaultConfig())
+			if err := manager.updateNodeUtilizationMetrics(context.Background(), node, newPersistenceTestMetrics("node-1", 400), nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			util, ok := manager.GetNodeUtilization("node-1")
+			if !ok {
+				t.Fatal("expected node-1 to be tracked")
+			}
+			if tt.restored {
+				if len(util.Samples) != 2 {
+					t.Errorf("expected the restored and the new sample, got %d samples", len(util.Samples))
+				}
+				if !util.UnneededSince.Equal(unneededSince.Truncate(time.Second)) {
+					t.Errorf("expected unneeded since %v, got %v", unneededSince, util.UnneededSince)
+				}
+			} else {
+				if len(util.Samples) != 1 {
+					t.Errorf("expected only the new sample, got %d samples", len(util.Samples))
+				}
+				if now.Sub(util.UnneededSince) > time.Minute {
+					t.Errorf("expected unneeded since to start over, got %v", util.UnneededSince)
+				}
+			}
+			if got := manager.hasBeenUnderutilizedForWindow(util, utilizationPolicy{}); got != tt.readyForScale {
+				t.Errorf("expected underutilized for window %v, got %v", tt.readyForScale, got)
+			}
+		})
+	}
+}
+
+func TestPersistNodeUtilization(t *testing.T) {
+	node := createTestNode("node-1", "test-group", 4000, 8000000000)
+	client := fake.NewSimpleClientset(node)
+	manager := NewScaleDownManager(client, nil, zaptest.NewLogger(t), DefaultConfig())
+	ctx := context.Background()
+
+	now := time.Now()
+	unneededSince := now.Add(-3 * time.Minute)
+	manager.nodeUtilization["node-1"] = &NodeUtilization{
+		NodeName:          "node-1",
+		CPUUtilization:    12.345,
+		MemoryUtilization: 20,
+		IsUnderutilized:   true,
+		UnneededSince:     unneededSince,
+		LastUpdated:       now,
+		Samples:           []UtilizationSample{{Timestamp: now, CPUUtilization: 12.345, MemoryUtilization: 20}},
+	}
+
+	if err := manager.persistNodeUtilization(ctx, node); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	persisted, err := client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get node: %v", err)
+	}
+	if got := persisted.Annotations[UnneededSinceAnnotation]; got != unneededSince.UTC().Format(time.RFC3339) {
+		t.Errorf("unexpected unneeded-since annotation %q", got)
+	}
+	summary, ok := parseUtilizationSummary(persisted)
+	if !ok {
+		t.Fatalf("expected a utilization summary, got %q", persisted.Annotations[UtilizationSummaryAnnotation])
+	}
+	if summary.CPUUtilization != 12.35 || summary.MemoryUtilization != 20 || summary.Samples != 1 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	// An unchanged node isn't patched again before the persist interval
+	client.ClearActions()
+	if err := manager.persistNodeUtilization(ctx, persisted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.Actions()) != 0 {
+		t.Errorf("expected no patch, got %d actions", len(client.Actions()))
+	}
+
+	// A node needed again loses its unneeded-since annotation right away
+	manager.nodeUtilization["node-1"].IsUnderutilized = false
+	manager.nodeUtilization["node-1"].UnneededSince = time.Time{}
+	if err := manager.persistNodeUtilization(ctx, persisted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	persisted, err = client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get node: %v", err)
+	}
+	if _, ok := persisted.Annotations[UnneededSinceAnnotation]; ok {
+		t.Error("expected the unneeded-since annotation to be removed")
+	}
+	if _, ok := persisted.Annotations[UtilizationSummaryAnnotation]; !ok {
+		t.Error("expected the utilization summary to remain")
+	}
+}
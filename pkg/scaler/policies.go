@@ -62,33 +62,43 @@ func (p *PolicyEngine) AllowScaleDown(
 	nodeGroup *autoscalerv1alpha1.NodeGroup,
 	node *corev1.Node,
 ) bool {
-	// Check if scale-down is globally disabled
-	currentMode := p.getCurrentMode()
-	if currentMode == PolicyModeDisabled {
-		p.logger.Debug("scale-down disabled by policy", "node", node.Name)
+	if reason := p.ScaleDownDeniedReason(nodeGroup, node); reason != "" {
+		p.logger.Debug("scale-down denied by policy",
+			"nodeGroup", nodeGroup.Name,
+			"node", node.Name,
+			"reason", reason)
 		return false
 	}
+	return true
+}
+
+// ScaleDownDeniedReason returns why the policies don't allow removing the
+// node, or an empty string if they do
+func (p *PolicyEngine) ScaleDownDeniedReason(
+	nodeGroup *autoscalerv1alpha1.NodeGroup,
+	node *corev1.Node,
+) string {
+	// Check if scale-down is globally disabled
+	if p.getCurrentMode() == PolicyModeDisabled {
+		return "scale-down disabled by policy"
+	}
 
 	// Check NodeGroup-specific scale-down policy
 	if !nodeGroup.Spec.ScaleDownPolicy.Enabled {
-		p.logger.Debug("scale-down disabled for NodeGroup",
-			"nodeGroup", nodeGroup.Name,
-			"node", node.Name)
-		return false
+		return "scale-down disabled for NodeGroup"
 	}
 
 	// Check time-based policies
 	if !p.isWithinAllowedTime() {
-		p.logger.Debug("scale-down not allowed at current time", "node", node.Name)
-		return false
+		return "scale-down not allowed at current time"
 	}
 
 	// Check node-specific policy annotations
 	if !p.isNodeAllowedForScaleDown(node) {
-		return false
+		return "scale-down not allowed by node annotations"
 	}
 
-	return true
+	return ""
 }
 
 // getCurrentMode returns the current policy mode based on time windows
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/drain"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/utils"
 
@@ -14,6 +15,127 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 )
 
+// SafetyCheckResult is the outcome of one of the ordered node removal safety checks
+type SafetyCheckResult struct {
+	// Name identifies the check, e.g. "local-storage"
+	Name string
+	// Passed is true if the check allows removing the node
+	Passed bool
+	// Message says why the check failed, or what it verified if it passed
+	Message string
+	// Err is set if the check could not be evaluated
+	Err error
+
+	// metric is the check's label of SafetyCheckFailuresTotal
+	metric string
+}
+
+// safetyCheck is a node removal safety check
+type safetyCheck struct {
+	name        string
+	metric      string
+	passMessage string
+	run         func(ctx context.Context, node *corev1.Node, pods []*corev1.Pod) (passed bool, reason string, err error)
+}
+
+// safetyChecks returns the safety checks of IsSafeToRemove in the order they run
+func (s *ScaleDownManager) safetyChecks() []safetyCheck {
+	return []safetyCheck{
+		{
+			// Check 1: No pod is annotated as not safe to evict
+			name: "safe-to-evict", metric: "pod_annotation",
+			passMessage: "no pod is annotated not safe to evict",
+			run: func(_ context.Context, _ *corev1.Node, pods []*corev1.Pod) (bool, string, error) {
+				_, reasons := blockingPods(pods)
+				return len(reasons) == 0, strings.Join(reasons, "; "), nil
+			},
+		},
+		{
+			// Check 2: Node has no pods with local storage
+			name: "local-storage", metric: "local_storage",
+			passMessage: "no pod uses local storage",
+			run: func(ctx context.Context, _ *corev1.Node, pods []*corev1.Pod) (bool, string, error) {
+				hasLocalStorage, reason := s.hasPodsWithLocalStorage(ctx, pods)
+				return !hasLocalStorage, reason, nil
+			},
+		},
+		{
+			// Check 3: All pods can be scheduled elsewhere
+			name: "rescheduling", metric: "rescheduling",
+			passMessage: "all pods can be scheduled elsewhere",
+			run: func(ctx context.Context, _ *corev1.Node, pods []*corev1.Pod) (bool, string, error) {
+				return s.canPodsBeRescheduled(ctx, pods)
+			},
+		},
+		{
+			// Check 4: System pods have alternatives
+			name: "system-pods", metric: "system_pods",
+			passMessage: "system pods have alternatives",
+			run: func(_ context.Context, _ *corev1.Node, pods []*corev1.Pod) (bool, string, error) {
+				hasUniqueSystem, reason := s.hasUniqueSystemPods(pods)
+				return !hasUniqueSystem, reason, nil
+			},
+		},
+		{
+			// Check 5: No pod anti-affinity violations
+			name: "anti-affinity", metric: "affinity",
+			passMessage: "no pod anti-affinity violations",
+			run: func(ctx context.Context, _ *corev1.Node, pods []*corev1.Pod) (bool, string, error) {
+				hasViolation, reason, err := s.hasAntiAffinityViolations(ctx, pods)
+				return !hasViolation, reason, err
+			},
+		},
+		{
+			// Check 6: Cluster has sufficient capacity after removal
+			name: "capacity", metric: "capacity",
+			passMessage: "the cluster has capacity after removal",
+			run: func(ctx context.Context, node *corev1.Node, pods []*corev1.Pod) (bool, string, error) {
+				insufficient, reason, err := s.hasInsufficientCapacity(ctx, node, pods)
+				return !insufficient, reason, err
+			},
+		},
+		{
+			// Check 7: Node is not annotated as protected
+			name: "protection", metric: "protection",
+			passMessage: "node is not protected",
+			run: func(_ context.Context, node *corev1.Node, _ []*corev1.Pod) (bool, string, error) {
+				return !s.isNodeProtected(node), "node is protected", nil
+			},
+		},
+	}
+}
+
+// RunSafetyChecks runs the safety checks of IsSafeToRemove in order, without
+// recording events or metrics. Unless all is set it stops at the first check
+// that fails or cannot be evaluated.
+func (s *ScaleDownManager) RunSafetyChecks(
+	ctx context.Context,
+	node *corev1.Node,
+	pods []*corev1.Pod,
+	all bool,
+) []SafetyCheckResult {
+	checks := s.safetyChecks()
+	results := make([]SafetyCheckResult, 0, len(checks))
+	for _, check := range checks {
+		passed, reason, err := check.run(ctx, node, pods)
+		result := SafetyCheckResult{Name: check.name, Passed: passed && err == nil, Err: err, metric: check.metric}
+		switch {
+		case err != nil:
+			result.Message = err.Error()
+		case passed:
+			result.Message = check.passMessage
+		default:
+			result.Message = reason
+		}
+		results = append(results, result)
+
+		if !result.Passed && !all {
+			break
+		}
+	}
+	return results
+}
+
 // IsSafeToRemove performs comprehensive safety checks before node removal
 func (s *ScaleDownManager) IsSafeToRemove(
 	ctx context.Context,
@@ -22,6 +144,20 @@ func (s *ScaleDownManager) IsSafeToRemove(
 ) (bool, string, error) {
 	s.logger.Debug("running safety checks for node removal", "node", node.Name)
 
+	results := s.RunSafetyChecks(ctx, node, pods, false)
+	last := results[len(results)-1]
+	if last.Err != nil {
+		return false, "", last.Err
+	}
+	if last.Passed {
+		s.logger.Debug("all safety checks passed", "node", node.Name)
+		return true, "safe to remove", nil
+	}
+
+	if last.Name == "safe-to-evict" {
+		s.recordBlockingPodEvents(node, pods)
+	}
+
 	// Get nodegroup labels for metrics (best effort)
 	nodeGroupName := node.Labels["autoscaler.vpsie.com/nodegroup"]
 	nodeGroupNamespace := node.Labels["autoscaler.vpsie.com/nodegroup-namespace"]
@@ -36,83 +172,42 @@ func (s *ScaleDownManager) IsSafeToRemove(
 	nodeGroupName, _ = metrics.SanitizeLabel(nodeGroupName)
 	nodeGroupNamespace, _ = metrics.SanitizeLabel(nodeGroupNamespace)
 
-	// Check 1: Node has no pods with local storage
-	if hasLocalStorage, reason := s.hasPodsWithLocalStorage(ctx, pods); hasLocalStorage {
-		// Record safety check failure: local storage
-		metrics.SafetyCheckFailuresTotal.WithLabelValues(
-			"local_storage",
-			nodeGroupName,
-			nodeGroupNamespace,
-		).Inc()
-		return false, reason, nil
-	}
-
-	// Check 2: All pods can be scheduled elsewhere
-	if canSchedule, reason, err := s.canPodsBeRescheduled(ctx, pods); err != nil {
-		return false, "", err
-	} else if !canSchedule {
-		// Record safety check failure: rescheduling
-		metrics.SafetyCheckFailuresTotal.WithLabelValues(
-			"rescheduling",
-			nodeGroupName,
-			nodeGroupNamespace,
-		).Inc()
-		return false, reason, nil
-	}
-
-	// Check 3: System pods have alternatives
-	if hasUniqueSystem, reason := s.hasUniqueSystemPods(pods); hasUniqueSystem {
-		// Record safety check failure: unique system pods
-		metrics.SafetyCheckFailuresTotal.WithLabelValues(
-			"system_pods",
-			nodeGroupName,
-			nodeGroupNamespace,
-		).Inc()
-		return false, reason, nil
-	}
-
-	// Check 4: No pod anti-affinity violations
-	if hasViolation, reason, err := s.hasAntiAffinityViolations(ctx, pods); err != nil {
-		return false, "", err
-	} else if hasViolation {
-		// Record safety check failure: affinity
-		metrics.SafetyCheckFailuresTotal.WithLabelValues(
-			"affinity",
-			nodeGroupName,
-			nodeGroupNamespace,
-		).Inc()
-		return false, reason, nil
-	}
-
-	// Check 5: Cluster has sufficient capacity after removal
-	if insufficient, reason, err := s.hasInsufficientCapacity(ctx, node, pods); err != nil {
-		return false, "", err
-	} else if insufficient {
-		// Record safety check failure: capacity
-		metrics.SafetyCheckFailuresTotal.WithLabelValues(
-			"capacity",
-			nodeGroupName,
-			nodeGroupNamespace,
-		).Inc()
-		return false, reason, nil
-	}
-
-	// Check 6: Node is not annotated as protected
-	if s.isNodeProtected(node) {
-		// Record safety check failure: protection
-		metrics.SafetyCheckFailuresTotal.WithLabelValues(
-			"protection",
-			nodeGroupName,
-			nodeGroupNamespace,
-		).Inc()
-		return false, "node is protected", nil
-	}
-
-	s.logger.Debug("all safety checks passed", "node", node.Name)
-	return true, "safe to remove", nil
-}
-
-// hasPodsWithLocalStorage checks if any pods use local storage volumes
+	metrics.SafetyCheckFailuresTotal.WithLabelValues(
+		last.metric,
+		nodeGroupName,
+		nodeGroupNamespace,
+	).Inc()
+	return false, last.Message, nil
+}
+
+// recordBlockingPodEvents records an event on each pod annotated
+// safe-to-evict=false naming the node it keeps
+func (s *ScaleDownManager) recordBlockingPodEvents(node *corev1.Node, pods []*corev1.Pod) {
+	if s.recorder == nil {
+		return
+	}
+	blocking, reasons := blockingPods(pods)
+	for i, pod := range blocking {
+		s.recorder.Eventf(pod, corev1.EventTypeWarning, ScaleDownBlockedReason,
+			"Pod blocks scale-down of node %s: %s", node.Name, reasons[i])
+	}
+}
+
+// blockingPods returns the pods annotated safe-to-evict=false and why each blocks
+func blockingPods(pods []*corev1.Pod) ([]*corev1.Pod, []string) {
+	var blocking []*corev1.Pod
+	var reasons []string
+	for _, pod := range pods {
+		if reason := drain.BlockingReason(pod); reason != "" {
+			blocking = append(blocking, pod)
+			reasons = append(reasons, reason)
+		}
+	}
+	return blocking, reasons
+}
+
+// hasPodsWithLocalStorage checks if any pods use local storage volumes.
+// Volumes the pod's safe-to-evict annotations allow losing are ignored.
 func (s *ScaleDownManager) hasPodsWithLocalStorage(ctx context.Context, pods []*corev1.Pod) (bool, string) {
 	for _, pod := range pods {
 		// Skip DaemonSet pods from system namespaces - they will be recreated on other nodes
@@ -125,6 +220,10 @@ func (s *ScaleDownManager) hasPodsWithLocalStorage(ctx context.Context, pods []*
 		}
 
 		for _, volume := range pod.Spec.Volumes {
+			if drain.IsLocalVolumeSafeToEvict(pod, volume.Name) {
+				continue
+			}
+
 			// Check for EmptyDir volumes
 			if volume.EmptyDir != nil {
 				// EmptyDir with Memory medium is okay (data is already in memory)
@@ -14,6 +14,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 )
 
 // TestIsSingleInstanceSystemPod tests the isSingleInstanceSystemPod function
@@ -752,6 +753,52 @@ func TestHasPodsWithLocalStorage(t *testing.T) {
 			},
 			expectLocal: true,
 		},
+		{
+			name: "Pod with EmptyDir listed in safe-to-evict-local-volumes (allowed)",
+			pods: []*corev1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "cache-pod",
+						Namespace:   "default",
+						Annotations: map[string]string{"autoscaler.vpsie.com/safe-to-evict-local-volumes": "scratch, cache"},
+					},
+					Spec: corev1.PodSpec{
+						Volumes: []corev1.Volume{
+							{
+								Name: "cache",
+								VolumeSource: corev1.VolumeSource{
+									EmptyDir: &corev1.EmptyDirVolumeSource{},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectLocal: false,
+		},
+		{
+			name: "Pod with HostPath annotated cluster-autoscaler safe-to-evict (allowed)",
+			pods: []*corev1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "host-pod",
+						Namespace:   "default",
+						Annotations: map[string]string{"cluster-autoscaler.kubernetes.io/safe-to-evict": "true"},
+					},
+					Spec: corev1.PodSpec{
+						Volumes: []corev1.Volume{
+							{
+								Name: "host",
+								VolumeSource: corev1.VolumeSource{
+									HostPath: &corev1.HostPathVolumeSource{Path: "/var/lib/data"},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectLocal: false,
+		},
 		{
 			name: "Pod with Memory EmptyDir (allowed)",
 			pods: []*corev1.Pod{
@@ -852,6 +899,85 @@ func TestHasPodsWithLocalStorage(t *testing.T) {
 	}
 }
 
+// TestRunSafetyChecks tests that the checks run in order, stop at the first
+// failure unless all are requested and record no events
+func TestRunSafetyChecks(t *testing.T) {
+	ctx := context.Background()
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:        "worker-1",
+		Annotations: map[string]string{ProtectedNodeAnnotation: "true"},
+	}}
+	pods := []*corev1.Pod{{ObjectMeta: metav1.ObjectMeta{
+		Name:        "db",
+		Namespace:   "default",
+		Annotations: map[string]string{"autoscaler.vpsie.com/safe-to-evict": "false"},
+	}}}
+
+	recorder := record.NewFakeRecorder(10)
+	manager := &ScaleDownManager{
+		client: fake.NewSimpleClientset(node),
+		logger: zaptest.NewLogger(t).Sugar(),
+		config: DefaultConfig(),
+	}
+	manager.SetEventRecorder(recorder)
+
+	results := manager.RunSafetyChecks(ctx, node, pods, false)
+	require.Len(t, results, 1)
+	assert.Equal(t, "safe-to-evict", results[0].Name)
+	assert.False(t, results[0].Passed)
+
+	results = manager.RunSafetyChecks(ctx, node, pods, true)
+	names := make([]string, len(results))
+	for i, result := range results {
+		names[i] = result.Name
+	}
+	assert.Equal(t, []string{"safe-to-evict", "local-storage", "rescheduling", "system-pods",
+		"anti-affinity", "capacity", "protection"}, names)
+	assert.False(t, results[6].Passed)
+	assert.Equal(t, "node is protected", results[6].Message)
+	assert.Empty(t, recorder.Events)
+}
+
+// TestIsSafeToRemove_PodAnnotations tests that pods annotated safe-to-evict=false
+// block the removal of their node and get an event
+func TestIsSafeToRemove_PodAnnotations(t *testing.T) {
+	ctx := context.Background()
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}}
+	newPod := func(name string, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: annotations}}
+	}
+	pods := []*corev1.Pod{
+		newPod("web", nil),
+		newPod("db", map[string]string{"autoscaler.vpsie.com/safe-to-evict": "false"}),
+		newPod("batch", map[string]string{"cluster-autoscaler.kubernetes.io/safe-to-evict": "false"}),
+		// Our own annotation takes precedence over the cluster-autoscaler alias
+		newPod("cache", map[string]string{
+			"autoscaler.vpsie.com/safe-to-evict":             "true",
+			"cluster-autoscaler.kubernetes.io/safe-to-evict": "false",
+		}),
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	manager := &ScaleDownManager{
+		client: fake.NewSimpleClientset(node),
+		logger: zaptest.NewLogger(t).Sugar(),
+		config: DefaultConfig(),
+	}
+	manager.SetEventRecorder(recorder)
+
+	safe, reason, err := manager.IsSafeToRemove(ctx, node, pods)
+	require.NoError(t, err)
+	assert.False(t, safe)
+	assert.Contains(t, reason, "default/db is annotated autoscaler.vpsie.com/safe-to-evict=false")
+	assert.Contains(t, reason, "default/batch is annotated cluster-autoscaler.kubernetes.io/safe-to-evict=false")
+	assert.NotContains(t, reason, "cache")
+
+	require.Len(t, recorder.Events, 2)
+	event := <-recorder.Events
+	assert.Contains(t, event, ScaleDownBlockedReason)
+	assert.Contains(t, event, "Pod blocks scale-down of node worker-1")
+}
+
 // TestHasUniqueSystemPods tests the hasUniqueSystemPods method
 func TestHasUniqueSystemPods(t *testing.T) {
 	logger := zaptest.NewLogger(t)
@@ -10,6 +10,7 @@ import (
 
 	"github.com/getsentry/sentry-go"
 	autoscalerv1alpha1 "github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/drain"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/tracing"
 
@@ -20,6 +21,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	metricsv1beta1 "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
@@ -30,9 +32,16 @@ const (
 	DefaultObservationWindow = 10 * time.Minute
 	DefaultCooldownPeriod    = 10 * time.Minute
 
+	// Default empty-node scale-down settings
+	DefaultEmptyNodeUnneededTime     = 2 * time.Minute
+	DefaultMaxEmptyNodesPerScaleDown = 10
+
 	// Annotations for node protection
 	ProtectedNodeAnnotation = "autoscaler.vpsie.com/protected"
 	ScaleDownDisabledLabel  = "autoscaler.vpsie.com/scale-down-disabled"
+
+	// ScaleDownBlockedReason is the reason of events on pods blocking a scale-down
+	ScaleDownBlockedReason = "ScaleDownBlocked"
 )
 
 // ScaleDownManager manages node scale-down operations.
@@ -64,6 +73,15 @@ type ScaleDownManager struct {
 	nodeUtilization map[string]*NodeUtilization
 	utilizationLock sync.RWMutex
 
+	// Utilization mode and aggregation of each NodeGroup, guarded by
+	// utilizationLock: namespace/name -> policy
+	utilizationPolicies map[string]utilizationPolicy
+
+	// NodeGroup each tracked node's policy is looked up under, guarded by
+	// utilizationLock: node -> namespace/name. Nodes only carry the NodeGroup
+	// name, which NodeGroups in different namespaces may share.
+	nodePolicyKeys map[string]string
+
 	// Configuration
 	config *Config
 
@@ -73,6 +91,19 @@ type ScaleDownManager struct {
 
 	// Policy engine
 	policyEngine *PolicyEngine
+
+	// Node drainer
+	drainer drain.Drainer
+
+	// Event recorder for pods blocking scale-down (optional)
+	recorder record.EventRecorder
+
+	// Offering prices for the MostExpensiveFirst deletion order (optional)
+	pricer OfferingPricer
+
+	// Empty node tracking: node -> when it was first seen empty
+	emptyNodes map[string]emptyNode
+	emptyLock  sync.Mutex
 }
 
 // Config holds configuration for scale-down operations
@@ -85,6 +116,18 @@ type Config struct {
 	EnablePodDisruptionBudget bool
 	DrainTimeout              time.Duration
 	EvictionGracePeriod       int32
+
+	// EmptyNodeUnneededTime is how long a node must run no pods other than
+	// DaemonSet and mirror pods before it is removed by the empty-node path
+	EmptyNodeUnneededTime time.Duration
+	// MaxEmptyNodesPerScaleDown is how many empty nodes are removed at once.
+	// Zero disables the empty-node path.
+	MaxEmptyNodesPerScaleDown int
+
+	// UtilizationPersistInterval is how often the rolling utilization of a
+	// node is written to its annotations, to be restored after a restart.
+	// Zero disables persistence.
+	UtilizationPersistInterval time.Duration
 }
 
 // NodeUtilization tracks resource utilization for a node
@@ -95,13 +138,25 @@ type NodeUtilization struct {
 	Samples           []UtilizationSample
 	LastUpdated       time.Time
 	IsUnderutilized   bool
+	// UnneededSince is when the node's rolling utilization last fell below
+	// the thresholds, or zero while it is above them
+	UnneededSince time.Time
 }
 
 // UtilizationSample represents a point-in-time utilization measurement
 type UtilizationSample struct {
 	Timestamp         time.Time
-	CPUUtilization    float64
-	MemoryUtilization float64
+	CPUUtilization    float64 // usage percentage (0-100)
+	MemoryUtilization float64 // usage percentage (0-100)
+	CPURequests       float64 // requested percentage of allocatable (0-100)
+	MemoryRequests    float64 // requested percentage of allocatable (0-100)
+}
+
+// utilizationPolicy is how a NodeGroup measures node utilization. The zero
+// value measures the mean usage.
+type utilizationPolicy struct {
+	mode        autoscalerv1alpha1.UtilizationMode
+	aggregation autoscalerv1alpha1.UtilizationAggregation
 }
 
 // ScaleDownCandidate represents a node that can be scaled down
@@ -126,27 +181,34 @@ func NewScaleDownManager(
 	}
 
 	return &ScaleDownManager{
-		client:          client,
-		metricsClient:   metricsClient,
-		logger:          logger.Sugar(),
-		nodeUtilization: make(map[string]*NodeUtilization),
-		config:          config,
-		lastScaleDown:   make(map[string]time.Time),
-		policyEngine:    NewPolicyEngine(logger.Sugar(), config),
+		client:              client,
+		metricsClient:       metricsClient,
+		logger:              logger.Sugar(),
+		nodeUtilization:     make(map[string]*NodeUtilization),
+		utilizationPolicies: make(map[string]utilizationPolicy),
+		nodePolicyKeys:      make(map[string]string),
+		config:              config,
+		lastScaleDown:       make(map[string]time.Time),
+		policyEngine:        NewPolicyEngine(logger.Sugar(), config),
+		drainer:             drain.NewNodeDrainer(client, logger.Named("drain")),
+		emptyNodes:          make(map[string]emptyNode),
 	}
 }
 
 // DefaultConfig returns default scale-down configuration
 func DefaultConfig() *Config {
 	return &Config{
-		CPUThreshold:              DefaultCPUThreshold,
-		MemoryThreshold:           DefaultMemoryThreshold,
-		ObservationWindow:         DefaultObservationWindow,
-		CooldownPeriod:            DefaultCooldownPeriod,
-		MaxNodesPerScaleDown:      1, // Safety: only remove one node at a time
-		EnablePodDisruptionBudget: true,
-		DrainTimeout:              5 * time.Minute,
-		EvictionGracePeriod:       30,
+		CPUThreshold:               DefaultCPUThreshold,
+		MemoryThreshold:            DefaultMemoryThreshold,
+		ObservationWindow:          DefaultObservationWindow,
+		CooldownPeriod:             DefaultCooldownPeriod,
+		MaxNodesPerScaleDown:       1, // Safety: only remove one node at a time
+		EnablePodDisruptionBudget:  true,
+		DrainTimeout:               5 * time.Minute,
+		EvictionGracePeriod:        30,
+		EmptyNodeUnneededTime:      DefaultEmptyNodeUnneededTime,
+		MaxEmptyNodesPerScaleDown:  DefaultMaxEmptyNodesPerScaleDown,
+		UtilizationPersistInterval: DefaultUtilizationPersistInterval,
 	}
 }
 
@@ -159,6 +221,17 @@ func (s *ScaleDownManager) GetMaxNodesPerScaleDown() int {
 	return s.config.MaxNodesPerScaleDown
 }
 
+// SetEventRecorder sets the recorder of events on pods blocking scale-down
+func (s *ScaleDownManager) SetEventRecorder(recorder record.EventRecorder) {
+	s.recorder = recorder
+}
+
+// SetOfferingPricer sets the source of offering prices for the
+// MostExpensiveFirst deletion order
+func (s *ScaleDownManager) SetOfferingPricer(pricer OfferingPricer) {
+	s.pricer = pricer
+}
+
 // IdentifyUnderutilizedNodes finds nodes with low utilization.
 // Only processes NodeGroups that have the managed label (autoscaler.vpsie.com/managed=true).
 func (s *ScaleDownManager) IdentifyUnderutilizedNodes(
@@ -187,54 +260,17 @@ func (s *ScaleDownManager) IdentifyUnderutilizedNodes(
 		return nil, fmt.Errorf("failed to get nodes: %w", err)
 	}
 
+	policy := s.setUtilizationPolicy(nodeGroup, nodes)
+
 	var candidates []*ScaleDownCandidate
 
 	for _, node := range nodes {
-		// Skip already cordoned nodes - they are likely being drained by a previous
-		// scale-down operation that was interrupted (e.g., autoscaler pod restart).
-		// This prevents duplicate scale-down operations from targeting multiple nodes.
-		if node.Spec.Unschedulable {
-			s.logger.Info("skipping already cordoned node (likely being drained)",
-				"node", node.Name,
-				"nodeGroup", nodeGroup.Name)
-			continue
-		}
-
-		// Skip protected nodes
-		if s.isNodeProtected(node) {
-			s.logger.Info("skipping protected node",
-				"node", node.Name,
-				"nodeGroup", nodeGroup.Name)
-			// Note: This is during candidate identification, not a block decision
-			// Blocking metrics are recorded in CanScaleDown where the final decision is made
-			continue
-		}
-
-		// Skip nodes not created due to metrics-based scaling
-		// Only scale down nodes that were created by the autoscaler due to resource metrics
-		creationReason := node.Annotations[autoscalerv1alpha1.CreationReasonAnnotationKey]
-		if creationReason != "" && creationReason != autoscalerv1alpha1.CreationReasonMetrics {
-			s.logger.Info("skipping node not created due to metrics",
+		utilizationCopy, reason := s.nodeCandidacy(nodeGroup, node, policy)
+		if reason != "" {
+			s.logger.Debugw("skipping node for scale-down",
 				"node", node.Name,
 				"nodeGroup", nodeGroup.Name,
-				"creationReason", creationReason)
-			continue
-		}
-
-		// Get utilization data and create a safe copy
-		// We hold the RLock while calling DeepCopy() to prevent race conditions
-		// with concurrent updates to the utilization data.
-		s.utilizationLock.RLock()
-		utilization, exists := s.nodeUtilization[node.Name]
-		if !exists || !utilization.IsUnderutilized {
-			s.utilizationLock.RUnlock()
-			continue
-		}
-		utilizationCopy := utilization.DeepCopy()
-		s.utilizationLock.RUnlock()
-
-		// Check if node has been underutilized for observation window
-		if !s.hasBeenUnderutilizedForWindow(utilizationCopy) {
+				"reason", reason)
 			continue
 		}
 
@@ -257,12 +293,89 @@ func (s *ScaleDownManager) IdentifyUnderutilizedNodes(
 		candidates = append(candidates, candidate)
 	}
 
-	// Sort candidates by priority (lower first)
+	// Sort candidates by priority (lower first), then by the NodeGroup's deletion order
 	sortCandidatesByPriority(candidates)
+	s.orderCandidates(ctx, nodeGroup, candidates, nodes)
 
 	return candidates, nil
 }
 
+// nodeCandidacy returns a copy of a node's utilization under the NodeGroup's
+// utilization policy and why the node is not a scale-down candidate, empty if
+// it is one. Utilization tracked under another policy is re-evaluated on the
+// copy, so the tracked state is left untouched.
+func (s *ScaleDownManager) nodeCandidacy(
+	nodeGroup *autoscalerv1alpha1.NodeGroup,
+	node *corev1.Node,
+	policy utilizationPolicy,
+) (*NodeUtilization, string) {
+	// Already cordoned nodes are likely being drained by a previous scale-down
+	// that was interrupted (e.g., autoscaler pod restart). Skipping them
+	// prevents duplicate scale-down operations from targeting multiple nodes.
+	if node.Spec.Unschedulable {
+		return nil, "node is cordoned"
+	}
+	// Blocking metrics are recorded in CanScaleDown where the final decision is made
+	if s.isNodeProtected(node) {
+		return nil, "node is protected"
+	}
+	// Only scale down nodes that were created by the autoscaler due to resource metrics
+	creationReason := node.Annotations[autoscalerv1alpha1.CreationReasonAnnotationKey]
+	if creationReason != "" && creationReason != autoscalerv1alpha1.CreationReasonMetrics {
+		return nil, fmt.Sprintf("node was created for %s, not metrics", creationReason)
+	}
+
+	// Copy under the read lock to prevent races with concurrent updates
+	s.utilizationLock.RLock()
+	utilization, exists := s.nodeUtilization[node.Name]
+	if !exists {
+		s.utilizationLock.RUnlock()
+		return nil, "no utilization data for the node"
+	}
+	utilizationCopy := utilization.DeepCopy()
+	tracked, ok := s.utilizationPolicies[utilizationPolicyKey(nodeGroup)]
+	s.utilizationLock.RUnlock()
+
+	// NodeGroups not seen yet were measured by mean usage
+	if !ok {
+		tracked = utilizationPolicy{
+			mode:        autoscalerv1alpha1.UtilizationModeUsage,
+			aggregation: autoscalerv1alpha1.UtilizationAggregationMean,
+		}
+	}
+	if tracked != policy {
+		s.evaluateUtilization(utilizationCopy, policy, time.Now())
+	}
+
+	if !utilizationCopy.IsUnderutilized {
+		return utilizationCopy, fmt.Sprintf("CPU %.1f%% / memory %.1f%% not below the %.0f%% / %.0f%% thresholds",
+			utilizationCopy.CPUUtilization, utilizationCopy.MemoryUtilization, s.config.CPUThreshold, s.config.MemoryThreshold)
+	}
+	if !s.hasBeenUnderutilizedForWindow(utilizationCopy, policy) {
+		if !utilizationCopy.UnneededSince.IsZero() {
+			return utilizationCopy, fmt.Sprintf("underutilized for %s of the %s observation window",
+				time.Since(utilizationCopy.UnneededSince).Round(time.Second), s.config.ObservationWindow)
+		}
+		return utilizationCopy, fmt.Sprintf("not underutilized in enough samples of the %s observation window",
+			s.config.ObservationWindow)
+	}
+	return utilizationCopy, ""
+}
+
+// orderCandidates orders candidates with the NodeGroup's deletion order
+func (s *ScaleDownManager) orderCandidates(
+	ctx context.Context,
+	nodeGroup *autoscalerv1alpha1.NodeGroup,
+	candidates []*ScaleDownCandidate,
+	nodes []*corev1.Node,
+) {
+	input := &DeletionOrderInput{Nodes: nodes}
+	if s.pricer != nil {
+		input.Price = OfferingPrices(ctx, s.pricer)
+	}
+	OrderForDeletion(candidates, nodeGroup.Spec.ScaleDownPolicy.DeletionOrder, input)
+}
+
 // CanScaleDown determines if a node can be safely scaled down
 func (s *ScaleDownManager) CanScaleDown(
 	ctx context.Context,
@@ -282,7 +395,7 @@ func (s *ScaleDownManager) CanScaleDown(
 
 	// Check minimum nodes constraint
 	currentNodes := len(nodeGroup.Status.Nodes)
-	if currentNodes <= int(nodeGroup.Spec.MinNodes) {
+	if currentNodes <= int(autoscalerv1alpha1.EffectiveMinNodes(nodeGroup)) {
 		// Record scale-down blocked due to minimum nodes constraint
 		metrics.ScaleDownBlockedTotal.WithLabelValues(
 			nodeGroup.Name,
@@ -314,6 +427,8 @@ func (s *ScaleDownManager) CanScaleDown(
 			blockReason = "capacity"
 		} else if strings.Contains(reason, "anti-affinity") {
 			blockReason = "affinity"
+		} else if strings.Contains(reason, "safe-to-evict") {
+			blockReason = "pod_annotation"
 		} else if strings.Contains(reason, "protected") {
 			blockReason = "protected_node"
 		} else if strings.Contains(reason, "PDB") || strings.Contains(reason, "disruptions") {
@@ -415,7 +530,7 @@ func (s *ScaleDownManager) ScaleDown(
 		}
 
 		// Drain the node
-		if err := s.DrainNode(ctx, candidate.Node); err != nil {
+		if err := s.drainNode(ctx, candidate.Node, nodeGroup); err != nil {
 			captureError(err, "drain_node", candidate.Node.Name)
 			errors = append(errors, fmt.Errorf("failed to drain node %s: %w", candidate.Node.Name, err))
 
@@ -527,7 +642,7 @@ func (s *ScaleDownManager) isNodeProtected(node *corev1.Node) bool {
 	return false
 }
 
-func (s *ScaleDownManager) hasBeenUnderutilizedForWindow(utilization *NodeUtilization) bool {
+func (s *ScaleDownManager) hasBeenUnderutilizedForWindow(utilization *NodeUtilization, policy utilizationPolicy) bool {
 	if len(utilization.Samples) == 0 {
 		return false
 	}
@@ -544,6 +659,12 @@ func (s *ScaleDownManager) hasBeenUnderutilizedForWindow(utilization *NodeUtiliz
 		return false
 	}
 
+	// The rolling utilization must have stayed below the thresholds for the
+	// whole observation window
+	if !utilization.UnneededSince.IsZero() && now.Sub(utilization.UnneededSince) < s.config.ObservationWindow {
+		return false
+	}
+
 	// Check if all samples within observation window are underutilized
 	windowStart := now.Add(-s.config.ObservationWindow)
 
@@ -556,8 +677,8 @@ func (s *ScaleDownManager) hasBeenUnderutilizedForWindow(utilization *NodeUtiliz
 		}
 
 		totalSamples++
-		if sample.CPUUtilization < s.config.CPUThreshold &&
-			sample.MemoryUtilization < s.config.MemoryThreshold {
+		cpu, memory := sample.resolve(policy.mode)
+		if cpu < s.config.CPUThreshold && memory < s.config.MemoryThreshold {
 			underutilizedCount++
 		}
 	}
@@ -153,6 +153,19 @@ func TestHasBeenUnderutilizedForWindow(t *testing.T) {
 			},
 			expected: false,
 		},
+		{
+			name: "unneeded for less than the window",
+			utilization: &NodeUtilization{
+				NodeName:      "node-4",
+				LastUpdated:   now,
+				UnneededSince: now.Add(-5 * time.Minute),
+				Samples: []UtilizationSample{
+					{Timestamp: now.Add(-5 * time.Minute), CPUUtilization: 30, MemoryUtilization: 30},
+					{Timestamp: now.Add(-4 * time.Minute), CPUUtilization: 28, MemoryUtilization: 28},
+				},
+			},
+			expected: false,
+		},
 		{
 			name: "no samples",
 			utilization: &NodeUtilization{
@@ -165,7 +178,7 @@ func TestHasBeenUnderutilizedForWindow(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := manager.hasBeenUnderutilizedForWindow(tt.utilization)
+			result := manager.hasBeenUnderutilizedForWindow(tt.utilization, utilizationPolicy{})
 			if result != tt.expected {
 				t.Errorf("expected %v, got %v", tt.expected, result)
 			}
@@ -173,6 +186,213 @@ func TestHasBeenUnderutilizedForWindow(t *testing.T) {
 	}
 }
 
+func TestUtilizationPolicy(t *testing.T) {
+	now := time.Now()
+	samples := []UtilizationSample{
+		{Timestamp: now.Add(-9 * time.Minute), CPUUtilization: 10, MemoryUtilization: 10, CPURequests: 70, MemoryRequests: 20},
+		{Timestamp: now.Add(-6 * time.Minute), CPUUtilization: 20, MemoryUtilization: 10, CPURequests: 70, MemoryRequests: 20},
+		{Timestamp: now.Add(-3 * time.Minute), CPUUtilization: 90, MemoryUtilization: 10, CPURequests: 70, MemoryRequests: 20},
+	}
+
+	tests := []struct {
+		name          string
+		policy        utilizationPolicy
+		cpu           float64
+		memory        float64
+		underutilized bool
+	}{
+		{name: "default", cpu: 40, memory: 10, underutilized: true},
+		{name: "usage p50", policy: utilizationPolicy{mode: autoscalerv1alpha1.UtilizationModeUsage, aggregation: autoscalerv1alpha1.UtilizationAggregationP50}, cpu: 20, memory: 10, underutilized: true},
+		{name: "usage p95", policy: utilizationPolicy{mode: autoscalerv1alpha1.UtilizationModeUsage, aggregation: autoscalerv1alpha1.UtilizationAggregationP95}, cpu: 90, memory: 10},
+		{name: "requests", policy: utilizationPolicy{mode: autoscalerv1alpha1.UtilizationModeRequests}, cpu: 70, memory: 20},
+		{name: "max", policy: utilizationPolicy{mode: autoscalerv1alpha1.UtilizationModeMax, aggregation: autoscalerv1alpha1.UtilizationAggregationP50}, cpu: 70, memory: 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewScaleDownManager(nil, nil, zaptest.NewLogger(t), DefaultConfig())
+			util := &NodeUtilization{NodeName: "node-1", Samples: samples, LastUpdated: now}
+
+			manager.evaluateUtilization(util, tt.policy, now)
+			if util.CPUUtilization != tt.cpu || util.MemoryUtilization != tt.memory {
+				t.Errorf("expected CPU %.0f%% and memory %.0f%%, got %.1f%% and %.1f%%",
+					tt.cpu, tt.memory, util.CPUUtilization, util.MemoryUtilization)
+			}
+			if util.IsUnderutilized != tt.underutilized {
+				t.Errorf("expected underutilized %v, got %v", tt.underutilized, util.IsUnderutilized)
+			}
+		})
+	}
+}
+
+func TestSetUtilizationPolicy(t *testing.T) {
+	now := time.Now()
+	manager := NewScaleDownManager(nil, nil, zaptest.NewLogger(t), DefaultConfig())
+	node := createTestNode("node-1", "test-group", 4000, 8000000000)
+	manager.nodeUtilization["node-1"] = &NodeUtilization{
+		NodeName:          "node-1",
+		CPUUtilization:    10,
+		MemoryUtilization: 10,
+		IsUnderutilized:   true,
+		UnneededSince:     now.Add(-20 * time.Minute),
+		LastUpdated:       now,
+		Samples: []UtilizationSample{
+			{Timestamp: now.Add(-time.Minute), CPUUtilization: 10, MemoryUtilization: 10, CPURequests: 80, MemoryRequests: 10},
+		},
+	}
+
+	// The default policy keeps the tracked utilization
+	nodeGroup := newEmptyNodeTestNodeGroup()
+	manager.setUtilizationPolicy(nodeGroup, []*corev1.Node{node})
+	util, _ := manager.GetNodeUtilization("node-1")
+	if !util.IsUnderutilized || !util.UnneededSince.Equal(now.Add(-20*time.Minute)) {
+		t.Errorf("expected the default policy to keep the utilization, got %+v", util)
+	}
+
+	// Switching to requests re-evaluates the node right away
+	nodeGroup.Spec.ScaleDownPolicy.UtilizationMode = autoscalerv1alpha1.UtilizationModeRequests
+	policy := manager.setUtilizationPolicy(nodeGroup, []*corev1.Node{node})
+	if policy.mode != autoscalerv1alpha1.UtilizationModeRequests {
+		t.Errorf("expected mode Requests, got %s", policy.mode)
+	}
+	util, _ = manager.GetNodeUtilization("node-1")
+	if util.IsUnderutilized || !util.UnneededSince.IsZero() || util.CPUUtilization != 80 {
+		t.Errorf("expected node-1 to be needed under requests, got %+v", util)
+	}
+	if manager.hasBeenUnderutilizedForWindow(util, policy) {
+		t.Error("expected node-1 not to be underutilized for the window under requests")
+	}
+
+	// New samples are evaluated with the NodeGroup's policy
+	pod := newEmptyNodeTestPod("app", "node-1", "ReplicaSet")
+	pod.Spec.Containers = []corev1.Container{{
+		Name: "app",
+		Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    *resource.NewMilliQuantity(3000, resource.DecimalSI),
+			corev1.ResourceMemory: *resource.NewQuantity(800000000, resource.BinarySI),
+		}},
+	}}
+	metrics := newPersistenceTestMetrics("node-1", 400)
+	if err := manager.updateNodeUtilizationMetrics(context.Background(), node, metrics, []*corev1.Pod{pod}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	util, _ = manager.GetNodeUtilization("node-1")
+	latest := util.Samples[len(util.Samples)-1]
+	if latest.CPUUtilization != 10 || latest.CPURequests != 75 {
+		t.Errorf("expected 10%% CPU usage and 75%% requested, got %+v", latest)
+	}
+	if util.CPUUtilization != 77.5 || util.IsUnderutilized {
+		t.Errorf("expected 77.5%% CPU requested on average, got %+v", util)
+	}
+}
+
+func TestSetUtilizationPolicy_SameNameInNamespaces(t *testing.T) {
+	now := time.Now()
+	manager := NewScaleDownManager(nil, nil, zaptest.NewLogger(t), DefaultConfig())
+	nodeA := createTestNode("node-a", "test-group", 4000, 8000000000)
+	nodeB := createTestNode("node-b", "test-group", 4000, 8000000000)
+	for _, name := range []string{"node-a", "node-b"} {
+		manager.nodeUtilization[name] = &NodeUtilization{
+			NodeName:          name,
+			CPUUtilization:    10,
+			MemoryUtilization: 10,
+			IsUnderutilized:   true,
+			UnneededSince:     now.Add(-20 * time.Minute),
+			LastUpdated:       now,
+			Samples: []UtilizationSample{
+				{Timestamp: now.Add(-time.Minute), CPUUtilization: 10, MemoryUtilization: 10, CPURequests: 80, MemoryRequests: 10},
+			},
+		}
+	}
+
+	// Same-named NodeGroups in two namespaces keep their own policies
+	requests := newEmptyNodeTestNodeGroup()
+	requests.Namespace = "team-a"
+	requests.Spec.ScaleDownPolicy.UtilizationMode = autoscalerv1alpha1.UtilizationModeRequests
+	usage := newEmptyNodeTestNodeGroup()
+	usage.Namespace = "team-b"
+
+	manager.setUtilizationPolicy(requests, []*corev1.Node{nodeA})
+	manager.setUtilizationPolicy(usage, []*corev1.Node{nodeB})
+
+	if policy := manager.utilizationPolicies["team-a/test-group"]; policy.mode != autoscalerv1alpha1.UtilizationModeRequests {
+		t.Errorf("expected team-a to measure requests, got %s", policy.mode)
+	}
+	if policy := manager.utilizationPolicies["team-b/test-group"]; policy.mode != autoscalerv1alpha1.UtilizationModeUsage {
+		t.Errorf("expected team-b to measure usage, got %s", policy.mode)
+	}
+
+	// Recording one NodeGroup's policy does not re-evaluate the other's node
+	manager.setUtilizationPolicy(requests, []*corev1.Node{nodeA})
+	if util, _ := manager.GetNodeUtilization("node-a"); util.IsUnderutilized || util.CPUUtilization != 80 {
+		t.Errorf("expected node-a to stay measured by requests, got %+v", util)
+	}
+	manager.setUtilizationPolicy(usage, []*corev1.Node{nodeB})
+	if util, _ := manager.GetNodeUtilization("node-b"); !util.IsUnderutilized ||
+		!util.UnneededSince.Equal(now.Add(-20*time.Minute)) {
+		t.Errorf("expected node-b to keep its observation window, got %+v", util)
+	}
+
+	// New samples of each node are evaluated with its own NodeGroup's policy:
+	// the empty sample halves node-a's requested CPU, node-b's usage is unchanged
+	for _, node := range []*corev1.Node{nodeA, nodeB} {
+		if err := manager.updateNodeUtilizationMetrics(context.Background(), node,
+			newPersistenceTestMetrics(node.Name, 400), nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if util, _ := manager.GetNodeUtilization("node-a"); util.CPUUtilization != 40 {
+		t.Errorf("expected 40%% CPU requested on average on node-a, got %+v", util)
+	}
+	if util, _ := manager.GetNodeUtilization("node-b"); util.CPUUtilization != 10 {
+		t.Errorf("expected 10%% CPU usage on node-b, got %+v", util)
+	}
+}
+
+func TestUpdateNodeUtilizationMetrics_Allocatable(t *testing.T) {
+	manager := NewScaleDownManager(nil, nil, zaptest.NewLogger(t), DefaultConfig())
+
+	// Usage and requests are both shares of allocatable, not of capacity
+	node := createTestNode("node-1", "test-group", 2000, 4000000000)
+	node.Status.Capacity = corev1.ResourceList{
+		corev1.ResourceCPU:    *resource.NewMilliQuantity(4000, resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(8000000000, resource.BinarySI),
+	}
+	if err := manager.updateNodeUtilizationMetrics(context.Background(), node, newPersistenceTestMetrics("node-1", 400), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	util, _ := manager.GetNodeUtilization("node-1")
+	latest := util.Samples[len(util.Samples)-1]
+	if latest.CPUUtilization != 20 || latest.MemoryUtilization != 20 {
+		t.Errorf("expected 20%% of allocatable CPU and memory used, got %+v", latest)
+	}
+
+	node.Status.Allocatable = nil
+	if err := manager.updateNodeUtilizationMetrics(context.Background(), node, newPersistenceTestMetrics("node-1", 400), nil); err == nil {
+		t.Error("expected an error for a node without allocatable resources")
+	}
+}
+
+func TestNodeUtilizationAggregate(t *testing.T) {
+	now := time.Now()
+	util := &NodeUtilization{Samples: []UtilizationSample{
+		{Timestamp: now.Add(-2 * time.Hour), CPUUtilization: 90, MemoryUtilization: 90},
+		{Timestamp: now.Add(-2 * time.Minute), CPUUtilization: 10, MemoryUtilization: 20, CPURequests: 50, MemoryRequests: 10},
+		{Timestamp: now.Add(-time.Minute), CPUUtilization: 30, MemoryUtilization: 40, CPURequests: 10, MemoryRequests: 10},
+	}}
+	since := now.Add(-time.Hour)
+
+	cpu, memory := util.Aggregate(autoscalerv1alpha1.UtilizationModeUsage, autoscalerv1alpha1.UtilizationAggregationMean, since)
+	if cpu != 20 || memory != 30 {
+		t.Errorf("expected mean usage 20/30 within the window, got %v/%v", cpu, memory)
+	}
+
+	cpu, memory = util.Aggregate(autoscalerv1alpha1.UtilizationModeMax, autoscalerv1alpha1.UtilizationAggregationMean, since)
+	if cpu != 40 || memory != 30 {
+		t.Errorf("expected mean of the higher of usage and requests 40/30, got %v/%v", cpu, memory)
+	}
+}
+
 func TestIsOutsideCooldownPeriod(t *testing.T) {
 	config := &Config{
 		CooldownPeriod: 10 * time.Minute,
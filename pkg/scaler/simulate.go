@@ -0,0 +1,254 @@
+package scaler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	autoscalerv1alpha1 "github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+)
+
+const (
+	// SimulationFormatText renders a simulation report for humans
+	SimulationFormatText = "text"
+
+	// SimulationFormatJSON renders a simulation report as JSON
+	SimulationFormatJSON = "json"
+)
+
+// SimulationReport is the outcome of a scale-down dry-run for a NodeGroup
+type SimulationReport struct {
+	NodeGroup   string            `json:"nodeGroup"`
+	Namespace   string            `json:"namespace"`
+	GeneratedAt time.Time         `json:"generatedAt"`
+	Checks      []SimulationCheck `json:"checks"`
+	Nodes       []NodeSimulation  `json:"nodes"`
+}
+
+// NodeSimulation is the outcome of a scale-down dry-run for a node
+type NodeSimulation struct {
+	Node string `json:"node"`
+	// Removable is true if the NodeGroup and all node checks passed
+	Removable         bool              `json:"removable"`
+	CPUUtilization    *float64          `json:"cpuUtilization,omitempty"`
+	MemoryUtilization *float64          `json:"memoryUtilization,omitempty"`
+	UnneededSince     *time.Time        `json:"unneededSince,omitempty"`
+	Pods              int               `json:"pods"`
+	Checks            []SimulationCheck `json:"checks"`
+}
+
+// SimulationCheck is the outcome of a single scale-down check
+type SimulationCheck struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// SimulateScaleDown runs the scale-down checks for the NodeGroup's nodes, or
+// only nodeName if set, without cordoning, evicting, recording events or
+// metrics, or changing the tracked utilization. Every check runs even if an
+// earlier one failed, so the report shows all reasons a node is kept.
+func (s *ScaleDownManager) SimulateScaleDown(
+	ctx context.Context,
+	nodeGroup *autoscalerv1alpha1.NodeGroup,
+	nodeName string,
+) (*SimulationReport, error) {
+	report := &SimulationReport{
+		NodeGroup:   nodeGroup.Name,
+		Namespace:   nodeGroup.Namespace,
+		GeneratedAt: time.Now(),
+	}
+
+	report.addCheck("managed", autoscalerv1alpha1.IsManagedNodeGroup(nodeGroup),
+		"NodeGroup is managed by the autoscaler",
+		fmt.Sprintf("NodeGroup lacks the %s=%s label", autoscalerv1alpha1.ManagedLabelKey, autoscalerv1alpha1.ManagedLabelValue))
+	report.addCheck("scale-down-enabled", nodeGroup.Spec.ScaleDownPolicy.Enabled,
+		"scale-down is enabled", "spec.scaleDownPolicy.enabled is false")
+
+	s.scaleDownLock.RLock()
+	lastScaleDown, scaledDown := s.lastScaleDown[nodeGroup.Name]
+	s.scaleDownLock.RUnlock()
+	if !scaledDown || time.Since(lastScaleDown) >= s.config.CooldownPeriod {
+		report.addCheck("cooldown", true, "outside the scale-down cooldown", "")
+	} else {
+		report.addCheck("cooldown", false, "", fmt.Sprintf("last scale-down %s ago, cooldown is %s",
+			time.Since(lastScaleDown).Round(time.Second), s.config.CooldownPeriod))
+	}
+
+	currentNodes := len(nodeGroup.Status.Nodes)
+	minNodes := autoscalerv1alpha1.EffectiveMinNodes(nodeGroup)
+	report.addCheck("min-nodes", currentNodes > int(minNodes),
+		fmt.Sprintf("%d nodes, above the minimum of %d", currentNodes, minNodes),
+		fmt.Sprintf("%d nodes, at the minimum of %d", currentNodes, minNodes))
+
+	nodes, err := s.getNodeGroupNodes(ctx, nodeGroup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes: %w", err)
+	}
+
+	policy := nodeGroupUtilizationPolicy(nodeGroup)
+
+	groupPassed := report.passed()
+	found := nodeName == ""
+	for _, node := range nodes {
+		if nodeName != "" && node.Name != nodeName {
+			continue
+		}
+		found = true
+
+		simulation, err := s.simulateNodeScaleDown(ctx, nodeGroup, node, policy)
+		if err != nil {
+			return nil, err
+		}
+		simulation.Removable = simulation.Removable && groupPassed
+		report.Nodes = append(report.Nodes, *simulation)
+	}
+	if !found {
+		return nil, fmt.Errorf("node %s not found in NodeGroup %s/%s", nodeName, nodeGroup.Namespace, nodeGroup.Name)
+	}
+
+	return report, nil
+}
+
+// simulateNodeScaleDown runs the checks of IdentifyUnderutilizedNodes,
+// IsSafeToRemove, PDB validation and the policy engine for a single node
+func (s *ScaleDownManager) simulateNodeScaleDown(
+	ctx context.Context,
+	nodeGroup *autoscalerv1alpha1.NodeGroup,
+	node *corev1.Node,
+	policy utilizationPolicy,
+) (*NodeSimulation, error) {
+	simulation := &NodeSimulation{Node: node.Name}
+
+	util, reason := s.nodeCandidacy(nodeGroup, node, policy)
+	if util == nil {
+		util, _ = s.GetNodeUtilization(node.Name)
+	}
+	if util != nil {
+		simulation.CPUUtilization = &util.CPUUtilization
+		simulation.MemoryUtilization = &util.MemoryUtilization
+		if !util.UnneededSince.IsZero() {
+			simulation.UnneededSince = &util.UnneededSince
+		}
+	}
+	simulation.addCheck("candidate", reason == "", "underutilized for the observation window", reason)
+
+	pods, err := s.getNodePods(ctx, node.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods of %s: %w", node.Name, err)
+	}
+	simulation.Pods = len(pods)
+
+	for i, result := range s.RunSafetyChecks(ctx, node, pods, true) {
+		simulation.addCheck(fmt.Sprintf("safety-%d-%s", i+1, result.Name), result.Passed, result.Message, result.Message)
+	}
+
+	if !s.config.EnablePodDisruptionBudget {
+		simulation.addCheck("pdb", true, "PodDisruptionBudget validation disabled", "")
+	} else if err := s.ValidatePodDisruptionBudgets(ctx, pods); err != nil {
+		simulation.addCheck("pdb", false, "", err.Error())
+	} else {
+		simulation.addCheck("pdb", true, "PodDisruptionBudgets allow evicting the pods", "")
+	}
+
+	denied := s.policyEngine.ScaleDownDeniedReason(nodeGroup, node)
+	simulation.addCheck("policy", denied == "", "policies allow scale-down", denied)
+
+	simulation.Removable = true
+	for _, check := range simulation.Checks {
+		simulation.Removable = simulation.Removable && check.Passed
+	}
+
+	return simulation, nil
+}
+
+// addCheck records a NodeGroup check with the message for its outcome
+func (r *SimulationReport) addCheck(name string, passed bool, passMessage, failMessage string) {
+	r.Checks = append(r.Checks, newSimulationCheck(name, passed, passMessage, failMessage))
+}
+
+// passed returns true if all NodeGroup checks passed
+func (r *SimulationReport) passed() bool {
+	for _, check := range r.Checks {
+		if !check.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// addCheck records a node check with the message for its outcome
+func (n *NodeSimulation) addCheck(name string, passed bool, passMessage, failMessage string) {
+	n.Checks = append(n.Checks, newSimulationCheck(name, passed, passMessage, failMessage))
+}
+
+func newSimulationCheck(name string, passed bool, passMessage, failMessage string) SimulationCheck {
+	check := SimulationCheck{Name: name, Passed: passed, Message: failMessage}
+	if passed {
+		check.Message = passMessage
+	}
+	return check
+}
+
+// WriteSimulationReport renders the report to w in the given format
+func WriteSimulationReport(w io.Writer, report *SimulationReport, format string) error {
+	switch format {
+	case SimulationFormatText, "":
+		return writeSimulationText(w, report)
+	case SimulationFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	default:
+		return fmt.Errorf("unsupported output format %q, must be one of: text, json", format)
+	}
+}
+
+func writeSimulationText(w io.Writer, report *SimulationReport) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Scale-down simulation for NodeGroup %s/%s at %s\n",
+		report.Namespace, report.NodeGroup, report.GeneratedAt.Format("2006-01-02 15:04:05 MST"))
+	writeSimulationChecks(&b, report.Checks)
+
+	if len(report.Nodes) == 0 {
+		b.WriteString("\nNo nodes\n")
+	}
+	for _, node := range report.Nodes {
+		verdict := "kept"
+		if node.Removable {
+			verdict = "removable"
+		}
+		fmt.Fprintf(&b, "\nNode %s: %s (%d pods", node.Node, verdict, node.Pods)
+		if node.CPUUtilization != nil && node.MemoryUtilization != nil {
+			fmt.Fprintf(&b, ", CPU %.1f%%, memory %.1f%%", *node.CPUUtilization, *node.MemoryUtilization)
+		}
+		if node.UnneededSince != nil {
+			fmt.Fprintf(&b, ", unneeded since %s", node.UnneededSince.Format(time.RFC3339))
+		}
+		b.WriteString(")\n")
+		writeSimulationChecks(&b, node.Checks)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeSimulationChecks(b *strings.Builder, checks []SimulationCheck) {
+	for _, check := range checks {
+		status := "PASS"
+		if !check.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(b, "  [%s] %s", status, check.Name)
+		if check.Message != "" {
+			fmt.Fprintf(b, ": %s", check.Message)
+		}
+		b.WriteString("\n")
+	}
+}
@@ -0,0 +1,225 @@
+package scaler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+	"k8s.io/client-go/tools/record"
+
+	autoscalerv1alpha1 "github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+)
+
+func newSimulationTestNodeGroup() *autoscalerv1alpha1.NodeGroup {
+	nodeGroup := newEmptyNodeTestNodeGroup()
+	nodeGroup.Spec.MinNodes = 1
+	nodeGroup.Status.Nodes = []autoscalerv1alpha1.NodeInfo{
+		{NodeName: "node-1"}, {NodeName: "node-2"}, {NodeName: "node-3"},
+	}
+	return nodeGroup
+}
+
+func findSimulationCheck(checks []SimulationCheck, name string) (SimulationCheck, bool) {
+	for _, check := range checks {
+		if check.Name == name {
+			return check, true
+		}
+	}
+	return SimulationCheck{}, false
+}
+
+func TestSimulateScaleDown(t *testing.T) {
+	blocking := newEmptyNodeTestPod("db", "node-2", "StatefulSet")
+	blocking.Annotations = map[string]string{"autoscaler.vpsie.com/safe-to-evict": "false"}
+
+	client := newEmptyNodeTestClient(
+		createTestNode("node-1", "test-group", 4000, 8000000000),
+		createTestNode("node-2", "test-group", 4000, 8000000000),
+		createTestNode("node-3", "test-group", 4000, 8000000000),
+		blocking,
+	)
+	recorder := record.NewFakeRecorder(10)
+	manager := NewScaleDownManager(client, nil, zaptest.NewLogger(t), DefaultConfig())
+	manager.SetEventRecorder(recorder)
+
+	now := time.Now()
+	for _, name := range []string{"node-1", "node-2"} {
+		manager.nodeUtilization[name] = &NodeUtilization{
+			NodeName:          name,
+			CPUUtilization:    10,
+			MemoryUtilization: 15,
+			IsUnderutilized:   true,
+			UnneededSince:     now.Add(-20 * time.Minute),
+			LastUpdated:       now,
+			Samples:           []UtilizationSample{{Timestamp: now, CPUUtilization: 10, MemoryUtilization: 15}},
+		}
+	}
+	client.ClearActions()
+
+	report, err := manager.SimulateScaleDown(context.Background(), newSimulationTestNodeGroup(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, check := range report.Checks {
+		if !check.Passed {
+			t.Errorf("expected NodeGroup check %s to pass: %s", check.Name, check.Message)
+		}
+	}
+	if len(report.Nodes) != 3 {
+		t.Fatalf("expected 3 nodes, got %d", len(report.Nodes))
+	}
+
+	expected := map[string]struct {
+		removable bool
+		failed    string
+	}{
+		"node-1": {removable: true},
+		"node-2": {failed: "safety-1-safe-to-evict"},
+		"node-3": {failed: "candidate"},
+	}
+	for _, node := range report.Nodes {
+		want := expected[node.Node]
+		if node.Removable != want.removable {
+			t.Errorf("expected %s removable %v, got %v", node.Node, want.removable, node.Removable)
+		}
+		if len(node.Checks) != 10 {
+			t.Errorf("expected all 10 checks for %s, got %d", node.Node, len(node.Checks))
+		}
+		if want.failed == "" {
+			continue
+		}
+		check, ok := findSimulationCheck(node.Checks, want.failed)
+		if !ok || check.Passed {
+			t.Errorf("expected check %s to fail for %s", want.failed, node.Node)
+		}
+	}
+
+	// The dry-run has no side effects
+	for _, action := range client.Actions() {
+		if verb := action.GetVerb(); verb != "get" && verb != "list" {
+			t.Errorf("unexpected %s of %s", verb, action.GetResource().Resource)
+		}
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected no events, got %d", len(recorder.Events))
+	}
+}
+
+func TestSimulateScaleDown_UtilizationPolicy(t *testing.T) {
+	client := newEmptyNodeTestClient(createTestNode("node-1", "test-group", 4000, 8000000000))
+	manager := NewScaleDownManager(client, nil, zaptest.NewLogger(t), DefaultConfig())
+
+	// Low usage but high requests, tracked under the default usage policy
+	now := time.Now()
+	manager.nodeUtilization["node-1"] = &NodeUtilization{
+		NodeName:          "node-1",
+		CPUUtilization:    10,
+		MemoryUtilization: 15,
+		IsUnderutilized:   true,
+		UnneededSince:     now.Add(-20 * time.Minute),
+		LastUpdated:       now,
+		Samples: []UtilizationSample{{
+			Timestamp: now, CPUUtilization: 10, MemoryUtilization: 15, CPURequests: 90, MemoryRequests: 90,
+		}},
+	}
+
+	nodeGroup := newSimulationTestNodeGroup()
+	nodeGroup.Spec.ScaleDownPolicy.UtilizationMode = autoscalerv1alpha1.UtilizationModeRequests
+
+	report, err := manager.SimulateScaleDown(context.Background(), nodeGroup, "node-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	check, ok := findSimulationCheck(report.Nodes[0].Checks, "candidate")
+	if !ok || check.Passed {
+		t.Errorf("expected node-1 not to be a candidate under the requests policy")
+	}
+
+	// The tracked utilization and policy are left untouched
+	if _, ok := manager.utilizationPolicies["default/test-group"]; ok {
+		t.Error("expected the simulation not to record a utilization policy")
+	}
+	if util := manager.nodeUtilization["node-1"]; !util.IsUnderutilized || util.CPUUtilization != 10 {
+		t.Errorf("expected the tracked utilization to be unchanged, got %+v", util)
+	}
+}
+
+func TestSimulateScaleDown_NodeGroupChecks(t *testing.T) {
+	client := newEmptyNodeTestClient(createTestNode("node-1", "test-group", 4000, 8000000000))
+	manager := NewScaleDownManager(client, nil, zaptest.NewLogger(t), DefaultConfig())
+	manager.lastScaleDown["test-group"] = time.Now()
+
+	nodeGroup := newSimulationTestNodeGroup()
+	nodeGroup.Spec.MinNodes = 3
+
+	report, err := manager.SimulateScaleDown(context.Background(), nodeGroup, "node-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"cooldown", "min-nodes"} {
+		check, ok := findSimulationCheck(report.Checks, name)
+		if !ok || check.Passed {
+			t.Errorf("expected NodeGroup check %s to fail", name)
+		}
+	}
+	if len(report.Nodes) != 1 || report.Nodes[0].Removable {
+		t.Errorf("expected node-1 not to be removable")
+	}
+
+	if _, err := manager.SimulateScaleDown(context.Background(), nodeGroup, "node-9"); err == nil {
+		t.Error("expected an error for a node outside the NodeGroup")
+	}
+}
+
+func TestWriteSimulationReport(t *testing.T) {
+	cpu, memory := 12.5, 20.0
+	report := &SimulationReport{
+		NodeGroup:   "test-group",
+		Namespace:   "default",
+		GeneratedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Checks:      []SimulationCheck{{Name: "cooldown", Passed: true, Message: "outside the scale-down cooldown"}},
+		Nodes: []NodeSimulation{{
+			Node:              "node-1",
+			CPUUtilization:    &cpu,
+			MemoryUtilization: &memory,
+			Pods:              2,
+			Checks:            []SimulationCheck{{Name: "policy", Passed: false, Message: "scale-down disabled by policy"}},
+		}},
+	}
+
+	var text bytes.Buffer
+	if err := WriteSimulationReport(&text, report, SimulationFormatText); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"NodeGroup default/test-group",
+		"[PASS] cooldown: outside the scale-down cooldown",
+		"Node node-1: kept (2 pods, CPU 12.5%, memory 20.0%)",
+		"[FAIL] policy: scale-down disabled by policy",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("expected text report to contain %q, got:\n%s", want, text.String())
+		}
+	}
+
+	var encoded bytes.Buffer
+	if err := WriteSimulationReport(&encoded, report, SimulationFormatJSON); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded SimulationReport
+	if err := json.Unmarshal(encoded.Bytes(), &decoded); err != nil {
+		t.Fatalf("failed to decode JSON report: %v", err)
+	}
+	if decoded.NodeGroup != "test-group" || len(decoded.Nodes) != 1 || *decoded.Nodes[0].CPUUtilization != cpu {
+		t.Errorf("unexpected decoded report %+v", decoded)
+	}
+
+	if err := WriteSimulationReport(&bytes.Buffer{}, report, "yaml"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
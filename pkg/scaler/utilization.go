@@ -9,6 +9,8 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	autoscalerv1alpha1 "github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
 )
 
 const (
@@ -51,6 +53,7 @@ func (s *ScaleDownManager) UpdateNodeUtilization(ctx context.Context) error {
 	for nodeName := range s.nodeUtilization {
 		if !currentNodes[nodeName] {
 			delete(s.nodeUtilization, nodeName)
+			delete(s.nodePolicyKeys, nodeName)
 			s.logger.Debug("removed deleted node from utilization tracking", "node", nodeName)
 		}
 	}
@@ -74,12 +77,27 @@ func (s *ScaleDownManager) UpdateNodeUtilization(ctx context.Context) error {
 			continue
 		}
 
-		if err := s.updateNodeUtilizationMetrics(ctx, node, metrics); err != nil {
+		// Pods are listed per node with a spec.nodeName field selector
+		pods, err := s.getNodePods(ctx, node.Name)
+		if err != nil {
+			s.logger.Warn("failed to list pods for node utilization",
+				"node", node.Name,
+				"error", err)
+			continue
+		}
+
+		if err := s.updateNodeUtilizationMetrics(ctx, node, metrics, pods); err != nil {
 			s.logger.Error("failed to update node utilization",
 				"node", node.Name,
 				"error", err)
 			continue
 		}
+
+		if err := s.persistNodeUtilization(ctx, node); err != nil {
+			s.logger.Warn("failed to persist node utilization",
+				"node", node.Name,
+				"error", err)
+		}
 	}
 
 	return nil
@@ -89,22 +107,33 @@ func (s *ScaleDownManager) updateNodeUtilizationMetrics(
 	ctx context.Context,
 	node *corev1.Node,
 	metrics *metricsv1beta1.NodeMetrics,
+	pods []*corev1.Pod,
 ) error {
+	// Usage is measured against allocatable, like requests, so that both
+	// describe the share of the capacity pods can use
+	cpuAllocatable, memAllocatable := GetNodeAllocatableResources(node)
+	if cpuAllocatable == 0 || memAllocatable == 0 {
+		return fmt.Errorf("node has no allocatable CPU or memory")
+	}
+
 	// Calculate CPU utilization
 	cpuUsage := metrics.Usage.Cpu().MilliValue()
-	cpuCapacity := node.Status.Capacity.Cpu().MilliValue()
-	cpuUtilization := float64(cpuUsage) / float64(cpuCapacity) * 100
+	cpuUtilization := float64(cpuUsage) / float64(cpuAllocatable) * 100
 
 	// Calculate memory utilization
 	memUsage := metrics.Usage.Memory().Value()
-	memCapacity := node.Status.Capacity.Memory().Value()
-	memUtilization := float64(memUsage) / float64(memCapacity) * 100
+	memUtilization := float64(memUsage) / float64(memAllocatable) * 100
+
+	// Calculate requested share of allocatable
+	cpuRequests, memRequests := CalculateNodeUtilizationFromPods(node, pods)
 
 	// Create new sample
 	sample := UtilizationSample{
 		Timestamp:         time.Now(),
 		CPUUtilization:    cpuUtilization,
 		MemoryUtilization: memUtilization,
+		CPURequests:       cpuRequests,
+		MemoryRequests:    memRequests,
 	}
 
 	// Update utilization tracking
@@ -117,6 +146,7 @@ func (s *ScaleDownManager) updateNodeUtilizationMetrics(
 			NodeName: node.Name,
 			Samples:  make([]UtilizationSample, 0, MaxSamplesPerNode),
 		}
+		s.restoreNodeUtilization(util, node, sample.Timestamp)
 		s.nodeUtilization[node.Name] = util
 	}
 
@@ -133,14 +163,10 @@ func (s *ScaleDownManager) updateNodeUtilizationMetrics(
 	}
 
 	util.Samples = newSamples
-
-	// Calculate rolling average
-	util.CPUUtilization, util.MemoryUtilization = s.calculateRollingAverage(util.Samples)
 	util.LastUpdated = time.Now()
 
-	// Determine if underutilized
-	util.IsUnderutilized = util.CPUUtilization < s.config.CPUThreshold &&
-		util.MemoryUtilization < s.config.MemoryThreshold
+	policy := s.utilizationPolicies[s.nodePolicyKeys[node.Name]]
+	s.evaluateUtilization(util, policy, sample.Timestamp)
 
 	s.logger.Debug("updated node utilization",
 		"node", node.Name,
@@ -151,32 +177,115 @@ func (s *ScaleDownManager) updateNodeUtilizationMetrics(
 	return nil
 }
 
-func (s *ScaleDownManager) calculateRollingAverage(samples []UtilizationSample) (cpu, memory float64) {
-	if len(samples) == 0 {
-		return 0, 0
+// evaluateUtilization sets the rolling utilization of a node under a
+// NodeGroup's policy and whether it is below the thresholds. The caller must
+// hold utilizationLock.
+func (s *ScaleDownManager) evaluateUtilization(util *NodeUtilization, policy utilizationPolicy, now time.Time) {
+	util.CPUUtilization, util.MemoryUtilization = s.calculateRollingUtilization(util.Samples, policy)
+
+	util.IsUnderutilized = util.CPUUtilization < s.config.CPUThreshold &&
+		util.MemoryUtilization < s.config.MemoryThreshold
+	if !util.IsUnderutilized {
+		util.UnneededSince = time.Time{}
+	} else if util.UnneededSince.IsZero() {
+		util.UnneededSince = now
 	}
+}
 
-	// Calculate average over observation window
-	now := time.Now()
-	windowStart := now.Add(-s.config.ObservationWindow)
+// calculateRollingUtilization aggregates the samples within the observation
+// window under a NodeGroup's utilization mode and aggregation
+func (s *ScaleDownManager) calculateRollingUtilization(samples []UtilizationSample, policy utilizationPolicy) (cpu, memory float64) {
+	return aggregateSamples(samples, policy, time.Now().Add(-s.config.ObservationWindow))
+}
 
-	var cpuSum, memSum float64
-	count := 0
+// Aggregate returns the node's CPU and memory utilization over the samples
+// taken since the given time, under a utilization mode and aggregation. It is
+// how scale-down measures the node, for consumers that rank nodes the same way.
+func (n *NodeUtilization) Aggregate(
+	mode autoscalerv1alpha1.UtilizationMode,
+	aggregation autoscalerv1alpha1.UtilizationAggregation,
+	since time.Time,
+) (cpu, memory float64) {
+	return aggregateSamples(n.Samples, utilizationPolicy{mode: mode, aggregation: aggregation}, since)
+}
 
+// aggregateSamples aggregates the samples taken since windowStart under a policy
+func aggregateSamples(samples []UtilizationSample, policy utilizationPolicy, windowStart time.Time) (cpu, memory float64) {
+	var cpuValues, memValues []float64
 	for _, sample := range samples {
 		if sample.Timestamp.Before(windowStart) {
 			continue
 		}
-		cpuSum += sample.CPUUtilization
-		memSum += sample.MemoryUtilization
-		count++
+		sampleCPU, sampleMemory := sample.resolve(policy.mode)
+		cpuValues = append(cpuValues, sampleCPU)
+		memValues = append(memValues, sampleMemory)
+	}
+
+	return autoscalerv1alpha1.AggregateUtilization(policy.aggregation, cpuValues),
+		autoscalerv1alpha1.AggregateUtilization(policy.aggregation, memValues)
+}
+
+// resolve returns the CPU and memory utilization of a sample under a mode
+func (u UtilizationSample) resolve(mode autoscalerv1alpha1.UtilizationMode) (cpu, memory float64) {
+	return autoscalerv1alpha1.ResolveUtilization(mode, u.CPUUtilization, u.CPURequests),
+		autoscalerv1alpha1.ResolveUtilization(mode, u.MemoryUtilization, u.MemoryRequests)
+}
+
+// setUtilizationPolicy records and returns how a NodeGroup measures
+// utilization. When it changed, the tracked utilization of the NodeGroup's
+// nodes is re-evaluated right away, restarting their observation window if
+// they became underutilized.
+func (s *ScaleDownManager) setUtilizationPolicy(nodeGroup *autoscalerv1alpha1.NodeGroup, nodes []*corev1.Node) utilizationPolicy {
+	policy := nodeGroupUtilizationPolicy(nodeGroup)
+
+	s.utilizationLock.Lock()
+	defer s.utilizationLock.Unlock()
+
+	if s.utilizationPolicies == nil {
+		s.utilizationPolicies = make(map[string]utilizationPolicy)
+	}
+	if s.nodePolicyKeys == nil {
+		s.nodePolicyKeys = make(map[string]string)
+	}
+	key := utilizationPolicyKey(nodeGroup)
+	for _, node := range nodes {
+		s.nodePolicyKeys[node.Name] = key
+	}
+
+	// NodeGroups not seen yet were measured by mean usage
+	current, ok := s.utilizationPolicies[key]
+	if !ok {
+		current = utilizationPolicy{
+			mode:        autoscalerv1alpha1.UtilizationModeUsage,
+			aggregation: autoscalerv1alpha1.UtilizationAggregationMean,
+		}
+	}
+	s.utilizationPolicies[key] = policy
+	if current == policy {
+		return policy
 	}
 
-	if count == 0 {
-		return 0, 0
+	now := time.Now()
+	for _, node := range nodes {
+		if util, ok := s.nodeUtilization[node.Name]; ok {
+			s.evaluateUtilization(util, policy, now)
+		}
 	}
+	return policy
+}
 
-	return cpuSum / float64(count), memSum / float64(count)
+// utilizationPolicyKey returns the namespace/name key a NodeGroup's
+// utilization policy is tracked under
+func utilizationPolicyKey(nodeGroup *autoscalerv1alpha1.NodeGroup) string {
+	return nodeGroup.Namespace + "/" + nodeGroup.Name
+}
+
+// nodeGroupUtilizationPolicy returns the utilization policy a NodeGroup specifies
+func nodeGroupUtilizationPolicy(nodeGroup *autoscalerv1alpha1.NodeGroup) utilizationPolicy {
+	return utilizationPolicy{
+		mode:        autoscalerv1alpha1.EffectiveUtilizationMode(&nodeGroup.Spec.ScaleDownPolicy),
+		aggregation: autoscalerv1alpha1.EffectiveUtilizationAggregation(&nodeGroup.Spec.ScaleDownPolicy),
+	}
 }
 
 // GetNodeUtilization returns a deep copy of utilization data for a specific node
@@ -221,6 +330,7 @@ func (n *NodeUtilization) DeepCopy() *NodeUtilization {
 		MemoryUtilization: n.MemoryUtilization,
 		IsUnderutilized:   n.IsUnderutilized,
 		LastUpdated:       n.LastUpdated,
+		UnneededSince:     n.UnneededSince,
 		Samples:           make([]UtilizationSample, len(n.Samples)),
 	}
 	copy(cp.Samples, n.Samples)
@@ -137,7 +137,8 @@ func (a *Analyzer) RecordCost(ctx context.Context, nodeGroup *v1alpha1.NodeGroup
 	}
 
 	// Calculate efficiency score (0-100)
-	efficiencyScore := a.calculateEfficiencyScore(cost, utilization)
+	mode := v1alpha1.EffectiveUtilizationMode(&nodeGroup.Spec.ScaleDownPolicy)
+	efficiencyScore := a.calculateEfficiencyScore(cost, utilization.Resolve(mode))
 
 	snapshot := &CostSnapshot{
 		Timestamp:       time.Now(),
@@ -327,45 +328,54 @@ func (a *Analyzer) AnalyzeUtilization(ctx context.Context, nodeGroup *v1alpha1.N
 		return nil, fmt.Errorf("no utilization data available")
 	}
 
-	// Calculate average and peak utilization
-	var avgCPU, avgMemory, avgDisk float64
-	peakCPU := snapshots[0].Utilization.CPUPercent
-	peakMemory := snapshots[0].Utilization.MemoryPercent
-	peakDisk := snapshots[0].Utilization.DiskPercent
+	// Measure utilization the way the NodeGroup's scale-down does
+	mode := v1alpha1.EffectiveUtilizationMode(&nodeGroup.Spec.ScaleDownPolicy)
+	aggregation := v1alpha1.EffectiveUtilizationAggregation(&nodeGroup.Spec.ScaleDownPolicy)
 
-	for _, snapshot := range snapshots {
-		avgCPU += snapshot.Utilization.CPUPercent
-		avgMemory += snapshot.Utilization.MemoryPercent
-		avgDisk += snapshot.Utilization.DiskPercent
+	// Calculate aggregated and peak utilization
+	n := len(snapshots)
+	cpu, memory := make([]float64, 0, n), make([]float64, 0, n)
+	cpuRequests, memoryRequests := make([]float64, 0, n), make([]float64, 0, n)
+	disk := make([]float64, 0, n)
+	peakUtilization := snapshots[0].Utilization.Resolve(mode)
 
-		if snapshot.Utilization.CPUPercent > peakCPU {
-			peakCPU = snapshot.Utilization.CPUPercent
+	for _, snapshot := range snapshots {
+		utilization := snapshot.Utilization.Resolve(mode)
+		cpu = append(cpu, utilization.CPUPercent)
+		memory = append(memory, utilization.MemoryPercent)
+		cpuRequests = append(cpuRequests, utilization.CPURequestsPercent)
+		memoryRequests = append(memoryRequests, utilization.MemoryRequestsPercent)
+		disk = append(disk, utilization.DiskPercent)
+
+		if utilization.CPUPercent > peakUtilization.CPUPercent {
+			peakUtilization.CPUPercent = utilization.CPUPercent
+		}
+		if utilization.MemoryPercent > peakUtilization.MemoryPercent {
+			peakUtilization.MemoryPercent = utilization.MemoryPercent
 		}
-		if snapshot.Utilization.MemoryPercent > peakMemory {
-			peakMemory = snapshot.Utilization.MemoryPercent
+		if utilization.CPURequestsPercent > peakUtilization.CPURequestsPercent {
+			peakUtilization.CPURequestsPercent = utilization.CPURequestsPercent
 		}
-		if snapshot.Utilization.DiskPercent > peakDisk {
-			peakDisk = snapshot.Utilization.DiskPercent
+		if utilization.MemoryRequestsPercent > peakUtilization.MemoryRequestsPercent {
+			peakUtilization.MemoryRequestsPercent = utilization.MemoryRequestsPercent
+		}
+		if utilization.DiskPercent > peakUtilization.DiskPercent {
+			peakUtilization.DiskPercent = utilization.DiskPercent
 		}
 	}
 
-	n := float64(len(snapshots))
 	avgUtilization := ResourceUtilization{
-		CPUPercent:    avgCPU / n,
-		MemoryPercent: avgMemory / n,
-		DiskPercent:   avgDisk / n,
-		NodeCount:     snapshots[len(snapshots)-1].Cost.TotalNodes,
-	}
-
-	peakUtilization := ResourceUtilization{
-		CPUPercent:    peakCPU,
-		MemoryPercent: peakMemory,
-		DiskPercent:   peakDisk,
-		NodeCount:     snapshots[len(snapshots)-1].Cost.TotalNodes,
+		CPUPercent:            v1alpha1.AggregateUtilization(aggregation, cpu),
+		MemoryPercent:         v1alpha1.AggregateUtilization(aggregation, memory),
+		CPURequestsPercent:    v1alpha1.AggregateUtilization(aggregation, cpuRequests),
+		MemoryRequestsPercent: v1alpha1.AggregateUtilization(aggregation, memoryRequests),
+		DiskPercent:           v1alpha1.AggregateUtilization(aggregation, disk),
+		NodeCount:             snapshots[n-1].Cost.TotalNodes,
 	}
+	peakUtilization.NodeCount = snapshots[n-1].Cost.TotalNodes
 
 	// Get current cost
-	latestSnapshot := snapshots[len(snapshots)-1]
+	latestSnapshot := snapshots[n-1]
 	cost := latestSnapshot.Cost
 
 	// Calculate cost per resource
@@ -401,6 +411,8 @@ func (a *Analyzer) AnalyzeUtilization(ctx context.Context, nodeGroup *v1alpha1.N
 	return &UtilizationAnalysis{
 		NodeGroupName:      nodeGroup.Name,
 		Namespace:          nodeGroup.Namespace,
+		Mode:               mode,
+		Aggregation:        aggregation,
 		AverageUtilization: avgUtilization,
 		PeakUtilization:    peakUtilization,
 		CostPerCPUCore:     costPerCPUCore,
@@ -15,6 +15,21 @@ import (
 type Calculator struct {
 	client client.VPSieClient
 	cache  *costCache
+
+	// pricing is the optional negotiated pricing plan (nil means list prices)
+	pricing *PricingPlan
+
+	// catalog is the optional offline price catalog used as fallback or override
+	catalog     *PriceCatalog
+	catalogMode CatalogMode
+
+	// driftedOfferings are the offering labels this calculator set the drift gauge for
+	driftedOfferings map[string]bool
+
+	// carbon is the optional carbon intensity source for carbon-aware selection
+	carbon CarbonIntensitySource
+
+	pricingMu sync.RWMutex
 }
 
 // costCache caches offering costs to reduce API calls
@@ -39,17 +54,18 @@ func NewCalculator(client client.VPSieClient) *Calculator {
 func (c *Calculator) GetOfferingCost(ctx context.Context, offeringID string) (*OfferingCost, error) {
 	// Check cache first
 	if cached := c.cache.get(offeringID); cached != nil {
+		recordPricingSource(cached.Source, "offering_cost")
 		return cached, nil
 	}
 
-	// Fetch from API
-	offerings, err := c.client.ListOfferings(ctx, nil)
+	// Fetch from API (or the price catalog)
+	offerings, err := c.listOfferings(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list offerings: %w", err)
+		return nil, err
 	}
 
 	// Find the specific offering
-	var found *client.Offering
+	var found *pricedOffering
 	for i := range offerings {
 		if offerings[i].ID == offeringID {
 			found = &offerings[i]
@@ -76,12 +92,17 @@ func (c *Calculator) GetOfferingCost(ctx context.Context, offeringID string) (*O
 			Bandwidth: found.Bandwidth,
 		},
 		Category:    found.Category,
+		Source:      found.source,
 		LastUpdated: time.Now(),
 	}
 
-	// Cache the result
-	c.cache.set(offeringID, cost)
+	// Cache the result. Fallback catalog prices are not cached so live prices
+	// are used again as soon as the API recovers.
+	if _, mode := c.PriceCatalog(); found.source == PricingSourceLive || mode == CatalogModeOverride {
+		c.cache.set(offeringID, cost)
+	}
 
+	recordPricingSource(cost.Source, "offering_cost")
 	return cost, nil
 }
 
@@ -113,12 +134,15 @@ func (c *Calculator) CalculateNodeGroupCost(ctx context.Context, nodeGroup *v1al
 			count = nodeGroup.Spec.MinNodes
 		}
 
+		committed, effective := c.effectiveInstanceCost(nodeGroup, cost, count)
 		instanceTypes[offeringID] = InstanceTypeCost{
-			OfferingID:   offeringID,
-			Count:        count,
-			HourlyEach:   cost.HourlyCost,
-			TotalHourly:  cost.HourlyCost * float64(count),
-			TotalMonthly: cost.MonthlyCost * float64(count),
+			OfferingID:       offeringID,
+			Count:            count,
+			HourlyEach:       cost.HourlyCost,
+			TotalHourly:      cost.HourlyCost * float64(count),
+			TotalMonthly:     cost.MonthlyCost * float64(count),
+			CommittedCount:   committed,
+			EffectiveMonthly: effective,
 		}
 
 		totalHourly = cost.HourlyCost * float64(count)
@@ -138,12 +162,15 @@ func (c *Calculator) CalculateNodeGroupCost(ctx context.Context, nodeGroup *v1al
 				return nil, fmt.Errorf("failed to get cost for offering %s: %w", offeringID, err)
 			}
 
+			committed, effective := c.effectiveInstanceCost(nodeGroup, cost, count)
 			instanceCost := InstanceTypeCost{
-				OfferingID:   offeringID,
-				Count:        count,
-				HourlyEach:   cost.HourlyCost,
-				TotalHourly:  cost.HourlyCost * float64(count),
-				TotalMonthly: cost.MonthlyCost * float64(count),
+				OfferingID:       offeringID,
+				Count:            count,
+				HourlyEach:       cost.HourlyCost,
+				TotalHourly:      cost.HourlyCost * float64(count),
+				TotalMonthly:     cost.MonthlyCost * float64(count),
+				CommittedCount:   committed,
+				EffectiveMonthly: effective,
 			}
 
 			instanceTypes[offeringID] = instanceCost
@@ -156,19 +183,94 @@ func (c *Calculator) CalculateNodeGroupCost(ctx context.Context, nodeGroup *v1al
 		costPerNode = totalHourly / float64(totalNodes)
 	}
 
+	totalMonthly := totalHourly * hoursPerMonth
+
+	// Without a pricing plan the effective cost is the list cost
+	effectiveMonthly := totalMonthly
+	var committedNodes int32
+	if plan := c.PricingPlan(); plan != nil {
+		effectiveMonthly = 0
+		for _, instanceCost := range instanceTypes {
+			effectiveMonthly += instanceCost.EffectiveMonthly
+			committedNodes += instanceCost.CommittedCount
+		}
+
+		// Commitments are billed in full even for offerings without nodes
+		for _, offeringID := range plan.committedOfferings(nodeGroup.Name, nodeGroup.Namespace, time.Now()) {
+			if _, ok := instanceTypes[offeringID]; ok {
+				continue
+			}
+			stranded, err := c.strandedCommitmentCost(ctx, nodeGroup, offeringID)
+			if err != nil {
+				return nil, err
+			}
+			effectiveMonthly += stranded
+		}
+	}
+
 	return &NodeGroupCost{
-		NodeGroupName: nodeGroup.Name,
-		Namespace:     nodeGroup.Namespace,
-		TotalNodes:    totalNodes,
-		CostPerNode:   costPerNode,
-		TotalHourly:   totalHourly,
-		TotalDaily:    totalHourly * 24,
-		TotalMonthly:  totalHourly * 730, // Average hours per month
-		InstanceTypes: instanceTypes,
-		LastUpdated:   time.Now(),
+		NodeGroupName:       nodeGroup.Name,
+		Namespace:           nodeGroup.Namespace,
+		TotalNodes:          totalNodes,
+		CostPerNode:         costPerNode,
+		TotalHourly:         totalHourly,
+		TotalDaily:          totalHourly * 24,
+		TotalMonthly:        totalMonthly,
+		InstanceTypes:       instanceTypes,
+		LastUpdated:         time.Now(),
+		EffectiveMonthly:    effectiveMonthly,
+		CommittedNodes:      committedNodes,
+		HasEffectiveMonthly: true,
 	}, nil
 }
 
+// effectiveInstanceCost applies the pricing plan to count nodes of an offering
+// in a NodeGroup and returns the committed node count and effective monthly cost
+func (c *Calculator) effectiveInstanceCost(nodeGroup *v1alpha1.NodeGroup, offering *OfferingCost, count int32) (int32, float64) {
+	plan := c.PricingPlan()
+	if plan == nil {
+		return 0, offering.MonthlyCost * float64(count)
+	}
+	return plan.effectiveInstanceCost(nodeGroup.Name, nodeGroup.Namespace, offering, count)
+}
+
+// effectiveReplacementCost returns the effective monthly cost of running count
+// nodes of an offering in place of a NodeGroup's current nodes. Commitments on
+// other offerings are billed until they expire, so they are included whether
+// or not those offerings have nodes.
+func (c *Calculator) effectiveReplacementCost(ctx context.Context, nodeGroup *v1alpha1.NodeGroup,
+	offering *OfferingCost, count int32) (float64, error) {
+	_, monthly := c.effectiveInstanceCost(nodeGroup, offering, count)
+	plan := c.PricingPlan()
+	if plan == nil {
+		return monthly, nil
+	}
+
+	for _, offeringID := range plan.committedOfferings(nodeGroup.Name, nodeGroup.Namespace, time.Now()) {
+		if offeringID == offering.OfferingID {
+			continue
+		}
+		stranded, err := c.strandedCommitmentCost(ctx, nodeGroup, offeringID)
+		if err != nil {
+			return 0, err
+		}
+		monthly += stranded
+	}
+
+	return monthly, nil
+}
+
+// strandedCommitmentCost returns the monthly charge of a NodeGroup's
+// commitments on an offering when none of its nodes use the offering
+func (c *Calculator) strandedCommitmentCost(ctx context.Context, nodeGroup *v1alpha1.NodeGroup, offeringID string) (float64, error) {
+	offering, err := c.GetOfferingCost(ctx, offeringID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cost for committed offering %s: %w", offeringID, err)
+	}
+	_, monthly := c.effectiveInstanceCost(nodeGroup, offering, 0)
+	return monthly, nil
+}
+
 // CompareOfferings compares costs between multiple offerings
 func (c *Calculator) CompareOfferings(ctx context.Context, offeringIDs []string) (*CostComparison, error) {
 	if len(offeringIDs) == 0 {
@@ -216,11 +318,13 @@ func (c *Calculator) CalculateSavings(current, proposed *NodeGroupCost) (*Saving
 		return nil, fmt.Errorf("current and proposed costs cannot be nil")
 	}
 
-	monthlySavings := current.TotalMonthly - proposed.TotalMonthly
+	// Compare effective costs so negotiated discounts and commitments are honoured
+	currentMonthly := current.effectiveMonthly()
+	monthlySavings := currentMonthly - proposed.effectiveMonthly()
 	annualSavings := monthlySavings * 12
 	savingsPercent := float64(0)
-	if current.TotalMonthly > 0 {
-		savingsPercent = (monthlySavings / current.TotalMonthly) * 100
+	if currentMonthly > 0 {
+		savingsPercent = (monthlySavings / currentMonthly) * 100
 	}
 
 	// Calculate break-even days (assumes migration has a cost)
@@ -263,36 +367,9 @@ func (c *Calculator) CalculateSavings(current, proposed *NodeGroupCost) (*Saving
 
 // FindCheapestOffering finds the cheapest offering that meets requirements
 func (c *Calculator) FindCheapestOffering(ctx context.Context, requirements ResourceRequirements, allowedOfferings []string) (*Recommendation, error) {
-	offerings, err := c.client.ListOfferings(ctx, nil)
+	candidates, err := c.eligibleOfferings(ctx, requirements, allowedOfferings)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list offerings: %w", err)
-	}
-
-	// Filter offerings by requirements and allowed list
-	allowedMap := make(map[string]bool)
-	for _, id := range allowedOfferings {
-		allowedMap[id] = true
-	}
-
-	var candidates []client.Offering
-	for _, offering := range offerings {
-		// Check if allowed
-		if len(allowedOfferings) > 0 && !allowedMap[offering.ID] {
-			continue
-		}
-
-		// Check if meets requirements
-		if offering.CPU >= requirements.MinCPU &&
-			offering.RAM >= requirements.MinMemoryMB &&
-			offering.Disk >= requirements.MinDiskGB &&
-			offering.Bandwidth >= requirements.MinBandwidth &&
-			offering.Available {
-			candidates = append(candidates, offering)
-		}
-	}
-
-	if len(candidates) == 0 {
-		return nil, fmt.Errorf("no offerings meet the requirements")
+		return nil, err
 	}
 
 	// Sort by price (cheapest first)
@@ -301,6 +378,7 @@ func (c *Calculator) FindCheapestOffering(ctx context.Context, requirements Reso
 	})
 
 	cheapest := candidates[0]
+	recordPricingSource(cheapest.source, "cheapest_offering")
 
 	// Build alternative options (next 2-3 cheapest)
 	var alternatives []string
@@ -327,9 +405,49 @@ func (c *Calculator) FindCheapestOffering(ctx context.Context, requirements Reso
 		PerformanceImpact:  "none",
 		Confidence:         confidence,
 		AlternativeOptions: alternatives,
+		PricingSource:      cheapest.source,
+		DatacenterID:       cheapest.DatacenterID,
 	}, nil
 }
 
+// eligibleOfferings returns the available offerings that meet the requirements,
+// restricted to allowedOfferings when it is not empty
+func (c *Calculator) eligibleOfferings(ctx context.Context, requirements ResourceRequirements, allowedOfferings []string) ([]pricedOffering, error) {
+	offerings, err := c.listOfferings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Filter offerings by requirements and allowed list
+	allowedMap := make(map[string]bool)
+	for _, id := range allowedOfferings {
+		allowedMap[id] = true
+	}
+
+	var candidates []pricedOffering
+	for _, offering := range offerings {
+		// Check if allowed
+		if len(allowedOfferings) > 0 && !allowedMap[offering.ID] {
+			continue
+		}
+
+		// Check if meets requirements
+		if offering.CPU >= requirements.MinCPU &&
+			offering.RAM >= requirements.MinMemoryMB &&
+			offering.Disk >= requirements.MinDiskGB &&
+			offering.Bandwidth >= requirements.MinBandwidth &&
+			offering.Available {
+			candidates = append(candidates, offering)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no offerings meet the requirements")
+	}
+
+	return candidates, nil
+}
+
 // CalculateCostPerResource calculates cost per CPU, memory, and disk
 func (c *Calculator) CalculateCostPerResource(ctx context.Context, offeringID string) (cpuCost, memoryCost, diskCost float64, err error) {
 	cost, err := c.GetOfferingCost(ctx, offeringID)
@@ -351,6 +469,30 @@ func (c *Calculator) CalculateCostPerResource(ctx context.Context, offeringID st
 	return cpuCost, memoryCost, diskCost, nil
 }
 
+// effectiveMonthly returns the effective monthly cost, falling back to the list
+// cost for NodeGroupCosts that were built without a pricing plan
+func (n *NodeGroupCost) effectiveMonthly() float64 {
+	if n.HasEffectiveMonthly {
+		return n.EffectiveMonthly
+	}
+	return n.TotalMonthly
+}
+
+// SetPricingPlan sets the negotiated pricing plan used for effective costs.
+// Pass nil to revert to list prices.
+func (c *Calculator) SetPricingPlan(plan *PricingPlan) {
+	c.pricingMu.Lock()
+	defer c.pricingMu.Unlock()
+	c.pricing = plan
+}
+
+// PricingPlan returns the current pricing plan, or nil if list prices are used
+func (c *Calculator) PricingPlan() *PricingPlan {
+	c.pricingMu.RLock()
+	defer c.pricingMu.RUnlock()
+	return c.pricing
+}
+
 // cache methods
 
 func (cc *costCache) get(offeringID string) *OfferingCost {
@@ -0,0 +1,318 @@
+package cost
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+
+	metricsutil "github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
+)
+
+const (
+	// wattsPerCPU is the estimated average power draw of one vCPU
+	wattsPerCPU = 10.0
+
+	// wattsPerGBMemory is the estimated average power draw of one GB of memory
+	wattsPerGBMemory = 0.375
+)
+
+// CarbonIntensitySource provides the grid carbon intensity of VPSie datacenters
+type CarbonIntensitySource interface {
+	// Intensity returns the carbon intensity in gCO2e/kWh for a datacenter.
+	// Returns false if the intensity of the datacenter is unknown.
+	Intensity(datacenterID string) (float64, bool)
+}
+
+// CarbonObjective weighs carbon intensity against price when choosing offerings
+// and datacenters
+type CarbonObjective string
+
+const (
+	// CarbonObjectiveCost ignores carbon intensity and optimizes for price only
+	CarbonObjectiveCost CarbonObjective = "cost"
+
+	// CarbonObjectiveBalanced gives price and carbon intensity equal weight
+	CarbonObjectiveBalanced CarbonObjective = "balanced"
+
+	// CarbonObjectiveLowCarbon optimizes for the lowest emissions, using price only to break ties
+	CarbonObjectiveLowCarbon CarbonObjective = "low-carbon"
+)
+
+// ParseCarbonObjective parses a carbon objective. An empty string is CarbonObjectiveCost.
+func ParseCarbonObjective(value string) (CarbonObjective, error) {
+	switch CarbonObjective(value) {
+	case "", CarbonObjectiveCost:
+		return CarbonObjectiveCost, nil
+	case CarbonObjectiveBalanced, CarbonObjectiveLowCarbon:
+		return CarbonObjective(value), nil
+	default:
+		return "", fmt.Errorf("invalid carbon objective %q, must be one of: cost, balanced, low-carbon", value)
+	}
+}
+
+// CarbonWeight returns the weight (0-1) given to emissions; price gets the remainder
+func (o CarbonObjective) CarbonWeight() float64 {
+	switch o {
+	case CarbonObjectiveBalanced:
+		return 0.5
+	case CarbonObjectiveLowCarbon:
+		return 1.0
+	default:
+		return 0
+	}
+}
+
+// DatacenterIntensity is the carbon intensity of a single datacenter
+type DatacenterIntensity struct {
+	// ID is the VPSie datacenter ID
+	ID string `json:"id"`
+
+	// Name is an optional human-readable name (e.g., "Toronto")
+	Name string `json:"name,omitempty"`
+
+	// Intensity is the grid carbon intensity in gCO2e/kWh, including datacenter overhead
+	Intensity float64 `json:"intensity"`
+}
+
+// CarbonIntensityTable is a static, file-backed CarbonIntensitySource
+type CarbonIntensityTable struct {
+	// Version identifies the table revision (e.g., "2024-q2")
+	Version string `json:"version"`
+
+	// DefaultIntensity is used for datacenters not listed in the table.
+	// Zero means unlisted datacenters have an unknown intensity.
+	DefaultIntensity float64 `json:"defaultIntensity,omitempty"`
+
+	// Datacenters are the per-datacenter intensities
+	Datacenters []DatacenterIntensity `json:"datacenters"`
+}
+
+// ParseCarbonIntensityTable parses a carbon intensity table from a YAML or JSON document
+func ParseCarbonIntensityTable(data []byte) (*CarbonIntensityTable, error) {
+	table := &CarbonIntensityTable{}
+	if err := yaml.UnmarshalStrict(data, table); err != nil {
+		return nil, fmt.Errorf("failed to parse carbon intensity table: %w", err)
+	}
+
+	if err := table.Validate(); err != nil {
+		return nil, err
+	}
+
+	return table, nil
+}
+
+// LoadCarbonIntensityFile loads a carbon intensity table from a YAML or JSON file
+func LoadCarbonIntensityFile(path string) (*CarbonIntensityTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read carbon intensity file %s: %w", path, err)
+	}
+	return ParseCarbonIntensityTable(data)
+}
+
+// Validate checks the carbon intensity table for missing or invalid entries
+func (t *CarbonIntensityTable) Validate() error {
+	if t.Version == "" {
+		return fmt.Errorf("carbon intensity table must specify a version")
+	}
+	if t.DefaultIntensity < 0 {
+		return fmt.Errorf("carbon intensity table has a negative defaultIntensity")
+	}
+
+	seen := make(map[string]bool)
+	for _, dc := range t.Datacenters {
+		if dc.ID == "" {
+			return fmt.Errorf("carbon intensity table datacenter must specify an id")
+		}
+		if seen[dc.ID] {
+			return fmt.Errorf("carbon intensity table lists datacenter %s more than once", dc.ID)
+		}
+		seen[dc.ID] = true
+
+		if dc.Intensity < 0 {
+			return fmt.Errorf("carbon intensity table datacenter %s has a negative intensity", dc.ID)
+		}
+	}
+
+	return nil
+}
+
+// Intensity returns the carbon intensity of a datacenter in gCO2e/kWh
+func (t *CarbonIntensityTable) Intensity(datacenterID string) (float64, bool) {
+	for _, dc := range t.Datacenters {
+		if dc.ID == datacenterID {
+			return dc.Intensity, true
+		}
+	}
+	if t.DefaultIntensity > 0 {
+		return t.DefaultIntensity, true
+	}
+	return 0, false
+}
+
+// EstimatePowerWatts estimates the average power draw of a node with the given specs
+func EstimatePowerWatts(specs ResourceSpecs) float64 {
+	return float64(specs.CPU)*wattsPerCPU + float64(specs.MemoryMB)/1024*wattsPerGBMemory
+}
+
+// EstimateEmissions estimates the emissions of a node in gCO2e per hour. Returns
+// false if source is nil or the datacenter's intensity is unknown.
+func EstimateEmissions(source CarbonIntensitySource, datacenterID string, specs ResourceSpecs) (float64, bool) {
+	if source == nil {
+		return 0, false
+	}
+	intensity, ok := source.Intensity(datacenterID)
+	if !ok {
+		return 0, false
+	}
+	return EstimatePowerWatts(specs) / 1000 * intensity, true
+}
+
+// PlacementCandidate is an offering or datacenter being ranked by RankPlacements
+type PlacementCandidate struct {
+	DatacenterID string
+	OfferingID   string
+	Specs        ResourceSpecs
+	MonthlyCost  float64
+
+	// Set by RankPlacements
+	EmissionsPerHour float64 // Estimated gCO2e per hour, 0 when unknown
+	EmissionsKnown   bool
+	Score            float64 // Weighted, normalized cost and emissions; lower is better
+}
+
+// RankPlacements scores candidates by the objective and returns them best first.
+// Cost and emissions are normalized against the most expensive and most emitting
+// candidate; candidates in datacenters with unknown intensity are scored as the
+// worst emitter. The input order is kept between equal scores.
+func RankPlacements(source CarbonIntensitySource, objective CarbonObjective, candidates []PlacementCandidate) []PlacementCandidate {
+	ranked := make([]PlacementCandidate, len(candidates))
+	copy(ranked, candidates)
+
+	var maxCost, maxEmissions float64
+	for i := range ranked {
+		ranked[i].EmissionsPerHour, ranked[i].EmissionsKnown = EstimateEmissions(source, ranked[i].DatacenterID, ranked[i].Specs)
+		if ranked[i].MonthlyCost > maxCost {
+			maxCost = ranked[i].MonthlyCost
+		}
+		if ranked[i].EmissionsPerHour > maxEmissions {
+			maxEmissions = ranked[i].EmissionsPerHour
+		}
+	}
+
+	weight := objective.CarbonWeight()
+	for i := range ranked {
+		var costScore, carbonScore float64
+		if maxCost > 0 {
+			costScore = ranked[i].MonthlyCost / maxCost
+		}
+		switch {
+		case !ranked[i].EmissionsKnown:
+			carbonScore = 1
+		case maxEmissions > 0:
+			carbonScore = ranked[i].EmissionsPerHour / maxEmissions
+		}
+		ranked[i].Score = (1-weight)*costScore + weight*carbonScore
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score < ranked[j].Score
+		}
+		return ranked[i].MonthlyCost < ranked[j].MonthlyCost
+	})
+
+	return ranked
+}
+
+// SetCarbonIntensity sets the carbon intensity source used by carbon-aware
+// recommendations. A nil source disables carbon-aware selection.
+func (c *Calculator) SetCarbonIntensity(source CarbonIntensitySource) {
+	c.pricingMu.Lock()
+	defer c.pricingMu.Unlock()
+	c.carbon = source
+}
+
+// CarbonIntensity returns the configured carbon intensity source, or nil if none is set
+func (c *Calculator) CarbonIntensity() CarbonIntensitySource {
+	c.pricingMu.RLock()
+	defer c.pricingMu.RUnlock()
+	return c.carbon
+}
+
+// FindLowestImpactOffering finds the offering that best meets the requirements
+// under requirements.Objective, weighing monthly price against estimated emissions
+// in the offering's datacenter
+func (c *Calculator) FindLowestImpactOffering(ctx context.Context, requirements ResourceRequirements, allowedOfferings []string) (*Recommendation, error) {
+	source := c.CarbonIntensity()
+	if source == nil {
+		return nil, fmt.Errorf("no carbon intensity source configured")
+	}
+
+	offerings, err := c.eligibleOfferings(ctx, requirements, allowedOfferings)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make(map[string]PricingSource, len(offerings))
+	candidates := make([]PlacementCandidate, 0, len(offerings))
+	for _, offering := range offerings {
+		sources[offering.ID] = offering.source
+		candidates = append(candidates, PlacementCandidate{
+			DatacenterID: offering.DatacenterID,
+			OfferingID:   offering.ID,
+			Specs: ResourceSpecs{
+				CPU:       offering.CPU,
+				MemoryMB:  offering.RAM,
+				DiskGB:    offering.Disk,
+				Bandwidth: offering.Bandwidth,
+			},
+			MonthlyCost: offering.Price,
+		})
+	}
+
+	ranked := RankPlacements(source, requirements.Objective, candidates)
+	best := ranked[0]
+	recordPricingSource(sources[best.OfferingID], "lowest_impact_offering")
+	recordPlacement("offering", requirements.Objective, best)
+
+	var alternatives []string
+	for i := 1; i < len(ranked) && i < 4; i++ {
+		alternatives = append(alternatives, ranked[i].OfferingID)
+	}
+
+	rationale := fmt.Sprintf("Best %s offering that meets requirements: %d CPU, %d MB RAM, %d GB disk at %.2f/month",
+		requirements.Objective, best.Specs.CPU, best.Specs.MemoryMB, best.Specs.DiskGB, best.MonthlyCost)
+	if best.EmissionsKnown {
+		rationale += fmt.Sprintf(", ~%.1f gCO2e/hour in datacenter %s", best.EmissionsPerHour, best.DatacenterID)
+	}
+
+	var name string
+	for _, offering := range offerings {
+		if offering.ID == best.OfferingID {
+			name = offering.Name
+			break
+		}
+	}
+
+	return &Recommendation{
+		OfferingID:         best.OfferingID,
+		OfferingName:       name,
+		Rationale:          rationale,
+		PerformanceImpact:  "none",
+		Confidence:         0.8,
+		AlternativeOptions: alternatives,
+		PricingSource:      sources[best.OfferingID],
+		DatacenterID:       best.DatacenterID,
+		EmissionsPerHour:   best.EmissionsPerHour,
+	}, nil
+}
+
+// recordPlacement records a carbon-aware placement decision
+func recordPlacement(component string, objective CarbonObjective, candidate PlacementCandidate) {
+	datacenter, _ := metricsutil.SanitizeLabel(candidate.DatacenterID)
+	metricsutil.CarbonPlacementDecisionsTotal.WithLabelValues(component, string(objective), datacenter).Inc()
+}
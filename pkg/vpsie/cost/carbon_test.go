@@ -0,0 +1,193 @@
+package cost
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/client"
+)
+
+const testCarbonTable = `
+version: "2024-q2"
+datacenters:
+  - id: dc-hydro
+    name: Montreal
+    intensity: 30
+  - id: dc-coal
+    name: Frankfurt
+    intensity: 600
+`
+
+func newCarbonTestClient() *MockVPSieClient {
+	return &MockVPSieClient{
+		offerings: []client.Offering{
+			{ID: "coal-small", Name: "Small (coal)", CPU: 2, RAM: 4096, Disk: 50, Price: 10.0, Available: true, DatacenterID: "dc-coal"},
+			{ID: "hydro-small", Name: "Small (hydro)", CPU: 2, RAM: 4096, Disk: 50, Price: 12.0, Available: true, DatacenterID: "dc-hydro"},
+			{ID: "unknown-small", Name: "Small (unknown)", CPU: 2, RAM: 4096, Disk: 50, Price: 9.0, Available: true, DatacenterID: "dc-unknown"},
+		},
+	}
+}
+
+func TestParseCarbonIntensityTable(t *testing.T) {
+	table, err := ParseCarbonIntensityTable([]byte(testCarbonTable))
+	if err != nil {
+		t.Fatalf("ParseCarbonIntensityTable failed: %v", err)
+	}
+
+	if intensity, ok := table.Intensity("dc-hydro"); !ok || intensity != 30 {
+		t.Errorf("Expected dc-hydro intensity 30, got %f (found=%v)", intensity, ok)
+	}
+	if _, ok := table.Intensity("dc-unknown"); ok {
+		t.Error("Expected unknown datacenter without a default intensity to be unknown")
+	}
+
+	table.DefaultIntensity = 400
+	if intensity, ok := table.Intensity("dc-unknown"); !ok || intensity != 400 {
+		t.Errorf("Expected default intensity 400, got %f (found=%v)", intensity, ok)
+	}
+
+	invalid := []string{
+		`datacenters: [{id: dc-1, intensity: 10}]`,
+		`{version: v1, datacenters: [{id: dc-1, intensity: -1}]}`,
+		`{version: v1, datacenters: [{id: dc-1}, {id: dc-1}]}`,
+		`{version: v1, defaultIntensity: -5}`,
+		`{version: v1, unknown: true}`,
+	}
+	for _, data := range invalid {
+		if _, err := ParseCarbonIntensityTable([]byte(data)); err == nil {
+			t.Errorf("Expected error parsing %q, got nil", data)
+		}
+	}
+}
+
+func TestParseCarbonObjective(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected CarbonObjective
+		weight   float64
+		wantErr  bool
+	}{
+		{value: "", expected: CarbonObjectiveCost, weight: 0},
+		{value: "cost", expected: CarbonObjectiveCost, weight: 0},
+		{value: "balanced", expected: CarbonObjectiveBalanced, weight: 0.5},
+		{value: "low-carbon", expected: CarbonObjectiveLowCarbon, weight: 1},
+		{value: "green", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		objective, err := ParseCarbonObjective(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCarbonObjective(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if objective != tt.expected || objective.CarbonWeight() != tt.weight {
+			t.Errorf("ParseCarbonObjective(%q) = %s (weight %f), expected %s (weight %f)",
+				tt.value, objective, objective.CarbonWeight(), tt.expected, tt.weight)
+		}
+	}
+}
+
+func TestEstimateEmissions(t *testing.T) {
+	table, err := ParseCarbonIntensityTable([]byte(testCarbonTable))
+	if err != nil {
+		t.Fatalf("ParseCarbonIntensityTable failed: %v", err)
+	}
+
+	// 2 vCPU * 10W + 4 GB * 0.375W = 21.5W = 0.0215 kWh per hour
+	grams, ok := EstimateEmissions(table, "dc-coal", ResourceSpecs{CPU: 2, MemoryMB: 4096})
+	if !ok {
+		t.Fatal("Expected emissions to be known for dc-coal")
+	}
+	if diff := grams - 0.0215*600; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("Expected %f gCO2e/hour, got %f", 0.0215*600, grams)
+	}
+
+	if _, ok := EstimateEmissions(nil, "dc-coal", ResourceSpecs{CPU: 2}); ok {
+		t.Error("Expected emissions to be unknown without a carbon intensity source")
+	}
+}
+
+func TestRankPlacements(t *testing.T) {
+	table, err := ParseCarbonIntensityTable([]byte(testCarbonTable))
+	if err != nil {
+		t.Fatalf("ParseCarbonIntensityTable failed: %v", err)
+	}
+
+	specs := ResourceSpecs{CPU: 2, MemoryMB: 4096}
+	candidates := []PlacementCandidate{
+		{DatacenterID: "dc-coal", Specs: specs, MonthlyCost: 10},
+		{DatacenterID: "dc-hydro", Specs: specs, MonthlyCost: 12},
+		{DatacenterID: "dc-unknown", Specs: specs, MonthlyCost: 9},
+	}
+
+	tests := []struct {
+		objective CarbonObjective
+		expected  string
+	}{
+		{objective: CarbonObjectiveCost, expected: "dc-unknown"},
+		{objective: CarbonObjectiveBalanced, expected: "dc-hydro"},
+		{objective: CarbonObjectiveLowCarbon, expected: "dc-hydro"},
+	}
+
+	for _, tt := range tests {
+		ranked := RankPlacements(table, tt.objective, candidates)
+		if len(ranked) != len(candidates) {
+			t.Fatalf("Expected %d ranked candidates, got %d", len(candidates), len(ranked))
+		}
+		if ranked[0].DatacenterID != tt.expected {
+			t.Errorf("Objective %s: expected %s first, got %s", tt.objective, tt.expected, ranked[0].DatacenterID)
+		}
+	}
+
+	// Unknown intensity scores as the worst emitter
+	for _, candidate := range RankPlacements(table, CarbonObjectiveLowCarbon, candidates) {
+		if candidate.DatacenterID == "dc-unknown" && (candidate.EmissionsKnown || candidate.Score != 1) {
+			t.Errorf("Expected dc-unknown to score as the worst emitter, got %+v", candidate)
+		}
+	}
+}
+
+func TestRecommendInstanceTypeWithCarbonObjective(t *testing.T) {
+	ctx := context.Background()
+
+	table, err := ParseCarbonIntensityTable([]byte(testCarbonTable))
+	if err != nil {
+		t.Fatalf("ParseCarbonIntensityTable failed: %v", err)
+	}
+
+	calc := NewCalculator(newCarbonTestClient())
+	optimizer := NewOptimizer(calc, nil, nil)
+	requirements := ResourceRequirements{MinCPU: 2, MinMemoryMB: 4096}
+
+	// Without a carbon intensity source the objective is ignored
+	requirements.Objective = CarbonObjectiveLowCarbon
+	rec, err := optimizer.RecommendInstanceType(ctx, requirements)
+	if err != nil {
+		t.Fatalf("RecommendInstanceType failed: %v", err)
+	}
+	if rec.OfferingID != "unknown-small" {
+		t.Errorf("Expected cheapest offering unknown-small, got %s", rec.OfferingID)
+	}
+
+	calc.SetCarbonIntensity(table)
+
+	rec, err = optimizer.RecommendInstanceType(ctx, requirements)
+	if err != nil {
+		t.Fatalf("RecommendInstanceType failed: %v", err)
+	}
+	if rec.OfferingID != "hydro-small" || rec.DatacenterID != "dc-hydro" {
+		t.Errorf("Expected hydro-small in dc-hydro, got %s in %s", rec.OfferingID, rec.DatacenterID)
+	}
+	if rec.EmissionsPerHour <= 0 {
+		t.Errorf("Expected estimated emissions, got %f", rec.EmissionsPerHour)
+	}
+
+	requirements.Objective = CarbonObjectiveCost
+	rec, err = optimizer.RecommendInstanceType(ctx, requirements)
+	if err != nil {
+		t.Fatalf("RecommendInstanceType failed: %v", err)
+	}
+	if rec.OfferingID != "unknown-small" {
+		t.Errorf("Expected cost objective to pick unknown-small, got %s", rec.OfferingID)
+	}
+}
@@ -0,0 +1,354 @@
+package cost
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	metricsutil "github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/client"
+)
+
+const (
+	// PriceCatalogConfigMapKey is the ConfigMap data key holding the price catalog document
+	PriceCatalogConfigMapKey = "catalog.yaml"
+
+	// PriceDriftTolerancePercent is the catalog vs. live price difference below which
+	// an offering is not reported as drifted
+	PriceDriftTolerancePercent = 1.0
+)
+
+// PricingSource identifies where an offering price came from
+type PricingSource string
+
+const (
+	// PricingSourceLive means the price was read from the live VPSie API
+	PricingSourceLive PricingSource = "live"
+
+	// PricingSourceCatalog means the price was read from the offline price catalog
+	PricingSourceCatalog PricingSource = "catalog"
+)
+
+// CatalogMode controls how the offline price catalog is combined with live offerings
+type CatalogMode string
+
+const (
+	// CatalogModeFallback uses the catalog only when the live API cannot be reached
+	CatalogModeFallback CatalogMode = "fallback"
+
+	// CatalogModeOverride prefers catalog prices over live prices for offerings it lists
+	CatalogModeOverride CatalogMode = "override"
+)
+
+// PriceCatalog is a versioned offline list of offering prices. Offerings use the
+// same schema as the VPSie offerings API so a catalog can be produced from an API dump.
+type PriceCatalog struct {
+	// Version identifies the catalog revision (e.g., "2024-06-01")
+	Version string `json:"version"`
+
+	// Currency is the currency the catalog prices are expressed in (default: USD)
+	Currency string `json:"currency,omitempty"`
+
+	// Offerings are the priced offerings
+	Offerings []client.Offering `json:"offerings"`
+}
+
+// PriceDrift describes the difference between a catalog price and the live price
+type PriceDrift struct {
+	OfferingID     string
+	CatalogMonthly float64
+	LiveMonthly    float64
+	DriftPercent   float64 // (catalog - live) / live * 100
+	MissingLive    bool    // Offering is in the catalog but not in the live API
+}
+
+// pricedOffering is an offering together with the source of its price
+type pricedOffering struct {
+	client.Offering
+	source PricingSource
+}
+
+// ParsePriceCatalog parses a price catalog from a YAML or JSON document
+func ParsePriceCatalog(data []byte) (*PriceCatalog, error) {
+	catalog := &PriceCatalog{}
+	if err := yaml.UnmarshalStrict(data, catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse price catalog: %w", err)
+	}
+
+	if err := catalog.Validate(); err != nil {
+		return nil, err
+	}
+
+	if catalog.Currency == "" {
+		catalog.Currency = "USD"
+	}
+
+	return catalog, nil
+}
+
+// LoadPriceCatalogFile loads a price catalog from a YAML or JSON file
+func LoadPriceCatalogFile(path string) (*PriceCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price catalog file %s: %w", path, err)
+	}
+	return ParsePriceCatalog(data)
+}
+
+// LoadPriceCatalogFromConfigMap loads a price catalog from the PriceCatalogConfigMapKey
+// entry of the given ConfigMap
+func LoadPriceCatalogFromConfigMap(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*PriceCatalog, error) {
+	cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price catalog ConfigMap %s/%s: %w", namespace, name, err)
+	}
+
+	data, ok := cm.Data[PriceCatalogConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("price catalog ConfigMap %s/%s has no %q key", namespace, name, PriceCatalogConfigMapKey)
+	}
+
+	return ParsePriceCatalog([]byte(data))
+}
+
+// Validate checks the price catalog for missing or invalid entries
+func (pc *PriceCatalog) Validate() error {
+	if pc.Version == "" {
+		return fmt.Errorf("price catalog must specify a version")
+	}
+
+	seen := make(map[string]bool)
+	for _, offering := range pc.Offerings {
+		if offering.ID == "" {
+			return fmt.Errorf("price catalog offering must specify an id")
+		}
+		if seen[offering.ID] {
+			return fmt.Errorf("price catalog lists offering %s more than once", offering.ID)
+		}
+		seen[offering.ID] = true
+
+		if offering.Price < 0 || offering.HourlyPrice < 0 {
+			return fmt.Errorf("price catalog offering %s has a negative price", offering.ID)
+		}
+	}
+
+	return nil
+}
+
+// Lookup returns the catalog entry for an offering
+func (pc *PriceCatalog) Lookup(offeringID string) (*client.Offering, bool) {
+	if pc == nil {
+		return nil, false
+	}
+	for i := range pc.Offerings {
+		if pc.Offerings[i].ID == offeringID {
+			return &pc.Offerings[i], true
+		}
+	}
+	return nil, false
+}
+
+// CompareLive compares catalog prices with live offerings and returns the offerings
+// whose price drifted beyond PriceDriftTolerancePercent or that no longer exist live
+func (pc *PriceCatalog) CompareLive(live []client.Offering) []PriceDrift {
+	if pc == nil {
+		return nil
+	}
+
+	liveByID := make(map[string]client.Offering, len(live))
+	for _, offering := range live {
+		liveByID[offering.ID] = offering
+	}
+
+	var drifts []PriceDrift
+	for _, entry := range pc.Offerings {
+		liveOffering, ok := liveByID[entry.ID]
+		if !ok {
+			drifts = append(drifts, PriceDrift{
+				OfferingID:     entry.ID,
+				CatalogMonthly: entry.Price,
+				MissingLive:    true,
+			})
+			continue
+		}
+
+		drift := PriceDrift{
+			OfferingID:     entry.ID,
+			CatalogMonthly: entry.Price,
+			LiveMonthly:    liveOffering.Price,
+		}
+		if liveOffering.Price > 0 {
+			drift.DriftPercent = (entry.Price - liveOffering.Price) / liveOffering.Price * 100
+		} else if entry.Price > 0 {
+			drift.DriftPercent = 100
+		}
+
+		if math.Abs(drift.DriftPercent) > PriceDriftTolerancePercent {
+			drifts = append(drifts, drift)
+		}
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		return drifts[i].OfferingID < drifts[j].OfferingID
+	})
+
+	return drifts
+}
+
+// SetPriceCatalog sets the offline price catalog and how it is combined with
+// live offerings. Pass nil to price from the live API only.
+func (c *Calculator) SetPriceCatalog(catalog *PriceCatalog, mode CatalogMode) {
+	c.pricingMu.Lock()
+	c.catalog = catalog
+	c.catalogMode = mode
+	c.pricingMu.Unlock()
+
+	// Cached live prices may now be overridden by the catalog
+	c.cache.clear()
+}
+
+// PriceCatalog returns the offline price catalog and its mode, or nil if none is set
+func (c *Calculator) PriceCatalog() (*PriceCatalog, CatalogMode) {
+	c.pricingMu.RLock()
+	defer c.pricingMu.RUnlock()
+	return c.catalog, c.catalogMode
+}
+
+// DetectPriceDrift compares the price catalog against live offerings, updates the
+// drift metrics and returns the drifted offerings
+func (c *Calculator) DetectPriceDrift(ctx context.Context) ([]PriceDrift, error) {
+	catalog, _ := c.PriceCatalog()
+	if catalog == nil {
+		return nil, nil
+	}
+
+	if c.client == nil {
+		return nil, fmt.Errorf("no VPSie client to compare the price catalog against")
+	}
+
+	live, err := c.client.ListOfferings(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offerings: %w", err)
+	}
+
+	return c.recordPriceDrift(catalog, live), nil
+}
+
+// ListOfferings returns all offerings with prices resolved from the live API and
+// the price catalog according to the catalog mode
+func (c *Calculator) ListOfferings(ctx context.Context) ([]client.Offering, error) {
+	priced, err := c.listOfferings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	offerings := make([]client.Offering, 0, len(priced))
+	for _, offering := range priced {
+		offerings = append(offerings, offering.Offering)
+	}
+	return offerings, nil
+}
+
+// listOfferings returns offerings priced from the live API and the price catalog
+// according to the catalog mode. A Calculator without a VPSie client prices from
+// the catalog only.
+func (c *Calculator) listOfferings(ctx context.Context) ([]pricedOffering, error) {
+	catalog, mode := c.PriceCatalog()
+
+	if c.client == nil {
+		if catalog == nil {
+			return nil, fmt.Errorf("no VPSie client or price catalog configured")
+		}
+		return catalogOfferings(catalog), nil
+	}
+
+	live, err := c.client.ListOfferings(ctx, nil)
+	if err != nil {
+		if catalog == nil {
+			return nil, fmt.Errorf("failed to list offerings: %w", err)
+		}
+		// Live API unavailable - serve the whole catalog regardless of mode
+		return catalogOfferings(catalog), nil
+	}
+
+	if catalog == nil {
+		return liveOfferings(live), nil
+	}
+
+	c.recordPriceDrift(catalog, live)
+
+	if mode != CatalogModeOverride {
+		return liveOfferings(live), nil
+	}
+
+	// Override mode: catalog prices replace live prices, live-only offerings are kept
+	result := catalogOfferings(catalog)
+	inCatalog := make(map[string]bool, len(result))
+	for _, offering := range result {
+		inCatalog[offering.ID] = true
+	}
+	for _, offering := range live {
+		if !inCatalog[offering.ID] {
+			result = append(result, pricedOffering{Offering: offering, source: PricingSourceLive})
+		}
+	}
+	return result, nil
+}
+
+func liveOfferings(live []client.Offering) []pricedOffering {
+	result := make([]pricedOffering, 0, len(live))
+	for _, offering := range live {
+		result = append(result, pricedOffering{Offering: offering, source: PricingSourceLive})
+	}
+	return result
+}
+
+func catalogOfferings(catalog *PriceCatalog) []pricedOffering {
+	result := make([]pricedOffering, 0, len(catalog.Offerings))
+	for _, offering := range catalog.Offerings {
+		result = append(result, pricedOffering{Offering: offering, source: PricingSourceCatalog})
+	}
+	return result
+}
+
+// recordPriceDrift updates the catalog drift metrics from a live offering list.
+// Only the label sets of offerings that no longer drift are removed, so the
+// gauge does not drop to zero between scrapes.
+func (c *Calculator) recordPriceDrift(catalog *PriceCatalog, live []client.Offering) []PriceDrift {
+	drifts := catalog.CompareLive(live)
+
+	c.pricingMu.Lock()
+	defer c.pricingMu.Unlock()
+
+	drifted := make(map[string]bool, len(drifts))
+	missing := 0
+	for _, drift := range drifts {
+		if drift.MissingLive {
+			missing++
+			continue
+		}
+		offering, _ := metricsutil.SanitizeLabel(drift.OfferingID)
+		metricsutil.PriceCatalogDriftPercent.WithLabelValues(offering).Set(drift.DriftPercent)
+		drifted[offering] = true
+	}
+	for offering := range c.driftedOfferings {
+		if !drifted[offering] {
+			metricsutil.PriceCatalogDriftPercent.DeleteLabelValues(offering)
+		}
+	}
+	c.driftedOfferings = drifted
+	metricsutil.PriceCatalogMissingOfferings.Set(float64(missing))
+
+	return drifts
+}
+
+// recordPricingSource records which price source served a cost lookup
+func recordPricingSource(source PricingSource, operation string) {
+	metricsutil.CostPricingSourceTotal.WithLabelValues(string(source), operation).Inc()
+}
@@ -0,0 +1,272 @@
+package cost
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	metricsutil "github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/client"
+)
+
+const testPriceCatalog = `
+version: "2024-06-01"
+offerings:
+  - id: small-1
+    name: Small Instance
+    cpu: 2
+    ram: 2048
+    disk: 50
+    price: 12.0
+    hourly_price: 0.018
+    available: true
+    category: standard
+  - id: retired-1
+    name: Retired Instance
+    cpu: 1
+    ram: 1024
+    disk: 25
+    price: 5.0
+    hourly_price: 0.007
+    available: true
+    category: standard
+`
+
+// unavailableClient simulates a VPSie API that cannot be reached
+type unavailableClient struct {
+	MockVPSieClient
+	err error
+}
+
+func (u *unavailableClient) ListOfferings(ctx context.Context, opts *client.ListOptions) ([]client.Offering, error) {
+	if u.err != nil {
+		return nil, u.err
+	}
+	return u.MockVPSieClient.ListOfferings(ctx, opts)
+}
+
+func newCatalogTestClient() *unavailableClient {
+	return &unavailableClient{
+		MockVPSieClient: MockVPSieClient{
+			offerings: []client.Offering{
+				{
+					ID:          "small-1",
+					Name:        "Small Instance",
+					CPU:         2,
+					RAM:         2048,
+					Disk:        50,
+					Price:       10.0,
+					HourlyPrice: 0.015,
+					Available:   true,
+					Category:    "standard",
+				},
+				{
+					ID:          "medium-1",
+					Name:        "Medium Instance",
+					CPU:         4,
+					RAM:         4096,
+					Disk:        80,
+					Price:       20.0,
+					HourlyPrice: 0.03,
+					Available:   true,
+					Category:    "standard",
+				},
+			},
+		},
+	}
+}
+
+func TestParsePriceCatalog(t *testing.T) {
+	catalog, err := ParsePriceCatalog([]byte(testPriceCatalog))
+	if err != nil {
+		t.Fatalf("ParsePriceCatalog failed: %v", err)
+	}
+
+	if catalog.Version != "2024-06-01" {
+		t.Errorf("Expected version 2024-06-01, got %s", catalog.Version)
+	}
+	if catalog.Currency != "USD" {
+		t.Errorf("Expected default currency USD, got %s", catalog.Currency)
+	}
+
+	offering, ok := catalog.Lookup("small-1")
+	if !ok || offering.Price != 12.0 || offering.RAM != 2048 {
+		t.Errorf("Unexpected catalog entry for small-1: %+v (found=%v)", offering, ok)
+	}
+
+	invalid := []string{
+		`offerings: [{id: small-1, price: 10}]`,
+		`{version: v1, offerings: [{id: small-1, price: -1}]}`,
+		`{version: v1, offerings: [{id: small-1}, {id: small-1}]}`,
+		`{version: v1, unknown: true}`,
+	}
+	for _, data := range invalid {
+		if _, err := ParsePriceCatalog([]byte(data)); err == nil {
+			t.Errorf("Expected error parsing %q, got nil", data)
+		}
+	}
+}
+
+func TestGetOfferingCostWithPriceCatalog(t *testing.T) {
+	ctx := context.Background()
+
+	catalog, err := ParsePriceCatalog([]byte(testPriceCatalog))
+	if err != nil {
+		t.Fatalf("ParsePriceCatalog failed: %v", err)
+	}
+
+	t.Run("Fallback mode uses live prices when the API is reachable", func(t *testing.T) {
+		calc := NewCalculator(newCatalogTestClient())
+		calc.SetPriceCatalog(catalog, CatalogModeFallback)
+
+		cost, err := calc.GetOfferingCost(ctx, "small-1")
+		if err != nil {
+			t.Fatalf("GetOfferingCost failed: %v", err)
+		}
+		if cost.Source != PricingSourceLive || cost.MonthlyCost != 10.0 {
+			t.Errorf("Expected live price 10.0, got %f from %s", cost.MonthlyCost, cost.Source)
+		}
+	})
+
+	t.Run("Fallback mode uses the catalog when the API is unavailable", func(t *testing.T) {
+		mockClient := newCatalogTestClient()
+		mockClient.err = errors.New("circuit breaker open")
+		calc := NewCalculator(mockClient)
+		calc.SetPriceCatalog(catalog, CatalogModeFallback)
+
+		cost, err := calc.GetOfferingCost(ctx, "small-1")
+		if err != nil {
+			t.Fatalf("GetOfferingCost failed: %v", err)
+		}
+		if cost.Source != PricingSourceCatalog || cost.MonthlyCost != 12.0 {
+			t.Errorf("Expected catalog price 12.0, got %f from %s", cost.MonthlyCost, cost.Source)
+		}
+
+		// Once the API recovers the live price is used again
+		mockClient.err = nil
+		cost, err = calc.GetOfferingCost(ctx, "small-1")
+		if err != nil {
+			t.Fatalf("GetOfferingCost failed: %v", err)
+		}
+		if cost.Source != PricingSourceLive {
+			t.Errorf("Expected live price after API recovery, got %s", cost.Source)
+		}
+	})
+
+	t.Run("API unavailable without catalog fails", func(t *testing.T) {
+		mockClient := newCatalogTestClient()
+		mockClient.err = errors.New("circuit breaker open")
+		calc := NewCalculator(mockClient)
+
+		if _, err := calc.GetOfferingCost(ctx, "small-1"); err == nil {
+			t.Error("Expected error without a price catalog, got nil")
+		}
+	})
+
+	t.Run("Override mode prefers catalog prices", func(t *testing.T) {
+		calc := NewCalculator(newCatalogTestClient())
+		calc.SetPriceCatalog(catalog, CatalogModeOverride)
+
+		cost, err := calc.GetOfferingCost(ctx, "small-1")
+		if err != nil {
+			t.Fatalf("GetOfferingCost failed: %v", err)
+		}
+		if cost.Source != PricingSourceCatalog || cost.MonthlyCost != 12.0 {
+			t.Errorf("Expected catalog price 12.0, got %f from %s", cost.MonthlyCost, cost.Source)
+		}
+
+		// Offerings missing from the catalog are still priced live
+		cost, err = calc.GetOfferingCost(ctx, "medium-1")
+		if err != nil {
+			t.Fatalf("GetOfferingCost failed: %v", err)
+		}
+		if cost.Source != PricingSourceLive {
+			t.Errorf("Expected live price for medium-1, got %s", cost.Source)
+		}
+	})
+
+	t.Run("FindCheapestOffering reports the pricing source", func(t *testing.T) {
+		mockClient := newCatalogTestClient()
+		mockClient.err = errors.New("circuit breaker open")
+		calc := NewCalculator(mockClient)
+		calc.SetPriceCatalog(catalog, CatalogModeFallback)
+
+		recommendation, err := calc.FindCheapestOffering(ctx, ResourceRequirements{MinCPU: 2, MinMemoryMB: 2048}, nil)
+		if err != nil {
+			t.Fatalf("FindCheapestOffering failed: %v", err)
+		}
+		if recommendation.OfferingID != "small-1" || recommendation.PricingSource != PricingSourceCatalog {
+			t.Errorf("Expected small-1 priced from catalog, got %s from %s",
+				recommendation.OfferingID, recommendation.PricingSource)
+		}
+	})
+}
+
+func TestDetectPriceDrift(t *testing.T) {
+	ctx := context.Background()
+
+	catalog, err := ParsePriceCatalog([]byte(testPriceCatalog))
+	if err != nil {
+		t.Fatalf("ParsePriceCatalog failed: %v", err)
+	}
+
+	calc := NewCalculator(newCatalogTestClient())
+
+	drifts, err := calc.DetectPriceDrift(ctx)
+	if err != nil || drifts != nil {
+		t.Fatalf("Expected no drift without a catalog, got %v (err=%v)", drifts, err)
+	}
+
+	calc.SetPriceCatalog(catalog, CatalogModeFallback)
+	drifts, err = calc.DetectPriceDrift(ctx)
+	if err != nil {
+		t.Fatalf("DetectPriceDrift failed: %v", err)
+	}
+
+	if len(drifts) != 2 {
+		t.Fatalf("Expected 2 drifted offerings, got %d: %+v", len(drifts), drifts)
+	}
+
+	if !drifts[0].MissingLive || drifts[0].OfferingID != "retired-1" {
+		t.Errorf("Expected retired-1 to be missing live, got %+v", drifts[0])
+	}
+
+	if drifts[1].OfferingID != "small-1" || !floatEquals(drifts[1].DriftPercent, 20.0) {
+		t.Errorf("Expected small-1 to drift by 20%%, got %+v", drifts[1])
+	}
+}
+
+func TestRecordPriceDrift(t *testing.T) {
+	metricsutil.PriceCatalogDriftPercent.Reset()
+
+	catalog, err := ParsePriceCatalog([]byte(testPriceCatalog))
+	if err != nil {
+		t.Fatalf("ParsePriceCatalog failed: %v", err)
+	}
+	live := newCatalogTestClient().offerings
+	calc := NewCalculator(nil)
+
+	// The gauge keeps its value across repeated checks
+	for i := 0; i < 2; i++ {
+		calc.recordPriceDrift(catalog, live)
+		if got := testutil.ToFloat64(metricsutil.PriceCatalogDriftPercent.WithLabelValues("small-1")); !floatEquals(got, 20.0) {
+			t.Errorf("Expected small-1 drift of 20%%, got %f", got)
+		}
+	}
+
+	// Another calculator only removes the label sets it set itself
+	matching := append([]client.Offering(nil), live...)
+	matching[0].Price = 12.0
+	NewCalculator(nil).recordPriceDrift(catalog, matching)
+	if got := testutil.CollectAndCount(metricsutil.PriceCatalogDriftPercent); got != 1 {
+		t.Errorf("Expected the small-1 drift series to be kept, got %d series", got)
+	}
+
+	// Once the live price matches the catalog the label set is removed
+	calc.recordPriceDrift(catalog, matching)
+	if got := testutil.CollectAndCount(metricsutil.PriceCatalogDriftPercent); got != 0 {
+		t.Errorf("Expected no drift series, got %d", got)
+	}
+}
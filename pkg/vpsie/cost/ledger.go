@@ -0,0 +1,358 @@
+package cost
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLedgerWindow is the default period measured before and after an
+	// optimization is applied to determine the realized savings
+	DefaultLedgerWindow = 7 * 24 * time.Hour
+
+	// MinLedgerSamples is the minimum number of snapshots required on each side
+	// of the applied time before an entry is verified
+	MinLedgerSamples = 3
+
+	// MinLedgerEntriesForFeedback is the number of verified entries of an
+	// optimization type required before confidence scores are adjusted
+	MinLedgerEntriesForFeedback = 3
+)
+
+// LedgerStatus represents the verification state of a ledger entry
+type LedgerStatus string
+
+const (
+	// LedgerStatusPending means the measurement window has not elapsed yet
+	LedgerStatusPending LedgerStatus = "pending"
+
+	// LedgerStatusVerified means actual savings were measured from snapshots
+	LedgerStatusVerified LedgerStatus = "verified"
+
+	// LedgerStatusInsufficientData means the window elapsed without enough snapshots
+	LedgerStatusInsufficientData LedgerStatus = "insufficient_data"
+)
+
+// LedgerEntry records an applied optimization and the savings it actually realized
+type LedgerEntry struct {
+	ID                      string           `json:"id"`
+	NodeGroupName           string           `json:"nodeGroup"`
+	Namespace               string           `json:"namespace"`
+	Type                    OptimizationType `json:"type"`
+	PredictedMonthlySavings float64          `json:"predictedMonthlySavings"`
+	PredictedConfidence     float64          `json:"predictedConfidence"`
+	AppliedAt               time.Time        `json:"appliedAt"`
+
+	// Measured when the entry is recorded, so the baseline survives restarts
+	// of the controller that lose in-memory snapshots
+	BaselineMonthly float64 `json:"baselineMonthly,omitempty"` // Average effective monthly cost before AppliedAt
+	BaselineSamples int     `json:"baselineSamples,omitempty"` // Snapshots BaselineMonthly was measured from
+
+	// Measured after the window has elapsed
+	Status                 LedgerStatus `json:"status"`
+	ActualMonthly          float64      `json:"actualMonthly,omitempty"` // Average effective monthly cost after AppliedAt
+	RealizedMonthlySavings float64      `json:"realizedMonthlySavings,omitempty"`
+	PredictionError        float64      `json:"predictionError,omitempty"` // (predicted - realized) / predicted, 0 is a perfect prediction
+	VerifiedAt             *time.Time   `json:"verifiedAt,omitempty"`
+}
+
+// LedgerStore persists ledger entries across controller restarts
+type LedgerStore interface {
+	// Load returns the stored entries, none if nothing was stored yet
+	Load(ctx context.Context) ([]LedgerEntry, error)
+
+	// Save replaces the stored entries
+	Save(ctx context.Context, entries []LedgerEntry) error
+}
+
+// SavingsLedger tracks applied optimizations and compares their predicted savings
+// with the NodeGroup cost measured from stored CostSnapshots
+type SavingsLedger struct {
+	storage CostStorage
+	store   LedgerStore
+	window  time.Duration
+	entries []*LedgerEntry
+	nextID  int
+	mu      sync.RWMutex
+}
+
+// NewSavingsLedger creates a savings ledger that measures cost over window on each
+// side of an applied optimization. A zero window uses DefaultLedgerWindow.
+func NewSavingsLedger(analyzer *Analyzer, window time.Duration) *SavingsLedger {
+	if window <= 0 {
+		window = DefaultLedgerWindow
+	}
+
+	var storage CostStorage
+	if analyzer != nil {
+		storage = analyzer.storage
+	}
+
+	return &SavingsLedger{
+		storage: storage,
+		window:  window,
+	}
+}
+
+// Window returns the period measured on each side of an applied optimization
+func (l *SavingsLedger) Window() time.Duration {
+	return l.window
+}
+
+// SetStore sets where entries are persisted. Call Load to restore the
+// entries stored by a previous run.
+func (l *SavingsLedger) SetStore(store LedgerStore) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.store = store
+}
+
+// Load restores the entries persisted in the ledger's store. Entries recorded
+// before Load are kept and renumbered after the stored ones.
+func (l *SavingsLedger) Load(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.store == nil {
+		return nil
+	}
+
+	stored, err := l.store.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load savings ledger: %w", err)
+	}
+
+	recorded := l.entries
+	l.entries = make([]*LedgerEntry, 0, len(stored)+len(recorded))
+	l.nextID = 0
+	for i := range stored {
+		entry := stored[i]
+		l.entries = append(l.entries, &entry)
+
+		// Continue numbering after the stored entries
+		if n, err := strconv.Atoi(entry.ID[strings.LastIndex(entry.ID, "-")+1:]); err == nil && n > l.nextID {
+			l.nextID = n
+		}
+	}
+
+	if len(recorded) == 0 {
+		return nil
+	}
+	for _, entry := range recorded {
+		l.nextID++
+		entry.ID = fmt.Sprintf("%s-%s-%d", entry.Namespace, entry.NodeGroupName, l.nextID)
+		l.entries = append(l.entries, entry)
+	}
+	return l.save(ctx)
+}
+
+// RecordApplied records that an optimization was applied to a NodeGroup. The
+// cost baseline is measured right away from the snapshots before appliedAt.
+// The entry is kept even if persisting it fails.
+func (l *SavingsLedger) RecordApplied(ctx context.Context, nodeGroup, namespace string, opportunity *Opportunity, appliedAt time.Time) (*LedgerEntry, error) {
+	if opportunity == nil {
+		return nil, fmt.Errorf("opportunity cannot be nil")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.nextID++
+	entry := &LedgerEntry{
+		ID:                      fmt.Sprintf("%s-%s-%d", namespace, nodeGroup, l.nextID),
+		NodeGroupName:           nodeGroup,
+		Namespace:               namespace,
+		Type:                    opportunity.Type,
+		PredictedMonthlySavings: opportunity.MonthlySavings,
+		PredictedConfidence:     opportunity.ConfidenceScore,
+		AppliedAt:               appliedAt,
+		Status:                  LedgerStatusPending,
+	}
+
+	if l.storage != nil {
+		before, err := l.baselineSnapshots(ctx, entry)
+		if err == nil && len(before) >= MinLedgerSamples {
+			entry.BaselineMonthly = averageEffectiveMonthly(before)
+			entry.BaselineSamples = len(before)
+		}
+	}
+
+	l.entries = append(l.entries, entry)
+
+	copied := *entry
+	return &copied, l.save(ctx)
+}
+
+// Verify measures actual savings for pending entries whose window has elapsed
+// and returns the entries that changed state
+func (l *SavingsLedger) Verify(ctx context.Context, now time.Time) ([]LedgerEntry, error) {
+	if l.storage == nil {
+		return nil, fmt.Errorf("savings ledger has no cost storage")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var updated []LedgerEntry
+	for _, entry := range l.entries {
+		if entry.Status != LedgerStatusPending || now.Before(entry.AppliedAt.Add(l.window)) {
+			continue
+		}
+
+		// Prefer the baseline measured when the entry was recorded
+		baselineMonthly, baselineSamples := entry.BaselineMonthly, entry.BaselineSamples
+		if baselineSamples < MinLedgerSamples {
+			before, err := l.baselineSnapshots(ctx, entry)
+			if err != nil {
+				return updated, fmt.Errorf("failed to get snapshots before %s: %w", entry.ID, err)
+			}
+			baselineMonthly, baselineSamples = averageEffectiveMonthly(before), len(before)
+		}
+
+		after, err := l.storage.GetSnapshots(ctx, entry.NodeGroupName, entry.Namespace,
+			entry.AppliedAt, entry.AppliedAt.Add(l.window))
+		if err != nil {
+			return updated, fmt.Errorf("failed to get snapshots after %s: %w", entry.ID, err)
+		}
+
+		verifiedAt := now
+		entry.VerifiedAt = &verifiedAt
+
+		if baselineSamples < MinLedgerSamples || len(after) < MinLedgerSamples {
+			entry.Status = LedgerStatusInsufficientData
+			updated = append(updated, *entry)
+			continue
+		}
+
+		entry.BaselineMonthly = baselineMonthly
+		entry.BaselineSamples = baselineSamples
+		entry.ActualMonthly = averageEffectiveMonthly(after)
+		entry.RealizedMonthlySavings = entry.BaselineMonthly - entry.ActualMonthly
+		entry.PredictionError = predictionError(entry.PredictedMonthlySavings, entry.RealizedMonthlySavings)
+		entry.Status = LedgerStatusVerified
+		updated = append(updated, *entry)
+	}
+
+	if len(updated) == 0 {
+		return nil, nil
+	}
+	return updated, l.save(ctx)
+}
+
+// baselineSnapshots returns the snapshots of the window before an entry was applied
+func (l *SavingsLedger) baselineSnapshots(ctx context.Context, entry *LedgerEntry) ([]*CostSnapshot, error) {
+	return l.storage.GetSnapshots(ctx, entry.NodeGroupName, entry.Namespace,
+		entry.AppliedAt.Add(-l.window), entry.AppliedAt.Add(-time.Nanosecond))
+}
+
+// save persists the entries to the ledger's store. The caller must hold l.mu.
+func (l *SavingsLedger) save(ctx context.Context) error {
+	if l.store == nil {
+		return nil
+	}
+
+	entries := make([]LedgerEntry, 0, len(l.entries))
+	for _, entry := range l.entries {
+		entries = append(entries, *entry)
+	}
+	if err := l.store.Save(ctx, entries); err != nil {
+		return fmt.Errorf("failed to persist savings ledger: %w", err)
+	}
+	return nil
+}
+
+// Entries returns a copy of all ledger entries
+func (l *SavingsLedger) Entries() []LedgerEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	result := make([]LedgerEntry, 0, len(l.entries))
+	for _, entry := range l.entries {
+		result = append(result, *entry)
+	}
+	return result
+}
+
+// Accuracy returns the mean prediction accuracy (0-1) of verified entries for an
+// optimization type. Returns false until MinLedgerEntriesForFeedback entries exist.
+func (l *SavingsLedger) Accuracy(optimizationType OptimizationType) (float64, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var totalError float64
+	var count int
+	for _, entry := range l.entries {
+		if entry.Status != LedgerStatusVerified || entry.Type != optimizationType {
+			continue
+		}
+		// Over-delivering is not penalised, under-delivering is capped at a full miss
+		totalError += math.Min(math.Max(entry.PredictionError, 0), 1)
+		count++
+	}
+
+	if count < MinLedgerEntriesForFeedback {
+		return 0, false
+	}
+	return 1 - totalError/float64(count), true
+}
+
+// AdjustConfidence scales an opportunity's confidence score by the measured
+// accuracy of past predictions of the same optimization type
+func (l *SavingsLedger) AdjustConfidence(opportunity *Opportunity) {
+	if l == nil || opportunity == nil {
+		return
+	}
+
+	accuracy, ok := l.Accuracy(opportunity.Type)
+	if !ok {
+		return
+	}
+	opportunity.ConfidenceScore *= accuracy
+}
+
+// Prune removes verified and insufficient-data entries applied before the given time
+func (l *SavingsLedger) Prune(ctx context.Context, before time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	kept := l.entries[:0]
+	for _, entry := range l.entries {
+		if entry.Status == LedgerStatusPending || !entry.AppliedAt.Before(before) {
+			kept = append(kept, entry)
+		}
+	}
+	if len(kept) == len(l.entries) {
+		return nil
+	}
+	l.entries = kept
+	return l.save(ctx)
+}
+
+// averageEffectiveMonthly returns the average effective monthly cost of snapshots
+func averageEffectiveMonthly(snapshots []*CostSnapshot) float64 {
+	if len(snapshots) == 0 {
+		return 0
+	}
+
+	var total float64
+	for _, snapshot := range snapshots {
+		total += snapshot.Cost.effectiveMonthly()
+	}
+	return total / float64(len(snapshots))
+}
+
+// predictionError returns the relative error of a savings prediction
+func predictionError(predicted, realized float64) float64 {
+	if predicted == 0 {
+		if realized == 0 {
+			return 0
+		}
+		return -1
+	}
+	return (predicted - realized) / math.Abs(predicted)
+}
@@ -0,0 +1,90 @@
+package cost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LedgerConfigMapKey is the ConfigMap data key holding the savings ledger entries
+const LedgerConfigMapKey = "ledger.json"
+
+// ConfigMapLedgerStore persists savings ledger entries as JSON in a ConfigMap.
+// The ConfigMap is created on the first save.
+type ConfigMapLedgerStore struct {
+	clientset kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewConfigMapLedgerStore creates a ledger store backed by the named ConfigMap
+func NewConfigMapLedgerStore(clientset kubernetes.Interface, namespace, name string) *ConfigMapLedgerStore {
+	return &ConfigMapLedgerStore{
+		clientset: clientset,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// Load returns the entries stored in the ConfigMap, none if it does not exist
+func (s *ConfigMapLedgerStore) Load(ctx context.Context) ([]LedgerEntry, error) {
+	cm, err := s.clientset.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get savings ledger ConfigMap %s/%s: %w", s.namespace, s.name, err)
+	}
+
+	data, ok := cm.Data[LedgerConfigMapKey]
+	if !ok || data == "" {
+		return nil, nil
+	}
+
+	var entries []LedgerEntry
+	if err := json.Unmarshal([]byte(data), &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse savings ledger ConfigMap %s/%s: %w", s.namespace, s.name, err)
+	}
+	return entries, nil
+}
+
+// Save replaces the entries stored in the ConfigMap
+func (s *ConfigMapLedgerStore) Save(ctx context.Context, entries []LedgerEntry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode savings ledger: %w", err)
+	}
+
+	configMaps := s.clientset.CoreV1().ConfigMaps(s.namespace)
+	cm, err := configMaps.Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.name,
+				Namespace: s.namespace,
+			},
+			Data: map[string]string{LedgerConfigMapKey: string(data)},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create savings ledger ConfigMap %s/%s: %w", s.namespace, s.name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get savings ledger ConfigMap %s/%s: %w", s.namespace, s.name, err)
+	}
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[LedgerConfigMapKey] = string(data)
+	if _, err := configMaps.Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update savings ledger ConfigMap %s/%s: %w", s.namespace, s.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package cost
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// recordLedgerSnapshots stores hourly snapshots with a fixed monthly cost
+func recordLedgerSnapshots(t *testing.T, storage CostStorage, start time.Time, hours int, monthly float64) {
+	t.Helper()
+	for i := 0; i < hours; i++ {
+		err := storage.RecordSnapshot(context.Background(), &CostSnapshot{
+			Timestamp:     start.Add(time.Duration(i) * time.Hour),
+			NodeGroupName: "test-group",
+			Namespace:     "default",
+			Cost:          NodeGroupCost{TotalMonthly: monthly},
+		})
+		if err != nil {
+			t.Fatalf("RecordSnapshot failed: %v", err)
+		}
+	}
+}
+
+func TestSavingsLedgerVerify(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryCostStorage()
+	analyzer := NewAnalyzer(NewCalculator(&MockVPSieClient{}), storage)
+	window := 24 * time.Hour
+	ledger := NewSavingsLedger(analyzer, window)
+
+	appliedAt := time.Now().Add(-48 * time.Hour).Truncate(time.Hour)
+	recordLedgerSnapshots(t, storage, appliedAt.Add(-window), 24, 100.0)
+	recordLedgerSnapshots(t, storage, appliedAt, 24, 70.0)
+
+	entry, err := ledger.RecordApplied(ctx, "test-group", "default", &Opportunity{
+		Type:            OptimizationDownsize,
+		MonthlySavings:  40.0,
+		ConfidenceScore: 0.8,
+	}, appliedAt)
+	if err != nil {
+		t.Fatalf("RecordApplied failed: %v", err)
+	}
+	if entry.Status != LedgerStatusPending {
+		t.Errorf("Expected pending entry, got %s", entry.Status)
+	}
+
+	t.Run("Window not elapsed", func(t *testing.T) {
+		updated, err := ledger.Verify(ctx, appliedAt.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+		if len(updated) != 0 {
+			t.Errorf("Expected no entries verified before the window elapsed, got %d", len(updated))
+		}
+	})
+
+	t.Run("Window elapsed", func(t *testing.T) {
+		updated, err := ledger.Verify(ctx, appliedAt.Add(window))
+		if err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+		if len(updated) != 1 {
+			t.Fatalf("Expected 1 verified entry, got %d", len(updated))
+		}
+
+		verified := updated[0]
+		if verified.Status != LedgerStatusVerified {
+			t.Errorf("Expected verified status, got %s", verified.Status)
+		}
+		if !floatEquals(verified.RealizedMonthlySavings, 30.0) {
+			t.Errorf("Expected realized savings 30.0, got %f", verified.RealizedMonthlySavings)
+		}
+		if !floatEquals(verified.PredictionError, 0.25) {
+			t.Errorf("Expected prediction error 0.25, got %f", verified.PredictionError)
+		}
+	})
+
+	t.Run("Insufficient data", func(t *testing.T) {
+		_, err := ledger.RecordApplied(ctx, "test-group", "default", &Opportunity{
+			Type:           OptimizationDownsize,
+			MonthlySavings: 10.0,
+		}, appliedAt.Add(-10*24*time.Hour))
+		if err != nil {
+			t.Fatalf("RecordApplied failed: %v", err)
+		}
+
+		updated, err := ledger.Verify(ctx, time.Now())
+		if err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+		if len(updated) != 1 || updated[0].Status != LedgerStatusInsufficientData {
+			t.Errorf("Expected 1 entry with insufficient data, got %+v", updated)
+		}
+	})
+}
+
+func TestSavingsLedgerAdjustConfidence(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryCostStorage()
+	analyzer := NewAnalyzer(NewCalculator(&MockVPSieClient{}), storage)
+	window := 24 * time.Hour
+	ledger := NewSavingsLedger(analyzer, window)
+
+	appliedAt := time.Now().Add(-48 * time.Hour).Truncate(time.Hour)
+	recordLedgerSnapshots(t, storage, appliedAt.Add(-window), 24, 100.0)
+	recordLedgerSnapshots(t, storage, appliedAt, 24, 80.0)
+
+	opportunity := &Opportunity{Type: OptimizationRightSize, MonthlySavings: 40.0, ConfidenceScore: 0.8}
+
+	// Not enough history yet - confidence is unchanged
+	ledger.AdjustConfidence(opportunity)
+	if opportunity.ConfidenceScore != 0.8 {
+		t.Errorf("Expected unchanged confidence 0.8, got %f", opportunity.ConfidenceScore)
+	}
+
+	for i := 0; i < MinLedgerEntriesForFeedback; i++ {
+		if _, err := ledger.RecordApplied(ctx, "test-group", "default", opportunity, appliedAt); err != nil {
+			t.Fatalf("RecordApplied failed: %v", err)
+		}
+	}
+	if _, err := ledger.Verify(ctx, appliedAt.Add(window)); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+
+	// Predicted 40, realized 20: half the predicted savings
+	accuracy, ok := ledger.Accuracy(OptimizationRightSize)
+	if !ok || !floatEquals(accuracy, 0.5) {
+		t.Fatalf("Expected accuracy 0.5, got %f (ok=%v)", accuracy, ok)
+	}
+
+	ledger.AdjustConfidence(opportunity)
+	if !floatEquals(opportunity.ConfidenceScore, 0.4) {
+		t.Errorf("Expected adjusted confidence 0.4, got %f", opportunity.ConfidenceScore)
+	}
+
+	// Other optimization types are unaffected
+	other := &Opportunity{Type: OptimizationDownsize, ConfidenceScore: 0.9}
+	ledger.AdjustConfidence(other)
+	if other.ConfidenceScore != 0.9 {
+		t.Errorf("Expected unchanged confidence for other type, got %f", other.ConfidenceScore)
+	}
+
+	// A nil ledger leaves confidence untouched
+	var nilLedger *SavingsLedger
+	nilLedger.AdjustConfidence(other)
+	if other.ConfidenceScore != 0.9 {
+		t.Errorf("Expected unchanged confidence with nil ledger, got %f", other.ConfidenceScore)
+	}
+}
+
+func TestSavingsLedgerPersistence(t *testing.T) {
+	ctx := context.Background()
+	store := NewConfigMapLedgerStore(fake.NewSimpleClientset(), "kube-system", "savings-ledger")
+	window := 24 * time.Hour
+
+	storage := NewMemoryCostStorage()
+	ledger := NewSavingsLedger(NewAnalyzer(NewCalculator(&MockVPSieClient{}), storage), window)
+	ledger.SetStore(store)
+
+	appliedAt := time.Now().Add(-48 * time.Hour).Truncate(time.Hour)
+	recordLedgerSnapshots(t, storage, appliedAt.Add(-window), 24, 100.0)
+
+	entry, err := ledger.RecordApplied(ctx, "test-group", "default", &Opportunity{
+		Type:           OptimizationScaleDown,
+		MonthlySavings: 30.0,
+	}, appliedAt)
+	if err != nil {
+		t.Fatalf("RecordApplied failed: %v", err)
+	}
+	if entry.BaselineSamples != 24 || !floatEquals(entry.BaselineMonthly, 100.0) {
+		t.Errorf("Expected baseline 100.0 from 24 snapshots, got %f from %d", entry.BaselineMonthly, entry.BaselineSamples)
+	}
+
+	// A restarted controller has lost the snapshots before the entry
+	restartedStorage := NewMemoryCostStorage()
+	restarted := NewSavingsLedger(NewAnalyzer(NewCalculator(&MockVPSieClient{}), restartedStorage), window)
+	restarted.SetStore(store)
+	if err := restarted.Load(ctx); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	entries := restarted.Entries()
+	if len(entries) != 1 || entries[0].ID != entry.ID || !entries[0].AppliedAt.Equal(appliedAt) {
+		t.Fatalf("Expected the recorded entry to be restored, got %+v", entries)
+	}
+
+	recordLedgerSnapshots(t, restartedStorage, appliedAt, 24, 70.0)
+	updated, err := restarted.Verify(ctx, appliedAt.Add(window))
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(updated) != 1 || updated[0].Status != LedgerStatusVerified || !floatEquals(updated[0].RealizedMonthlySavings, 30.0) {
+		t.Fatalf("Expected verified savings of 30.0 from the stored baseline, got %+v", updated)
+	}
+
+	// New entries continue the numbering of the stored ones
+	next, err := restarted.RecordApplied(ctx, "test-group", "default", &Opportunity{Type: OptimizationScaleDown}, time.Now())
+	if err != nil {
+		t.Fatalf("RecordApplied failed: %v", err)
+	}
+	if next.ID == entry.ID {
+		t.Errorf("Expected a new entry ID, got %s again", next.ID)
+	}
+
+	// Pruning old verified entries is persisted too
+	if err := restarted.Prune(ctx, appliedAt.Add(time.Hour)); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	stored, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(stored) != 1 || stored[0].ID != next.ID {
+		t.Errorf("Expected only the pending entry to be stored, got %+v", stored)
+	}
+}
@@ -10,11 +10,12 @@ import (
 // Metrics holds all Prometheus metrics for cost optimization
 type Metrics struct {
 	// Cost metrics
-	NodeGroupCostHourly  *prometheus.GaugeVec
-	NodeGroupCostMonthly *prometheus.GaugeVec
-	CostPerNode          *prometheus.GaugeVec
-	CostPerCPUCore       *prometheus.GaugeVec
-	CostPerGBMemory      *prometheus.GaugeVec
+	NodeGroupCostHourly           *prometheus.GaugeVec
+	NodeGroupCostMonthly          *prometheus.GaugeVec
+	NodeGroupCostEffectiveMonthly *prometheus.GaugeVec
+	CostPerNode                   *prometheus.GaugeVec
+	CostPerCPUCore                *prometheus.GaugeVec
+	CostPerGBMemory               *prometheus.GaugeVec
 
 	// Optimization metrics
 	OptimizationOpportunities *prometheus.GaugeVec
@@ -22,6 +23,7 @@ type Metrics struct {
 	OptimizationsApplied      *prometheus.CounterVec
 	OptimizationsFailed       *prometheus.CounterVec
 	SavingsRealizedMonthly    *prometheus.GaugeVec
+	SavingsPredictionError    *prometheus.GaugeVec
 
 	// Utilization metrics
 	ResourceUtilizationCPU    *prometheus.GaugeVec
@@ -67,6 +69,14 @@ func NewMetrics(registry prometheus.Registerer) *Metrics {
 			[]string{"nodegroup", "namespace", "datacenter"},
 		),
 
+		NodeGroupCostEffectiveMonthly: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "vpsie_nodegroup_cost_effective_monthly",
+				Help: "Monthly cost of the NodeGroup in USD after pricing plan discounts and commitments",
+			},
+			[]string{"nodegroup", "namespace", "datacenter"},
+		),
+
 		CostPerNode: factory.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "vpsie_nodegroup_cost_per_node",
@@ -132,6 +142,14 @@ func NewMetrics(registry prometheus.Registerer) *Metrics {
 			[]string{"nodegroup", "namespace"},
 		),
 
+		SavingsPredictionError: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "vpsie_cost_savings_prediction_error_ratio",
+				Help: "Relative error between predicted and realized monthly savings of the last verified optimization (0 = exact)",
+			},
+			[]string{"nodegroup", "namespace", "type"},
+		),
+
 		// Utilization metrics
 		ResourceUtilizationCPU: factory.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -235,6 +253,7 @@ func (m *Metrics) RecordCost(nodeGroup, namespace, datacenter string, cost *Node
 
 	m.NodeGroupCostHourly.WithLabelValues(nodeGroup, namespace, datacenter).Set(cost.TotalHourly)
 	m.NodeGroupCostMonthly.WithLabelValues(nodeGroup, namespace, datacenter).Set(cost.TotalMonthly)
+	m.NodeGroupCostEffectiveMonthly.WithLabelValues(nodeGroup, namespace, datacenter).Set(cost.effectiveMonthly())
 	m.CostPerNode.WithLabelValues(nodeGroup, namespace).Set(cost.CostPerNode)
 }
 
@@ -365,3 +384,18 @@ func (m *Metrics) RecordSavingsRealized(nodeGroup, namespace string, savings flo
 
 	m.SavingsRealizedMonthly.WithLabelValues(nodeGroup, namespace).Set(savings)
 }
+
+// RecordLedgerEntry records the realized savings and prediction error of a verified ledger entry
+func (m *Metrics) RecordLedgerEntry(entry *LedgerEntry) {
+	if entry == nil || entry.Status != LedgerStatusVerified {
+		return
+	}
+
+	// Sanitize label values to prevent cardinality explosion
+	nodeGroup, _ := metricsutil.SanitizeLabel(entry.NodeGroupName)
+	namespace, _ := metricsutil.SanitizeLabel(entry.Namespace)
+	optType, _ := metricsutil.SanitizeLabel(string(entry.Type))
+
+	m.SavingsRealizedMonthly.WithLabelValues(nodeGroup, namespace).Set(entry.RealizedMonthlySavings)
+	m.SavingsPredictionError.WithLabelValues(nodeGroup, namespace, optType).Set(entry.PredictionError)
+}
@@ -10,11 +10,22 @@ import (
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/client"
 )
 
+const (
+	// CommittedBaselineWindow is how far back snapshots are inspected to find the
+	// steady node baseline that is safe to convert to committed pricing
+	CommittedBaselineWindow = 30 * 24 * time.Hour
+
+	// MinCommittedBaselineSamples is the minimum number of snapshots required
+	// before the observed history is trusted over MinNodes
+	MinCommittedBaselineSamples = 24
+)
+
 // Optimizer analyzes NodeGroups and recommends cost optimizations
 type Optimizer struct {
 	calculator *Calculator
 	analyzer   *Analyzer
 	client     client.VPSieClient
+	ledger     *SavingsLedger
 }
 
 // NewOptimizer creates a new cost optimizer
@@ -26,6 +37,12 @@ func NewOptimizer(calculator *Calculator, analyzer *Analyzer, client client.VPSi
 	}
 }
 
+// SetSavingsLedger sets the ledger whose measured prediction accuracy is used
+// to adjust the confidence score of new opportunities
+func (o *Optimizer) SetSavingsLedger(ledger *SavingsLedger) {
+	o.ledger = ledger
+}
+
 // AnalyzeOptimizations identifies optimization opportunities for a NodeGroup
 func (o *Optimizer) AnalyzeOptimizations(ctx context.Context, nodeGroup *v1alpha1.NodeGroup) (*OptimizationReport, error) {
 	if nodeGroup == nil {
@@ -84,6 +101,18 @@ func (o *Optimizer) AnalyzeOptimizations(ctx context.Context, nodeGroup *v1alpha
 		}
 	}
 
+	// Check for committed pricing on the steady baseline of nodes
+	opp, err = o.analyzeCommittedPricing(ctx, nodeGroup, currentCost)
+	if err == nil && opp != nil {
+		opportunities = append(opportunities, *opp)
+		totalSavings += opp.MonthlySavings
+	}
+
+	// Temper confidence with how well past predictions of each type held up
+	for i := range opportunities {
+		o.ledger.AdjustConfidence(&opportunities[i])
+	}
+
 	// Sort opportunities by savings (descending)
 	sort.Slice(opportunities, func(i, j int) bool {
 		return opportunities[i].MonthlySavings > opportunities[j].MonthlySavings
@@ -117,7 +146,7 @@ func (o *Optimizer) analyzeDownsizing(ctx context.Context, nodeGroup *v1alpha1.N
 	currentCost *NodeGroupCost, utilization *UtilizationAnalysis) (*Opportunity, error) {
 
 	// Get all available offerings
-	offerings, err := o.client.ListOfferings(ctx, nil)
+	offerings, err := o.calculator.ListOfferings(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list offerings: %w", err)
 	}
@@ -290,7 +319,7 @@ func (o *Optimizer) analyzeCategoryOptimization(ctx context.Context, nodeGroup *
 	}
 
 	// Find offerings in target category
-	offerings, err := o.client.ListOfferings(ctx, nil)
+	offerings, err := o.calculator.ListOfferings(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -375,7 +404,7 @@ func (o *Optimizer) analyzeConsolidation(ctx context.Context, nodeGroup *v1alpha
 	requiredMemory := int(float64(totalMemory) * (utilization.PeakUtilization.MemoryPercent / 100) * 1.2)
 
 	// Find larger instances that can fit the workload
-	offerings, err := o.client.ListOfferings(ctx, nil)
+	offerings, err := o.calculator.ListOfferings(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -411,10 +440,17 @@ func (o *Optimizer) analyzeConsolidation(ctx context.Context, nodeGroup *v1alpha
 			continue
 		}
 
-		// Calculate savings
-		newCost := offering.Price * float64(requiredNodes)
-		oldCost := currentCost.TotalMonthly
-		savings := oldCost - newCost
+		// Calculate savings, with the pricing plan applied on both sides
+		newCost, err := o.calculator.effectiveReplacementCost(ctx, nodeGroup, &OfferingCost{
+			OfferingID:  offering.ID,
+			Name:        offering.Name,
+			MonthlyCost: offering.Price,
+			Category:    offering.Category,
+		}, int32(requiredNodes))
+		if err != nil {
+			return nil, err
+		}
+		savings := currentCost.effectiveMonthly() - newCost
 
 		if savings > 0 {
 			options = append(options, consolidationOption{
@@ -456,6 +492,98 @@ func (o *Optimizer) analyzeConsolidation(ctx context.Context, nodeGroup *v1alpha
 	}, nil
 }
 
+// analyzeCommittedPricing checks if converting the steady baseline of nodes to
+// committed pricing would save costs under the calculator's pricing plan
+func (o *Optimizer) analyzeCommittedPricing(ctx context.Context, nodeGroup *v1alpha1.NodeGroup,
+	currentCost *NodeGroupCost) (*Opportunity, error) {
+
+	plan := o.calculator.PricingPlan()
+	if plan == nil || currentCost.TotalNodes == 0 {
+		return nil, nil
+	}
+
+	// Commit only the dominant offering; mixed groups keep the rest on-demand
+	var offeringID string
+	var offeringCount int32
+	for id, instanceCost := range currentCost.InstanceTypes {
+		if instanceCost.Count > offeringCount || (instanceCost.Count == offeringCount && id < offeringID) {
+			offeringID = id
+			offeringCount = instanceCost.Count
+		}
+	}
+
+	baseline, fromHistory := o.steadyBaseline(ctx, nodeGroup)
+	if baseline > offeringCount {
+		baseline = offeringCount
+	}
+	uncommitted := baseline - currentCost.InstanceTypes[offeringID].CommittedCount
+	if uncommitted <= 0 {
+		return nil, nil
+	}
+
+	offering, err := o.calculator.GetOfferingCost(ctx, offeringID)
+	if err != nil {
+		return nil, err
+	}
+
+	termMonths, committedMonthly, ok := plan.BestCommittedTerm(offering)
+	if !ok {
+		return nil, nil
+	}
+
+	savings := (plan.OnDemandMonthly(offering) - committedMonthly) * float64(uncommitted)
+	if savings <= 0 {
+		return nil, nil
+	}
+
+	// A baseline observed over time is more trustworthy than MinNodes alone
+	confidence := 0.7
+	if fromHistory {
+		confidence = 0.85
+	}
+
+	return &Opportunity{
+		Type:                OptimizationReserved,
+		Description:         fmt.Sprintf("Commit %d steady-state %s nodes for %d months", uncommitted, offering.Name, termMonths),
+		CurrentOffering:     offeringID,
+		RecommendedOffering: offeringID,
+		MonthlySavings:      savings,
+		AnnualSavings:       savings * 12,
+		ConfidenceScore:     confidence,
+		Risk:                RiskLow,
+		PerformanceImpact:   "None - billing change only",
+		Implementation:      fmt.Sprintf("Purchase a %d-month commitment for %d nodes and add it to the pricing plan", termMonths, uncommitted),
+	}, nil
+}
+
+// steadyBaseline returns the number of nodes the NodeGroup has never dropped
+// below over the committed baseline window. Falls back to MinNodes when no
+// history is recorded. The second return value reports whether history was used.
+func (o *Optimizer) steadyBaseline(ctx context.Context, nodeGroup *v1alpha1.NodeGroup) (int32, bool) {
+	baseline := nodeGroup.Spec.MinNodes
+	if o.analyzer == nil {
+		return baseline, false
+	}
+
+	end := time.Now()
+	snapshots, err := o.analyzer.storage.GetSnapshots(ctx, nodeGroup.Name, nodeGroup.Namespace, end.Add(-CommittedBaselineWindow), end)
+	if err != nil || len(snapshots) < MinCommittedBaselineSamples {
+		return baseline, false
+	}
+
+	historyMin := snapshots[0].Cost.TotalNodes
+	for _, snapshot := range snapshots[1:] {
+		if snapshot.Cost.TotalNodes < historyMin {
+			historyMin = snapshot.Cost.TotalNodes
+		}
+	}
+
+	if historyMin > baseline {
+		baseline = historyMin
+	}
+	return baseline, true
+}
+
 // SimulateOptimization simulates the impact of applying an optimization
 func (o *Optimizer) SimulateOptimization(ctx context.Context, optimization *Optimization) (*SimulationResult, error) {
 	if optimization == nil {
@@ -555,7 +683,12 @@ func (o *Optimizer) SimulateOptimization(ctx context.Context, optimization *Opti
 	}, nil
 }
 
-// RecommendInstanceType recommends the optimal instance type for given requirements
+// RecommendInstanceType recommends the optimal instance type for given requirements.
+// When requirements.Objective weighs carbon and a carbon intensity source is
+// configured, emissions are weighed against price.
 func (o *Optimizer) RecommendInstanceType(ctx context.Context, requirements ResourceRequirements) (*Recommendation, error) {
+	if requirements.Objective.CarbonWeight() > 0 && o.calculator.CarbonIntensity() != nil {
+		return o.calculator.FindLowestImpactOffering(ctx, requirements, nil)
+	}
 	return o.calculator.FindCheapestOffering(ctx, requirements, nil)
 }
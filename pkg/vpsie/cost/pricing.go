@@ -0,0 +1,307 @@
+package cost
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// PricingPlanConfigMapKey is the ConfigMap data key holding the pricing plan document
+	PricingPlanConfigMapKey = "pricing.yaml"
+
+	// hoursPerMonth is the average number of hours in a month used for cost projections
+	hoursPerMonth = 730
+)
+
+// PricingModel represents how a node is billed
+type PricingModel string
+
+const (
+	// PricingOnDemand bills the node at the (optionally discounted) list price
+	PricingOnDemand PricingModel = "on_demand"
+
+	// PricingCommitted bills the node at a committed monthly price for a contract term
+	PricingCommitted PricingModel = "committed"
+)
+
+// PricingPlan describes negotiated pricing that differs from the VPSie list price.
+// It is typically loaded from a ConfigMap so billing agreements can change without
+// a controller rebuild.
+type PricingPlan struct {
+	// Currency is the currency the plan prices are expressed in (default: USD)
+	Currency string `json:"currency,omitempty"`
+
+	// CategoryDiscounts maps an offering category to an on-demand discount percentage (0-100)
+	CategoryDiscounts map[string]float64 `json:"categoryDiscounts,omitempty"`
+
+	// ContractTerms are the committed contract terms that can be purchased
+	ContractTerms []ContractTerm `json:"contractTerms,omitempty"`
+
+	// CommittedPrices are explicit committed monthly prices per offering and term.
+	// They take precedence over the generic ContractTerms discount.
+	CommittedPrices []CommittedPrice `json:"committedPrices,omitempty"`
+
+	// Commitments are the commitments that are currently held for NodeGroups
+	Commitments []Commitment `json:"commitments,omitempty"`
+}
+
+// ContractTerm is a committed pricing contract length with its discount
+type ContractTerm struct {
+	// Name is a human-readable name for the term (e.g., "1-year")
+	Name string `json:"name"`
+
+	// Months is the length of the contract in months
+	Months int `json:"months"`
+
+	// DiscountPercent is the discount off the list monthly price (0-100)
+	DiscountPercent float64 `json:"discountPercent"`
+}
+
+// CommittedPrice is an explicit committed monthly price for an offering
+type CommittedPrice struct {
+	// OfferingID is the VPSie offering the price applies to
+	OfferingID string `json:"offeringID"`
+
+	// TermMonths is the contract length in months
+	TermMonths int `json:"termMonths"`
+
+	// MonthlyPrice is the committed monthly price per node
+	MonthlyPrice float64 `json:"monthlyPrice"`
+}
+
+// Commitment is a committed capacity held for a NodeGroup
+type Commitment struct {
+	// NodeGroup is the name of the NodeGroup the commitment covers
+	NodeGroup string `json:"nodeGroup"`
+
+	// Namespace is the namespace of the NodeGroup
+	Namespace string `json:"namespace"`
+
+	// OfferingID is the committed VPSie offering
+	OfferingID string `json:"offeringID"`
+
+	// Count is the number of committed nodes
+	Count int32 `json:"count"`
+
+	// TermMonths is the contract length in months
+	TermMonths int `json:"termMonths"`
+
+	// MonthlyPrice overrides the committed monthly price per node (optional)
+	MonthlyPrice float64 `json:"monthlyPrice,omitempty"`
+
+	// ExpiresAt is when the commitment ends; expired commitments are ignored (optional)
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
+
+// ParsePricingPlan parses a pricing plan from a YAML or JSON document
+func ParsePricingPlan(data []byte) (*PricingPlan, error) {
+	plan := &PricingPlan{}
+	if err := yaml.UnmarshalStrict(data, plan); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing plan: %w", err)
+	}
+
+	if err := plan.Validate(); err != nil {
+		return nil, err
+	}
+
+	if plan.Currency == "" {
+		plan.Currency = "USD"
+	}
+
+	return plan, nil
+}
+
+// LoadPricingPlanFromConfigMap loads a pricing plan from the PricingPlanConfigMapKey
+// entry of the given ConfigMap
+func LoadPricingPlanFromConfigMap(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*PricingPlan, error) {
+	cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pricing ConfigMap %s/%s: %w", namespace, name, err)
+	}
+
+	data, ok := cm.Data[PricingPlanConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("pricing ConfigMap %s/%s has no %q key", namespace, name, PricingPlanConfigMapKey)
+	}
+
+	return ParsePricingPlan([]byte(data))
+}
+
+// Validate checks the pricing plan for invalid values
+func (p *PricingPlan) Validate() error {
+	for category, discount := range p.CategoryDiscounts {
+		if discount < 0 || discount > 100 {
+			return fmt.Errorf("discount for category %q must be between 0 and 100, got %v", category, discount)
+		}
+	}
+
+	for _, term := range p.ContractTerms {
+		if term.Months <= 0 {
+			return fmt.Errorf("contract term %q must have a positive number of months", term.Name)
+		}
+		if term.DiscountPercent < 0 || term.DiscountPercent > 100 {
+			return fmt.Errorf("discount for contract term %q must be between 0 and 100, got %v", term.Name, term.DiscountPercent)
+		}
+	}
+
+	for _, price := range p.CommittedPrices {
+		if price.OfferingID == "" {
+			return fmt.Errorf("committed price must specify an offeringID")
+		}
+		if price.TermMonths <= 0 || price.MonthlyPrice < 0 {
+			return fmt.Errorf("committed price for offering %s must have positive termMonths and non-negative monthlyPrice", price.OfferingID)
+		}
+	}
+
+	for _, commitment := range p.Commitments {
+		if commitment.NodeGroup == "" || commitment.OfferingID == "" {
+			return fmt.Errorf("commitment must specify nodeGroup and offeringID")
+		}
+		if commitment.Count < 0 {
+			return fmt.Errorf("commitment for NodeGroup %s has negative count", commitment.NodeGroup)
+		}
+	}
+
+	return nil
+}
+
+// OnDemandMonthly returns the effective on-demand monthly price of an offering
+// after any category discount
+func (p *PricingPlan) OnDemandMonthly(offering *OfferingCost) float64 {
+	if p == nil {
+		return offering.MonthlyCost
+	}
+	discount := p.CategoryDiscounts[offering.Category]
+	return offering.MonthlyCost * (1 - discount/100)
+}
+
+// CommittedMonthly returns the committed monthly price of an offering for a term.
+// Explicit CommittedPrices win over the generic ContractTerms discount.
+// Returns false if the plan offers no committed price for the offering and term.
+func (p *PricingPlan) CommittedMonthly(offering *OfferingCost, termMonths int) (float64, bool) {
+	if p == nil {
+		return 0, false
+	}
+
+	for _, price := range p.CommittedPrices {
+		if price.OfferingID == offering.OfferingID && price.TermMonths == termMonths {
+			return price.MonthlyPrice, true
+		}
+	}
+
+	for _, term := range p.ContractTerms {
+		if term.Months == termMonths {
+			return offering.MonthlyCost * (1 - term.DiscountPercent/100), true
+		}
+	}
+
+	return 0, false
+}
+
+// BestCommittedTerm returns the contract term with the lowest committed monthly
+// price for an offering. Returns false if no committed pricing is available.
+func (p *PricingPlan) BestCommittedTerm(offering *OfferingCost) (termMonths int, monthly float64, ok bool) {
+	if p == nil {
+		return 0, 0, false
+	}
+
+	terms := make(map[int]bool)
+	for _, term := range p.ContractTerms {
+		terms[term.Months] = true
+	}
+	for _, price := range p.CommittedPrices {
+		if price.OfferingID == offering.OfferingID {
+			terms[price.TermMonths] = true
+		}
+	}
+
+	for months := range terms {
+		price, found := p.CommittedMonthly(offering, months)
+		if !found {
+			continue
+		}
+		// Prefer the cheaper price, then the shorter term on ties
+		if !ok || price < monthly || (price == monthly && months < termMonths) {
+			termMonths, monthly, ok = months, price, true
+		}
+	}
+
+	return termMonths, monthly, ok
+}
+
+// activeCommitments returns the non-expired commitments for a NodeGroup and offering
+func (p *PricingPlan) activeCommitments(nodeGroup, namespace, offeringID string, now time.Time) []Commitment {
+	if p == nil {
+		return nil
+	}
+
+	var result []Commitment
+	for _, commitment := range p.Commitments {
+		if commitment.NodeGroup != nodeGroup || commitment.OfferingID != offeringID {
+			continue
+		}
+		if commitment.Namespace != "" && commitment.Namespace != namespace {
+			continue
+		}
+		if commitment.ExpiresAt != nil && !commitment.ExpiresAt.Time.After(now) {
+			continue
+		}
+		result = append(result, commitment)
+	}
+	return result
+}
+
+// committedOfferings returns the offerings with non-expired commitments for a
+// NodeGroup, in the order they appear in the plan
+func (p *PricingPlan) committedOfferings(nodeGroup, namespace string, now time.Time) []string {
+	if p == nil {
+		return nil
+	}
+
+	var result []string
+	for _, commitment := range p.Commitments {
+		if slices.Contains(result, commitment.OfferingID) {
+			continue
+		}
+		if len(p.activeCommitments(nodeGroup, namespace, commitment.OfferingID, now)) > 0 {
+			result = append(result, commitment.OfferingID)
+		}
+	}
+	return result
+}
+
+// effectiveInstanceCost splits count nodes of an offering between committed and
+// on-demand pricing and returns the committed node count and effective monthly cost.
+// Commitments are billed in full, so committed slots without a node still cost
+// the committed price.
+func (p *PricingPlan) effectiveInstanceCost(nodeGroup, namespace string, offering *OfferingCost, count int32) (committed int32, monthly float64) {
+	remaining := count
+	for _, commitment := range p.activeCommitments(nodeGroup, namespace, offering.OfferingID, time.Now()) {
+		covered := commitment.Count
+		if covered > remaining {
+			covered = remaining
+		}
+
+		price := commitment.MonthlyPrice
+		if price <= 0 {
+			var ok bool
+			price, ok = p.CommittedMonthly(offering, commitment.TermMonths)
+			if !ok {
+				price = p.OnDemandMonthly(offering)
+			}
+		}
+
+		monthly += price * float64(commitment.Count)
+		committed += covered
+		remaining -= covered
+	}
+
+	monthly += p.OnDemandMonthly(offering) * float64(remaining)
+	return committed, monthly
+}
@@ -0,0 +1,440 @@
+package cost
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/vpsie/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testPricingPlan = `
+currency: USD
+categoryDiscounts:
+  standard: 10
+contractTerms:
+  - name: 1-year
+    months: 12
+    discountPercent: 20
+  - name: 3-year
+    months: 36
+    discountPercent: 35
+committedPrices:
+  - offeringID: small-1
+    termMonths: 12
+    monthlyPrice: 5
+commitments:
+  - nodeGroup: test-group
+    namespace: default
+    offeringID: small-1
+    count: 2
+    termMonths: 12
+`
+
+func newPricingTestClient() *MockVPSieClient {
+	return &MockVPSieClient{
+		offerings: []client.Offering{
+			{
+				ID:          "small-1",
+				Name:        "Small Instance",
+				CPU:         2,
+				RAM:         2048,
+				Disk:        50,
+				Price:       10.0,
+				HourlyPrice: 0.015,
+				Available:   true,
+				Category:    "standard",
+			},
+		},
+	}
+}
+
+func newPricingTestNodeGroup(nodes int) *v1alpha1.NodeGroup {
+	nodeGroup := &v1alpha1.NodeGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-group",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.NodeGroupSpec{
+			MinNodes:    3,
+			MaxNodes:    10,
+			OfferingIDs: []string{"small-1"},
+		},
+	}
+	for i := 0; i < nodes; i++ {
+		nodeGroup.Status.Nodes = append(nodeGroup.Status.Nodes, v1alpha1.NodeInfo{
+			NodeName:     "node",
+			InstanceType: "small-1",
+		})
+	}
+	nodeGroup.Status.CurrentNodes = int32(nodes)
+	nodeGroup.Status.DesiredNodes = int32(nodes)
+	return nodeGroup
+}
+
+func floatEquals(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestParsePricingPlan(t *testing.T) {
+	t.Run("Valid plan", func(t *testing.T) {
+		plan, err := ParsePricingPlan([]byte(testPricingPlan))
+		if err != nil {
+			t.Fatalf("ParsePricingPlan failed: %v", err)
+		}
+
+		if len(plan.ContractTerms) != 2 {
+			t.Errorf("Expected 2 contract terms, got %d", len(plan.ContractTerms))
+		}
+		if plan.CategoryDiscounts["standard"] != 10 {
+			t.Errorf("Expected standard discount 10, got %f", plan.CategoryDiscounts["standard"])
+		}
+		if len(plan.Commitments) != 1 {
+			t.Errorf("Expected 1 commitment, got %d", len(plan.Commitments))
+		}
+	})
+
+	t.Run("Default currency", func(t *testing.T) {
+		plan, err := ParsePricingPlan([]byte(`categoryDiscounts: {standard: 5}`))
+		if err != nil {
+			t.Fatalf("ParsePricingPlan failed: %v", err)
+		}
+		if plan.Currency != "USD" {
+			t.Errorf("Expected default currency USD, got %s", plan.Currency)
+		}
+	})
+
+	t.Run("Invalid documents", func(t *testing.T) {
+		tests := []struct {
+			name string
+			data string
+		}{
+			{"unknown field", `discount: 10`},
+			{"discount out of range", `categoryDiscounts: {standard: 120}`},
+			{"zero month term", `contractTerms: [{name: bad, months: 0, discountPercent: 10}]`},
+			{"commitment without offering", `commitments: [{nodeGroup: test, count: 1, termMonths: 12}]`},
+		}
+
+		for _, tt := range tests {
+			if _, err := ParsePricingPlan([]byte(tt.data)); err == nil {
+				t.Errorf("%s: expected error, got nil", tt.name)
+			}
+		}
+	})
+}
+
+func TestPricingPlanPrices(t *testing.T) {
+	plan, err := ParsePricingPlan([]byte(testPricingPlan))
+	if err != nil {
+		t.Fatalf("ParsePricingPlan failed: %v", err)
+	}
+
+	offering := &OfferingCost{OfferingID: "small-1", MonthlyCost: 10.0, Category: "standard"}
+
+	if got := plan.OnDemandMonthly(offering); !floatEquals(got, 9.0) {
+		t.Errorf("Expected discounted on-demand price 9.0, got %f", got)
+	}
+
+	// Explicit committed price wins over the 12-month term discount
+	if got, ok := plan.CommittedMonthly(offering, 12); !ok || !floatEquals(got, 5.0) {
+		t.Errorf("Expected 12-month committed price 5.0, got %f (ok=%v)", got, ok)
+	}
+
+	if got, ok := plan.CommittedMonthly(offering, 36); !ok || !floatEquals(got, 6.5) {
+		t.Errorf("Expected 36-month committed price 6.5, got %f (ok=%v)", got, ok)
+	}
+
+	if _, ok := plan.CommittedMonthly(offering, 24); ok {
+		t.Error("Expected no committed price for a 24-month term")
+	}
+
+	months, monthly, ok := plan.BestCommittedTerm(offering)
+	if !ok || months != 12 || !floatEquals(monthly, 5.0) {
+		t.Errorf("Expected best term 12 months at 5.0, got %d months at %f (ok=%v)", months, monthly, ok)
+	}
+
+	var nilPlan *PricingPlan
+	if got := nilPlan.OnDemandMonthly(offering); got != 10.0 {
+		t.Errorf("Expected nil plan to return list price, got %f", got)
+	}
+}
+
+func TestCalculateNodeGroupCostWithPricingPlan(t *testing.T) {
+	ctx := context.Background()
+	calc := NewCalculator(newPricingTestClient())
+
+	nodeGroup := newPricingTestNodeGroup(4)
+
+	t.Run("Without pricing plan", func(t *testing.T) {
+		cost, err := calc.CalculateNodeGroupCost(ctx, nodeGroup)
+		if err != nil {
+			t.Fatalf("CalculateNodeGroupCost failed: %v", err)
+		}
+		if cost.EffectiveMonthly != cost.TotalMonthly {
+			t.Errorf("Expected effective cost to equal list cost, got %f vs %f", cost.EffectiveMonthly, cost.TotalMonthly)
+		}
+		if cost.CommittedNodes != 0 {
+			t.Errorf("Expected no committed nodes, got %d", cost.CommittedNodes)
+		}
+	})
+
+	plan, err := ParsePricingPlan([]byte(testPricingPlan))
+	if err != nil {
+		t.Fatalf("ParsePricingPlan failed: %v", err)
+	}
+	calc.SetPricingPlan(plan)
+
+	t.Run("With commitments and discounts", func(t *testing.T) {
+		cost, err := calc.CalculateNodeGroupCost(ctx, nodeGroup)
+		if err != nil {
+			t.Fatalf("CalculateNodeGroupCost failed: %v", err)
+		}
+
+		// 2 committed nodes at 5.0 plus 2 on-demand nodes at 9.0
+		if !floatEquals(cost.EffectiveMonthly, 28.0) {
+			t.Errorf("Expected effective monthly cost 28.0, got %f", cost.EffectiveMonthly)
+		}
+		if cost.CommittedNodes != 2 {
+			t.Errorf("Expected 2 committed nodes, got %d", cost.CommittedNodes)
+		}
+		if cost.InstanceTypes["small-1"].CommittedCount != 2 {
+			t.Errorf("Expected 2 committed small-1 nodes, got %d", cost.InstanceTypes["small-1"].CommittedCount)
+		}
+	})
+
+	t.Run("Expired commitment is ignored", func(t *testing.T) {
+		expired := *plan
+		expired.Commitments = []Commitment{plan.Commitments[0]}
+		expired.Commitments[0].ExpiresAt = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+		calc.SetPricingPlan(&expired)
+		defer calc.SetPricingPlan(plan)
+
+		cost, err := calc.CalculateNodeGroupCost(ctx, nodeGroup)
+		if err != nil {
+			t.Fatalf("CalculateNodeGroupCost failed: %v", err)
+		}
+		if cost.CommittedNodes != 0 {
+			t.Errorf("Expected expired commitment to be ignored, got %d committed nodes", cost.CommittedNodes)
+		}
+		if !floatEquals(cost.EffectiveMonthly, 36.0) {
+			t.Errorf("Expected effective monthly cost 36.0, got %f", cost.EffectiveMonthly)
+		}
+	})
+
+	t.Run("Unused committed slots are billed", func(t *testing.T) {
+		cost, err := calc.CalculateNodeGroupCost(ctx, newPricingTestNodeGroup(1))
+		if err != nil {
+			t.Fatalf("CalculateNodeGroupCost failed: %v", err)
+		}
+
+		// Both committed nodes at 5.0 are paid for with a single node running
+		if !floatEquals(cost.EffectiveMonthly, 10.0) {
+			t.Errorf("Expected effective monthly cost 10.0, got %f", cost.EffectiveMonthly)
+		}
+		if cost.CommittedNodes != 1 {
+			t.Errorf("Expected 1 committed node, got %d", cost.CommittedNodes)
+		}
+	})
+
+	t.Run("Commitments on offerings without nodes are billed", func(t *testing.T) {
+		mockClient := newPricingTestClient()
+		mockClient.offerings = append(mockClient.offerings, client.Offering{
+			ID:          "medium-1",
+			Name:        "Medium Instance",
+			CPU:         4,
+			RAM:         4096,
+			Price:       20.0,
+			HourlyPrice: 0.03,
+			Available:   true,
+			Category:    "standard",
+		})
+		mediumCalc := NewCalculator(mockClient)
+		mediumCalc.SetPricingPlan(plan)
+
+		mediumGroup := newPricingTestNodeGroup(2)
+		for i := range mediumGroup.Status.Nodes {
+			mediumGroup.Status.Nodes[i].InstanceType = "medium-1"
+		}
+
+		cost, err := mediumCalc.CalculateNodeGroupCost(ctx, mediumGroup)
+		if err != nil {
+			t.Fatalf("CalculateNodeGroupCost failed: %v", err)
+		}
+
+		// 2 on-demand medium-1 nodes at 18.0 plus the 2 committed small-1 slots at 5.0
+		if !floatEquals(cost.EffectiveMonthly, 46.0) {
+			t.Errorf("Expected effective monthly cost 46.0, got %f", cost.EffectiveMonthly)
+		}
+		if cost.CommittedNodes != 0 {
+			t.Errorf("Expected no committed nodes, got %d", cost.CommittedNodes)
+		}
+		if _, ok := cost.InstanceTypes["small-1"]; ok {
+			t.Errorf("Expected no small-1 instance type without small-1 nodes")
+		}
+
+		medium, err := mediumCalc.GetOfferingCost(ctx, "medium-1")
+		if err != nil {
+			t.Fatalf("GetOfferingCost failed: %v", err)
+		}
+		replacement, err := mediumCalc.effectiveReplacementCost(ctx, mediumGroup, medium, 1)
+		if err != nil {
+			t.Fatalf("effectiveReplacementCost failed: %v", err)
+		}
+		if !floatEquals(replacement, 28.0) {
+			t.Errorf("Expected replacement cost 28.0 with the committed slots, got %f", replacement)
+		}
+	})
+
+	t.Run("Zero effective cost is kept", func(t *testing.T) {
+		cost := &NodeGroupCost{TotalMonthly: 40.0, EffectiveMonthly: 0, HasEffectiveMonthly: true}
+		if got := cost.effectiveMonthly(); got != 0 {
+			t.Errorf("Expected effective monthly cost 0, got %f", got)
+		}
+
+		cost.HasEffectiveMonthly = false
+		if got := cost.effectiveMonthly(); got != 40.0 {
+			t.Errorf("Expected list cost without an effective cost, got %f", got)
+		}
+	})
+
+	t.Run("Savings use effective cost", func(t *testing.T) {
+		current := &NodeGroupCost{TotalMonthly: 40.0, EffectiveMonthly: 28.0, HasEffectiveMonthly: true}
+		proposed := &NodeGroupCost{TotalMonthly: 30.0, EffectiveMonthly: 30.0, HasEffectiveMonthly: true}
+
+		savings, err := calc.CalculateSavings(current, proposed)
+		if err != nil {
+			t.Fatalf("CalculateSavings failed: %v", err)
+		}
+		if savings.MonthlySavings >= 0 {
+			t.Errorf("Expected negative savings against committed pricing, got %f", savings.MonthlySavings)
+		}
+	})
+}
+
+func TestAnalyzeCommittedPricing(t *testing.T) {
+	ctx := context.Background()
+	mockClient := newPricingTestClient()
+	calc := NewCalculator(mockClient)
+	optimizer := NewOptimizer(calc, nil, mockClient)
+
+	plan, err := ParsePricingPlan([]byte(`
+categoryDiscounts:
+  standard: 10
+contractTerms:
+  - name: 1-year
+    months: 12
+    discountPercent: 30
+`))
+	if err != nil {
+		t.Fatalf("ParsePricingPlan failed: %v", err)
+	}
+	calc.SetPricingPlan(plan)
+
+	nodeGroup := newPricingTestNodeGroup(5)
+	currentCost, err := calc.CalculateNodeGroupCost(ctx, nodeGroup)
+	if err != nil {
+		t.Fatalf("CalculateNodeGroupCost failed: %v", err)
+	}
+
+	opportunity, err := optimizer.analyzeCommittedPricing(ctx, nodeGroup, currentCost)
+	if err != nil {
+		t.Fatalf("analyzeCommittedPricing failed: %v", err)
+	}
+	if opportunity == nil {
+		t.Fatal("Expected a committed pricing opportunity")
+	}
+
+	if opportunity.Type != OptimizationReserved {
+		t.Errorf("Expected type %s, got %s", OptimizationReserved, opportunity.Type)
+	}
+
+	// MinNodes (3) baseline, on-demand 9.0 vs committed 7.0
+	if !floatEquals(opportunity.MonthlySavings, 6.0) {
+		t.Errorf("Expected monthly savings 6.0, got %f", opportunity.MonthlySavings)
+	}
+
+	t.Run("No opportunity without pricing plan", func(t *testing.T) {
+		calc.SetPricingPlan(nil)
+		defer calc.SetPricingPlan(plan)
+
+		opportunity, err := optimizer.analyzeCommittedPricing(ctx, nodeGroup, currentCost)
+		if err != nil {
+			t.Fatalf("analyzeCommittedPricing failed: %v", err)
+		}
+		if opportunity != nil {
+			t.Errorf("Expected no opportunity without a pricing plan, got %+v", opportunity)
+		}
+	})
+}
+
+func TestAnalyzeConsolidationWithPricingPlan(t *testing.T) {
+	ctx := context.Background()
+	mockClient := newPricingTestClient()
+	mockClient.offerings = append(mockClient.offerings, client.Offering{
+		ID:          "large-1",
+		Name:        "Large Instance",
+		CPU:         8,
+		RAM:         8192,
+		Disk:        200,
+		Price:       15.0,
+		HourlyPrice: 0.021,
+		Available:   true,
+		Category:    "standard",
+	})
+	calc := NewCalculator(mockClient)
+	optimizer := NewOptimizer(calc, nil, mockClient)
+
+	nodeGroup := newPricingTestNodeGroup(4)
+	utilization := &UtilizationAnalysis{
+		PeakUtilization: ResourceUtilization{CPUPercent: 40, MemoryPercent: 40},
+	}
+
+	t.Run("List prices", func(t *testing.T) {
+		currentCost, err := calc.CalculateNodeGroupCost(ctx, nodeGroup)
+		if err != nil {
+			t.Fatalf("CalculateNodeGroupCost failed: %v", err)
+		}
+
+		opportunity, err := optimizer.analyzeConsolidation(ctx, nodeGroup, currentCost, utilization)
+		if err != nil {
+			t.Fatalf("analyzeConsolidation failed: %v", err)
+		}
+		if opportunity == nil || opportunity.RecommendedOffering != "large-1" {
+			t.Fatalf("Expected consolidation onto large-1, got %+v", opportunity)
+		}
+		// 4 nodes at 0.015/hour against one large-1 node at 15.0
+		if !floatEquals(opportunity.MonthlySavings, 4*0.015*hoursPerMonth-15.0) {
+			t.Errorf("Expected monthly savings 28.8, got %f", opportunity.MonthlySavings)
+		}
+	})
+
+	t.Run("Effective prices", func(t *testing.T) {
+		plan, err := ParsePricingPlan([]byte(testPricingPlan))
+		if err != nil {
+			t.Fatalf("ParsePricingPlan failed: %v", err)
+		}
+		calc.SetPricingPlan(plan)
+		defer calc.SetPricingPlan(nil)
+
+		currentCost, err := calc.CalculateNodeGroupCost(ctx, nodeGroup)
+		if err != nil {
+			t.Fatalf("CalculateNodeGroupCost failed: %v", err)
+		}
+
+		// Moving to large-1 still pays the small-1 commitment, so keeping
+		// the two committed small-1 nodes is cheaper
+		opportunity, err := optimizer.analyzeConsolidation(ctx, nodeGroup, currentCost, utilization)
+		if err != nil {
+			t.Fatalf("analyzeConsolidation failed: %v", err)
+		}
+		if opportunity == nil || opportunity.RecommendedOffering != "small-1" {
+			t.Fatalf("Expected consolidation onto small-1, got %+v", opportunity)
+		}
+		if !floatEquals(opportunity.MonthlySavings, 18.0) {
+			t.Errorf("Expected monthly savings 18.0, got %f", opportunity.MonthlySavings)
+		}
+	})
+}
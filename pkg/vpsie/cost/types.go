@@ -16,6 +16,7 @@ type OfferingCost struct {
 	Currency    string
 	Specs       ResourceSpecs
 	Category    string
+	Source      PricingSource // Where the price came from (live API or catalog)
 	LastUpdated time.Time
 }
 
@@ -39,15 +40,23 @@ type NodeGroupCost struct {
 	InstanceTypes    map[string]InstanceTypeCost // offeringID -> cost breakdown
 	LastUpdated      time.Time
 	EstimatedSavings float64 // Potential savings if optimized
+	EffectiveMonthly float64 // Monthly cost after pricing plan discounts and commitments
+	CommittedNodes   int32   // Nodes billed at committed pricing
+
+	// HasEffectiveMonthly reports whether EffectiveMonthly was computed; a
+	// computed effective cost may legitimately be zero
+	HasEffectiveMonthly bool
 }
 
 // InstanceTypeCost represents cost for a specific instance type in the group
 type InstanceTypeCost struct {
-	OfferingID   string
-	Count        int32
-	HourlyEach   float64
-	TotalHourly  float64
-	TotalMonthly float64
+	OfferingID       string
+	Count            int32
+	HourlyEach       float64
+	TotalHourly      float64
+	TotalMonthly     float64
+	CommittedCount   int32   // Nodes billed at committed pricing
+	EffectiveMonthly float64 // Monthly cost after pricing plan discounts and commitments
 }
 
 // CostComparison compares costs between multiple offerings
@@ -69,12 +78,24 @@ type CostSnapshot struct {
 	EfficiencyScore float64 // 0-100, higher is better
 }
 
-// ResourceUtilization represents resource utilization metrics
+// ResourceUtilization represents resource utilization metrics. CPUPercent and
+// MemoryPercent are measured by usage, CPURequestsPercent and
+// MemoryRequestsPercent by pod requests.
 type ResourceUtilization struct {
-	CPUPercent    float64
-	MemoryPercent float64
-	DiskPercent   float64
-	NodeCount     int32
+	CPUPercent            float64
+	MemoryPercent         float64
+	CPURequestsPercent    float64
+	MemoryRequestsPercent float64
+	DiskPercent           float64
+	NodeCount             int32
+}
+
+// Resolve returns the utilization with CPUPercent and MemoryPercent measured
+// under a NodeGroup's utilization mode
+func (u ResourceUtilization) Resolve(mode v1alpha1.UtilizationMode) ResourceUtilization {
+	u.CPUPercent = v1alpha1.ResolveUtilization(mode, u.CPUPercent, u.CPURequestsPercent)
+	u.MemoryPercent = v1alpha1.ResolveUtilization(mode, u.MemoryPercent, u.MemoryRequestsPercent)
+	return u
 }
 
 // SavingsAnalysis analyzes potential savings from optimization
@@ -127,6 +148,7 @@ const (
 	OptimizationConsolidateNodes OptimizationType = "consolidate"
 	OptimizationSpotInstances    OptimizationType = "use_spot_instances"
 	OptimizationReserved         OptimizationType = "reserved_instances"
+	OptimizationScaleDown        OptimizationType = "scale_down"
 )
 
 // RiskLevel represents the risk level of an optimization
@@ -147,6 +169,9 @@ type Recommendation struct {
 	PerformanceImpact  string
 	Confidence         float64
 	AlternativeOptions []string
+	PricingSource      PricingSource
+	DatacenterID       string  // Datacenter of the recommended offering
+	EmissionsPerHour   float64 // Estimated gCO2e per hour, 0 when unknown
 }
 
 // Optimization represents an optimization to be applied
@@ -246,8 +271,13 @@ type CostForecast struct {
 
 // UtilizationAnalysis analyzes resource utilization vs cost
 type UtilizationAnalysis struct {
-	NodeGroupName      string
-	Namespace          string
+	NodeGroupName string
+	Namespace     string
+	// Mode and Aggregation are the NodeGroup's utilizationMode and
+	// utilizationAggregation the utilization was measured with
+	Mode        v1alpha1.UtilizationMode
+	Aggregation v1alpha1.UtilizationAggregation
+	// AverageUtilization aggregates the snapshots with Aggregation
 	AverageUtilization ResourceUtilization
 	PeakUtilization    ResourceUtilization
 	CostPerCPUCore     float64
@@ -314,6 +344,7 @@ type ResourceRequirements struct {
 	MinBandwidth int
 	Workload     WorkloadType
 	Priority     PriorityClass
+	Objective    CarbonObjective // Weighs emissions against price (default: cost only)
 }
 
 // WorkloadType represents the type of workload
@@ -2,12 +2,14 @@ package webhook
 
 import (
 	"fmt"
+	"net/url"
 	"regexp"
 
 	"go.uber.org/zap"
 	admissionv1 "k8s.io/api/admission/v1"
 
 	autoscalerv1alpha1 "github.com/vpsie/vpsie-k8s-autoscaler/pkg/apis/autoscaler/v1alpha1"
+	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/controller/nodegroup"
 	"github.com/vpsie/vpsie-k8s-autoscaler/pkg/metrics"
 )
 
@@ -110,6 +112,21 @@ func (v *NodeGroupValidator) Validate(ng *autoscalerv1alpha1.NodeGroup, operatio
 		if err := v.validateTaints(ng); err != nil {
 			return err
 		}
+
+		// Validate drain hooks
+		if err := v.validateDrain(ng); err != nil {
+			return err
+		}
+
+		// Validate consolidation
+		if err := v.validateConsolidation(ng); err != nil {
+			return err
+		}
+
+		// Validate scaling schedules
+		if err := v.validateSchedules(ng); err != nil {
+			return err
+		}
 	}
 
 	// UPDATE-specific validations can be added here if needed in the future
@@ -299,6 +316,61 @@ func (v *NodeGroupValidator) validateScaleDownPolicy(ng *autoscalerv1alpha1.Node
 			policy.MemoryThreshold)
 	}
 
+	// Validate utilization mode and aggregation
+	switch policy.UtilizationMode {
+	case "", autoscalerv1alpha1.UtilizationModeUsage, autoscalerv1alpha1.UtilizationModeRequests, autoscalerv1alpha1.UtilizationModeMax:
+	default:
+		return fmt.Errorf("spec.scaleDownPolicy.utilizationMode '%s' is not valid (must be Usage, Requests, or Max)",
+			policy.UtilizationMode)
+	}
+	switch policy.UtilizationAggregation {
+	case "", autoscalerv1alpha1.UtilizationAggregationMean, autoscalerv1alpha1.UtilizationAggregationP50, autoscalerv1alpha1.UtilizationAggregationP95:
+	default:
+		return fmt.Errorf("spec.scaleDownPolicy.utilizationAggregation '%s' is not valid (must be Mean, P50, or P95)",
+			policy.UtilizationAggregation)
+	}
+
+	// Validate deletion order
+	switch policy.DeletionOrder {
+	case "", autoscalerv1alpha1.NodeDeletionOrderDefault, autoscalerv1alpha1.NodeDeletionOrderOldestFirst,
+		autoscalerv1alpha1.NodeDeletionOrderNewestFirst, autoscalerv1alpha1.NodeDeletionOrderLeastUtilized,
+		autoscalerv1alpha1.NodeDeletionOrderMostExpensiveFirst, autoscalerv1alpha1.NodeDeletionOrderFewestPods,
+		autoscalerv1alpha1.NodeDeletionOrderSpreadPreserving:
+	default:
+		return fmt.Errorf("spec.scaleDownPolicy.deletionOrder '%s' is not valid (must be Default, OldestFirst, NewestFirst, LeastUtilized, MostExpensiveFirst, FewestPods, or SpreadPreserving)",
+			policy.DeletionOrder)
+	}
+
+	return nil
+}
+
+// validateConsolidation validates the consolidation settings
+func (v *NodeGroupValidator) validateConsolidation(ng *autoscalerv1alpha1.NodeGroup) error {
+	consolidation := ng.Spec.Consolidation
+	if consolidation == nil {
+		return nil
+	}
+
+	if consolidation.MinSavingsPercent < 0 || consolidation.MinSavingsPercent > 100 {
+		return fmt.Errorf("spec.consolidation.minSavingsPercent must be between 0 and 100, got %d",
+			consolidation.MinSavingsPercent)
+	}
+
+	if consolidation.ReplacementTimeoutSeconds != 0 && consolidation.ReplacementTimeoutSeconds < 60 {
+		return fmt.Errorf("spec.consolidation.replacementTimeoutSeconds must be >= 60, got %d",
+			consolidation.ReplacementTimeoutSeconds)
+	}
+
+	return nil
+}
+
+// validateSchedules validates the scaling schedules with the same rules the
+// NodeGroup controller applies, so a bad cron expression or time zone is
+// rejected at admission
+func (v *NodeGroupValidator) validateSchedules(ng *autoscalerv1alpha1.NodeGroup) error {
+	if err := nodegroup.ValidateSchedules(ng.Spec.Schedules); err != nil {
+		return fmt.Errorf("spec.%w", err)
+	}
 	return nil
 }
 
@@ -365,6 +437,60 @@ func (v *NodeGroupValidator) validateTaints(ng *autoscalerv1alpha1.NodeGroup) er
 	return nil
 }
 
+// validateDrain validates the eviction concurrency and the drain hooks
+func (v *NodeGroupValidator) validateDrain(ng *autoscalerv1alpha1.NodeGroup) error {
+	if ng.Spec.Drain == nil {
+		return nil
+	}
+
+	if n := ng.Spec.Drain.MaxConcurrentEvictions; n != nil && (*n < 1 || *n > 100) {
+		return fmt.Errorf("spec.drain.maxConcurrentEvictions must be between 1 and 100, got %d", *n)
+	}
+
+	if err := validateDrainHooks("spec.drain.preDrainHooks", ng.Spec.Drain.PreDrainHooks); err != nil {
+		return err
+	}
+	return validateDrainHooks("spec.drain.postDrainHooks", ng.Spec.Drain.PostDrainHooks)
+}
+
+// validateDrainHooks validates the drain hooks of one stage
+func validateDrainHooks(field string, hooks []autoscalerv1alpha1.DrainHook) error {
+	names := make(map[string]bool, len(hooks))
+	for i, hook := range hooks {
+		if hook.Name == "" {
+			return fmt.Errorf("%s[%d].name cannot be empty", field, i)
+		}
+		if names[hook.Name] {
+			return fmt.Errorf("%s[%d].name '%s' is used by another hook", field, i, hook.Name)
+		}
+		names[hook.Name] = true
+
+		if (hook.HTTP == nil) == (hook.Job == nil) {
+			return fmt.Errorf("%s[%d] must set exactly one of http and job", field, i)
+		}
+
+		if hook.HTTP != nil {
+			u, err := url.Parse(hook.HTTP.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%s[%d].http.url '%s' is not a valid http or https URL", field, i, hook.HTTP.URL)
+			}
+		}
+
+		if hook.Job != nil && len(hook.Job.Template.Spec.Containers) == 0 {
+			return fmt.Errorf("%s[%d].job.template must have at least one container", field, i)
+		}
+
+		if hook.Timeout != nil && hook.Timeout.Duration <= 0 {
+			return fmt.Errorf("%s[%d].timeout must be positive", field, i)
+		}
+		if hook.HTTP != nil && hook.Timeout != nil && hook.Timeout.Duration > autoscalerv1alpha1.MaxHTTPDrainHookTimeout {
+			return fmt.Errorf("%s[%d].timeout of an http hook cannot exceed %s", field, i, autoscalerv1alpha1.MaxHTTPDrainHookTimeout)
+		}
+	}
+
+	return nil
+}
+
 // hasReservedPrefix checks if a label or taint key uses a reserved Kubernetes prefix
 func hasReservedPrefix(key string) bool {
 	reservedPrefixes := []string{
@@ -1,7 +1,9 @@
 package webhook
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 	admissionv1 "k8s.io/api/admission/v1"
@@ -551,6 +553,47 @@ func TestNodeGroupValidator_ValidateScaleDownPolicy(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid utilization mode and aggregation",
+			policy: autoscalerv1alpha1.ScaleDownPolicy{
+				Enabled:                true,
+				UtilizationMode:        autoscalerv1alpha1.UtilizationModeMax,
+				UtilizationAggregation: autoscalerv1alpha1.UtilizationAggregationP95,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid utilization mode",
+			policy: autoscalerv1alpha1.ScaleDownPolicy{
+				Enabled:         true,
+				UtilizationMode: "Limits",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid utilization aggregation",
+			policy: autoscalerv1alpha1.ScaleDownPolicy{
+				Enabled:                true,
+				UtilizationAggregation: "P99",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid deletion order",
+			policy: autoscalerv1alpha1.ScaleDownPolicy{
+				Enabled:       true,
+				DeletionOrder: autoscalerv1alpha1.NodeDeletionOrderSpreadPreserving,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid deletion order",
+			policy: autoscalerv1alpha1.ScaleDownPolicy{
+				Enabled:       true,
+				DeletionOrder: "Random",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -774,6 +817,281 @@ func TestNodeGroupValidator_ValidateTaints(t *testing.T) {
 	}
 }
 
+func TestNodeGroupValidator_ValidateDrain(t *testing.T) {
+	v := NewNodeGroupValidator(zap.NewNop())
+	evictions, noEvictions := int32(5), int32(0)
+
+	httpHook := func(name, url string) autoscalerv1alpha1.DrainHook {
+		return autoscalerv1alpha1.DrainHook{
+			Name: name,
+			HTTP: &autoscalerv1alpha1.HTTPDrainHook{URL: url},
+		}
+	}
+	jobHook := autoscalerv1alpha1.DrainHook{
+		Name: "flush",
+		Job: &autoscalerv1alpha1.JobDrainHook{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "flush", Image: "busybox"}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		drain   *autoscalerv1alpha1.DrainConfig
+		wantErr bool
+	}{
+		{
+			name:    "nil drain config",
+			drain:   nil,
+			wantErr: false,
+		},
+		{
+			name: "valid hooks",
+			drain: &autoscalerv1alpha1.DrainConfig{
+				PreDrainHooks:  []autoscalerv1alpha1.DrainHook{httpHook("deregister", "https://lb.example.com/deregister"), jobHook},
+				PostDrainHooks: []autoscalerv1alpha1.DrainHook{httpHook("deregister", "http://ops.example.com/drained")},
+			},
+			wantErr: false,
+		},
+		{
+			name: "duplicate hook name",
+			drain: &autoscalerv1alpha1.DrainConfig{
+				PreDrainHooks: []autoscalerv1alpha1.DrainHook{
+					httpHook("notify", "https://a.example.com"),
+					httpHook("notify", "https://b.example.com"),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "neither http nor job",
+			drain: &autoscalerv1alpha1.DrainConfig{
+				PreDrainHooks: []autoscalerv1alpha1.DrainHook{{Name: "empty"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "both http and job",
+			drain: &autoscalerv1alpha1.DrainConfig{
+				PreDrainHooks: []autoscalerv1alpha1.DrainHook{{
+					Name: "both",
+					HTTP: &autoscalerv1alpha1.HTTPDrainHook{URL: "https://example.com"},
+					Job:  jobHook.Job,
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid URL scheme",
+			drain: &autoscalerv1alpha1.DrainConfig{
+				PostDrainHooks: []autoscalerv1alpha1.DrainHook{httpHook("notify", "ftp://example.com")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "job without containers",
+			drain: &autoscalerv1alpha1.DrainConfig{
+				PreDrainHooks: []autoscalerv1alpha1.DrainHook{{
+					Name: "flush",
+					Job:  &autoscalerv1alpha1.JobDrainHook{},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "http hook timeout above the maximum",
+			drain: &autoscalerv1alpha1.DrainConfig{
+				PreDrainHooks: []autoscalerv1alpha1.DrainHook{{
+					Name:    "deregister",
+					HTTP:    &autoscalerv1alpha1.HTTPDrainHook{URL: "https://lb.example.com/deregister"},
+					Timeout: &metav1.Duration{Duration: 10 * time.Minute},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "job hook timeout above the http maximum",
+			drain: &autoscalerv1alpha1.DrainConfig{
+				PreDrainHooks: []autoscalerv1alpha1.DrainHook{{
+					Name:    "flush",
+					Job:     jobHook.Job,
+					Timeout: &metav1.Duration{Duration: 30 * time.Minute},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "valid eviction concurrency",
+			drain:   &autoscalerv1alpha1.DrainConfig{MaxConcurrentEvictions: &evictions},
+			wantErr: false,
+		},
+		{
+			name:    "zero eviction concurrency",
+			drain:   &autoscalerv1alpha1.DrainConfig{MaxConcurrentEvictions: &noEvictions},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ng := &autoscalerv1alpha1.NodeGroup{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-nodegroup",
+					Namespace: "kube-system",
+				},
+				Spec: validNodeGroupSpec(),
+			}
+			ng.Spec.Drain = tt.drain
+			err := v.Validate(ng, admissionv1.Create)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateDrain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNodeGroupValidator_ValidateConsolidation(t *testing.T) {
+	v := NewNodeGroupValidator(zap.NewNop())
+
+	tests := []struct {
+		name          string
+		consolidation *autoscalerv1alpha1.ConsolidationConfig
+		wantErr       bool
+	}{
+		{
+			name:          "nil consolidation config",
+			consolidation: nil,
+			wantErr:       false,
+		},
+		{
+			name:          "valid consolidation",
+			consolidation: &autoscalerv1alpha1.ConsolidationConfig{Enabled: true, MinSavingsPercent: 10, ReplacementTimeoutSeconds: 900},
+			wantErr:       false,
+		},
+		{
+			name:          "defaults unset",
+			consolidation: &autoscalerv1alpha1.ConsolidationConfig{Enabled: true},
+			wantErr:       false,
+		},
+		{
+			name:          "savings above 100 percent",
+			consolidation: &autoscalerv1alpha1.ConsolidationConfig{Enabled: true, MinSavingsPercent: 101},
+			wantErr:       true,
+		},
+		{
+			name:          "replacement timeout too short",
+			consolidation: &autoscalerv1alpha1.ConsolidationConfig{Enabled: true, ReplacementTimeoutSeconds: 30},
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ng := &autoscalerv1alpha1.NodeGroup{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-nodegroup",
+					Namespace: "kube-system",
+				},
+				Spec: validNodeGroupSpec(),
+			}
+			ng.Spec.Consolidation = tt.consolidation
+			err := v.Validate(ng, admissionv1.Create)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConsolidation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNodeGroupValidator_ValidateSchedules(t *testing.T) {
+	v := NewNodeGroupValidator(zap.NewNop())
+
+	businessHours := func() autoscalerv1alpha1.ScalingSchedule {
+		minNodes := int32(5)
+		return autoscalerv1alpha1.ScalingSchedule{
+			Name:     "business-hours",
+			Schedule: "0 8 * * 1-5",
+			Duration: metav1.Duration{Duration: 10 * time.Hour},
+			TimeZone: "Europe/Berlin",
+			MinNodes: &minNodes,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		schedules func() []autoscalerv1alpha1.ScalingSchedule
+		wantErr   bool
+	}{
+		{
+			name:      "no schedules",
+			schedules: func() []autoscalerv1alpha1.ScalingSchedule { return nil },
+			wantErr:   false,
+		},
+		{
+			name: "valid schedule",
+			schedules: func() []autoscalerv1alpha1.ScalingSchedule {
+				return []autoscalerv1alpha1.ScalingSchedule{businessHours()}
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid cron expression",
+			schedules: func() []autoscalerv1alpha1.ScalingSchedule {
+				s := businessHours()
+				s.Schedule = "0 25 * * *"
+				return []autoscalerv1alpha1.ScalingSchedule{s}
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown time zone",
+			schedules: func() []autoscalerv1alpha1.ScalingSchedule {
+				s := businessHours()
+				s.TimeZone = "Mars/Olympus_Mons"
+				return []autoscalerv1alpha1.ScalingSchedule{s}
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate names",
+			schedules: func() []autoscalerv1alpha1.ScalingSchedule {
+				return []autoscalerv1alpha1.ScalingSchedule{businessHours(), businessHours()}
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing duration",
+			schedules: func() []autoscalerv1alpha1.ScalingSchedule {
+				s := businessHours()
+				s.Duration = metav1.Duration{}
+				return []autoscalerv1alpha1.ScalingSchedule{s}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ng := &autoscalerv1alpha1.NodeGroup{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-nodegroup",
+					Namespace: "kube-system",
+				},
+				Spec: validNodeGroupSpec(),
+			}
+			ng.Spec.Schedules = tt.schedules()
+			err := v.Validate(ng, admissionv1.Create)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSchedules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.HasPrefix(err.Error(), "spec.schedules[") {
+				t.Errorf("validateSchedules() error = %v, want it to name spec.schedules", err)
+			}
+		})
+	}
+}
+
 func TestNodeGroupValidator_Operations(t *testing.T) {
 	v := NewNodeGroupValidator(zap.NewNop())
 	ng := &autoscalerv1alpha1.NodeGroup{